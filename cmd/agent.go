@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/agent"
+	"github.com/sensepost/gowitness/pkg/jobs"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var agentCmdOptions = struct {
+	Server       string
+	Token        string
+	Name         string
+	Kinds        []string
+	PollInterval int
+	ChromePath   string
+	ChromeWSS    string
+}{}
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run jobs of a report server's job queue on this machine",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# agent
+
+Run jobs of a report server's job queue on this machine.
+
+The agent polls the server for queued jobs, runs them with a local browser and
+uploads the results and screenshots back to the server, which saves them as if
+it ran the job itself. This spreads probing over several machines, or runs it
+from another network than the server's.
+
+The server must be started with an --agent-token, which the agent
+authenticates with. The token can also be set with the GOWITNESS_AGENT_TOKEN
+environment variable. Agents send heartbeats while they run a job, and jobs of
+agents that go quiet are retried.`)),
+	Example: ascii.Markdown(`
+- gowitness agent --server https://gowitness.example.com --token s3cr3t
+- gowitness agent --server http://10.0.0.5:7171 --token s3cr3t --kinds probe,retake
+- gowitness agent --server http://10.0.0.5:7171 --token s3cr3t --chrome-wss-url ws://localhost:9222`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if agentCmdOptions.Server == "" {
+			return errors.New("a --server to get jobs from must be specified")
+		}
+
+		if agentCmdOptions.Token == "" {
+			agentCmdOptions.Token = os.Getenv("GOWITNESS_AGENT_TOKEN")
+		}
+		if agentCmdOptions.Token == "" {
+			return errors.New("an agent --token must be specified")
+		}
+
+		for _, kind := range agentCmdOptions.Kinds {
+			if !slices.Contains(jobs.Kinds, kind) {
+				return errors.New("unknown job kind " + kind)
+			}
+		}
+
+		if agentCmdOptions.Name == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				return errors.New("could not get the hostname, specify a --name")
+			}
+			agentCmdOptions.Name = hostname
+		}
+		if agentCmdOptions.Name == jobs.LocalWorker {
+			return errors.New("the agent --name may not be " + jobs.LocalWorker)
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := interruptContext()
+		defer stop()
+
+		client := agent.NewClient(agentCmdOptions.Server, agentCmdOptions.Token, agentCmdOptions.Name, 2*time.Minute)
+
+		a := agent.New(slog.New(log.Logger), client, agentCmdOptions.Kinds)
+		a.PollInterval = time.Duration(agentCmdOptions.PollInterval) * time.Second
+		a.ChromePath = agentCmdOptions.ChromePath
+		a.ChromeWSS = agentCmdOptions.ChromeWSS
+
+		log.Info("agent started", "server", agentCmdOptions.Server, "name", agentCmdOptions.Name,
+			"kinds", agentCmdOptions.Kinds)
+		a.Run(ctx)
+		log.Info("agent stopped")
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+
+	agentCmd.Flags().StringVar(&agentCmdOptions.Server, "server", "", "The URL of the report server to get jobs from")
+	agentCmd.Flags().StringVar(&agentCmdOptions.Token, "token", "", "The agent token of the server. Defaults to the GOWITNESS_AGENT_TOKEN environment variable")
+	agentCmd.Flags().StringVar(&agentCmdOptions.Name, "name", "", "The name the agent is known by on the server. Defaults to the hostname")
+	agentCmd.Flags().StringSliceVar(&agentCmdOptions.Kinds, "kinds", jobs.Kinds, "Job kinds to run: probe, retake or ip-enrich. Supports multiple --kinds flags")
+	agentCmd.Flags().IntVar(&agentCmdOptions.PollInterval, "poll-interval", 5, "Seconds to wait between asking for jobs when idle")
+	agentCmd.Flags().StringVar(&agentCmdOptions.ChromePath, "chrome-path", "", "The path to a Google Chrome binary to use (downloads a platform-appropriate binary by default)")
+	agentCmd.Flags().StringVar(&agentCmdOptions.ChromeWSS, "chrome-wss-url", "", "A websocket URL to connect to a remote, already running Chrome DevTools instance (i.e., Chrome started with --remote-debugging-port)")
+}
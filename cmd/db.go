@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/spf13/cobra"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Maintain gowitness databases",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# db
+
+Maintain gowitness databases.
+`)),
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/backup"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var backupCmdFlags = struct {
+	Target       string
+	ProjectsPath string
+	OutFile      string
+	InFile       string
+	Force        bool
+}{}
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Archive a project directory",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# db backup
+
+Archive a project directory.
+
+The project created by 'scan init' in <projects-path>/<target> is archived
+into a single tar file, gzip compressed if it ends in .tar.gz or .tgz, and
+zstd compressed if it ends in .tar.zst. The
+archive holds a consistent snapshot of the project database, taken with
+SQLite's VACUUM INTO so that scans may keep running, along with the
+screenshots, logo, scripts and any other files of the project.
+
+Restore the archive with 'db restore'.`)),
+	Example: ascii.Markdown(`
+- gowitness db backup --target almbrand --out almbrand.tar.gz
+- gowitness db backup --target almbrand --projects-path /data/targets --out almbrand.tar.zst`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if backupCmdFlags.Target == "" {
+			return errors.New("a --target must be specified")
+		}
+		if backupCmdFlags.Target != filepath.Base(backupCmdFlags.Target) {
+			return errors.New("--target must be a project name, not a path")
+		}
+
+		if backupCmdFlags.OutFile == "" {
+			backupCmdFlags.OutFile = fmt.Sprintf("%s-%s.tar.gz", backupCmdFlags.Target, time.Now().Format("20060102-150405"))
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := filepath.Join(backupCmdFlags.ProjectsPath, backupCmdFlags.Target)
+
+		summary, err := backup.Backup(dir, backupCmdFlags.OutFile)
+		if err != nil {
+			return err
+		}
+
+		log.Info("backed up project", "target", summary.Manifest.Target, "file", backupCmdFlags.OutFile,
+			"files", summary.Files, "bytes", summary.Bytes, "schema-version", summary.Manifest.SchemaVersion)
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore a project directory from an archive",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# db restore
+
+Restore a project directory from an archive made by 'db backup'.
+
+The project is restored into <projects-path>/<target>, with the target name
+taken from the archive. An existing project is not replaced, unless --force
+is given. The archive is extracted next to the project first, so that an
+existing project is only replaced once the archive was restored in full. The
+screenshot and logo paths recorded by the project's scan sessions are updated
+to the restored location, and the database is migrated to the latest schema.`)),
+	Example: ascii.Markdown(`
+- gowitness db restore --in almbrand.tar.gz
+- gowitness db restore --in almbrand.tar.gz --projects-path /data/targets --force`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if backupCmdFlags.InFile == "" {
+			return errors.New("an archive must be specified with --in")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		summary, err := backup.Restore(backupCmdFlags.InFile, backupCmdFlags.ProjectsPath, backupCmdFlags.Force)
+		if err != nil {
+			return err
+		}
+
+		log.Info("restored project", "target", summary.Manifest.Target,
+			"dir", filepath.Join(backupCmdFlags.ProjectsPath, summary.Manifest.Target),
+			"files", summary.Files, "bytes", summary.Bytes, "backed-up-at", summary.Manifest.CreatedAt.Format(time.RFC3339))
+		return nil
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(backupCmd, restoreCmd)
+
+	backupCmd.Flags().StringVar(&backupCmdFlags.Target, "target", "", "The name of the project to back up, as given to scan init")
+	backupCmd.Flags().StringVar(&backupCmdFlags.OutFile, "out", "", "The archive to write, ending in .tar, .tar.gz, .tgz or .tar.zst (default: <target>-<time>.tar.gz)")
+	backupCmd.Flags().StringVar(&backupCmdFlags.ProjectsPath, "projects-path", "targets", "The directory scan init creates projects in")
+
+	restoreCmd.Flags().StringVar(&backupCmdFlags.InFile, "in", "", "The archive to restore")
+	restoreCmd.Flags().StringVar(&backupCmdFlags.ProjectsPath, "projects-path", "targets", "The directory to restore the project into")
+	restoreCmd.Flags().BoolVar(&backupCmdFlags.Force, "force", false, "Replace the project if it already exists")
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/discovery"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// discoverTargetsFile runs host discovery against the IP addresses and
+// ranges of a targets file, and writes the ones that answered to a new
+// file, along with the targets that are not addresses, returning its path.
+// The outcome for every address is saved to the database, if db is not nil.
+func discoverTargetsFile(ctx context.Context, db *gorm.DB, command string, path string, opts discovery.Options, sessionID *uint) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read targets: %w", err)
+	}
+
+	var targets []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			targets = append(targets, line)
+		}
+	}
+
+	// targets that are not addresses are kept, as they can not be probed
+	ips, keep, err := discovery.Expand(targets)
+	if err != nil {
+		return "", fmt.Errorf("failed to expand targets: %w", err)
+	}
+
+	log.Info("discovering live hosts", "addresses", len(ips), "ports", opts.Ports, "icmp", opts.ICMP)
+
+	progress := startScanProgress("host discovery", len(ips))
+	var live, dead int
+	discovery.Discover(ctx, ips, opts, func(host discovery.Host) {
+		if host.Alive {
+			keep = append(keep, host.IP)
+			live++
+			progress.Increment("alive")
+		} else {
+			dead++
+			progress.Increment("dead")
+		}
+
+		if db != nil {
+			if err := saveHostStatus(db, host, sessionID); err != nil {
+				log.Warn("failed to save host status", "ip", host.IP, "err", err)
+			}
+		}
+	})
+	stopScanProgress(progress)
+
+	log.Info("host discovery finished", "alive", live, "dead", dead)
+
+	discovered := fmt.Sprintf("gowitness-%s-alive-%d.txt", command, time.Now().Unix())
+	if err := os.WriteFile(discovered, []byte(strings.Join(keep, "\n")+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write live targets: %w", err)
+	}
+
+	return discovered, nil
+}
+
+// saveHostStatus records the outcome of host discovery for an address,
+// replacing an earlier one
+func saveHostStatus(db *gorm.DB, host discovery.Host, sessionID *uint) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "ip_address"}},
+		DoUpdates: clause.AssignmentColumns([]string{"alive", "method", "checked_at", "scan_session_id"}),
+	}).Create(&models.HostStatus{
+		IPAddress:     host.IP,
+		Alive:         host.Alive,
+		Method:        host.Method,
+		CheckedAt:     time.Now(),
+		ScanSessionID: sessionID,
+	}).Error
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"gorm.io/gorm"
+)
+
+// scanCheckpoint records where an interrupted scan stopped, so that it can
+// be picked up again with --resume
+type scanCheckpoint struct {
+	Command       string   `json:"command"`
+	ScanSessionID uint     `json:"scan_session_id,omitempty"`
+	File          string   `json:"file,omitempty"`       // file the targets were read from
+	Remaining     []string `json:"remaining,omitempty"`  // targets that were not processed yet
+	Unverified    []string `json:"unverified,omitempty"` // remaining targets not known to be responsive
+	// Resolved are the IPs in scope domains resolved to, by domain, which
+	// a scope of only domains allows
+	Resolved    map[string][]string `json:"resolved,omitempty"`
+	Interrupted time.Time           `json:"interrupted"`
+}
+
+// interruptContext returns a context that is cancelled on SIGINT or
+// SIGTERM. Once a signal arrives the default handling is restored, so a
+// second one exits right away instead of waiting for the flush.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return ctx, stop
+}
+
+// checkpointPath returns the path of the checkpoint file of a command
+func checkpointPath(command string) string {
+	return fmt.Sprintf("gowitness-%s.checkpoint.json", command)
+}
+
+// writeCheckpoint writes the checkpoint of an interrupted scan
+func writeCheckpoint(checkpoint *scanCheckpoint) error {
+	checkpoint.Interrupted = time.Now()
+
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	if err := os.WriteFile(checkpointPath(checkpoint.Command), data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// readCheckpoint reads the checkpoint a command left when it was interrupted
+func readCheckpoint(command string) (*scanCheckpoint, error) {
+	data, err := os.ReadFile(checkpointPath(command))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no checkpoint to resume from, %s does not exist", checkpointPath(command))
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var checkpoint scanCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+
+	return &checkpoint, nil
+}
+
+// removeCheckpoint removes the checkpoint of a command, once the scan it
+// was left by has finished
+func removeCheckpoint(command string) {
+	if err := os.Remove(checkpointPath(command)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn("failed to remove checkpoint", "file", checkpointPath(command), "err", err)
+	}
+}
+
+// interruptScan records that a scan was interrupted after its pending
+// results were flushed. It writes the checkpoint to resume from and marks
+// the scan session, if any, as cancelled in phase.
+func interruptScan(db *gorm.DB, phase string, checkpoint *scanCheckpoint) {
+	if err := writeCheckpoint(checkpoint); err != nil {
+		log.Error("failed to write resume checkpoint", "err", err)
+	} else {
+		log.Warn("scan interrupted, resume it with --resume",
+			"checkpoint", checkpointPath(checkpoint.Command), "remaining", len(checkpoint.Remaining))
+	}
+
+	if db == nil || checkpoint.ScanSessionID == 0 {
+		return
+	}
+
+	if err := database.CancelSession(db, checkpoint.ScanSessionID, phase); err != nil {
+		log.Warn("could not update scan session status", "session-id", checkpoint.ScanSessionID, "err", err)
+		return
+	}
+	log.Info("marked scan session as cancelled", "session-id", checkpoint.ScanSessionID, "phase", phase)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/log"
+)
+
+// startScanProgress starts a progress display of a scan of total items on
+// stderr, writing logs through it until it is stopped. It is hidden for JSON
+// logs and --quiet, where it would only get in the way.
+func startScanProgress(title string, total int) *ascii.Progress {
+	var out *os.File
+	if opts.Logging.Format != log.FormatJSON && !opts.Logging.Silence {
+		out = os.Stderr
+	}
+
+	progress := ascii.NewProgress(out, title, total)
+	log.SetOutput(progress)
+
+	return progress
+}
+
+// stopScanProgress removes a progress display, writing logs to stderr again
+func stopScanProgress(progress *ascii.Progress) {
+	progress.Stop()
+	log.SetOutput(os.Stderr)
+}
+
+// printScanSummary prints the counters of a finished scan as a table. With
+// JSON logs they are logged instead, so that CI can parse them. keyvals
+// alternates names and values.
+func printScanSummary(title string, keyvals ...any) {
+	if opts.Logging.Format == log.FormatJSON {
+		log.Info(title, keyvals...)
+		return
+	}
+	if opts.Logging.Silence {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, ascii.SummaryTable(title, keyvals...))
+}
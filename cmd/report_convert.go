@@ -87,6 +87,10 @@ target.`)),
 				log.Error("failed to convert to SQLite", "err", err)
 				return
 			}
+			if err := backfillDatabase(fmt.Sprintf("sqlite://%s", convertCmdFlags.toFile)); err != nil {
+				log.Error("failed to backfill the database", "err", err)
+				return
+			}
 		} else if convertCmdFlags.toExt == ".jsonl" {
 			toFile, err := islazy.CreateFileWithDir(convertCmdFlags.toFile)
 			if err != nil {
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/export"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"github.com/spf13/cobra"
+)
+
+var findingsCmdFlags = struct {
+	DbURI         string
+	Format        string
+	OutFile       string
+	ScanSessionID uint
+	MinSeverity   string
+}{}
+var findingsCmd = &cobra.Command{
+	Use:   "findings",
+	Short: "Export findings for vulnerability management platforms",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# report findings
+
+Export findings for vulnerability management platforms.
+
+Findings are exported with the URL, host and IP address they were found on,
+in a format a vulnerability management platform imports:
+
+- **defectdojo** is a DefectDojo "Generic Findings Import" JSON report. Each
+  finding has a unique_id_from_tool that stays the same across scans, so
+  reimporting deduplicates them.
+- **faraday** is a Faraday XML report of hosts, with their open ports as
+  services, and the findings as vulnerabilities of the host or, for findings
+  on a URL, web vulnerabilities of its service.`)),
+	Example: ascii.Markdown(`
+- gowitness report findings --format defectdojo --out-file defectdojo.json
+- gowitness report findings --format faraday --scan-session-id 2 --out-file faraday.xml
+- gowitness report findings --format defectdojo --min-severity medium`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if findingsCmdFlags.Format != "defectdojo" && findingsCmdFlags.Format != "faraday" {
+			return errors.New("format must be defectdojo or faraday")
+		}
+		if findingsCmdFlags.MinSeverity != "" && !slices.Contains(plugins.Severities, findingsCmdFlags.MinSeverity) {
+			return errors.New("min-severity must be info, low, medium, high or critical")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := database.Connection(findingsCmdFlags.DbURI, true, false)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		var scanSessionID *uint
+		if findingsCmdFlags.ScanSessionID > 0 {
+			scanSessionID = &findingsCmdFlags.ScanSessionID
+		}
+
+		findings, err := export.Findings(conn, scanSessionID)
+		if err != nil {
+			return err
+		}
+		findings = export.FilterSeverity(findings, findingsCmdFlags.MinSeverity)
+
+		var out io.Writer = os.Stdout
+		if findingsCmdFlags.OutFile != "" {
+			file, err := os.Create(findingsCmdFlags.OutFile)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			out = file
+		}
+
+		if findingsCmdFlags.Format == "faraday" {
+			ips, err := export.IPs(conn, scanSessionID)
+			if err != nil {
+				return err
+			}
+			err = export.WriteFaraday(out, ips, findings)
+		} else {
+			err = export.WriteDefectDojo(out, findings)
+		}
+		if err != nil {
+			return err
+		}
+
+		if findingsCmdFlags.OutFile != "" {
+			log.Info("exported findings", "format", findingsCmdFlags.Format, "findings", len(findings), "file", findingsCmdFlags.OutFile)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	reportCmd.AddCommand(findingsCmd)
+
+	findingsCmd.Flags().StringVar(&findingsCmdFlags.DbURI, "db-uri", "sqlite://gowitness.sqlite3", "The location of a gowitness database")
+	findingsCmd.Flags().StringVar(&findingsCmdFlags.Format, "format", "defectdojo", "The export format. Valid formats are: defectdojo, faraday")
+	findingsCmd.Flags().StringVar(&findingsCmdFlags.OutFile, "out-file", "", "The file to write the export to. Defaults to stdout")
+	findingsCmd.Flags().UintVar(&findingsCmdFlags.ScanSessionID, "scan-session-id", 0, "Only export findings from this scan session")
+	findingsCmd.Flags().StringVar(&findingsCmdFlags.MinSeverity, "min-severity", "", "Only export findings at least this severe")
+}
@@ -5,18 +5,25 @@ import (
 	"bufio"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"html/template"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/sensepost/gowitness/internal/ascii"
 	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/assets"
 	"github.com/sensepost/gowitness/pkg/database"
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/sections"
+	"github.com/sensepost/gowitness/pkg/tlsaudit"
+	"github.com/sensepost/gowitness/pkg/whois"
 	"github.com/sensepost/gowitness/web/templates"
 	"github.com/spf13/cobra"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
@@ -25,6 +32,10 @@ var generateCmdFlags = struct {
 	ScreenshotPath string
 	DbURI          string
 	JsonFile       string
+	ScanSessionID  uint
+	TLSDays        int
+	DomainDays     int
+	SectionsDir    string
 
 	// temp working dir
 	TempDir string
@@ -43,13 +54,40 @@ The data source can be a JSON Lines file, or a database URI (ie.
 sqlite://yourdatabase.sqlite3).
 
 The output file is a zip archive with an index.html file containing the report.
+The report ends with a TLS section, listing certificates that expire within
+--tls-days or already expired, self-signed certificates, and hosts that
+negotiated a deprecated protocol version or a weak cipher.
+
+Reports generated from a database also list the apex domains whose
+registration expires within --domain-days, as looked up by 'scan whois'.
+
+Custom sections are added to the end of the report from the templates in
+--sections-dir, which defaults to a report-templates directory next to a
+SQLite database. Files ending in .html or .tmpl are Go HTML templates, and
+files ending in .md are Go templates rendered as markdown, with the values
+they print escaped and raw HTML left out. Sections are
+ordered and titled by their file names, so 10-executive-summary.md comes
+before 20-scope.md, titled "Executive summary". Templates have the scan
+session (.Session), counts (.Stats), results (.Results, with the
+**screenshot** function giving the path of their screenshot), findings
+(.Findings), and the TLS (.TLS) and domain (.Domains) issues.
+
+Results show the assets imported with 'scan import' that their hosts are, and
+the owner, environment and criticality of each is in their .Asset.
 `)),
+	Example: ascii.Markdown(`
+- gowitness report generate
+- gowitness report generate --scan-session-id 2 --sections-dir ./acme/report-templates`),
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		var err error
 		if generateCmdFlags.DbURI == "" && generateCmdFlags.JsonFile == "" {
 			return errors.New("no data source defined")
 		}
 
+		if generateCmdFlags.SectionsDir == "" && generateCmdFlags.JsonFile == "" {
+			generateCmdFlags.SectionsDir = sections.FindProjectDir(generateCmdFlags.DbURI)
+		}
+
 		generateCmdFlags.TempDir, err = os.MkdirTemp("", "gowitness3-report-*")
 		if err != nil {
 			return err
@@ -87,14 +125,17 @@ The output file is a zip archive with an index.html file containing the report.
 					log.Error("could not unmarshal JSON line", "err", err)
 					continue
 				}
-				results = append(results, result)
+				if generateCmdFlags.ScanSessionID == 0 ||
+					(result.ScanSessionID != nil && *result.ScanSessionID == generateCmdFlags.ScanSessionID) {
+					results = append(results, result)
+				}
 
 				if err == io.EOF {
 					break
 				}
 			}
 
-			if err := generateHTML(results); err != nil {
+			if err := generateHTML(results, nil, nil); err != nil {
 				log.Fatal("an error occurred generating the html report", "err", err)
 			}
 
@@ -107,11 +148,34 @@ The output file is a zip archive with an index.html file containing the report.
 			log.Fatal("could not connect to database", "err", err)
 		}
 
-		if err := conn.Model(&models.Result{}).Preload(clause.Associations).Find(&results).Error; err != nil {
+		query := conn.Model(&models.Result{}).Preload(clause.Associations)
+		if generateCmdFlags.ScanSessionID > 0 {
+			query = query.Where("scan_session_id = ?", generateCmdFlags.ScanSessionID)
+		}
+
+		if err := query.Find(&results).Error; err != nil {
 			log.Fatal("could not get list", "err", err)
 		}
 
-		if err := generateHTML(results); err != nil {
+		registrations := []models.DomainRegistration{}
+		registrationQuery := conn.Model(&models.DomainRegistration{})
+		if generateCmdFlags.ScanSessionID > 0 {
+			registrationQuery = registrationQuery.Where("scan_session_id = ?", generateCmdFlags.ScanSessionID)
+		}
+		if err := registrationQuery.Find(&registrations).Error; err != nil {
+			log.Fatal("could not get domain registrations", "err", err)
+		}
+
+		// results are reported with the business context of their hosts
+		inventory, err := assets.Load(conn)
+		if err != nil {
+			log.Fatal("could not get assets", "err", err)
+		}
+		for i := range results {
+			results[i].Asset = inventory.Match(results[i].URL, results[i].IPAddress)
+		}
+
+		if err := generateHTML(results, registrations, conn); err != nil {
 			log.Fatal("an error occurred generating the html report", "err", err)
 		}
 	},
@@ -123,6 +187,10 @@ func init() {
 	generateCmd.Flags().StringVar(&generateCmdFlags.ScreenshotPath, "screenshot-path", "./screenshots", "The path where screenshots are stored")
 	generateCmd.Flags().StringVar(&generateCmdFlags.DbURI, "db-uri", "sqlite://gowitness.sqlite3", "The location of a gowitness database")
 	generateCmd.Flags().StringVar(&generateCmdFlags.JsonFile, "json-file", "", "The location of a JSON Lines results file (e.g., ./gowitness.jsonl). This flag takes precedence over --db-uri")
+	generateCmd.Flags().UintVar(&generateCmdFlags.ScanSessionID, "scan-session-id", 0, "Only report on results from this scan session ID")
+	generateCmd.Flags().IntVar(&generateCmdFlags.TLSDays, "tls-days", tlsaudit.DefaultDays, "Days ahead to list expiring certificates for in the TLS section")
+	generateCmd.Flags().IntVar(&generateCmdFlags.DomainDays, "domain-days", whois.DefaultDays, "Days ahead to list expiring domain registrations for in the domains section")
+	generateCmd.Flags().StringVar(&generateCmdFlags.SectionsDir, "sections-dir", "", "A directory of templates to render as custom report sections. Defaults to the report-templates directory next to a SQLite database")
 	generateCmd.Flags().StringVar(&generateCmdFlags.ReportFile, "zip-name", "gowitness-report.zip", "The name and location of the final report ZIP file that will be generated")
 }
 
@@ -142,8 +210,10 @@ func statusClass(responseCode int) string {
 	}
 }
 
-// generateHTML generates an HTML report from results
-func generateHTML(results []models.Result) error {
+// generateHTML generates an HTML report from results, and the domain
+// registrations of their targets if the data source has them. Custom
+// sections are rendered with the findings and counts of conn, if it is set.
+func generateHTML(results []models.Result, registrations []models.DomainRegistration, conn *gorm.DB) error {
 	log.Info("generating HTML report for results", "count", len(results))
 
 	tmplContent, err := templates.ReportTemplate.ReadFile("static-report.tmpl")
@@ -167,8 +237,24 @@ func generateHTML(results []models.Result) error {
 	}
 	defer file.Close()
 
+	tlsReport := tlsaudit.Audit(results, generateCmdFlags.TLSDays, time.Now())
+	expiring := whois.Expiring(registrations, generateCmdFlags.DomainDays, time.Now())
+
+	customSections, err := renderSections(results, tlsReport, expiring, conn)
+	if err != nil {
+		return err
+	}
+
 	err = tmpl.Execute(file, map[string]interface{}{
 		"Results": results,
+		"TLS":     tlsReport,
+		"TLSDays": generateCmdFlags.TLSDays,
+
+		"Domains":          expiring,
+		"DomainDays":       generateCmdFlags.DomainDays,
+		"HasRegistrations": registrations != nil,
+
+		"Sections": customSections,
 	})
 	if err != nil {
 		return err
@@ -201,6 +287,33 @@ func generateHTML(results []models.Result) error {
 	return nil
 }
 
+// renderSections renders the custom report sections in --sections-dir, if
+// it is set
+func renderSections(results []models.Result, tlsReport *tlsaudit.Report, expiring []whois.Expiry, conn *gorm.DB) ([]sections.Rendered, error) {
+	if generateCmdFlags.SectionsDir == "" {
+		return nil, nil
+	}
+
+	loaded, err := sections.Load(generateCmdFlags.SectionsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load report sections: %w", err)
+	}
+	if len(loaded) == 0 {
+		return nil, nil
+	}
+
+	data := sections.NewData(results)
+	data.TLS, data.Domains = tlsReport, expiring
+	if conn != nil {
+		if err := data.Load(conn, generateCmdFlags.ScanSessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("rendering custom report sections", "dir", generateCmdFlags.SectionsDir, "count", len(loaded))
+	return sections.RenderAll(loaded, data)
+}
+
 // copyCSS extracts the embedded pico.min.css file and writes it to the temp dir
 func copyCSS() string {
 	cssContent, err := templates.ReportTemplate.ReadFile("pico.min.css")
@@ -9,6 +9,7 @@ import (
 
 	"github.com/glebarez/sqlite"
 	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/models"
 	"github.com/spf13/cobra"
@@ -129,6 +130,11 @@ their data.`)),
 			}
 		}
 
+		if err := database.Backfill(destDB); err != nil {
+			log.Error("failed to backfill the merged database", "err", err)
+			return
+		}
+
 		log.Info("data merge completed successfully.")
 	},
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/glebarez/sqlite"
 	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/models"
 	"github.com/sensepost/gowitness/pkg/models/oldv2"
@@ -83,6 +84,11 @@ and as a result will remain empty.`)),
 			}
 		}
 
+		if err := backfillDatabase(fmt.Sprintf("sqlite://%s", targetFile)); err != nil {
+			log.Error("failed to backfill the database", "err", err)
+			return
+		}
+
 		log.Info("database migrated")
 	},
 }
@@ -93,6 +99,17 @@ func init() {
 	migrateCmd.Flags().StringVarP(&migrateCmdFlags.Source, "source", "s", "", "A gowitness v2 SQLite database file to migrate to v3")
 }
 
+// backfillDatabase fills in the values that results from older builds
+// lack, once they are imported into a database
+func backfillDatabase(uri string) error {
+	conn, err := database.Connection(uri, true, false)
+	if err != nil {
+		return err
+	}
+
+	return database.Backfill(conn)
+}
+
 // isV2Database checks if the 'urls' table exists in the source database to verify it's a v2 database
 func isV2Database(db *gorm.DB) bool {
 	var count int64
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/export"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var stixCmdFlags = struct {
+	DbURI         string
+	OutFile       string
+	ScanSessionID uint
+	TAXIIURL      string
+	TAXIIUser     string
+}{}
+var stixCmd = &cobra.Command{
+	Use:   "stix",
+	Short: "Export domains, IPs, ports and certificates as STIX observables",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# report stix
+
+Export domains, IPs, ports and certificates as STIX observables.
+
+Writes a STIX 2.1 bundle of cyber-observables, for correlation in threat-intel
+platforms such as OpenCTI, which imports bundles as files:
+
+- **domain-name** for every known domain, with a resolves-to relationship to
+  the IP addresses it was seen on
+- **ipv4-addr** and **ipv6-addr** for every IP address
+- **network-traffic** for every open port, to its IP address
+- **x509-certificate** for every certificate, related to the domains it was
+  served for
+
+Observables have deterministic ids, so exporting the same data again merges
+with what the platform has rather than duplicating it.
+
+With --taxii-url, the bundle is pushed to a TAXII 2.1 collection instead of
+written out. The URL is that of the collection, e.g.
+https://taxii.example.com/api1/collections/{id}/. The server is
+authenticated with --taxii-user and the TAXII_PASSWORD environment variable.`)),
+	Example: ascii.Markdown(`
+- gowitness report stix --out-file gowitness.stix.json
+- gowitness report stix --scan-session-id 2 --out-file acme.stix.json
+- gowitness report stix --taxii-url https://taxii.example.com/api1/collections/9cfa669c-ee94-4ece-afd2-f8edac37d8fd/ --taxii-user gowitness`),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		conn, err := database.Connection(stixCmdFlags.DbURI, true, false)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		var scanSessionID *uint
+		if stixCmdFlags.ScanSessionID > 0 {
+			scanSessionID = &stixCmdFlags.ScanSessionID
+		}
+
+		bundle, err := export.STIX(conn, scanSessionID)
+		if err != nil {
+			return err
+		}
+
+		if stixCmdFlags.TAXIIURL != "" {
+			_ = godotenv.Load()
+			accepted, err := export.PushTAXII(stixCmdFlags.TAXIIURL, stixCmdFlags.TAXIIUser, os.Getenv("TAXII_PASSWORD"), bundle)
+			if err != nil {
+				return err
+			}
+
+			log.Info("pushed STIX objects to TAXII collection", "objects", len(bundle.Objects), "accepted", accepted)
+			return nil
+		}
+
+		var out io.Writer = os.Stdout
+		if stixCmdFlags.OutFile != "" {
+			file, err := os.Create(stixCmdFlags.OutFile)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			out = file
+		}
+
+		if err := export.WriteSTIX(out, bundle); err != nil {
+			return err
+		}
+
+		if stixCmdFlags.OutFile != "" {
+			log.Info("exported STIX bundle", "objects", len(bundle.Objects), "file", stixCmdFlags.OutFile)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	reportCmd.AddCommand(stixCmd)
+
+	stixCmd.Flags().StringVar(&stixCmdFlags.DbURI, "db-uri", "sqlite://gowitness.sqlite3", "The location of a gowitness database")
+	stixCmd.Flags().StringVar(&stixCmdFlags.OutFile, "out-file", "", "The file to write the bundle to. Defaults to stdout")
+	stixCmd.Flags().UintVar(&stixCmdFlags.ScanSessionID, "scan-session-id", 0, "Only export data from this scan session")
+	stixCmd.Flags().StringVar(&stixCmdFlags.TAXIIURL, "taxii-url", "", "A TAXII 2.1 collection to push the bundle to, instead of writing it out")
+	stixCmd.Flags().StringVar(&stixCmdFlags.TAXIIUser, "taxii-user", "", "The user to authenticate to the TAXII server as (password from TAXII_PASSWORD)")
+}
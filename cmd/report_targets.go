@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/export"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var targetsCmdFlags = struct {
+	DbURI         string
+	Kind          string
+	OutFile       string
+	ScanSessionID uint
+	Tag           string
+	Status        string
+	IncludeFailed bool
+}{}
+var targetsCmd = &cobra.Command{
+	Use:   "targets",
+	Short: "Export target lists for other tools",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# report targets
+
+Export target lists for other tools.
+
+Writes one target per line, ready to be fed to the next tool in a recon
+toolchain. The --kind flag selects the list:
+
+- **urls** are live URLs, for tools such as nuclei and ffuf
+- **ip-ports** are ip:port pairs of open ports, for tools such as nmap
+- **hostnames** are hostnames, for tools such as amass
+
+Lists can be limited to a scan session, to IP addresses with an IP information
+tag (for example 'cloud'), and, for URLs, to response codes. URLs that failed
+to load are left out unless --include-failed is set.`)),
+	Example: ascii.Markdown(`
+- gowitness report targets --kind urls --status 2xx,3xx | nuclei
+- gowitness report targets --kind ip-ports --scan-session-id 2 --out-file ports.txt
+- gowitness report targets --kind hostnames --out-file hosts.txt
+- gowitness report targets --kind urls --tag cloud --status 200,401,403`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !slices.Contains(export.TargetKinds, targetsCmdFlags.Kind) {
+			return errors.New("kind must be urls, ip-ports or hostnames")
+		}
+		if targetsCmdFlags.Status != "" && targetsCmdFlags.Kind != export.TargetURLs {
+			return errors.New("--status can only be used with --kind urls")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options := export.TargetOptions{
+			Kind:          targetsCmdFlags.Kind,
+			Tag:           targetsCmdFlags.Tag,
+			IncludeFailed: targetsCmdFlags.IncludeFailed,
+		}
+		if targetsCmdFlags.ScanSessionID > 0 {
+			options.ScanSessionID = &targetsCmdFlags.ScanSessionID
+		}
+		if targetsCmdFlags.Status != "" {
+			status, err := export.ParseStatus(targetsCmdFlags.Status)
+			if err != nil {
+				return err
+			}
+			options.Status = status
+		}
+
+		conn, err := database.Connection(targetsCmdFlags.DbURI, true, false)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		targets, err := export.Targets(conn, options)
+		if err != nil {
+			return err
+		}
+
+		var out io.Writer = os.Stdout
+		if targetsCmdFlags.OutFile != "" {
+			file, err := os.Create(targetsCmdFlags.OutFile)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			out = file
+		}
+
+		if err := export.WriteTargets(out, targets); err != nil {
+			return err
+		}
+
+		if targetsCmdFlags.OutFile != "" {
+			log.Info("exported targets", "kind", targetsCmdFlags.Kind, "targets", len(targets), "file", targetsCmdFlags.OutFile)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	reportCmd.AddCommand(targetsCmd)
+
+	targetsCmd.Flags().StringVar(&targetsCmdFlags.DbURI, "db-uri", "sqlite://gowitness.sqlite3", "The location of a gowitness database")
+	targetsCmd.Flags().StringVar(&targetsCmdFlags.Kind, "kind", export.TargetURLs, "The kind of target list. Valid kinds are: urls, ip-ports, hostnames")
+	targetsCmd.Flags().StringVar(&targetsCmdFlags.OutFile, "out-file", "", "The file to write the targets to. Defaults to stdout")
+	targetsCmd.Flags().UintVar(&targetsCmdFlags.ScanSessionID, "scan-session-id", 0, "Only export targets from this scan session")
+	targetsCmd.Flags().StringVar(&targetsCmdFlags.Tag, "tag", "", "Only export targets on IP addresses with this IP information tag")
+	targetsCmd.Flags().StringVar(&targetsCmdFlags.Status, "status", "", "Only export URLs with these response codes, comma separated (e.g. 200,3xx)")
+	targetsCmd.Flags().BoolVar(&targetsCmdFlags.IncludeFailed, "include-failed", false, "Include URLs that failed to load")
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"github.com/sensepost/gowitness/pkg/tickets"
+	"github.com/spf13/cobra"
+)
+
+var ticketsCmdFlags = struct {
+	DbURI          string
+	Provider       string
+	MinSeverity    string
+	ScanSessionID  uint
+	ScreenshotPath string
+	SkipFindings   bool
+	SkipServices   bool
+	DryRun         bool
+}{}
+var ticketsCmd = &cobra.Command{
+	Use:   "tickets",
+	Short: "Create tickets for severe findings and newly exposed services",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# report tickets
+
+Create tickets for severe findings and newly exposed services.
+
+One ticket is created for every finding, and every alert 'scan alerts' raised
+for a newly exposed service, that is at least --min-severity. The screenshot
+of the affected host is attached to its ticket. Every ticket created is
+recorded in the database, so running this again only creates tickets for
+what is new.
+
+JIRA is configured with these environment variables:
+
+- JIRA_URL, the address of the JIRA instance
+- JIRA_PROJECT, the key of the project to create issues in
+- JIRA_USER and JIRA_API_TOKEN, the email address and API token of a JIRA
+  Cloud user. JIRA Data Center takes a personal access token in
+  JIRA_API_TOKEN, without JIRA_USER.
+- JIRA_ISSUE_TYPE, the type of the issues, Bug by default
+- JIRA_NO_PRIORITY, set for projects without a priority field
+
+The report server creates tickets too, with POST /api/tickets.`)),
+	Example: ascii.Markdown(`
+- gowitness report tickets --provider jira --dry-run
+- gowitness report tickets --provider jira --scan-session-id 2
+- gowitness report tickets --provider jira --min-severity medium --skip-services`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !slices.Contains(tickets.Providers, ticketsCmdFlags.Provider) {
+			return errors.New("provider must be one of: " + strings.Join(tickets.Providers, ", "))
+		}
+		if !slices.Contains(plugins.Severities, ticketsCmdFlags.MinSeverity) {
+			return errors.New("min-severity must be one of: " + strings.Join(plugins.Severities, ", "))
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tracker, err := tickets.New(ticketsCmdFlags.Provider)
+		if err != nil {
+			return err
+		}
+
+		conn, err := database.Connection(ticketsCmdFlags.DbURI, false, false)
+		if err != nil {
+			return err
+		}
+
+		var scanSessionID *uint
+		if ticketsCmdFlags.ScanSessionID > 0 {
+			scanSessionID = &ticketsCmdFlags.ScanSessionID
+		}
+
+		summary, err := tickets.Run(slog.New(log.Logger), conn, tracker, tickets.Options{
+			MinSeverity:    ticketsCmdFlags.MinSeverity,
+			SkipFindings:   ticketsCmdFlags.SkipFindings,
+			SkipServices:   ticketsCmdFlags.SkipServices,
+			ScanSessionID:  scanSessionID,
+			ScreenshotPath: ticketsCmdFlags.ScreenshotPath,
+			DryRun:         ticketsCmdFlags.DryRun,
+		})
+		if err != nil {
+			return err
+		}
+
+		if ticketsCmdFlags.DryRun {
+			for _, ticket := range summary.Tickets {
+				log.Info("would create ticket", "summary", ticket.Summary)
+			}
+		}
+		log.Info("tickets created", "provider", tracker.Name(), "created", summary.Created,
+			"existing", summary.Existing, "failed", summary.Failed, "dry-run", ticketsCmdFlags.DryRun)
+
+		return nil
+	},
+}
+
+func init() {
+	reportCmd.AddCommand(ticketsCmd)
+
+	ticketsCmd.Flags().StringVar(&ticketsCmdFlags.DbURI, "db-uri", "sqlite://gowitness.sqlite3", "The location of a gowitness database")
+	ticketsCmd.Flags().StringVar(&ticketsCmdFlags.Provider, "provider", "jira", "The ticketing system to create tickets in. Valid providers are: "+strings.Join(tickets.Providers, ", "))
+	ticketsCmd.Flags().StringVar(&ticketsCmdFlags.MinSeverity, "min-severity", "high", "The least severe finding or alert to create a ticket for")
+	ticketsCmd.Flags().UintVar(&ticketsCmdFlags.ScanSessionID, "scan-session-id", 0, "Only create tickets for this scan session")
+	ticketsCmd.Flags().StringVar(&ticketsCmdFlags.ScreenshotPath, "screenshot-path", "./screenshots", "The path where screenshots are stored, for results without a scan session")
+	ticketsCmd.Flags().BoolVar(&ticketsCmdFlags.SkipFindings, "skip-findings", false, "Don't create tickets for findings")
+	ticketsCmd.Flags().BoolVar(&ticketsCmdFlags.SkipServices, "skip-services", false, "Don't create tickets for newly exposed services")
+	ticketsCmd.Flags().BoolVar(&ticketsCmdFlags.DryRun, "dry-run", false, "List the tickets that would be created without creating them")
+}
@@ -2,10 +2,16 @@ package cmd
 
 import (
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 
 	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/cloud"
+	"github.com/sensepost/gowitness/pkg/database"
 	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/metrics"
 	"github.com/sensepost/gowitness/pkg/runner"
 	"github.com/spf13/cobra"
 )
@@ -14,24 +20,93 @@ var (
 	opts = &runner.Options{}
 )
 
+var rootCmdOptions = struct {
+	Proxy         string
+	JSONLog       bool
+	MetricsListen string
+	NoAutoMigrate bool
+	DbKey         string
+}{}
+
 var rootCmd = &cobra.Command{
 	Use:   "gowitness",
 	Short: "A web screenshot and information gathering tool",
 	Long:  ascii.Logo(),
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// --json-log is a shorthand for --log-format json
+		if rootCmdOptions.JSONLog {
+			opts.Logging.Format = log.FormatJSON
+		}
+		if err := log.SetFormat(opts.Logging.Format); err != nil {
+			return err
+		}
+
+		// --debug-log and the deprecated per command --verbose flags are
+		// shorthands for --log-level debug
+		level := opts.Logging.Level
+		if verbose := cmd.Flags().Lookup("verbose"); opts.Logging.Debug || (verbose != nil && verbose.Changed) {
+			level = "debug"
+		}
+		if err := log.SetLevel(level); err != nil {
+			return err
+		}
+
 		if opts.Logging.Silence {
 			log.EnableSilence()
 		}
 
-		if opts.Logging.Debug && !opts.Logging.Silence {
-			log.EnableDebug()
-			log.Debug("debug logging enabled")
+		if err := islazy.SetProxy(rootCmdOptions.Proxy); err != nil {
+			return err
+		}
+
+		// cloud provider ranges updated by 'db cloud-ranges' replace the
+		// bundled ones
+		if err := cloud.LoadCache(); err != nil {
+			log.Warn("using the bundled cloud provider ranges", "err", err)
+		}
+
+		log.Debug("debug logging enabled")
+
+		database.AutoMigrate = !rootCmdOptions.NoAutoMigrate
+
+		// the environment keeps the key out of shell history and process
+		// listings
+		database.EncryptionKey = rootCmdOptions.DbKey
+		if database.EncryptionKey == "" {
+			database.EncryptionKey = os.Getenv("GOWITNESS_DB_KEY")
+		}
+
+		if rootCmdOptions.MetricsListen != "" {
+			if err := serveMetrics(rootCmdOptions.MetricsListen); err != nil {
+				return err
+			}
 		}
 
 		return nil
 	},
 }
 
+// serveMetrics serves Prometheus metrics on /metrics of addr in the
+// background, for as long as the command runs
+func serveMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("could not listen for metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	log.Info("serving metrics", "addr", listener.Addr().String(), "path", "/metrics")
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Error("metrics listener stopped", "err", err)
+		}
+	}()
+
+	return nil
+}
+
 func Execute() {
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 	rootCmd.SilenceErrors = true
@@ -59,6 +134,13 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().BoolVarP(&opts.Logging.Debug, "debug-log", "D", false, "Enable debug logging")
+	rootCmd.PersistentFlags().StringVar(&opts.Logging.Level, "log-level", "info", "Least severe level to log. Valid levels are: debug, info, warn, error")
+	rootCmd.PersistentFlags().StringVar(&opts.Logging.Format, "log-format", log.FormatText, "Format to write logs in. Valid formats are: text, json")
+	rootCmd.PersistentFlags().BoolVar(&rootCmdOptions.JSONLog, "json-log", false, "Write machine readable JSON logs, without progress bars or summary tables (shorthand for --log-format json)")
+	rootCmd.PersistentFlags().BoolVarP(&opts.Logging.Debug, "debug-log", "D", false, "Enable debug logging (shorthand for --log-level debug)")
 	rootCmd.PersistentFlags().BoolVarP(&opts.Logging.Silence, "quiet", "q", false, "Silence (almost all) logging")
+	rootCmd.PersistentFlags().StringVar(&rootCmdOptions.Proxy, "proxy", "", "An HTTP or SOCKS5 proxy for requests to APIs such as Shodan, IP-API and Clearbit, in the format proto://address:port. Defaults to the HTTP_PROXY and HTTPS_PROXY environment variables. Use --chrome-proxy for the browser")
+	rootCmd.PersistentFlags().StringVar(&rootCmdOptions.MetricsListen, "metrics-listen", "", "An address, e.g. 127.0.0.1:9171, to serve Prometheus metrics (probes, Shodan and fallback lookups) on while a command runs")
+	rootCmd.PersistentFlags().BoolVar(&rootCmdOptions.NoAutoMigrate, "no-auto-migrate", false, "Do not apply pending database schema migrations automatically, and refuse to use databases that have them. Apply them with 'db migrate up'")
+	rootCmd.PersistentFlags().StringVar(&rootCmdOptions.DbKey, "db-key", "", "A passphrase to encrypt sensitive database columns (response bodies, credential header and cookie values, secrets and finding descriptions) with. Defaults to the GOWITNESS_DB_KEY environment variable")
 }
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/alerts"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+var alertsCmdOptions = struct {
+	RulesFile     string
+	Webhook       string
+	Baseline      bool
+	ScanSessionID uint
+}{}
+
+var alertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "Raise alerts on newly exposed ports, subdomains and expiring domains",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan alerts
+
+Raise alerts on newly exposed ports, subdomains and expiring domains.
+
+Open ports, discovered subdomains and domain registrations are compared with what alerts were raised
+for before, and every rule that fires on something new raises an alert. Alerts
+are stored in the database, and posted to a webhook if one is configured with
+--webhook or the ALERT_WEBHOOK_URL environment variable. The webhook payload
+has a "text" summary, so Slack and Mattermost incoming webhooks accept it.
+
+Without --rules, alerts are raised for:
+
+- RDP or SMB exposed (3389, 445, 139) as high
+- any open port outside 80/443 as medium
+- new subdomains as info
+- apex domains whose registration expires within 30 days as high, using the
+  registrations 'scan whois' looked up
+
+A rules file is a JSON array of rules, e.g.:
+
+    [{"name": "databases", "kind": "port", "severity": "high", "ports": [3306, 5432]},
+     {"name": "new-subdomain", "kind": "subdomain", "severity": "info"},
+     {"name": "domain-expiry", "kind": "domain-expiry", "severity": "high", "days": 60}]
+
+Use --baseline on the first run to record what is exposed now without
+delivering alerts, so that only later changes are delivered. 'scan run' does
+this by itself for a project's first scan.`)),
+	Example: ascii.Markdown(`
+- gowitness scan alerts --write-db --baseline
+- gowitness scan alerts --write-db --webhook https://hooks.slack.com/services/...
+- gowitness scan alerts --write-db --rules rules.json --scan-session-id 2`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for alerts")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		var scanSessionID *uint
+		if alertsCmdOptions.ScanSessionID > 0 {
+			scanSessionID = &alertsCmdOptions.ScanSessionID
+		}
+
+		return evaluateAlerts(db, alertsCmdOptions.RulesFile, alertsCmdOptions.Webhook, scanSessionID, alertsCmdOptions.Baseline)
+	},
+}
+
+// evaluateAlerts evaluates alert rules, from rulesFile or the defaults, and
+// delivers new alerts to the webhook, or the one in ALERT_WEBHOOK_URL
+func evaluateAlerts(db *gorm.DB, rulesFile, webhook string, scanSessionID *uint, baseline bool) error {
+	rules := alerts.DefaultRules
+	if rulesFile != "" {
+		var err error
+		if rules, err = alerts.LoadRules(rulesFile); err != nil {
+			return err
+		}
+	}
+
+	if webhook == "" {
+		_ = godotenv.Load()
+		webhook = os.Getenv("ALERT_WEBHOOK_URL")
+	}
+
+	var notifiers []alerts.Notifier
+	if webhook != "" {
+		notifiers = append(notifiers, alerts.NewWebhook(webhook))
+	} else if !baseline {
+		log.Warn("no alert webhook configured, alerts will only be stored")
+	}
+
+	engine := alerts.NewEngine(slog.New(log.Logger), db, rules, notifiers...)
+	raised, err := engine.Evaluate(scanSessionID, baseline)
+	if err != nil {
+		return err
+	}
+
+	for _, alert := range raised {
+		log.Debug("alert raised", "rule", alert.Rule, "severity", alert.Severity, "subject", alert.Subject)
+	}
+	log.Info("alert rules evaluated", "rules", len(rules), "alerts", len(raised), "baseline", baseline)
+
+	return nil
+}
+
+// alertsBaselineNeeded checks if alerts were never evaluated for a
+// database that holds a single scan session, i.e. its first scan
+func alertsBaselineNeeded(db *gorm.DB) (bool, error) {
+	var alertCount, sessionCount int64
+	if err := db.Model(&models.Alert{}).Count(&alertCount).Error; err != nil {
+		return false, err
+	}
+	if err := db.Model(&models.ScanSession{}).Count(&sessionCount).Error; err != nil {
+		return false, err
+	}
+
+	return alertCount == 0 && sessionCount <= 1, nil
+}
+
+func init() {
+	scanCmd.AddCommand(alertsCmd)
+
+	alertsCmd.Flags().StringVar(&alertsCmdOptions.RulesFile, "rules", "", "JSON file with alert rules (uses the default rules if not set)")
+	alertsCmd.Flags().StringVar(&alertsCmdOptions.Webhook, "webhook", "", "URL to post alerts to (defaults to ALERT_WEBHOOK_URL)")
+	alertsCmd.Flags().BoolVar(&alertsCmdOptions.Baseline, "baseline", false, "Record current exposure without delivering alerts")
+	alertsCmd.Flags().UintVar(&alertsCmdOptions.ScanSessionID, "scan-session-id", 0, "Only evaluate ports, subdomains and domains of a specific scan session ID")
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/breach"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var breachesCmdOptions = struct {
+	Domains       []string
+	ScanSessionID uint
+}{}
+
+var breachesCmd = &cobra.Command{
+	Use:   "breaches",
+	Short: "Look up breached accounts of apex domains",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan breaches
+
+Look up breached accounts of apex domains.
+
+Breach data providers are asked how many accounts on each apex domain of a
+scan session appeared in public data breaches, and in which breaches. The
+counts are stored per domain and provider, and are shown with the target
+information of the statistics for reporting.
+
+Providers are enabled by setting their API keys in the environment or a .env
+file:
+
+- HIBP_API_KEY for HaveIBeenPwned. Domains must be verified for the key.
+- DEHASHED_API_KEY for DeHashed.`)),
+	Example: ascii.Markdown(`
+- gowitness scan breaches --write-db
+- gowitness scan breaches --write-db --scan-session-id 2
+- gowitness scan breaches --write-db -d example.com -d example.org`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for breach lookups")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		providers := breach.ProvidersFromEnv()
+		if len(providers) == 0 {
+			log.Warn("no breach data providers configured. set HIBP_API_KEY or DEHASHED_API_KEY")
+			return nil
+		}
+
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		domains, scanSessionID, err := breachDomains(db)
+		if err != nil {
+			return err
+		}
+
+		var accounts int
+		for _, domain := range domains {
+			for _, provider := range providers {
+				exposure, err := provider.Domain(domain)
+				if err != nil {
+					log.Warn("failed to look up breaches", "provider", provider.Name(), "domain", domain, "err", err)
+					continue
+				}
+
+				log.Info("breached accounts", "provider", provider.Name(), "domain", domain,
+					"accounts", exposure.Accounts, "breaches", len(exposure.Breaches))
+				accounts += exposure.Accounts
+
+				if err := saveBreachExposure(db, exposure, scanSessionID); err != nil {
+					log.Warn("failed to save breach exposure", "domain", domain, "err", err)
+				}
+			}
+		}
+
+		log.Info("breach lookups completed", "domains", len(domains), "accounts", accounts)
+		return nil
+	},
+}
+
+// breachDomains returns the domains to look up, either from -d/--domain or
+// the apex domains of the scan session, and the scan session they belong to
+func breachDomains(db *gorm.DB) ([]string, *uint, error) {
+	var scanSessionID *uint
+	if breachesCmdOptions.ScanSessionID > 0 {
+		scanSessionID = &breachesCmdOptions.ScanSessionID
+	}
+
+	var domains []string
+	for _, domain := range breachesCmdOptions.Domains {
+		domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" && !slices.Contains(domains, domain) {
+			domains = append(domains, domain)
+		}
+	}
+	if len(domains) > 0 {
+		return domains, scanSessionID, nil
+	}
+
+	session, err := database.ScopeSession(db, breachesCmdOptions.ScanSessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if session == nil {
+		return nil, nil, errors.New("no active scan session to take domains from. specify -d/--domain or --scan-session-id")
+	}
+
+	domains = session.ScopeDomains()
+	if len(domains) == 0 {
+		return nil, nil, fmt.Errorf("scan session %d has no domains", session.ID)
+	}
+	log.Info("looking up the apex domains of the scan session", "session-id", session.ID, "domains", strings.Join(domains, ","))
+
+	return domains, &session.ID, nil
+}
+
+// saveBreachExposure stores the exposure of a domain, replacing what the
+// same provider reported before
+func saveBreachExposure(db *gorm.DB, exposure *breach.Exposure, scanSessionID *uint) error {
+	record := &models.BreachExposure{
+		Domain:        exposure.Domain,
+		Source:        exposure.Source,
+		Accounts:      exposure.Accounts,
+		Breaches:      len(exposure.Breaches),
+		ScanSessionID: scanSessionID,
+		CheckedAt:     time.Now(),
+	}
+	if err := record.SetBreachNames(exposure.Breaches); err != nil {
+		return err
+	}
+
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "domain"}, {Name: "source"}},
+		DoUpdates: clause.AssignmentColumns([]string{"accounts", "breaches", "breach_names", "scan_session_id", "checked_at"}),
+	}).Create(record).Error
+}
+
+func init() {
+	scanCmd.AddCommand(breachesCmd)
+
+	breachesCmd.Flags().StringSliceVarP(&breachesCmdOptions.Domains, "domain", "d", []string{}, "Apex domain to look up. Defaults to the apex domains of the scan session. Supports multiple --domain flags")
+	breachesCmd.Flags().UintVar(&breachesCmdOptions.ScanSessionID, "scan-session-id", 0, "Scan session to look up the apex domains of. Defaults to the latest active session")
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/buckets"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// bucketsSource is the source of findings reported by scan buckets
+const bucketsSource = "buckets"
+
+var bucketsCmdOptions = struct {
+	ScanSessionID uint
+	Timeout       int
+}{}
+
+var bucketsCmd = &cobra.Command{
+	Use:   "buckets",
+	Short: "Detect publicly listable cloud storage buckets",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan buckets
+
+Detect publicly listable cloud storage buckets.
+
+The HTML and network logs of probed results are searched for references to
+AWS S3 buckets, Google Cloud Storage buckets and Azure blob containers. Every
+bucket that is found is then asked to list its contents, without credentials.
+
+Buckets that anyone can list are stored as high severity findings, linked to
+the results that reference them. Network log content is only searched if it
+was saved while probing, using --save-content.`)),
+	Example: ascii.Markdown(`
+- gowitness scan buckets --write-db
+- gowitness scan buckets --write-db --scan-session-id 2`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for bucket detection")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		checker := buckets.NewChecker(time.Duration(bucketsCmdOptions.Timeout) * time.Second)
+		return detectBuckets(db, checker)
+	},
+}
+
+// bucketReference is a bucket and the results that reference it
+type bucketReference struct {
+	bucket  buckets.Bucket
+	results []models.Result
+}
+
+// detectBuckets checks the buckets results reference for public listings,
+// saving listable buckets as findings
+func detectBuckets(db *gorm.DB, checker *buckets.Checker) error {
+	references, err := bucketReferences(db)
+	if err != nil {
+		return err
+	}
+	if len(references) == 0 {
+		log.Warn("no bucket references found")
+		return nil
+	}
+
+	log.Info("checking buckets", "buckets", len(references))
+
+	var exposed int
+	for _, reference := range references {
+		exposure, err := checker.Check(reference.bucket)
+		if err != nil {
+			log.Warn("failed to check bucket", "provider", reference.bucket.Provider,
+				"bucket", reference.bucket.Name, "err", err)
+			continue
+		}
+		if !exposure.Listable {
+			log.Debug("bucket is not listable", "provider", reference.bucket.Provider,
+				"bucket", reference.bucket.Name, "evidence", exposure.Evidence)
+			continue
+		}
+
+		exposed++
+		log.Warn("publicly listable bucket", "provider", reference.bucket.Provider,
+			"bucket", reference.bucket.Name, "objects", exposure.Objects)
+
+		for _, result := range reference.results {
+			if err := saveBucketFinding(db, reference.bucket, exposure, result); err != nil {
+				log.Warn("failed to save bucket finding", "bucket", reference.bucket.Name, "err", err)
+			}
+		}
+	}
+
+	log.Info("bucket detection completed", "buckets", len(references), "exposed", exposed)
+	return nil
+}
+
+// bucketReferences returns the buckets that the successful results of a
+// scan session, or of all sessions, reference
+func bucketReferences(db *gorm.DB) ([]*bucketReference, error) {
+	query := db.Model(&models.Result{}).Select("id", "url", "html", "scan_session_id").
+		Where("failed = ?", false).
+		Preload("Network", func(tx *gorm.DB) *gorm.DB {
+			return tx.Select("id", "result_id", "url", "content")
+		})
+	if bucketsCmdOptions.ScanSessionID > 0 {
+		query = query.Where("scan_session_id = ?", bucketsCmdOptions.ScanSessionID)
+	}
+
+	var references []*bucketReference
+	index := make(map[string]*bucketReference)
+
+	var batch []models.Result
+	if err := query.FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+		for _, result := range batch {
+			content := result.HTML
+			for _, entry := range result.Network {
+				content += "\n" + entry.URL + "\n" + string(entry.Content)
+			}
+
+			for _, bucket := range buckets.Find(content) {
+				reference, ok := index[bucket.Key()]
+				if !ok {
+					reference = &bucketReference{bucket: bucket}
+					index[bucket.Key()] = reference
+					references = append(references, reference)
+				}
+
+				// the html and network logs are not needed anymore
+				reference.results = append(reference.results, models.Result{
+					ID:            result.ID,
+					URL:           result.URL,
+					ScanSessionID: result.ScanSessionID,
+				})
+			}
+		}
+		return nil
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to get results: %w", err)
+	}
+
+	return references, nil
+}
+
+// saveBucketFinding stores a listable bucket as a finding of a result that
+// references it, unless it was reported for that result before
+func saveBucketFinding(db *gorm.DB, bucket buckets.Bucket, exposure *buckets.Exposure, result models.Result) error {
+	title := fmt.Sprintf("Publicly listable %s bucket %s", bucket.Provider, bucket.Name)
+
+	var count int64
+	if err := db.Model(&models.Finding{}).
+		Where("source = ? AND title = ? AND result_id = ?", bucketsSource, title, result.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return db.Create(&models.Finding{
+		ResultID: &result.ID,
+		Source:   bucketsSource,
+		Title:    title,
+		Severity: "high",
+		Description: fmt.Sprintf("%s references the %s bucket %s, which anyone can list: %s.",
+			result.URL, bucket.Provider, bucket.Name, exposure.Evidence),
+		ScanSessionID: result.ScanSessionID,
+	}).Error
+}
+
+func init() {
+	scanCmd.AddCommand(bucketsCmd)
+
+	bucketsCmd.Flags().UintVar(&bucketsCmdOptions.ScanSessionID, "scan-session-id", 0, "Only check buckets referenced by results of this scan session")
+	bucketsCmd.Flags().IntVar(&bucketsCmdOptions.Timeout, "probe-timeout", 10, "Number of seconds before a bucket listing request times out")
+}
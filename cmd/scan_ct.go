@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/passivedns"
+	"github.com/sensepost/gowitness/pkg/readers"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+var ctCmdOptions = struct {
+	Domains       []string
+	ProjectPath   string
+	OutputFile    string
+	Probe         bool
+	CurrentOnly   bool
+	ScanSessionID uint
+}{}
+
+var ctCmd = &cobra.Command{
+	Use:   "ct",
+	Short: "Discover hostnames of a domain from certificate transparency logs",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan ct
+
+Discover hostnames of a domain from certificate transparency logs.
+
+Several domains can be searched by repeating -d/--domain. Without it, the apex
+domains of the scan session (--scan-session-id, or the latest active session)
+are searched, which requires --write-db.
+
+Certificates logged for the domain are searched on crt.sh, and on Censys if
+CENSYS_API_ID and CENSYS_API_SECRET are set in the environment (or a .env
+file). The unique hostnames named in those certificates are collected, while
+wildcard names and names outside of the domain are dropped.
+
+New hostnames are appended to a domains file, either the domains.txt of a
+project directory (-p/--path) or any other file (-o/--output). Hostnames that
+are already in the file are not added again. With --probe, the new hostnames
+are probed directly as well. With --write-db, every hostname is also stored
+along with the source that found it.`)),
+	Example: ascii.Markdown(`
+- gowitness scan ct -d example.com -p targets/example/
+- gowitness scan ct -d example.com -o domains.txt --current-only
+- gowitness scan ct -d example.com --probe --write-db --scan-session-id 1
+- gowitness scan ct -p targets/example/ --write-db --write-db-uri sqlite://targets/example/example.sqlite3`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(ctCmdOptions.Domains) == 0 && !opts.Writer.Db {
+			return errors.New("a target domain must be specified with -d/--domain, or taken from a scan session with --write-db")
+		}
+
+		if ctCmdOptions.ProjectPath != "" && ctCmdOptions.OutputFile != "" {
+			return errors.New("only one of -p/--path and -o/--output may be specified")
+		}
+
+		if ctCmdOptions.ProjectPath == "" && ctCmdOptions.OutputFile == "" && !ctCmdOptions.Probe {
+			return errors.New("specify a domains file with -p/--path or -o/--output, and/or probe hostnames with --probe")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var conn *gorm.DB
+		if opts.Writer.Db {
+			var err error
+			conn, err = database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+		}
+
+		domains, err := ctDomains(conn)
+		if err != nil {
+			return err
+		}
+
+		var hostnames []string
+		seen := make(map[string]bool)
+		for _, domain := range domains {
+			for _, hostname := range discoverCertificateHostnames(conn, domain) {
+				if !seen[hostname] {
+					seen[hostname] = true
+					hostnames = append(hostnames, hostname)
+				}
+			}
+		}
+		if len(hostnames) == 0 {
+			log.Warn("no hostnames found in certificate transparency logs", "domains", strings.Join(domains, ","))
+			return nil
+		}
+
+		outputFile := ctCmdOptions.OutputFile
+		if ctCmdOptions.ProjectPath != "" {
+			outputFile = filepath.Join(ctCmdOptions.ProjectPath, "domains.txt")
+		}
+
+		added := hostnames
+		if outputFile != "" {
+			var err error
+			added, err = appendDomains(outputFile, hostnames)
+			if err != nil {
+				return err
+			}
+			log.Info("updated domains file", "file", outputFile, "added", len(added), "found", len(hostnames))
+		}
+
+		if !ctCmdOptions.Probe {
+			return nil
+		}
+		if len(added) == 0 {
+			log.Info("no new hostnames to probe")
+			return nil
+		}
+
+		return probeHostnames(added)
+	},
+}
+
+// ctDomains returns the domains to search, either from -d/--domain or the
+// apex domains of the scan session
+func ctDomains(conn *gorm.DB) ([]string, error) {
+	var domains []string
+	for _, domain := range ctCmdOptions.Domains {
+		domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" && !slices.Contains(domains, domain) {
+			domains = append(domains, domain)
+		}
+	}
+	if len(domains) > 0 {
+		return domains, nil
+	}
+
+	var session *models.ScanSession
+	if ctCmdOptions.ScanSessionID > 0 {
+		session = &models.ScanSession{}
+		if err := conn.Preload("ApexDomains").First(session, ctCmdOptions.ScanSessionID).Error; err != nil {
+			return nil, fmt.Errorf("failed to get scan session %d: %w", ctCmdOptions.ScanSessionID, err)
+		}
+	} else {
+		var err error
+		session, err = database.LatestActiveSession(conn)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			return nil, errors.New("no active scan session to take domains from. specify -d/--domain or --scan-session-id")
+		}
+		if err := conn.Model(session).Association("ApexDomains").Find(&session.ApexDomains); err != nil {
+			return nil, fmt.Errorf("failed to get apex domains of scan session %d: %w", session.ID, err)
+		}
+	}
+
+	domains = session.ScopeDomains()
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("scan session %d has no domains", session.ID)
+	}
+	log.Info("searching the apex domains of the scan session", "session-id", session.ID, "domains", strings.Join(domains, ","))
+
+	return domains, nil
+}
+
+// discoverCertificateHostnames queries the certificate transparency
+// providers for hostnames of a domain, saving them to the database if conn
+// is set
+func discoverCertificateHostnames(conn *gorm.DB, domain string) []string {
+	var scanSessionID *uint
+	if ctCmdOptions.ScanSessionID > 0 {
+		scanSessionID = &ctCmdOptions.ScanSessionID
+	}
+
+	var hostnames []string
+	seen := make(map[string]bool)
+
+	for _, provider := range passivedns.CertificateProvidersFromEnv() {
+		log.Info("searching certificate transparency logs", "provider", provider.Name(), "domain", domain)
+
+		subdomains, err := provider.Subdomains(domain)
+		if err != nil {
+			log.Warn("certificate transparency search failed", "provider", provider.Name(), "err", err)
+			continue
+		}
+
+		log.Info("certificate transparency search returned hostnames", "provider", provider.Name(), "count", len(subdomains))
+
+		for _, subdomain := range subdomains {
+			if ctCmdOptions.CurrentOnly && !subdomain.Current {
+				continue
+			}
+
+			if conn != nil {
+				if err := saveDiscoveredDomain(conn, subdomain, scanSessionID); err != nil {
+					log.Warn("failed to save discovered domain", "domain", subdomain.Hostname, "err", err)
+				}
+			}
+
+			if seen[subdomain.Hostname] {
+				continue
+			}
+			seen[subdomain.Hostname] = true
+			hostnames = append(hostnames, subdomain.Hostname)
+		}
+	}
+
+	return hostnames
+}
+
+// appendDomains appends the hostnames that are not in a domains file yet
+// to it, creating the file if needed. The added hostnames are returned.
+func appendDomains(path string, hostnames []string) ([]string, error) {
+	existing := make(map[string]bool)
+	if file, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			existing[strings.ToLower(strings.TrimSpace(scanner.Text()))] = true
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read domains file: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open domains file: %w", err)
+	}
+
+	var added []string
+	for _, hostname := range hostnames {
+		if !existing[hostname] {
+			added = append(added, hostname)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open domains file: %w", err)
+	}
+	defer file.Close()
+
+	// don't glue the first hostname onto a last line without a newline
+	var prefix string
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			prefix = "\n"
+		}
+	}
+
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		return nil, fmt.Errorf("failed to write domains file: %w", err)
+	}
+	if _, err := file.WriteString(prefix + strings.Join(added, "\n") + "\n"); err != nil {
+		return nil, fmt.Errorf("failed to write domains file: %w", err)
+	}
+
+	return added, nil
+}
+
+// probeHostnames probes hostnames over http and https with the scan runner
+func probeHostnames(hostnames []string) error {
+	tmp, err := os.CreateTemp("", "gowitness-ct-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary targets file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(strings.Join(hostnames, "\n") + "\n"); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary targets file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary targets file: %w", err)
+	}
+
+	log.Info("probing new hostnames", "count", len(hostnames))
+
+	reader := readers.NewFileReader(&readers.FileReaderOptions{
+		Source: tmp.Name(),
+		Ports:  []int{80, 443},
+	})
+	go func() {
+		if err := reader.Read(scanRunner.Targets); err != nil {
+			log.Error("error in reader.Read", "err", err)
+		}
+	}()
+
+	scanRunner.Run()
+	scanRunner.Close()
+
+	return nil
+}
+
+func init() {
+	scanCmd.AddCommand(ctCmd)
+
+	ctCmd.Flags().StringSliceVarP(&ctCmdOptions.Domains, "domain", "d", []string{}, "Target domain to search certificate transparency logs for. Repeat for several domains. Defaults to the scan session's apex domains")
+	ctCmd.Flags().StringVarP(&ctCmdOptions.ProjectPath, "path", "p", "", "Project directory whose domains.txt new hostnames are appended to")
+	ctCmd.Flags().StringVarP(&ctCmdOptions.OutputFile, "output", "o", "", "Domains file new hostnames are appended to")
+	ctCmd.Flags().BoolVar(&ctCmdOptions.Probe, "probe", false, "Probe new hostnames directly")
+	ctCmd.Flags().BoolVar(&ctCmdOptions.CurrentOnly, "current-only", false, "Only keep hostnames named in certificates that have not expired")
+	ctCmd.Flags().UintVar(&ctCmdOptions.ScanSessionID, "scan-session-id", 0, "Associate discovered domains with specific scan session ID")
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/vulndb"
+	"github.com/spf13/cobra"
+)
+
+var cveCmdOptions = struct {
+	ScanSessionID    uint
+	Feeds            []string
+	SkipTechnologies bool
+	SkipIPVulns      bool
+}{}
+
+var cveCmd = &cobra.Command{
+	Use:   "cve",
+	Short: "Correlate detected software versions and Shodan vulns with CVE details",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan cve
+
+Correlate detected software versions and Shodan vulns with CVE details.
+
+Technologies that were detected with a version, and so a versioned CPE name,
+are matched against the CVEs affecting that version. The CVE identifiers
+Shodan reported for IP addresses are looked up as well, to add their
+descriptions and CVSS scores.
+
+CVE details are fetched from the NVD API by default. Set NVD_API_KEY in the
+environment (or a .env file) to raise its rate limit. For offline use, pass
+NVD JSON 2.0 data feed files with --feed instead.
+
+Every affected result and IP address is stored as a finding, with the CVSS
+score and a severity derived from it. The report server lists them most
+severe first.`)),
+	Example: ascii.Markdown(`
+- gowitness scan cve --write-db
+- gowitness scan cve --write-db --scan-session-id 2
+- gowitness scan cve --write-db --feed nvdcve-2.0-2024.json.gz --feed nvdcve-2.0-2025.json.gz`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for cve correlation")
+		}
+
+		if cveCmdOptions.SkipTechnologies && cveCmdOptions.SkipIPVulns {
+			return errors.New("nothing to correlate with both --skip-technologies and --skip-ip-vulns")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		var source vulndb.Source
+		if len(cveCmdOptions.Feeds) > 0 {
+			feed, err := vulndb.LoadFeed(cveCmdOptions.Feeds...)
+			if err != nil {
+				return err
+			}
+			log.Info("loaded cve feeds", "files", len(cveCmdOptions.Feeds), "cves", feed.Len())
+			source = feed
+		} else {
+			// Try to load .env file (ignore errors as it may not exist)
+			_ = godotenv.Load()
+			apiKey := os.Getenv("NVD_API_KEY")
+			if apiKey == "" {
+				log.Warn("NVD_API_KEY is not set, so NVD requests are rate limited heavily")
+			}
+			source = vulndb.NewNVD(apiKey)
+		}
+
+		var scanSessionID *uint
+		if cveCmdOptions.ScanSessionID > 0 {
+			scanSessionID = &cveCmdOptions.ScanSessionID
+		}
+
+		correlator := vulndb.NewCorrelator(slog.New(log.Logger), db, source)
+
+		if !cveCmdOptions.SkipTechnologies {
+			log.Info("correlating technology versions", "source", source.Name())
+			saved, err := correlator.Technologies(scanSessionID)
+			if err != nil {
+				return err
+			}
+			log.Info("technology versions correlated", "findings", saved)
+		}
+
+		if !cveCmdOptions.SkipIPVulns {
+			log.Info("correlating shodan vulns", "source", source.Name())
+			saved, err := correlator.IPVulns(scanSessionID)
+			if err != nil {
+				return err
+			}
+			log.Info("shodan vulns correlated", "findings", saved)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	scanCmd.AddCommand(cveCmd)
+
+	cveCmd.Flags().UintVar(&cveCmdOptions.ScanSessionID, "scan-session-id", 0, "Only correlate results and IP addresses of this scan session")
+	cveCmd.Flags().StringSliceVar(&cveCmdOptions.Feeds, "feed", []string{}, "NVD JSON 2.0 data feed files to use instead of the NVD API (.json or .json.gz)")
+	cveCmd.Flags().BoolVar(&cveCmdOptions.SkipTechnologies, "skip-technologies", false, "Do not correlate detected technology versions")
+	cveCmd.Flags().BoolVar(&cveCmdOptions.SkipIPVulns, "skip-ip-vulns", false, "Do not correlate the vulns Shodan reported")
+}
@@ -4,16 +4,29 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"slices"
+	"strings"
 
 	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/dns"
 	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/passivedns"
 	"github.com/spf13/cobra"
+	"gorm.io/gorm"
 )
 
 var domainsCmdOptions = struct {
-	Domain     string
-	OutputFile string
-	Verbose    bool
+	Domain          string
+	OutputFile      string
+	Verbose         bool
+	Passive         bool
+	Engine          string
+	ScanSessionID   uint
+	FilterWildcards bool
+	Resolvers       string
 }{}
 
 var domainsCmd = &cobra.Command{
@@ -26,20 +39,39 @@ Discover domains and subdomains for a target domain using various techniques.
 
 This command takes a target domain and discovers subdomains using:
 
-1. **DNS enumeration** (placeholder - future implementation)
-2. **Certificate transparency logs** (placeholder - future implementation) 
-3. **Search engine dorking** (placeholder - future implementation)
-4. **Wordlist-based subdomain bruteforcing** (placeholder - future implementation)
+1. **Passive DNS providers** (--passive) such as SecurityTrails
+2. **External tools** (--engine) such as subfinder and amass, if installed
+3. **Certificate transparency logs** (see 'scan ct')
+4. **Search engine dorking** (placeholder - future implementation)
+5. **Wordlist-based subdomain bruteforcing** (placeholder - future implementation)
 
-For now, this command generates example subdomains for testing purposes.
+Passive DNS providers are enabled by setting their API key in the environment
+(or a .env file). Supported providers and their keys are:
+
+- SecurityTrails: SECURITYTRAILS_API_KEY
+
+With --engine, the subfinder or amass binary is run against the target and its
+output is parsed. Domains found by an engine are tagged with the engine's name
+as their source.
+
+Without --passive or --engine, this command generates example subdomains for testing purposes.
+
+Domains with a wildcard DNS record resolve any subdomain, which floods results
+with subdomains that do not really exist. With --filter-wildcards, random
+subdomains of each parent domain are resolved first, and discovered subdomains
+that resolve to the same answers are dropped.
 
 The discovered domains are written to a file that can be used with other
-gowitness commands like 'scan file' for screenshot collection.
+gowitness commands like 'scan file' for screenshot collection. With --write-db,
+each domain is also stored along with the source that found it.
 `)),
 	Example: ascii.Markdown(`
 - gowitness scan domains -d example.com -o domains.txt
-- gowitness scan domains -d target.com -o targets/company/domains.txt --verbose
-- gowitness scan domains -d example.org -o domains.txt --project myproject`),
+- gowitness scan domains -d target.com -o targets/company/domains.txt --log-level debug
+- gowitness scan domains -d example.org -o domains.txt --project myproject
+- gowitness scan domains -d example.com -o domains.txt --passive --write-db --scan-session-id 1
+- gowitness scan domains -d example.com -o domains.txt --engine subfinder --write-db
+- gowitness scan domains -d example.com -o domains.txt --passive --filter-wildcards --resolvers resolvers.txt`),
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		if domainsCmdOptions.Domain == "" {
 			return errors.New("a target domain must be specified with -d/--domain")
@@ -49,6 +81,19 @@ gowitness commands like 'scan file' for screenshot collection.
 			return errors.New("an output file must be specified with -o/--output")
 		}
 
+		switch domainsCmdOptions.Engine {
+		case "":
+		case "subfinder", "amass":
+			if domainsCmdOptions.Passive {
+				return errors.New("--passive and --engine cannot be used together")
+			}
+			if _, err := exec.LookPath(domainsCmdOptions.Engine); err != nil {
+				return fmt.Errorf("%s is not installed or not in PATH", domainsCmdOptions.Engine)
+			}
+		default:
+			return errors.New("--engine must be one of subfinder or amass")
+		}
+
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
@@ -56,8 +101,21 @@ gowitness commands like 'scan file' for screenshot collection.
 			"target", domainsCmdOptions.Domain,
 			"output", domainsCmdOptions.OutputFile)
 
-		// Perform domain discovery (placeholder implementation)
-		err := discoverDomains(domainsCmdOptions.Domain, domainsCmdOptions.OutputFile)
+		var err error
+		if domainsCmdOptions.Passive {
+			providers := passivedns.ProvidersFromEnv()
+			if len(providers) == 0 {
+				log.Error("no passive dns providers are configured. set an api key such as SECURITYTRAILS_API_KEY")
+				return
+			}
+			err = discoverPassiveDomains(domainsCmdOptions.Domain, domainsCmdOptions.OutputFile, providers)
+		} else if domainsCmdOptions.Engine != "" {
+			err = discoverPassiveDomains(domainsCmdOptions.Domain, domainsCmdOptions.OutputFile,
+				[]passivedns.Provider{engineProvider(domainsCmdOptions.Engine)})
+		} else {
+			// Perform domain discovery (placeholder implementation)
+			err = discoverDomains(domainsCmdOptions.Domain, domainsCmdOptions.OutputFile)
+		}
 		if err != nil {
 			log.Error("domain discovery failed", "error", err)
 			return
@@ -76,6 +134,16 @@ func discoverDomains(targetDomain, outputFile string) error {
 	// Create example domains for testing
 	exampleDomains := generateExampleDomains(targetDomain)
 
+	if domainsCmdOptions.FilterWildcards {
+		wildcards, err := findWildcardDomains(exampleDomains)
+		if err != nil {
+			return err
+		}
+		exampleDomains = slices.DeleteFunc(exampleDomains, func(domain string) bool {
+			return wildcards[strings.ToLower(domain)]
+		})
+	}
+
 	// Create output file
 	file, err := os.Create(outputFile)
 	if err != nil {
@@ -99,6 +167,171 @@ func discoverDomains(targetDomain, outputFile string) error {
 	return nil
 }
 
+// engineProvider returns the provider running an external discovery tool
+func engineProvider(engine string) passivedns.Provider {
+	if engine == "amass" {
+		return passivedns.NewAmass()
+	}
+
+	return passivedns.NewSubfinder()
+}
+
+// discoverPassiveDomains queries providers for subdomains of the target,
+// writing them to the output file and, if a database writer is configured,
+// recording which provider found each one.
+func discoverPassiveDomains(targetDomain, outputFile string, providers []passivedns.Provider) error {
+	var conn *gorm.DB
+	known := make(map[string]bool)
+	if opts.Writer.Db {
+		var err error
+		conn, err = database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		var names []string
+		if err := conn.Model(&models.Domain{}).
+			Where("name = ? OR name LIKE ?", targetDomain, "%."+targetDomain).
+			Distinct().Pluck("name", &names).Error; err != nil {
+			return fmt.Errorf("failed to get known domains: %w", err)
+		}
+		for _, name := range names {
+			known[strings.ToLower(name)] = true
+		}
+	}
+
+	var subdomains []passivedns.Subdomain
+	for _, provider := range providers {
+		log.Info("querying passive dns provider", "provider", provider.Name(), "domain", targetDomain)
+
+		found, err := provider.Subdomains(targetDomain)
+		if err != nil {
+			log.Warn("passive dns provider failed", "provider", provider.Name(), "err", err)
+			continue
+		}
+
+		log.Info("passive dns provider returned subdomains", "provider", provider.Name(), "count", len(found))
+		subdomains = append(subdomains, found...)
+	}
+
+	var wildcards map[string]bool
+	if domainsCmdOptions.FilterWildcards {
+		var hostnames []string
+		for _, subdomain := range subdomains {
+			hostnames = append(hostnames, subdomain.Hostname)
+		}
+
+		var err error
+		if wildcards, err = findWildcardDomains(hostnames); err != nil {
+			return err
+		}
+	}
+
+	// the target itself is always in scope
+	hostnames := []string{targetDomain}
+	seen := map[string]bool{targetDomain: true}
+	var newDomains int
+
+	for _, subdomain := range subdomains {
+		if wildcards[strings.ToLower(subdomain.Hostname)] {
+			continue
+		}
+
+		log.Debug("discovered domain", "domain", subdomain.Hostname,
+			"source", subdomain.Source, "current", subdomain.Current)
+
+		if conn != nil {
+			if err := saveDiscoveredDomain(conn, subdomain, getValidDomainsScanSessionID()); err != nil {
+				log.Warn("failed to save discovered domain", "domain", subdomain.Hostname, "err", err)
+			}
+		}
+
+		if seen[subdomain.Hostname] {
+			continue
+		}
+		seen[subdomain.Hostname] = true
+		hostnames = append(hostnames, subdomain.Hostname)
+
+		if !known[strings.ToLower(subdomain.Hostname)] {
+			newDomains++
+		}
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	for _, hostname := range hostnames {
+		if _, err := file.WriteString(hostname + "\n"); err != nil {
+			return fmt.Errorf("failed to write domain to file: %w", err)
+		}
+	}
+
+	log.Info("passive domain discovery completed",
+		"target", targetDomain,
+		"domains_found", len(hostnames),
+		"new_domains", newDomains,
+		"output_file", outputFile)
+
+	return nil
+}
+
+// saveDiscoveredDomain records a subdomain and the source that found it,
+// updating the last seen time if the source already reported it before.
+func saveDiscoveredDomain(db *gorm.DB, subdomain passivedns.Subdomain, scanSessionID *uint) error {
+	return database.SaveDomain(db, &models.Domain{
+		Name:          subdomain.Hostname,
+		Source:        subdomain.Source,
+		Historical:    !subdomain.Current,
+		LastSeen:      subdomain.SeenAt,
+		ScanSessionID: scanSessionID,
+	})
+}
+
+// findWildcardDomains resolves hostnames, returning the ones that resolve
+// to the same answers as random subdomains of their parent domain
+func findWildcardDomains(hostnames []string) (map[string]bool, error) {
+	var servers []string
+	if domainsCmdOptions.Resolvers != "" {
+		var err error
+		servers, err = dns.LoadServers(domainsCmdOptions.Resolvers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load resolvers: %w", err)
+		}
+		log.Info("using custom resolvers", "count", len(servers))
+	}
+
+	resolver := dns.NewResolver(servers)
+	detector := dns.NewWildcards(resolver)
+
+	log.Info("checking domains for wildcard dns records", "domains", len(hostnames))
+
+	wildcards := make(map[string]bool)
+	for _, answer := range resolver.ResolveAll(hostnames) {
+		if !detector.Matches(answer) {
+			continue
+		}
+
+		wildcards[answer.Host] = true
+		log.Debug("dropping wildcard domain", "domain", answer.Host, "ips", answer.IPs())
+	}
+
+	if len(wildcards) > 0 {
+		log.Info("dropped domains matching wildcard dns records", "count", len(wildcards))
+	}
+
+	return wildcards, nil
+}
+
+func getValidDomainsScanSessionID() *uint {
+	if domainsCmdOptions.ScanSessionID > 0 {
+		return &domainsCmdOptions.ScanSessionID
+	}
+	return nil
+}
+
 // generateExampleDomains creates example subdomains for testing
 func generateExampleDomains(baseDomain string) []string {
 	// Common subdomain prefixes for realistic testing
@@ -159,4 +392,10 @@ func init() {
 	domainsCmd.Flags().StringVarP(&domainsCmdOptions.Domain, "domain", "d", "", "Target domain to discover subdomains for")
 	domainsCmd.Flags().StringVarP(&domainsCmdOptions.OutputFile, "output", "o", "", "Output file to write discovered domains")
 	domainsCmd.Flags().BoolVarP(&domainsCmdOptions.Verbose, "verbose", "v", false, "Enable verbose output")
+	domainsCmd.Flags().MarkDeprecated("verbose", "use --log-level debug instead")
+	domainsCmd.Flags().BoolVar(&domainsCmdOptions.Passive, "passive", false, "Discover subdomains using configured passive DNS providers (e.g., SecurityTrails)")
+	domainsCmd.Flags().StringVar(&domainsCmdOptions.Engine, "engine", "", "Discover subdomains by running an installed tool: subfinder or amass")
+	domainsCmd.Flags().UintVar(&domainsCmdOptions.ScanSessionID, "scan-session-id", 0, "Associate discovered domains with specific scan session ID")
+	domainsCmd.Flags().BoolVar(&domainsCmdOptions.FilterWildcards, "filter-wildcards", false, "Drop discovered subdomains that only resolve because of a wildcard DNS record")
+	domainsCmd.Flags().StringVar(&domainsCmdOptions.Resolvers, "resolvers", "", "File with DNS resolvers to use with --filter-wildcards, one per line (e.g., 1.1.1.1 or 9.9.9.9:53). Defaults to the system resolver")
 }
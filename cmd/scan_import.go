@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/assets"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+var importCmdOptions = struct {
+	File          string
+	ScanSessionID uint
+}{}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import assets with their owner and criticality from CSV",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan import
+
+Import assets with their owner and criticality from CSV.
+
+Reads a CSV export, such as from a CMDB, with a header row and a host column.
+The label, owner, environment and criticality columns are optional, and other
+columns are kept as metadata of the asset. Criticality is one of low, medium,
+high or critical.
+
+A host is a hostname, an IP address, a CIDR or a wildcard such as
+*.example.com. Results, IPs and domains are matched to the asset they are,
+which shows in API responses and reports. Importing a host again updates its
+asset.
+
+Hostnames are also added to the known domains, so that scans of domains
+include them. This command does NOT perform web screenshots.`)),
+	Example: ascii.Markdown(`
+- gowitness scan import -f assets.csv --write-db
+- gowitness scan import -f cmdb-export.csv --write-db --scan-session-id 1`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if importCmdOptions.File == "" {
+			return errors.New("an assets CSV file must be specified")
+		}
+
+		if _, err := os.Stat(importCmdOptions.File); os.IsNotExist(err) {
+			return fmt.Errorf("file does not exist: %s", importCmdOptions.File)
+		}
+
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for asset imports")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		var scanSessionID *uint
+		if importCmdOptions.ScanSessionID > 0 {
+			scanSessionID = &importCmdOptions.ScanSessionID
+		}
+
+		return importAssetsFile(db, importCmdOptions.File, scanSessionID)
+	},
+}
+
+// importAssetsFile saves the assets in a CSV file, and their hostnames as
+// known domains
+func importAssetsFile(db *gorm.DB, path string, scanSessionID *uint) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open assets file: %w", err)
+	}
+	defer file.Close()
+
+	imported, err := assets.Parse(file)
+	if err != nil {
+		return err
+	}
+
+	created, updated, err := assets.Save(db, imported, scanSessionID)
+	if err != nil {
+		return err
+	}
+
+	var domains int
+	for _, asset := range imported {
+		// addresses, ranges and wildcards are not domains to scan
+		if net.ParseIP(asset.Host) != nil || strings.ContainsAny(asset.Host, "/*") ||
+			!strings.Contains(asset.Host, ".") {
+			continue
+		}
+
+		if err := database.SaveDomain(db, &models.Domain{
+			Name:          asset.Host,
+			Source:        models.DomainSourceManual,
+			ScanSessionID: scanSessionID,
+		}); err != nil {
+			log.Warn("failed to save asset domain", "host", asset.Host, "err", err)
+			continue
+		}
+		domains++
+	}
+
+	log.Info("assets imported", "assets", len(imported), "created", created, "updated", updated, "domains", domains)
+	return nil
+}
+
+func init() {
+	scanCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVarP(&importCmdOptions.File, "file", "f", "", "CSV file of assets to import (required)")
+	importCmd.Flags().UintVar(&importCmdOptions.ScanSessionID, "scan-session-id", 0, "Associate assets with specific scan session ID")
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/masscan"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+var importMasscanCmdOptions = struct {
+	File          string
+	ScanSessionID uint
+}{}
+
+var importMasscanCmd = &cobra.Command{
+	Use:   "import-masscan",
+	Short: "Import open ports from masscan JSON output",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan import-masscan
+
+Import open ports from masscan JSON output (-oJ) into the IPPort table, for
+sweeps that were run with masscan outside of gowitness.
+
+Ports that are already known are skipped, but banners masscan grabbed are
+added to them if they had none. This command does NOT perform web screenshots.`)),
+	Example: ascii.Markdown(`
+- gowitness scan import-masscan -f masscan.json --write-db
+- gowitness scan import-masscan -f masscan.json --write-db --scan-session-id 1`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if importMasscanCmdOptions.File == "" {
+			return errors.New("a masscan JSON file must be specified")
+		}
+
+		if _, err := os.Stat(importMasscanCmdOptions.File); os.IsNotExist(err) {
+			return fmt.Errorf("file does not exist: %s", importMasscanCmdOptions.File)
+		}
+
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for masscan imports")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		var scanSessionID *uint
+		if importMasscanCmdOptions.ScanSessionID > 0 {
+			scanSessionID = &importMasscanCmdOptions.ScanSessionID
+		}
+
+		return importMasscanFile(db, importMasscanCmdOptions.File, scanSessionID)
+	},
+}
+
+// importMasscanFile saves the open ports in a masscan -oJ file
+func importMasscanFile(db *gorm.DB, path string, scanSessionID *uint) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open masscan output: %w", err)
+	}
+	defer file.Close()
+
+	ports, err := masscan.Parse(file)
+	if err != nil {
+		return err
+	}
+
+	var saved, updated, skipped int
+	for _, port := range ports {
+		if port.Status != "open" {
+			skipped++
+			continue
+		}
+
+		var existing models.IPPort
+		err := db.Where("ip_address = ? AND port = ?", port.IP, port.Port).First(&existing).Error
+		if err == nil {
+			// Known port, only add the banner masscan grabbed
+			if existing.Banner == "" && port.Banner != "" {
+				if err := db.Model(&existing).Updates(map[string]interface{}{
+					"service": port.Service,
+					"banner":  port.Banner,
+				}).Error; err != nil {
+					log.Warn("failed to update port banner", "ip", port.IP, "port", port.Port, "err", err)
+					skipped++
+					continue
+				}
+				updated++
+				continue
+			}
+			skipped++
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warn("database error checking for existing port", "ip", port.IP, "port", port.Port, "err", err)
+			skipped++
+			continue
+		}
+
+		if err := db.Create(&models.IPPort{
+			IPAddress:     port.IP,
+			Port:          port.Port,
+			Protocol:      port.Protocol,
+			Service:       port.Service,
+			State:         "open",
+			Banner:        port.Banner,
+			ScanSessionID: scanSessionID,
+		}).Error; err != nil {
+			log.Warn("failed to save port result", "ip", port.IP, "port", port.Port, "err", err)
+			skipped++
+			continue
+		}
+		saved++
+	}
+
+	log.Info("masscan results processed", "ports", len(ports), "saved", saved, "updated", updated, "skipped", skipped)
+	return nil
+}
+
+func init() {
+	scanCmd.AddCommand(importMasscanCmd)
+
+	importMasscanCmd.Flags().StringVarP(&importMasscanCmdOptions.File, "file", "f", "", "masscan JSON output file (-oJ) to import (required)")
+	importMasscanCmd.Flags().UintVar(&importMasscanCmdOptions.ScanSessionID, "scan-session-id", 0, "Associate ports with specific scan session ID")
+}
@@ -5,13 +5,19 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/sensepost/gowitness/internal/ascii"
 	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/asn"
+	"github.com/sensepost/gowitness/pkg/company"
 	"github.com/sensepost/gowitness/pkg/database"
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/portprofile"
+	"github.com/sensepost/gowitness/pkg/scripting"
 	"github.com/spf13/cobra"
 )
 
@@ -27,8 +33,20 @@ This creates:
 - A target folder: targets/<target>/
 - Target-specific database: targets/<target>/<target>.sqlite3  
 - Screenshot directory: targets/<target>/screenshots/
+- Check scripts directory: targets/<target>/scripts/
+- Port profiles for 'scan naabu': targets/<target>/port-profiles.json
 - Scan session record with company information
 
+The company record can optionally be enriched with its industry, employee
+count, known netblocks and official domains. Use --enrich clearbit to query
+the Clearbit company API (needs CLEARBIT_API_KEY in the environment or a .env
+file), or --enrich <file> to read a JSON profile gathered by hand. The
+announced prefixes of the company's ASNs (--asn) are added to its netblocks.
+
+Companies with several brands can have several main domains, by repeating
+--domain. The first domain is the main domain, and together they define the
+session's scope.
+
 The target name must contain only lowercase letters, numbers, and underscores
 for folder organization, while the company name can be the full business name.
 
@@ -37,15 +55,20 @@ Example:
 - Target: "almbrand"`),
 	Example: ascii.Markdown(`
 - gowitness scan init --company "Alm. Brand Forsikring A/S" --target almbrand --domain almbrand.dk
-- gowitness scan init -c "Acme Corporation Ltd" --target acme_corp -d acme.com`),
+- gowitness scan init -c "Acme Corporation Ltd" --target acme_corp -d acme.com
+- gowitness scan init -c "Acme Corporation Ltd" --target acme_corp -d acme.com -d acme-brand.com
+- gowitness scan init -c "Acme Corporation Ltd" --target acme_corp -d acme.com --enrich clearbit --asn AS64496`),
 	RunE: scanInitCmdRunE,
 }
 
 var (
 	scanInitCompanyName string
 	scanInitTargetName  string
-	scanInitMainDomain  string
+	scanInitDomains     []string
 	scanInitNotes       string
+	scanInitTimezone    string
+	scanInitEnrich      string
+	scanInitASNs        []string
 )
 
 func scanInitCmdRunE(cmd *cobra.Command, args []string) error {
@@ -55,9 +78,19 @@ func scanInitCmdRunE(cmd *cobra.Command, args []string) error {
 	if scanInitTargetName == "" {
 		return fmt.Errorf("target name is required (--target)")
 	}
-	if scanInitMainDomain == "" {
+
+	// the first domain is the main domain, the others are further brands
+	var domains []string
+	for _, domain := range scanInitDomains {
+		domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" && !slices.Contains(domains, domain) {
+			domains = append(domains, domain)
+		}
+	}
+	if len(domains) == 0 {
 		return fmt.Errorf("main domain is required (--domain)")
 	}
+	mainDomain := domains[0]
 
 	// Validate target name format (lowercase, numbers, underscore only)
 	validTargetName := regexp.MustCompile(`^[a-z0-9_]+$`)
@@ -65,28 +98,52 @@ func scanInitCmdRunE(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("target name must contain only lowercase letters, numbers, and underscores (got: %s)", scanInitTargetName)
 	}
 
+	// Resolve the timezone the session is run from, used to display times
+	if scanInitTimezone == "" {
+		scanInitTimezone = os.Getenv("TZ")
+	}
+	if scanInitTimezone == "" {
+		scanInitTimezone = "UTC"
+	}
+	if _, err := time.LoadLocation(scanInitTimezone); err != nil {
+		return fmt.Errorf("invalid timezone %s: %w", scanInitTimezone, err)
+	}
+
 	// Create target directory structure
 	targetDir := filepath.Join("targets", scanInitTargetName)
 	screenshotDir := filepath.Join(targetDir, "screenshots")
+	scriptsDir := filepath.Join(targetDir, scripting.ProjectDir)
 	dbPath := filepath.Join(targetDir, scanInitTargetName+".sqlite3")
 
 	// Create directories
-	if err := os.MkdirAll(screenshotDir, 0755); err != nil {
-		return fmt.Errorf("failed to create target directory structure: %w", err)
+	for _, dir := range []string{screenshotDir, scriptsDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create target directory structure: %w", err)
+		}
+	}
+
+	// Port profiles are only written once, as they may have been edited
+	profilesPath := filepath.Join(targetDir, portprofile.ProjectFile)
+	if !islazy.FileExists(profilesPath) {
+		if err := portprofile.Default().Save(profilesPath); err != nil {
+			return fmt.Errorf("failed to write port profiles: %w", err)
+		}
 	}
 
 	log.Info("created target directory structure",
 		"target-dir", targetDir,
 		"screenshot-dir", screenshotDir,
+		"scripts-dir", scriptsDir,
+		"port-profiles", profilesPath,
 		"database-path", dbPath)
 
 	// Try to fetch company logo from Clearbit
 	var logoPath string
-	log.Info("attempting to fetch company logo from Clearbit", "domain", scanInitMainDomain)
-	fetchedLogoPath, err := islazy.FetchClearbitLogo(scanInitMainDomain, targetDir)
+	log.Info("attempting to fetch company logo from Clearbit", "domain", mainDomain)
+	fetchedLogoPath, err := islazy.FetchClearbitLogo(mainDomain, targetDir)
 	if err != nil {
 		log.Warn("failed to fetch logo from Clearbit - you may need to add one manually",
-			"domain", scanInitMainDomain,
+			"domain", mainDomain,
 			"error", err.Error(),
 			"location", filepath.Join(targetDir, "logo.png"))
 	} else {
@@ -94,6 +151,17 @@ func scanInitCmdRunE(cmd *cobra.Command, args []string) error {
 		log.Info("successfully fetched company logo", "path", logoPath)
 	}
 
+	// Store absolute paths on the session, so that they resolve no matter
+	// where the report server is started from
+	if abs, err := filepath.Abs(screenshotDir); err == nil {
+		screenshotDir = abs
+	}
+	if logoPath != "" {
+		if abs, err := filepath.Abs(logoPath); err == nil {
+			logoPath = abs
+		}
+	}
+
 	// Connect to target-specific database
 	dbURI := fmt.Sprintf("sqlite://%s", dbPath)
 	conn, err := database.Connection(dbURI, false, opts.Writer.DbDebug)
@@ -103,12 +171,21 @@ func scanInitCmdRunE(cmd *cobra.Command, args []string) error {
 
 	// Create new scan session
 	session := &models.ScanSession{
-		CompanyName: scanInitCompanyName,
-		MainDomain:  scanInitMainDomain,
-		LogoPath:    logoPath,
-		StartTime:   time.Now(),
-		Status:      "active",
-		Notes:       scanInitNotes,
+		CompanyName:    scanInitCompanyName,
+		MainDomain:     mainDomain,
+		LogoPath:       logoPath,
+		ScreenshotPath: screenshotDir,
+		Timezone:       scanInitTimezone,
+		StartTime:      time.Now(),
+		Status:         database.SessionActive,
+		Notes:          scanInitNotes,
+	}
+	for _, domain := range domains {
+		session.ApexDomains = append(session.ApexDomains, models.ApexDomain{Domain: domain})
+	}
+
+	if scanInitEnrich != "" || len(scanInitASNs) > 0 {
+		enrichSession(session)
 	}
 
 	if err := conn.Create(session).Error; err != nil {
@@ -119,11 +196,23 @@ func scanInitCmdRunE(cmd *cobra.Command, args []string) error {
 		"session-id", session.ID,
 		"company", session.CompanyName,
 		"target", scanInitTargetName,
-		"domain", session.MainDomain,
+		"domains", strings.Join(session.ScopeDomains(), ","),
 		"database", dbPath,
 		"screenshots", screenshotDir,
+		"timezone", session.Timezone,
 		"start-time", session.StartTime.Format(time.RFC3339))
 
+	if session.EnrichmentSource != "" {
+		netblocks, _ := session.GetNetblocks()
+		domains, _ := session.GetDomains()
+		log.Info("company profile enriched",
+			"source", session.EnrichmentSource,
+			"industry", session.Industry,
+			"employees", session.EmployeeCount,
+			"netblocks", len(netblocks),
+			"domains", len(domains))
+	}
+
 	log.Info("use these settings for subsequent scans:",
 		"db-uri", dbURI,
 		"screenshot-path", screenshotDir)
@@ -131,13 +220,68 @@ func scanInitCmdRunE(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// enrichSession fills the company profile of a session from the enrichment
+// source and ASNs. Failures are logged, as the profile is nice to have.
+func enrichSession(session *models.ScanSession) {
+	profile := &company.Profile{}
+	var sources []string
+
+	if scanInitEnrich != "" {
+		provider, err := company.NewProvider(scanInitEnrich)
+		if err != nil {
+			log.Warn("company enrichment is disabled", "err", err)
+		} else {
+			log.Info("enriching company profile", "source", provider.Name(), "domain", session.MainDomain)
+			found, err := provider.Lookup(session.CompanyName, session.MainDomain)
+			if err != nil {
+				log.Warn("failed to enrich company profile", "source", provider.Name(), "err", err)
+			} else {
+				profile.Merge(found)
+				sources = append(sources, provider.Name())
+			}
+		}
+	}
+
+	for _, as := range scanInitASNs {
+		prefixes, err := asn.AnnouncedPrefixes(as)
+		if err != nil {
+			log.Warn("failed to get announced prefixes", "asn", asn.Normalise(as), "err", err)
+			continue
+		}
+		profile.Merge(&company.Profile{Netblocks: prefixes})
+		sources = append(sources, asn.Normalise(as))
+	}
+
+	if len(sources) == 0 {
+		return
+	}
+
+	// the session's own domains are always official domains, main first
+	merged := &company.Profile{Domains: session.ScopeDomains()}
+	merged.Merge(profile)
+	profile = merged
+
+	session.Industry = profile.Industry
+	session.EmployeeCount = profile.EmployeeCount
+	session.EnrichmentSource = strings.Join(sources, ",")
+	if err := session.SetNetblocks(profile.Netblocks); err != nil {
+		log.Warn("failed to store netblocks", "err", err)
+	}
+	if err := session.SetDomains(profile.Domains); err != nil {
+		log.Warn("failed to store domains", "err", err)
+	}
+}
+
 func init() {
 	scanCmd.AddCommand(scanInitCmd)
 
 	scanInitCmd.Flags().StringVarP(&scanInitCompanyName, "company", "c", "", "Full company name (required)")
 	scanInitCmd.Flags().StringVar(&scanInitTargetName, "target", "", "Target folder name - lowercase, numbers, underscore only (required)")
-	scanInitCmd.Flags().StringVarP(&scanInitMainDomain, "domain", "d", "", "Target company main domain (required)")
+	scanInitCmd.Flags().StringSliceVarP(&scanInitDomains, "domain", "d", []string{}, "Target company main domain (required). Repeat for companies with several brands, the first is the main domain")
 	scanInitCmd.Flags().StringVarP(&scanInitNotes, "notes", "n", "", "Optional notes about the scan session")
+	scanInitCmd.Flags().StringVar(&scanInitEnrich, "enrich", "", "Enrich the company profile from a source: \"clearbit\" or a JSON profile file")
+	scanInitCmd.Flags().StringSliceVar(&scanInitASNs, "asn", []string{}, "ASNs of the company, whose announced prefixes are added to its netblocks (e.g., AS64496)")
+	scanInitCmd.Flags().StringVar(&scanInitTimezone, "timezone", "", "IANA timezone the scan is run from, used to display times (e.g., Europe/Copenhagen). Defaults to $TZ, or UTC")
 
 	// Mark required flags
 	scanInitCmd.MarkFlagRequired("company")
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var masscanCmdOptions = struct {
+	CIDRs         []string
+	File          string
+	ExcludeFile   string
+	Ports         string
+	TopPorts      int
+	Rate          int
+	Banners       bool
+	ScanSessionID uint
+	OutputFile    string
+}{}
+
+var masscanCmd = &cobra.Command{
+	Use:   "masscan",
+	Short: "Run masscan against address ranges and store the open ports",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan masscan
+
+Run masscan against address ranges and store the open ports it finds in the
+IPPort table. masscan is much faster than naabu on very large CIDR sweeps, but
+it only takes IP addresses and ranges, not domains. This command does NOT
+perform web screenshots.
+
+Ports that are already known are skipped. With --banners, masscan grabs
+service banners, which are stored with the ports.
+
+If the scan is interrupted with Ctrl-C, masscan is stopped, the ports it found
+so far are saved, and the scan session is marked as cancelled.
+
+**Note**: masscan must be installed, and usually needs root to send raw
+packets. Results of earlier masscan runs can be imported with
+'gowitness scan import-masscan'.`)),
+	Example: ascii.Markdown(`
+- gowitness scan masscan --cidr 192.0.2.0/24 --write-db
+- gowitness scan masscan --cidr 198.51.100.0/22 --cidr 203.0.113.0/24 --ports 1-65535 --rate 10000 --write-db
+- gowitness scan masscan -f ranges.txt --top-ports 100 --banners --write-db --scan-session-id 1`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(masscanCmdOptions.CIDRs) == 0 && masscanCmdOptions.File == "" {
+			return errors.New("a --cidr or a file with ranges must be specified")
+		}
+
+		for _, cidr := range masscanCmdOptions.CIDRs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+				return fmt.Errorf("invalid cidr %s: %w", cidr, err)
+			}
+		}
+
+		for _, file := range []string{masscanCmdOptions.File, masscanCmdOptions.ExcludeFile} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); os.IsNotExist(err) {
+				return fmt.Errorf("file does not exist: %s", file)
+			}
+		}
+
+		if _, err := exec.LookPath("masscan"); err != nil {
+			return errors.New("masscan is not installed or not in PATH")
+		}
+
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for masscan scans")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log.Info("starting masscan port scan",
+			"cidrs", len(masscanCmdOptions.CIDRs),
+			"file", masscanCmdOptions.File,
+			"rate", masscanCmdOptions.Rate,
+			"scan-session-id", masscanCmdOptions.ScanSessionID)
+
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		outputFile := masscanCmdOptions.OutputFile
+		if outputFile == "" {
+			outputFile = fmt.Sprintf("masscan_results_%d.json", time.Now().Unix())
+			defer os.Remove(outputFile)
+		}
+
+		// Out of scope ranges are never scanned
+		if scanScope != nil {
+			var skipped int
+			masscanCmdOptions.CIDRs, skipped = scanScope.Filter(masscanCmdOptions.CIDRs)
+			if skipped > 0 {
+				log.Warn("skipping out of scope ranges", "count", skipped)
+			}
+
+			if masscanCmdOptions.File != "" {
+				masscanCmdOptions.File, err = scopeTargetsFile("masscan", masscanCmdOptions.File)
+				if err != nil {
+					return fmt.Errorf("failed to apply the scope to the targets: %w", err)
+				}
+				defer os.Remove(masscanCmdOptions.File)
+			}
+
+			if len(masscanCmdOptions.CIDRs) == 0 && masscanCmdOptions.File == "" {
+				return errors.New("no targets are in scope")
+			}
+		}
+
+		ctx, stop := interruptContext()
+		defer stop()
+
+		// When interrupted, masscan still writes the ports it found so far,
+		// so those are saved before giving up
+		if err := executeMasscan(ctx, buildMasscanCommand(outputFile)); err != nil {
+			if ctx.Err() == nil {
+				return fmt.Errorf("failed to execute masscan: %w", err)
+			}
+			log.Warn("masscan was interrupted, saving the ports found so far")
+		}
+
+		var scanSessionID *uint
+		if masscanCmdOptions.ScanSessionID > 0 {
+			scanSessionID = &masscanCmdOptions.ScanSessionID
+		}
+
+		err = importMasscanFile(db, outputFile, scanSessionID)
+		if err != nil && ctx.Err() != nil && errors.Is(err, os.ErrNotExist) {
+			// masscan was interrupted before it found any ports
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			if scanSessionID != nil {
+				if err := database.CancelSession(db, *scanSessionID, "masscan"); err != nil {
+					log.Warn("could not update scan session status", "session-id", *scanSessionID, "err", err)
+				}
+			}
+			log.Warn("masscan port scan interrupted")
+			return nil
+		}
+
+		log.Info("masscan port scan completed successfully")
+		return nil
+	},
+}
+
+func buildMasscanCommand(outputFile string) []string {
+	args := append([]string{}, masscanCmdOptions.CIDRs...)
+	args = append(args, "-oJ", outputFile)
+
+	if masscanCmdOptions.File != "" {
+		args = append(args, "-iL", masscanCmdOptions.File)
+	}
+
+	if masscanCmdOptions.ExcludeFile != "" {
+		args = append(args, "--excludefile", masscanCmdOptions.ExcludeFile)
+	}
+
+	// Port selection
+	if masscanCmdOptions.TopPorts > 0 {
+		args = append(args, "--top-ports", fmt.Sprintf("%d", masscanCmdOptions.TopPorts))
+	} else {
+		args = append(args, "-p", masscanCmdOptions.Ports)
+	}
+
+	if masscanCmdOptions.Rate > 0 {
+		args = append(args, "--rate", fmt.Sprintf("%d", masscanCmdOptions.Rate))
+	}
+
+	if masscanCmdOptions.Banners {
+		args = append(args, "--banners")
+	}
+
+	return args
+}
+
+// executeMasscan runs masscan. If ctx is cancelled, masscan is interrupted
+// too, so that it writes its results before exiting.
+func executeMasscan(ctx context.Context, args []string) error {
+	log.Info("executing masscan", "args", strings.Join(args, " "))
+
+	cmd := exec.CommandContext(ctx, "masscan", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = 30 * time.Second
+
+	return cmd.Run()
+}
+
+func init() {
+	scanCmd.AddCommand(masscanCmd)
+
+	masscanCmd.Flags().StringSliceVar(&masscanCmdOptions.CIDRs, "cidr", []string{}, "Address range to scan. Supports multiple --cidr flags")
+	masscanCmd.Flags().StringVarP(&masscanCmdOptions.File, "file", "f", "", "File with IP addresses and ranges to scan, one per line")
+	masscanCmd.Flags().StringVar(&masscanCmdOptions.ExcludeFile, "exclude-file", "", "File with IP addresses and ranges to never scan")
+	masscanCmd.Flags().StringVarP(&masscanCmdOptions.Ports, "ports", "p", "80,443,8000,8080,8443", "Ports to scan (e.g., '22,80,443' or '1-65535')")
+	masscanCmd.Flags().IntVar(&masscanCmdOptions.TopPorts, "top-ports", 0, "Scan this many of the most common ports instead of --ports")
+	masscanCmd.Flags().IntVar(&masscanCmdOptions.Rate, "rate", 1000, "Packets to send per second")
+	masscanCmd.Flags().BoolVar(&masscanCmdOptions.Banners, "banners", false, "Grab service banners")
+	masscanCmd.Flags().UintVar(&masscanCmdOptions.ScanSessionID, "scan-session-id", 0, "Associate results with specific scan session ID")
+	masscanCmd.Flags().StringVar(&masscanCmdOptions.OutputFile, "output", "", "File to save masscan JSON results (optional, uses temp file by default)")
+}
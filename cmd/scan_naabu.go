@@ -1,18 +1,20 @@
 package cmd
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"os/exec"
-	"strings"
 	"time"
 
 	"github.com/sensepost/gowitness/internal/ascii"
 	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/discovery"
 	"github.com/sensepost/gowitness/pkg/log"
-	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/naabu"
+	"github.com/sensepost/gowitness/pkg/portprofile"
+	"github.com/sensepost/gowitness/pkg/writers"
 	"github.com/spf13/cobra"
 	"gorm.io/gorm"
 )
@@ -25,21 +27,19 @@ var naabuCmdOptions = struct {
 	Threads       int
 	Timeout       int
 	ExcludeCDN    bool
+	PortProfiles  string
 	DisplayCDN    bool
 	Verbose       bool
 	ScanSessionID uint
 	OutputFile    string
-}{}
+	Resume        bool
 
-// NaabuResult represents a single port scan result from naabu JSON output
-type NaabuResult struct {
-	Host     string `json:"host"`
-	IP       string `json:"ip"`
-	Port     int    `json:"port"`
-	CDN      bool   `json:"cdn"`
-	CDNName  string `json:"cdn-name"`
-	Protocol string `json:"protocol"`
-}
+	Discover        bool
+	DiscoverPorts   []int
+	DiscoverICMP    bool
+	DiscoverTimeout int
+	DiscoverThreads int
+}{}
 
 var naabuCmd = &cobra.Command{
 	Use:   "naabu",
@@ -47,12 +47,41 @@ var naabuCmd = &cobra.Command{
 	Long: ascii.LogoHelp(ascii.Markdown(`
 # scan naabu
 
-Run naabu port scanner against a list of domains and store the results in the 
-IPPort table. This command does NOT perform web screenshots - it only does 
+Run naabu port scanner against a list of domains, from a file or piped to
+stdin, and store the results in the IPPort table. This command does NOT perform web screenshots - it only does 
 port scanning and populates the port information in the database.
 
-The command automatically excludes CDN/WAF services from full port scans to 
-avoid scanning CDN infrastructure (only scans ports 80,443 for CDN hosts).
+How much of each host is scanned is set by port profiles, read from the
+--port-profiles file, or port-profiles.json next to the project database as
+written by 'scan init'. Profiles match hosts by network, or by the CDN naabu
+detects in front of them, and set their ports, or skip them. Hosts get the
+first profile that matches, and the --top-ports or --custom-ports of the scan
+if none do. To detect CDNs, ports 80 and 443 of every host are scanned first.
+Without a profiles file, hosts behind cloud WAFs are skipped and only ports 80
+and 443 are scanned on other CDN hosts, unless --exclude-cdn=false is given.
+
+With --write-jsonl, every open port is also written to the --write-jsonl-file
+as a JSON line, such as {"record":"ip_port","source":"naabu","data":{...}}, to
+pipe into jq or other tools. --write-db is optional then.
+
+With --scope-domain, --scope-cidr or --scope-exclude, out of scope targets are
+not passed to naabu, and naabu skips the excluded addresses. Note that the
+addresses in scope domains resolve to are not checked against --scope-cidr.
+
+Ports are saved as naabu reports them, so the ports found before a crash or an
+interrupted scan are kept, and the number of ports found so far is logged every
+30 seconds. With --output, naabu's JSON results are written to a file as well.
+
+With --discover, the IP addresses and ranges among the targets are first probed
+with an ICMP echo request and TCP connections to a few --discover-ports, and
+only the addresses that answer are port scanned. Hostnames are always scanned.
+Whether each address answered is saved to the database, so dead addresses can
+be told apart from ones without open ports. This saves a lot of time on large
+ranges that are mostly empty.
+
+If the scan is interrupted with Ctrl-C, naabu is stopped. A checkpoint file is
+written, the scan session is marked as cancelled, and --resume continues the
+scan from naabu's own resume state.
 
 **Note**: This command requires naabu to be installed. Run 'make prerequisites' 
 to install naabu and its dependencies.`)),
@@ -60,25 +89,50 @@ to install naabu and its dependencies.`)),
 - gowitness scan naabu -f domains.txt --write-db
 - gowitness scan naabu -f targets.txt --top-ports 1000 --write-db --scan-session-id 1
 - gowitness scan naabu -f hosts.txt --custom-ports "22,80,443,8080" --rate 500 --write-db
-- gowitness scan naabu -f domains.txt --exclude-cdn --display-cdn --verbose --write-db`),
+- gowitness scan naabu -f domains.txt --exclude-cdn --display-cdn --log-level debug --write-db
+- gowitness scan naabu -f ranges.txt --discover --top-ports 1000 --write-db
+- gowitness scan naabu -f targets.txt --port-profiles profiles.json --write-db
+- gowitness scan naabu -f domains.txt --write-jsonl --write-jsonl-file ports.jsonl
+- subfinder -d example.com -silent | gowitness scan naabu --write-db
+- gowitness scan naabu --resume --write-db`),
 	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// Pick up the file and scan session of the interrupted scan
+		if naabuCmdOptions.Resume {
+			checkpoint, err := readCheckpoint("naabu")
+			if err != nil {
+				return err
+			}
+			if naabuCmdOptions.File == "" {
+				naabuCmdOptions.File = checkpoint.File
+			}
+			if naabuCmdOptions.ScanSessionID == 0 {
+				naabuCmdOptions.ScanSessionID = checkpoint.ScanSessionID
+			}
+		}
+
+		// Targets piped in are read without needing -f -
+		if naabuCmdOptions.File == "" && stdinPiped() {
+			naabuCmdOptions.File = "-"
+		}
 		if naabuCmdOptions.File == "" {
-			return errors.New("a file with domains must be specified")
+			return errors.New("a file with domains must be specified, or domains piped to stdin")
 		}
 
 		// Check if file exists
-		if _, err := os.Stat(naabuCmdOptions.File); os.IsNotExist(err) {
-			return fmt.Errorf("file does not exist: %s", naabuCmdOptions.File)
+		if naabuCmdOptions.File != "-" {
+			if _, err := os.Stat(naabuCmdOptions.File); os.IsNotExist(err) {
+				return fmt.Errorf("file does not exist: %s", naabuCmdOptions.File)
+			}
 		}
 
 		// Check if naabu is installed
-		if _, err := exec.LookPath("naabu"); err != nil {
+		if !naabu.Installed() {
 			return errors.New("naabu is not installed. Please run 'make prerequisites' to install it")
 		}
 
-		// Check if database output is specified
-		if !opts.Writer.Db {
-			return errors.New("--write-db flag is required for naabu scans")
+		// Check if database or JSON lines output is specified
+		if !opts.Writer.Db && !opts.Writer.Jsonl {
+			return errors.New("--write-db or --write-jsonl is required for naabu scans")
 		}
 
 		return nil
@@ -89,156 +143,210 @@ to install naabu and its dependencies.`)),
 			"exclude-cdn", naabuCmdOptions.ExcludeCDN,
 			"scan-session-id", naabuCmdOptions.ScanSessionID)
 
-		// Create temporary output file for naabu results
-		tempFile := naabuCmdOptions.OutputFile
-		if tempFile == "" {
-			tempFile = fmt.Sprintf("naabu_results_%d.json", time.Now().Unix())
-		}
-		defer func() {
-			if naabuCmdOptions.OutputFile == "" {
-				os.Remove(tempFile) // Clean up temp file if we created it
+		// naabu reads its targets from a file, which a resume needs too. It
+		// is removed once the scan completes.
+		var stdinFile string
+		if naabuCmdOptions.File == "-" {
+			path, err := saveStdin("naabu")
+			if err != nil {
+				log.Error("failed to save targets from stdin", "err", err)
+				return
 			}
-		}()
-
-		// Build naabu command
-		naabuArgs := buildNaabuCommand(tempFile)
-
-		// Execute naabu
-		if err := executeNaabu(naabuArgs); err != nil {
-			log.Error("failed to execute naabu", "err", err)
-			return
+			naabuCmdOptions.File, stdinFile = path, path
 		}
 
-		// Parse results and save to database
-		if err := parseAndSaveResults(tempFile); err != nil {
-			log.Error("failed to parse and save naabu results", "err", err)
-			return
+		// Connect to database
+		var db *gorm.DB
+		var err error
+		if opts.Writer.Db {
+			db, err = database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+			if err != nil {
+				log.Error("failed to connect to database", "err", err)
+				return
+			}
 		}
 
-		log.Info("naabu port scan completed successfully")
-	},
-}
-
-func buildNaabuCommand(outputFile string) []string {
-	args := []string{
-		"-l", naabuCmdOptions.File,
-		"-json",
-		"-o", outputFile,
-		"-display-cdn", // Always enable CDN detection for database storage
-	}
+		var records *writers.RecordWriter
+		if opts.Writer.Jsonl {
+			records, err = writers.NewRecordWriter(opts.Writer.JsonlFile)
+			if err != nil {
+				log.Error("failed to open JSON lines file", "err", err)
+				return
+			}
+			defer records.Close()
+		}
 
-	// Always exclude CDN by default for safety
-	if naabuCmdOptions.ExcludeCDN {
-		args = append(args, "-exclude-cdn")
-	}
+		if db != nil && naabuCmdOptions.Resume && naabuCmdOptions.ScanSessionID > 0 {
+			if err := database.ReactivateSession(db, naabuCmdOptions.ScanSessionID); err != nil {
+				log.Warn("could not update scan session status", "session-id", naabuCmdOptions.ScanSessionID, "err", err)
+			}
+		}
 
-	if naabuCmdOptions.Verbose {
-		args = append(args, "-verbose")
-	}
+		started := time.Now()
 
-	// Port selection
-	if naabuCmdOptions.CustomPorts != "" {
-		args = append(args, "-p", naabuCmdOptions.CustomPorts)
-	} else if naabuCmdOptions.TopPorts != "" {
-		args = append(args, "-top-ports", naabuCmdOptions.TopPorts)
-	}
+		ctx, stop := interruptContext()
+		defer stop()
 
-	// Performance settings
-	if naabuCmdOptions.Rate > 0 {
-		args = append(args, "-rate", fmt.Sprintf("%d", naabuCmdOptions.Rate))
-	}
+		// Build naabu command
+		// Out of scope targets are left out of the file naabu reads
+		targetsFile := naabuCmdOptions.File
+		if scanScope != nil {
+			targetsFile, err = scopeTargetsFile("naabu", naabuCmdOptions.File)
+			if err != nil {
+				log.Error("failed to apply the scope to the targets", "err", err)
+				return
+			}
+			defer os.Remove(targetsFile)
+		}
 
-	if naabuCmdOptions.Threads > 0 {
-		args = append(args, "-c", fmt.Sprintf("%d", naabuCmdOptions.Threads))
-	}
+		// Addresses that do not answer host discovery are not port scanned
+		if naabuCmdOptions.Discover {
+			targetsFile, err = discoverTargetsFile(ctx, db, "naabu", targetsFile, discovery.Options{
+				Ports:   naabuCmdOptions.DiscoverPorts,
+				ICMP:    naabuCmdOptions.DiscoverICMP,
+				Timeout: time.Duration(naabuCmdOptions.DiscoverTimeout) * time.Millisecond,
+				Threads: naabuCmdOptions.DiscoverThreads,
+			}, getValidScanSessionID())
+			if err != nil {
+				log.Error("failed to discover live hosts", "err", err)
+				return
+			}
+			defer os.Remove(targetsFile)
+		}
 
-	if naabuCmdOptions.Timeout > 0 {
-		args = append(args, "-timeout", fmt.Sprintf("%d", naabuCmdOptions.Timeout))
-	}
+		policy, err := naabuPortPolicy()
+		if err != nil {
+			log.Error("failed to load port profiles", "err", err)
+			return
+		}
 
-	return args
-}
+		logger := slog.New(log.Logger)
+		saver := naabu.NewSaver(logger, db, records)
+		saver.ScanSessionID = getValidScanSessionID()
+		if naabuCmdOptions.OutputFile != "" {
+			output, err := os.Create(naabuCmdOptions.OutputFile)
+			if err != nil {
+				log.Error("failed to create output file", "err", err)
+				return
+			}
+			defer output.Close()
+			saver.Output = output
+		}
 
-func executeNaabu(args []string) error {
-	log.Info("executing naabu", "args", strings.Join(args, " "))
+		scanner := naabu.NewScanner(logger, naabu.Options{
+			TopPorts:    naabuCmdOptions.TopPorts,
+			CustomPorts: naabuCmdOptions.CustomPorts,
+			Rate:        naabuCmdOptions.Rate,
+			Threads:     naabuCmdOptions.Threads,
+			Timeout:     naabuCmdOptions.Timeout,
+			// naabu skips excluded addresses itself, such as those in CIDR
+			// targets or that in scope domains resolve to
+			ExcludeFile: opts.Scope.ExcludeFile,
+			Verbose:     log.DebugEnabled(),
+		})
+		scanner.Policy = policy
+		scanner.Resume = naabuCmdOptions.Resume
+		scanner.Pass = naabuPass(saver, naabuProgressInterval)
+
+		// Execute naabu, saving the ports it finds as they are reported. The
+		// ports found before naabu fails or is interrupted are kept.
+		err = scanner.Scan(ctx, targetsFile, saver)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error("failed to execute naabu", "err", err, "ports_saved", saver.Summary().Saved)
+				return
+			}
+			log.Warn("naabu was interrupted, the ports found so far are saved")
+		}
 
-	cmd := exec.Command("naabu", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+		summary := saver.Summary()
+		printScanSummary("naabu scan results",
+			"ports_found", summary.Found,
+			"saved", summary.Saved,
+			"skipped", summary.Skipped,
+			"duration", time.Since(started).Round(time.Second).String())
+
+		if ctx.Err() != nil {
+			interruptScan(db, "naabu", &scanCheckpoint{
+				Command:       "naabu",
+				ScanSessionID: naabuCmdOptions.ScanSessionID,
+				File:          naabuCmdOptions.File,
+			})
+			return
+		}
 
-	return cmd.Run()
+		removeCheckpoint("naabu")
+		if stdinFile != "" {
+			os.Remove(stdinFile)
+		}
+		log.Info("naabu port scan completed successfully")
+	},
 }
 
-func parseAndSaveResults(filename string) error {
-	// Connect to database
-	db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
-	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+// naabuPortPolicy returns the port profiles of the scan, from
+// --port-profiles or the project directory. Without either, the default
+// profiles are used if --exclude-cdn is set.
+func naabuPortPolicy() (*portprofile.Policy, error) {
+	path := naabuCmdOptions.PortProfiles
+	if path == "" {
+		path = portprofile.FindProjectFile(opts.Writer.DbURI)
 	}
-
-	// Read naabu results file
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return fmt.Errorf("failed to read results file: %w", err)
+	if path != "" {
+		policy, err := portprofile.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("loaded port profiles", "file", path, "profiles", len(policy.Profiles))
+		return policy, nil
 	}
 
-	// Parse JSON lines
-	lines := strings.Split(string(data), "\n")
-	var savedCount int
-	var skippedCount int
+	if !naabuCmdOptions.ExcludeCDN {
+		return &portprofile.Policy{}, nil
+	}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+	return portprofile.Default(), nil
+}
 
-		var result NaabuResult
-		if err := json.Unmarshal([]byte(line), &result); err != nil {
-			log.Warn("failed to parse naabu result line", "line", line, "err", err)
-			skippedCount++
-			continue
-		}
+// naabuPass shows the progress of a run of naabu, with naabu's output
+// written above it, and logs the ports saved so far every interval
+func naabuPass(saver *naabu.Saver, interval time.Duration) func(title string) (io.Writer, io.Writer, func()) {
+	return func(title string) (io.Writer, io.Writer, func()) {
+		progress := startScanProgress(title, 0)
+		saver.Count = progress.Count
 
-		// Create IPPort entry
-		ipPort := models.IPPort{
-			IPAddress:     result.IP,
-			Port:          result.Port,
-			Protocol:      result.Protocol, // Use protocol from naabu result
-			State:         "open",
-			ScanSessionID: getValidScanSessionID(),
-			IsCDN:         result.CDN,
-			CDNName:       result.CDNName,
-			CDNDetected:   true, // We always run CDN detection
-			OriginalHost:  result.Host,
+		stdout := io.Writer(os.Stdout)
+		if progress.Visible() {
+			stdout = progress
 		}
 
-		// Check if this IP:Port combination already exists
-		var existing models.IPPort
-		if err := db.Where("ip_address = ? AND port = ?", result.IP, result.Port).First(&existing).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				// Not found, create new record
-				if err := db.Create(&ipPort).Error; err != nil {
-					log.Warn("failed to save port result", "ip", result.IP, "port", result.Port, "err", err)
-					skippedCount++
-					continue
+		done := make(chan struct{})
+		ticker := time.NewTicker(interval)
+		go func() {
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					summary := saver.Summary()
+					log.Info("naabu scan progress", "ports_found", summary.Found,
+						"saved", summary.Saved, "skipped", summary.Skipped,
+						"elapsed", progress.Elapsed().Round(time.Second).String())
 				}
-				savedCount++
-			} else {
-				log.Warn("database error checking for existing port", "ip", result.IP, "port", result.Port, "err", err)
-				skippedCount++
-				continue
 			}
-		} else {
-			// Record already exists, skip
-			skippedCount++
+		}()
+
+		return stdout, progress, func() {
+			close(done)
+			stopScanProgress(progress)
 		}
 	}
-
-	log.Info("naabu results processed", "saved", savedCount, "skipped", skippedCount)
-	return nil
 }
 
+// naabuProgressInterval is how often the ports found by a running naabu
+// scan are logged
+const naabuProgressInterval = 30 * time.Second
+
 func getValidScanSessionID() *uint {
 	if naabuCmdOptions.ScanSessionID > 0 {
 		return &naabuCmdOptions.ScanSessionID
@@ -249,15 +357,23 @@ func getValidScanSessionID() *uint {
 func init() {
 	scanCmd.AddCommand(naabuCmd)
 
-	naabuCmd.Flags().StringVarP(&naabuCmdOptions.File, "file", "f", "", "File containing list of domains/hosts to scan (required)")
+	naabuCmd.Flags().StringVarP(&naabuCmdOptions.File, "file", "f", "", "File containing list of domains/hosts to scan. Use - to read from stdin, which is also read when domains are piped in")
 	naabuCmd.Flags().StringVar(&naabuCmdOptions.TopPorts, "top-ports", "100", "Top ports to scan [100,1000,full]")
 	naabuCmd.Flags().StringVar(&naabuCmdOptions.CustomPorts, "custom-ports", "", "Custom ports to scan (e.g., '22,80,443,8080')")
 	naabuCmd.Flags().IntVar(&naabuCmdOptions.Rate, "rate", 500, "Packets to send per second")
 	naabuCmd.Flags().IntVar(&naabuCmdOptions.Threads, "threads", 25, "Number of concurrent threads")
 	naabuCmd.Flags().IntVar(&naabuCmdOptions.Timeout, "timeout", 1000, "Timeout in milliseconds")
-	naabuCmd.Flags().BoolVar(&naabuCmdOptions.ExcludeCDN, "exclude-cdn", true, "Skip full port scans for CDN/WAF (only scan 80,443)")
+	naabuCmd.Flags().BoolVar(&naabuCmdOptions.ExcludeCDN, "exclude-cdn", true, "Without port profiles, skip cloud WAFs and only scan 80,443 on CDNs")
+	naabuCmd.Flags().StringVar(&naabuCmdOptions.PortProfiles, "port-profiles", "", "JSON file with port profiles (default: port-profiles.json next to the project database)")
 	naabuCmd.Flags().BoolVar(&naabuCmdOptions.DisplayCDN, "display-cdn", false, "Display CDN detection information")
 	naabuCmd.Flags().BoolVar(&naabuCmdOptions.Verbose, "verbose", false, "Enable verbose output")
+	naabuCmd.Flags().MarkDeprecated("verbose", "use --log-level debug instead")
 	naabuCmd.Flags().UintVar(&naabuCmdOptions.ScanSessionID, "scan-session-id", 0, "Associate results with specific scan session ID")
-	naabuCmd.Flags().StringVar(&naabuCmdOptions.OutputFile, "output", "", "File to save naabu JSON results (optional, uses temp file by default)")
+	naabuCmd.Flags().BoolVar(&naabuCmdOptions.Resume, "resume", false, "Resume an interrupted scan from its checkpoint and naabu's resume state")
+	naabuCmd.Flags().BoolVar(&naabuCmdOptions.Discover, "discover", false, "Only port scan the IP addresses that answer host discovery")
+	naabuCmd.Flags().IntSliceVar(&naabuCmdOptions.DiscoverPorts, "discover-ports", discovery.DefaultPorts, "TCP ports to probe during host discovery")
+	naabuCmd.Flags().BoolVar(&naabuCmdOptions.DiscoverICMP, "discover-icmp", true, "Send ICMP echo requests during host discovery, if this process may")
+	naabuCmd.Flags().IntVar(&naabuCmdOptions.DiscoverTimeout, "discover-timeout", 1000, "Host discovery timeout per address in milliseconds")
+	naabuCmd.Flags().IntVar(&naabuCmdOptions.DiscoverThreads, "discover-threads", 100, "Number of addresses to probe at once during host discovery")
+	naabuCmd.Flags().StringVar(&naabuCmdOptions.OutputFile, "output", "", "File to also save naabu JSON results to (optional)")
 }
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/origin"
+	"github.com/sensepost/gowitness/pkg/passivedns"
+	"github.com/sensepost/gowitness/pkg/shodan"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+var originsCmdOptions = struct {
+	Hostname      string
+	ScanSessionID uint
+	MaxPerSource  int
+	Timeout       int
+	NoProbe       bool
+}{}
+
+var originsCmd = &cobra.Command{
+	Use:   "origins",
+	Short: "Discover origin servers of hosts behind a CDN",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan origins
+
+Discover origin servers of hosts behind a CDN.
+
+Ports that were detected as CDN fronted (for example by 'scan naabu') record the
+hostname that resolved to them. For each of those hostnames, candidate origin
+IPs are suggested using:
+
+1. **Historical DNS** A records from passive DNS providers such as SecurityTrails
+2. **Favicon hash pivots**, searching Shodan for hosts serving the same favicon
+3. **SSL certificate matching**, searching Shodan for certificates naming the host
+
+Unless --no-probe is set, every candidate is then probed directly, requesting
+the hostname's page from the candidate IP. Candidates serving the same page as
+the CDN are marked as verified.
+
+Candidates are stored in the database, linked to the CDN fronted ports. Shodan
+pivots need SHODAN_API_KEY and historical DNS needs a provider key such as
+SECURITYTRAILS_API_KEY, set in the environment or a .env file. Sources without
+a key are skipped.`)),
+	Example: ascii.Markdown(`
+- gowitness scan origins --write-db
+- gowitness scan origins --write-db --scan-session-id 2
+- gowitness scan origins --write-db --hostname www.example.com --no-probe`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for origin discovery")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		client, err := shodan.InitFromEnv()
+		if err != nil {
+			log.Warn("shodan pivots are disabled", "err", err)
+		}
+
+		history := passivedns.HistoryProvidersFromEnv()
+		if client == nil && len(history) == 0 {
+			return errors.New("no origin sources are configured. set SHODAN_API_KEY and/or SECURITYTRAILS_API_KEY")
+		}
+
+		discoverer := origin.NewDiscoverer(slog.New(log.Logger), client, history)
+		discoverer.MaxPerSource = originsCmdOptions.MaxPerSource
+		discoverer.Timeout = time.Duration(originsCmdOptions.Timeout) * time.Second
+
+		return discoverOrigins(db, discoverer)
+	},
+}
+
+// discoverOrigins suggests origin candidates for every hostname with CDN
+// fronted ports, saving them against those ports
+func discoverOrigins(db *gorm.DB, discoverer *origin.Discoverer) error {
+	query := db.Where("is_cdn = ? AND original_host <> ''", true)
+	if originsCmdOptions.Hostname != "" {
+		query = query.Where("original_host = ?", originsCmdOptions.Hostname)
+	}
+	if originsCmdOptions.ScanSessionID > 0 {
+		query = query.Where("scan_session_id = ?", originsCmdOptions.ScanSessionID)
+	}
+
+	var ports []models.IPPort
+	if err := query.Find(&ports).Error; err != nil {
+		return fmt.Errorf("failed to get cdn fronted ports: %w", err)
+	}
+	if len(ports) == 0 {
+		log.Warn("no cdn fronted ports found. run a port scan with cdn detection first")
+		return nil
+	}
+
+	// CDN edges are never origins
+	edges := make(map[string]bool)
+	var cdnPorts []models.IPPort
+	if err := db.Select("ip_address").Where("is_cdn = ?", true).Find(&cdnPorts).Error; err != nil {
+		return fmt.Errorf("failed to get cdn ips: %w", err)
+	}
+	for _, port := range cdnPorts {
+		edges[port.IPAddress] = true
+	}
+
+	byHost := make(map[string][]models.IPPort)
+	var hostnames []string
+	for _, port := range ports {
+		if _, ok := byHost[port.OriginalHost]; !ok {
+			hostnames = append(hostnames, port.OriginalHost)
+		}
+		byHost[port.OriginalHost] = append(byHost[port.OriginalHost], port)
+	}
+
+	log.Info("discovering origins", "hostnames", len(hostnames), "ports", len(ports))
+
+	var total, verified int
+	for _, hostname := range hostnames {
+		exclude := make(map[string]bool, len(edges))
+		for ip := range edges {
+			exclude[ip] = true
+		}
+		if addrs, err := net.LookupHost(hostname); err == nil {
+			for _, addr := range addrs {
+				exclude[addr] = true
+			}
+		}
+
+		candidates := discoverer.Discover(hostname, exclude)
+		if len(candidates) == 0 {
+			log.Info("no origin candidates found", "hostname", hostname)
+			continue
+		}
+
+		var probes map[string]*origin.Probe
+		if !originsCmdOptions.NoProbe {
+			var err error
+			probes, err = discoverer.Verify(hostname, candidates)
+			if err != nil {
+				log.Warn("could not probe origin candidates", "hostname", hostname, "err", err)
+			}
+		}
+
+		for _, candidate := range candidates {
+			probe := probes[candidate.IP]
+			if probe != nil && probe.Verified {
+				log.Info("verified origin", "hostname", hostname, "ip", candidate.IP,
+					"source", candidate.Source, "evidence", probe.Evidence)
+			}
+
+			for _, port := range byHost[hostname] {
+				if err := saveOriginCandidate(db, port, candidate, probe); err != nil {
+					log.Warn("failed to save origin candidate", "hostname", hostname, "ip", candidate.IP, "err", err)
+				}
+			}
+		}
+
+		total += len(candidates)
+		for _, probe := range probes {
+			if probe.Verified {
+				verified++
+			}
+		}
+
+		log.Info("origin candidates found", "hostname", hostname, "candidates", len(candidates))
+	}
+
+	log.Info("origin discovery completed", "hostnames", len(hostnames), "candidates", total, "verified", verified)
+	return nil
+}
+
+// saveOriginCandidate records a candidate against a CDN fronted port,
+// updating the probe outcome if the candidate is already known.
+func saveOriginCandidate(db *gorm.DB, port models.IPPort, candidate origin.Candidate, probe *origin.Probe) error {
+	var existing models.OriginCandidate
+	err := db.Where("ip_port_id = ? AND ip_address = ? AND source = ?",
+		port.ID, candidate.IP, candidate.Source).First(&existing).Error
+	if err == nil {
+		if probe == nil {
+			return nil
+		}
+		return db.Model(&existing).Updates(map[string]interface{}{
+			"probed":         true,
+			"verified":       probe.Verified,
+			"probe_evidence": probe.Evidence,
+		}).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	record := &models.OriginCandidate{
+		IPPortID:      port.ID,
+		Hostname:      candidate.Hostname,
+		IPAddress:     candidate.IP,
+		Source:        candidate.Source,
+		Evidence:      candidate.Evidence,
+		ScanSessionID: port.ScanSessionID,
+	}
+	if probe != nil {
+		record.Probed = true
+		record.Verified = probe.Verified
+		record.ProbeEvidence = probe.Evidence
+	}
+
+	return db.Create(record).Error
+}
+
+func init() {
+	scanCmd.AddCommand(originsCmd)
+
+	originsCmd.Flags().StringVar(&originsCmdOptions.Hostname, "hostname", "", "Only discover origins for this hostname")
+	originsCmd.Flags().UintVar(&originsCmdOptions.ScanSessionID, "scan-session-id", 0, "Only discover origins for CDN fronted ports of this scan session")
+	originsCmd.Flags().IntVar(&originsCmdOptions.MaxPerSource, "max-per-source", 50, "The maximum number of candidates a Shodan pivot may return per hostname")
+	originsCmd.Flags().IntVar(&originsCmdOptions.Timeout, "probe-timeout", 10, "Number of seconds before a direct probe times out")
+	originsCmd.Flags().BoolVar(&originsCmdOptions.NoProbe, "no-probe", false, "Do not probe candidates directly")
+}
@@ -0,0 +1,318 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/paths"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var pathsCmdOptions = struct {
+	Source        string
+	Wordlist      string
+	ScanSessionID uint
+	Threads       int
+	Timeout       int
+	StatusCodes   []int
+	NoScreenshot  bool
+}{}
+
+var pathsCmd = &cobra.Command{
+	Use:   "paths",
+	Short: "Brute force common paths on the servers of probed results",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan paths
+
+Brute force common paths on the servers of probed results.
+
+Every path in the wordlist (a built in list of paths such as /.git/, /admin and
+/backup.zip by default) is requested on the server of each live result. The
+URLs to brute force are read from --file, and must have been probed before.
+Without --file, the servers of all live results are brute forced.
+
+Servers that answer every path are recognised by first requesting a path that
+can't exist. Paths answering the same way are not reported.
+
+Paths that exist are stored against the result they were found on. Paths that
+answer with a 2xx response are screenshotted as well, unless --no-screenshot
+is set.`)),
+	Example: ascii.Markdown(`
+- gowitness scan paths --write-db
+- gowitness scan paths -f urls.txt -w wordlist.txt --write-db
+- gowitness scan paths --write-db --scan-session-id 2 --status 200 --status 401`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for path brute forcing")
+		}
+
+		if pathsCmdOptions.Source != "" && pathsCmdOptions.Source != "-" && !islazy.FileExists(pathsCmdOptions.Source) {
+			return errors.New("source is not readable")
+		}
+
+		if pathsCmdOptions.Wordlist != "" && !islazy.FileExists(pathsCmdOptions.Wordlist) {
+			return errors.New("wordlist is not readable")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		wordlist := paths.DefaultWordlist
+		if pathsCmdOptions.Wordlist != "" {
+			if wordlist, err = paths.LoadWordlist(pathsCmdOptions.Wordlist); err != nil {
+				return fmt.Errorf("failed to load wordlist: %w", err)
+			}
+			if len(wordlist) == 0 {
+				return errors.New("the wordlist is empty")
+			}
+		}
+
+		prober := paths.NewProber(slog.New(log.Logger))
+		prober.Concurrency = pathsCmdOptions.Threads
+		prober.Timeout = time.Duration(pathsCmdOptions.Timeout) * time.Second
+		prober.UserAgent = opts.Chrome.UserAgent
+		if len(pathsCmdOptions.StatusCodes) > 0 {
+			prober.StatusCodes = pathsCmdOptions.StatusCodes
+		}
+
+		return bruteForcePaths(db, prober, wordlist)
+	},
+}
+
+// bruteForcePaths probes the wordlist on the server of every live result,
+// saving the paths that exist and screenshotting the interesting ones
+func bruteForcePaths(db *gorm.DB, prober *paths.Prober, wordlist []string) error {
+	targets, err := pathsTargets(db)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		log.Warn("no live results to brute force. probe some targets first")
+		return nil
+	}
+
+	log.Info("brute forcing paths", "servers", len(targets), "paths", len(wordlist))
+
+	var found int
+	var screenshots []string
+	for _, target := range targets {
+		hits, err := prober.Probe(target.base, wordlist)
+		if err != nil {
+			log.Warn("failed to brute force paths", "url", target.base, "err", err)
+			continue
+		}
+
+		for _, hit := range hits {
+			log.Info("found path", "url", hit.URL, "status", hit.StatusCode, "length", hit.ContentLength)
+
+			path, err := saveDiscoveredPath(db, target.result, hit)
+			if err != nil {
+				log.Warn("failed to save path", "url", hit.URL, "err", err)
+				continue
+			}
+
+			if hit.StatusCode >= 200 && hit.StatusCode < 300 && path.ScreenshotResultID == nil {
+				screenshots = append(screenshots, hit.URL)
+			}
+		}
+
+		found += len(hits)
+	}
+
+	log.Info("path brute forcing completed", "servers", len(targets), "found", found)
+
+	if pathsCmdOptions.NoScreenshot || len(screenshots) == 0 {
+		return nil
+	}
+
+	log.Info("screenshotting found paths", "paths", len(screenshots))
+	go func() {
+		for _, target := range screenshots {
+			scanRunner.Targets <- target
+		}
+		close(scanRunner.Targets)
+	}()
+
+	scanRunner.Run()
+	scanRunner.Close()
+
+	return linkPathScreenshots(db, screenshots)
+}
+
+// pathsTarget is a server to brute force and the result it was probed as
+type pathsTarget struct {
+	base   string
+	result models.Result
+}
+
+// pathsTargets returns the servers to brute force. Servers probed more than
+// once are brute forced once, against the result of their root page if
+// there is one.
+func pathsTargets(db *gorm.DB) ([]pathsTarget, error) {
+	query := db.Model(&models.Result{}).Select("id", "url", "final_url", "scan_session_id").
+		Where("failed = ?", false).Order("id DESC")
+	if pathsCmdOptions.ScanSessionID > 0 {
+		query = query.Where("scan_session_id = ?", pathsCmdOptions.ScanSessionID)
+	}
+
+	if pathsCmdOptions.Source != "" {
+		urls, err := readPathsSource(pathsCmdOptions.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source: %w", err)
+		}
+		if len(urls) == 0 {
+			return nil, nil
+		}
+		query = query.Where("url IN ? OR final_url IN ?", urls, urls)
+	}
+
+	var results []models.Result
+	if err := query.Find(&results).Error; err != nil {
+		return nil, fmt.Errorf("failed to get results: %w", err)
+	}
+
+	var targets []pathsTarget
+	index := make(map[string]int)
+	for _, result := range results {
+		u, err := url.Parse(result.URL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+
+		base := u.Scheme + "://" + u.Host
+		if i, ok := index[base]; ok {
+			if isRootURL(result.URL) && !isRootURL(targets[i].result.URL) {
+				targets[i].result = result
+			}
+			continue
+		}
+
+		index[base] = len(targets)
+		targets = append(targets, pathsTarget{base: base, result: result})
+	}
+
+	return targets, nil
+}
+
+// isRootURL checks if a URL is the root page of a server
+func isRootURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	return u.Path == "" || u.Path == "/"
+}
+
+// readPathsSource reads the URLs to brute force from a file, or stdin
+func readPathsSource(source string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if source != "-" {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var urls []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.Contains(line, "://") {
+			log.Warn("skipping target without a scheme", "target", line)
+			continue
+		}
+		urls = append(urls, line)
+	}
+
+	return urls, scanner.Err()
+}
+
+// saveDiscoveredPath stores a path found on the server of a result, updating
+// it if it was found before
+func saveDiscoveredPath(db *gorm.DB, result models.Result, hit paths.Hit) (*models.DiscoveredPath, error) {
+	path := &models.DiscoveredPath{
+		ResultID:      result.ID,
+		Path:          hit.Path,
+		URL:           hit.URL,
+		StatusCode:    hit.StatusCode,
+		ContentLength: hit.ContentLength,
+		ContentType:   hit.ContentType,
+		Title:         hit.Title,
+		Location:      hit.Location,
+		ScanSessionID: result.ScanSessionID,
+	}
+
+	if err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "result_id"}, {Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"url", "status_code", "content_length", "content_type", "title", "location", "updated_at",
+		}),
+	}).Create(path).Error; err != nil {
+		return nil, err
+	}
+
+	// the screenshot of a path found before is kept
+	var saved models.DiscoveredPath
+	if err := db.Where("result_id = ? AND path = ?", result.ID, hit.Path).First(&saved).Error; err != nil {
+		return nil, err
+	}
+
+	return &saved, nil
+}
+
+// linkPathScreenshots links found paths to the results of their screenshots
+func linkPathScreenshots(db *gorm.DB, urls []string) error {
+	for _, target := range urls {
+		var result models.Result
+		if err := db.Select("id").Where("url = ?", target).Order("id DESC").First(&result).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return fmt.Errorf("failed to get screenshot result: %w", err)
+		}
+
+		if err := db.Model(&models.DiscoveredPath{}).Where("url = ?", target).
+			Update("screenshot_result_id", result.ID).Error; err != nil {
+			return fmt.Errorf("failed to link screenshot: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	scanCmd.AddCommand(pathsCmd)
+
+	pathsCmd.Flags().StringVarP(&pathsCmdOptions.Source, "file", "f", "", "A file with URLs of probed results to brute force. Use - for stdin. Defaults to all live results")
+	pathsCmd.Flags().StringVarP(&pathsCmdOptions.Wordlist, "wordlist", "w", "", "A file with paths to request, one per line. Defaults to a built in list of common paths")
+	pathsCmd.Flags().UintVar(&pathsCmdOptions.ScanSessionID, "scan-session-id", 0, "Only brute force results of this scan session")
+	pathsCmd.Flags().IntVar(&pathsCmdOptions.Threads, "path-threads", 10, "Number of paths to request at once per server")
+	pathsCmd.Flags().IntVar(&pathsCmdOptions.Timeout, "probe-timeout", 10, "Number of seconds before a path request times out")
+	pathsCmd.Flags().IntSliceVar(&pathsCmdOptions.StatusCodes, "status", []int{}, "Response codes to report as found. Defaults to 200, 204, 301, 302, 307, 308, 401 and 403. Supports multiple --status flags")
+	pathsCmd.Flags().BoolVar(&pathsCmdOptions.NoScreenshot, "no-screenshot", false, "Do not screenshot found paths")
+}
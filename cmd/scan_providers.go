@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/enrich"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+var providersCmdOptions = struct {
+	ScanSessionID uint
+	Providers     []string
+	SkipDomains   bool
+	SkipIPs       bool
+}{}
+
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "Look up IP addresses and domains with enrichment providers",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan providers
+
+Look up IP addresses and domains with enrichment providers.
+
+Providers are intel sources beyond the built in Shodan, InternetDB and IP-API
+lookups. Every IP address with IP information or open ports, and every known
+domain, is passed to each provider, paced by the rate limit the provider
+asks for. The fields and findings a provider returns are saved under its
+name.
+
+There are two kinds of providers:
+
+- Plugin executables, loaded with --plugin, that subscribe to the **ip** or
+  **domain** hooks in their init response. They receive
+  {"hook":"ip","target":"1.2.3.4"} and answer with fields, tags and findings
+  like the other hooks. A **rate_limit** in the init response sets the most
+  lookups a minute the plugin is sent.
+- Go providers, compiled into a build of gowitness, that implement
+  enrich.Provider and call enrich.Register from an init function.
+
+Providers also look up every IP that 'scan shodan' saves.`)),
+	Example: ascii.Markdown(`
+- gowitness scan providers --write-db --plugin ./greynoise
+- gowitness scan providers --write-db --plugin ./greynoise --plugin ./virustotal --provider virustotal
+- gowitness scan providers --write-db --plugin ./crtsh --skip-ips --scan-session-id 2`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for provider lookups")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		providers := scanProviders()
+		if len(providersCmdOptions.Providers) > 0 {
+			providers = slices.DeleteFunc(providers, func(p enrich.Provider) bool {
+				return !slices.Contains(providersCmdOptions.Providers, p.Name())
+			})
+		}
+		if len(providers) == 0 {
+			return errors.New("no enrichment providers to run. load plugins with --plugin")
+		}
+
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		var scanSessionID *uint
+		if providersCmdOptions.ScanSessionID > 0 {
+			scanSessionID = &providersCmdOptions.ScanSessionID
+		}
+
+		ctx, stop := interruptContext()
+		defer stop()
+
+		runner := enrich.NewProviders(slog.New(log.Logger), providers)
+		log.Info("running enrichment providers", "providers", runner.Names())
+
+		var targets []enrich.Target
+		if !providersCmdOptions.SkipIPs {
+			ips, err := providerIPs(db)
+			if err != nil {
+				return err
+			}
+			for _, ip := range ips {
+				targets = append(targets, enrich.Target{Kind: enrich.TargetIP, Value: ip})
+			}
+		}
+		if !providersCmdOptions.SkipDomains {
+			domains, err := database.KnownDomains(db)
+			if err != nil {
+				return fmt.Errorf("failed to get domains: %w", err)
+			}
+			for _, domain := range domains {
+				targets = append(targets, enrich.Target{Kind: enrich.TargetDomain, Value: domain})
+			}
+		}
+
+		progress := startScanProgress("Provider lookups", len(targets))
+		defer stopScanProgress(progress)
+
+		var saved int
+		for _, target := range targets {
+			if ctx.Err() != nil {
+				log.Warn("interrupted, stopping provider lookups")
+				break
+			}
+
+			runner.Enrich(ctx, target, func(source string, out *plugins.Output) error {
+				saved++
+				progress.Count(source)
+				if target.Kind == enrich.TargetDomain {
+					return plugins.SaveDomain(db, source, target.Value, scanSessionID, out)
+				}
+				return plugins.Save(db, source, nil, target.Value, scanSessionID, out)
+			})
+			progress.Increment("")
+		}
+
+		log.Info("provider lookups completed", "targets", len(targets), "saved", saved)
+		return nil
+	},
+}
+
+// scanProviders returns the registered enrichment providers and the plugins
+// loaded with --plugin that are providers
+func scanProviders() []enrich.Provider {
+	return append(enrich.Registered(), enrich.PluginProviders(scanPlugins)...)
+}
+
+// providerIPs returns the IP addresses with IP information or open ports
+func providerIPs(db *gorm.DB) ([]string, error) {
+	var ips []string
+	if err := db.Raw(`SELECT ip_address FROM ip_infos WHERE ip_address != ''
+		UNION SELECT ip_address FROM ip_ports WHERE ip_address != ''`).
+		Scan(&ips).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ip addresses: %w", err)
+	}
+
+	return ips, nil
+}
+
+func init() {
+	scanCmd.AddCommand(providersCmd)
+
+	providersCmd.Flags().UintVar(&providersCmdOptions.ScanSessionID, "scan-session-id", 0, "Scan session to record the provider output under")
+	providersCmd.Flags().StringSliceVar(&providersCmdOptions.Providers, "provider", []string{}, "Only run the providers with this name. Supports multiple --provider flags")
+	providersCmd.Flags().BoolVar(&providersCmdOptions.SkipDomains, "skip-domains", false, "Don't look up domains")
+	providersCmd.Flags().BoolVar(&providersCmdOptions.SkipIPs, "skip-ips", false, "Don't look up IP addresses")
+}
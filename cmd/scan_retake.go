@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+var retakeCmdOptions = struct {
+	ID uint
+}{}
+
+var retakeCmd = &cobra.Command{
+	Use:   "retake",
+	Short: "Retake the screenshot of a result",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan retake
+
+Retake the screenshot of a result.
+
+The URL of the result is probed again and a fresh screenshot is captured. It
+is stored as a new result, in the same scan session, that links back to the
+result it retakes with previous_result_id. The old result is kept, so the
+two can be compared.`)),
+	Example: ascii.Markdown(`
+- gowitness scan retake --write-db --id 5
+- gowitness scan retake --write-db --id 5 --screenshot-fullpage`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required to retake a result")
+		}
+
+		if retakeCmdOptions.ID == 0 {
+			return errors.New("a result --id must be specified")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		var previous models.Result
+		if err := db.Select("id", "url", "scan_session_id").First(&previous, retakeCmdOptions.ID).Error; err != nil {
+			return fmt.Errorf("failed to get result %d: %w", retakeCmdOptions.ID, err)
+		}
+
+		logger := slog.New(log.Logger)
+		result, err := runner.Retake(logger, scanDriver, *opts, &previous, scanWriters)
+		if err != nil {
+			return err
+		}
+
+		log.Info("retook result", "id", previous.ID, "new-id", result.ID, "url", previous.URL)
+		return nil
+	},
+}
+
+func init() {
+	scanCmd.AddCommand(retakeCmd)
+
+	retakeCmd.Flags().UintVar(&retakeCmdOptions.ID, "id", 0, "The ID of the result to retake")
+}
@@ -1,14 +1,19 @@
 package cmd
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/gowitness"
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +24,21 @@ var runCmdOptions = struct {
 	ProjectName string // Project name for status updates
 	SkipShodan  bool   // Skip Shodan scan
 	SkipScreens bool   // Skip screenshot collection
+	SkipAlerts  bool   // Skip alert evaluation
+	AlertRules  string // Alert rules file
+	AlertHook   string // Webhook to deliver alerts to
+
+	// Per-phase resource limits
+	ProbeThreads  int  // Threads for the screenshot phase
+	ProbeAutoTune bool // Autotune threads for the screenshot phase
+	PortscanRate  int  // Rate limit (per minute) for the Shodan phase
+	MaxMemory     int  // Heap size in MB to pause new probes at
+	MaxOpenFiles  int  // Open file descriptors to pause new probes at
+
+	Robots        bool // Collect robots.txt and sitemap.xml files in the screenshot phase
+	RobotsEnqueue bool // Screenshot the URLs those files name as well
+
+	ProbeDiscoveredPorts bool // Screenshot the open ports port scans found as well
 }{}
 
 var runCmd = &cobra.Command{
@@ -27,12 +47,13 @@ var runCmd = &cobra.Command{
 	Long: ascii.LogoHelp(ascii.Markdown(`
 # scan run
 
-Execute a complete scan workflow for a project directory containing a domains.txt file.
+Execute a complete scan workflow for a project directory.
 This command orchestrates multiple gowitness commands in sequence:
 
 1. **Shodan Intelligence Gathering**: Query Shodan API for IP information with fallback
 2. **Screenshot Collection**: Capture website screenshots for all discovered domains
-3. **Database Updates**: Update project status and completion tracking
+3. **Alerts**: Raise alerts on newly exposed ports and subdomains, see 'scan alerts'
+4. **Database Updates**: Update project status and completion tracking
 
 The command expects a project directory structure like:
 - targets/project_name/
@@ -40,13 +61,44 @@ The command expects a project directory structure like:
   - project_name.sqlite3 (database file)
   - screenshots/ (screenshot output directory)
 
-Status updates are logged to the console for monitoring.
+The project database is the source of truth for the domains that are scanned.
+Before the phases run, the hostnames in domains.txt are recorded in it as
+manually added domains, next to the domains discovered by 'scan domains',
+'scan ct' and Shodan. Every domain a source still considers active, along with
+any IPs and CIDRs in domains.txt, is then written to targets.txt in the project
+directory, which the phases scan.
+
+Status updates are logged to the console for monitoring, while the output of
+each phase is written to a log file in the project's logs/ directory. The
+--log-level and --log-format flags are passed on to every phase. When all phases
+finish, the project's active scan session is marked as completed. If a phase
+fails, the session is marked as failed, recording the phase and its error.
+
+With --probe-discovered-ports, the open ports that Shodan and earlier port
+scans recorded in the project database (such as 8080, 8443 or 3000) are
+screenshotted as well. Candidate http and https URLs are added to targets.txt
+for each port, on its IP and every hostname known to resolve to that IP. Ports
+80 and 443, and ports of services that do not speak HTTP such as SSH, are left
+out.
+
+With --probe-autotune, the screenshot phase backs off when probes fail and
+ramps back up when they succeed, and --max-memory and --max-open-files pause
+new probes until running ones finish. These only apply to the screenshot
+phase. The Shodan phase is limited by --portscan-rate, and naabu port scans
+are not autotuned, so set their --rate and --threads by hand.
+
+Alerts are only recorded, not delivered, on a project's first scan, so that
+later scans alert on what changed. Failing to evaluate alerts does not fail
+the scan.
 `)),
 	Example: ascii.Markdown(`
 - gowitness scan run -p targets/company_name/
-- gowitness scan run -p targets/demo_project/ --project demo_project --verbose
+- gowitness scan run -p targets/demo_project/ --project demo_project --log-level debug
 - gowitness scan run -p targets/example/ --skip-shodan  # Screenshots only
-- gowitness scan run -p targets/test/ --skip-screens    # Shodan only`),
+- gowitness scan run -p targets/test/ --skip-screens    # Shodan only
+- gowitness scan run -p targets/big/ --probe-threads 30 --probe-autotune --max-memory 2048
+- gowitness scan run -p targets/example/ --alert-webhook https://hooks.slack.com/services/...
+- gowitness scan run -p targets/example/ --probe-discovered-ports`),
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		if runCmdOptions.ProjectPath == "" {
 			return errors.New("project path must be specified with -p/--path")
@@ -57,10 +109,12 @@ Status updates are logged to the console for monitoring.
 			return fmt.Errorf("project directory does not exist: %s", runCmdOptions.ProjectPath)
 		}
 
-		// Check if domains.txt exists
+		// Check if there are domains, in domains.txt or the database
 		domainsFile := filepath.Join(runCmdOptions.ProjectPath, "domains.txt")
 		if _, err := os.Stat(domainsFile); os.IsNotExist(err) {
-			return fmt.Errorf("domains.txt file not found in project directory: %s", domainsFile)
+			if _, err := os.Stat(projectDatabaseFile(runCmdOptions.ProjectPath)); os.IsNotExist(err) {
+				return fmt.Errorf("domains.txt file not found in project directory: %s", domainsFile)
+			}
 		}
 
 		return nil
@@ -104,6 +158,11 @@ type ScanPhase struct {
 func executeFullScanWorkflow(projectPath, projectName string) error {
 	log.Info("executing full scan workflow", "project", projectName, "path", projectPath)
 
+	if err := writeProjectTargets(projectPath); err != nil {
+		finishProjectSession(projectPath, "Targets", err)
+		return err
+	}
+
 	// Define scan phases
 	phases := []ScanPhase{
 		{
@@ -134,6 +193,7 @@ func executeFullScanWorkflow(projectPath, projectName string) error {
 		if err != nil {
 			log.Error("scan phase failed", "phase", phase.Name, "error", err)
 			updateRunProjectStatus(projectName, fmt.Sprintf("Error - (%s failed)", phase.StatusName))
+			finishProjectSession(projectPath, phase.Name, err)
 			return fmt.Errorf("scan phase '%s' failed: %w", phase.Name, err)
 		}
 
@@ -144,36 +204,97 @@ func executeFullScanWorkflow(projectPath, projectName string) error {
 		time.Sleep(1 * time.Second)
 	}
 
+	if !runCmdOptions.SkipAlerts {
+		evaluateProjectAlerts(projectPath)
+	}
+
+	finishProjectSession(projectPath, "", nil)
 	return nil
 }
 
+// evaluateProjectAlerts raises alerts on what the scan found in a project,
+// recording a baseline instead on its first scan
+func evaluateProjectAlerts(projectPath string) {
+	dbFile := projectDatabaseFile(projectPath)
+	if _, err := os.Stat(dbFile); err != nil {
+		return
+	}
+
+	conn, err := database.Connection(fmt.Sprintf("sqlite://%s", dbFile), true, false)
+	if err != nil {
+		log.Warn("could not evaluate alerts", "database", dbFile, "err", err)
+		return
+	}
+
+	baseline, err := alertsBaselineNeeded(conn)
+	if err != nil {
+		log.Warn("could not evaluate alerts", "database", dbFile, "err", err)
+		return
+	}
+
+	if err := evaluateAlerts(conn, runCmdOptions.AlertRules, runCmdOptions.AlertHook, nil, baseline); err != nil {
+		log.Warn("could not evaluate alerts", "database", dbFile, "err", err)
+	}
+}
+
+// finishProjectSession marks the active scan session of a project as
+// completed, or as failed in phase if phaseErr is set. Projects without a
+// database or an active session are left alone.
+func finishProjectSession(projectPath, phase string, phaseErr error) {
+	dbFile := projectDatabaseFile(projectPath)
+	if _, err := os.Stat(dbFile); err != nil {
+		return
+	}
+
+	conn, err := database.Connection(fmt.Sprintf("sqlite://%s", dbFile), true, false)
+	if err != nil {
+		log.Warn("could not update scan session status", "database", dbFile, "err", err)
+		return
+	}
+
+	session, err := database.LatestActiveSession(conn)
+	if err != nil {
+		log.Warn("could not update scan session status", "database", dbFile, "err", err)
+		return
+	}
+	if session == nil {
+		return
+	}
+
+	if phaseErr != nil {
+		err = database.FailSession(conn, session.ID, phase, phaseErr.Error())
+	} else {
+		err = database.CompleteSession(conn, session.ID)
+	}
+	if err != nil {
+		log.Warn("could not update scan session status", "session-id", session.ID, "err", err)
+		return
+	}
+
+	log.Info("updated scan session status", "session-id", session.ID, "failed-phase", phase)
+}
+
 // executeShodanScan runs the Shodan intelligence gathering phase
 func executeShodanScan(projectPath, projectName string) error {
 	log.Info("executing Shodan scan", "project", projectName)
 
-	domainsFile := filepath.Join(projectPath, "domains.txt")
-	projectDirName := filepath.Base(projectPath)
-	dbFile := filepath.Join(projectPath, fmt.Sprintf("%s.sqlite3", projectDirName))
+	targetsFile := filepath.Join(projectPath, "targets.txt")
+	dbFile := projectDatabaseFile(projectPath)
 
 	// Build command arguments
-	args := []string{"scan", "shodan", "-f", domainsFile, "--write-db", "--write-db-uri", fmt.Sprintf("sqlite://%s", dbFile)}
+	args := []string{"scan", "shodan", "-f", targetsFile, "--write-db", "--write-db-uri", fmt.Sprintf("sqlite://%s", dbFile)}
 
-	if runCmdOptions.Verbose {
-		args = append(args, "--verbose")
+	if runCmdOptions.PortscanRate > 0 {
+		args = append(args, "--rate-limit", strconv.Itoa(runCmdOptions.PortscanRate))
 	}
 
 	if projectName != "" {
 		args = append(args, "--project", projectName)
 	}
 
-	// Execute command
-	cmd := exec.Command("./gowitness", args...)
-	cmd.Dir = "." // Run from current directory
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Error("Shodan scan command failed", "error", err, "output", string(output))
-		return fmt.Errorf("shodan scan failed: %s", string(output))
+	if err := runPhaseCommand(projectPath, "shodan", args); err != nil {
+		log.Error("Shodan scan command failed", "error", err)
+		return fmt.Errorf("shodan scan failed: %w", err)
 	}
 
 	log.Info("Shodan scan completed successfully", "project", projectName)
@@ -184,38 +305,177 @@ func executeShodanScan(projectPath, projectName string) error {
 func executeScreenshotScan(projectPath, projectName string) error {
 	log.Info("executing screenshot scan", "project", projectName)
 
-	domainsFile := filepath.Join(projectPath, "domains.txt")
-	projectDirName := filepath.Base(projectPath)
-	dbFile := filepath.Join(projectPath, fmt.Sprintf("%s.sqlite3", projectDirName))
+	targetsFile := filepath.Join(projectPath, "targets.txt")
+	dbFile := projectDatabaseFile(projectPath)
 	screenshotDir := filepath.Join(projectPath, "screenshots")
 
+	if runCmdOptions.ProbeDiscoveredPorts {
+		if err := appendPortTargets(projectPath); err != nil {
+			return err
+		}
+	}
+
 	// Ensure screenshot directory exists
 	if err := os.MkdirAll(screenshotDir, 0755); err != nil {
 		return fmt.Errorf("failed to create screenshot directory: %w", err)
 	}
 
 	// Build command arguments
-	args := []string{"scan", "file", "-f", domainsFile, "--write-db", "--write-db-uri", fmt.Sprintf("sqlite://%s", dbFile), "--screenshot-path", screenshotDir}
+	args := []string{"scan", "file", "-f", targetsFile, "--write-db", "--write-db-uri", fmt.Sprintf("sqlite://%s", dbFile), "--screenshot-path", screenshotDir}
 
-	if runCmdOptions.Verbose {
-		args = append(args, "--debug-log")
+	if runCmdOptions.ProbeThreads > 0 {
+		args = append(args, "--threads", strconv.Itoa(runCmdOptions.ProbeThreads))
+	}
+
+	if runCmdOptions.ProbeAutoTune {
+		args = append(args, "--autotune")
+	}
+
+	if runCmdOptions.MaxMemory > 0 {
+		args = append(args, "--max-memory", strconv.Itoa(runCmdOptions.MaxMemory))
+	}
+
+	if runCmdOptions.MaxOpenFiles > 0 {
+		args = append(args, "--max-open-files", strconv.Itoa(runCmdOptions.MaxOpenFiles))
+	}
+
+	if runCmdOptions.RobotsEnqueue {
+		args = append(args, "--robots-enqueue")
+	} else if runCmdOptions.Robots {
+		args = append(args, "--robots")
 	}
 
 	if projectName != "" {
 		args = append(args, "--project", projectName)
 	}
 
-	// Execute command
+	if err := runPhaseCommand(projectPath, "screenshots", args); err != nil {
+		log.Error("screenshot scan command failed", "error", err)
+		return fmt.Errorf("screenshot scan failed: %w", err)
+	}
+
+	log.Info("screenshot scan completed successfully", "project", projectName)
+	return nil
+}
+
+// projectDatabaseFile returns the path of a project's sqlite database
+func projectDatabaseFile(projectPath string) string {
+	return filepath.Join(projectPath, fmt.Sprintf("%s.sqlite3", filepath.Base(projectPath)))
+}
+
+// writeProjectTargets records the hostnames in a project's domains.txt as
+// manual domains, then writes every known domain of the project database,
+// and the IPs and CIDRs of domains.txt, to the project's targets.txt
+func writeProjectTargets(projectPath string) error {
+	conn, err := database.Connection(fmt.Sprintf("sqlite://%s", projectDatabaseFile(projectPath)), false, false)
+	if err != nil {
+		return fmt.Errorf("failed to open project database: %w", err)
+	}
+
+	var scanSessionID *uint
+	if session, err := database.LatestActiveSession(conn); err == nil && session != nil {
+		scanSessionID = &session.ID
+	}
+
+	var lines []string
+	domainsFile := filepath.Join(projectPath, "domains.txt")
+	if file, err := os.Open(domainsFile); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read domains.txt: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to open domains.txt: %w", err)
+	}
+
+	targets, err := gowitness.ProjectTargets(conn, lines, scanSessionID)
+	if err != nil {
+		return err
+	}
+
+	targetsFile := filepath.Join(projectPath, "targets.txt")
+	if err := os.WriteFile(targetsFile, []byte(strings.Join(targets, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write targets file: %w", err)
+	}
+
+	log.Info("wrote project targets", "file", targetsFile, "targets", len(targets))
+
+	return nil
+}
+
+// appendPortTargets adds candidate URLs for the open ports recorded in a
+// project database to the project's targets.txt
+func appendPortTargets(projectPath string) error {
+	conn, err := database.Connection(fmt.Sprintf("sqlite://%s", projectDatabaseFile(projectPath)), true, false)
+	if err != nil {
+		return fmt.Errorf("failed to open project database: %w", err)
+	}
+
+	urls, err := database.PortURLs(conn)
+	if err != nil {
+		return fmt.Errorf("failed to get discovered ports: %w", err)
+	}
+	if len(urls) == 0 {
+		log.Info("no discovered ports to probe")
+		return nil
+	}
+
+	targetsFile := filepath.Join(projectPath, "targets.txt")
+	file, err := os.OpenFile(targetsFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open targets file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(strings.Join(urls, "\n") + "\n"); err != nil {
+		return fmt.Errorf("failed to write targets file: %w", err)
+	}
+
+	log.Info("added discovered port targets", "file", targetsFile, "urls", len(urls))
+
+	return nil
+}
+
+// runPhaseCommand runs a gowitness command for a scan phase. The logging
+// flags are passed on, and the command's output is written to the phase's
+// log file in the project's logs directory.
+func runPhaseCommand(projectPath, phase string, args []string) error {
+	level := opts.Logging.Level
+	if log.DebugEnabled() {
+		level = "debug"
+	}
+	args = append(args, "--log-level", level, "--log-format", opts.Logging.Format)
+	if opts.Logging.Silence {
+		args = append(args, "--quiet")
+	}
+
+	logDir := filepath.Join(projectPath, "logs")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return fmt.Errorf("failed to create logs directory: %w", err)
+	}
+
+	logFile := filepath.Join(logDir, phase+".log")
+	file, err := os.Create(logFile)
+	if err != nil {
+		return fmt.Errorf("failed to create phase log file: %w", err)
+	}
+	defer file.Close()
+
+	log.Info("writing phase log", "phase", phase, "file", logFile)
+
 	cmd := exec.Command("./gowitness", args...)
 	cmd.Dir = "." // Run from current directory
+	cmd.Stdout = file
+	cmd.Stderr = file
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Error("screenshot scan command failed", "error", err, "output", string(output))
-		return fmt.Errorf("screenshot scan failed: %s", string(output))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w, see %s", err, logFile)
 	}
 
-	log.Info("screenshot scan completed successfully", "project", projectName)
 	return nil
 }
 
@@ -234,7 +494,19 @@ func init() {
 
 	runCmd.Flags().StringVarP(&runCmdOptions.ProjectPath, "path", "p", "", "Path to the project directory")
 	runCmd.Flags().BoolVarP(&runCmdOptions.Verbose, "verbose", "v", false, "Enable verbose output")
+	runCmd.Flags().MarkDeprecated("verbose", "use --log-level debug instead")
 	runCmd.Flags().StringVar(&runCmdOptions.ProjectName, "project", "", "Project name for status tracking")
 	runCmd.Flags().BoolVar(&runCmdOptions.SkipShodan, "skip-shodan", false, "Skip Shodan intelligence gathering phase")
 	runCmd.Flags().BoolVar(&runCmdOptions.SkipScreens, "skip-screens", false, "Skip screenshot collection phase")
+	runCmd.Flags().BoolVar(&runCmdOptions.SkipAlerts, "skip-alerts", false, "Skip alert evaluation")
+	runCmd.Flags().StringVar(&runCmdOptions.AlertRules, "alert-rules", "", "JSON file with alert rules (uses the default rules if not set)")
+	runCmd.Flags().StringVar(&runCmdOptions.AlertHook, "alert-webhook", "", "URL to post alerts to (defaults to ALERT_WEBHOOK_URL)")
+	runCmd.Flags().IntVar(&runCmdOptions.ProbeThreads, "probe-threads", 0, "Threads to use for the screenshot phase (0 uses the scan default)")
+	runCmd.Flags().BoolVar(&runCmdOptions.ProbeAutoTune, "probe-autotune", false, "Autotune threads for the screenshot phase, using --probe-threads as the upper bound")
+	runCmd.Flags().IntVar(&runCmdOptions.PortscanRate, "portscan-rate", 0, "Shodan phase API calls per minute (0 uses the scan default)")
+	runCmd.Flags().IntVar(&runCmdOptions.MaxMemory, "max-memory", 0, "Heap size in MB at which the screenshot phase pauses new probes (0 to disable)")
+	runCmd.Flags().IntVar(&runCmdOptions.MaxOpenFiles, "max-open-files", 0, "Open file descriptors at which the screenshot phase pauses new probes (0 to disable)")
+	runCmd.Flags().BoolVar(&runCmdOptions.Robots, "robots", false, "Collect robots.txt and sitemap.xml files in the screenshot phase")
+	runCmd.Flags().BoolVar(&runCmdOptions.RobotsEnqueue, "robots-enqueue", false, "Also screenshot the URLs named in robots.txt and sitemap.xml files")
+	runCmd.Flags().BoolVar(&runCmdOptions.ProbeDiscoveredPorts, "probe-discovered-ports", false, "Also screenshot http and https on the open ports port scans found, for each hostname of their IP")
 }
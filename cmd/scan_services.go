@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/services"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// servicesSource is the source of findings for services that show their
+// screen without authentication
+const servicesSource = "services"
+
+var servicesCmdOptions = struct {
+	IPAddress     string
+	ScanSessionID uint
+	Timeout       int
+	Protocols     string
+}{}
+
+var servicesCmd = &cobra.Command{
+	Use:   "services",
+	Short: "Screenshot VNC and X11 services found by port scans",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan services
+
+Screenshot VNC and X11 services found by port scans.
+
+Open ports in the database (for example from 'scan naabu') are matched to a
+protocol by their service name or well known port: 5900-5909 for VNC and
+6000-6009 for X11. Each one is connected to natively, and if it shows its
+screen without credentials the screen is saved as a PNG alongside the web
+screenshots, linked to the port.
+
+Services that show their screen are also reported as high severity findings.
+Services that need authentication are recorded as failed captures.
+
+RDP ports are recognised, but rendering an RDP login screen needs a full RDP
+client, so they are skipped.`)),
+	Example: ascii.Markdown(`
+- gowitness scan services --write-db
+- gowitness scan services --write-db --scan-session-id 2
+- gowitness scan services --write-db --ip 192.0.2.10 --protocol vnc -s ./screenshots`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for service screenshots")
+		}
+
+		for _, protocol := range strings.Split(servicesCmdOptions.Protocols, ",") {
+			switch strings.TrimSpace(protocol) {
+			case services.VNC, services.X11:
+			default:
+				return fmt.Errorf("unsupported protocol %q, use vnc and/or x11", protocol)
+			}
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		ctx, stop := interruptContext()
+		defer stop()
+
+		return screenshotServices(ctx, db)
+	},
+}
+
+// screenshotServices captures every open port that speaks a selected
+// protocol, saving the screenshots against the ports
+func screenshotServices(ctx context.Context, db *gorm.DB) error {
+	var protocols []string
+	for _, protocol := range strings.Split(servicesCmdOptions.Protocols, ",") {
+		protocols = append(protocols, strings.TrimSpace(protocol))
+	}
+
+	query := db.Where("state = ? AND protocol = ?", "open", "tcp")
+	if servicesCmdOptions.IPAddress != "" {
+		query = query.Where("ip_address = ?", servicesCmdOptions.IPAddress)
+	}
+	if servicesCmdOptions.ScanSessionID > 0 {
+		query = query.Where("scan_session_id = ?", servicesCmdOptions.ScanSessionID)
+	}
+
+	var ports []models.IPPort
+	if err := query.Order("ip_address, port").Find(&ports).Error; err != nil {
+		return fmt.Errorf("failed to get open ports: %w", err)
+	}
+
+	var targets []models.IPPort
+	for _, port := range ports {
+		protocol := services.Protocol(port.Port, port.Service)
+		if protocol == services.RDP {
+			log.Debug("skipping rdp port, rdp screenshots are not supported", "ip", port.IPAddress, "port", port.Port)
+			continue
+		}
+		if slices.Contains(protocols, protocol) {
+			targets = append(targets, port)
+		}
+	}
+	if len(targets) == 0 {
+		log.Warn("no vnc or x11 ports found. run a port scan first")
+		return nil
+	}
+
+	log.Info("screenshotting services", "ports", len(targets))
+
+	timeout := time.Duration(servicesCmdOptions.Timeout) * time.Second
+	var captured, failed int
+	for _, port := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+
+		protocol := services.Protocol(port.Port, port.Service)
+		record := &models.ServiceScreenshot{
+			IPPortID:      port.ID,
+			IPAddress:     port.IPAddress,
+			Port:          port.Port,
+			Protocol:      protocol,
+			ScanSessionID: port.ScanSessionID,
+			CapturedAt:    time.Now(),
+		}
+
+		capture, err := services.Screenshot(ctx, protocol, port.IPAddress, port.Port, timeout)
+		if err == nil {
+			record.Filename = services.Filename(protocol, port.IPAddress, port.Port)
+			err = services.Save(capture, opts.Scan.ScreenshotPath, record.Filename)
+		}
+		if err != nil {
+			log.Debug("could not screenshot service", "ip", port.IPAddress, "port", port.Port, "protocol", protocol, "err", err)
+			record.Filename = ""
+			record.Failed = true
+			record.FailedReason = err.Error()
+			failed++
+		} else {
+			bounds := capture.Image.Bounds()
+			record.Width, record.Height = bounds.Dx(), bounds.Dy()
+			record.Name = capture.Name
+			log.Info("captured service screenshot", "ip", port.IPAddress, "port", port.Port,
+				"protocol", protocol, "file", record.Filename)
+			captured++
+		}
+
+		if err := saveServiceScreenshot(db, record); err != nil {
+			log.Warn("failed to save service screenshot", "ip", port.IPAddress, "port", port.Port, "err", err)
+			continue
+		}
+		if !record.Failed {
+			if err := saveServiceFinding(db, record); err != nil {
+				log.Warn("failed to save service finding", "ip", port.IPAddress, "port", port.Port, "err", err)
+			}
+		}
+	}
+
+	log.Info("service screenshots completed", "ports", len(targets), "captured", captured, "failed", failed)
+	return nil
+}
+
+// saveServiceScreenshot records the latest capture attempt of a port,
+// replacing any earlier one
+func saveServiceScreenshot(db *gorm.DB, record *models.ServiceScreenshot) error {
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "ip_port_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"protocol", "filename", "width", "height", "name",
+			"failed", "failed_reason", "scan_session_id", "captured_at",
+		}),
+	}).Create(record).Error
+}
+
+// saveServiceFinding reports a service that shows its screen without
+// authentication, unless it was reported before
+func saveServiceFinding(db *gorm.DB, record *models.ServiceScreenshot) error {
+	title := fmt.Sprintf("Unauthenticated %s on %s:%d", strings.ToUpper(record.Protocol), record.IPAddress, record.Port)
+
+	var count int64
+	if err := db.Model(&models.Finding{}).
+		Where("source = ? AND title = ? AND ip_address = ?", servicesSource, title, record.IPAddress).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return db.Create(&models.Finding{
+		IPAddress: record.IPAddress,
+		Source:    servicesSource,
+		Title:     title,
+		Severity:  "high",
+		Description: fmt.Sprintf("The %s service on %s:%d shows its screen to anyone who connects, without credentials. Screenshot: %s.",
+			strings.ToUpper(record.Protocol), record.IPAddress, record.Port, record.Filename),
+		ScanSessionID: record.ScanSessionID,
+	}).Error
+}
+
+func init() {
+	scanCmd.AddCommand(servicesCmd)
+
+	servicesCmd.Flags().StringVar(&servicesCmdOptions.IPAddress, "ip", "", "Only screenshot services on this IP address")
+	servicesCmd.Flags().UintVar(&servicesCmdOptions.ScanSessionID, "scan-session-id", 0, "Only screenshot services found by this scan session")
+	servicesCmd.Flags().IntVar(&servicesCmdOptions.Timeout, "timeout", 10, "Number of seconds before a service screenshot times out")
+	servicesCmd.Flags().StringVar(&servicesCmdOptions.Protocols, "protocol", "vnc,x11", "Comma separated protocols to screenshot (vnc, x11)")
+}
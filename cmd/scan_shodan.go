@@ -2,46 +2,70 @@ package cmd
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
-	"net/http"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 
 	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/internal/islazy"
 	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/dns"
+	"github.com/sensepost/gowitness/pkg/enrich"
 	"github.com/sensepost/gowitness/pkg/log"
-	"github.com/sensepost/gowitness/pkg/models"
 	"github.com/sensepost/gowitness/pkg/shodan"
+	"github.com/sensepost/gowitness/pkg/writers"
 	"github.com/spf13/cobra"
 	"gorm.io/gorm"
 )
 
 var shodanCmdOptions = struct {
-	File          string
-	Verbose       bool
-	ScanSessionID uint
-	RateLimit     int    // Rate limit for API calls (per minute)
-	ProjectName   string // Project name for status updates
+	File           string
+	Verbose        bool
+	ScanSessionID  uint
+	RateLimit      int    // Rate limit for API calls (per minute)
+	Threads        int    // Number of IPs enriched concurrently
+	Resume         bool   // Resume from the checkpoint of an interrupted scan
+	ProjectName    string // Project name for status updates
+	ASNs           []string
+	CIDRs          []string
+	MaxIPs         int // Maximum number of IPs to enumerate from an ASN or CIDR
+	Resolvers      string
+	ResolveThreads int
+	// SkipInternetDB queries the full Shodan API without trying InternetDB
+	SkipInternetDB bool
+	// Full stores the services Shodan saw on each port, not just the ports
+	Full bool
+
+	// Refresh re-queries IPs whose information is older than MaxAge
+	Refresh bool
+	MaxAge  string
+	maxAge  time.Duration
 }{}
 
 var shodanCmd = &cobra.Command{
 	Use:   "shodan",
-	Short: "Query Shodan API for IP information with IP-API/naabu fallback",
+	Short: "Query Shodan InternetDB and API for IP information with IP-API/naabu fallback",
 	Long: ascii.LogoHelp(ascii.Markdown(`
 # scan shodan
 
 Query Shodan API for comprehensive IP address information with automatic 
 fallback to IP-API and naabu port scanning when Shodan data is unavailable.
 
-This command takes a list of domains/IPs, resolves them to IP addresses, and:
+This command takes a list of domains/IPs, from a file or piped to stdin,
+resolves them to IP addresses, and:
 
-1. **First tries Shodan API** for detailed information including:
+1. **First tries Shodan InternetDB**, a free endpoint that needs no API key:
+   - Open ports, hostnames, tags and vulnerability information
+   - IP-API.com adds geolocation and ISP information
+   - Uses no Shodan credits, so the API is only queried for IPs it misses
+
+2. **Then tries the Shodan API** for detailed information including:
    - Open ports and services
    - Organization and ISP information  
    - Geographic location
@@ -50,396 +74,377 @@ This command takes a list of domains/IPs, resolves them to IP addresses, and:
    - Hostnames and domains
    - ASN information
 
-2. **Falls back to IP-API + naabu** when Shodan fails or has no data:
+Hostnames are resolved concurrently, using the resolvers in --resolvers if
+set, and all of their A, AAAA and CNAME records are stored. Reverse DNS (PTR)
+records are looked up for every IP, regardless of the source used.
+
+3. **Falls back to IP-API + naabu** when Shodan fails or has no data:
    - IP-API.com for geolocation and ISP information
    - naabu port scanner for open port detection
    - Ensures data is always populated
 
+Instead of a file, targets can be enumerated from an ASN (--asn) or a CIDR
+(--cidr). With a Shodan API key, Shodan search is used to find the responsive
+IPs in the address space. Without one, the ASN's announced BGP prefixes are
+expanded and only IPs where naabu finds an open port are kept. Only IPv4
+address space is enumerated, and expansion stops at --max-ips.
+
 This guarantees that IP intelligence is gathered regardless of Shodan API 
 availability. Shodan requires an API key (SHODAN_API_KEY environment variable), 
 but the command will work without it using fallback methods.
 
-**Note**: Shodan queries consume 1 API credit each. Fallback methods are free.`)),
+Several API keys can be set as a comma separated list in SHODAN_API_KEYS. When
+a key is rate limited or runs out of credits, queries move on to the next one.
+The remaining credits of every key are logged before the scan starts, with a
+warning if there are more IPs to enrich than credits left.
+
+IPs are enriched by a pool of --threads workers. Shodan queries are paced by a
+single limiter shared by all workers, so --rate-limit holds no matter the
+number of threads, while fallback lookups, naabu scans and database writes of
+other IPs overlap with them.
+
+With --write-jsonl, every enriched IP is also written to the --write-jsonl-file
+as a JSON line, such as {"record":"ip_info","source":"shodan","data":{...}}, to
+pipe into jq or other tools. --write-db is optional then, but without a
+database IPs that were enriched before can not be skipped.
+
+IPs that were enriched before are skipped. With --refresh, IPs whose
+information is older than --max-age are queried again and updated in place,
+keeping when they were first seen.
+
+With --scope-domain, --scope-cidr or --scope-exclude, out of scope hosts are
+skipped before they are resolved, and out of scope IPs, resolved or enumerated,
+before they are looked up or port scanned.
+
+If the scan is interrupted with Ctrl-C, the IPs being enriched are finished and
+saved first. The IPs that were not enriched yet are written to a checkpoint
+file, the scan session is marked as cancelled, and --resume picks the scan up
+again where it stopped. Press Ctrl-C twice to exit right away.
+
+**Note**: Shodan API queries consume 1 API credit each. InternetDB and fallback
+methods are free. Use --skip-internetdb to always query the API for its richer
+data, such as the operating system and organization.
+
+With --full, the complete host record is queried instead of the minimal one,
+and the services Shodan saw are stored on their ports: the service, product,
+version, banner, HTTP status and title, and TLS certificate and versions.
+Banners are searchable in the report server. As InternetDB knows nothing about
+services, --full always queries the Shodan API.`)),
 	Example: ascii.Markdown(`
 - gowitness scan shodan -f domains.txt --write-db
 - gowitness scan shodan -f targets.txt --write-db --scan-session-id 1  
-- gowitness scan shodan -f hosts.txt --rate-limit 30 --verbose --write-db
-- gowitness scan shodan -f ips.txt --write-db  # Works without Shodan API key`),
+- gowitness scan shodan -f hosts.txt --rate-limit 30 --log-level debug --write-db
+- gowitness scan shodan -f ips.txt --write-db  # Works without Shodan API key
+- subfinder -d example.com -silent | gowitness scan shodan --write-db
+- gowitness scan shodan --asn AS12345 --write-db --scan-session-id 1
+- gowitness scan shodan --cidr 192.0.2.0/24 --cidr 198.51.100.0/24 --write-db
+- gowitness scan shodan -f domains.txt --refresh --max-age 7d --write-db
+- gowitness scan shodan -f ips.txt --skip-internetdb --write-db
+- gowitness scan shodan -f ips.txt --full --write-db
+- gowitness scan shodan -f ips.txt --write-jsonl --write-jsonl-file shodan.jsonl
+- gowitness scan shodan --resume --write-db`),
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		if shodanCmdOptions.File == "" {
-			return errors.New("a file with domains/IPs must be specified")
+		if !shodanCmdOptions.Resume && shodanCmdOptions.File == "" && len(shodanCmdOptions.ASNs) == 0 && len(shodanCmdOptions.CIDRs) == 0 {
+			// Targets piped in are read without needing -f -
+			if !stdinPiped() {
+				return errors.New("a file with domains/IPs, an --asn or a --cidr must be specified, or targets piped to stdin")
+			}
+			shodanCmdOptions.File = "-"
 		}
 
 		// Check if file exists
-		if _, err := os.Stat(shodanCmdOptions.File); os.IsNotExist(err) {
-			return fmt.Errorf("file does not exist: %s", shodanCmdOptions.File)
+		if shodanCmdOptions.File != "" && shodanCmdOptions.File != "-" {
+			if _, err := os.Stat(shodanCmdOptions.File); os.IsNotExist(err) {
+				return fmt.Errorf("file does not exist: %s", shodanCmdOptions.File)
+			}
 		}
 
-		// Check if database output is specified
-		if !opts.Writer.Db {
-			return errors.New("--write-db flag is required for shodan scans")
+		for _, cidr := range shodanCmdOptions.CIDRs {
+			ip, _, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return fmt.Errorf("invalid cidr %s: %w", cidr, err)
+			}
+			if ip.To4() == nil {
+				return fmt.Errorf("only IPv4 CIDRs are supported: %s", cidr)
+			}
 		}
 
+		// Check if database or JSON lines output is specified
+		if !opts.Writer.Db && !opts.Writer.Jsonl {
+			return errors.New("--write-db or --write-jsonl is required for shodan scans")
+		}
+
+		maxAge, err := islazy.ParseDuration(shodanCmdOptions.MaxAge)
+		if err != nil {
+			return fmt.Errorf("invalid --max-age: %w", err)
+		}
+		shodanCmdOptions.maxAge = maxAge
+
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		log.Info("starting Shodan IP information gathering",
 			"file", shodanCmdOptions.File,
 			"scan-session-id", shodanCmdOptions.ScanSessionID,
-			"rate-limit", shodanCmdOptions.RateLimit)
+			"rate-limit", shodanCmdOptions.RateLimit,
+			"threads", shodanCmdOptions.Threads,
+			"refresh", shodanCmdOptions.Refresh,
+			"skip-internetdb", shodanCmdOptions.SkipInternetDB,
+			"full", shodanCmdOptions.Full)
 
 		// Update project status to running
 		updateProjectStatus(shodanCmdOptions.ProjectName, "Running - (Portscanning)")
 
-		if err := runShodanScan(); err != nil {
+		ctx, stop := interruptContext()
+		defer stop()
+
+		if err := runShodanScan(ctx); err != nil {
 			log.Error("failed to complete Shodan scan", "err", err)
 			// Update status to error
 			updateProjectStatus(shodanCmdOptions.ProjectName, "Error - (Portscanning failed)")
 			return
 		}
 
+		if ctx.Err() != nil {
+			updateProjectStatus(shodanCmdOptions.ProjectName, "Cancelled - (Portscanning)")
+			return
+		}
+
 		// Update status to complete
+		removeCheckpoint("shodan")
 		updateProjectStatus(shodanCmdOptions.ProjectName, "Complete - (Portscanning)")
 		log.Info("Shodan IP information gathering completed successfully")
 	},
 }
 
-// IPAPIResponse represents response from ip-api.com
-type IPAPIResponse struct {
-	Query       string  `json:"query"`
-	Status      string  `json:"status"`
-	Country     string  `json:"country"`
-	CountryCode string  `json:"countryCode"`
-	Region      string  `json:"region"`
-	RegionName  string  `json:"regionName"`
-	City        string  `json:"city"`
-	Zip         string  `json:"zip"`
-	Lat         float64 `json:"lat"`
-	Lon         float64 `json:"lon"`
-	Timezone    string  `json:"timezone"`
-	ISP         string  `json:"isp"`
-	Org         string  `json:"org"`
-	AS          string  `json:"as"`
-	Message     string  `json:"message,omitempty"`
-}
-
-// shodanNaabuResult represents naabu port scan result for shodan command
-type shodanNaabuResult struct {
-	Host string `json:"host"`
-	IP   string `json:"ip"`
-	Port int    `json:"port"`
-}
-
-// fetchIPAPIData fetches geolocation data from ip-api.com as fallback
-func fetchIPAPIData(ip string) (*IPAPIResponse, error) {
-	url := fmt.Sprintf("http://ip-api.com/json/%s?fields=status,message,country,countryCode,region,regionName,city,zip,lat,lon,timezone,isp,org,as,query", ip)
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch from IP-API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+// runShodanScan enriches the IPs of the targets. If ctx is cancelled, the
+// IPs being enriched are finished and the rest are left in a checkpoint.
+func runShodanScan(ctx context.Context) error {
+	// Try to initialize Shodan client - it's OK if this fails, we'll use fallback
+	client, err := shodan.InitFromEnv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read IP-API response: %w", err)
+		log.Warn("failed to initialize Shodan client, will use fallback methods", "err", err)
+		client = nil // Explicitly set to nil for clarity
+	} else {
+		log.Info("Shodan client initialized successfully")
+		logShodanKeys(client)
 	}
 
-	var ipApiResp IPAPIResponse
-	if err := json.Unmarshal(body, &ipApiResp); err != nil {
-		return nil, fmt.Errorf("failed to parse IP-API response: %w", err)
+	// Connect to database
+	var db *gorm.DB
+	if opts.Writer.Db {
+		db, err = database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
 	}
 
-	if ipApiResp.Status == "fail" {
-		return nil, fmt.Errorf("IP-API error: %s", ipApiResp.Message)
+	var records *writers.RecordWriter
+	if opts.Writer.Jsonl {
+		records, err = writers.NewRecordWriter(opts.Writer.JsonlFile)
+		if err != nil {
+			return fmt.Errorf("failed to open JSON lines file: %w", err)
+		}
+		defer records.Close()
 	}
 
-	return &ipApiResp, nil
-}
-
-// runNaabuScan runs naabu port scanner for the given IP
-func runNaabuScan(ip string) ([]int, error) {
-	// Check if naabu is available
-	if _, err := exec.LookPath("naabu"); err != nil {
-		return nil, fmt.Errorf("naabu not found: %w", err)
+	enricher := enrich.New(slog.New(log.Logger), client, shodanCmdOptions.RateLimit)
+	enricher.DB = db
+	enricher.Records = records
+	enricher.Full = shodanCmdOptions.Full
+	enricher.Refresh = shodanCmdOptions.Refresh
+	enricher.MaxAge = shodanCmdOptions.maxAge
+	enricher.Threads = shodanCmdOptions.Threads
+	enricher.Plugins = scanPlugins
+	if providers := scanProviders(); len(providers) > 0 {
+		enricher.Providers = enrich.NewProviders(slog.New(log.Logger), providers)
 	}
-
-	// Run naabu with top 100 ports and JSON output
-	cmd := exec.Command("naabu", "-host", ip, "-top-ports", "100", "-json", "-silent")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("naabu execution failed: %w", err)
+	if shodanCmdOptions.SkipInternetDB {
+		enricher.InternetDB = nil
 	}
 
-	// Parse naabu output (JSON lines)
-	ports := []int{}
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		var result shodanNaabuResult
-		if err := json.Unmarshal([]byte(line), &result); err != nil {
-			log.Warn("failed to parse naabu line", "line", line, "err", err)
-			continue
-		}
-
-		if result.IP == ip {
-			ports = append(ports, result.Port)
-		}
+	// Collect the IPs to enrich, or take the ones an interrupted scan left
+	var ips []string
+	var unverified map[string]bool
+	if shodanCmdOptions.Resume {
+		ips, unverified, err = resumeShodanTargets(db)
+	} else {
+		ips, unverified, err = collectShodanTargets(enricher)
 	}
-
-	return ports, nil
-}
-
-// createFallbackIPInfo creates IP info from fallback sources
-func createFallbackIPInfo(db *gorm.DB, ip string) (*models.IPInfo, error) {
-	log.Info("attempting fallback IP intelligence gathering", "ip", ip)
-
-	// Try IP-API for geolocation
-	ipApiData, err := fetchIPAPIData(ip)
 	if err != nil {
-		log.Warn("failed to fetch IP-API data", "ip", ip, "err", err)
-		return nil, fmt.Errorf("fallback IP-API failed: %w", err)
+		return err
 	}
 
-	// Try naabu for port scanning
-	ports, err := runNaabuScan(ip)
-	if err != nil {
-		log.Warn("failed to run naabu scan", "ip", ip, "err", err)
-		// Continue without port data - IP-API data is still valuable
-		ports = []int{}
-	} else {
-		log.Info("naabu scan completed", "ip", ip, "ports_found", len(ports))
+	// a resume may pick up the scan session of the checkpoint
+	enricher.ScanSessionID = getValidShodanScanSessionID()
+	enricher.Unverified = unverified
+	if db != nil {
+		enricher.ApexDomains, err = enrich.ApexDomains(db, shodanCmdOptions.ScanSessionID)
+		if err != nil {
+			log.Warn("failed to get apex domains of scan session", "err", err)
+		}
 	}
 
-	// Create IPInfo from fallback data
-	ipInfo := &models.IPInfo{
-		IPAddress:     ip,
-		Organization:  ipApiData.Org,
-		ISP:           ipApiData.ISP,
-		ASN:           ipApiData.AS,
-		Country:       ipApiData.Country,
-		CountryCode:   ipApiData.CountryCode,
-		City:          ipApiData.City,
-		Region:        ipApiData.RegionName,
-		Postal:        ipApiData.Zip,
-		Latitude:      ipApiData.Lat,
-		Longitude:     ipApiData.Lon,
-		LastUpdate:    time.Now(),
-		ScanSessionID: getValidShodanScanSessionID(),
+	log.Info("resolved unique IP addresses", "count", len(ips))
+	if client != nil {
+		if credits := client.QueryCredits(); len(ips) > credits {
+			log.Warn("more IPs to enrich than Shodan query credits left, the rest will use fallback methods",
+				"ips", len(ips), "credits", credits)
+		}
 	}
 
-	// Set ports from naabu scan
-	if len(ports) > 0 {
-		if err := ipInfo.SetPorts(ports); err != nil {
-			log.Warn("failed to set ports for IP info", "ip", ip, "err", err)
+	progress := startScanProgress("shodan", len(ips))
+
+	// IPs that fail once interrupted, e.g. because the signal also stopped
+	// their naabu scan, are retried on resume
+	var summary enrich.Summary
+	var interrupted []string
+	started := enricher.EnrichAll(ctx, ips, func(result enrich.Result) {
+		summary.Add(result)
+		log.Debug("enriched IP", "progress", fmt.Sprintf("%d/%d", summary.Processed, len(ips)))
+
+		switch result.Outcome {
+		case enrich.Saved:
+			progress.Increment("shodan")
+		case enrich.SavedInternetDB:
+			progress.Increment("internetdb")
+		case enrich.SavedFallback:
+			progress.Increment("fallback")
+		case enrich.Skipped:
+			progress.Increment("skipped")
+		case enrich.Failed:
+			progress.Increment("errors")
+			if ctx.Err() != nil {
+				interrupted = append(interrupted, result.IP)
+			}
 		}
-
-		// Also create IPPort entries for consistency with Shodan data
-		if err := createFallbackIPPortEntries(db, ip, ports); err != nil {
-			log.Warn("failed to create IPPort entries for fallback", "ip", ip, "err", err)
+	})
+	stopScanProgress(progress)
+
+	printScanSummary("Shodan scan results",
+		"processed", summary.Processed,
+		"saved", summary.Saved,
+		"refreshed", summary.Refreshed,
+		"skipped", summary.Skipped,
+		"errors", summary.Failed,
+		"internetdb_used", summary.InternetDB,
+		"fallback_used", summary.Fallback,
+		"duration", progress.Elapsed().Round(time.Second).String())
+
+	if ctx.Err() != nil {
+		checkpoint := &scanCheckpoint{
+			Command:       "shodan",
+			ScanSessionID: shodanCmdOptions.ScanSessionID,
+			Remaining:     append(interrupted, ips[started:]...),
+			Resolved:      scanScope.Resolved(),
 		}
-	}
-
-	log.Info("created fallback IP info", "ip", ip, "source", "ip-api+naabu", "org", ipInfo.Organization)
-	return ipInfo, nil
-}
-
-// createFallbackIPPortEntries creates IPPort entries for fallback scan results
-func createFallbackIPPortEntries(db *gorm.DB, ip string, ports []int) error {
-	sessionID := getValidShodanScanSessionID()
-
-	for _, port := range ports {
-		// Check if this IP:Port combination already exists
-		var existing models.IPPort
-		if err := db.Where("ip_address = ? AND port = ?", ip, port).First(&existing).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				// Create new IPPort entry
-				ipPort := models.IPPort{
-					IPAddress:     ip,
-					Port:          port,
-					Protocol:      "tcp", // naabu typically scans TCP ports
-					State:         "open",
-					Service:       "", // No service detection in fallback
-					ScanSessionID: sessionID,
-					IsCDN:         false,
-					CDNDetected:   false,
-				}
-
-				if err := db.Create(&ipPort).Error; err != nil {
-					log.Warn("failed to create fallback IPPort entry", "ip", ip, "port", port, "err", err)
-				}
+		for _, ip := range checkpoint.Remaining {
+			if unverified[ip] {
+				checkpoint.Unverified = append(checkpoint.Unverified, ip)
 			}
 		}
+		interruptScan(db, "Shodan Intelligence", checkpoint)
 	}
 
 	return nil
 }
 
-func runShodanScan() error {
-	// Try to initialize Shodan client - it's OK if this fails, we'll use fallback
-	client, err := shodan.InitFromEnv()
+// resumeShodanTargets returns the IPs the checkpoint of an interrupted scan
+// left, and reactivates its scan session
+func resumeShodanTargets(db *gorm.DB) ([]string, map[string]bool, error) {
+	checkpoint, err := readCheckpoint("shodan")
 	if err != nil {
-		log.Warn("failed to initialize Shodan client, will use fallback methods", "err", err)
-		client = nil // Explicitly set to nil for clarity
-	} else {
-		log.Info("Shodan client initialized successfully")
+		return nil, nil, err
 	}
 
-	// Connect to database
-	db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
-	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+	if shodanCmdOptions.ScanSessionID == 0 {
+		shodanCmdOptions.ScanSessionID = checkpoint.ScanSessionID
 	}
-
-	// Read hosts from file
-	hosts, err := readHostsFromFile(shodanCmdOptions.File)
-	if err != nil {
-		return fmt.Errorf("failed to read hosts from file: %w", err)
+	if db != nil && shodanCmdOptions.ScanSessionID > 0 {
+		if err := database.ReactivateSession(db, shodanCmdOptions.ScanSessionID); err != nil {
+			log.Warn("could not update scan session status", "session-id", shodanCmdOptions.ScanSessionID, "err", err)
+		}
 	}
 
-	// Resolve domains to IPs and deduplicate
-	ips, err := resolveAndDeduplicateIPs(hosts)
-	if err != nil {
-		return fmt.Errorf("failed to resolve IPs: %w", err)
+	unverified := make(map[string]bool)
+	for _, ip := range checkpoint.Unverified {
+		unverified[ip] = true
 	}
 
-	log.Info("resolved unique IP addresses", "count", len(ips))
-
-	// Process each IP with rate limiting
-	var processedCount, savedCount, skippedCount, errorCount, fallbackCount int
-	rateLimiter := time.NewTicker(time.Minute / time.Duration(shodanCmdOptions.RateLimit))
-	defer rateLimiter.Stop()
-
-	for _, ip := range ips {
-		// Rate limiting
-		if processedCount > 0 {
-			<-rateLimiter.C
-		}
-		processedCount++
+	// The scope may have changed since the scan was interrupted
+	for host, ips := range checkpoint.Resolved {
+		scanScope.AddResolved(host, ips...)
+	}
+	remaining, skipped := scanScope.Filter(checkpoint.Remaining)
+	if skipped > 0 {
+		log.Warn("skipping out of scope IPs", "count", skipped)
+	}
 
-		if shodanCmdOptions.Verbose {
-			log.Info("querying Shodan for IP", "ip", ip, "progress", fmt.Sprintf("%d/%d", processedCount, len(ips)))
-		}
+	log.Info("resuming interrupted scan", "interrupted", checkpoint.Interrupted, "remaining", len(remaining))
+	return remaining, unverified, nil
+}
 
-		// Check if we already have this IP in the database
-		var existing models.IPInfo
-		if err := db.Where("ip_address = ?", ip).First(&existing).Error; err == nil {
-			// IP already exists, skip
-			skippedCount++
-			continue
-		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Warn("database error checking existing IP", "ip", ip, "err", err)
-			errorCount++
+// logShodanKeys logs the plan and remaining credits of the Shodan API keys
+func logShodanKeys(client *shodan.Client) {
+	for _, key := range client.Keys() {
+		if key.Err != nil {
+			log.Warn("skipping invalid Shodan API key", "key", key.Key, "err", key.Err)
 			continue
 		}
+		log.Info("Shodan API key", "key", key.Key, "plan", key.Info.Plan,
+			"query-credits", key.Info.QueryCredits, "scan-credits", key.Info.ScanCredits)
+	}
+}
 
-		var ipInfo *models.IPInfo
-		var usedFallback bool
-
-		// Try Shodan first if client is available
-		if client != nil {
-			host, err := client.GetHostMinimal(ip)
-			if err != nil {
-				log.Warn("failed to query Shodan for IP", "ip", ip, "err", err)
-				// ipInfo remains nil, will trigger fallback
-			} else {
-				// Successfully got Shodan data
-				ipInfo = &models.IPInfo{
-					IPAddress:     host.IP,
-					Organization:  host.Organization,
-					ISP:           host.ISP,
-					ASN:           host.ASN,
-					Country:       host.Country,
-					CountryCode:   host.CountryCode,
-					City:          host.City,
-					Region:        host.Region,
-					Postal:        host.Postal,
-					Latitude:      host.Latitude,
-					Longitude:     host.Longitude,
-					OS:            host.OS,
-					LastUpdate:    host.LastUpdate.Time,
-					ScanSessionID: getValidShodanScanSessionID(),
-				}
-
-				// Set array fields using helper methods
-				if err := ipInfo.SetTags(host.Tags); err != nil {
-					log.Warn("failed to set tags for IP", "ip", ip, "err", err)
-				}
-				if err := ipInfo.SetPorts(host.Ports); err != nil {
-					log.Warn("failed to set ports for IP", "ip", ip, "err", err)
-				}
-				if err := ipInfo.SetHostnames(host.Hostnames); err != nil {
-					log.Warn("failed to set hostnames for IP", "ip", ip, "err", err)
-				}
-				if err := ipInfo.SetDomains(host.Domains); err != nil {
-					log.Warn("failed to set domains for IP", "ip", ip, "err", err)
-				}
-				if err := ipInfo.SetVulns(host.Vulns); err != nil {
-					log.Warn("failed to set vulnerabilities for IP", "ip", ip, "err", err)
-				}
-
-				// Also create IPPort entries for open ports
-				if err := createIPPortEntries(db, host); err != nil {
-					log.Warn("failed to create IPPort entries", "ip", ip, "err", err)
-				}
-			}
-		}
-
-		// If Shodan failed or no client available, try fallback
-		if ipInfo == nil {
-			if fallbackInfo, err := createFallbackIPInfo(db, ip); err != nil {
-				log.Error("both Shodan and fallback failed for IP", "ip", ip, "err", err)
-				errorCount++
-				continue
-			} else {
-				ipInfo = fallbackInfo
-				usedFallback = true
-				fallbackCount++
-			}
-		}
+// collectShodanTargets gathers the IPs to enrich from the configured file,
+// ASNs and CIDRs. IPs that were enumerated from an address space without
+// knowing if they are responsive are flagged in the returned unverified map.
+func collectShodanTargets(enricher *enrich.Enricher) ([]string, map[string]bool, error) {
+	targets := enrich.Targets{
+		ASNs:           shodanCmdOptions.ASNs,
+		CIDRs:          shodanCmdOptions.CIDRs,
+		MaxIPs:         shodanCmdOptions.MaxIPs,
+		ResolveThreads: shodanCmdOptions.ResolveThreads,
+		Scope:          scanScope,
+	}
 
-		// Save to database
-		if err := db.Create(ipInfo).Error; err != nil {
-			log.Warn("failed to save IP info to database", "ip", ip, "err", err)
-			errorCount++
-			continue
+	if shodanCmdOptions.File != "" {
+		// Read hosts from file
+		hosts, err := readHostsFromFile(shodanCmdOptions.File)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read hosts from file: %w", err)
 		}
+		targets.Hosts = hosts
+	}
 
-		savedCount++
-
-		if shodanCmdOptions.Verbose {
-			source := "shodan"
-			if usedFallback {
-				source = "ip-api+naabu"
-			}
-			log.Info("saved IP information", "ip", ip, "organization", ipInfo.Organization, "source", source)
+	if shodanCmdOptions.Resolvers != "" {
+		servers, err := dns.LoadServers(shodanCmdOptions.Resolvers)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load resolvers: %w", err)
 		}
+		log.Info("using custom resolvers", "count", len(servers))
+		targets.Resolvers = servers
 	}
 
-	log.Info("Shodan scan results",
-		"processed", processedCount,
-		"saved", savedCount,
-		"skipped", skippedCount,
-		"errors", errorCount,
-		"fallback_used", fallbackCount)
+	enricher.ScanSessionID = getValidShodanScanSessionID()
 
-	return nil
+	return enricher.Collect(targets)
 }
 
+// readHostsFromFile reads the hosts in a file, or stdin if filename is -
 func readHostsFromFile(filename string) ([]string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+	var r io.Reader = os.Stdin
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
 	}
-	defer file.Close()
 
 	var hosts []string
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line != "" && !strings.HasPrefix(line, "#") {
@@ -450,80 +455,6 @@ func readHostsFromFile(filename string) ([]string, error) {
 	return hosts, scanner.Err()
 }
 
-func resolveAndDeduplicateIPs(hosts []string) ([]string, error) {
-	ipSet := make(map[string]bool)
-
-	for _, host := range hosts {
-		// Check if it's already an IP address
-		if ip := net.ParseIP(host); ip != nil {
-			ipSet[host] = true
-			continue
-		}
-
-		// Remove protocol and port if present
-		host = strings.TrimPrefix(host, "http://")
-		host = strings.TrimPrefix(host, "https://")
-		if colonIndex := strings.LastIndex(host, ":"); colonIndex > 0 {
-			// Only remove port if it's not an IPv6 address
-			if !strings.Contains(host, "]") {
-				host = host[:colonIndex]
-			}
-		}
-
-		// Resolve domain to IP addresses
-		ips, err := net.LookupIP(host)
-		if err != nil {
-			log.Warn("failed to resolve host", "host", host, "err", err)
-			continue
-		}
-
-		for _, ip := range ips {
-			// Only include IPv4 addresses
-			if ipv4 := ip.To4(); ipv4 != nil {
-				ipSet[ip.String()] = true
-			}
-		}
-	}
-
-	// Convert set to slice
-	var result []string
-	for ip := range ipSet {
-		result = append(result, ip)
-	}
-
-	return result, nil
-}
-
-func createIPPortEntries(db *gorm.DB, host *shodan.Host) error {
-	sessionID := getValidShodanScanSessionID()
-
-	for _, port := range host.Ports {
-		// Check if this IP:Port combination already exists
-		var existing models.IPPort
-		if err := db.Where("ip_address = ? AND port = ?", host.IP, port).First(&existing).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				// Create new IPPort entry
-				ipPort := models.IPPort{
-					IPAddress:     host.IP,
-					Port:          port,
-					Protocol:      "tcp", // Shodan typically reports TCP ports
-					State:         "open",
-					Service:       "", // Could be enhanced with service detection from Shodan data
-					ScanSessionID: sessionID,
-					IsCDN:         false, // Could be enhanced with CDN detection
-					CDNDetected:   false,
-				}
-
-				if err := db.Create(&ipPort).Error; err != nil {
-					log.Warn("failed to create IPPort entry", "ip", host.IP, "port", port, "err", err)
-				}
-			}
-		}
-	}
-
-	return nil
-}
-
 func getValidShodanScanSessionID() *uint {
 	if shodanCmdOptions.ScanSessionID > 0 {
 		return &shodanCmdOptions.ScanSessionID
@@ -542,9 +473,21 @@ func updateProjectStatus(projectName, status string) {
 func init() {
 	scanCmd.AddCommand(shodanCmd)
 
-	shodanCmd.Flags().StringVarP(&shodanCmdOptions.File, "file", "f", "", "File containing list of domains/IPs to query (required)")
+	shodanCmd.Flags().StringVarP(&shodanCmdOptions.File, "file", "f", "", "File containing list of domains/IPs to query. Use - to read from stdin, which is also read when targets are piped in")
 	shodanCmd.Flags().BoolVar(&shodanCmdOptions.Verbose, "verbose", false, "Enable verbose output")
+	shodanCmd.Flags().MarkDeprecated("verbose", "use --log-level debug instead")
 	shodanCmd.Flags().UintVar(&shodanCmdOptions.ScanSessionID, "scan-session-id", 0, "Associate results with specific scan session ID")
 	shodanCmd.Flags().IntVar(&shodanCmdOptions.RateLimit, "rate-limit", 60, "API calls per minute (default: 60)")
+	shodanCmd.Flags().BoolVar(&shodanCmdOptions.Full, "full", false, "Query complete Shodan host records and store the services seen on each port (banners, HTTP titles, TLS certificates)")
+	shodanCmd.Flags().BoolVar(&shodanCmdOptions.SkipInternetDB, "skip-internetdb", false, "Query the Shodan API without trying the free InternetDB first")
+	shodanCmd.Flags().IntVar(&shodanCmdOptions.Threads, "threads", 4, "Number of IPs to enrich concurrently. Shodan queries still respect --rate-limit")
+	shodanCmd.Flags().BoolVar(&shodanCmdOptions.Resume, "resume", false, "Resume an interrupted scan from its checkpoint, instead of collecting new targets")
+	shodanCmd.Flags().BoolVar(&shodanCmdOptions.Refresh, "refresh", false, "Query IPs that were enriched before again if their information is older than --max-age")
+	shodanCmd.Flags().StringVar(&shodanCmdOptions.MaxAge, "max-age", "30d", "Age after which --refresh queries an IP again (e.g. 30d, 2w, 12h)")
 	shodanCmd.Flags().StringVar(&shodanCmdOptions.ProjectName, "project", "", "Project name for status updates (optional)")
+	shodanCmd.Flags().StringSliceVar(&shodanCmdOptions.ASNs, "asn", []string{}, "Enumerate and enrich IPs announced by an ASN (e.g., AS12345). Supports multiple --asn flags")
+	shodanCmd.Flags().StringSliceVar(&shodanCmdOptions.CIDRs, "cidr", []string{}, "Enumerate and enrich IPs in a CIDR. Supports multiple --cidr flags")
+	shodanCmd.Flags().StringVar(&shodanCmdOptions.Resolvers, "resolvers", "", "File with DNS resolvers to use, one per line (e.g., 1.1.1.1 or 9.9.9.9:53). Defaults to the system resolver")
+	shodanCmd.Flags().IntVar(&shodanCmdOptions.ResolveThreads, "resolve-threads", 20, "Number of hosts to resolve concurrently")
+	shodanCmd.Flags().IntVar(&shodanCmdOptions.MaxIPs, "max-ips", 4096, "Maximum number of IPs to enumerate from --asn and --cidr (0 for no limit)")
 }
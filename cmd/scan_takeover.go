@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/dns"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/takeover"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// takeoverSource is the source of findings reported by scan takeover
+const takeoverSource = "takeover"
+
+var takeoverCmdOptions = struct {
+	ScanSessionID  uint
+	Resolvers      string
+	ResolveThreads int
+}{}
+
+var takeoverCmd = &cobra.Command{
+	Use:   "takeover",
+	Short: "Detect subdomains that may be vulnerable to takeover",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan takeover
+
+Detect subdomains that may be vulnerable to takeover.
+
+The hostnames of probed results, and hosts with CNAME records in the database,
+are resolved again. Hosts that are a CNAME to a service such as GitHub Pages,
+AWS S3, Azure or Heroku are then matched against fingerprints of that
+service's unclaimed resources, using the stored response body of the host, or
+a CNAME target that no longer resolves where that alone is enough to claim it.
+
+Matches are stored as high severity findings, linked to the results of the
+host. Fresh DNS records are stored as well.`)),
+	Example: ascii.Markdown(`
+- gowitness scan takeover --write-db
+- gowitness scan takeover --write-db --scan-session-id 2 --resolvers resolvers.txt`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for takeover detection")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		return detectTakeovers(db)
+	},
+}
+
+// detectTakeovers checks the hosts in the database for takeovers, saving
+// matches as findings
+func detectTakeovers(db *gorm.DB) error {
+	var scanSessionID *uint
+	if takeoverCmdOptions.ScanSessionID > 0 {
+		scanSessionID = &takeoverCmdOptions.ScanSessionID
+	}
+
+	resultsByHost, err := takeoverResults(db, scanSessionID)
+	if err != nil {
+		return err
+	}
+
+	var cnameHosts []string
+	query := db.Model(&models.DNSRecord{}).Distinct("host").Where("type = ?", dns.TypeCNAME)
+	if scanSessionID != nil {
+		query = query.Where("scan_session_id = ?", *scanSessionID)
+	}
+	if err := query.Pluck("host", &cnameHosts).Error; err != nil {
+		return fmt.Errorf("failed to get cname records: %w", err)
+	}
+
+	var hosts []string
+	for host := range resultsByHost {
+		hosts = append(hosts, host)
+	}
+	for _, host := range cnameHosts {
+		if _, ok := resultsByHost[host]; !ok {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		log.Warn("no hosts found. probe some targets or resolve hostnames first")
+		return nil
+	}
+
+	var servers []string
+	if takeoverCmdOptions.Resolvers != "" {
+		servers, err = dns.LoadServers(takeoverCmdOptions.Resolvers)
+		if err != nil {
+			return fmt.Errorf("failed to load resolvers: %w", err)
+		}
+		log.Info("using custom resolvers", "count", len(servers))
+	}
+
+	resolver := dns.NewResolver(servers)
+	resolver.Concurrency = takeoverCmdOptions.ResolveThreads
+
+	log.Info("checking hosts for takeovers", "hosts", len(hosts))
+
+	answers := resolver.ResolveAll(hosts)
+	if err := dns.Save(db, answers, scanSessionID); err != nil {
+		log.Warn("failed to save dns records", "err", err)
+	}
+
+	var matches int
+	for _, answer := range answers {
+		results := resultsByHost[answer.Host]
+
+		// hosts that were never probed can still match on their dns alone
+		if len(results) == 0 {
+			if match := takeover.Check(answer, ""); match != nil {
+				matches++
+				logTakeover(match)
+				if err := saveTakeoverFinding(db, match, nil, scanSessionID); err != nil {
+					log.Warn("failed to save takeover finding", "host", answer.Host, "err", err)
+				}
+			}
+			continue
+		}
+
+		for _, result := range results {
+			match := takeover.Check(answer, result.HTML)
+			if match == nil {
+				continue
+			}
+
+			matches++
+			logTakeover(match)
+			if err := saveTakeoverFinding(db, match, &result.ID, result.ScanSessionID); err != nil {
+				log.Warn("failed to save takeover finding", "host", answer.Host, "err", err)
+			}
+		}
+	}
+
+	log.Info("takeover detection completed", "hosts", len(hosts), "matches", matches)
+	return nil
+}
+
+// takeoverResults returns the successful results of a scan session, or of
+// all sessions, by hostname
+func takeoverResults(db *gorm.DB, scanSessionID *uint) (map[string][]models.Result, error) {
+	query := db.Model(&models.Result{}).Select("id", "url", "html", "scan_session_id").Where("failed = ?", false)
+	if scanSessionID != nil {
+		query = query.Where("scan_session_id = ?", *scanSessionID)
+	}
+
+	resultsByHost := make(map[string][]models.Result)
+	var batch []models.Result
+	if err := query.FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+		for _, result := range batch {
+			u, err := url.Parse(result.URL)
+			if err != nil || u.Hostname() == "" {
+				continue
+			}
+
+			host := strings.ToLower(u.Hostname())
+			resultsByHost[host] = append(resultsByHost[host], result)
+		}
+		return nil
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to get results: %w", err)
+	}
+
+	return resultsByHost, nil
+}
+
+// logTakeover logs a takeover match
+func logTakeover(match *takeover.Match) {
+	log.Warn("possible subdomain takeover", "host", match.Host, "service", match.Service,
+		"cname", match.CNAME, "evidence", match.Evidence)
+}
+
+// saveTakeoverFinding stores a takeover match as a finding, unless the same
+// match was reported before
+func saveTakeoverFinding(db *gorm.DB, match *takeover.Match, resultID *uint, scanSessionID *uint) error {
+	title := fmt.Sprintf("Possible subdomain takeover of %s (%s)", match.Host, match.Service)
+
+	query := db.Model(&models.Finding{}).Where("source = ? AND title = ?", takeoverSource, title)
+	if resultID != nil {
+		query = query.Where("result_id = ?", *resultID)
+	} else {
+		query = query.Where("result_id IS NULL")
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return db.Create(&models.Finding{
+		ResultID: resultID,
+		Source:   takeoverSource,
+		Title:    title,
+		Severity: "high",
+		Description: fmt.Sprintf("%s is a CNAME to %s, a %s resource that looks unclaimed: %s.",
+			match.Host, match.CNAME, match.Service, match.Evidence),
+		ScanSessionID: scanSessionID,
+	}).Error
+}
+
+func init() {
+	scanCmd.AddCommand(takeoverCmd)
+
+	takeoverCmd.Flags().UintVar(&takeoverCmdOptions.ScanSessionID, "scan-session-id", 0, "Only check hosts of this scan session")
+	takeoverCmd.Flags().StringVar(&takeoverCmdOptions.Resolvers, "resolvers", "", "File with DNS resolvers to use, one per line (e.g., 1.1.1.1 or 9.9.9.9:53). Defaults to the system resolver")
+	takeoverCmd.Flags().IntVar(&takeoverCmdOptions.ResolveThreads, "resolve-threads", 20, "Number of hosts to resolve concurrently")
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/dns"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/paths"
+	"github.com/sensepost/gowitness/pkg/vhosts"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// vhostsSource is what tags results found by virtual host brute forcing
+const vhostsSource = "vhosts"
+
+// vhostsTag is the tag of results found by virtual host brute forcing
+const vhostsTag = "vhost-bruteforce"
+
+var vhostsCmdOptions = struct {
+	IPAddress     string
+	Hosts         string
+	ScanSessionID uint
+	Threads       int
+	Timeout       int
+	StatusCodes   []int
+}{}
+
+var vhostsCmd = &cobra.Command{
+	Use:   "vhosts",
+	Short: "Brute force virtual hosts on discovered IPs",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan vhosts
+
+Brute force virtual hosts on discovered IPs.
+
+The open web ports in the database (for example from 'scan naabu' or Shodan)
+are requested directly by IP, once for every candidate hostname, with the
+hostname as the Host header and TLS server name. Candidates are the project's
+domains, as well as any hostnames in --hosts. Hostnames that DNS already
+points at an IP are not tried on it.
+
+Servers that answer every hostname are recognised by first requesting a
+hostname that can't exist, and the IP itself. Hostnames answering the same
+way are not reported.
+
+Virtual hosts that are found are stored as new results, linked to the IP and
+tagged with 'vhost-bruteforce'. They are recorded from the brute force
+response, without a screenshot, since their hostnames may not resolve to the
+IP they were found on.`)),
+	Example: ascii.Markdown(`
+- gowitness scan vhosts --write-db
+- gowitness scan vhosts --write-db --ip 192.0.2.10 --hosts hostnames.txt
+- gowitness scan vhosts --write-db --scan-session-id 2 --status 200`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for virtual host brute forcing")
+		}
+
+		if vhostsCmdOptions.Hosts != "" && !islazy.FileExists(vhostsCmdOptions.Hosts) {
+			return errors.New("hosts file is not readable")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		hosts, err := database.KnownDomains(db)
+		if err != nil {
+			return fmt.Errorf("failed to get project domains: %w", err)
+		}
+		if vhostsCmdOptions.Hosts != "" {
+			extra, err := paths.LoadWordlist(vhostsCmdOptions.Hosts)
+			if err != nil {
+				return fmt.Errorf("failed to load hosts: %w", err)
+			}
+			for _, host := range extra {
+				hosts = append(hosts, strings.ToLower(host))
+			}
+		}
+		slices.Sort(hosts)
+		hosts = slices.Compact(hosts)
+		if len(hosts) == 0 {
+			return errors.New("there are no candidate hostnames. add domains to the project or use --hosts")
+		}
+
+		prober := vhosts.NewProber(slog.New(log.Logger))
+		prober.Concurrency = vhostsCmdOptions.Threads
+		prober.Timeout = time.Duration(vhostsCmdOptions.Timeout) * time.Second
+		prober.UserAgent = opts.Chrome.UserAgent
+		if len(vhostsCmdOptions.StatusCodes) > 0 {
+			prober.StatusCodes = vhostsCmdOptions.StatusCodes
+		}
+
+		return bruteForceVhosts(db, prober, hosts)
+	},
+}
+
+// bruteForceVhosts tries every candidate hostname on the open web ports of
+// every IP, saving the virtual hosts that are found as results
+func bruteForceVhosts(db *gorm.DB, prober *vhosts.Prober, hosts []string) error {
+	query := db
+	if vhostsCmdOptions.IPAddress != "" {
+		query = query.Where("ip_address = ?", vhostsCmdOptions.IPAddress)
+	}
+	if vhostsCmdOptions.ScanSessionID > 0 {
+		query = query.Where("scan_session_id = ?", vhostsCmdOptions.ScanSessionID)
+	}
+
+	ports, err := database.HTTPPorts(query)
+	if err != nil {
+		return fmt.Errorf("failed to get open ports: %w", err)
+	}
+	if len(ports) == 0 {
+		log.Warn("no open web ports found. run a port scan first")
+		return nil
+	}
+
+	// hostnames DNS points at an IP are screenshotted by normal scans
+	var records []models.DNSRecord
+	if err := db.Where("type IN ?", []string{dns.TypeA, dns.TypeAAAA}).Find(&records).Error; err != nil {
+		return fmt.Errorf("failed to get dns records: %w", err)
+	}
+	resolved := make(map[string]map[string]bool)
+	for _, record := range records {
+		if resolved[record.Value] == nil {
+			resolved[record.Value] = make(map[string]bool)
+		}
+		resolved[record.Value][strings.ToLower(record.Host)] = true
+	}
+
+	log.Info("brute forcing virtual hosts", "ports", len(ports), "hosts", len(hosts))
+
+	var found int
+	for _, port := range ports {
+		var candidates []string
+		for _, host := range hosts {
+			if !resolved[port.IPAddress][host] {
+				candidates = append(candidates, host)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		hits, err := probeVhosts(prober, port, candidates)
+		if err != nil {
+			log.Warn("failed to brute force virtual hosts", "ip", port.IPAddress, "port", port.Port, "err", err)
+			continue
+		}
+
+		for _, hit := range hits {
+			log.Info("found virtual host", "ip", port.IPAddress, "port", port.Port, "host", hit.Host,
+				"status", hit.StatusCode, "title", hit.Title)
+
+			if err := saveVhostResult(db, port, hit); err != nil {
+				log.Warn("failed to save virtual host", "url", hit.URL, "ip", port.IPAddress, "err", err)
+			}
+		}
+
+		found += len(hits)
+	}
+
+	if err := database.SyncTags(db); err != nil {
+		log.Warn("failed to sync tags", "err", err)
+	}
+
+	log.Info("virtual host brute forcing completed", "ports", len(ports), "found", found)
+	return nil
+}
+
+// probeVhosts brute forces a port over https, falling back to http if it
+// does not speak TLS
+func probeVhosts(prober *vhosts.Prober, port models.IPPort, hosts []string) ([]vhosts.Hit, error) {
+	address := port.IPAddress
+	if strings.Contains(address, ":") {
+		address = "[" + address + "]"
+	}
+	address += ":" + strconv.Itoa(port.Port)
+
+	schemes := []string{"https", "http"}
+	if port.Port == 80 || strings.Contains(strings.ToLower(port.Service), "http") &&
+		!strings.Contains(strings.ToLower(port.Service), "https") {
+		schemes = []string{"http", "https"}
+	}
+
+	var err error
+	for _, scheme := range schemes {
+		var hits []vhosts.Hit
+		if hits, err = prober.Probe(scheme+"://"+address, hosts); err == nil {
+			return hits, nil
+		}
+	}
+
+	return nil, err
+}
+
+// saveVhostResult stores a virtual host as a result of the IP it was found
+// on, tagged with how it was found, unless it was stored before
+func saveVhostResult(db *gorm.DB, port models.IPPort, hit vhosts.Hit) error {
+	var count int64
+	if err := db.Model(&models.Result{}).
+		Where("url = ? AND ip_address = ?", hit.URL, port.IPAddress).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	result := &models.Result{
+		URL:            hit.URL,
+		IPAddress:      port.IPAddress,
+		ScanSessionID:  port.ScanSessionID,
+		ProbedAt:       time.Now(),
+		FinalURL:       hit.URL,
+		ResponseCode:   hit.StatusCode,
+		ResponseReason: http.StatusText(hit.StatusCode),
+		Protocol:       hit.Protocol,
+		ContentLength:  int64(hit.ContentLength),
+		HTML:           hit.HTML,
+		Title:          hit.Title,
+		Tags:           []models.ResultTag{{Name: vhostsTag, Source: vhostsSource}},
+	}
+
+	return db.Create(result).Error
+}
+
+func init() {
+	scanCmd.AddCommand(vhostsCmd)
+
+	vhostsCmd.Flags().StringVar(&vhostsCmdOptions.IPAddress, "ip", "", "Only brute force virtual hosts on this IP address")
+	vhostsCmd.Flags().StringVar(&vhostsCmdOptions.Hosts, "hosts", "", "A file with more hostnames to try, one per line")
+	vhostsCmd.Flags().UintVar(&vhostsCmdOptions.ScanSessionID, "scan-session-id", 0, "Only brute force ports found by this scan session")
+	vhostsCmd.Flags().IntVar(&vhostsCmdOptions.Threads, "vhost-threads", 10, "Number of hostnames to request at once per port")
+	vhostsCmd.Flags().IntVar(&vhostsCmdOptions.Timeout, "probe-timeout", 10, "Number of seconds before a request times out")
+	vhostsCmd.Flags().IntSliceVar(&vhostsCmdOptions.StatusCodes, "status", []int{}, "Response codes to report as virtual hosts. Defaults to 200, 204, 301, 302, 307, 308, 401 and 403. Supports multiple --status flags")
+}
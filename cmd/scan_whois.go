@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/ascii"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/whois"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var whoisCmdOptions = struct {
+	ScanSessionID uint
+	SkipDomains   bool
+	SkipIPs       bool
+	Timeout       int
+	Delay         int
+}{}
+
+var whoisCmd = &cobra.Command{
+	Use:   "whois",
+	Short: "Look up domain registrations and netblock owners with RDAP",
+	Long: ascii.LogoHelp(ascii.Markdown(`
+# scan whois
+
+Look up domain registrations and netblock owners with RDAP.
+
+The registrar, creation, expiry and last update dates of the apex domains of
+a scan session are recorded, as well as the netblocks the IP addresses of
+its results and open ports are in, and who they are assigned to.
+
+IP addresses that are in a netblock that was looked up before are skipped, so
+running this again only looks up new ranges. Registrations are shown by
+/api/domain/{domain}, and netblocks with the IP information of /api/ip/{ip}.`)),
+	Example: ascii.Markdown(`
+- gowitness scan whois --write-db
+- gowitness scan whois --write-db --scan-session-id 2
+- gowitness scan whois --write-db --skip-ips`),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !opts.Writer.Db {
+			return errors.New("--write-db flag is required for whois lookups")
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := database.Connection(opts.Writer.DbURI, false, opts.Writer.DbDebug)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+
+		session, err := database.ScopeSession(db, whoisCmdOptions.ScanSessionID)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return errors.New("no active scan session to look up. specify --scan-session-id")
+		}
+
+		client := whois.NewClient(time.Duration(whoisCmdOptions.Timeout) * time.Second)
+		delay := time.Duration(whoisCmdOptions.Delay) * time.Millisecond
+
+		if !whoisCmdOptions.SkipDomains {
+			lookupRegistrations(db, client, session, delay)
+		}
+		if !whoisCmdOptions.SkipIPs {
+			if err := lookupNetblocks(db, client, session, delay); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+// lookupRegistrations looks up the registrations of the apex domains of a
+// scan session
+func lookupRegistrations(db *gorm.DB, client *whois.Client, session *models.ScanSession, delay time.Duration) {
+	for i, domain := range session.ScopeDomains() {
+		if i > 0 {
+			time.Sleep(delay)
+		}
+
+		registration, err := client.Domain(domain)
+		if err != nil {
+			log.Warn("failed to look up domain registration", "domain", domain, "err", err)
+			continue
+		}
+		registration.ScanSessionID = &session.ID
+
+		log.Info("domain registration", "domain", domain, "registrar", registration.Registrar,
+			"expires", registration.ExpiryDate)
+
+		if err := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "domain"}},
+			DoUpdates: clause.AssignmentColumns([]string{"registrar", "status", "nameservers",
+				"created_date", "expiry_date", "updated_date", "scan_session_id", "checked_at"}),
+		}).Create(registration).Error; err != nil {
+			log.Warn("failed to save domain registration", "domain", domain, "err", err)
+		}
+	}
+}
+
+// lookupNetblocks looks up the netblocks of the IP addresses of a scan
+// session that are not in a known netblock yet
+func lookupNetblocks(db *gorm.DB, client *whois.Client, session *models.ScanSession, delay time.Duration) error {
+	var ips []string
+	if err := db.Raw(`SELECT ip_address FROM results WHERE scan_session_id = ? AND ip_address != ''
+		UNION SELECT ip_address FROM ip_ports WHERE scan_session_id = ?`, session.ID, session.ID).
+		Scan(&ips).Error; err != nil {
+		return fmt.Errorf("failed to get ip addresses: %w", err)
+	}
+
+	var netblocks []models.Netblock
+	if err := db.Find(&netblocks).Error; err != nil {
+		return fmt.Errorf("failed to get netblocks: %w", err)
+	}
+
+	var looked int
+	for _, ip := range ips {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+			continue
+		}
+		if inNetblock(netblocks, ip) {
+			continue
+		}
+
+		if looked > 0 {
+			time.Sleep(delay)
+		}
+		looked++
+
+		netblock, err := client.Network(ip)
+		if err != nil {
+			log.Warn("failed to look up netblock", "ip", ip, "err", err)
+			continue
+		}
+		netblock.ScanSessionID = &session.ID
+
+		log.Info("netblock", "ip", ip, "handle", netblock.Handle, "owner", netblock.Owner,
+			"range", netblock.StartAddress+" - "+netblock.EndAddress)
+
+		if err := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "handle"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "start_address", "end_address", "cidrs",
+				"owner", "country", "type", "scan_session_id", "checked_at"}),
+		}).Create(netblock).Error; err != nil {
+			log.Warn("failed to save netblock", "handle", netblock.Handle, "err", err)
+		}
+		netblocks = append(netblocks, *netblock)
+	}
+
+	log.Info("netblock lookups completed", "ips", len(ips), "looked-up", looked)
+	return nil
+}
+
+// inNetblock checks if any of the netblocks contain ip
+func inNetblock(netblocks []models.Netblock, ip string) bool {
+	for _, netblock := range netblocks {
+		if netblock.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func init() {
+	scanCmd.AddCommand(whoisCmd)
+
+	whoisCmd.Flags().UintVar(&whoisCmdOptions.ScanSessionID, "scan-session-id", 0, "Scan session to look up the domains and IP addresses of. Defaults to the latest active session")
+	whoisCmd.Flags().BoolVar(&whoisCmdOptions.SkipDomains, "skip-domains", false, "Don't look up the registrations of the apex domains")
+	whoisCmd.Flags().BoolVar(&whoisCmdOptions.SkipIPs, "skip-ips", false, "Don't look up the netblocks of IP addresses")
+	whoisCmd.Flags().IntVar(&whoisCmdOptions.Timeout, "rdap-timeout", 30, "Number of seconds before an RDAP query times out")
+	whoisCmd.Flags().IntVar(&whoisCmdOptions.Delay, "rdap-delay", 1000, "Milliseconds to wait between RDAP queries, to stay under registry rate limits")
+}
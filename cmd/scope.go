@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/log"
+)
+
+// scopeTargetsFile writes the in scope targets of a file to a new file, for
+// port scanners that read their targets from one, returning its path
+func scopeTargetsFile(command string, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read targets: %w", err)
+	}
+
+	var targets []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			targets = append(targets, line)
+		}
+	}
+
+	targets, skipped := scanScope.Filter(targets)
+	if skipped > 0 {
+		log.Warn("skipping out of scope targets", "count", skipped)
+	}
+
+	scoped := fmt.Sprintf("gowitness-%s-scoped-%d.txt", command, time.Now().Unix())
+	if err := os.WriteFile(scoped, []byte(strings.Join(targets, "\n")+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write scoped targets: %w", err)
+	}
+
+	return scoped, nil
+}
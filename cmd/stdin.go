@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// stdinPiped checks if stdin is a pipe or file, such as the output of
+// subfinder, rather than a terminal
+func stdinPiped() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+// saveStdin copies stdin to a new file for tools that need to read their
+// targets from one, returning its path
+func saveStdin(command string) (string, error) {
+	path := fmt.Sprintf("gowitness-%s-targets-%d.txt", command, time.Now().Unix())
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create targets file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, os.Stdin); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to read targets from stdin: %w", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,205 @@
+package ascii
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+)
+
+// progressWidth is the width of the progress bar, in characters
+const progressWidth = 30
+
+var (
+	progressDoneStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("99"))
+	progressTodoStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	progressLabelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+)
+
+// Progress is a progress bar with an ETA and named counters, redrawn in
+// place on a terminal. Logs written through it while it is drawn are
+// printed above the bar.
+type Progress struct {
+	mu       sync.Mutex
+	out      *os.File
+	title    string
+	total    int
+	done     int
+	counters map[string]int
+	order    []string
+	started  time.Time
+	drawn    bool
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewProgress starts a progress display of total items on out. If total is
+// not known, it is 0 and only the counters are drawn. If out is nil or not a
+// terminal, nothing is drawn.
+func NewProgress(out *os.File, title string, total int) *Progress {
+	p := &Progress{
+		title:    title,
+		total:    total,
+		counters: make(map[string]int),
+		started:  time.Now(),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	if out == nil || !term.IsTerminal(out.Fd()) {
+		close(p.stopped)
+		return p
+	}
+	p.out = out
+
+	go func() {
+		defer close(p.stopped)
+
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				p.mu.Lock()
+				p.draw()
+				p.mu.Unlock()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// Increment marks an item as done, counting it towards counter if it is
+// not empty
+func (p *Progress) Increment(counter string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	p.count(counter)
+}
+
+// Count counts an event towards counter, without marking an item as done
+func (p *Progress) Count(counter string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.count(counter)
+}
+
+// Visible reports if the progress display is drawn
+func (p *Progress) Visible() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.out != nil
+}
+
+// Elapsed returns the time since the progress display started
+func (p *Progress) Elapsed() time.Duration {
+	return time.Since(p.started)
+}
+
+// Write writes b above the progress bar, so that logs can be written
+// through the progress display
+func (p *Progress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.out == nil {
+		return os.Stderr.Write(b)
+	}
+
+	p.clear()
+	n, err := p.out.Write(b)
+	p.draw()
+
+	return n, err
+}
+
+// Stop stops redrawing the progress bar and removes it
+func (p *Progress) Stop() {
+	p.mu.Lock()
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+	p.mu.Unlock()
+
+	<-p.stopped
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	p.out = nil
+}
+
+// count counts towards counter. p.mu must be held.
+func (p *Progress) count(counter string) {
+	if counter == "" {
+		return
+	}
+	if _, ok := p.counters[counter]; !ok {
+		p.order = append(p.order, counter)
+	}
+	p.counters[counter]++
+}
+
+// clear removes the drawn progress bar. p.mu must be held.
+func (p *Progress) clear() {
+	if p.out != nil && p.drawn {
+		io.WriteString(p.out, "\r\033[2K")
+		p.drawn = false
+	}
+}
+
+// draw redraws the progress bar. p.mu must be held.
+func (p *Progress) draw() {
+	if p.out == nil {
+		return
+	}
+
+	var line strings.Builder
+	line.WriteString("\r\033[2K")
+	line.WriteString(p.title)
+
+	elapsed := time.Since(p.started)
+	if p.total > 0 {
+		ratio := min(1, float64(p.done)/float64(p.total))
+		filled := int(ratio * progressWidth)
+		line.WriteString(" " + progressDoneStyle.Render(strings.Repeat("█", filled)))
+		line.WriteString(progressTodoStyle.Render(strings.Repeat("░", progressWidth-filled)))
+		line.WriteString(fmt.Sprintf(" %d/%d %3.0f%%", p.done, p.total, ratio*100))
+
+		// the ETA assumes the remaining items take as long as the done ones
+		if p.done > 0 && p.done < p.total {
+			eta := time.Duration(float64(elapsed) / float64(p.done) * float64(p.total-p.done))
+			line.WriteString(progressLabelStyle.Render(" eta ") + eta.Round(time.Second).String())
+		}
+	} else {
+		line.WriteString(progressLabelStyle.Render(" elapsed ") + elapsed.Round(time.Second).String())
+	}
+
+	for _, name := range p.order {
+		line.WriteString(progressLabelStyle.Render(" "+name+" ") + fmt.Sprint(p.counters[name]))
+	}
+
+	// keep the bar on a single line, or it can not be redrawn in place
+	if width, _, err := term.GetSize(p.out.Fd()); err == nil && width > 0 {
+		io.WriteString(p.out, lipgloss.NewStyle().MaxWidth(width-1).Render(line.String()))
+	} else {
+		io.WriteString(p.out, line.String())
+	}
+	p.drawn = true
+}
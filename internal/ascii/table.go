@@ -0,0 +1,36 @@
+package ascii
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// SummaryTable renders a two column table of names and values, such as the
+// counters of a finished scan. keyvals alternates names and values.
+func SummaryTable(title string, keyvals ...any) string {
+	padded := lipgloss.NewStyle().PaddingLeft(1).PaddingRight(1)
+	header := padded.Bold(true).Underline(true)
+
+	t := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("99"))).
+		Headers(title, "").
+		StyleFunc(func(row, col int) lipgloss.Style {
+			switch {
+			case row == table.HeaderRow:
+				return header
+			case col == 1:
+				return padded.Align(lipgloss.Right)
+			default:
+				return padded
+			}
+		})
+
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		t.Row(fmt.Sprint(keyvals[i]), fmt.Sprint(keyvals[i+1]))
+	}
+
+	return t.String()
+}
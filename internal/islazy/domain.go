@@ -0,0 +1,27 @@
+package islazy
+
+import (
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// ApexDomain returns the apex domain of a hostname using the public suffix
+// list. This properly handles country-code TLDs like .co.uk, .com.au, etc.
+func ApexDomain(hostname string) string {
+	if hostname == "" {
+		return ""
+	}
+
+	etld, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+	if err != nil {
+		// If parsing fails, fall back to simple logic for basic cases
+		parts := strings.Split(hostname, ".")
+		if len(parts) >= 2 {
+			return strings.Join(parts[len(parts)-2:], ".")
+		}
+		return hostname
+	}
+
+	return etld
+}
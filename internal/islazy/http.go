@@ -0,0 +1,73 @@
+package islazy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	proxyMu  sync.RWMutex
+	proxyURL *url.URL
+)
+
+// SetProxy sets the proxy HTTP clients made by NewHTTPClient send requests
+// through. http, https, socks5 and socks5h proxies are supported, such as
+// socks5://127.0.0.1:1080. An empty proxy falls back to the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables.
+func SetProxy(proxy string) error {
+	var parsed *url.URL
+	if proxy != "" {
+		var err error
+		if parsed, err = url.Parse(proxy); err != nil {
+			return fmt.Errorf("invalid proxy %q: %w", proxy, err)
+		}
+
+		switch parsed.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("invalid proxy %q: scheme must be one of http, https, socks5 or socks5h", proxy)
+		}
+		if parsed.Host == "" {
+			return fmt.Errorf("invalid proxy %q: no host", proxy)
+		}
+	}
+
+	proxyMu.Lock()
+	defer proxyMu.Unlock()
+
+	proxyURL = parsed
+	return nil
+}
+
+// proxyFunc returns the proxy of a request, for http.Transport
+func proxyFunc(req *http.Request) (*url.URL, error) {
+	proxyMu.RLock()
+	defer proxyMu.RUnlock()
+
+	if proxyURL != nil {
+		return proxyURL, nil
+	}
+
+	return http.ProxyFromEnvironment(req)
+}
+
+// NewHTTPTransport returns a transport using the proxy set with SetProxy.
+// It is a clone of the default transport, so it can be tuned further.
+func NewHTTPTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc
+
+	return transport
+}
+
+// NewHTTPClient returns an HTTP client with a timeout, using the proxy set
+// with SetProxy. Clients of third party APIs should be made with it.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: NewHTTPTransport(),
+	}
+}
@@ -17,9 +17,7 @@ func FetchClearbitLogo(domain, targetDir string) (string, error) {
 	clearbitURL := fmt.Sprintf("https://logo.clearbit.com/%s", domain)
 
 	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	client := NewHTTPClient(10 * time.Second)
 
 	// Make request to Clearbit
 	resp, err := client.Get(clearbitURL)
@@ -2,32 +2,68 @@ package islazy
 
 import (
 	"encoding/binary"
+	"fmt"
 	"net"
+	"strings"
 )
 
 // IpsInCIDR returns a list of usable IP addresses in a given CIDR block
 // excluding network and broadcast addresses for CIDRs larger than /31.
+// Only IPv4 CIDRs are supported.
 func IpsInCIDR(cidr string) ([]string, error) {
+	var ips []string
+	err := EachIPInCIDR(cidr, func(ip string) bool {
+		ips = append(ips, ip)
+		return true
+	})
+
+	return ips, err
+}
+
+// EachIPInCIDR calls fn with the usable IP addresses in a given IPv4 CIDR
+// block, in order, until fn returns false. Large blocks are walked without
+// holding every address in memory.
+func EachIPInCIDR(cidr string, fn func(ip string) bool) error {
 	_, ipnet, err := net.ParseCIDR(cidr)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if len(ipnet.Mask) != net.IPv4len {
+		return fmt.Errorf("only IPv4 CIDRs can be expanded: %s", cidr)
 	}
 
 	mask := binary.BigEndian.Uint32(ipnet.Mask)
 	start := binary.BigEndian.Uint32(ipnet.IP)
 	end := (start & mask) | (mask ^ 0xFFFFFFFF)
 
-	var ips []string
 	ip := make(net.IP, 4) // Preallocate buffer
 
-	// Iterate over the range of IPs
-	for i := start; i <= end; i++ {
+	// Iterate over the range of IPs. i is wider than an address, so that
+	// the loop ends at 255.255.255.255.
+	for i := uint64(start); i <= uint64(end); i++ {
 		// Exclude network and broadcast addresses in larger CIDR ranges
 		if !(i&0xFF == 255 || i&0xFF == 0) || ipnet.Mask[3] >= 30 {
-			binary.BigEndian.PutUint32(ip, i)
-			ips = append(ips, ip.String())
+			binary.BigEndian.PutUint32(ip, uint32(i))
+			if !fn(ip.String()) {
+				return nil
+			}
 		}
 	}
 
-	return ips, nil
+	return nil
+}
+
+// ReverseLookup returns the PTR names for an IP address, without the
+// trailing dot.
+func ReverseLookup(ip string) ([]string, error) {
+	names, err := net.LookupAddr(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, name := range names {
+		names[i] = strings.TrimSuffix(name, ".")
+	}
+
+	return names, nil
 }
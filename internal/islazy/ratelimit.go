@@ -0,0 +1,50 @@
+package islazy
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket rate limiter that is safe to share between
+// goroutines. Waiters are served in the order they arrive.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // how long it takes to earn a token
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+// NewRateLimiter returns a limiter allowing perMinute events a minute, and
+// at most burst at once
+func NewRateLimiter(perMinute int, burst int) *RateLimiter {
+	perMinute = max(1, perMinute)
+	burst = max(1, burst)
+
+	return &RateLimiter{
+		interval: time.Minute / time.Duration(perMinute),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until an event is allowed
+func (r *RateLimiter) Wait() {
+	r.mu.Lock()
+
+	now := time.Now()
+	r.tokens = min(r.burst, r.tokens+float64(now.Sub(r.last))/float64(r.interval))
+	r.last = now
+
+	// take the token now, even if it still has to be earned, so that later
+	// waiters queue up behind this one
+	r.tokens--
+	wait := time.Duration(-r.tokens * float64(r.interval))
+
+	r.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
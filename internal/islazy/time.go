@@ -1,6 +1,11 @@
 package islazy
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Float64ToTime takes a float64 as number of seconds since unix epoch and returns time.Time
 //
@@ -14,3 +19,19 @@ func Float64ToTime(f float64) time.Time {
 	}
 	return time.Unix(0, int64(f*float64(time.Second)))
 }
+
+// ParseDuration parses a duration like time.ParseDuration does, but also
+// accepts a whole number of days or weeks, e.g. 30d or 2w
+func ParseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,239 @@
+// Package agent runs the jobs of a gowitness server's job queue on other
+// machines. Agents claim jobs over HTTP, run them with a local browser and
+// upload their results, screenshots included, back to the server. This
+// spreads probing over several machines, or runs it from another network.
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/fallback"
+	"github.com/sensepost/gowitness/pkg/jobs"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/runner"
+	driver "github.com/sensepost/gowitness/pkg/runner/drivers"
+	"github.com/sensepost/gowitness/pkg/writers"
+)
+
+const (
+	// DefaultPollInterval is how often an idle agent asks for jobs
+	DefaultPollInterval = 5 * time.Second
+	// DefaultHeartbeatInterval is how often an agent tells the server it is
+	// still running a job
+	DefaultHeartbeatInterval = 30 * time.Second
+)
+
+// Agent claims jobs from a server and runs them
+type Agent struct {
+	Client *Client
+	// Kinds are the job kinds the agent runs
+	Kinds []string
+	// PollInterval is how often an idle agent asks for jobs
+	PollInterval time.Duration
+	// HeartbeatInterval is how often the agent tells the server it is still
+	// running a job
+	HeartbeatInterval time.Duration
+	// ChromePath and ChromeWSS replace the browser of the options jobs are
+	// queued with, as the browser is local to the agent
+	ChromePath string
+	ChromeWSS  string
+
+	logger *slog.Logger
+}
+
+// New returns a new agent running jobs of kinds from the server of client
+func New(logger *slog.Logger, client *Client, kinds []string) *Agent {
+	return &Agent{
+		Client:            client,
+		Kinds:             kinds,
+		PollInterval:      DefaultPollInterval,
+		HeartbeatInterval: DefaultHeartbeatInterval,
+		logger:            logger,
+	}
+}
+
+// Run claims and runs jobs until ctx is done
+func (a *Agent) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := a.Client.Claim(a.Kinds)
+		if err != nil {
+			a.logger.Error("failed to claim job", "err", err)
+		}
+		if job != nil {
+			a.run(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(a.PollInterval):
+		}
+	}
+}
+
+// run runs a claimed job, sending heartbeats while it does, and reports how
+// it went
+func (a *Agent) run(ctx context.Context, job *Job) {
+	a.logger.Info("running job", "id", job.ID, "kind", job.Kind, "attempt", job.Attempts)
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go a.heartbeat(jobCtx, cancel, job)
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+
+		switch job.Kind {
+		case jobs.KindProbe:
+			return a.probe(jobCtx, job)
+		case jobs.KindRetake:
+			return a.retake(job)
+		case jobs.KindIPEnrich:
+			return a.ipEnrich(jobCtx, job)
+		default:
+			return fmt.Errorf("%w: %s", jobs.ErrUnknownKind, job.Kind)
+		}
+	}()
+	// jobs that were stopped early are retried
+	if err == nil && jobCtx.Err() != nil {
+		err = fmt.Errorf("agent stopped running the job: %w", jobCtx.Err())
+	}
+
+	if err != nil {
+		a.logger.Error("job failed", "id", job.ID, "kind", job.Kind, "err", err)
+	} else {
+		a.logger.Info("job completed", "id", job.ID, "kind", job.Kind)
+	}
+
+	if err := a.Client.Finish(job.ID, err); err != nil {
+		a.logger.Error("failed to finish job", "id", job.ID, "err", err)
+	}
+}
+
+// heartbeat sends heartbeats for a job until ctx is done. The job is
+// stopped if the server no longer has it running for the agent.
+func (a *Agent) heartbeat(ctx context.Context, cancel context.CancelFunc, job *Job) {
+	ticker := time.NewTicker(a.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := a.Client.Heartbeat(job.ID); err != nil {
+			a.logger.Error("failed to send heartbeat, stopping job", "id", job.ID, "err", err)
+			cancel()
+			return
+		}
+	}
+}
+
+// options returns the options of a job, to run with the agent's browser.
+// Screenshots are uploaded to the server instead of saved.
+func (a *Agent) options(options runner.Options) runner.Options {
+	if a.ChromePath != "" {
+		options.Chrome.Path = a.ChromePath
+	}
+	if a.ChromeWSS != "" {
+		options.Chrome.WSS = a.ChromeWSS
+	}
+	options.Scan.ScreenshotToWriter = true
+	options.Scan.ScreenshotSkipSave = true
+
+	return options
+}
+
+// probe probes the URLs of a probe job
+func (a *Agent) probe(ctx context.Context, job *Job) error {
+	var payload jobs.Probe
+	if err := decode(job, &payload); err != nil {
+		return err
+	}
+	options := a.options(payload.Options)
+
+	driver, err := driver.NewChromedp(a.logger, options)
+	if err != nil {
+		return fmt.Errorf("failed to start driver: %w", err)
+	}
+
+	return runner.Probe(ctx, a.logger, driver, options, payload.URLs, payload.RateLimit,
+		[]writers.Writer{&uploadWriter{client: a.Client, job: job.ID}}, a.failure(job))
+}
+
+// retake retakes the screenshot of the result of a retake job
+func (a *Agent) retake(job *Job) error {
+	var payload jobs.Retake
+	if err := decode(job, &payload); err != nil {
+		return err
+	}
+	options := a.options(payload.Options)
+
+	driver, err := driver.NewChromedp(a.logger, options)
+	if err != nil {
+		return fmt.Errorf("failed to start driver: %w", err)
+	}
+
+	previous := &models.Result{ID: payload.ResultID, URL: payload.URL, ScanSessionID: payload.ScanSessionID}
+	if _, err := runner.Retake(a.logger, driver, options, previous,
+		[]writers.Writer{&uploadWriter{client: a.Client, job: job.ID}}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ipEnrich gathers fallback information for the IP address of an ip-enrich
+// job
+func (a *Agent) ipEnrich(ctx context.Context, job *Job) error {
+	var payload jobs.IPEnrich
+	if err := decode(job, &payload); err != nil {
+		return err
+	}
+
+	ipApiData, ports, err := fallback.Gather(ctx, payload.IPAddress, payload.ScanPorts)
+	if err != nil {
+		return err
+	}
+
+	return a.Client.IPInfo(job.ID, ipApiData, ports)
+}
+
+// failure returns a function reporting failed URLs of a job to the server
+func (a *Agent) failure(job *Job) func(target string, err error) {
+	return func(target string, err error) {
+		if err := a.Client.Failure(job.ID, target, err); err != nil {
+			a.logger.Error("failed to report failure", "id", job.ID, "target", target, "err", err)
+		}
+	}
+}
+
+// decode decodes the payload of a job into v
+func decode(job *Job, v any) error {
+	if err := json.Unmarshal(job.Payload, v); err != nil {
+		return fmt.Errorf("failed to decode job payload: %w", err)
+	}
+
+	return nil
+}
+
+// uploadWriter uploads results to the server
+type uploadWriter struct {
+	client *Client
+	job    uint
+}
+
+// Write uploads a result
+func (uw *uploadWriter) Write(result *models.Result) error {
+	return uw.client.Result(uw.job, result)
+}
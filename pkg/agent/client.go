@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/fallback"
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// agentHeader is the header the agent's name is sent in
+const agentHeader = "X-Gowitness-Agent"
+
+// maxBodySize is the most of an error response that is read
+const maxBodySize = 1 << 20
+
+// Job is a job claimed from the server
+type Job struct {
+	ID       uint            `json:"id"`
+	Kind     string          `json:"kind"`
+	Attempts int             `json:"attempts"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// Client is a client of the agent API of a gowitness server
+type Client struct {
+	baseURL    string
+	token      string
+	name       string
+	httpClient *http.Client
+}
+
+// NewClient returns a new client for the server at serverURL, that
+// authenticates with token as the agent name
+func NewClient(serverURL string, token string, name string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(serverURL, "/") + "/api/agent",
+		token:      token,
+		name:       name,
+		httpClient: islazy.NewHTTPClient(timeout),
+	}
+}
+
+// Claim claims the next queued job of one of kinds, returning nil if there
+// is none
+func (c *Client) Claim(kinds []string) (*Job, error) {
+	var job Job
+	found, err := c.post("/claim", map[string]any{"kinds": kinds}, &job)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Heartbeat tells the server the agent is still running a job
+func (c *Client) Heartbeat(id uint) error {
+	_, err := c.post(fmt.Sprintf("/jobs/%d/heartbeat", id), struct{}{}, nil)
+	return err
+}
+
+// Result uploads a result of a job, with its screenshot base64 encoded
+func (c *Client) Result(id uint, result *models.Result) error {
+	_, err := c.post(fmt.Sprintf("/jobs/%d/results", id), result, nil)
+	return err
+}
+
+// Failure reports an item of a job, such as a URL, that failed
+func (c *Client) Failure(id uint, item string, failure error) error {
+	_, err := c.post(fmt.Sprintf("/jobs/%d/failures", id),
+		map[string]string{"url": item, "error": failure.Error()}, nil)
+	return err
+}
+
+// IPInfo uploads the fallback information gathered for the IP address of a
+// job
+func (c *Client) IPInfo(id uint, ipApiData *fallback.IPAPIResponse, ports []int) error {
+	_, err := c.post(fmt.Sprintf("/jobs/%d/ip-info", id),
+		map[string]any{"ip_api": ipApiData, "ports": ports}, nil)
+	return err
+}
+
+// Finish reports the outcome of a job, failing the attempt if failure is
+// not nil
+func (c *Client) Finish(id uint, failure error) error {
+	request := map[string]string{"error": ""}
+	if failure != nil {
+		request["error"] = failure.Error()
+	}
+
+	_, err := c.post(fmt.Sprintf("/jobs/%d/finish", id), request, nil)
+	return err
+}
+
+// post posts body as JSON to an agent API path, decoding the response into
+// v if it is not nil. It reports if the server returned content.
+func (c *Client) post(path string, body any, v any) (bool, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set(agentHeader, c.name)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to reach server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		return false, fmt.Errorf("server error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return false, fmt.Errorf("failed to parse server response: %w", err)
+		}
+	}
+
+	return true, nil
+}
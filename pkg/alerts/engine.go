@@ -0,0 +1,203 @@
+package alerts
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/whois"
+	"gorm.io/gorm"
+)
+
+// Engine evaluates alert rules against what scans stored, and delivers the
+// alerts raised to its notifiers
+type Engine struct {
+	Rules     []Rule
+	Notifiers []Notifier
+
+	db  *gorm.DB
+	log *slog.Logger
+}
+
+// NewEngine returns a new Engine
+func NewEngine(logger *slog.Logger, db *gorm.DB, rules []Rule, notifiers ...Notifier) *Engine {
+	return &Engine{
+		Rules:     rules,
+		Notifiers: notifiers,
+		db:        db,
+		log:       logger,
+	}
+}
+
+// Evaluate raises alerts on the open ports, subdomains and expiring domains
+// of a scan session, or of all sessions if scanSessionID is nil, that a rule
+// fires on and did not fire on before. New alerts are delivered to the notifiers,
+// unless baseline is set. A baseline only records what exists, so that
+// only later changes are delivered.
+func (e *Engine) Evaluate(scanSessionID *uint, baseline bool) ([]models.Alert, error) {
+	seen, err := e.fired()
+	if err != nil {
+		return nil, err
+	}
+
+	var raised []models.Alert
+	raise := func(rule *Rule, subject string, ip string, sessionID *uint) {
+		key := rule.Name + "\x00" + subject
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+
+		description := rule.Description
+		if description == "" {
+			description = rule.Name
+		}
+		raised = append(raised, models.Alert{
+			Rule:          rule.Name,
+			Subject:       subject,
+			Severity:      rule.Severity,
+			Message:       fmt.Sprintf("%s: %s", description, subject),
+			IPAddress:     ip,
+			ScanSessionID: sessionID,
+		})
+	}
+
+	if e.hasKind(KindPort) {
+		var ports []models.IPPort
+		query := e.db.Where("state = ?", "open")
+		if scanSessionID != nil {
+			query = query.Where("scan_session_id = ?", *scanSessionID)
+		}
+		if err := query.Order("ip_address, port").Find(&ports).Error; err != nil {
+			return nil, fmt.Errorf("failed to get open ports: %w", err)
+		}
+
+		for _, port := range ports {
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			subject := fmt.Sprintf("%s:%d/%s", port.IPAddress, port.Port, protocol)
+
+			for i := range e.Rules {
+				if e.Rules[i].MatchesPort(port.Port) {
+					raise(&e.Rules[i], subject, port.IPAddress, port.ScanSessionID)
+				}
+			}
+		}
+	}
+
+	if e.hasKind(KindSubdomain) {
+		var domains []models.Domain
+		query := e.db.Select("name", "MIN(scan_session_id) AS scan_session_id").
+			Where("historical = ?", false).Group("name")
+		if scanSessionID != nil {
+			query = query.Where("scan_session_id = ?", *scanSessionID)
+		}
+		if err := query.Order("name").Find(&domains).Error; err != nil {
+			return nil, fmt.Errorf("failed to get subdomains: %w", err)
+		}
+
+		for _, domain := range domains {
+			for i := range e.Rules {
+				if e.Rules[i].Kind == KindSubdomain {
+					raise(&e.Rules[i], domain.Name, "", domain.ScanSessionID)
+				}
+			}
+		}
+	}
+
+	if e.hasKind(KindDomainExpiry) {
+		var registrations []models.DomainRegistration
+		query := e.db.Where("expiry_date IS NOT NULL")
+		if scanSessionID != nil {
+			query = query.Where("scan_session_id = ?", *scanSessionID)
+		}
+		if err := query.Find(&registrations).Error; err != nil {
+			return nil, fmt.Errorf("failed to get domain registrations: %w", err)
+		}
+
+		now := time.Now()
+		for i := range e.Rules {
+			if e.Rules[i].Kind != KindDomainExpiry {
+				continue
+			}
+
+			// the expiry date is part of the subject, so a renewed domain
+			// raises an alert again when its new expiry date comes up
+			for _, expiry := range whois.Expiring(registrations, e.Rules[i].Days, now) {
+				subject := fmt.Sprintf("%s expires %s", expiry.Domain, expiry.ExpiryDate.Format("2006-01-02"))
+				raise(&e.Rules[i], subject, "", expiry.ScanSessionID)
+			}
+		}
+	}
+
+	if len(raised) == 0 {
+		return raised, nil
+	}
+
+	if err := e.db.CreateInBatches(&raised, 100).Error; err != nil {
+		return nil, fmt.Errorf("failed to save alerts: %w", err)
+	}
+
+	if baseline {
+		e.log.Info("recorded alert baseline", "alerts", len(raised))
+		return raised, nil
+	}
+
+	e.deliver(raised)
+	return raised, nil
+}
+
+// fired returns the rule and subject pairs alerts were raised for before
+func (e *Engine) fired() (map[string]bool, error) {
+	var alerts []models.Alert
+	if err := e.db.Select("rule", "subject").Find(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get raised alerts: %w", err)
+	}
+
+	seen := make(map[string]bool, len(alerts))
+	for _, alert := range alerts {
+		seen[alert.Rule+"\x00"+alert.Subject] = true
+	}
+
+	return seen, nil
+}
+
+// hasKind checks if any rule is of a kind
+func (e *Engine) hasKind(kind string) bool {
+	for _, rule := range e.Rules {
+		if rule.Kind == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deliver sends alerts to every notifier, marking them delivered if any
+// notifier accepted them
+func (e *Engine) deliver(alerts []models.Alert) {
+	delivered := false
+	for _, notifier := range e.Notifiers {
+		if err := notifier.Notify(alerts); err != nil {
+			e.log.Warn("failed to deliver alerts", "notifier", notifier.Name(), "alerts", len(alerts), "err", err)
+			continue
+		}
+		e.log.Info("delivered alerts", "notifier", notifier.Name(), "alerts", len(alerts))
+		delivered = true
+	}
+	if !delivered {
+		return
+	}
+
+	ids := make([]uint, len(alerts))
+	for i, alert := range alerts {
+		ids[i] = alert.ID
+	}
+	if err := e.db.Model(&models.Alert{}).Where("id IN ?", ids).
+		UpdateColumn("delivered_at", time.Now()).Error; err != nil {
+		e.log.Warn("failed to mark alerts delivered", "err", err)
+	}
+}
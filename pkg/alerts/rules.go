@@ -0,0 +1,125 @@
+// Package alerts compares what scans found with what was seen before, and
+// raises alerts on changes that matter, such as newly exposed ports.
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"github.com/sensepost/gowitness/pkg/whois"
+)
+
+// Rule kinds
+const (
+	// KindPort rules fire on open ports
+	KindPort = "port"
+	// KindSubdomain rules fire on discovered hostnames
+	KindSubdomain = "subdomain"
+	// KindDomainExpiry rules fire on apex domains whose registration
+	// expires within Days, from 'scan whois'
+	KindDomainExpiry = "domain-expiry"
+)
+
+// Rule decides which changes raise an alert. Port rules fire on open ports
+// in Ports, or on any open port outside ExcludePorts if Ports is empty.
+// Domain expiry rules fire on registrations that expire within Days.
+type Rule struct {
+	Name         string `json:"name"`
+	Kind         string `json:"kind"`
+	Description  string `json:"description,omitempty"`
+	Severity     string `json:"severity"`
+	Ports        []int  `json:"ports,omitempty"`
+	ExcludePorts []int  `json:"exclude_ports,omitempty"`
+	Days         int    `json:"days,omitempty"`
+}
+
+// DefaultRules are the rules used without a rules file
+var DefaultRules = []Rule{
+	{
+		Name:        "remote-access",
+		Kind:        KindPort,
+		Description: "RDP or SMB exposed",
+		Severity:    "high",
+		Ports:       []int{3389, 445, 139},
+	},
+	{
+		Name:         "non-web-port",
+		Kind:         KindPort,
+		Description:  "port outside 80/443 exposed",
+		Severity:     "medium",
+		ExcludePorts: []int{80, 443},
+	},
+	{
+		Name:        "new-subdomain",
+		Kind:        KindSubdomain,
+		Description: "new subdomain discovered",
+		Severity:    "info",
+	},
+	{
+		Name:        "domain-expiry",
+		Kind:        KindDomainExpiry,
+		Description: "domain registration expiring",
+		Severity:    "high",
+		Days:        whois.DefaultDays,
+	},
+}
+
+// LoadRules reads rules from a JSON file holding an array of rules, e.g.
+//
+//	[
+//	  {"name": "databases", "kind": "port", "severity": "high", "ports": [3306, 5432, 27017]},
+//	  {"name": "new-subdomain", "kind": "subdomain", "severity": "info"},
+//	  {"name": "domain-expiry", "kind": "domain-expiry", "severity": "high", "days": 30}
+//	]
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse alert rules: %w", err)
+	}
+
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+// Validate checks a rule is complete
+func (r *Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("alert rule without a name")
+	}
+	if r.Kind != KindPort && r.Kind != KindSubdomain && r.Kind != KindDomainExpiry {
+		return fmt.Errorf("alert rule %s has unknown kind %q", r.Name, r.Kind)
+	}
+	if r.Kind == KindDomainExpiry && r.Days <= 0 {
+		return fmt.Errorf("alert rule %s needs days to be more than 0", r.Name)
+	}
+	if !slices.Contains(plugins.Severities, r.Severity) {
+		return fmt.Errorf("alert rule %s has invalid severity %q", r.Name, r.Severity)
+	}
+
+	return nil
+}
+
+// MatchesPort checks if a port rule fires on an open port
+func (r *Rule) MatchesPort(port int) bool {
+	if r.Kind != KindPort {
+		return false
+	}
+	if len(r.Ports) > 0 {
+		return slices.Contains(r.Ports, port)
+	}
+
+	return !slices.Contains(r.ExcludePorts, port)
+}
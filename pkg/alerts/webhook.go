@@ -0,0 +1,80 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// Notifier delivers alerts somewhere people will see them
+type Notifier interface {
+	Name() string
+	Notify(alerts []models.Alert) error
+}
+
+// Webhook posts alerts as JSON to a URL. The payload carries a "text"
+// summary, so Slack and Mattermost incoming webhooks can take it as is.
+type Webhook struct {
+	URL string
+
+	client *http.Client
+}
+
+// webhookPayload is the body posted to a webhook
+type webhookPayload struct {
+	Text   string         `json:"text"`
+	Alerts []models.Alert `json:"alerts"`
+}
+
+// NewWebhook returns a new Webhook notifier
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		URL:    url,
+		client: islazy.NewHTTPClient(30 * time.Second),
+	}
+}
+
+// Name returns the notifier name
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Notify posts alerts to the webhook
+func (w *Webhook) Notify(alerts []models.Alert) error {
+	body, err := json.Marshal(&webhookPayload{
+		Text:   Summary(alerts),
+		Alerts: alerts,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alerts: %w", err)
+	}
+
+	resp, err := w.client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post alerts: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Summary renders alerts as text, one alert per line
+func Summary(alerts []models.Alert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "gowitness raised %d alert(s):", len(alerts))
+	for _, alert := range alerts {
+		fmt.Fprintf(&b, "\n[%s] %s", alert.Severity, alert.Message)
+	}
+
+	return b.String()
+}
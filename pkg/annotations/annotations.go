@@ -0,0 +1,127 @@
+// Package annotations flags noteworthy characteristics of probed
+// responses, to guide manual review.
+package annotations
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// Kinds of annotations
+const (
+	// KindLargeBody is a response body larger than LargeBodySize
+	KindLargeBody = "large-body"
+	// KindSmallBody is a successful response with a body smaller than
+	// SmallBodySize, often a blank page or an API
+	KindSmallBody = "small-body"
+	// KindAuthChallenge is a 401 response with a WWW-Authenticate
+	// challenge, such as basic auth
+	KindAuthChallenge = "auth-challenge"
+	// KindCrossApexRedirect is a redirect to a different apex domain
+	KindCrossApexRedirect = "cross-apex-redirect"
+	// KindProtectedServer is a 401 or 403 response from a server that
+	// names its product or version
+	KindProtectedServer = "protected-server"
+)
+
+// Kinds are all annotation kinds
+var Kinds = []string{
+	KindLargeBody,
+	KindSmallBody,
+	KindAuthChallenge,
+	KindCrossApexRedirect,
+	KindProtectedServer,
+}
+
+const (
+	// LargeBodySize is the body size from which a body is large
+	LargeBodySize = 5 << 20
+	// SmallBodySize is the body size below which a body is small
+	SmallBodySize = 64
+)
+
+// versionRe matches a product version in a Server header, e.g. nginx/1.18.0
+var versionRe = regexp.MustCompile(`/\d`)
+
+// genericServers are Server header values too common to be interesting
+var genericServers = []string{
+	"cloudflare", "akamaighost", "awselb", "cloudfront", "envoy", "varnish",
+	"nginx", "apache", "microsoft-iis", "gws", "amazons3",
+}
+
+// Annotate returns the annotations of a result
+func Annotate(result *models.Result) []models.Annotation {
+	var annotations []models.Annotation
+	add := func(kind, detail string) {
+		annotations = append(annotations, models.Annotation{Kind: kind, Detail: detail})
+	}
+
+	size := max(result.ContentLength, int64(len(result.HTML)))
+	switch {
+	case size >= LargeBodySize:
+		add(KindLargeBody, fmt.Sprintf("%d bytes", size))
+	case size < SmallBodySize && result.ResponseCode >= 200 && result.ResponseCode < 300:
+		add(KindSmallBody, fmt.Sprintf("%d bytes", size))
+	}
+
+	var challenge, server string
+	for _, header := range result.Headers {
+		switch strings.ToLower(header.Key) {
+		case "www-authenticate":
+			challenge = header.Value
+		case "server":
+			server = header.Value
+		}
+	}
+
+	if result.ResponseCode == 401 && challenge != "" {
+		add(KindAuthChallenge, challenge)
+	}
+
+	if (result.ResponseCode == 401 || result.ResponseCode == 403) && interestingServer(server) {
+		add(KindProtectedServer, server)
+	}
+
+	if from, to := apex(result.URL), apex(result.FinalURL); from != "" && to != "" && from != to {
+		add(KindCrossApexRedirect, from+" -> "+to)
+	}
+
+	return annotations
+}
+
+// interestingServer checks if a Server header names a product worth a look,
+// meaning it has a version or is not a common web server or CDN
+func interestingServer(server string) bool {
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return false
+	}
+	if versionRe.MatchString(server) {
+		return true
+	}
+
+	name := strings.ToLower(strings.Fields(server)[0])
+	return !islazy.SliceHasStr(genericServers, name)
+}
+
+// apex returns the apex domain of a URL's host, or the host itself for IP
+// addresses
+func apex(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" || net.ParseIP(host) != nil {
+		return host
+	}
+
+	return islazy.ApexDomain(host)
+}
@@ -0,0 +1,129 @@
+// Package archive stores response bodies on disk, content addressed by
+// their SHA-256 hash and gzip compressed, so that a body served by many
+// hosts, such as a framework asset, is only stored once.
+package archive
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrInvalidHash is returned for hashes that are not a hex SHA-256 hash
+var ErrInvalidHash = errors.New("invalid body hash")
+
+// Archive is a directory of response bodies. Bodies are stored at
+// <dir>/<first two characters of the hash>/<hash>.gz, so that they can be
+// searched with zgrep.
+type Archive struct {
+	dir string
+}
+
+// New returns an archive in dir, creating it if needed
+func New(dir string) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create body archive: %w", err)
+	}
+
+	return &Archive{dir: dir}, nil
+}
+
+// Hash returns the hash a body is stored under
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Store archives a body, returning its hash. Bodies that are archived
+// already are not written again.
+func (a *Archive) Store(content []byte) (string, error) {
+	hash := Hash(content)
+	file := a.path(hash)
+
+	if _, err := os.Stat(file); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", fmt.Errorf("could not create body archive directory: %w", err)
+	}
+
+	// write to a temporary file first, so that a body that is stored by
+	// several probes at once is never seen half written
+	tmp, err := os.CreateTemp(filepath.Dir(file), hash+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("could not archive body: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	if _, err := zw.Write(content); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("could not archive body: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("could not archive body: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("could not archive body: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return "", fmt.Errorf("could not archive body: %w", err)
+	}
+
+	return hash, nil
+}
+
+// Open returns a reader of an archived body
+func (a *Archive) Open(hash string) (io.ReadCloser, error) {
+	if !validHash(hash) {
+		return nil, ErrInvalidHash
+	}
+
+	file, err := os.Open(a.path(hash))
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not read archived body: %w", err)
+	}
+
+	return &body{Reader: zr, file: file}, nil
+}
+
+// path returns the file a body is stored in
+func (a *Archive) path(hash string) string {
+	return filepath.Join(a.dir, hash[:2], hash+".gz")
+}
+
+// validHash checks that a hash is a hex SHA-256 hash, which also keeps it
+// from escaping the archive directory
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+
+	return err == nil
+}
+
+// body is an archived body being read
+type body struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (b *body) Close() error {
+	b.Reader.Close()
+	return b.file.Close()
+}
@@ -0,0 +1,69 @@
+package asn
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// ripeStatURL is the RIPEstat announced prefixes endpoint. It serves BGP
+// data for any ASN, not just those in the RIPE region.
+const ripeStatURL = "https://stat.ripe.net/data/announced-prefixes/data.json?resource=%s"
+
+// ripeStatResponse is the response from the announced prefixes endpoint
+type ripeStatResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		Prefixes []struct {
+			Prefix string `json:"prefix"`
+		} `json:"prefixes"`
+	} `json:"data"`
+}
+
+// Normalise returns an ASN in the AS12345 form, accepting either
+// "AS12345", "as12345" or "12345".
+func Normalise(asn string) string {
+	asn = strings.ToUpper(strings.TrimSpace(asn))
+	if !strings.HasPrefix(asn, "AS") {
+		asn = "AS" + asn
+	}
+
+	return asn
+}
+
+// AnnouncedPrefixes returns the prefixes an ASN currently announces in BGP
+func AnnouncedPrefixes(asn string) ([]string, error) {
+	client := islazy.NewHTTPClient(30 * time.Second)
+
+	resp, err := client.Get(fmt.Sprintf(ripeStatURL, Normalise(asn)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query RIPEstat: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RIPEstat response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("RIPEstat error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response ripeStatResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse RIPEstat response: %w", err)
+	}
+
+	var prefixes []string
+	for _, prefix := range response.Data.Prefixes {
+		prefixes = append(prefixes, prefix.Prefix)
+	}
+
+	return prefixes, nil
+}
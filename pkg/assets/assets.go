@@ -0,0 +1,239 @@
+// Package assets imports the business context of hosts, such as who owns
+// them and how critical they are, from CMDB exports, and matches it to the
+// hosts gowitness scans.
+package assets
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// columns are the names of the known CSV columns, and the other names
+// CMDB exports give them
+var columns = map[string][]string{
+	"host":        {"host", "hostname", "ip", "ip_address", "address", "fqdn"},
+	"label":       {"label", "name", "asset", "asset_name"},
+	"owner":       {"owner", "team", "contact"},
+	"environment": {"environment", "env"},
+	"criticality": {"criticality", "priority", "tier"},
+}
+
+// Parse reads assets from CSV with a header row. The host column is
+// required, and label, owner, environment and criticality are optional.
+// Other columns are kept as the metadata of each asset. Rows without a host
+// are skipped.
+func Parse(r io.Reader) ([]models.Asset, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	// index is the column of each known field, and extra the other columns
+	index := make(map[string]int)
+	extra := make(map[int]string)
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		key := strings.ReplaceAll(strings.ToLower(name), " ", "_")
+
+		field := ""
+		for f, aliases := range columns {
+			if slices.Contains(aliases, key) {
+				field = f
+				break
+			}
+		}
+		if _, seen := index[field]; field != "" && !seen {
+			index[field] = i
+		} else if name != "" {
+			extra[i] = name
+		}
+	}
+	if _, ok := index["host"]; !ok {
+		return nil, errors.New("the CSV has no host column")
+	}
+
+	var assets []models.Asset
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+
+		value := func(field string) string {
+			i, ok := index[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		host := NormaliseHost(value("host"))
+		if host == "" {
+			continue
+		}
+
+		asset := models.Asset{
+			Host:        host,
+			Label:       value("label"),
+			Owner:       value("owner"),
+			Environment: strings.ToLower(value("environment")),
+			Criticality: strings.ToLower(value("criticality")),
+		}
+		if asset.Criticality != "" && !slices.Contains(models.AssetCriticalities, asset.Criticality) {
+			return nil, fmt.Errorf("invalid criticality %q on CSV line %d. use one of: %s",
+				asset.Criticality, line, strings.Join(models.AssetCriticalities, ", "))
+		}
+
+		metadata := make(map[string]string)
+		for i, name := range extra {
+			if i < len(record) && strings.TrimSpace(record[i]) != "" {
+				metadata[name] = strings.TrimSpace(record[i])
+			}
+		}
+		if err := asset.SetMetadata(metadata); err != nil {
+			return nil, err
+		}
+
+		assets = append(assets, asset)
+	}
+
+	return assets, nil
+}
+
+// NormaliseHost cleans up the host of an asset, which may be a URL
+func NormaliseHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if _, after, ok := strings.Cut(host, "://"); ok {
+		host = after
+	}
+	// CIDRs keep their prefix, and anything else loses its path
+	if _, err := netip.ParsePrefix(host); err == nil {
+		return host
+	}
+	host, _, _ = strings.Cut(host, "/")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+}
+
+// Save saves assets, updating the assets of hosts that were imported
+// before. It returns how many were created and how many updated.
+func Save(db *gorm.DB, assets []models.Asset, scanSessionID *uint) (created int, updated int, err error) {
+	for _, asset := range assets {
+		asset.ScanSessionID = scanSessionID
+
+		var count int64
+		if err := db.Model(&models.Asset{}).Where("host = ?", asset.Host).Count(&count).Error; err != nil {
+			return created, updated, err
+		}
+
+		if err := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "host"}},
+			DoUpdates: clause.AssignmentColumns([]string{"label", "owner", "environment", "criticality",
+				"metadata", "scan_session_id", "updated_at"}),
+		}).Create(&asset).Error; err != nil {
+			return created, updated, fmt.Errorf("failed to save asset %s: %w", asset.Host, err)
+		}
+
+		if count > 0 {
+			updated++
+		} else {
+			created++
+		}
+	}
+
+	return created, updated, nil
+}
+
+// Inventory is the imported assets, to match hosts to
+type Inventory struct {
+	assets []models.Asset
+}
+
+// Load returns the imported assets of a database
+func Load(db *gorm.DB) (*Inventory, error) {
+	inventory := &Inventory{}
+	if err := db.Order("host").Find(&inventory.assets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+
+	return inventory, nil
+}
+
+// Len returns the number of assets
+func (inv *Inventory) Len() int {
+	return len(inv.assets)
+}
+
+// Match returns the asset a host is, or nil. host is a hostname, IP address
+// or URL, and ip the address it was seen on, if known. The asset of the
+// host itself is preferred, then that of a wildcard covering it, and then
+// the smallest CIDR containing its address.
+func (inv *Inventory) Match(host string, ip string) *models.Asset {
+	if inv == nil || len(inv.assets) == 0 {
+		return nil
+	}
+
+	host = NormaliseHost(host)
+	if ip == "" && net.ParseIP(host) != nil {
+		ip = host
+	}
+
+	var wildcard, network *models.Asset
+	networkBits := -1
+	addr, addrErr := netip.ParseAddr(ip)
+
+	for i := range inv.assets {
+		asset := &inv.assets[i]
+		switch {
+		case asset.Host == host || (ip != "" && asset.Host == ip):
+			return asset
+		case strings.HasPrefix(asset.Host, "*."):
+			domain := asset.Host[2:]
+			if wildcard == nil && host != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+				wildcard = asset
+			}
+		case addrErr == nil:
+			prefix, err := netip.ParsePrefix(asset.Host)
+			if err == nil && prefix.Contains(addr) && prefix.Bits() > networkBits {
+				network, networkBits = asset, prefix.Bits()
+			}
+		}
+	}
+
+	if wildcard != nil {
+		return wildcard
+	}
+
+	return network
+}
+
+// ForHost returns the asset a host is, from the assets of a database, or
+// nil. See Inventory.Match.
+func ForHost(db *gorm.DB, host string, ip string) (*models.Asset, error) {
+	inventory, err := Load(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return inventory.Match(host, ip), nil
+}
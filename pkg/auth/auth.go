@@ -0,0 +1,343 @@
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Roles a user can have
+const (
+	// RoleAdmin can do everything, including managing users
+	RoleAdmin = "admin"
+	// RoleAnalyst can view, submit and delete results
+	RoleAnalyst = "analyst"
+	// RoleViewer can only view results
+	RoleViewer = "viewer"
+)
+
+// Roles are all of the valid roles
+var Roles = []string{RoleAdmin, RoleAnalyst, RoleViewer}
+
+const (
+	// SessionLifetime is how long a login session lasts
+	SessionLifetime = 12 * time.Hour
+
+	// hashIterations is the number of pbkdf2 iterations password hashes use
+	hashIterations = 600000
+	// hashPrefix identifies the password hash format
+	hashPrefix = "pbkdf2-sha256"
+	// minPasswordLength is the shortest password a user may have
+	minPasswordLength = 8
+)
+
+var (
+	// ErrInvalidCredentials is returned when a username or password is wrong
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrInvalidRole is returned for roles that don't exist
+	ErrInvalidRole = errors.New("invalid role. valid roles are admin, analyst and viewer")
+	// ErrWeakPassword is returned for passwords that are too short
+	ErrWeakPassword = fmt.Errorf("passwords must be at least %d characters", minPasswordLength)
+	// ErrInvalidSession is returned for unknown or expired sessions
+	ErrInvalidSession = errors.New("invalid or expired session")
+)
+
+// ValidRole checks if role is a valid role
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanWrite checks if a role may change data
+func CanWrite(role string) bool {
+	return role == RoleAdmin || role == RoleAnalyst
+}
+
+// HashPassword hashes a password for storage
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, hashIterations, 32)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s$%d$%s$%s", hashPrefix, hashIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPassword checks a password against a hash from HashPassword
+func CheckPassword(hash string, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != hashPrefix {
+		return false
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+// HasUsers checks if any users exist, meaning per-user logins are in use
+func HasUsers(db *gorm.DB) (bool, error) {
+	var count int64
+	if err := db.Model(&models.User{}).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// CreateUser creates a new user
+func CreateUser(db *gorm.DB, username string, password string, role string) (*models.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, errors.New("a username is required")
+	}
+	if !ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if len(password) < minPasswordLength {
+		return nil, ErrWeakPassword
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &models.User{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+	}
+	if err := db.Create(user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SetPassword changes the password of a user, ending their sessions
+func SetPassword(db *gorm.DB, user *models.User, password string) error {
+	if len(password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("password_hash", hash).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", user.ID).Delete(&models.UserSession{}).Error
+	})
+}
+
+// Authenticate checks a username and password, returning the user
+func Authenticate(db *gorm.DB, username string, password string) (*models.User, error) {
+	var user models.User
+	if err := db.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// spend the same time as a real check, so that valid usernames
+			// can't be told apart by timing
+			CheckPassword(dummyHash(), password)
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if user.Disabled || !CheckPassword(user.PasswordHash, password) {
+		return nil, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	if err := db.Model(&user).Update("last_login", now).Error; err != nil {
+		return nil, err
+	}
+	user.LastLogin = &now
+
+	return &user, nil
+}
+
+// ExternalUser returns the user an identity provider authenticated as
+// subject, creating it with role on their first login. Users are found by
+// issuer and subject only, so a provider account never logs in as a local
+// user of the same name. New users are named username, or username with a
+// suffix if that name is taken. External users have no password, so they
+// can't log in with one.
+func ExternalUser(db *gorm.DB, issuer string, subject string, username string, role string) (*models.User, error) {
+	if issuer == "" || subject == "" {
+		return nil, errors.New("an issuer and subject are required")
+	}
+	username = strings.TrimSpace(username)
+	if username == "" {
+		username = subject
+	}
+	if !ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+
+	var identity models.UserIdentity
+	err := db.Preload("User").Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		identity, err = createExternalUser(db, issuer, subject, username, role)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user := identity.User
+	if user.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	if err := db.Model(&user).Update("last_login", now).Error; err != nil {
+		return nil, err
+	}
+	user.LastLogin = &now
+
+	return &user, nil
+}
+
+// createExternalUser creates a user and the identity they log in with
+func createExternalUser(db *gorm.DB, issuer string, subject string, username string, role string) (models.UserIdentity, error) {
+	identity := models.UserIdentity{Issuer: issuer, Subject: subject}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// the name of a local or other external user is not reused
+		name := username
+		sum := sha256.Sum256([]byte(issuer + "\x00" + subject))
+		for i := 0; ; i++ {
+			var count int64
+			if err := tx.Model(&models.User{}).Where("username = ?", name).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				break
+			}
+			if i >= 3 {
+				return fmt.Errorf("no free username for %q", username)
+			}
+			name = fmt.Sprintf("%s#%x", username, sum[:2+i])
+		}
+
+		identity.User = models.User{Username: name, Role: role}
+		return tx.Create(&identity).Error
+	})
+
+	return identity, err
+}
+
+// dummyHash is checked against when a user does not exist
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("gowitness")
+	return hash
+})
+
+// NewSession starts a login session for a user, returning the token that
+// identifies it. Only a hash of the token is stored.
+func NewSession(db *gorm.DB, user *models.User) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	session := &models.UserSession{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(SessionLifetime),
+	}
+	if err := db.Create(session).Error; err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return token, nil
+}
+
+// SessionUser returns the user of a session token
+func SessionUser(db *gorm.DB, token string) (*models.User, error) {
+	var session models.UserSession
+	if err := db.Where("token_hash = ?", hashToken(token)).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, err
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		db.Delete(&session)
+		return nil, ErrInvalidSession
+	}
+
+	var user models.User
+	if err := db.First(&user, session.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, ErrInvalidSession
+	}
+
+	return &user, nil
+}
+
+// EndSession ends the session of a token
+func EndSession(db *gorm.DB, token string) error {
+	return db.Where("token_hash = ?", hashToken(token)).Delete(&models.UserSession{}).Error
+}
+
+// PruneSessions deletes expired sessions
+func PruneSessions(db *gorm.DB) error {
+	return db.Where("expires_at < ?", time.Now()).Delete(&models.UserSession{}).Error
+}
+
+// hashToken hashes a session token for storage
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,468 @@
+// Package backup archives project directories, as created by scan init,
+// and restores them. An archive holds a consistent snapshot of the
+// project's database, taken while it may be in use, along with its
+// screenshots, logo and other files.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// ManifestName is the name of the manifest in an archive
+const ManifestName = "gowitness-backup.json"
+
+// ErrUnsupportedFormat is returned for archive names that are not .tar,
+// .tar.gz, .tgz or .tar.zst
+var ErrUnsupportedFormat = errors.New("unsupported archive format, use .tar, .tar.gz, .tgz or .tar.zst")
+
+// ErrProjectExists is returned when restoring over an existing project
+var ErrProjectExists = errors.New("project directory already exists")
+
+// Manifest describes an archive
+type Manifest struct {
+	// Target is the name of the project, and of its directory
+	Target string `json:"target"`
+	// Database is the name of the project's database file
+	Database string `json:"database"`
+	// Dir is the absolute path the project was archived from. Sessions
+	// record absolute screenshot and logo paths inside it, which are
+	// rewritten when the project is restored elsewhere.
+	Dir           string    `json:"dir"`
+	SchemaVersion uint      `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Summary is a summary of a backup or restore
+type Summary struct {
+	Manifest Manifest `json:"manifest"`
+	Files    int64    `json:"files"`
+	Bytes    int64    `json:"bytes"`
+}
+
+// Compressions of an archive
+const (
+	compressNone = iota
+	compressGzip
+	compressZstd
+)
+
+// compression returns how an archive is compressed, by its name, checking
+// that it is a supported format
+func compression(name string) (int, error) {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return compressGzip, nil
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return compressZstd, nil
+	case strings.HasSuffix(name, ".tar"):
+		return compressNone, nil
+	}
+
+	return compressNone, ErrUnsupportedFormat
+}
+
+// sqliteFile checks if a file is a SQLite database, or one of its journals
+func sqliteFile(name string) bool {
+	for _, suffix := range []string{".sqlite3", ".sqlite3-wal", ".sqlite3-shm", ".sqlite3-journal"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Backup archives the project in dir to out. The project's database,
+// <dir>/<target>.sqlite3, is snapshotted with VACUUM INTO, so scans may keep
+// writing to it.
+func Backup(dir string, out string) (*Summary, error) {
+	compress, err := compression(out)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	// the archive may be written inside the project, and is not archived
+	outFile, err := filepath.Abs(out)
+	if err != nil {
+		return nil, err
+	}
+	target := filepath.Base(dir)
+	dbFile := filepath.Join(dir, target+".sqlite3")
+	if _, err := os.Stat(dbFile); err != nil {
+		return nil, fmt.Errorf("failed to find project database: %w", err)
+	}
+
+	snapshot, version, err := snapshotDatabase(dbFile)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(snapshot)
+
+	summary := &Summary{Manifest: Manifest{
+		Target:        target,
+		Database:      target + ".sqlite3",
+		Dir:           dir,
+		SchemaVersion: version,
+		CreatedAt:     time.Now(),
+	}}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer file.Close()
+
+	var w io.Writer = file
+	var zw io.WriteCloser
+	switch compress {
+	case compressGzip:
+		zw = gzip.NewWriter(file)
+		w = zw
+	case compressZstd:
+		if zw, err = zstd.NewWriter(file); err != nil {
+			return nil, err
+		}
+		w = zw
+	}
+	tw := tar.NewWriter(w)
+
+	manifest, err := json.MarshalIndent(summary.Manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    ManifestName,
+		Mode:    0644,
+		Size:    int64(len(manifest)),
+		ModTime: summary.Manifest.CreatedAt,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	if err := addFile(tw, snapshot, path.Join(target, summary.Manifest.Database), summary); err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// the database is archived from its snapshot
+		if entry.IsDir() || !entry.Type().IsRegular() || sqliteFile(entry.Name()) || file == outFile {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+
+		return addFile(tw, file, path.Join(target, filepath.ToSlash(rel)), summary)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive project: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	return summary, nil
+}
+
+// snapshotDatabase writes a consistent copy of a SQLite database to a
+// temporary file, returning its path and schema version
+func snapshotDatabase(dbFile string) (string, uint, error) {
+	db, err := database.Open("sqlite://"+filepath.ToSlash(dbFile), true, false)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open project database: %w", err)
+	}
+	defer closeDatabase(db)
+
+	version, err := database.SchemaVersion(db)
+	if err != nil {
+		return "", 0, err
+	}
+
+	tmp, err := os.CreateTemp("", "gowitness-backup-*.sqlite3")
+	if err != nil {
+		return "", 0, err
+	}
+	tmp.Close()
+	// VACUUM INTO refuses to overwrite a file
+	os.Remove(tmp.Name())
+
+	if err := db.Exec("VACUUM INTO ?", tmp.Name()).Error; err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, fmt.Errorf("failed to snapshot project database: %w", err)
+	}
+
+	return tmp.Name(), version, nil
+}
+
+// closeDatabase closes the connections of a database
+func closeDatabase(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// addFile adds a file to an archive under name
+func addFile(tw *tar.Writer, file string, name string, summary *Summary) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	n, err := io.Copy(tw, f)
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	summary.Files++
+	summary.Bytes += n
+
+	return nil
+}
+
+// Restore extracts an archive into projectsDir, as <projectsDir>/<target>.
+// An existing project is only replaced with force, and only once the
+// archive was extracted in full. Screenshot and logo
+// paths recorded by the project's sessions are rewritten to the restored
+// location, and its database is migrated to the latest schema.
+func Restore(archive string, projectsDir string, force bool) (*Summary, error) {
+	compress, err := compression(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	switch compress {
+	case compressGzip:
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case compressZstd:
+		zr, err := zstd.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	tr := tar.NewReader(r)
+
+	// the manifest is always the first entry
+	header, err := tr.Next()
+	if err != nil || header.Name != ManifestName {
+		return nil, errors.New("not a gowitness backup, it has no manifest")
+	}
+	summary := &Summary{}
+	if err := json.NewDecoder(tr).Decode(&summary.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+	if summary.Manifest.Target == "" || summary.Manifest.Target != filepath.Base(summary.Manifest.Target) {
+		return nil, fmt.Errorf("backup manifest has an invalid target: %q", summary.Manifest.Target)
+	}
+	if summary.Manifest.SchemaVersion > database.LatestVersion() {
+		return nil, fmt.Errorf("%w: version %d, expected %d", database.ErrSchemaTooNew, summary.Manifest.SchemaVersion, database.LatestVersion())
+	}
+
+	dir, err := filepath.Abs(filepath.Join(projectsDir, summary.Manifest.Target))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err == nil && !force {
+		return nil, fmt.Errorf("%w: %s", ErrProjectExists, dir)
+	}
+
+	// the project is extracted next to where it goes, and only replaces an
+	// existing project once it was restored in full
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create projects directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), "."+summary.Manifest.Target+"-restore-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// entries are <target>/<file>, and must stay inside the project
+		name := path.Clean(header.Name)
+		rel, ok := strings.CutPrefix(name, summary.Manifest.Target+"/")
+		if !ok || rel == "" || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+			return nil, fmt.Errorf("archive entry is outside the project: %s", header.Name)
+		}
+
+		n, err := extractFile(tr, filepath.Join(tmp, filepath.FromSlash(rel)), header.FileInfo().Mode().Perm())
+		if err != nil {
+			return nil, err
+		}
+		summary.Files++
+		summary.Bytes += n
+	}
+
+	if err := relocateSessions(filepath.Join(tmp, summary.Manifest.Database), summary.Manifest.Dir, dir); err != nil {
+		return nil, err
+	}
+
+	if err := replaceDir(tmp, dir); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// replaceDir moves the directory from to to, replacing any directory there.
+// The existing directory is moved aside first, and moved back if from can't
+// take its place.
+func replaceDir(from string, to string) error {
+	if _, err := os.Stat(to); err != nil {
+		if err := os.Rename(from, to); err != nil {
+			return fmt.Errorf("failed to move restored project into place: %w", err)
+		}
+		return nil
+	}
+
+	old := from + "-old"
+	if err := os.Rename(to, old); err != nil {
+		return fmt.Errorf("failed to move existing project aside: %w", err)
+	}
+	if err := os.Rename(from, to); err != nil {
+		if restoreErr := os.Rename(old, to); restoreErr != nil {
+			return fmt.Errorf("failed to move restored project into place: %w, and the existing project was left at %s: %w", err, old, restoreErr)
+		}
+		return fmt.Errorf("failed to move restored project into place: %w", err)
+	}
+	if err := os.RemoveAll(old); err != nil {
+		return fmt.Errorf("failed to remove the replaced project at %s: %w", old, err)
+	}
+
+	return nil
+}
+
+// extractFile writes an archive entry to file
+func extractFile(r io.Reader, file string, mode fs.FileMode) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create project directory: %w", err)
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode|0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore %s: %w", file, err)
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return n, fmt.Errorf("failed to restore %s: %w", file, err)
+	}
+
+	return n, f.Close()
+}
+
+// relocateSessions rewrites the screenshot and logo paths of a restored
+// project's sessions from the directory it was archived from to the one it
+// was restored to
+func relocateSessions(dbFile string, from string, to string) error {
+	db, err := database.Connection("sqlite://"+filepath.ToSlash(dbFile), true, false)
+	if err != nil {
+		return fmt.Errorf("failed to open restored database: %w", err)
+	}
+	defer closeDatabase(db)
+
+	if from == "" || from == to {
+		return nil
+	}
+
+	relocate := func(p string) string {
+		rel, err := filepath.Rel(from, p)
+		if p == "" || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return p
+		}
+		return filepath.Join(to, rel)
+	}
+
+	var sessions []models.ScanSession
+	if err := db.Select("id", "screenshot_path", "logo_path").Find(&sessions).Error; err != nil {
+		return fmt.Errorf("failed to read restored sessions: %w", err)
+	}
+
+	for _, session := range sessions {
+		if err := db.Model(&models.ScanSession{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+			"screenshot_path": relocate(session.ScreenshotPath),
+			"logo_path":       relocate(session.LogoPath),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to relocate session %d: %w", session.ID, err)
+		}
+	}
+
+	return nil
+}
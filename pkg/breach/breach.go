@@ -0,0 +1,62 @@
+// Package breach looks up how exposed the accounts of a domain are in
+// public data breaches, through services such as HaveIBeenPwned.
+package breach
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// Exposure is what a provider knows about the breached accounts of a domain
+type Exposure struct {
+	Domain string
+	// Source is the name of the provider that reported the exposure
+	Source string
+	// Accounts is the number of breached accounts on the domain
+	Accounts int
+	// Breaches are the names of the breaches the accounts appeared in
+	Breaches []string
+}
+
+// Provider looks up the breach exposure of domains
+type Provider interface {
+	// Name returns the name of the provider
+	Name() string
+	// Domain returns the breach exposure of the accounts of an apex domain
+	Domain(domain string) (*Exposure, error)
+}
+
+// ProvidersFromEnv returns all of the providers that have API keys
+// configured in the environment. It attempts to load from a .env file
+// first, then falls back to the system environment.
+func ProvidersFromEnv() []Provider {
+	// Try to load .env file (ignore errors as it may not exist)
+	_ = godotenv.Load()
+
+	var providers []Provider
+
+	if apiKey := os.Getenv("HIBP_API_KEY"); apiKey != "" {
+		providers = append(providers, NewHIBP(apiKey))
+	}
+
+	if apiKey := os.Getenv("DEHASHED_API_KEY"); apiKey != "" {
+		providers = append(providers, NewDehashed(apiKey))
+	}
+
+	return providers
+}
+
+// appendUnique appends the values not in list yet to it
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" && !slices.Contains(list, value) {
+			list = append(list, value)
+		}
+	}
+
+	return list
+}
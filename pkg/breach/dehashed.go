@@ -0,0 +1,97 @@
+package breach
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// SourceDehashed is the source name of DeHashed
+const SourceDehashed = "dehashed"
+
+// dehashedPageSize is the number of entries requested to name the breaches
+// of a domain. The total number of accounts is reported regardless.
+const dehashedPageSize = 1000
+
+// Dehashed is a DeHashed search client
+type Dehashed struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// dehashedSearch is the response from the search endpoint
+type dehashedSearch struct {
+	Total   int `json:"total"`
+	Entries []struct {
+		DatabaseName string `json:"database_name"`
+	} `json:"entries"`
+}
+
+// NewDehashed returns a new DeHashed client
+func NewDehashed(apiKey string) *Dehashed {
+	return &Dehashed{
+		apiKey:     apiKey,
+		baseURL:    "https://api.dehashed.com/v2",
+		httpClient: islazy.NewHTTPClient(30 * time.Second),
+	}
+}
+
+// Name returns the provider name
+func (d *Dehashed) Name() string {
+	return SourceDehashed
+}
+
+// Domain returns the breached records DeHashed knows of for a domain
+func (d *Dehashed) Domain(domain string) (*Exposure, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"query": "domain:" + domain,
+		"size":  dehashedPageSize,
+		"page":  1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.baseURL+"/search", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Dehashed-Api-Key", d.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query DeHashed API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DeHashed API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response dehashedSearch
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse DeHashed response: %w", err)
+	}
+
+	exposure := &Exposure{Domain: domain, Source: SourceDehashed, Accounts: response.Total}
+	for _, entry := range response.Entries {
+		exposure.Breaches = appendUnique(exposure.Breaches, entry.DatabaseName)
+	}
+	sort.Strings(exposure.Breaches)
+
+	return exposure, nil
+}
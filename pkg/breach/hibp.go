@@ -0,0 +1,84 @@
+package breach
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// SourceHIBP is the source name of HaveIBeenPwned
+const SourceHIBP = "hibp"
+
+// HIBP is a HaveIBeenPwned domain search client. Domains must be verified
+// in the HaveIBeenPwned dashboard of the API key before they can be
+// searched.
+type HIBP struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewHIBP returns a new HaveIBeenPwned client
+func NewHIBP(apiKey string) *HIBP {
+	return &HIBP{
+		apiKey:     apiKey,
+		baseURL:    "https://haveibeenpwned.com/api/v3",
+		httpClient: islazy.NewHTTPClient(30 * time.Second),
+	}
+}
+
+// Name returns the provider name
+func (h *HIBP) Name() string {
+	return SourceHIBP
+}
+
+// Domain returns the breached accounts HaveIBeenPwned knows of for a domain
+func (h *HIBP) Domain(domain string) (*Exposure, error) {
+	req, err := http.NewRequest(http.MethodGet, h.baseURL+"/breacheddomain/"+url.PathEscape(domain), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("hibp-api-key", h.apiKey)
+	req.Header.Set("User-Agent", "gowitness")
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query HaveIBeenPwned API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	exposure := &Exposure{Domain: domain, Source: SourceHIBP}
+
+	// a domain without breached accounts is not found
+	if resp.StatusCode == http.StatusNotFound {
+		return exposure, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HaveIBeenPwned API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	// breached aliases of the domain, and the breaches they were in
+	var aliases map[string][]string
+	if err := json.Unmarshal(body, &aliases); err != nil {
+		return nil, fmt.Errorf("failed to parse HaveIBeenPwned response: %w", err)
+	}
+
+	exposure.Accounts = len(aliases)
+	for _, breaches := range aliases {
+		exposure.Breaches = appendUnique(exposure.Breaches, breaches...)
+	}
+	sort.Strings(exposure.Breaches)
+
+	return exposure, nil
+}
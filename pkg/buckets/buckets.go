@@ -0,0 +1,157 @@
+// Package buckets finds references to cloud storage buckets, such as AWS S3,
+// Google Cloud Storage and Azure blob containers, and checks if anyone can
+// list their contents.
+package buckets
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Providers of cloud storage
+const (
+	ProviderS3    = "AWS S3"
+	ProviderGCS   = "Google Cloud Storage"
+	ProviderAzure = "Azure Blob Storage"
+)
+
+// maxBodySize is the most of a listing that is read
+const maxBodySize = 1 << 20
+
+// path style references must not be preceded by a bucket name, as in
+// bucket.s3.amazonaws.com/key
+var (
+	s3HostRe    = regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9.\-]{1,61}[a-z0-9])\.s3(?:[.\-](?:dualstack\.)?[a-z0-9\-]+)?\.amazonaws\.com`)
+	s3PathRe    = regexp.MustCompile(`(?i)(?:^|[^a-z0-9.\-])s3(?:[.\-](?:dualstack\.)?[a-z0-9\-]+)?\.amazonaws\.com/([a-z0-9][a-z0-9.\-]{1,61}[a-z0-9])`)
+	s3URIRe     = regexp.MustCompile(`(?i)\bs3://([a-z0-9][a-z0-9.\-]{1,61}[a-z0-9])`)
+	gcsHostRe   = regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9._\-]{1,61}[a-z0-9])\.storage\.googleapis\.com`)
+	gcsPathRe   = regexp.MustCompile(`(?i)(?:^|[^a-z0-9.\-])storage\.(?:googleapis|cloud\.google)\.com/([a-z0-9][a-z0-9._\-]{1,61}[a-z0-9])`)
+	gcsURIRe    = regexp.MustCompile(`(?i)\bgs://([a-z0-9][a-z0-9._\-]{1,61}[a-z0-9])`)
+	azureBlobRe = regexp.MustCompile(`(?i)\b([a-z0-9]{3,24})\.blob\.core\.windows\.net/([a-z0-9][a-z0-9\-]{1,61}[a-z0-9]|\$web)`)
+)
+
+// Bucket is a reference to a cloud storage bucket
+type Bucket struct {
+	Provider string
+	// Name is the bucket name, or account/container for Azure
+	Name string
+}
+
+// Key identifies a bucket
+func (b Bucket) Key() string {
+	return b.Provider + "|" + strings.ToLower(b.Name)
+}
+
+// ListURL is the URL that lists the contents of the bucket, if it is public
+func (b Bucket) ListURL() string {
+	switch b.Provider {
+	case ProviderS3:
+		// virtual hosted names can't have dots with https
+		if strings.Contains(b.Name, ".") {
+			return "https://s3.amazonaws.com/" + b.Name + "/"
+		}
+		return "https://" + b.Name + ".s3.amazonaws.com/"
+	case ProviderGCS:
+		return "https://storage.googleapis.com/" + b.Name + "/"
+	case ProviderAzure:
+		account, container, _ := strings.Cut(b.Name, "/")
+		return "https://" + account + ".blob.core.windows.net/" + container + "?restype=container&comp=list"
+	}
+
+	return ""
+}
+
+// Find returns the buckets content references
+func Find(content string) []Bucket {
+	var found []Bucket
+	seen := make(map[string]bool)
+	add := func(provider string, name string) {
+		b := Bucket{Provider: provider, Name: strings.ToLower(name)}
+		if seen[b.Key()] {
+			return
+		}
+		seen[b.Key()] = true
+		found = append(found, b)
+	}
+
+	for _, re := range []*regexp.Regexp{s3HostRe, s3PathRe, s3URIRe} {
+		for _, match := range re.FindAllStringSubmatch(content, -1) {
+			add(ProviderS3, match[1])
+		}
+	}
+
+	for _, re := range []*regexp.Regexp{gcsHostRe, gcsPathRe, gcsURIRe} {
+		for _, match := range re.FindAllStringSubmatch(content, -1) {
+			add(ProviderGCS, match[1])
+		}
+	}
+
+	for _, match := range azureBlobRe.FindAllStringSubmatch(content, -1) {
+		add(ProviderAzure, match[1]+"/"+match[2])
+	}
+
+	return found
+}
+
+// Exposure is the outcome of checking a bucket
+type Exposure struct {
+	// Listable is true if anyone can list the bucket's contents
+	Listable bool
+	// Objects is the number of objects in the first page of the listing
+	Objects  int
+	Evidence string
+}
+
+// Checker checks if buckets can be listed
+type Checker struct {
+	client *http.Client
+}
+
+// NewChecker returns a new Checker
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+	}
+}
+
+// Check requests the listing of a bucket
+func (c *Checker) Check(bucket Bucket) (*Exposure, error) {
+	resp, err := c.client.Get(bucket.ListURL())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	content := string(body)
+
+	if resp.StatusCode != http.StatusOK {
+		return &Exposure{Evidence: fmt.Sprintf("listing denied with status %d", resp.StatusCode)}, nil
+	}
+
+	switch {
+	case strings.Contains(content, "<ListBucketResult"):
+		objects := strings.Count(content, "<Key>")
+		return &Exposure{Listable: true, Objects: objects,
+			Evidence: fmt.Sprintf("%s lists %d objects", bucket.ListURL(), objects)}, nil
+	case strings.Contains(content, "<EnumerationResults"):
+		objects := strings.Count(content, "<Blob>")
+		return &Exposure{Listable: true, Objects: objects,
+			Evidence: fmt.Sprintf("%s lists %d blobs", bucket.ListURL(), objects)}, nil
+	}
+
+	return &Exposure{Evidence: "listing returned no bucket contents"}, nil
+}
@@ -0,0 +1,202 @@
+// Package classify tags probed results by what kind of page they are, such
+// as login panels and default install pages, to speed up screenshot triage.
+package classify
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/origin"
+)
+
+// Source is the source of the tags the classifier adds
+const Source = "classifier"
+
+// Tags the classifier adds
+const (
+	// TagLoginPanel is a page with a login form
+	TagLoginPanel = "login-panel"
+	// TagAdminInterface is an administrative interface or dashboard
+	TagAdminInterface = "admin-interface"
+	// TagDefaultPage is the default page of a fresh web server install
+	TagDefaultPage = "default-page"
+	// TagDirectoryListing is a web server's listing of a directory
+	TagDirectoryListing = "directory-listing"
+	// TagDefaultCredentials is a product known to ship with default
+	// credentials, that are worth trying
+	TagDefaultCredentials = "default-credentials"
+)
+
+// Tags are all tags the classifier adds
+var Tags = []string{
+	TagLoginPanel,
+	TagAdminInterface,
+	TagDefaultPage,
+	TagDirectoryListing,
+	TagDefaultCredentials,
+}
+
+// maxBodySize is how much of a response body is searched
+const maxBodySize = 512 << 10
+
+var (
+	passwordInputRe = regexp.MustCompile(`(?i)<input[^>]+type\s*=\s*["']?password`)
+	loginTitleRe    = regexp.MustCompile(`(?i)\b(log\s?in|log\s?on|sign\s?in|authenticat(e|ion))\b`)
+	adminTitleRe    = regexp.MustCompile(`(?i)\b(admin|administrator|administration|dashboard|control panel|management console)\b`)
+	adminPathRe     = regexp.MustCompile(`(?i)/(admin|administrator|wp-admin|manager|console|cpanel)(/|$)`)
+	listingTitleRe  = regexp.MustCompile(`(?i)^\s*(index of /|directory listing for )`)
+	defaultPageRe   = regexp.MustCompile(`(?i)^\s*(welcome to nginx!?|apache2 (ubuntu|debian) default page|test page for the (apache|nginx) http server|iis windows server|iis\d* welcome|internet information services|it works!?|welcome to centos|apache tomcat/[\d.]+|welcome to jboss|welcome to wildfly|default web site page|web server's default page)\s*$`)
+)
+
+// signature identifies a product by its title, body, basic auth realm or
+// favicon
+type signature struct {
+	Product  string
+	Title    *regexp.Regexp
+	Body     *regexp.Regexp
+	Realm    *regexp.Regexp // matched against the WWW-Authenticate header
+	Favicons []int32        // Shodan favicon hashes, see origin.FaviconHash
+	Tags     []string
+}
+
+// signatures are products worth tagging beyond the generic heuristics
+var signatures = []signature{
+	{
+		Product: "Apache Tomcat Manager",
+		Body:    regexp.MustCompile(`(?i)tomcat web application manager`),
+		Realm:   regexp.MustCompile(`(?i)tomcat manager application`),
+		Tags:    []string{TagLoginPanel, TagAdminInterface, TagDefaultCredentials},
+	},
+	{
+		Product:  "Jenkins",
+		Title:    regexp.MustCompile(`(?i)\bjenkins\b`),
+		Favicons: []int32{81586312},
+		Tags:     []string{TagAdminInterface},
+	},
+	{
+		Product: "Grafana",
+		Title:   regexp.MustCompile(`(?i)^\s*grafana\s*$`),
+		Tags:    []string{TagLoginPanel, TagAdminInterface, TagDefaultCredentials},
+	},
+	{
+		Product: "RabbitMQ Management",
+		Title:   regexp.MustCompile(`(?i)rabbitmq management`),
+		Tags:    []string{TagLoginPanel, TagAdminInterface, TagDefaultCredentials},
+	},
+	{
+		Product: "phpMyAdmin",
+		Title:   regexp.MustCompile(`(?i)phpmyadmin`),
+		Tags:    []string{TagLoginPanel, TagAdminInterface, TagDefaultCredentials},
+	},
+	{
+		Product:  "SonarQube",
+		Title:    regexp.MustCompile(`(?i)\bsonarqube\b`),
+		Favicons: []int32{1485257654},
+		Tags:     []string{TagLoginPanel, TagAdminInterface, TagDefaultCredentials},
+	},
+	{
+		Product: "Webmin",
+		Title:   regexp.MustCompile(`(?i)login to webmin`),
+		Tags:    []string{TagLoginPanel, TagAdminInterface},
+	},
+	{
+		Product:  "GitLab",
+		Favicons: []int32{1278323681},
+		Tags:     []string{TagLoginPanel},
+	},
+}
+
+// Classify returns the classifier tags of a result
+func Classify(result *models.Result) []models.ResultTag {
+	var names []string
+	add := func(tags ...string) {
+		for _, tag := range tags {
+			if !slices.Contains(names, tag) {
+				names = append(names, tag)
+			}
+		}
+	}
+
+	body := result.HTML
+	if len(body) > maxBodySize {
+		body = body[:maxBodySize]
+	}
+
+	if passwordInputRe.MatchString(body) || loginTitleRe.MatchString(result.Title) {
+		add(TagLoginPanel)
+	}
+
+	if adminTitleRe.MatchString(result.Title) || adminPathRe.MatchString(urlPath(result)) {
+		add(TagAdminInterface)
+	}
+
+	if defaultPageRe.MatchString(result.Title) {
+		add(TagDefaultPage)
+	}
+
+	if listingTitleRe.MatchString(result.Title) {
+		add(TagDirectoryListing)
+	}
+
+	var realm string
+	for _, header := range result.Headers {
+		if strings.EqualFold(header.Key, "www-authenticate") {
+			realm = header.Value
+		}
+	}
+
+	favicon, hasFavicon := faviconHash(result)
+	for _, sig := range signatures {
+		switch {
+		case sig.Title != nil && sig.Title.MatchString(result.Title),
+			sig.Body != nil && sig.Body.MatchString(body),
+			sig.Realm != nil && realm != "" && sig.Realm.MatchString(realm),
+			hasFavicon && slices.Contains(sig.Favicons, favicon):
+			add(sig.Tags...)
+		}
+	}
+
+	tags := make([]models.ResultTag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, models.ResultTag{Name: name, Source: Source})
+	}
+
+	return tags
+}
+
+// urlPath returns the path of the URL a result ended up on
+func urlPath(result *models.Result) string {
+	target := result.FinalURL
+	if target == "" {
+		target = result.URL
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+
+	return u.Path
+}
+
+// faviconHash returns the favicon hash of a result, if its favicon was
+// captured in the network log
+func faviconHash(result *models.Result) (int32, bool) {
+	for _, entry := range result.Network {
+		if len(entry.Content) == 0 || entry.StatusCode != 200 {
+			continue
+		}
+
+		u, err := url.Parse(entry.URL)
+		if err != nil || !strings.HasSuffix(strings.ToLower(u.Path), "/favicon.ico") {
+			continue
+		}
+
+		return origin.FaviconHash(entry.Content), true
+	}
+
+	return 0, false
+}
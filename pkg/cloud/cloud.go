@@ -0,0 +1,357 @@
+// Package cloud attributes IP addresses to the cloud providers and CDNs
+// they belong to, from the address ranges the providers publish. Addresses
+// outside of them are taken to be self hosted.
+package cloud
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/asn"
+)
+
+// Providers that addresses are attributed to
+const (
+	AWS        = "aws"
+	GCP        = "gcp"
+	Azure      = "azure"
+	Cloudflare = "cloudflare"
+	Fastly     = "fastly"
+)
+
+// Providers lists every provider, in the order their ranges are checked
+var Providers = []string{AWS, GCP, Azure, Cloudflare, Fastly}
+
+// SelfHosted is the hosting of addresses outside every provider's ranges,
+// for filtering
+const SelfHosted = "self-hosted"
+
+// ErrPartialUpdate is returned by Update when some providers failed to
+// download
+var ErrPartialUpdate = errors.New("some providers failed to update, their previous ranges are kept")
+
+//go:embed ranges.txt
+var bundled []byte
+
+// prefix is an address range of a provider
+type prefix struct {
+	provider string
+	prefix   netip.Prefix
+}
+
+// ranges are the address ranges addresses are attributed with
+var ranges struct {
+	sync.RWMutex
+	prefixes []prefix
+	source   string
+}
+
+func init() {
+	prefixes, err := parse(bytes.NewReader(bundled))
+	if err != nil {
+		panic(fmt.Sprintf("invalid bundled cloud ranges: %s", err))
+	}
+	set(prefixes, "bundled")
+}
+
+// Provider returns the provider an IP address belongs to, or an empty
+// string if it is self hosted or not an address
+func Provider(ip string) string {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	ranges.RLock()
+	defer ranges.RUnlock()
+
+	for _, p := range ranges.prefixes {
+		if p.prefix.Contains(addr) {
+			return p.provider
+		}
+	}
+
+	return ""
+}
+
+// Hosting returns the provider of an IP address, or SelfHosted
+func Hosting(ip string) string {
+	if provider := Provider(ip); provider != "" {
+		return provider
+	}
+
+	return SelfHosted
+}
+
+// Source returns where the ranges in use came from, "bundled" or the path
+// of a cached ranges file
+func Source() string {
+	ranges.RLock()
+	defer ranges.RUnlock()
+
+	return ranges.source
+}
+
+// CachePath returns the path ranges are cached at by Update
+func CachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "gowitness", "cloud-ranges.txt"), nil
+}
+
+// LoadCache uses the ranges cached by Update instead of the bundled ones,
+// if there are any
+func LoadCache() error {
+	path, err := CachePath()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	prefixes, err := parse(file)
+	if err != nil {
+		return fmt.Errorf("invalid cached cloud ranges %s: %w", path, err)
+	}
+	set(prefixes, path)
+
+	return nil
+}
+
+// Update downloads the current ranges of every provider, caches them and
+// uses them from then on. It returns the number of ranges per provider.
+// Providers that fail to download keep their previous ranges.
+func Update() (map[string]int, error) {
+	counts := make(map[string]int)
+
+	ranges.RLock()
+	previous := slices.Clone(ranges.prefixes)
+	ranges.RUnlock()
+
+	var prefixes []prefix
+	var failed []string
+	for _, provider := range Providers {
+		fetched, err := fetchers[provider]()
+		if err != nil || len(fetched) == 0 {
+			if err == nil {
+				err = errors.New("no ranges")
+			}
+			failed = append(failed, fmt.Sprintf("%s: %s", provider, err))
+			for _, p := range previous {
+				if p.provider == provider {
+					prefixes = append(prefixes, p)
+					counts[provider]++
+				}
+			}
+			continue
+		}
+
+		for _, cidr := range fetched {
+			parsed, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				continue
+			}
+			prefixes = append(prefixes, prefix{provider: provider, prefix: parsed.Masked()})
+			counts[provider]++
+		}
+	}
+
+	path, err := CachePath()
+	if err != nil {
+		return counts, err
+	}
+	if err := write(path, prefixes); err != nil {
+		return counts, err
+	}
+	set(prefixes, path)
+
+	if len(failed) > 0 {
+		return counts, fmt.Errorf("%w: %s", ErrPartialUpdate, strings.Join(failed, "; "))
+	}
+
+	return counts, nil
+}
+
+// set replaces the ranges in use
+func set(prefixes []prefix, source string) {
+	ranges.Lock()
+	defer ranges.Unlock()
+
+	ranges.prefixes = prefixes
+	ranges.source = source
+}
+
+// parse reads ranges as "provider prefix" lines, skipping comments
+func parse(r io.Reader) ([]prefix, error) {
+	var prefixes []prefix
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		parsed, err := netip.ParsePrefix(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid prefix %q: %w", fields[1], err)
+		}
+
+		prefixes = append(prefixes, prefix{provider: fields[0], prefix: parsed.Masked()})
+	}
+
+	return prefixes, scanner.Err()
+}
+
+// write caches ranges in the format parse reads
+func write(path string, prefixes []prefix) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Cloud provider address ranges, downloaded %s\n", time.Now().UTC().Format(time.RFC3339))
+	for _, p := range prefixes {
+		fmt.Fprintf(&b, "%s %s\n", p.provider, p.prefix)
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// fetchers download the current ranges of each provider
+var fetchers = map[string]func() ([]string, error){
+	AWS: func() ([]string, error) {
+		var response struct {
+			Prefixes []struct {
+				IPPrefix string `json:"ip_prefix"`
+			} `json:"prefixes"`
+			IPv6Prefixes []struct {
+				IPv6Prefix string `json:"ipv6_prefix"`
+			} `json:"ipv6_prefixes"`
+		}
+		if err := getJSON("https://ip-ranges.amazonaws.com/ip-ranges.json", &response); err != nil {
+			return nil, err
+		}
+
+		var prefixes []string
+		for _, p := range response.Prefixes {
+			prefixes = append(prefixes, p.IPPrefix)
+		}
+		for _, p := range response.IPv6Prefixes {
+			prefixes = append(prefixes, p.IPv6Prefix)
+		}
+		return prefixes, nil
+	},
+	GCP: func() ([]string, error) {
+		var response struct {
+			Prefixes []struct {
+				IPv4Prefix string `json:"ipv4Prefix"`
+				IPv6Prefix string `json:"ipv6Prefix"`
+			} `json:"prefixes"`
+		}
+		if err := getJSON("https://www.gstatic.com/ipranges/cloud.json", &response); err != nil {
+			return nil, err
+		}
+
+		var prefixes []string
+		for _, p := range response.Prefixes {
+			if p.IPv4Prefix != "" {
+				prefixes = append(prefixes, p.IPv4Prefix)
+			}
+			if p.IPv6Prefix != "" {
+				prefixes = append(prefixes, p.IPv6Prefix)
+			}
+		}
+		return prefixes, nil
+	},
+	// Azure's service tags are not published at a stable address, so the
+	// prefixes Microsoft's cloud network announces are used instead
+	Azure: func() ([]string, error) {
+		return asn.AnnouncedPrefixes("AS8075")
+	},
+	Cloudflare: func() ([]string, error) {
+		var prefixes []string
+		for _, url := range []string{"https://www.cloudflare.com/ips-v4", "https://www.cloudflare.com/ips-v6"} {
+			body, err := get(url)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, strings.Fields(string(body))...)
+		}
+		return prefixes, nil
+	},
+	Fastly: func() ([]string, error) {
+		var response struct {
+			Addresses     []string `json:"addresses"`
+			IPv6Addresses []string `json:"ipv6_addresses"`
+		}
+		if err := getJSON("https://api.fastly.com/public-ip-list", &response); err != nil {
+			return nil, err
+		}
+		return append(response.Addresses, response.IPv6Addresses...), nil
+	},
+}
+
+// get downloads a published range list
+func get(url string) ([]byte, error) {
+	client := islazy.NewHTTPClient(30 * time.Second)
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s (status %d)", url, resp.StatusCode)
+	}
+
+	return body, nil
+}
+
+// getJSON downloads a published range list in JSON
+func getJSON(url string, v any) error {
+	body, err := get(url)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", url, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package company
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// Clearbit is a Clearbit company API client
+type Clearbit struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// clearbitCompany is the response from the company find endpoint
+type clearbitCompany struct {
+	Domain        string   `json:"domain"`
+	DomainAliases []string `json:"domainAliases"`
+	Category      struct {
+		Industry string `json:"industry"`
+	} `json:"category"`
+	Metrics struct {
+		Employees int `json:"employees"`
+	} `json:"metrics"`
+}
+
+// NewClearbit returns a new Clearbit client
+func NewClearbit(apiKey string) *Clearbit {
+	return &Clearbit{
+		apiKey:     apiKey,
+		baseURL:    "https://company.clearbit.com/v2",
+		httpClient: islazy.NewHTTPClient(30 * time.Second),
+	}
+}
+
+// Name returns the provider name
+func (c *Clearbit) Name() string {
+	return SourceClearbit
+}
+
+// Lookup returns the profile Clearbit has for the company owning a domain.
+// Clearbit does not know netblocks.
+func (c *Clearbit) Lookup(name string, domain string) (*Profile, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/companies/find?domain="+url.QueryEscape(domain), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Clearbit API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Clearbit API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response clearbitCompany
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse Clearbit response: %w", err)
+	}
+
+	profile := &Profile{
+		Industry:      response.Category.Industry,
+		EmployeeCount: response.Metrics.Employees,
+	}
+	profile.Domains = appendUnique(profile.Domains, response.Domain)
+	profile.Domains = appendUnique(profile.Domains, response.DomainAliases...)
+
+	return profile, nil
+}
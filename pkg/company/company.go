@@ -0,0 +1,83 @@
+// Package company looks up profile information about a target company, such
+// as its industry, size, netblocks and official domains.
+package company
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// SourceClearbit is the source name of the Clearbit company API
+const SourceClearbit = "clearbit"
+
+// Profile is what is known about a company
+type Profile struct {
+	Industry      string   `json:"industry"`
+	EmployeeCount int      `json:"employee_count"`
+	Netblocks     []string `json:"netblocks"`
+	Domains       []string `json:"domains"`
+}
+
+// Provider looks up company profiles
+type Provider interface {
+	// Name returns the name of the provider
+	Name() string
+	// Lookup returns the profile of a company, by its name and main domain
+	Lookup(name string, domain string) (*Profile, error)
+}
+
+// NewProvider returns the provider for a source. The source is either the
+// name of an online source, like "clearbit", or the path to a JSON file
+// holding a Profile.
+func NewProvider(source string) (Provider, error) {
+	if strings.EqualFold(source, SourceClearbit) {
+		// Try to load .env file (ignore errors as it may not exist)
+		_ = godotenv.Load()
+
+		apiKey := os.Getenv("CLEARBIT_API_KEY")
+		if apiKey == "" {
+			return nil, errors.New("CLEARBIT_API_KEY must be set to enrich from clearbit")
+		}
+		return NewClearbit(apiKey), nil
+	}
+
+	if _, err := os.Stat(source); err != nil {
+		return nil, errors.New("enrichment source must be \"clearbit\" or a JSON profile file")
+	}
+
+	return NewFile(source), nil
+}
+
+// Merge adds the netblocks and domains of other to the profile, and fills
+// fields the profile does not have yet
+func (p *Profile) Merge(other *Profile) {
+	if other == nil {
+		return
+	}
+
+	if p.Industry == "" {
+		p.Industry = other.Industry
+	}
+	if p.EmployeeCount == 0 {
+		p.EmployeeCount = other.EmployeeCount
+	}
+
+	p.Netblocks = appendUnique(p.Netblocks, other.Netblocks...)
+	p.Domains = appendUnique(p.Domains, other.Domains...)
+}
+
+// appendUnique appends the values not in list yet to it
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" && !slices.Contains(list, value) {
+			list = append(list, value)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,49 @@
+package company
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// File reads a company profile from a JSON file, for details gathered by
+// hand or from sources without an API, e.g.
+//
+//	{
+//	  "industry": "Insurance",
+//	  "employee_count": 2000,
+//	  "netblocks": ["192.0.2.0/24"],
+//	  "domains": ["example.com", "example.net"]
+//	}
+type File struct {
+	path string
+}
+
+// NewFile returns a provider reading the profile in path
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Name returns the provider name
+func (f *File) Name() string {
+	return "file"
+}
+
+// Lookup returns the profile in the file, whatever the company
+func (f *File) Lookup(name string, domain string) (*Profile, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile file: %w", err)
+	}
+
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse profile file: %w", err)
+	}
+
+	// normalise the lists like the other providers do
+	profile.Netblocks = appendUnique(nil, profile.Netblocks...)
+	profile.Domains = appendUnique(nil, profile.Domains...)
+
+	return &profile, nil
+}
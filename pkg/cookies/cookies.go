@@ -0,0 +1,156 @@
+// Package cookies flags session cookies that are set without the Secure or
+// HttpOnly flags, which lets them leak over plain HTTP or to scripts.
+package cookies
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// FindingSource is the source of findings about insecure cookies
+const FindingSource = "cookies"
+
+// Problems a session cookie can have
+const (
+	// ProblemNotSecure is a cookie that is also sent over plain HTTP
+	ProblemNotSecure = "not-secure"
+	// ProblemNotHTTPOnly is a cookie that scripts can read
+	ProblemNotHTTPOnly = "not-httponly"
+	// ProblemSameSiteNone is a cookie that is sent with cross-site requests
+	ProblemSameSiteNone = "samesite-none"
+)
+
+var (
+	// sessionNameRe matches the names of cookies that look like they hold
+	// a session or credential
+	sessionNameRe = regexp.MustCompile(`(?i)(^|[_.-])(sess|session|sessid|sid|ssid|auth|token|jwt|login|remember|identity)([_.-]|$)|` +
+		`^(phpsessid|jsessionid|asp\.net_sessionid|aspsessionid\w*|connect\.sid|laravel_session|ci_session|_session_id|cfid|cftoken|wordpress_(logged_in|sec)_\w+|\.aspxauth)$`)
+	// csrfNameRe matches anti-CSRF token cookies, which scripts are meant
+	// to read
+	csrfNameRe = regexp.MustCompile(`(?i)(csrf|xsrf)`)
+)
+
+// Issue is a session cookie of a result that is missing a security flag
+type Issue struct {
+	Host     string   `json:"host"` // the host the result was served from
+	Name     string   `json:"name"`
+	Domain   string   `json:"domain"`
+	Secure   bool     `json:"secure"`
+	HTTPOnly bool     `json:"http_only"`
+	SameSite string   `json:"same_site,omitempty"`
+	Problems []string `json:"problems"`
+	Severity string   `json:"severity"`
+}
+
+// SessionLike checks if a cookie name looks like it holds a session
+func SessionLike(name string) bool {
+	return sessionNameRe.MatchString(name) && !csrfNameRe.MatchString(name)
+}
+
+// Analyze returns the session cookies of a result, set by the host of the
+// result, that are missing the Secure or HttpOnly flag. Cookies set by
+// third parties, such as analytics, are not the host's to fix.
+func Analyze(result *models.Result) []Issue {
+	host, https := resultHost(result)
+
+	var issues []Issue
+	for _, cookie := range result.Cookies {
+		if (cookie.Secure && cookie.HTTPOnly) || !SessionLike(cookie.Name) || !ownCookie(host, cookie.Domain) {
+			continue
+		}
+
+		issue := Issue{
+			Host:     host,
+			Name:     cookie.Name,
+			Domain:   cookie.Domain,
+			Secure:   cookie.Secure,
+			HTTPOnly: cookie.HTTPOnly,
+			SameSite: cookie.SameSite,
+			Severity: "low",
+		}
+		if !cookie.Secure {
+			issue.Problems = append(issue.Problems, ProblemNotSecure)
+		}
+		if !cookie.HTTPOnly {
+			issue.Problems = append(issue.Problems, ProblemNotHTTPOnly)
+		}
+		if strings.EqualFold(cookie.SameSite, "None") {
+			issue.Problems = append(issue.Problems, ProblemSameSiteNone)
+		}
+
+		// a cookie of an HTTPS site that is sent over plain HTTP can be
+		// stolen by anyone on the network
+		if (!cookie.Secure && https) || (!cookie.Secure && !cookie.HTTPOnly) {
+			issue.Severity = "medium"
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
+
+// Findings returns a finding for every issue of a result
+func Findings(result *models.Result) []models.Finding {
+	var findings []models.Finding
+	for _, issue := range Analyze(result) {
+		findings = append(findings, models.Finding{
+			Source:      FindingSource,
+			Title:       fmt.Sprintf("Insecure session cookie %s on %s", issue.Name, issue.Host),
+			Severity:    issue.Severity,
+			Description: describe(issue),
+			IPAddress:   result.IPAddress,
+		})
+	}
+
+	return findings
+}
+
+// describe explains the problems of an issue
+func describe(issue Issue) string {
+	var problems []string
+	for _, problem := range issue.Problems {
+		switch problem {
+		case ProblemNotSecure:
+			problems = append(problems, "it lacks the Secure flag, so it is also sent over plain HTTP")
+		case ProblemNotHTTPOnly:
+			problems = append(problems, "it lacks the HttpOnly flag, so scripts, including injected ones, can read it")
+		case ProblemSameSiteNone:
+			problems = append(problems, "it is SameSite=None, so it is sent with cross-site requests")
+		}
+	}
+
+	return fmt.Sprintf("The cookie %s looks like it holds a session, but %s.", issue.Name, strings.Join(problems, ", and "))
+}
+
+// resultHost returns the hostname a result was served from, after
+// redirects, and whether it was served over HTTPS
+func resultHost(result *models.Result) (string, bool) {
+	target := result.FinalURL
+	if target == "" {
+		target = result.URL
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", false
+	}
+
+	return strings.ToLower(u.Hostname()), u.Scheme == "https"
+}
+
+// ownCookie checks if a cookie domain belongs to a host: the host itself, a
+// parent domain of it, or a subdomain of it
+func ownCookie(host string, domain string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	if host == "" || domain == "" {
+		// host only cookies have the host as their domain
+		return true
+	}
+
+	return host == domain || strings.HasSuffix(host, "."+domain) || strings.HasSuffix(domain, "."+host)
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"github.com/sensepost/gowitness/pkg/cloud"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// CloudSummary counts the addresses attributed to each cloud provider, with
+// self hosted addresses under cloud.SelfHosted
+type CloudSummary map[string]int
+
+// AttributeCloudProviders attributes the addresses of IP info and ports to
+// their cloud providers with the ranges in use, such as after they were
+// updated. It updates the column only, as the information did not change.
+func AttributeCloudProviders(db *gorm.DB) (CloudSummary, error) {
+	summary := make(CloudSummary)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var addresses []string
+		if err := tx.Model(&models.IPInfo{}).Distinct().Pluck("ip_address", &addresses).Error; err != nil {
+			return err
+		}
+		var ports []string
+		if err := tx.Model(&models.IPPort{}).Distinct().Pluck("ip_address", &ports).Error; err != nil {
+			return err
+		}
+
+		seen := make(map[string]bool, len(addresses))
+		for _, address := range append(addresses, ports...) {
+			if seen[address] {
+				continue
+			}
+			seen[address] = true
+
+			provider := cloud.Provider(address)
+			summary[cloud.Hosting(address)]++
+
+			for _, model := range []any{&models.IPInfo{}, &models.IPPort{}} {
+				if err := tx.Model(model).Where("ip_address = ? AND (cloud_provider IS NULL OR cloud_provider <> ?)", address, provider).
+					UpdateColumn("cloud_provider", provider).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return summary, err
+}
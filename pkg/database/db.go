@@ -17,8 +17,81 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-// Connection returns a Database connection based on a URI
+// AutoMigrate applies pending schema migrations when a connection is made.
+// If it is disabled, connecting to a database with pending migrations
+// fails instead, so that they can be reviewed and applied with db migrate.
+var AutoMigrate = true
+
+// Connection returns a Database connection based on a URI, with its schema
+// migrated to the latest version
 func Connection(uri string, shouldExist, debug bool) (*gorm.DB, error) {
+	return ConnectionWithKey(uri, shouldExist, debug, EncryptionKey)
+}
+
+// ConnectionWithKey returns a Database connection like Connection, with
+// the passphrase of an encrypted database given instead of taken from
+// EncryptionKey. Each connection has its own key, so databases encrypted
+// with different passphrases can be used together.
+func ConnectionWithKey(uri string, shouldExist, debug bool, passphrase string) (*gorm.DB, error) {
+	c, err := Open(uri, shouldExist, debug)
+	if err != nil {
+		return nil, err
+	}
+
+	// the key is set before migrating, so that migrations read and write
+	// encrypted columns with it. Databases without the table keys are kept
+	// in have nothing encrypted yet, and get their key once migrated.
+	keyed := c.Migrator().HasTable(&models.EncryptionKey{})
+	if keyed {
+		if err := setupEncryption(c, passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	if AutoMigrate {
+		if _, err := MigrateUp(c, 0); err != nil {
+			return nil, err
+		}
+	} else if err := checkSchema(c); err != nil {
+		return nil, err
+	}
+
+	if !keyed {
+		if err := setupEncryption(c, passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	// search falls back to LIKE queries if the index could not be set up,
+	// so this is not fatal
+	_ = setupSearchIndex(c)
+
+	return c, nil
+}
+
+// Backfill fills in values that rows written by older builds, or imported
+// from their databases and files, lack: the name and version of
+// technologies, when IP info was first seen and its first history snapshot,
+// the security grades of results, and the tag list.
+func Backfill(db *gorm.DB) error {
+	for _, backfill := range []func(*gorm.DB) error{
+		backfillTechnologyVersions,
+		backfillIPInfoFirstSeen,
+		backfillIPInfoHistory,
+		backfillSecurityScores,
+		SyncTags,
+	} {
+		if err := backfill(db); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Open returns a Database connection based on a URI, without touching its
+// schema
+func Open(uri string, shouldExist, debug bool) (*gorm.DB, error) {
 	var err error
 	var c *gorm.DB
 
@@ -63,11 +136,12 @@ func Connection(uri string, shouldExist, debug bool) (*gorm.DB, error) {
 			}
 		}
 
-		c, err = gorm.Open(sqlite.Open(db.Host+db.Path+"?cache=shared"), config)
+		// foreign keys are enabled per connection, so in the DSN rather
+		// than once, for the cascades deleting results rely on
+		c, err = gorm.Open(sqlite.Open(db.Host+db.Path+"?cache=shared&_pragma=foreign_keys(1)"), config)
 		if err != nil {
 			return nil, err
 		}
-		c.Exec("PRAGMA foreign_keys = ON")
 	case "postgres":
 		c, err = gorm.Open(postgres.Open(uri), config)
 		if err != nil {
@@ -82,22 +156,5 @@ func Connection(uri string, shouldExist, debug bool) (*gorm.DB, error) {
 		return nil, errors.New("invalid db uri scheme")
 	}
 
-	// run database migrations on the connection
-	if err := c.AutoMigrate(
-		&models.Result{},
-		&models.TLS{},
-		&models.TLSSanList{},
-		&models.Technology{},
-		&models.Header{},
-		&models.NetworkLog{},
-		&models.ConsoleLog{},
-		&models.Cookie{},
-		&models.ScanSession{},
-		&models.IPPort{},
-		&models.IPInfo{},
-	); err != nil {
-		return nil, err
-	}
-
 	return c, nil
 }
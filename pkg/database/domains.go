@@ -0,0 +1,50 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// SaveDomain records a hostname seen by a source. If the source reported
+// the hostname before, its last seen time and historical state are
+// updated instead, so that each source has a single row per hostname.
+func SaveDomain(db *gorm.DB, domain *models.Domain) error {
+	domain.Name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain.Name)), ".")
+	if domain.LastSeen.IsZero() {
+		domain.LastSeen = time.Now()
+	}
+
+	var existing models.Domain
+	err := db.Where("name = ? AND source = ?", domain.Name, domain.Source).First(&existing).Error
+	if err == nil {
+		return db.Model(&existing).Updates(map[string]interface{}{
+			"last_seen":  domain.LastSeen,
+			"historical": domain.Historical,
+		}).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if domain.FirstSeen.IsZero() {
+		domain.FirstSeen = domain.LastSeen
+	}
+
+	return db.Create(domain).Error
+}
+
+// KnownDomains returns the distinct hostnames that at least one source
+// still considers active, which is what a project scans
+func KnownDomains(db *gorm.DB) ([]string, error) {
+	var names []string
+	err := db.Model(&models.Domain{}).
+		Where("historical = ?", false).
+		Distinct().Order("name").
+		Pluck("name", &names).Error
+
+	return names, err
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// EncryptionKey is the passphrase sensitive columns, such as response
+// bodies, credential header and cookie values, secrets and finding
+// descriptions, are encrypted with. Setting it on a database that is not encrypted yet encrypts the
+// values written from then on, and EncryptExisting encrypts the rest.
+var EncryptionKey string
+
+const (
+	// keyIterations is the number of pbkdf2 iterations the encryption key
+	// is derived from its passphrase with
+	keyIterations = 600000
+	// keyCheck is the value encrypted to check passphrases against
+	keyCheck = "gowitness"
+)
+
+var (
+	// ErrDatabaseEncrypted is returned when connecting to an encrypted
+	// database without a key
+	ErrDatabaseEncrypted = errors.New("database is encrypted, a key is required. Set --db-key or GOWITNESS_DB_KEY")
+	// ErrWrongDatabaseKey is returned when connecting to an encrypted
+	// database with the wrong key
+	ErrWrongDatabaseKey = errors.New("wrong database key")
+)
+
+// setupEncryption derives the encryption key of a database from a
+// passphrase, recording how it was derived the first time
+func setupEncryption(db *gorm.DB, passphrase string) error {
+	var key models.EncryptionKey
+	err := db.First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if passphrase == "" {
+			return models.SetEncryptionKey(db, nil)
+		}
+		return createEncryptionKey(db, passphrase)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read database encryption key: %w", err)
+	}
+
+	if passphrase == "" {
+		return ErrDatabaseEncrypted
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(key.Salt)
+	if err != nil {
+		return fmt.Errorf("invalid database encryption salt: %w", err)
+	}
+	derived, err := pbkdf2.Key(sha256.New, passphrase, salt, key.Iterations, 32)
+	if err != nil {
+		return err
+	}
+	if err := models.SetEncryptionKey(db, derived); err != nil {
+		return err
+	}
+
+	if check, err := models.Decrypt(db, key.Verifier); err != nil || check != keyCheck {
+		models.SetEncryptionKey(db, nil)
+		return ErrWrongDatabaseKey
+	}
+
+	return nil
+}
+
+// createEncryptionKey derives a new encryption key from a passphrase, and
+// records it
+func createEncryptionKey(db *gorm.DB, passphrase string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	derived, err := pbkdf2.Key(sha256.New, passphrase, salt, keyIterations, 32)
+	if err != nil {
+		return err
+	}
+	if err := models.SetEncryptionKey(db, derived); err != nil {
+		return err
+	}
+
+	verifier, err := models.Encrypt(db, keyCheck)
+	if err != nil {
+		return err
+	}
+
+	return db.Create(&models.EncryptionKey{
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Iterations: keyIterations,
+		Verifier:   verifier,
+	}).Error
+}
+
+// EncryptionSummary is the number of values encrypted, per table
+type EncryptionSummary struct {
+	Results  int64 `json:"results"`
+	Headers  int64 `json:"headers"`
+	Cookies  int64 `json:"cookies"`
+	Secrets  int64 `json:"secrets"`
+	Findings int64 `json:"findings"`
+}
+
+// EncryptExisting encrypts the sensitive values stored before a database
+// was encrypted, and removes the response bodies and credential headers
+// from the search index.
+// Values are read and written back, which encrypts them, so values that
+// are encrypted already are only encrypted again.
+func EncryptExisting(db *gorm.DB) (*EncryptionSummary, error) {
+	if !models.EncryptionEnabled(db) {
+		return nil, errors.New("no database key is set")
+	}
+
+	summary := &EncryptionSummary{}
+
+	var results []models.Result
+	err := db.Select("id", "html").Where("html != ''").FindInBatches(&results, 200, func(tx *gorm.DB, _ int) error {
+		for _, result := range results {
+			if err := db.Model(&models.Result{ID: result.ID}).Select("html").
+				Updates(&models.Result{HTML: result.HTML}).Error; err != nil {
+				return err
+			}
+			summary.Results++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return summary, fmt.Errorf("failed to encrypt response bodies: %w", err)
+	}
+
+	var headers []models.Header
+	err = db.Select("id", "key", "value").Where("LOWER(headers.key) IN ? AND headers.value != ''", models.CredentialHeaders()).
+		FindInBatches(&headers, 500, func(tx *gorm.DB, _ int) error {
+			for _, header := range headers {
+				// the key decides if the value is encrypted
+				if err := db.Model(&models.Header{ID: header.ID}).Select("value").
+					Updates(&models.Header{Key: header.Key, Value: header.Value}).Error; err != nil {
+					return err
+				}
+				summary.Headers++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return summary, fmt.Errorf("failed to encrypt credential headers: %w", err)
+	}
+
+	var cookies []models.Cookie
+	err = db.Select("id", "value").Where("value != ''").FindInBatches(&cookies, 500, func(tx *gorm.DB, _ int) error {
+		for _, cookie := range cookies {
+			if err := db.Model(&models.Cookie{ID: cookie.ID}).Select("value").
+				Updates(&models.Cookie{Value: cookie.Value}).Error; err != nil {
+				return err
+			}
+			summary.Cookies++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return summary, fmt.Errorf("failed to encrypt cookies: %w", err)
+	}
+
+	var secrets []models.Secret
+	err = db.Select("id", "value").Where("value != ''").FindInBatches(&secrets, 500, func(tx *gorm.DB, _ int) error {
+		for _, secret := range secrets {
+			if err := db.Model(&models.Secret{ID: secret.ID}).Select("value").
+				Updates(&models.Secret{Value: secret.Value}).Error; err != nil {
+				return err
+			}
+			summary.Secrets++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return summary, fmt.Errorf("failed to encrypt secrets: %w", err)
+	}
+
+	var findings []models.Finding
+	err = db.Select("id", "description").Where("description != ''").FindInBatches(&findings, 500, func(tx *gorm.DB, _ int) error {
+		for _, finding := range findings {
+			if err := db.Model(&models.Finding{ID: finding.ID}).Select("description").
+				Updates(&models.Finding{Description: finding.Description}).Error; err != nil {
+				return err
+			}
+			summary.Findings++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return summary, fmt.Errorf("failed to encrypt findings: %w", err)
+	}
+
+	// bodies and credential headers are not indexed in encrypted databases
+	if err := db.Where("field = ?", "body").Delete(&models.SearchDocument{}).Error; err != nil {
+		return summary, fmt.Errorf("failed to remove response bodies from the search index: %w", err)
+	}
+	if err := db.Where("field = ? AND name IN ?", "header", models.CredentialHeaders()).
+		Delete(&models.SearchDocument{}).Error; err != nil {
+		return summary, fmt.Errorf("failed to remove credential headers from the search index: %w", err)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+func TestMigrateEncrypted(t *testing.T) {
+	// the database log is written to the working directory
+	t.Chdir(t.TempDir())
+
+	const passphrase = "correct horse battery staple"
+	uri := "sqlite://" + filepath.Join(t.TempDir(), "gowitness.sqlite3")
+
+	// an encrypted database, written before the latest migration
+	db, err := Open(uri, false, false)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := MigrateUp(db, LatestVersion()-1); err != nil {
+		t.Fatalf("MigrateUp() error = %v", err)
+	}
+	if err := setupEncryption(db, passphrase); err != nil {
+		t.Fatalf("setupEncryption() error = %v", err)
+	}
+	result := models.Result{
+		URL:     "https://example.com",
+		HTML:    "<html>secret</html>",
+		Headers: []models.Header{{Key: "Set-Cookie", Value: "session=secret"}, {Key: "Server", Value: "nginx"}},
+		Cookies: []models.Cookie{{Name: "session", Value: "secret"}},
+	}
+	if err := db.Create(&result).Error; err != nil {
+		t.Fatalf("failed to create result: %v", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	if _, err := ConnectionWithKey(uri, true, false, "wrong"); !errors.Is(err, ErrWrongDatabaseKey) {
+		t.Fatalf("ConnectionWithKey() with the wrong key error = %v, want %v", err, ErrWrongDatabaseKey)
+	}
+
+	db, err = ConnectionWithKey(uri, true, false, passphrase)
+	if err != nil {
+		t.Fatalf("ConnectionWithKey() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if version, err := SchemaVersion(db); err != nil || version != LatestVersion() {
+		t.Fatalf("SchemaVersion() = %d, %v, want %d", version, err, LatestVersion())
+	}
+
+	var got models.Result
+	if err := db.Preload("Headers").Preload("Cookies").First(&got, result.ID).Error; err != nil {
+		t.Fatalf("failed to read result: %v", err)
+	}
+	if got.HTML != result.HTML || got.Cookies[0].Value != "secret" {
+		t.Errorf("read result = %q and cookie %q, want them decrypted", got.HTML, got.Cookies[0].Value)
+	}
+	for _, header := range got.Headers {
+		var raw string
+		if err := db.Table("headers").Select("value").Where("id = ?", header.ID).Scan(&raw).Error; err != nil {
+			t.Fatalf("failed to read header: %v", err)
+		}
+
+		wantEnc := models.IsCredentialHeader(header.Key)
+		if strings.HasPrefix(raw, encryptedPrefix) != wantEnc {
+			t.Errorf("stored %s header = %q, want encrypted %v", header.Key, raw, wantEnc)
+		}
+	}
+
+	if err := RefreshSearchIndex(db); err != nil {
+		t.Fatalf("RefreshSearchIndex() error = %v", err)
+	}
+	var documents []models.SearchDocument
+	if err := db.Find(&documents).Error; err != nil {
+		t.Fatalf("failed to read search documents: %v", err)
+	}
+	for _, document := range documents {
+		if strings.Contains(document.Content, "secret") {
+			t.Errorf("search document %s %s = %q, want no encrypted values indexed",
+				document.Field, document.Name, document.Content)
+		}
+	}
+}
+
+func TestEncryptExistingHeaders(t *testing.T) {
+	// the database log is written to the working directory
+	t.Chdir(t.TempDir())
+
+	uri := "sqlite://" + filepath.Join(t.TempDir(), "gowitness.sqlite3")
+	db, err := ConnectionWithKey(uri, false, false, "")
+	if err != nil {
+		t.Fatalf("ConnectionWithKey() error = %v", err)
+	}
+	result := models.Result{
+		URL:     "https://example.com",
+		Headers: []models.Header{{Key: "set-cookie", Value: "session=secret"}, {Key: "Server", Value: "nginx"}},
+	}
+	if err := db.Create(&result).Error; err != nil {
+		t.Fatalf("failed to create result: %v", err)
+	}
+	if err := RefreshSearchIndex(db); err != nil {
+		t.Fatalf("RefreshSearchIndex() error = %v", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	db, err = ConnectionWithKey(uri, true, false, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("ConnectionWithKey() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	summary, err := EncryptExisting(db)
+	if err != nil {
+		t.Fatalf("EncryptExisting() error = %v", err)
+	}
+	if summary.Headers != 1 {
+		t.Errorf("EncryptExisting() headers = %d, want 1", summary.Headers)
+	}
+
+	var raw string
+	if err := db.Table("headers").Select("value").Where("key = ?", "set-cookie").Scan(&raw).Error; err != nil {
+		t.Fatalf("failed to read header: %v", err)
+	}
+	if !strings.HasPrefix(raw, encryptedPrefix) {
+		t.Errorf("stored set-cookie header = %q, want it encrypted", raw)
+	}
+	var count int64
+	if err := db.Model(&models.SearchDocument{}).Where("content LIKE ?", "%secret%").Count(&count).Error; err != nil {
+		t.Fatalf("failed to count search documents: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("%d search documents hold the credential header, want none", count)
+	}
+}
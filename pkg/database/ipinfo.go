@@ -0,0 +1,30 @@
+package database
+
+import (
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// backfillIPInfoFirstSeen sets the first seen time of IP info stored before
+// it was recorded, to when it was last updated. It updates the column only,
+// as bumping updated_at would make stale IP info look fresh.
+func backfillIPInfoFirstSeen(db *gorm.DB) error {
+	return db.Model(&models.IPInfo{}).Where("first_seen IS NULL").
+		UpdateColumn("first_seen", gorm.Expr("updated_at")).Error
+}
+
+// backfillIPInfoHistory records the current information of IPs enriched
+// before history was kept as their first snapshot
+func backfillIPInfoHistory(db *gorm.DB) error {
+	var infos []models.IPInfo
+	return db.Where("ip_address NOT IN (?)", db.Model(&models.IPInfoHistory{}).Select("ip_address")).
+		FindInBatches(&infos, 500, func(tx *gorm.DB, _ int) error {
+			snapshots := make([]*models.IPInfoHistory, 0, len(infos))
+			for i := range infos {
+				snapshot := models.NewIPInfoHistory(&infos[i], "")
+				snapshot.RecordedAt = infos[i].UpdatedAt
+				snapshots = append(snapshots, snapshot)
+			}
+			return db.Create(&snapshots).Error
+		}).Error
+}
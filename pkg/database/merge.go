@@ -0,0 +1,441 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/thumbnail"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mergeBatchSize is the number of results read from the source per query
+const mergeBatchSize = 100
+
+// MergeOptions configure how one database is merged into another
+type MergeOptions struct {
+	// FromScreenshotPath is where the source's screenshots are stored when
+	// a result's scan session does not record its own path
+	FromScreenshotPath string
+	// IntoScreenshotPath is where merged screenshots are copied to when a
+	// result's scan session does not record its own path. If either path
+	// is empty, screenshots are not copied.
+	IntoScreenshotPath string
+}
+
+// MergeSummary is a summary of a merge. Skipped counts are records the
+// destination already had.
+type MergeSummary struct {
+	Sessions        int64 `json:"sessions"`
+	SkippedSessions int64 `json:"skipped_sessions"`
+	Results         int64 `json:"results"`
+	SkippedResults  int64 `json:"skipped_results"`
+	IPInfo          int64 `json:"ip_info"`
+	UpdatedIPInfo   int64 `json:"updated_ip_info"`
+	IPPorts         int64 `json:"ip_ports"`
+	SkippedIPPorts  int64 `json:"skipped_ip_ports"`
+	Screenshots     int64 `json:"screenshots"`
+}
+
+// merger holds the state of a merge, mapping source ids to destination ids
+type merger struct {
+	from, into *gorm.DB
+	opts       MergeOptions
+	summary    *MergeSummary
+
+	sessions map[uint]uint
+	results  map[uint]uint
+	// screenshot directories, keyed by the scan session id of their
+	// database. 0 is no session.
+	fromDirs map[uint]string
+	intoDirs map[uint]string
+}
+
+// Merge copies the scan sessions, results (with their screenshots), IP
+// information and open ports of one database into another, remapping
+// their ids. Records the destination already has are not copied again,
+// so merging the same database twice is safe.
+func Merge(from, into *gorm.DB, opts MergeOptions) (*MergeSummary, error) {
+	m := &merger{
+		from:     from,
+		into:     into,
+		opts:     opts,
+		summary:  &MergeSummary{},
+		sessions: make(map[uint]uint),
+		results:  make(map[uint]uint),
+		fromDirs: make(map[uint]string),
+		intoDirs: make(map[uint]string),
+	}
+
+	if err := m.mergeSessions(); err != nil {
+		return m.summary, err
+	}
+	if err := m.mergeResults(); err != nil {
+		return m.summary, err
+	}
+	if err := m.mergeIPInfo(); err != nil {
+		return m.summary, err
+	}
+	if err := m.mergeIPPorts(); err != nil {
+		return m.summary, err
+	}
+
+	return m.summary, nil
+}
+
+// session maps a source scan session id to its destination id
+func (m *merger) session(id *uint) *uint {
+	if id == nil {
+		return nil
+	}
+
+	mapped, ok := m.sessions[*id]
+	if !ok {
+		return nil
+	}
+
+	return &mapped
+}
+
+// mergeSessions copies scan sessions with their apex domains. A session
+// of the same company, main domain and start time is the same session.
+func (m *merger) mergeSessions() error {
+	var sessions []models.ScanSession
+	if err := m.from.Preload("ApexDomains").Order("id").Find(&sessions).Error; err != nil {
+		return fmt.Errorf("failed to read scan sessions: %w", err)
+	}
+
+	for _, session := range sessions {
+		sourceID := session.ID
+
+		var existing models.ScanSession
+		err := m.into.Select("id").
+			Where("company_name = ? AND main_domain = ? AND start_time = ?", session.CompanyName, session.MainDomain, session.StartTime).
+			First(&existing).Error
+		if err == nil {
+			m.sessions[sourceID] = existing.ID
+			m.summary.SkippedSessions++
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to look up scan session %d: %w", sourceID, err)
+		}
+
+		session.ID = 0
+		for i := range session.ApexDomains {
+			session.ApexDomains[i].ID = 0
+			session.ApexDomains[i].ScanSessionID = 0
+		}
+		if err := m.into.Create(&session).Error; err != nil {
+			return fmt.Errorf("failed to copy scan session %d: %w", sourceID, err)
+		}
+
+		m.sessions[sourceID] = session.ID
+		m.summary.Sessions++
+	}
+
+	return nil
+}
+
+// mergeResults copies results with their associated data. A result of the
+// same URL, probe time and scan session is the same result.
+func (m *merger) mergeResults() error {
+	var batch []models.Result
+	err := m.from.Preload(clause.Associations).Preload("TLS.SanList").Order("id").
+		FindInBatches(&batch, mergeBatchSize, func(_ *gorm.DB, _ int) error {
+			for i := range batch {
+				if err := m.mergeResult(&batch[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to merge results: %w", err)
+	}
+
+	return nil
+}
+
+// mergeResult copies a single result
+func (m *merger) mergeResult(result *models.Result) error {
+	sourceID := result.ID
+	sourceSession := result.ScanSessionID
+	session := m.session(result.ScanSessionID)
+
+	q := m.into.Model(&models.Result{}).Select("id").
+		Where("url = ? AND probed_at = ?", result.URL, result.ProbedAt)
+	if session != nil {
+		q = q.Where("scan_session_id = ?", *session)
+	} else {
+		q = q.Where("scan_session_id IS NULL")
+	}
+
+	var existing models.Result
+	err := q.First(&existing).Error
+	if err == nil {
+		m.results[sourceID] = existing.ID
+		m.summary.SkippedResults++
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to look up result %d: %w", sourceID, err)
+	}
+
+	screenshots := []*string{&result.Filename, &result.FullPageFilename, &result.ElementFilename}
+	if m.opts.FromScreenshotPath != "" && m.opts.IntoScreenshotPath != "" {
+		fromDir := m.screenshotDir(m.from, m.fromDirs, sourceSession, m.opts.FromScreenshotPath)
+		intoDir := m.screenshotDir(m.into, m.intoDirs, session, m.opts.IntoScreenshotPath)
+
+		for _, name := range screenshots {
+			// sessions that record their own screenshot path keep it, so
+			// their screenshots are already where the merged result looks
+			if *name == "" || filepath.Clean(fromDir) == filepath.Clean(intoDir) {
+				continue
+			}
+
+			copied, err := copyScreenshot(fromDir, intoDir, *name, sourceID)
+			if err != nil {
+				return err
+			}
+			if copied != "" {
+				*name = copied
+				m.summary.Screenshots++
+			}
+		}
+	}
+
+	resetResultIDs(result, session)
+	if result.PreviousResultID != nil {
+		if previous, ok := m.results[*result.PreviousResultID]; ok {
+			result.PreviousResultID = &previous
+		} else {
+			result.PreviousResultID = nil
+		}
+	}
+
+	if err := m.into.Create(result).Error; err != nil {
+		return fmt.Errorf("failed to copy result %d: %w", sourceID, err)
+	}
+
+	m.results[sourceID] = result.ID
+	m.summary.Results++
+
+	return nil
+}
+
+// screenshotDir resolves the screenshot directory of a scan session once
+func (m *merger) screenshotDir(db *gorm.DB, dirs map[uint]string, session *uint, fallback string) string {
+	var key uint
+	if session != nil {
+		key = *session
+	}
+
+	dir, ok := dirs[key]
+	if !ok {
+		dir = ScreenshotPath(db, session, fallback)
+		dirs[key] = dir
+	}
+
+	return dir
+}
+
+// resetResultIDs clears the ids of a result and its associated data, so
+// that they are assigned new ones when created, and sets its scan session
+func resetResultIDs(result *models.Result, session *uint) {
+	result.ID = 0
+	result.ScanSessionID = session
+
+	result.TLS.ID = 0
+	result.TLS.ResultID = 0
+	for i := range result.TLS.SanList {
+		result.TLS.SanList[i].ID = 0
+		result.TLS.SanList[i].TLSID = 0
+	}
+
+	for i := range result.Technologies {
+		result.Technologies[i].ID, result.Technologies[i].ResultID = 0, 0
+	}
+	for i := range result.Headers {
+		result.Headers[i].ID, result.Headers[i].ResultID = 0, 0
+	}
+	for i := range result.Network {
+		result.Network[i].ID, result.Network[i].ResultID = 0, 0
+	}
+	for i := range result.Console {
+		result.Console[i].ID, result.Console[i].ResultID = 0, 0
+	}
+	for i := range result.Cookies {
+		result.Cookies[i].ID, result.Cookies[i].ResultID = 0, 0
+	}
+	for i := range result.Tags {
+		result.Tags[i].ID, result.Tags[i].ResultID = 0, 0
+	}
+	for i := range result.Annotations {
+		result.Annotations[i].ID, result.Annotations[i].ResultID = 0, 0
+	}
+	for i := range result.SecurityIssues {
+		result.SecurityIssues[i].ID, result.SecurityIssues[i].ResultID = 0, 0
+	}
+	for i := range result.Paths {
+		result.Paths[i].ID, result.Paths[i].ResultID = 0, 0
+	}
+	for i := range result.SiteFiles {
+		result.SiteFiles[i].ID, result.SiteFiles[i].ResultID = 0, 0
+	}
+	for i := range result.ExtractedURLs {
+		result.ExtractedURLs[i].ID, result.ExtractedURLs[i].ResultID = 0, 0
+	}
+	for i := range result.Secrets {
+		result.Secrets[i].ID, result.Secrets[i].ResultID = 0, 0
+	}
+
+	for i := range result.Findings {
+		result.Findings[i].ID, result.Findings[i].ResultID = 0, nil
+		result.Findings[i].ScanSessionID = session
+	}
+	for i := range result.Enrichments {
+		result.Enrichments[i].ID, result.Enrichments[i].ResultID = 0, nil
+		result.Enrichments[i].ScanSessionID = session
+	}
+	for i := range result.Notes {
+		result.Notes[i].ID, result.Notes[i].ResultID = 0, nil
+		result.Notes[i].ScanSessionID = nil
+	}
+}
+
+// copyScreenshot copies a screenshot between screenshot directories,
+// returning the name it was stored under, or "" if the source file does
+// not exist. A different file of the same name in the destination is
+// kept, and the screenshot is stored under a name suffixed with its
+// source result id instead.
+func copyScreenshot(fromDir, intoDir, name string, resultID uint) (string, error) {
+	// thumbnail.Clean keeps us inside the screenshot paths
+	name = thumbnail.Clean(name)
+	src := filepath.Join(fromDir, filepath.FromSlash(name))
+
+	content, err := os.ReadFile(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read screenshot %s: %w", src, err)
+	}
+
+	dst := filepath.Join(intoDir, filepath.FromSlash(name))
+	if existing, err := os.ReadFile(dst); err == nil {
+		if bytes.Equal(existing, content) {
+			return name, nil
+		}
+
+		name = fmt.Sprintf("%s-merged-%d%s", strings.TrimSuffix(name, path.Ext(name)), resultID, path.Ext(name))
+		dst = filepath.Join(intoDir, filepath.FromSlash(name))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", fmt.Errorf("failed to create screenshot directory: %w", err)
+	}
+
+	if err := os.WriteFile(dst, content, os.FileMode(0664)); err != nil {
+		return "", fmt.Errorf("failed to copy screenshot %s: %w", src, err)
+	}
+
+	return name, nil
+}
+
+// mergeIPInfo copies IP information. IPs are unique, so an IP the
+// destination has already is only updated if the source's information is
+// newer, keeping when it was first seen.
+func (m *merger) mergeIPInfo() error {
+	var batch []models.IPInfo
+	err := m.from.Order("id").FindInBatches(&batch, mergeBatchSize, func(_ *gorm.DB, _ int) error {
+		for _, info := range batch {
+			var existing models.IPInfo
+			err := m.into.Where("ip_address = ?", info.IPAddress).First(&existing).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				info.ID = 0
+				info.ScanSessionID = m.session(info.ScanSessionID)
+				if err := m.into.Create(&info).Error; err != nil {
+					return fmt.Errorf("failed to copy ip info of %s: %w", info.IPAddress, err)
+				}
+				if err := m.into.Create(models.NewIPInfoHistory(&info, "merge")).Error; err != nil {
+					return fmt.Errorf("failed to record ip info history of %s: %w", info.IPAddress, err)
+				}
+				m.summary.IPInfo++
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to look up ip info of %s: %w", info.IPAddress, err)
+			}
+
+			if !info.LastUpdate.After(existing.LastUpdate) {
+				continue
+			}
+
+			info.ID = existing.ID
+			info.ScanSessionID = m.session(info.ScanSessionID)
+			if !existing.FirstSeen.IsZero() && (info.FirstSeen.IsZero() || existing.FirstSeen.Before(info.FirstSeen)) {
+				info.FirstSeen = existing.FirstSeen
+			}
+			if err := m.into.Save(&info).Error; err != nil {
+				return fmt.Errorf("failed to update ip info of %s: %w", info.IPAddress, err)
+			}
+			if err := m.into.Create(models.NewIPInfoHistory(&info, "merge")).Error; err != nil {
+				return fmt.Errorf("failed to record ip info history of %s: %w", info.IPAddress, err)
+			}
+			m.summary.UpdatedIPInfo++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to merge ip info: %w", err)
+	}
+
+	return nil
+}
+
+// mergeIPPorts copies open ports. A port of the same IP, number, protocol
+// and scan session is the same port.
+func (m *merger) mergeIPPorts() error {
+	var batch []models.IPPort
+	err := m.from.Order("id").FindInBatches(&batch, mergeBatchSize, func(_ *gorm.DB, _ int) error {
+		for _, port := range batch {
+			port.ScanSessionID = m.session(port.ScanSessionID)
+
+			q := m.into.Model(&models.IPPort{}).
+				Where("ip_address = ? AND port = ? AND protocol = ?", port.IPAddress, port.Port, port.Protocol)
+			if port.ScanSessionID != nil {
+				q = q.Where("scan_session_id = ?", *port.ScanSessionID)
+			} else {
+				q = q.Where("scan_session_id IS NULL")
+			}
+
+			var count int64
+			if err := q.Count(&count).Error; err != nil {
+				return fmt.Errorf("failed to look up port %s:%d: %w", port.IPAddress, port.Port, err)
+			}
+			if count > 0 {
+				m.summary.SkippedIPPorts++
+				continue
+			}
+
+			port.ID = 0
+			if err := m.into.Create(&port).Error; err != nil {
+				return fmt.Errorf("failed to copy port %s:%d: %w", port.IPAddress, port.Port, err)
+			}
+			m.summary.IPPorts++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to merge ports: %w", err)
+	}
+
+	return nil
+}
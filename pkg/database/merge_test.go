@@ -0,0 +1,125 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// encryptedDatabase creates a SQLite database encrypted with passphrase
+func encryptedDatabase(t *testing.T, name string, passphrase string) *gorm.DB {
+	t.Helper()
+
+	db, err := ConnectionWithKey("sqlite://"+filepath.Join(t.TempDir(), name), false, false, passphrase)
+	if err != nil {
+		t.Fatalf("ConnectionWithKey() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return db
+}
+
+// encryptedPrefix marks encrypted column values
+const encryptedPrefix = "enc:v1:"
+
+// rawColumn reads a column as stored, without the encrypted serializer
+func rawColumn(t *testing.T, db *gorm.DB, table string, column string) string {
+	t.Helper()
+
+	var value string
+	if err := db.Table(table).Select(column).Limit(1).Scan(&value).Error; err != nil {
+		t.Fatalf("failed to read %s.%s: %v", table, column, err)
+	}
+
+	return value
+}
+
+func TestMergeEncrypted(t *testing.T) {
+	// the database log is written to the working directory
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name    string
+		fromKey string
+		intoKey string
+		wantEnc bool
+	}{
+		{
+			name:    "Test same passphrase",
+			fromKey: "correct horse battery staple",
+			intoKey: "correct horse battery staple",
+			wantEnc: true,
+		},
+		{
+			name:    "Test different passphrases",
+			fromKey: "correct horse battery staple",
+			intoKey: "tr0ub4dor&3",
+			wantEnc: true,
+		},
+		{
+			name:    "Test encrypted into plain",
+			fromKey: "correct horse battery staple",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := encryptedDatabase(t, "from.sqlite3", tt.fromKey)
+			into := encryptedDatabase(t, "into.sqlite3", tt.intoKey)
+
+			result := &models.Result{
+				URL:      "https://example.com",
+				ProbedAt: time.Now(),
+				HTML:     "<html>secret body</html>",
+				Cookies:  []models.Cookie{{Name: "session", Value: "s3cr3t"}},
+			}
+			if err := from.Create(result).Error; err != nil {
+				t.Fatalf("failed to create result: %v", err)
+			}
+			if raw := rawColumn(t, from, "results", "html"); !strings.HasPrefix(raw, encryptedPrefix) {
+				t.Fatalf("source body is stored as %q, want it encrypted", raw)
+			}
+
+			summary, err := Merge(from, into, MergeOptions{})
+			if err != nil {
+				t.Fatalf("Merge() error = %v", err)
+			}
+			if summary.Results != 1 {
+				t.Fatalf("Merge() results = %d, want 1", summary.Results)
+			}
+
+			var merged models.Result
+			if err := into.Preload("Cookies").First(&merged).Error; err != nil {
+				t.Fatalf("failed to read merged result: %v", err)
+			}
+			if merged.HTML != result.HTML {
+				t.Errorf("merged body = %q, want %q", merged.HTML, result.HTML)
+			}
+			if len(merged.Cookies) != 1 || merged.Cookies[0].Value != "s3cr3t" {
+				t.Errorf("merged cookies = %+v, want a cookie valued s3cr3t", merged.Cookies)
+			}
+
+			raw := rawColumn(t, into, "results", "html")
+			if got := strings.HasPrefix(raw, encryptedPrefix); got != tt.wantEnc {
+				t.Errorf("merged body is stored as %q, want encrypted %v", raw, tt.wantEnc)
+			}
+			if tt.wantEnc && raw == rawColumn(t, from, "results", "html") {
+				t.Error("merged body was copied as stored, not encrypted with the destination's key")
+			}
+
+			// the source still reads with its own key
+			var source models.Result
+			if err := from.First(&source).Error; err != nil || source.HTML != result.HTML {
+				t.Errorf("source body = %q, %v after the merge, want %q", source.HTML, err, result.HTML)
+			}
+		})
+	}
+}
@@ -0,0 +1,534 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/cloud"
+	"gorm.io/gorm"
+)
+
+// ErrPendingMigrations is returned when connecting to a database with
+// pending migrations while AutoMigrate is disabled
+var ErrPendingMigrations = errors.New("database has pending schema migrations, apply them with: gowitness db migrate up")
+
+// ErrSchemaTooNew is returned for databases migrated by a newer build,
+// whose schema this build does not know
+var ErrSchemaTooNew = errors.New("database schema is newer than this build of gowitness supports")
+
+// ErrIrreversibleMigration is returned when rolling back a migration that
+// can not be rolled back
+var ErrIrreversibleMigration = errors.New("migration can not be rolled back")
+
+// SchemaMigration records a migration applied to a database
+type SchemaMigration struct {
+	Version   uint      `json:"version" gorm:"primarykey;autoIncrement:false"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Migration is a versioned change to the database schema. Migrations are
+// applied in order of their version, each in a transaction where the
+// database supports transactional schema changes.
+type Migration struct {
+	Version uint
+	Name    string
+	// Up applies the migration
+	Up func(tx *gorm.DB) error
+	// Down rolls the migration back. Migrations without it can not be
+	// rolled back.
+	Down func(tx *gorm.DB) error
+}
+
+// MigrationState is a migration and whether it was applied to a database
+type MigrationState struct {
+	Version   uint       `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// migrations are the schema migrations, in order. Once released, a
+// migration must never change: schema changes, such as a new model or
+// column, get a new migration with the next version instead.
+var migrations = []Migration{
+	{
+		// the schema as of the first versioned migration. Databases created
+		// by earlier builds were auto-migrated to (a subset of) it, which
+		// this completes without touching existing data.
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(
+				&resultV1{},
+				&tlsV1{},
+				&tlsSanListV1{},
+				&technologyV1{},
+				&headerV1{},
+				&networkLogV1{},
+				&consoleLogV1{},
+				&cookieV1{},
+				&scanSessionV1{},
+				&apexDomainV1{},
+				&ipPortV1{},
+				&ipInfoV1{},
+				&ipInfoHistoryV1{},
+				&domainV1{},
+				&resultHostV1{},
+				&searchDocumentV1{},
+				&originCandidateV1{},
+				&userV1{},
+				&userSessionV1{},
+				&tagV1{},
+				&resultTagV1{},
+				&findingV1{},
+				&enrichmentV1{},
+				&dnsRecordV1{},
+				&annotationV1{},
+				&securityIssueV1{},
+				&alertV1{},
+				&noteV1{},
+				&discoveredPathV1{},
+				&siteFileV1{},
+				&extractedURLV1{},
+				&secretV1{},
+				&breachExposureV1{},
+				&domainRegistrationV1{},
+				&netblockV1{},
+				&jobV1{},
+			)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "encryption keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&encryptionKeyV2{})
+		},
+		Down: func(tx *gorm.DB) error {
+			var count int64
+			if err := tx.Model(&encryptionKeyV2{}).Count(&count).Error; err != nil {
+				return err
+			}
+			// the key can not be derived without it
+			if count > 0 {
+				return errors.New("the database is encrypted, and would be unreadable without its key")
+			}
+			return tx.Migrator().DropTable(&encryptionKeyV2{})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "cookie same site",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&cookieV3{}, "SameSite") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&cookieV3{}, "SameSite")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&cookieV3{}, "SameSite")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "host status",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&hostStatusV4{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&hostStatusV4{})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "cloud provider",
+		Up: func(tx *gorm.DB) error {
+			for _, model := range []any{&ipInfoV5{}, &ipPortV5{}} {
+				if tx.Migrator().HasColumn(model, "CloudProvider") {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(model, "CloudProvider"); err != nil {
+					return err
+				}
+				if err := tx.Migrator().CreateIndex(model, "CloudProvider"); err != nil {
+					return err
+				}
+			}
+			// attribute the addresses stored before the column was added
+			for _, model := range []any{&ipInfoV5{}, &ipPortV5{}} {
+				var addresses []string
+				if err := tx.Model(model).Distinct().Pluck("ip_address", &addresses).Error; err != nil {
+					return err
+				}
+				for _, address := range addresses {
+					if err := tx.Model(model).Where("ip_address = ?", address).
+						UpdateColumn("cloud_provider", cloud.Provider(address)).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, model := range []any{&ipInfoV5{}, &ipPortV5{}} {
+				if tx.Migrator().HasIndex(model, "CloudProvider") {
+					if err := tx.Migrator().DropIndex(model, "CloudProvider"); err != nil {
+						return err
+					}
+				}
+				if err := tx.Migrator().DropColumn(model, "CloudProvider"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 6,
+		Name:    "service screenshots",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&serviceScreenshotV6{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&serviceScreenshotV6{})
+		},
+	},
+	{
+		Version: 7,
+		Name:    "provider domains",
+		Up: func(tx *gorm.DB) error {
+			for _, model := range []any{&enrichmentV7{}, &findingV7{}} {
+				if tx.Migrator().HasColumn(model, "Domain") {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(model, "Domain"); err != nil {
+					return err
+				}
+				if err := tx.Migrator().CreateIndex(model, "Domain"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, model := range []any{&enrichmentV7{}, &findingV7{}} {
+				if tx.Migrator().HasIndex(model, "Domain") {
+					if err := tx.Migrator().DropIndex(model, "Domain"); err != nil {
+						return err
+					}
+				}
+				if err := tx.Migrator().DropColumn(model, "Domain"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 8,
+		Name:    "tickets",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&ticketV8{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&ticketV8{})
+		},
+	},
+	{
+		Version: 9,
+		Name:    "assets",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&assetV9{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&assetV9{})
+		},
+	},
+	{
+		// results and ports written since they were indexed are indexed
+		// again, by when they were last written
+		Version: 10,
+		Name:    "search index updates",
+		Up: func(tx *gorm.DB) error {
+			for _, model := range []any{&resultV10{}, &ipPortV10{}} {
+				if tx.Migrator().HasColumn(model, "UpdatedAt") {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(model, "UpdatedAt"); err != nil {
+					return err
+				}
+				if err := tx.Migrator().CreateIndex(model, "UpdatedAt"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasColumn(&searchDocumentV10{}, "SourceUpdatedAt") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&searchDocumentV10{}, "SourceUpdatedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, model := range []any{&resultV10{}, &ipPortV10{}} {
+				if tx.Migrator().HasIndex(model, "UpdatedAt") {
+					if err := tx.Migrator().DropIndex(model, "UpdatedAt"); err != nil {
+						return err
+					}
+				}
+				if err := tx.Migrator().DropColumn(model, "UpdatedAt"); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropColumn(&searchDocumentV10{}, "SourceUpdatedAt")
+		},
+	},
+	{
+		// users logging in through an identity provider are found by
+		// issuer and subject instead of by username
+		Version: 11,
+		Name:    "user identities",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&userIdentityV11{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&userIdentityV11{})
+		},
+	},
+}
+
+// The columns migrations add or read, as of those migrations, so that they
+// do not change with the models
+type cookieV3 struct {
+	SameSite string
+}
+
+func (cookieV3) TableName() string { return "cookies" }
+
+type ipInfoV5 struct {
+	IPAddress     string
+	CloudProvider string `gorm:"index"`
+}
+
+func (ipInfoV5) TableName() string { return "ip_infos" }
+
+type ipPortV5 struct {
+	IPAddress     string
+	CloudProvider string `gorm:"index"`
+}
+
+func (ipPortV5) TableName() string { return "ip_ports" }
+
+type enrichmentV7 struct {
+	Domain string `gorm:"index"`
+}
+
+func (enrichmentV7) TableName() string { return "enrichments" }
+
+type findingV7 struct {
+	Domain string `gorm:"index"`
+}
+
+func (findingV7) TableName() string { return "findings" }
+
+type resultV10 struct {
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+func (resultV10) TableName() string { return "results" }
+
+type ipPortV10 struct {
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+func (ipPortV10) TableName() string { return "ip_ports" }
+
+type searchDocumentV10 struct {
+	SourceUpdatedAt time.Time
+}
+
+func (searchDocumentV10) TableName() string { return "search_documents" }
+
+// Migrations returns the schema migrations this build knows, in order
+func Migrations() []Migration {
+	return migrations
+}
+
+// LatestVersion returns the schema version this build migrates to
+func LatestVersion() uint {
+	return migrations[len(migrations)-1].Version
+}
+
+// appliedMigrations returns the migrations applied to a database, by
+// version, creating the table they are recorded in if needed
+func appliedMigrations(db *gorm.DB) (map[uint]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema migrations table: %w", err)
+	}
+
+	var rows []SchemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied schema migrations: %w", err)
+	}
+
+	applied := make(map[uint]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+// SchemaVersion returns the highest migration version applied to a
+// database, 0 if none were
+func SchemaVersion(db *gorm.DB) (uint, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	var version uint
+	for v := range applied {
+		version = max(version, v)
+	}
+
+	return version, nil
+}
+
+// MigrationStatus returns every migration this build knows and whether it
+// was applied to a database
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		state := MigrationState{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			state.Applied = true
+			state.AppliedAt = &row.AppliedAt
+		}
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// checkSchema checks that a database is migrated to the version this build
+// migrates to
+func checkSchema(db *gorm.DB) error {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version > LatestVersion():
+		return fmt.Errorf("%w: version %d, expected %d", ErrSchemaTooNew, version, LatestVersion())
+	case version < LatestVersion():
+		return fmt.Errorf("%w: version %d, expected %d", ErrPendingMigrations, version, LatestVersion())
+	}
+
+	return nil
+}
+
+// MigrateUp applies the pending migrations up to and including version
+// to, or all of them if to is 0, returning the migrations applied
+func MigrateUp(db *gorm.DB, to uint) ([]Migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for version := range applied {
+		if version > LatestVersion() {
+			return nil, fmt.Errorf("%w: version %d, expected %d", ErrSchemaTooNew, version, LatestVersion())
+		}
+	}
+
+	var done []Migration
+	for _, migration := range migrations {
+		if to > 0 && migration.Version > to {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := migrationTransaction(db, func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("failed to apply migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// MigrateDown rolls back the applied migrations newer than version to,
+// newest first, returning the migrations rolled back
+func MigrateDown(db *gorm.DB, to uint) ([]Migration, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version <= to {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == nil {
+			return done, fmt.Errorf("%w: %d (%s)", ErrIrreversibleMigration, migration.Version, migration.Name)
+		}
+
+		err := migrationTransaction(db, func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("failed to roll back migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// migrationTransaction runs a migration in a transaction. SQLite rebuilds
+// a table to drop or change its columns, and dropping the old table would
+// cascade to the rows referencing it, so its foreign keys are not enforced
+// while a migration runs. The pragma is per connection and has no effect in
+// a transaction, so the migration is pinned to one connection.
+func migrationTransaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if db.Dialector.Name() != "sqlite" {
+		return db.Transaction(fn)
+	}
+
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+
+		return conn.Transaction(fn)
+	})
+}
@@ -0,0 +1,666 @@
+package database
+
+import "time"
+
+// The tables of the baseline migration and the migrations that create
+// tables, as of those migrations. Migrations use these instead of the
+// models, so that they do not change as the models do.
+
+type resultV1 struct {
+	ID uint `gorm:"primarykey"`
+
+	URL                   string
+	IPAddress             string `gorm:"index"`
+	ScanSessionID         *uint  `gorm:"index"`
+	PreviousResultID      *uint  `gorm:"index"`
+	ProbedAt              time.Time
+	FinalURL              string
+	ResponseCode          int
+	ResponseReason        string
+	Protocol              string
+	ContentLength         int64
+	HTML                  string `gorm:"index"`
+	Title                 string `gorm:"index"`
+	PerceptionHash        string `gorm:"index"`
+	PerceptionHashGroupId uint   `gorm:"index"`
+	Screenshot            string
+
+	Filename         string
+	Thumbnail        string
+	IsPDF            bool
+	FullPageFilename string
+	ElementFilename  string
+
+	Failed       bool
+	FailedReason string
+
+	Noise       bool `gorm:"index"`
+	NoiseReason string
+
+	SecurityScore int    `gorm:"index"`
+	SecurityGrade string `gorm:"index"`
+
+	Reviewed    bool `gorm:"index"`
+	ReviewedBy  string
+	ReviewedAt  *time.Time
+	Interesting bool   `gorm:"index"`
+	AssignedTo  string `gorm:"index"`
+
+	TLS          tlsV1          `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	Technologies []technologyV1 `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+
+	Headers []headerV1     `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	Network []networkLogV1 `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	Console []consoleLogV1 `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	Cookies []cookieV1     `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+
+	Tags        []resultTagV1  `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	Findings    []findingV1    `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	Enrichments []enrichmentV1 `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	Annotations []annotationV1 `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+
+	SecurityIssues []securityIssueV1  `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	Notes          []noteV1           `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	Paths          []discoveredPathV1 `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	SiteFiles      []siteFileV1       `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	ExtractedURLs  []extractedURLV1   `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+	Secrets        []secretV1         `gorm:"foreignKey:ResultID;constraint:OnDelete:CASCADE"`
+}
+
+func (resultV1) TableName() string { return "results" }
+
+type tlsV1 struct {
+	ID       uint `gorm:"primarykey"`
+	ResultID uint
+
+	Protocol                 string
+	KeyExchange              string
+	Cipher                   string
+	SubjectName              string
+	SanList                  []tlsSanListV1 `gorm:"foreignKey:TLSID;constraint:OnDelete:CASCADE"`
+	Issuer                   string
+	ValidFrom                time.Time
+	ValidTo                  time.Time
+	ServerSignatureAlgorithm int64
+	EncryptedClientHello     bool
+}
+
+func (tlsV1) TableName() string { return "tls" }
+
+type tlsSanListV1 struct {
+	ID    uint `gorm:"primarykey"`
+	TLSID uint
+
+	Value string
+}
+
+func (tlsSanListV1) TableName() string { return "tls_san_lists" }
+
+type technologyV1 struct {
+	ID       uint `gorm:"primarykey"`
+	ResultID uint
+
+	Value   string `gorm:"index"`
+	Name    string `gorm:"index"`
+	Version string
+	CPE     string
+}
+
+func (technologyV1) TableName() string { return "technologies" }
+
+type headerV1 struct {
+	ID       uint `gorm:"primarykey"`
+	ResultID uint
+
+	Key   string
+	Value string `gorm:"index"`
+}
+
+func (headerV1) TableName() string { return "headers" }
+
+type networkLogV1 struct {
+	ID       uint `gorm:"primarykey"`
+	ResultID uint
+
+	RequestType int
+	StatusCode  int64
+	URL         string
+	RemoteIP    string
+	MIMEType    string
+	Time        time.Time
+	Content     []byte
+	Error       string
+	ContentHash string `gorm:"index"`
+}
+
+func (networkLogV1) TableName() string { return "network_logs" }
+
+type consoleLogV1 struct {
+	ID       uint `gorm:"primarykey"`
+	ResultID uint
+
+	Type  string
+	Value string `gorm:"index"`
+}
+
+func (consoleLogV1) TableName() string { return "console_logs" }
+
+type cookieV1 struct {
+	ID       uint `gorm:"primarykey"`
+	ResultID uint
+
+	Name         string
+	Value        string
+	Domain       string
+	Path         string
+	Expires      time.Time
+	Size         int64
+	HTTPOnly     bool
+	Secure       bool
+	Session      bool
+	Priority     string
+	SourceScheme string
+	SourcePort   int64
+}
+
+func (cookieV1) TableName() string { return "cookies" }
+
+type scanSessionV1 struct {
+	ID             uint   `gorm:"primarykey"`
+	CompanyName    string `gorm:"index"`
+	MainDomain     string `gorm:"index"`
+	LogoPath       string
+	ScreenshotPath string
+	Timezone       string
+	StartTime      time.Time
+	EndTime        *time.Time
+	Status         string `gorm:"default:'active'"`
+	FailedPhase    string
+	FailureReason  string
+	Notes          string
+
+	Industry         string
+	EmployeeCount    int
+	Netblocks        string
+	Domains          string
+	EnrichmentSource string
+
+	ApexDomains []apexDomainV1 `gorm:"foreignKey:ScanSessionID;constraint:OnDelete:CASCADE"`
+}
+
+func (scanSessionV1) TableName() string { return "scan_sessions" }
+
+type apexDomainV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	ScanSessionID uint   `gorm:"index"`
+	Domain        string `gorm:"index"`
+}
+
+func (apexDomainV1) TableName() string { return "apex_domains" }
+
+type ipPortV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	IPAddress     string `gorm:"index;not null"`
+	Port          int    `gorm:"index;not null"`
+	Protocol      string `gorm:"default:'tcp'"`
+	Service       string
+	State         string `gorm:"default:'open'"`
+	Banner        string
+	ScanSessionID *uint     `gorm:"index"`
+	DiscoveredAt  time.Time `gorm:"autoCreateTime"`
+
+	Product     string
+	Version     string
+	HTTPStatus  int
+	HTTPTitle   string
+	TLSSubject  string
+	TLSIssuer   string
+	TLSVersions string
+	TLSExpires  *time.Time
+
+	IsCDN        bool `gorm:"default:false"`
+	CDNName      string
+	CDNDetected  bool `gorm:"default:false"`
+	OriginalHost string
+}
+
+func (ipPortV1) TableName() string { return "ip_ports" }
+
+type ipInfoV1 struct {
+	ID           uint   `gorm:"primarykey"`
+	IPAddress    string `gorm:"uniqueIndex;not null"`
+	Organization string
+	ISP          string
+	ASN          string
+	Country      string
+	CountryCode  string
+	City         string
+	Region       string
+	Postal       string
+	Latitude     float64
+	Longitude    float64
+	OS           string
+	Tags         string
+	Ports        string
+	Hostnames    string
+	Domains      string
+	Vulns        string
+	ReverseDNS   string
+	LastUpdate   time.Time
+	FirstSeen    time.Time
+	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+
+	ScanSessionID *uint `gorm:"index"`
+}
+
+func (ipInfoV1) TableName() string { return "ip_infos" }
+
+type ipInfoHistoryV1 struct {
+	ID           uint   `gorm:"primarykey"`
+	IPAddress    string `gorm:"index;not null"`
+	Source       string
+	Organization string
+	ISP          string
+	ASN          string
+	Country      string
+	CountryCode  string
+	City         string
+	OS           string
+	Tags         string
+	Ports        string
+	Hostnames    string
+	Vulns        string
+	LastUpdate   time.Time
+	RecordedAt   time.Time `gorm:"index"`
+
+	ScanSessionID *uint `gorm:"index"`
+}
+
+func (ipInfoHistoryV1) TableName() string { return "ip_info_histories" }
+
+type domainV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	Name          string `gorm:"index;not null"`
+	Source        string `gorm:"index"`
+	Historical    bool
+	FirstSeen     time.Time
+	LastSeen      time.Time
+	ScanSessionID *uint  `gorm:"index"`
+	Resolution    string `gorm:"index"`
+	ResolvedAt    *time.Time
+}
+
+func (domainV1) TableName() string { return "domains" }
+
+type resultHostV1 struct {
+	ID         uint `gorm:"primarykey"`
+	ResultID   uint `gorm:"uniqueIndex"`
+	URL        string
+	Hostname   string `gorm:"index"`
+	ApexDomain string `gorm:"index"`
+	Protocol   string
+	Port       string
+	IPAddress  string `gorm:"index"`
+	ProbedAt   time.Time
+}
+
+func (resultHostV1) TableName() string { return "result_hosts" }
+
+type searchDocumentV1 struct {
+	ID       uint   `gorm:"primarykey"`
+	ResultID uint   `gorm:"index"`
+	IPPortID uint   `gorm:"index"`
+	Field    string `gorm:"index"`
+	Name     string `gorm:"index"`
+	Content  string
+}
+
+func (searchDocumentV1) TableName() string { return "search_documents" }
+
+type originCandidateV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	IPPortID      uint   `gorm:"index;not null"`
+	Hostname      string `gorm:"index"`
+	IPAddress     string `gorm:"index;not null"`
+	Source        string
+	Evidence      string
+	Probed        bool
+	Verified      bool
+	ProbeEvidence string
+	ScanSessionID *uint     `gorm:"index"`
+	DiscoveredAt  time.Time `gorm:"autoCreateTime"`
+}
+
+func (originCandidateV1) TableName() string { return "origin_candidates" }
+
+type userV1 struct {
+	ID           uint   `gorm:"primarykey"`
+	Username     string `gorm:"uniqueIndex;not null"`
+	PasswordHash string `gorm:"not null"`
+	Role         string `gorm:"not null"`
+	Disabled     bool
+	CreatedAt    time.Time
+	LastLogin    *time.Time
+}
+
+func (userV1) TableName() string { return "users" }
+
+type userSessionV1 struct {
+	ID        uint      `gorm:"primarykey"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	UserID    uint      `gorm:"index"`
+	User      userV1    `gorm:"constraint:OnDelete:CASCADE"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (userSessionV1) TableName() string { return "user_sessions" }
+
+type tagV1 struct {
+	ID          uint   `gorm:"primarykey"`
+	Name        string `gorm:"uniqueIndex;not null"`
+	Description string
+	CreatedAt   time.Time
+}
+
+func (tagV1) TableName() string { return "tags" }
+
+type resultTagV1 struct {
+	ID        uint   `gorm:"primarykey"`
+	ResultID  uint   `gorm:"index"`
+	Name      string `gorm:"index;not null"`
+	Source    string
+	CreatedAt time.Time
+}
+
+func (resultTagV1) TableName() string { return "result_tags" }
+
+type findingV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	ResultID      *uint  `gorm:"index"`
+	IPAddress     string `gorm:"index"`
+	Source        string `gorm:"index"`
+	Title         string
+	Severity      string `gorm:"index"`
+	Description   string
+	CVE           string `gorm:"index"`
+	CVSS          float64
+	ScanSessionID *uint `gorm:"index"`
+	CreatedAt     time.Time
+}
+
+func (findingV1) TableName() string { return "findings" }
+
+type enrichmentV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	ResultID      *uint  `gorm:"index"`
+	IPAddress     string `gorm:"index"`
+	Source        string `gorm:"index"`
+	Key           string
+	Value         string
+	ScanSessionID *uint `gorm:"index"`
+	CreatedAt     time.Time
+}
+
+func (enrichmentV1) TableName() string { return "enrichments" }
+
+type dnsRecordV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	Host          string `gorm:"index;not null"`
+	Type          string `gorm:"index"`
+	Value         string `gorm:"index"`
+	Dangling      bool   `gorm:"index"`
+	FirstSeen     time.Time
+	LastSeen      time.Time
+	ScanSessionID *uint `gorm:"index"`
+}
+
+func (dnsRecordV1) TableName() string { return "dns_records" }
+
+type annotationV1 struct {
+	ID       uint   `gorm:"primarykey"`
+	ResultID uint   `gorm:"index"`
+	Kind     string `gorm:"index"`
+	Detail   string
+}
+
+func (annotationV1) TableName() string { return "annotations" }
+
+type securityIssueV1 struct {
+	ID       uint   `gorm:"primarykey"`
+	ResultID uint   `gorm:"index"`
+	Kind     string `gorm:"index"`
+	Severity string
+	Detail   string
+	Penalty  int
+}
+
+func (securityIssueV1) TableName() string { return "security_issues" }
+
+type alertV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	Rule          string `gorm:"uniqueIndex:idx_alert_rule_subject"`
+	Subject       string `gorm:"uniqueIndex:idx_alert_rule_subject"`
+	Severity      string `gorm:"index"`
+	Message       string
+	IPAddress     string `gorm:"index"`
+	ScanSessionID *uint  `gorm:"index"`
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time
+}
+
+func (alertV1) TableName() string { return "alerts" }
+
+type noteV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	ResultID      *uint  `gorm:"index"`
+	IPInfoID      *uint  `gorm:"index"`
+	ScanSessionID *uint  `gorm:"index"`
+	Author        string `gorm:"index"`
+	Body          string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (noteV1) TableName() string { return "notes" }
+
+type discoveredPathV1 struct {
+	ID                 uint   `gorm:"primarykey"`
+	ResultID           uint   `gorm:"uniqueIndex:idx_discovered_path"`
+	Path               string `gorm:"uniqueIndex:idx_discovered_path"`
+	URL                string
+	StatusCode         int `gorm:"index"`
+	ContentLength      int
+	ContentType        string
+	Title              string
+	Location           string
+	ScreenshotResultID *uint
+	ScanSessionID      *uint `gorm:"index"`
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+func (discoveredPathV1) TableName() string { return "discovered_paths" }
+
+type siteFileV1 struct {
+	ID         uint   `gorm:"primarykey"`
+	ResultID   uint   `gorm:"index"`
+	Kind       string `gorm:"index"`
+	URL        string
+	StatusCode int
+	Content    string
+	URLCount   int
+	CreatedAt  time.Time
+}
+
+func (siteFileV1) TableName() string { return "site_files" }
+
+type extractedURLV1 struct {
+	ID       uint   `gorm:"primarykey"`
+	ResultID uint   `gorm:"index"`
+	Kind     string `gorm:"index"`
+	URL      string `gorm:"index"`
+	Host     string `gorm:"index"`
+	Source   string
+}
+
+func (extractedURLV1) TableName() string { return "extracted_urls" }
+
+type secretV1 struct {
+	ID       uint   `gorm:"primarykey"`
+	ResultID uint   `gorm:"index"`
+	Kind     string `gorm:"index"`
+	Value    string
+	Source   string
+	Location string
+}
+
+func (secretV1) TableName() string { return "secrets" }
+
+type breachExposureV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	Domain        string `gorm:"uniqueIndex:idx_breach_exposure"`
+	Source        string `gorm:"uniqueIndex:idx_breach_exposure"`
+	Accounts      int
+	Breaches      int
+	BreachNames   string
+	ScanSessionID *uint `gorm:"index"`
+	CheckedAt     time.Time
+}
+
+func (breachExposureV1) TableName() string { return "breach_exposures" }
+
+type domainRegistrationV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	Domain        string `gorm:"uniqueIndex;not null"`
+	Registrar     string
+	Status        string
+	Nameservers   string
+	CreatedDate   *time.Time
+	ExpiryDate    *time.Time `gorm:"index"`
+	UpdatedDate   *time.Time
+	ScanSessionID *uint `gorm:"index"`
+	CheckedAt     time.Time
+}
+
+func (domainRegistrationV1) TableName() string { return "domain_registrations" }
+
+type netblockV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	Handle        string `gorm:"uniqueIndex;not null"`
+	Name          string
+	StartAddress  string
+	EndAddress    string
+	CIDRs         string
+	Owner         string
+	Country       string
+	Type          string
+	ScanSessionID *uint `gorm:"index"`
+	CheckedAt     time.Time
+}
+
+func (netblockV1) TableName() string { return "netblocks" }
+
+type jobV1 struct {
+	ID            uint   `gorm:"primarykey"`
+	Kind          string `gorm:"index"`
+	Status        string `gorm:"index"`
+	Priority      int    `gorm:"index"`
+	Payload       string
+	Attempts      int
+	MaxAttempts   int
+	RunAt         time.Time `gorm:"index"`
+	Total         int
+	Completed     int
+	Failed        int
+	Errors        string
+	Error         string
+	ScanSessionID *uint `gorm:"index"`
+	Worker        string
+	HeartbeatAt   *time.Time
+	CreatedAt     time.Time
+	StartedAt     *time.Time
+	FinishedAt    *time.Time
+}
+
+func (jobV1) TableName() string { return "jobs" }
+
+type encryptionKeyV2 struct {
+	ID         uint `gorm:"primarykey"`
+	Salt       string
+	Iterations int
+	Verifier   string
+	CreatedAt  time.Time
+}
+
+func (encryptionKeyV2) TableName() string { return "encryption_keys" }
+
+type hostStatusV4 struct {
+	ID            uint   `gorm:"primarykey"`
+	IPAddress     string `gorm:"uniqueIndex;not null"`
+	Alive         bool   `gorm:"index"`
+	Method        string
+	CheckedAt     time.Time
+	ScanSessionID *uint `gorm:"index"`
+}
+
+func (hostStatusV4) TableName() string { return "host_statuses" }
+
+type serviceScreenshotV6 struct {
+	ID            uint   `gorm:"primarykey"`
+	IPPortID      uint   `gorm:"uniqueIndex;not null"`
+	IPAddress     string `gorm:"index;not null"`
+	Port          int
+	Protocol      string
+	Filename      string
+	Width         int
+	Height        int
+	Name          string
+	Failed        bool
+	FailedReason  string
+	ScanSessionID *uint `gorm:"index"`
+	CapturedAt    time.Time
+}
+
+func (serviceScreenshotV6) TableName() string { return "service_screenshots" }
+
+type ticketV8 struct {
+	ID            uint   `gorm:"primarykey"`
+	Provider      string `gorm:"uniqueIndex:idx_ticket_provider_fingerprint"`
+	Fingerprint   string `gorm:"uniqueIndex:idx_ticket_provider_fingerprint"`
+	Key           string
+	URL           string
+	Summary       string
+	FindingID     *uint `gorm:"index"`
+	AlertID       *uint `gorm:"index"`
+	ScanSessionID *uint `gorm:"index"`
+	CreatedAt     time.Time
+}
+
+func (ticketV8) TableName() string { return "tickets" }
+
+type assetV9 struct {
+	ID            uint   `gorm:"primarykey"`
+	Host          string `gorm:"uniqueIndex;not null"`
+	Label         string
+	Owner         string `gorm:"index"`
+	Environment   string `gorm:"index"`
+	Criticality   string `gorm:"index"`
+	Metadata      string
+	ScanSessionID *uint `gorm:"index"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (assetV9) TableName() string { return "assets" }
+
+type userIdentityV11 struct {
+	ID        uint   `gorm:"primarykey"`
+	Issuer    string `gorm:"uniqueIndex:idx_user_identities_subject;not null"`
+	Subject   string `gorm:"uniqueIndex:idx_user_identities_subject;not null"`
+	UserID    uint   `gorm:"index"`
+	User      userV1 `gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time
+}
+
+func (userIdentityV11) TableName() string { return "user_identities" }
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/dns"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// nonHTTPServices are service names port scanners report for ports that do
+// not speak HTTP, so probing them for a web page is pointless
+var nonHTTPServices = []string{
+	"ssh", "ftp", "telnet", "smtp", "pop3", "imap", "domain", "dns",
+	"microsoft-ds", "netbios", "smb", "msrpc", "ldap", "kerberos",
+	"mysql", "postgresql", "ms-sql", "oracle", "redis", "mongodb", "memcache",
+	"ms-wbt-server", "rdp", "vnc", "x11", "snmp", "ntp", "sip", "rtsp",
+}
+
+// nonHTTPPorts are well known ports of services that do not speak HTTP,
+// for ports without a service name
+var nonHTTPPorts = []int{
+	21, 22, 23, 25, 53, 110, 111, 135, 139, 143, 389, 445, 465, 587, 636,
+	993, 995, 1433, 1521, 3306, 3389, 5432, 5900, 6000, 6379, 11211, 27017,
+}
+
+// HTTPPorts returns the open TCP ports of query that may serve HTTP
+func HTTPPorts(query *gorm.DB) ([]models.IPPort, error) {
+	var ports []models.IPPort
+	if err := query.Where("state = ? AND protocol = ?", "open", "tcp").
+		Order("ip_address, port").Find(&ports).Error; err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(ports, func(port models.IPPort) bool {
+		return !probablyHTTP(port)
+	}), nil
+}
+
+// PortURLs returns candidate URLs for the open TCP ports that may serve
+// HTTP, over both http and https, on the port's IP and every hostname known
+// to resolve to it. Ports 80 and 443 are left out, as targets are probed on
+// them already.
+func PortURLs(db *gorm.DB) ([]string, error) {
+	ports, err := HTTPPorts(db.Where("port NOT IN ?", []int{80, 443}))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []models.DNSRecord
+	if err := db.Where("type IN ?", []string{dns.TypeA, dns.TypeAAAA}).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	hostnames := make(map[string][]string)
+	for _, record := range records {
+		hostnames[record.Value] = appendHost(hostnames[record.Value], record.Host)
+	}
+
+	seen := make(map[string]bool)
+	var urls []string
+	for _, port := range ports {
+		hosts := appendHost([]string{port.IPAddress}, port.OriginalHost)
+		for _, host := range hostnames[port.IPAddress] {
+			hosts = appendHost(hosts, host)
+		}
+
+		for _, host := range hosts {
+			address := net.JoinHostPort(host, strconv.Itoa(port.Port))
+			for _, scheme := range []string{"http", "https"} {
+				url := scheme + "://" + address
+				if !seen[url] {
+					seen[url] = true
+					urls = append(urls, url)
+				}
+			}
+		}
+	}
+
+	return urls, nil
+}
+
+// probablyHTTP checks if a port may serve HTTP, from its service name or,
+// without one, its port number
+func probablyHTTP(port models.IPPort) bool {
+	service := strings.ToLower(port.Service)
+	if strings.Contains(service, "http") {
+		return true
+	}
+	if service != "" && service != "unknown" {
+		return !slices.ContainsFunc(nonHTTPServices, func(name string) bool {
+			return strings.Contains(service, name)
+		})
+	}
+
+	return !slices.Contains(nonHTTPPorts, port.Port)
+}
+
+// appendHost adds a hostname to a list, unless it is empty or listed
+func appendHost(hosts []string, host string) []string {
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+	if host == "" || slices.Contains(hosts, host) {
+		return hosts
+	}
+
+	return append(hosts, host)
+}
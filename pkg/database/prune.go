@@ -0,0 +1,161 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/thumbnail"
+	"gorm.io/gorm"
+)
+
+// pruneBatchSize is the number of results deleted per query
+const pruneBatchSize = 500
+
+// ErrNoPruneFilter is returned when a prune is attempted without filters
+var ErrNoPruneFilter = errors.New("at least one prune filter is required")
+
+// PruneOptions are the filters used to select results to prune.
+// Filters are combined, so a result has to match all of them.
+type PruneOptions struct {
+	// OlderThan prunes results probed more than this long ago
+	OlderThan time.Duration
+	// FailedOnly prunes only failed results
+	FailedOnly bool
+	// ScanSessionID prunes only results from this scan session
+	ScanSessionID *uint
+	// ScreenshotPath is where screenshots are stored when a result's scan
+	// session does not record its own path. If set, the screenshot files
+	// of pruned results are removed from disk too.
+	ScreenshotPath string
+	// DryRun only counts what would be pruned
+	DryRun bool
+}
+
+// PruneSummary is a summary of a prune operation
+type PruneSummary struct {
+	Results     int64 `json:"results"`
+	Screenshots int64 `json:"screenshots"`
+}
+
+// hasFilter checks if at least one filter is set
+func (o *PruneOptions) hasFilter() bool {
+	return o.OlderThan > 0 || o.FailedOnly || o.ScanSessionID != nil
+}
+
+// query applies the prune filters to a results query
+func (o *PruneOptions) query(db *gorm.DB) *gorm.DB {
+	q := db.Model(&models.Result{})
+
+	if o.OlderThan > 0 {
+		q = q.Where("probed_at < ?", time.Now().Add(-o.OlderThan))
+	}
+	if o.FailedOnly {
+		q = q.Where("failed = ?", true)
+	}
+	if o.ScanSessionID != nil {
+		q = q.Where("scan_session_id = ?", *o.ScanSessionID)
+	}
+
+	return q
+}
+
+// Prune deletes results matching the options, together with their
+// associated data and, optionally, their screenshots on disk.
+func Prune(db *gorm.DB, opts PruneOptions) (*PruneSummary, error) {
+	if !opts.hasFilter() {
+		return nil, ErrNoPruneFilter
+	}
+
+	summary := &PruneSummary{}
+
+	if opts.DryRun {
+		if err := opts.query(db).Count(&summary.Results).Error; err != nil {
+			return nil, fmt.Errorf("failed to count results to prune: %w", err)
+		}
+
+		return summary, nil
+	}
+
+	// screenshot directories, keyed by scan session id. 0 is no session.
+	dirs := make(map[uint]string)
+
+	for {
+		var batch []models.Result
+		if err := opts.query(db).Select("id", "filename", "full_page_filename", "element_filename", "scan_session_id").Limit(pruneBatchSize).Find(&batch).Error; err != nil {
+			return summary, fmt.Errorf("failed to query results to prune: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		ids := make([]uint, 0, len(batch))
+		for _, result := range batch {
+			ids = append(ids, result.ID)
+		}
+
+		// associated data is removed by the OnDelete:CASCADE constraints
+		if err := db.Delete(&models.Result{}, ids).Error; err != nil {
+			return summary, fmt.Errorf("failed to delete results: %w", err)
+		}
+		summary.Results += int64(len(ids))
+
+		if opts.ScreenshotPath == "" {
+			continue
+		}
+
+		for _, result := range batch {
+			if result.Filename == "" && result.FullPageFilename == "" && result.ElementFilename == "" {
+				continue
+			}
+
+			var key uint
+			if result.ScanSessionID != nil {
+				key = *result.ScanSessionID
+			}
+			dir, ok := dirs[key]
+			if !ok {
+				dir = ScreenshotPath(db, result.ScanSessionID, opts.ScreenshotPath)
+				dirs[key] = dir
+			}
+
+			for _, filename := range []string{result.Filename, result.FullPageFilename, result.ElementFilename} {
+				removed, err := removeScreenshot(dir, filename)
+				if err != nil {
+					return summary, err
+				}
+				if removed {
+					summary.Screenshots++
+				}
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// removeScreenshot removes a screenshot file in dir and its thumbnails,
+// reporting whether the file was there
+func removeScreenshot(dir string, filename string) (bool, error) {
+	if filename == "" {
+		return false, nil
+	}
+
+	if _, err := thumbnail.Remove(dir, filename); err != nil {
+		return false, fmt.Errorf("failed to remove thumbnails of %s: %w", filename, err)
+	}
+
+	// thumbnail.Clean keeps us inside the screenshot path
+	file := filepath.Join(dir, filepath.FromSlash(thumbnail.Clean(filename)))
+	if err := os.Remove(file); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to remove screenshot %s: %w", file, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/thumbnail"
+	"gorm.io/gorm"
+)
+
+// testDatabase creates a SQLite database in a temporary directory
+func testDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	// the database log is written to the working directory
+	t.Chdir(t.TempDir())
+
+	db, err := Connection("sqlite://"+filepath.Join(t.TempDir(), "gowitness.sqlite3"), false, false)
+	if err != nil {
+		t.Fatalf("Connection() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return db
+}
+
+func TestPruneScreenshots(t *testing.T) {
+	db := testDatabase(t)
+	dir := t.TempDir()
+
+	old := models.Result{
+		URL:              "https://old.example.com",
+		ProbedAt:         time.Now().Add(-48 * time.Hour),
+		Filename:         "https-old.example.com.png",
+		FullPageFilename: "https-old.example.com-full.png",
+		ElementFilename:  "https-old.example.com-element.png",
+	}
+	recent := models.Result{
+		URL:      "https://recent.example.com",
+		ProbedAt: time.Now(),
+		Filename: "https-recent.example.com.png",
+	}
+	if err := db.Create(&[]*models.Result{&old, &recent}).Error; err != nil {
+		t.Fatalf("failed to create results: %v", err)
+	}
+
+	files := []string{old.Filename, old.FullPageFilename, old.ElementFilename, recent.Filename}
+	for _, file := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte("png"), 0o644); err != nil {
+			t.Fatalf("failed to write screenshot: %v", err)
+		}
+	}
+	for _, file := range []string{old.Filename, old.FullPageFilename} {
+		if _, err := thumbnail.Write(dir, file, image.NewRGBA(image.Rect(0, 0, 8, 8)), thumbnail.DefaultWidth); err != nil {
+			t.Fatalf("thumbnail.Write() error = %v", err)
+		}
+	}
+
+	summary, err := Prune(db, PruneOptions{OlderThan: 24 * time.Hour, ScreenshotPath: dir})
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if summary.Results != 1 || summary.Screenshots != 3 {
+		t.Errorf("Prune() = %+v, want 1 result and 3 screenshots", summary)
+	}
+
+	removed := []string{
+		filepath.Join(dir, old.Filename),
+		filepath.Join(dir, old.FullPageFilename),
+		filepath.Join(dir, old.ElementFilename),
+		thumbnail.Path(dir, old.Filename, thumbnail.DefaultWidth),
+		thumbnail.Path(dir, old.FullPageFilename, thumbnail.DefaultWidth),
+	}
+	for _, file := range removed {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("%s of the pruned result was not removed", file)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, recent.Filename)); err != nil {
+		t.Errorf("screenshot of the kept result was removed: %v", err)
+	}
+}
+
+func TestPruneAssociatedData(t *testing.T) {
+	db := testDatabase(t)
+
+	// foreign keys are set per connection, so make every query use a new one
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db.DB() error = %v", err)
+	}
+	sqlDB.SetMaxIdleConns(0)
+
+	result := models.Result{
+		URL:          "https://example.com",
+		Failed:       true,
+		TLS:          models.TLS{Protocol: "TLS 1.3"},
+		Technologies: []models.Technology{{Value: "Nginx"}},
+		Headers:      []models.Header{{Key: "Server", Value: "nginx"}},
+		Network:      []models.NetworkLog{{URL: "https://example.com/app.js"}},
+		Console:      []models.ConsoleLog{{Type: "console.log", Value: "hello"}},
+		Cookies:      []models.Cookie{{Name: "session", Value: "secret"}},
+		Tags:         []models.ResultTag{{Name: "login"}},
+	}
+	if err := db.Create(&result).Error; err != nil {
+		t.Fatalf("failed to create result: %v", err)
+	}
+
+	if _, err := Prune(db, PruneOptions{FailedOnly: true}); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+
+	for _, model := range []any{&models.TLS{}, &models.Technology{}, &models.Header{}, &models.NetworkLog{},
+		&models.ConsoleLog{}, &models.Cookie{}, &models.ResultTag{}} {
+		var count int64
+		if err := db.Model(model).Count(&count).Error; err != nil {
+			t.Fatalf("failed to count %T: %v", model, err)
+		}
+		if count != 0 {
+			t.Errorf("%d %T rows of the pruned result are left", count, model)
+		}
+	}
+}
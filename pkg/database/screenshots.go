@@ -0,0 +1,26 @@
+package database
+
+import (
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// ScreenshotPath resolves the directory the screenshots of a scan session
+// are stored in. If there is no session, or the session does not record a
+// path, fallback is returned.
+func ScreenshotPath(db *gorm.DB, scanSessionID *uint, fallback string) string {
+	if scanSessionID == nil {
+		return fallback
+	}
+
+	var session models.ScanSession
+	if err := db.Select("screenshot_path").First(&session, *scanSessionID).Error; err != nil {
+		return fallback
+	}
+
+	if session.ScreenshotPath == "" {
+		return fallback
+	}
+
+	return session.ScreenshotPath
+}
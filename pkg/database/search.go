@@ -0,0 +1,231 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// searchBatchSize is the number of results indexed per query
+const searchBatchSize = 200
+
+// maxSearchContent is the maximum length of a search document. Postgres
+// can't build a tsvector from much more, and it keeps the index sane.
+const maxSearchContent = 512 * 1024
+
+// setupSearchIndex creates the full-text index over search documents.
+//
+// SQLite gets an FTS5 table kept in sync with triggers, and Postgres gets a
+// GIN index on a tsvector. Other databases fall back to LIKE queries.
+func setupSearchIndex(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "sqlite":
+		statements := []string{
+			`CREATE VIRTUAL TABLE IF NOT EXISTS search_documents_fts
+				USING fts5(content, content='search_documents', content_rowid='id')`,
+			`CREATE TRIGGER IF NOT EXISTS search_documents_ai AFTER INSERT ON search_documents BEGIN
+				INSERT INTO search_documents_fts(rowid, content) VALUES (new.id, new.content);
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS search_documents_ad AFTER DELETE ON search_documents BEGIN
+				INSERT INTO search_documents_fts(search_documents_fts, rowid, content) VALUES ('delete', old.id, old.content);
+			END`,
+		}
+
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to setup sqlite full-text search: %w", err)
+			}
+		}
+	case "postgres":
+		if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_search_documents_tsv
+			ON search_documents USING GIN (to_tsvector('simple', content))`).Error; err != nil {
+			return fmt.Errorf("failed to setup postgres full-text search: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// searchDocument returns a search document, truncating the content if needed
+func searchDocument(field, name, content string) models.SearchDocument {
+	if len(content) > maxSearchContent {
+		content = strings.ToValidUTF8(content[:maxSearchContent], "")
+	}
+
+	return models.SearchDocument{
+		Field:   field,
+		Name:    name,
+		Content: content,
+	}
+}
+
+// resultDocuments returns the search documents for a result. Every result
+// gets at least a url document, which is how indexed results are tracked.
+func resultDocuments(db *gorm.DB, result *models.Result) []models.SearchDocument {
+	docs := []models.SearchDocument{searchDocument("url", "", result.URL)}
+
+	if result.Title != "" {
+		docs = append(docs, searchDocument("title", "", result.Title))
+	}
+	// response bodies of encrypted databases are not indexed, as the index
+	// would hold them in plain text
+	if result.HTML != "" && !models.EncryptionEnabled(db) {
+		docs = append(docs, searchDocument("body", "", result.HTML))
+	}
+	for _, header := range result.Headers {
+		// neither are the credential headers encrypted with them
+		if models.IsCredentialHeader(header.Key) && models.EncryptionEnabled(db) {
+			continue
+		}
+		docs = append(docs, searchDocument("header", strings.ToLower(header.Key), header.Value))
+	}
+	for _, console := range result.Console {
+		docs = append(docs, searchDocument("console", console.Type, console.Value))
+	}
+
+	for i := range docs {
+		docs[i].ResultID = result.ID
+		docs[i].SourceUpdatedAt = result.UpdatedAt
+	}
+
+	return docs
+}
+
+// indexedUpTo returns the id of the newest row indexed and the newest time
+// an indexed row was written, of the rows column refers to
+func indexedUpTo(db *gorm.DB, column string) (uint, time.Time, error) {
+	var last uint
+	if err := db.Model(&models.SearchDocument{}).Select("COALESCE(MAX(" + column + "), 0)").
+		Scan(&last).Error; err != nil {
+		return 0, time.Time{}, err
+	}
+
+	var newest models.SearchDocument
+	if err := db.Select("source_updated_at").Where(column + " != 0").
+		Order("source_updated_at DESC").Limit(1).Find(&newest).Error; err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return last, newest.SourceUpdatedAt, nil
+}
+
+// RefreshSearchIndex indexes results and port banners that have not been
+// indexed yet, indexes those written since they were indexed again, and
+// removes documents of deleted results.
+func RefreshSearchIndex(db *gorm.DB) error {
+	// remove documents of results that no longer exist
+	if err := db.Where("result_id != 0 AND result_id NOT IN (?)", db.Model(&models.Result{}).Select("id")).
+		Delete(&models.SearchDocument{}).Error; err != nil {
+		return fmt.Errorf("failed to remove stale search documents: %w", err)
+	}
+
+	lastResult, resultsUpdated, err := indexedUpTo(db, "result_id")
+	if err != nil {
+		return fmt.Errorf("failed to get the last indexed result: %w", err)
+	}
+
+	// results are walked by id, so that those written since they were
+	// indexed are found along with the new ones
+	var cursor uint
+	for {
+		var batch []models.Result
+		if err := db.Select("id", "url", "title", "html", "updated_at").
+			Preload("Headers").Preload("Console").
+			Where("id > ?", cursor).
+			Where("id > ? OR updated_at > ?", lastResult, resultsUpdated).
+			Order("id").Limit(searchBatchSize).Find(&batch).Error; err != nil {
+			return fmt.Errorf("failed to query results to index: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		var ids []uint
+		var docs []models.SearchDocument
+		for i := range batch {
+			ids = append(ids, batch[i].ID)
+			docs = append(docs, resultDocuments(db, &batch[i])...)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("result_id IN ?", ids).Delete(&models.SearchDocument{}).Error; err != nil {
+				return err
+			}
+			return tx.CreateInBatches(&docs, 100).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write search documents: %w", err)
+		}
+
+		cursor = batch[len(batch)-1].ID
+	}
+
+	lastPort, portsUpdated, err := indexedUpTo(db, "ip_port_id")
+	if err != nil {
+		return fmt.Errorf("failed to get the last indexed port: %w", err)
+	}
+
+	var ports []models.IPPort
+	if err := db.Where("(id > ? AND banner != '') OR updated_at > ?", lastPort, portsUpdated).
+		Order("id").Find(&ports).Error; err != nil {
+		return fmt.Errorf("failed to query ports to index: %w", err)
+	}
+
+	if len(ports) > 0 {
+		ids := make([]uint, 0, len(ports))
+		docs := make([]models.SearchDocument, 0, len(ports))
+		for _, port := range ports {
+			ids = append(ids, port.ID)
+			// ports whose banner was removed only lose their document
+			if port.Banner == "" {
+				continue
+			}
+			doc := searchDocument("banner", port.IPAddress, port.Banner)
+			doc.IPPortID = port.ID
+			doc.SourceUpdatedAt = port.UpdatedAt
+			docs = append(docs, doc)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for start := 0; start < len(ids); start += searchBatchSize {
+				end := min(start+searchBatchSize, len(ids))
+				if err := tx.Where("ip_port_id IN ?", ids[start:end]).Delete(&models.SearchDocument{}).Error; err != nil {
+					return err
+				}
+			}
+			if len(docs) == 0 {
+				return nil
+			}
+			return tx.CreateInBatches(&docs, 100).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write banner search documents: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SearchDocuments returns a query for the ids of search documents with
+// content matching text, using the full-text index where available
+func SearchDocuments(db *gorm.DB, text string) *gorm.DB {
+	q := db.Model(&models.SearchDocument{}).Select("id")
+
+	switch db.Dialector.Name() {
+	case "sqlite":
+		if !db.Migrator().HasTable("search_documents_fts") {
+			break
+		}
+
+		// a quoted fts5 phrase, matching as a prefix
+		phrase := `"` + strings.ReplaceAll(text, `"`, `""`) + `"*`
+		return q.Where("id IN (SELECT rowid FROM search_documents_fts WHERE search_documents_fts MATCH ?)", phrase)
+	case "postgres":
+		return q.Where("to_tsvector('simple', content) @@ phraseto_tsquery('simple', ?)", text)
+	}
+
+	return q.Where("LOWER(content) LIKE ?", "%"+strings.ToLower(text)+"%")
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"github.com/sensepost/gowitness/pkg/headers"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// backfillSecurityScores grades the security headers of results stored
+// before they were graded
+func backfillSecurityScores(db *gorm.DB) error {
+	var results []models.Result
+	return db.Select("id", "url", "final_url").Where("security_grade = '' OR security_grade IS NULL").
+		Preload("Headers").Preload("Cookies").
+		FindInBatches(&results, 500, func(tx *gorm.DB, _ int) error {
+			for i := range results {
+				security := headers.Analyze(&results[i])
+				for j := range security.Issues {
+					security.Issues[j].ResultID = results[i].ID
+				}
+
+				if len(security.Issues) > 0 {
+					if err := db.Create(&security.Issues).Error; err != nil {
+						return err
+					}
+				}
+				if err := db.Model(&models.Result{}).Where("id = ?", results[i].ID).
+					UpdateColumns(map[string]interface{}{
+						"security_score": security.Score,
+						"security_grade": security.Grade,
+					}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// ErrNoScanSessions is returned when sessions are assigned in a database
+// without scan sessions
+var ErrNoScanSessions = errors.New("the database has no scan sessions")
+
+// AssignOptions are options used when assigning rows to scan sessions
+type AssignOptions struct {
+	// Slack widens each session's time window on both sides, to catch rows
+	// written just before a session was created or just after it ended
+	Slack time.Duration
+	// DryRun only counts what would be assigned
+	DryRun bool
+}
+
+// AssignSummary is a summary of rows assigned to scan sessions
+type AssignSummary struct {
+	Results   int64 `json:"results"`
+	IPPorts   int64 `json:"ip_ports"`
+	IPInfo    int64 `json:"ip_info"`
+	Domains   int64 `json:"domains"`
+	Remaining int64 `json:"remaining"` // rows that matched no session
+}
+
+// sessionTables are the tables with a scan session id, and the column that
+// records when a row was created
+var sessionTables = []struct {
+	model  interface{}
+	column string
+}{
+	{&models.Result{}, "probed_at"},
+	{&models.IPPort{}, "discovered_at"},
+	{&models.IPInfo{}, "last_update"},
+	{&models.Domain{}, "first_seen"},
+}
+
+// sessionWindow is the time range rows of a scan session were written in
+type sessionWindow struct {
+	id    uint
+	start time.Time
+	end   *time.Time // nil is open ended
+}
+
+// AssignSessions back-fills the scan session of rows written without one.
+//
+// A database holding a single scan session is treated as a project
+// database, and every row is assigned to that session. Otherwise rows are
+// assigned to the session whose time window contains their timestamp. A
+// session's window runs from its start to its end, or to the start of the
+// next session if it never ended. Rows outside of all windows are left as
+// is.
+func AssignSessions(db *gorm.DB, opts AssignOptions) (*AssignSummary, error) {
+	var sessions []models.ScanSession
+	if err := db.Order("start_time, id").Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get scan sessions: %w", err)
+	}
+	if len(sessions) == 0 {
+		return nil, ErrNoScanSessions
+	}
+
+	windows := make([]sessionWindow, 0, len(sessions))
+	for i, session := range sessions {
+		window := sessionWindow{id: session.ID, start: session.StartTime.Add(-opts.Slack)}
+
+		switch {
+		case len(sessions) == 1:
+			// project database, everything belongs to the one session
+			window.start = time.Time{}
+		case session.EndTime != nil:
+			end := session.EndTime.Add(opts.Slack)
+			window.end = &end
+		case i+1 < len(sessions):
+			end := sessions[i+1].StartTime
+			window.end = &end
+		}
+
+		windows = append(windows, window)
+	}
+
+	summary := &AssignSummary{}
+	counts := []*int64{&summary.Results, &summary.IPPorts, &summary.IPInfo, &summary.Domains}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i, table := range sessionTables {
+			// assigned rows drop out of the scan_session_id IS NULL filter,
+			// so in a dry run rows matching several windows would be
+			// counted more than once. count per row instead.
+			if opts.DryRun {
+				var total, unmatched int64
+				if err := tx.Model(table.model).Where("scan_session_id IS NULL").Count(&total).Error; err != nil {
+					return fmt.Errorf("failed to count unassigned rows: %w", err)
+				}
+				q := tx.Model(table.model).Where("scan_session_id IS NULL")
+				for _, window := range windows {
+					q = window.exclude(q, table.column)
+				}
+				if err := q.Count(&unmatched).Error; err != nil {
+					return fmt.Errorf("failed to count unassigned rows: %w", err)
+				}
+
+				*counts[i] = total - unmatched
+				summary.Remaining += unmatched
+				continue
+			}
+
+			for _, window := range windows {
+				q := window.include(tx.Model(table.model).Where("scan_session_id IS NULL"), table.column)
+				result := q.UpdateColumn("scan_session_id", window.id)
+				if result.Error != nil {
+					return fmt.Errorf("failed to assign scan session %d: %w", window.id, result.Error)
+				}
+				*counts[i] += result.RowsAffected
+			}
+
+			var unmatched int64
+			if err := tx.Model(table.model).Where("scan_session_id IS NULL").Count(&unmatched).Error; err != nil {
+				return fmt.Errorf("failed to count unassigned rows: %w", err)
+			}
+			summary.Remaining += unmatched
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// include filters a query to rows inside the window
+func (w sessionWindow) include(q *gorm.DB, column string) *gorm.DB {
+	if !w.start.IsZero() {
+		q = q.Where(column+" >= ?", w.start)
+	}
+	if w.end != nil {
+		q = q.Where(column+" < ?", *w.end)
+	}
+
+	return q
+}
+
+// exclude filters a query to rows outside the window
+func (w sessionWindow) exclude(q *gorm.DB, column string) *gorm.DB {
+	switch {
+	case w.start.IsZero() && w.end == nil:
+		return q.Where("1 = 0")
+	case w.start.IsZero():
+		return q.Where(column+" >= ?", *w.end)
+	case w.end == nil:
+		return q.Where(column+" < ?", w.start)
+	}
+
+	return q.Where("("+column+" < ? OR "+column+" >= ?)", w.start, *w.end)
+}
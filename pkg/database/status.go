@@ -0,0 +1,127 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Scan session statuses
+const (
+	SessionActive    = "active"
+	SessionCompleted = "completed"
+	SessionFailed    = "failed"
+	SessionCancelled = "cancelled"
+)
+
+// DefaultStaleAfter is how long an active scan session may go without
+// writing anything before it is considered stale
+const DefaultStaleAfter = 24 * time.Hour
+
+// LatestActiveSession returns the most recently started active scan
+// session, or nil if there is none
+func LatestActiveSession(db *gorm.DB) (*models.ScanSession, error) {
+	var sessions []models.ScanSession
+	if err := db.Where("status = ?", SessionActive).Order("start_time DESC").Limit(1).Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get active scan session: %w", err)
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+
+	return &sessions[0], nil
+}
+
+// ScopeSession returns a scan session with its apex domains, or the latest
+// active session if id is 0. It returns nil if there is no active session.
+func ScopeSession(db *gorm.DB, id uint) (*models.ScanSession, error) {
+	if id > 0 {
+		var session models.ScanSession
+		if err := db.Preload("ApexDomains").First(&session, id).Error; err != nil {
+			return nil, fmt.Errorf("failed to get scan session %d: %w", id, err)
+		}
+		return &session, nil
+	}
+
+	session, err := LatestActiveSession(db)
+	if err != nil || session == nil {
+		return nil, err
+	}
+	if err := db.Model(session).Association("ApexDomains").Find(&session.ApexDomains); err != nil {
+		return nil, fmt.Errorf("failed to get apex domains of scan session %d: %w", session.ID, err)
+	}
+
+	return session, nil
+}
+
+// CompleteSession marks a scan session as completed, ending it now
+func CompleteSession(db *gorm.DB, id uint) error {
+	return db.Model(&models.ScanSession{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":         SessionCompleted,
+		"end_time":       time.Now(),
+		"failed_phase":   "",
+		"failure_reason": "",
+	}).Error
+}
+
+// FailSession marks a scan session as failed, recording the phase that
+// failed and why
+func FailSession(db *gorm.DB, id uint, phase string, reason string) error {
+	return db.Model(&models.ScanSession{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":         SessionFailed,
+		"end_time":       time.Now(),
+		"failed_phase":   phase,
+		"failure_reason": reason,
+	}).Error
+}
+
+// CancelSession marks a scan session as cancelled, recording the phase
+// that was interrupted
+func CancelSession(db *gorm.DB, id uint, phase string) error {
+	return db.Model(&models.ScanSession{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":         SessionCancelled,
+		"end_time":       time.Now(),
+		"failed_phase":   phase,
+		"failure_reason": "interrupted",
+	}).Error
+}
+
+// ReactivateSession marks a cancelled or failed scan session as active
+// again, for when its interrupted phase is resumed
+func ReactivateSession(db *gorm.DB, id uint) error {
+	return db.Model(&models.ScanSession{}).
+		Where("id = ? AND status IN ?", id, []string{SessionCancelled, SessionFailed}).
+		Updates(map[string]interface{}{
+			"status":         SessionActive,
+			"end_time":       nil,
+			"failed_phase":   "",
+			"failure_reason": "",
+		}).Error
+}
+
+// LastActivity returns when a scan session last wrote a row, or its start
+// time if it never did
+func LastActivity(db *gorm.DB, session *models.ScanSession) (time.Time, error) {
+	last := session.StartTime
+
+	for _, table := range sessionTables {
+		var times []time.Time
+		if err := db.Model(table.model).Where("scan_session_id = ?", session.ID).
+			Order(table.column+" DESC").Limit(1).Pluck(table.column, &times).Error; err != nil {
+			return last, fmt.Errorf("failed to get last activity of scan session %d: %w", session.ID, err)
+		}
+		if len(times) > 0 && times[0].After(last) {
+			last = times[0]
+		}
+	}
+
+	return last, nil
+}
+
+// IsStale checks if a scan session is still active but has not written
+// anything for longer than staleAfter, which usually means its scan died
+func IsStale(session *models.ScanSession, lastActivity time.Time, staleAfter time.Duration) bool {
+	return session.Status == SessionActive && time.Since(lastActivity) > staleAfter
+}
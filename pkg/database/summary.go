@@ -0,0 +1,107 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// summaryBatchSize is the number of results summarised per query
+const summaryBatchSize = 1000
+
+// resultHost derives the host summary of a result
+func resultHost(result *models.Result) models.ResultHost {
+	host := models.ResultHost{
+		ResultID:  result.ID,
+		URL:       result.URL,
+		IPAddress: result.IPAddress,
+		ProbedAt:  result.ProbedAt,
+	}
+
+	parsedURL, err := url.Parse(result.URL)
+	if err != nil {
+		return host
+	}
+
+	host.Hostname = parsedURL.Hostname()
+	host.ApexDomain = islazy.ApexDomain(host.Hostname)
+	host.Protocol = parsedURL.Scheme
+	host.Port = parsedURL.Port()
+	if host.Port == "" {
+		// Set default ports for common schemes
+		switch host.Protocol {
+		case "http":
+			host.Port = "80"
+		case "https":
+			host.Port = "443"
+		default:
+			host.Port = "unknown"
+		}
+	}
+
+	return host
+}
+
+// RefreshResultHosts brings the result host summary table up to date with
+// the results table. Only rows for new or deleted results are touched, so
+// this is cheap to call when nothing changed.
+func RefreshResultHosts(db *gorm.DB) error {
+	var results, hosts int64
+	var maxResult, maxHost uint
+
+	if err := db.Model(&models.Result{}).Count(&results).Error; err != nil {
+		return fmt.Errorf("failed to count results: %w", err)
+	}
+	if err := db.Model(&models.ResultHost{}).Count(&hosts).Error; err != nil {
+		return fmt.Errorf("failed to count result hosts: %w", err)
+	}
+	if err := db.Model(&models.Result{}).Select("COALESCE(MAX(id), 0)").Scan(&maxResult).Error; err != nil {
+		return fmt.Errorf("failed to get the latest result: %w", err)
+	}
+	if err := db.Model(&models.ResultHost{}).Select("COALESCE(MAX(result_id), 0)").Scan(&maxHost).Error; err != nil {
+		return fmt.Errorf("failed to get the latest result host: %w", err)
+	}
+
+	if results == hosts && maxResult == maxHost {
+		return nil
+	}
+
+	// remove summaries of results that no longer exist
+	if err := db.Where("result_id NOT IN (?)", db.Model(&models.Result{}).Select("id")).
+		Delete(&models.ResultHost{}).Error; err != nil {
+		return fmt.Errorf("failed to remove stale result hosts: %w", err)
+	}
+
+	// summarise results that don't have a row yet
+	var lastID uint
+	for {
+		var batch []models.Result
+		if err := db.Select("id", "url", "ip_address", "probed_at").
+			Where("id > ?", lastID).
+			Where("id NOT IN (?)", db.Model(&models.ResultHost{}).Select("result_id")).
+			Order("id").Limit(summaryBatchSize).Find(&batch).Error; err != nil {
+			return fmt.Errorf("failed to query results to summarise: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		rows := make([]models.ResultHost, 0, len(batch))
+		for i := range batch {
+			rows = append(rows, resultHost(&batch[i]))
+		}
+
+		// concurrent refreshes may race us to some rows, which is fine
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+			return fmt.Errorf("failed to write result hosts: %w", err)
+		}
+
+		lastID = batch[len(batch)-1].ID
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// SyncTags adds the tags results were tagged with, by plugins or the
+// classifier, that are missing from the tag list
+func SyncTags(db *gorm.DB) error {
+	var names []string
+	if err := db.Model(&models.ResultTag{}).Distinct("name").
+		Where("name NOT IN (?)", db.Model(&models.Tag{}).Select("name")).
+		Pluck("name", &names).Error; err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, models.Tag{Name: name})
+	}
+
+	return db.Create(&tags).Error
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// backfillTechnologyVersions splits the name and version out of technology
+// values stored before they were recorded separately
+func backfillTechnologyVersions(db *gorm.DB) error {
+	var technologies []models.Technology
+	return db.Select("id", "value").Where("name = '' OR name IS NULL").
+		FindInBatches(&technologies, 500, func(tx *gorm.DB, _ int) error {
+			for _, technology := range technologies {
+				name, version, _ := strings.Cut(technology.Value, ":")
+				if err := db.Model(&models.Technology{}).Where("id = ?", technology.ID).
+					Updates(map[string]interface{}{"name": name, "version": version}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
@@ -0,0 +1,257 @@
+// Package discovery checks which IP addresses are alive before they are
+// port scanned, so that full port scans of large ranges skip the addresses
+// nothing answers on. A host is alive if it answers an ICMP echo request,
+// or if a TCP connection to one of a few common ports is accepted or reset.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// DefaultPorts are the TCP ports probed for hosts that do not answer ICMP
+var DefaultPorts = []int{80, 443, 22, 445, 3389}
+
+// Options are the options of host discovery
+type Options struct {
+	// Ports are the TCP ports probed, in addition to ICMP
+	Ports []int
+	// ICMP sends an ICMP echo request too. It needs unprivileged ping
+	// sockets, or raw socket privileges, and is skipped without either.
+	ICMP bool
+	// Timeout is how long to wait for a host to answer
+	Timeout time.Duration
+	// Threads is how many hosts are probed at once
+	Threads int
+}
+
+// Host is the outcome of probing an IP address
+type Host struct {
+	IP     string
+	Alive  bool
+	Method string // how the host answered, e.g. icmp or tcp/443
+}
+
+// Expand splits targets into the IP addresses they cover, with IPv4 ranges
+// in CIDR notation expanded, and the targets that are not addresses, such
+// as hostnames and IPv6 ranges, which can not be probed up front
+func Expand(targets []string) (ips []string, other []string, err error) {
+	for _, target := range targets {
+		if ip := net.ParseIP(target); ip != nil {
+			ips = append(ips, target)
+			continue
+		}
+
+		if ip, _, err := net.ParseCIDR(target); err == nil && ip.To4() != nil {
+			expanded, err := islazy.IpsInCIDR(target)
+			if err != nil {
+				return nil, nil, err
+			}
+			ips = append(ips, expanded...)
+			continue
+		}
+
+		other = append(other, target)
+	}
+
+	return ips, other, nil
+}
+
+// Discover probes ips, calling fn with the outcome of every one of them.
+// fn is not called concurrently.
+func Discover(ctx context.Context, ips []string, opts Options, fn func(Host)) {
+	threads := max(opts.Threads, 1)
+
+	jobs := make(chan string)
+	results := make(chan Host)
+
+	var wg sync.WaitGroup
+	for range threads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range jobs {
+				results <- Probe(ctx, ip, opts)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, ip := range ips {
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- ip:
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for host := range results {
+		fn(host)
+	}
+}
+
+// Probe sends an ICMP echo request and tries the TCP ports of an IP
+// address at once, returning as soon as one of them is answered
+func Probe(ctx context.Context, ip string, opts Options) Host {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	answered := make(chan string, len(opts.Ports)+1)
+	var wg sync.WaitGroup
+
+	if opts.ICMP {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ping(ctx, ip) {
+				answered <- "icmp"
+			}
+		}()
+	}
+
+	for _, port := range opts.Ports {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if connect(ctx, ip, port) {
+				answered <- "tcp/" + strconv.Itoa(port)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(answered)
+	}()
+
+	host := Host{IP: ip}
+	if method, ok := <-answered; ok {
+		host.Alive = true
+		host.Method = method
+	}
+
+	return host
+}
+
+// connect checks if a TCP port of ip answers. A refused connection means
+// the host is up, it just has nothing listening on the port.
+func connect(ctx context.Context, ip string, port int) bool {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err == nil {
+		conn.Close()
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// ping sends an ICMP echo request to an IPv4 address and waits for its
+// reply. Unprivileged ping sockets are tried before raw sockets.
+func ping(ctx context.Context, ip string) bool {
+	dst := net.ParseIP(ip).To4()
+	if dst == nil {
+		return false
+	}
+
+	network, conn := "udp4", listen("udp4")
+	if conn == nil {
+		network, conn = "ip4:icmp", listen("ip4:icmp")
+	}
+	if conn == nil {
+		return false
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	go func() {
+		// unblock the read below when ctx is cancelled early
+		<-ctx.Done()
+		conn.SetDeadline(time.Now())
+	}()
+
+	id := os.Getpid() & 0xffff
+	request := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{ID: id, Seq: 1, Data: []byte("gowitness")},
+	}
+	data, err := request.Marshal(nil)
+	if err != nil {
+		return false
+	}
+
+	var addr net.Addr = &net.IPAddr{IP: dst}
+	if network == "udp4" {
+		addr = &net.UDPAddr{IP: dst}
+	}
+	if _, err := conn.WriteTo(data, addr); err != nil {
+		return false
+	}
+
+	reply := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(reply)
+		if err != nil {
+			return false
+		}
+
+		// raw sockets receive every ICMP message of the host
+		if !strings.EqualFold(peerIP(peer), dst.String()) {
+			continue
+		}
+
+		message, err := icmp.ParseMessage(1, reply[:n])
+		if err != nil {
+			continue
+		}
+		if message.Type == ipv4.ICMPTypeEchoReply {
+			return true
+		}
+	}
+}
+
+// listen opens an ICMP socket, or returns nil if this process may not
+func listen(network string) *icmp.PacketConn {
+	conn, err := icmp.ListenPacket(network, "0.0.0.0")
+	if err != nil {
+		return nil
+	}
+
+	return conn
+}
+
+// peerIP returns the IP address of the sender of an ICMP message
+func peerIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP.String()
+	case *net.IPAddr:
+		return a.IP.String()
+	}
+
+	return ""
+}
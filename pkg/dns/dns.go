@@ -0,0 +1,278 @@
+// Package dns resolves hostnames concurrently, with caching, retries and
+// support for custom resolvers.
+package dns
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Record types
+const (
+	TypeA     = "A"
+	TypeAAAA  = "AAAA"
+	TypeCNAME = "CNAME"
+)
+
+// Record is a DNS record of a host
+type Record struct {
+	Host  string
+	Type  string
+	Value string
+}
+
+// Answer is the outcome of resolving a host
+type Answer struct {
+	Host    string
+	Records []Record
+	// Err is set if the host could not be resolved
+	Err error
+}
+
+// Dangling checks if the host is a CNAME to a target that does not exist
+func (a *Answer) Dangling() bool {
+	if len(a.values(TypeCNAME)) == 0 || len(a.IPs()) > 0 {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	return errors.As(a.Err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// IPv4 returns the A record values of the answer
+func (a *Answer) IPv4() []string {
+	return a.values(TypeA)
+}
+
+// IPs returns the A and AAAA record values of the answer
+func (a *Answer) IPs() []string {
+	return append(a.values(TypeA), a.values(TypeAAAA)...)
+}
+
+// values returns the values of records of a type
+func (a *Answer) values(recordType string) []string {
+	var values []string
+	for _, record := range a.Records {
+		if record.Type == recordType {
+			values = append(values, record.Value)
+		}
+	}
+
+	return values
+}
+
+// Resolver resolves hostnames, caching every answer for its lifetime
+type Resolver struct {
+	// Servers are the resolvers queried, as host:port. Queries are spread
+	// over them round robin. Empty uses the system resolver.
+	Servers []string
+	// Concurrency is how many hosts ResolveAll resolves at once
+	Concurrency int
+	// Retries is how often a lookup that timed out or failed temporarily
+	// is retried
+	Retries int
+	// Timeout is the timeout of a single lookup
+	Timeout time.Duration
+
+	resolver *net.Resolver
+	next     atomic.Uint64
+
+	mu    sync.Mutex
+	cache map[string]*cacheEntry
+}
+
+// cacheEntry is a cached answer. done is closed once the answer is set, so
+// that concurrent lookups of the same host wait for the first one.
+type cacheEntry struct {
+	answer *Answer
+	done   chan struct{}
+}
+
+// NewResolver returns a resolver querying servers, or the system resolver
+// if there are none
+func NewResolver(servers []string) *Resolver {
+	r := &Resolver{
+		Servers:     servers,
+		Concurrency: 20,
+		Retries:     2,
+		Timeout:     5 * time.Second,
+		cache:       make(map[string]*cacheEntry),
+	}
+
+	r.resolver = net.DefaultResolver
+	if len(servers) > 0 {
+		r.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				server := r.Servers[r.next.Add(1)%uint64(len(r.Servers))]
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	return r
+}
+
+// LoadServers reads resolvers from a file, one per line. Lines starting
+// with # are ignored, and port 53 is used for resolvers without a port.
+func LoadServers(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(line); err != nil {
+			line = net.JoinHostPort(strings.Trim(line, "[]"), "53")
+		}
+		servers = append(servers, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no resolvers found in %s", path)
+	}
+
+	return servers, nil
+}
+
+// Resolve looks up the A, AAAA and CNAME records of a host. Answers are
+// cached, including failures, so a host is only ever looked up once.
+func (r *Resolver) Resolve(host string) *Answer {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+
+	r.mu.Lock()
+	entry, ok := r.cache[host]
+	if !ok {
+		entry = &cacheEntry{done: make(chan struct{})}
+		r.cache[host] = entry
+	}
+	r.mu.Unlock()
+
+	if ok {
+		<-entry.done
+		return entry.answer
+	}
+
+	entry.answer = r.lookup(host)
+	close(entry.done)
+
+	return entry.answer
+}
+
+// ResolveAll resolves hosts concurrently, returning their answers in the
+// same order
+func (r *Resolver) ResolveAll(hosts []string) []*Answer {
+	answers := make([]*Answer, len(hosts))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < max(1, r.Concurrency); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				answers[i] = r.Resolve(hosts[i])
+			}
+		}()
+	}
+
+	for i := range hosts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return answers
+}
+
+// lookup queries the records of a host
+func (r *Resolver) lookup(host string) *Answer {
+	answer := &Answer{Host: host}
+
+	if ip := net.ParseIP(host); ip != nil {
+		recordType := TypeA
+		if ip.To4() == nil {
+			recordType = TypeAAAA
+		}
+		answer.Records = []Record{{Host: host, Type: recordType, Value: ip.String()}}
+		return answer
+	}
+
+	cname, err := r.retry(func(ctx context.Context) (any, error) {
+		return r.resolver.LookupCNAME(ctx, host)
+	})
+	if err == nil {
+		target := strings.TrimSuffix(strings.ToLower(cname.(string)), ".")
+		if target != "" && target != host {
+			answer.Records = append(answer.Records, Record{Host: host, Type: TypeCNAME, Value: target})
+		}
+	}
+
+	for _, network := range []string{"ip4", "ip6"} {
+		ips, err := r.retry(func(ctx context.Context) (any, error) {
+			return r.resolver.LookupIP(ctx, network, host)
+		})
+		if err != nil {
+			// hosts without AAAA records are normal, only keep the first error
+			if answer.Err == nil && network == "ip4" {
+				answer.Err = err
+			}
+			continue
+		}
+
+		recordType := TypeA
+		if network == "ip6" {
+			recordType = TypeAAAA
+		}
+		for _, ip := range ips.([]net.IP) {
+			answer.Records = append(answer.Records, Record{Host: host, Type: recordType, Value: ip.String()})
+		}
+	}
+
+	// a host with any addresses resolved
+	if len(answer.IPs()) > 0 {
+		answer.Err = nil
+	}
+
+	return answer
+}
+
+// retry runs a lookup, retrying timeouts and temporary failures
+func (r *Resolver) retry(lookup func(ctx context.Context) (any, error)) (any, error) {
+	var err error
+	for attempt := 0; attempt <= r.Retries; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), r.Timeout)
+		var result any
+		result, err = lookup(ctx)
+		cancel()
+		if err == nil {
+			return result, nil
+		}
+
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && !dnsErr.IsTimeout && !dnsErr.IsTemporary {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
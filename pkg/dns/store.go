@@ -0,0 +1,74 @@
+package dns
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Save stores the records of answers as DNS records of a scan session.
+// Records seen before have their last seen time and dangling state
+// updated, so that the table keeps the history of every host. Discovered
+// domains of the hosts get their resolution status updated too.
+func Save(db *gorm.DB, answers []*Answer, scanSessionID *uint) error {
+	now := time.Now()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, answer := range answers {
+			dangling := answer.Dangling()
+
+			for _, record := range answer.Records {
+				recordDangling := dangling && record.Type == TypeCNAME
+
+				query := tx.Where("host = ? AND type = ? AND value = ?", record.Host, record.Type, record.Value)
+				if scanSessionID != nil {
+					query = query.Where("scan_session_id = ?", *scanSessionID)
+				} else {
+					query = query.Where("scan_session_id IS NULL")
+				}
+
+				var existing models.DNSRecord
+				err := query.First(&existing).Error
+				if err == nil {
+					if err := tx.Model(&existing).Updates(map[string]interface{}{
+						"last_seen": now,
+						"dangling":  recordDangling,
+					}).Error; err != nil {
+						return err
+					}
+					continue
+				}
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+
+				if err := tx.Create(&models.DNSRecord{
+					Host:          record.Host,
+					Type:          record.Type,
+					Value:         record.Value,
+					Dangling:      recordDangling,
+					FirstSeen:     now,
+					LastSeen:      now,
+					ScanSessionID: scanSessionID,
+				}).Error; err != nil {
+					return err
+				}
+			}
+
+			resolution := models.DomainUnresolved
+			if len(answer.IPs()) > 0 {
+				resolution = models.DomainResolved
+			}
+			if err := tx.Model(&models.Domain{}).Where("name = ?", answer.Host).Updates(map[string]interface{}{
+				"resolution":  resolution,
+				"resolved_at": now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
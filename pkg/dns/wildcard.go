@@ -0,0 +1,143 @@
+package dns
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// wildcardProbes is how many random labels are resolved to detect a
+// wildcard
+const wildcardProbes = 3
+
+// Wildcard is the answers a domain with a wildcard record (*.domain) gives
+// for names that do not exist
+type Wildcard struct {
+	Domain string
+	IPs    map[string]bool
+	CNAMEs map[string]bool
+}
+
+// Matches checks if an answer is the wildcard's. Answers with a CNAME are
+// compared by CNAME, as the addresses behind it may rotate, and other
+// answers match if every address is one the wildcard also returned. A nil
+// wildcard matches nothing.
+func (w *Wildcard) Matches(a *Answer) bool {
+	if w == nil || a == nil || a.Err != nil {
+		return false
+	}
+
+	values := a.values(TypeCNAME)
+	known := w.CNAMEs
+	if len(values) == 0 {
+		values = a.IPs()
+		known = w.IPs
+	}
+	if len(values) == 0 {
+		return false
+	}
+
+	for _, value := range values {
+		if !known[value] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Wildcards detects wildcard domains, probing each parent domain once
+type Wildcards struct {
+	resolver *Resolver
+
+	mu    sync.Mutex
+	cache map[string]*wildcardEntry
+}
+
+// wildcardEntry is a cached detection, see cacheEntry
+type wildcardEntry struct {
+	wildcard *Wildcard
+	done     chan struct{}
+}
+
+// NewWildcards returns a wildcard detector resolving with r
+func NewWildcards(r *Resolver) *Wildcards {
+	return &Wildcards{
+		resolver: r,
+		cache:    make(map[string]*wildcardEntry),
+	}
+}
+
+// Detect resolves random labels under a domain, returning the wildcard
+// answers, or nil if the domain has no wildcard
+func (w *Wildcards) Detect(domain string) *Wildcard {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+
+	w.mu.Lock()
+	entry, ok := w.cache[domain]
+	if !ok {
+		entry = &wildcardEntry{done: make(chan struct{})}
+		w.cache[domain] = entry
+	}
+	w.mu.Unlock()
+
+	if ok {
+		<-entry.done
+		return entry.wildcard
+	}
+
+	entry.wildcard = w.detect(domain)
+	close(entry.done)
+
+	return entry.wildcard
+}
+
+// Matches checks if an answer is what the wildcard of its parent domain
+// returns, meaning the host likely does not really exist
+func (w *Wildcards) Matches(a *Answer) bool {
+	_, parent, ok := strings.Cut(a.Host, ".")
+	if !ok || !strings.Contains(parent, ".") {
+		return false
+	}
+
+	return w.Detect(parent).Matches(a)
+}
+
+// detect probes a domain for a wildcard. Random labels are not cached by
+// the resolver, as they are never looked up again.
+func (w *Wildcards) detect(domain string) *Wildcard {
+	wildcard := &Wildcard{
+		Domain: domain,
+		IPs:    make(map[string]bool),
+		CNAMEs: make(map[string]bool),
+	}
+
+	for range wildcardProbes {
+		answer := w.resolver.lookup(randomLabel() + "." + domain)
+		if answer.Err != nil {
+			continue
+		}
+
+		for _, ip := range answer.IPs() {
+			wildcard.IPs[ip] = true
+		}
+		for _, cname := range answer.values(TypeCNAME) {
+			wildcard.CNAMEs[cname] = true
+		}
+	}
+
+	if len(wildcard.IPs) == 0 && len(wildcard.CNAMEs) == 0 {
+		return nil
+	}
+
+	return wildcard
+}
+
+// randomLabel returns a DNS label that is very unlikely to exist
+func randomLabel() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return "gw" + hex.EncodeToString(b)
+}
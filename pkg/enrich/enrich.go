@@ -0,0 +1,491 @@
+// Package enrich gathers information on IP addresses from Shodan, trying
+// the free InternetDB first and falling back to IP-API and a naabu scan,
+// and saves it along with the open ports that were found.
+package enrich
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/fallback"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"github.com/sensepost/gowitness/pkg/shodan"
+	"github.com/sensepost/gowitness/pkg/writers"
+	"gorm.io/gorm"
+)
+
+// Outcome is the outcome of enriching an IP
+type Outcome int
+
+const (
+	// Saved IPs were enriched with the Shodan API
+	Saved Outcome = iota
+	// SavedInternetDB IPs were enriched with InternetDB
+	SavedInternetDB
+	// SavedFallback IPs were enriched with IP-API and naabu
+	SavedFallback
+	// Skipped IPs were known already, or unresponsive
+	Skipped
+	// Failed IPs could not be enriched or saved
+	Failed
+)
+
+// Result is the outcome of enriching an IP
+type Result struct {
+	IP      string
+	Outcome Outcome
+	// Refreshed is set if the IP was known, and its information updated
+	Refreshed bool
+}
+
+// Summary counts the outcomes of enriching IPs
+type Summary struct {
+	Processed  int
+	Saved      int
+	Refreshed  int
+	Skipped    int
+	Failed     int
+	InternetDB int
+	Fallback   int
+}
+
+// Add counts the outcome of an IP
+func (s *Summary) Add(result Result) {
+	s.Processed++
+
+	switch result.Outcome {
+	case Saved, SavedInternetDB, SavedFallback:
+		if result.Refreshed {
+			s.Refreshed++
+		} else {
+			s.Saved++
+		}
+		switch result.Outcome {
+		case SavedInternetDB:
+			s.InternetDB++
+		case SavedFallback:
+			s.Fallback++
+		}
+	case Skipped:
+		s.Skipped++
+	case Failed:
+		s.Failed++
+	}
+}
+
+// maxBanner is the most of a Shodan banner that is stored
+const maxBanner = 8192
+
+// Enricher enriches IPs with Shodan InternetDB or API data, falling back to
+// IP-API and naabu. It is safe to use from several goroutines.
+type Enricher struct {
+	// DB is where IP information is saved, if it is set. Without it, IPs
+	// that were enriched before can not be skipped.
+	DB *gorm.DB
+	// Records gets every enriched IP as a JSON line, if it is set
+	Records *writers.RecordWriter
+	// Client queries the Shodan API. Without it, IPs InternetDB does not
+	// know use the fallback methods.
+	Client *shodan.Client
+	// InternetDB is tried before the Shodan API, if it is set
+	InternetDB *shodan.InternetDB
+	// Full queries complete host records, with their services, and skips
+	// InternetDB, which knows nothing about services
+	Full bool
+	// Unverified IPs were enumerated from an address space without knowing
+	// if they are responsive. They are only kept if naabu finds open ports.
+	Unverified map[string]bool
+	// ApexDomains are the domains of the scan session. Hostnames Shodan
+	// knows for an IP are recorded as domains if they are under one.
+	ApexDomains []string
+	// Refresh re-queries IPs whose information is older than MaxAge
+	Refresh bool
+	MaxAge  time.Duration
+	// Threads is the number of IPs EnrichAll enriches at once
+	Threads       int
+	ScanSessionID *uint
+	// Plugins enrich IP information once it is saved, if it is set
+	Plugins *plugins.Manager
+	// Providers look up every IP that was saved, if it is set
+	Providers *Providers
+
+	logger *slog.Logger
+	// limiter paces Shodan queries, internetdbLimiter paces InternetDB
+	// queries and fallbackLimiter paces IP-API queries
+	limiter           *islazy.RateLimiter
+	internetdbLimiter *islazy.RateLimiter
+	fallbackLimiter   *islazy.RateLimiter
+
+	// dbMu serialises database access, as sqlite does not like
+	// concurrent writers
+	dbMu sync.Mutex
+}
+
+// New returns an enricher that tries InternetDB before querying client, at
+// most rateLimit times a minute. client may be nil.
+func New(logger *slog.Logger, client *shodan.Client, rateLimit int) *Enricher {
+	return &Enricher{
+		Client:            client,
+		InternetDB:        shodan.NewInternetDB(),
+		Threads:           1,
+		logger:            logger,
+		limiter:           islazy.NewRateLimiter(rateLimit, 1),
+		internetdbLimiter: islazy.NewRateLimiter(60, 1),
+		// ip-api allows 45 requests a minute without a key
+		fallbackLimiter: islazy.NewRateLimiter(45, 1),
+	}
+}
+
+// EnrichAll enriches ips with a pool of Threads workers, calling fn with the
+// outcome of every IP as it is done. Shodan queries share a single rate
+// limiter, so that more workers only overlap the rest of the work, such as
+// fallback lookups, naabu scans and database writes. If ctx is done, the
+// IPs being enriched are finished and the rest left alone. It returns the
+// number of IPs that were started, which are the first of ips.
+func (e *Enricher) EnrichAll(ctx context.Context, ips []string, fn func(result Result)) int {
+	jobs := make(chan string)
+	results := make(chan Result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < max(1, e.Threads); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range jobs {
+				results <- e.Enrich(ip)
+			}
+		}()
+	}
+
+	var started int
+	go func() {
+	feed:
+		for _, ip := range ips {
+			select {
+			case jobs <- ip:
+				started++
+			case <-ctx.Done():
+				e.logger.Warn("interrupted, finishing the IPs being enriched")
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		fn(result)
+	}
+
+	return started
+}
+
+// Enrich gathers and saves the information of an IP
+func (e *Enricher) Enrich(ip string) Result {
+	outcome, refreshed := e.enrich(ip)
+	return Result{IP: ip, Outcome: outcome, Refreshed: refreshed}
+}
+
+// enrich gathers and saves the information of an IP. It reports whether
+// the IP was known already, and its information refreshed.
+func (e *Enricher) enrich(ip string) (Outcome, bool) {
+	// Check if we already have this IP in the database
+	var existing *models.IPInfo
+	if e.DB != nil {
+		var known models.IPInfo
+		e.dbMu.Lock()
+		err := e.DB.Where("ip_address = ?", ip).First(&known).Error
+		e.dbMu.Unlock()
+		if err == nil {
+			// IP already exists, skip it unless it is due a refresh
+			if !e.Refresh || time.Since(known.UpdatedAt) < e.MaxAge {
+				return Skipped, false
+			}
+			e.logger.Debug("refreshing stale IP information", "ip", ip, "updated", known.UpdatedAt)
+			existing = &known
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			e.logger.Warn("database error checking existing IP", "ip", ip, "err", err)
+			return Failed, false
+		}
+	}
+	refreshed := existing != nil
+
+	var err error
+
+	var ipInfo *models.IPInfo
+	var usedInternetDB, usedFallback bool
+
+	// InternetDB is free, so try it before spending Shodan credits
+	if e.InternetDB != nil && !e.Full {
+		e.internetdbLimiter.Wait()
+		e.logger.Debug("querying InternetDB for IP", "ip", ip)
+
+		host, err := e.InternetDB.GetHost(ip)
+		if err != nil {
+			if !errors.Is(err, shodan.ErrNotInInternetDB) {
+				e.logger.Warn("failed to query InternetDB for IP", "ip", ip, "err", err)
+			}
+			// ipInfo remains nil, will trigger the Shodan API
+		} else {
+			e.fallbackLimiter.Wait()
+			ipInfo = e.internetDBIPInfo(ip, host)
+			usedInternetDB = true
+
+			if len(host.Ports) > 0 {
+				err := e.withDB(func(db *gorm.DB) error {
+					return e.createFallbackIPPorts(db, ip, host.Ports)
+				})
+				if err != nil {
+					e.logger.Warn("failed to create IPPort entries for InternetDB", "ip", ip, "err", err)
+				}
+			}
+		}
+	}
+
+	// Try the Shodan API next if client is available
+	if ipInfo == nil && e.Client != nil {
+		e.limiter.Wait()
+		e.logger.Debug("querying Shodan for IP", "ip", ip)
+
+		var host *shodan.Host
+		if e.Full {
+			host, err = e.Client.GetHost(ip)
+		} else {
+			host, err = e.Client.GetHostMinimal(ip)
+		}
+		if err != nil {
+			e.logger.Warn("failed to query Shodan for IP", "ip", ip, "err", err)
+			// ipInfo remains nil, will trigger fallback
+		} else {
+			// Successfully got Shodan data
+			ipInfo = &models.IPInfo{
+				IPAddress:     host.IP,
+				Organization:  host.Organization,
+				ISP:           host.ISP,
+				ASN:           host.ASN,
+				Country:       host.Country,
+				CountryCode:   host.CountryCode,
+				City:          host.City,
+				Region:        host.Region,
+				Postal:        host.Postal,
+				Latitude:      host.Latitude,
+				Longitude:     host.Longitude,
+				OS:            host.OS,
+				LastUpdate:    host.LastUpdate.Time,
+				ScanSessionID: e.ScanSessionID,
+			}
+
+			// Set array fields using helper methods
+			if err := ipInfo.SetTags(host.Tags); err != nil {
+				e.logger.Warn("failed to set tags for IP", "ip", ip, "err", err)
+			}
+			if err := ipInfo.SetPorts(host.Ports); err != nil {
+				e.logger.Warn("failed to set ports for IP", "ip", ip, "err", err)
+			}
+			if err := ipInfo.SetHostnames(host.Hostnames); err != nil {
+				e.logger.Warn("failed to set hostnames for IP", "ip", ip, "err", err)
+			}
+			if err := ipInfo.SetDomains(host.Domains); err != nil {
+				e.logger.Warn("failed to set domains for IP", "ip", ip, "err", err)
+			}
+			if err := ipInfo.SetVulns(host.Vulns); err != nil {
+				e.logger.Warn("failed to set vulnerabilities for IP", "ip", ip, "err", err)
+			}
+
+			// Also create IPPort entries for open ports
+			err := e.withDB(func(db *gorm.DB) error {
+				return e.createIPPorts(db, host)
+			})
+			if err != nil {
+				e.logger.Warn("failed to create IPPort entries", "ip", ip, "err", err)
+			}
+		}
+	}
+
+	// If Shodan failed or no client available, try fallback
+	if ipInfo == nil {
+		// IPs enumerated from an address space without Shodan may not be
+		// responsive at all. Only keep those with open ports.
+		var ports []int
+		if e.Unverified[ip] {
+			ports, err = fallback.NaabuScan(context.Background(), ip)
+			if err != nil {
+				e.logger.Warn("failed to check if IP is responsive", "ip", ip, "err", err)
+				return Failed, refreshed
+			}
+			if len(ports) == 0 {
+				e.logger.Debug("skipping unresponsive IP", "ip", ip)
+				return Skipped, refreshed
+			}
+		}
+
+		e.fallbackLimiter.Wait()
+		fallbackInfo, err := e.fallbackIPInfo(ip, ports)
+		if err != nil {
+			e.logger.Error("both Shodan and fallback failed for IP", "ip", ip, "err", err)
+			return Failed, refreshed
+		}
+		ipInfo = fallbackInfo
+		usedFallback = true
+
+		// Also create IPPort entries for consistency with Shodan data
+		if ports, _ := ipInfo.GetPorts(); len(ports) > 0 {
+			err := e.withDB(func(db *gorm.DB) error {
+				return e.createFallbackIPPorts(db, ip, ports)
+			})
+			if err != nil {
+				e.logger.Warn("failed to create IPPort entries for fallback", "ip", ip, "err", err)
+			}
+		}
+	}
+
+	// Reverse DNS is free, so always add it regardless of the source
+	e.setReverseDNS(ipInfo, ip)
+
+	source, outcome := "shodan", Saved
+	switch {
+	case usedInternetDB:
+		source, outcome = "internetdb", SavedInternetDB
+	case usedFallback:
+		source, outcome = "ip-api+naabu", SavedFallback
+	}
+
+	if err := e.save(ipInfo, existing, source); err != nil {
+		e.logger.Warn("failed to save IP info to database", "ip", ip, "err", err)
+		return Failed, refreshed
+	}
+
+	if e.Records != nil {
+		if err := e.Records.Write("ip_info", source, ipInfo); err != nil {
+			e.logger.Warn("failed to write IP info record", "ip", ip, "err", err)
+		}
+	}
+
+	e.logger.Debug("saved IP information", "ip", ip, "organization", ipInfo.Organization, "source", source)
+
+	if e.Providers != nil {
+		e.Providers.Enrich(context.Background(), Target{Kind: TargetIP, Value: ip}, func(name string, out *plugins.Output) error {
+			return e.withDB(func(db *gorm.DB) error {
+				return plugins.Save(db, name, nil, ip, e.ScanSessionID, out)
+			})
+		})
+	}
+
+	return outcome, refreshed
+}
+
+// save saves the information of an IP to the database, if results are
+// written to one, updating the row of a refreshed IP in place
+func (e *Enricher) save(ipInfo *models.IPInfo, existing *models.IPInfo, source string) error {
+	if existing != nil {
+		// Keep when the IP was first seen
+		ipInfo.ID = existing.ID
+		ipInfo.FirstSeen = existing.FirstSeen
+		if ipInfo.ScanSessionID == nil {
+			ipInfo.ScanSessionID = existing.ScanSessionID
+		}
+	} else {
+		ipInfo.FirstSeen = time.Now()
+	}
+
+	// Plugins write too, so they run under the lock
+	return e.withDB(func(db *gorm.DB) error {
+		var err error
+		if existing != nil {
+			err = db.Save(ipInfo).Error
+		} else {
+			err = db.Create(ipInfo).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		// Keep a snapshot, so that changes show across scan sessions
+		if err := db.Create(models.NewIPInfoHistory(ipInfo, source)).Error; err != nil {
+			e.logger.Warn("failed to save IP info history", "ip", ipInfo.IPAddress, "err", err)
+		}
+
+		if e.Plugins != nil {
+			e.Plugins.EnrichIPInfo(db, ipInfo)
+		}
+
+		e.saveHostnames(db, ipInfo)
+
+		return nil
+	})
+}
+
+// saveHostnames records the hostnames Shodan knows for an IP that are
+// under an apex domain of the scan session as discovered domains
+func (e *Enricher) saveHostnames(db *gorm.DB, ipInfo *models.IPInfo) {
+	if len(e.ApexDomains) == 0 {
+		return
+	}
+
+	hostnames, err := ipInfo.GetHostnames()
+	if err != nil {
+		return
+	}
+
+	for _, hostname := range hostnames {
+		hostname = strings.ToLower(hostname)
+		if !slices.ContainsFunc(e.ApexDomains, func(apex string) bool {
+			return hostname == apex || strings.HasSuffix(hostname, "."+apex)
+		}) {
+			continue
+		}
+
+		if err := database.SaveDomain(db, &models.Domain{
+			Name:          hostname,
+			Source:        models.DomainSourceShodan,
+			ScanSessionID: ipInfo.ScanSessionID,
+		}); err != nil {
+			e.logger.Warn("failed to save shodan hostname", "hostname", hostname, "err", err)
+		}
+	}
+}
+
+// withDB runs fn with the database under the lock. Without a database, fn
+// is not run.
+func (e *Enricher) withDB(fn func(db *gorm.DB) error) error {
+	if e.DB == nil {
+		return nil
+	}
+
+	e.dbMu.Lock()
+	defer e.dbMu.Unlock()
+
+	return fn(e.DB)
+}
+
+// ApexDomains returns the apex domains of a scan session, or of the latest
+// active one if scanSessionID is 0
+func ApexDomains(db *gorm.DB, scanSessionID uint) ([]string, error) {
+	var session *models.ScanSession
+	if scanSessionID > 0 {
+		session = &models.ScanSession{}
+		if err := db.First(session, scanSessionID).Error; err != nil {
+			return nil, nil
+		}
+	} else {
+		var err error
+		if session, err = database.LatestActiveSession(db); err != nil || session == nil {
+			return nil, nil
+		}
+	}
+
+	if err := db.Model(session).Association("ApexDomains").Find(&session.ApexDomains); err != nil {
+		return nil, err
+	}
+
+	return session.ScopeDomains(), nil
+}
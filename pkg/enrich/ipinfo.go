@@ -0,0 +1,239 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/fallback"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/shodan"
+	"gorm.io/gorm"
+)
+
+// fallbackIPInfo creates IP info from fallback sources. If ports is not
+// nil, those are used instead of running a naabu scan. The open ports are
+// set on the IP info, but not saved as IPPort entries.
+func (e *Enricher) fallbackIPInfo(ip string, ports []int) (*models.IPInfo, error) {
+	e.logger.Info("attempting fallback IP intelligence gathering", "ip", ip)
+
+	// Try IP-API for geolocation
+	ipApiData, err := fallback.FetchIPAPI(ip)
+	if err != nil {
+		e.logger.Warn("failed to fetch IP-API data", "ip", ip, "err", err)
+		return nil, fmt.Errorf("fallback IP-API failed: %w", err)
+	}
+
+	// Try naabu for port scanning
+	if ports == nil {
+		ports, err = fallback.NaabuScan(context.Background(), ip)
+		if err != nil {
+			e.logger.Warn("failed to run naabu scan", "ip", ip, "err", err)
+			// Continue without port data - IP-API data is still valuable
+			ports = []int{}
+		} else {
+			e.logger.Info("naabu scan completed", "ip", ip, "ports_found", len(ports))
+		}
+	}
+
+	// Create IPInfo from fallback data
+	ipInfo := &models.IPInfo{
+		IPAddress:     ip,
+		Organization:  ipApiData.Org,
+		ISP:           ipApiData.ISP,
+		ASN:           ipApiData.AS,
+		Country:       ipApiData.Country,
+		CountryCode:   ipApiData.CountryCode,
+		City:          ipApiData.City,
+		Region:        ipApiData.RegionName,
+		Postal:        ipApiData.Zip,
+		Latitude:      ipApiData.Lat,
+		Longitude:     ipApiData.Lon,
+		LastUpdate:    time.Now(),
+		ScanSessionID: e.ScanSessionID,
+	}
+
+	// Set ports from naabu scan
+	if len(ports) > 0 {
+		if err := ipInfo.SetPorts(ports); err != nil {
+			e.logger.Warn("failed to set ports for IP info", "ip", ip, "err", err)
+		}
+	}
+
+	e.logger.Info("created fallback IP info", "ip", ip, "source", "ip-api+naabu", "org", ipInfo.Organization)
+	return ipInfo, nil
+}
+
+// internetDBIPInfo creates IP info from InternetDB data. InternetDB knows
+// nothing about who owns an IP, so IP-API adds the geolocation and ISP
+// information if it can.
+func (e *Enricher) internetDBIPInfo(ip string, host *shodan.InternetDBHost) *models.IPInfo {
+	ipInfo := &models.IPInfo{
+		IPAddress:     ip,
+		LastUpdate:    time.Now(),
+		ScanSessionID: e.ScanSessionID,
+	}
+
+	if ipApiData, err := fallback.FetchIPAPI(ip); err != nil {
+		e.logger.Warn("failed to fetch IP-API data", "ip", ip, "err", err)
+	} else {
+		ipInfo.Organization = ipApiData.Org
+		ipInfo.ISP = ipApiData.ISP
+		ipInfo.ASN = ipApiData.AS
+		ipInfo.Country = ipApiData.Country
+		ipInfo.CountryCode = ipApiData.CountryCode
+		ipInfo.City = ipApiData.City
+		ipInfo.Region = ipApiData.RegionName
+		ipInfo.Postal = ipApiData.Zip
+		ipInfo.Latitude = ipApiData.Lat
+		ipInfo.Longitude = ipApiData.Lon
+	}
+
+	if err := ipInfo.SetTags(host.Tags); err != nil {
+		e.logger.Warn("failed to set tags for IP", "ip", ip, "err", err)
+	}
+	if err := ipInfo.SetPorts(host.Ports); err != nil {
+		e.logger.Warn("failed to set ports for IP", "ip", ip, "err", err)
+	}
+	if err := ipInfo.SetHostnames(host.Hostnames); err != nil {
+		e.logger.Warn("failed to set hostnames for IP", "ip", ip, "err", err)
+	}
+	if err := ipInfo.SetVulns(host.Vulns); err != nil {
+		e.logger.Warn("failed to set vulnerabilities for IP", "ip", ip, "err", err)
+	}
+
+	e.logger.Debug("created InternetDB IP info", "ip", ip, "ports", len(host.Ports), "vulns", len(host.Vulns))
+	return ipInfo
+}
+
+// createFallbackIPPorts creates IPPort entries for the ports InternetDB or
+// a fallback scan found, which know nothing of their services
+func (e *Enricher) createFallbackIPPorts(db *gorm.DB, ip string, ports []int) error {
+	for _, port := range ports {
+		// Check if this IP:Port combination already exists
+		var existing models.IPPort
+		if err := db.Where("ip_address = ? AND port = ?", ip, port).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// Create new IPPort entry
+				ipPort := models.IPPort{
+					IPAddress:     ip,
+					Port:          port,
+					Protocol:      "tcp", // naabu typically scans TCP ports
+					State:         "open",
+					Service:       "", // No service detection in fallback
+					ScanSessionID: e.ScanSessionID,
+					IsCDN:         false,
+					CDNDetected:   false,
+				}
+
+				if err := db.Create(&ipPort).Error; err != nil {
+					e.logger.Warn("failed to create fallback IPPort entry", "ip", ip, "port", port, "err", err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// createIPPorts creates IPPort entries for the open ports of a Shodan host.
+// If the host is a full record, the services seen on the ports are stored
+// too, updating ports that were known already.
+func (e *Enricher) createIPPorts(db *gorm.DB, host *shodan.Host) error {
+	services := make(map[int]shodan.Service)
+	for _, service := range host.Data {
+		services[service.Port] = service
+	}
+
+	for _, port := range host.Ports {
+		service, hasService := services[port]
+
+		// Check if this IP:Port combination already exists
+		var existing models.IPPort
+		if err := db.Where("ip_address = ? AND port = ?", host.IP, port).First(&existing).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// Create new IPPort entry
+				ipPort := models.IPPort{
+					IPAddress:     host.IP,
+					Port:          port,
+					Protocol:      "tcp", // Shodan typically reports TCP ports
+					State:         "open",
+					ScanSessionID: e.ScanSessionID,
+					IsCDN:         false, // Could be enhanced with CDN detection
+					CDNDetected:   false,
+				}
+				if hasService {
+					applyService(&ipPort, service)
+				}
+
+				if err := db.Create(&ipPort).Error; err != nil {
+					e.logger.Warn("failed to create IPPort entry", "ip", host.IP, "port", port, "err", err)
+				}
+			}
+		} else if hasService {
+			applyService(&existing, service)
+			if err := db.Save(&existing).Error; err != nil {
+				e.logger.Warn("failed to update IPPort entry", "ip", host.IP, "port", port, "err", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyService sets the details of the service Shodan saw on a port
+func applyService(ipPort *models.IPPort, service shodan.Service) {
+	if service.Transport != "" {
+		ipPort.Protocol = service.Transport
+	}
+	ipPort.Service = service.Shodan.Module
+	ipPort.Product = service.Product
+	ipPort.Version = service.Version
+	ipPort.Banner = service.Banner
+	if len(ipPort.Banner) > maxBanner {
+		ipPort.Banner = strings.ToValidUTF8(ipPort.Banner[:maxBanner], "")
+	}
+
+	if service.HTTP != nil {
+		ipPort.HTTPStatus = service.HTTP.Status
+		ipPort.HTTPTitle = service.HTTP.Title
+	}
+
+	if service.SSL != nil {
+		cert := service.SSL.Certificate
+		ipPort.TLSSubject = cert.Subject.CN
+		ipPort.TLSIssuer = cert.Issuer.O
+		if ipPort.TLSIssuer == "" {
+			ipPort.TLSIssuer = cert.Issuer.CN
+		}
+		if !cert.ValidUntil.IsZero() {
+			expires := cert.ValidUntil.Time
+			ipPort.TLSExpires = &expires
+		}
+
+		// unsupported versions are prefixed with a -
+		var versions []string
+		for _, version := range service.SSL.Versions {
+			if !strings.HasPrefix(version, "-") {
+				versions = append(versions, version)
+			}
+		}
+		ipPort.TLSVersions = strings.Join(versions, ",")
+	}
+}
+
+// setReverseDNS adds the PTR records for an IP to its IP info
+func (e *Enricher) setReverseDNS(ipInfo *models.IPInfo, ip string) {
+	names, err := islazy.ReverseLookup(ip)
+	if err != nil {
+		e.logger.Debug("no reverse dns for IP", "ip", ip, "err", err)
+		return
+	}
+
+	if err := ipInfo.SetReverseDNS(names); err != nil {
+		e.logger.Warn("failed to set reverse dns for IP", "ip", ip, "err", err)
+	}
+}
@@ -0,0 +1,169 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/plugins"
+)
+
+// Target kinds a provider looks up
+const (
+	TargetIP     = plugins.HookIP
+	TargetDomain = plugins.HookDomain
+)
+
+// Target is an IP address or domain to look up
+type Target struct {
+	// Kind is TargetIP or TargetDomain
+	Kind  string
+	Value string
+}
+
+// Provider is an intel source for IP addresses and domains, beyond the
+// built in Shodan, InternetDB and IP-API lookups. Go programs embedding
+// gowitness add their own with Register, and plugin executables subscribed
+// to the ip or domain hooks are providers too.
+type Provider interface {
+	// Name is the source the provider's output is saved as
+	Name() string
+	// RateLimit is the most lookups a minute the provider allows, 0 for no
+	// limit
+	RateLimit() int
+	// Enrich looks up a target, returning the fields, tags and findings it
+	// adds. It returns nil output for kinds of targets it does not support.
+	Enrich(ctx context.Context, target Target) (*plugins.Output, error)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []Provider
+)
+
+// Register makes a provider available to every scan that runs providers,
+// typically from the init function of the package implementing it, much
+// like database/sql drivers. It panics if a provider of the same name was
+// registered already.
+func Register(provider Provider) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if slices.ContainsFunc(registry, func(p Provider) bool { return p.Name() == provider.Name() }) {
+		panic(fmt.Sprintf("enrich: provider %s registered twice", provider.Name()))
+	}
+
+	registry = append(registry, provider)
+}
+
+// Registered returns the providers that were registered
+func Registered() []Provider {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	return slices.Clone(registry)
+}
+
+// pluginProvider is a plugin executable subscribed to the ip or domain
+// hooks
+type pluginProvider struct {
+	plugin *plugins.Plugin
+}
+
+func (p pluginProvider) Name() string   { return p.plugin.Name }
+func (p pluginProvider) RateLimit() int { return p.plugin.RateLimit }
+
+func (p pluginProvider) Enrich(ctx context.Context, target Target) (*plugins.Output, error) {
+	if !p.plugin.Subscribed(target.Kind) {
+		return nil, nil
+	}
+
+	return p.plugin.Enrich(target.Kind, target.Value)
+}
+
+// PluginProviders returns the plugins of m that subscribed to the ip or
+// domain hooks as providers. m may be nil.
+func PluginProviders(m *plugins.Manager) []Provider {
+	if m == nil {
+		return nil
+	}
+
+	var providers []Provider
+	for _, plugin := range m.Subscribed(plugins.HookIP) {
+		providers = append(providers, pluginProvider{plugin})
+	}
+	for _, plugin := range m.Subscribed(plugins.HookDomain) {
+		if !plugin.Subscribed(plugins.HookIP) {
+			providers = append(providers, pluginProvider{plugin})
+		}
+	}
+
+	return providers
+}
+
+// Providers looks targets up with a set of providers, pacing each by its
+// own rate limit. It is safe to use from several goroutines.
+type Providers struct {
+	providers []Provider
+	// limiters pace the provider at the same index, if it has a rate limit
+	limiters []*islazy.RateLimiter
+	logger   *slog.Logger
+}
+
+// NewProviders returns a set of providers
+func NewProviders(logger *slog.Logger, providers []Provider) *Providers {
+	ps := &Providers{
+		providers: providers,
+		limiters:  make([]*islazy.RateLimiter, len(providers)),
+		logger:    logger,
+	}
+	for i, provider := range providers {
+		if limit := provider.RateLimit(); limit > 0 {
+			ps.limiters[i] = islazy.NewRateLimiter(limit, 1)
+		}
+	}
+
+	return ps
+}
+
+// Names returns the names of the providers
+func (ps *Providers) Names() []string {
+	names := make([]string, len(ps.providers))
+	for i, provider := range ps.providers {
+		names[i] = provider.Name()
+	}
+
+	return names
+}
+
+// Enrich looks a target up with every provider, passing what each adds to
+// save along with its name. Providers that fail are logged and skipped.
+func (ps *Providers) Enrich(ctx context.Context, target Target, save func(source string, out *plugins.Output) error) {
+	for i, provider := range ps.providers {
+		if ctx.Err() != nil {
+			return
+		}
+		if ps.limiters[i] != nil {
+			ps.limiters[i].Wait()
+		}
+
+		out, err := provider.Enrich(ctx, target)
+		if err != nil {
+			ps.logger.Warn("provider failed to enrich target", "provider", provider.Name(),
+				target.Kind, target.Value, "err", err)
+			continue
+		}
+		if out == nil {
+			continue
+		}
+
+		out.Normalise()
+		if err := save(provider.Name(), out); err != nil {
+			ps.logger.Warn("failed to save provider output", "provider", provider.Name(),
+				target.Kind, target.Value, "err", err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package enrich
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/asn"
+	"github.com/sensepost/gowitness/pkg/dns"
+	"github.com/sensepost/gowitness/pkg/scope"
+)
+
+// Targets are what to collect the IPs to enrich from
+type Targets struct {
+	// Hosts are hostnames, IPs or URLs, resolved to their IPv4 addresses
+	Hosts []string
+	// ASNs and CIDRs are enumerated, with Shodan search if there is a
+	// client, or by expanding their address space
+	ASNs  []string
+	CIDRs []string
+	// MaxIPs is the most IPs enumerated from ASNs and CIDRs, 0 for no limit
+	MaxIPs int
+	// Resolvers resolve Hosts, the system resolver if it is empty
+	Resolvers      []string
+	ResolveThreads int
+	// Scope leaves out of scope hosts and IPs out, if it is set
+	Scope *scope.Scope
+}
+
+// Collect gathers the IPs to enrich from targets. IPs that were enumerated
+// from an address space without knowing if they are responsive are flagged
+// in the returned unverified map, which suits Unverified. The DNS records
+// found resolving hosts are saved, if there is a database.
+func (e *Enricher) Collect(targets Targets) ([]string, map[string]bool, error) {
+	var ips []string
+	seen := make(map[string]bool)
+	unverified := make(map[string]bool)
+	var outOfScope int
+
+	// add adds an IP, returning false once the maximum number of IPs is
+	// reached, so that address space is not expanded any further
+	full := false
+	add := func(ip string, verified bool) bool {
+		if full {
+			return false
+		}
+		if seen[ip] {
+			return true
+		}
+		if err := targets.Scope.Check(ip); err != nil {
+			seen[ip] = true
+			outOfScope++
+			return true
+		}
+		if targets.MaxIPs > 0 && len(ips) >= targets.MaxIPs {
+			e.logger.Warn("reached the maximum number of IPs to enrich, ignoring the rest", "max-ips", targets.MaxIPs)
+			full = true
+			return false
+		}
+
+		seen[ip] = true
+		ips = append(ips, ip)
+		if !verified {
+			unverified[ip] = true
+		}
+		return true
+	}
+	addAll := func(candidates []string, verified bool) {
+		for _, ip := range candidates {
+			if !add(ip, verified) {
+				return
+			}
+		}
+	}
+	expand := func(cidr string) error {
+		return islazy.EachIPInCIDR(cidr, func(ip string) bool {
+			return add(ip, false)
+		})
+	}
+
+	if len(targets.Hosts) > 0 {
+		// Resolve domains to IPs and deduplicate
+		addAll(e.resolve(targets), true)
+	}
+
+	for _, a := range targets.ASNs {
+		a = asn.Normalise(a)
+
+		// Shodan knows which IPs are responsive, so prefer it
+		if e.Client != nil {
+			found, err := e.Client.SearchIPs("asn:"+a, targets.MaxIPs)
+			if err != nil {
+				e.logger.Warn("failed to search Shodan for ASN", "asn", a, "err", err)
+			} else {
+				e.logger.Info("found responsive IPs in ASN", "asn", a, "count", len(found))
+				addAll(found, true)
+				continue
+			}
+		}
+
+		prefixes, err := asn.AnnouncedPrefixes(a)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get prefixes for %s: %w", a, err)
+		}
+		e.logger.Info("found announced prefixes for ASN", "asn", a, "count", len(prefixes))
+
+		for _, prefix := range prefixes {
+			if full {
+				break
+			}
+			// only ipv4 address space is enumerated
+			if strings.Contains(prefix, ":") {
+				continue
+			}
+
+			if err := expand(prefix); err != nil {
+				e.logger.Warn("failed to expand prefix", "prefix", prefix, "err", err)
+			}
+		}
+	}
+
+	for _, cidr := range targets.CIDRs {
+		if full {
+			break
+		}
+		// only ipv4 address space is enumerated
+		if strings.Contains(cidr, ":") {
+			e.logger.Warn("skipping IPv6 CIDR, only IPv4 address space is enumerated", "cidr", cidr)
+			continue
+		}
+
+		if e.Client != nil {
+			found, err := e.Client.SearchIPs("net:"+cidr, targets.MaxIPs)
+			if err != nil {
+				e.logger.Warn("failed to search Shodan for CIDR", "cidr", cidr, "err", err)
+			} else {
+				e.logger.Info("found responsive IPs in CIDR", "cidr", cidr, "count", len(found))
+				addAll(found, true)
+				continue
+			}
+		}
+
+		if err := expand(cidr); err != nil {
+			return nil, nil, fmt.Errorf("failed to expand cidr %s: %w", cidr, err)
+		}
+	}
+
+	if outOfScope > 0 {
+		e.logger.Warn("skipping out of scope IPs", "count", outOfScope)
+	}
+
+	return ips, unverified, nil
+}
+
+// resolve resolves the hosts of targets to their unique IPv4 addresses,
+// storing every DNS record found along the way
+func (e *Enricher) resolve(targets Targets) []string {
+	resolver := dns.NewResolver(targets.Resolvers)
+	if targets.ResolveThreads > 0 {
+		resolver.Concurrency = targets.ResolveThreads
+	}
+
+	names := make([]string, 0, len(targets.Hosts))
+	for _, host := range targets.Hosts {
+		// Remove protocol and port if present
+		if net.ParseIP(host) == nil {
+			host = strings.TrimPrefix(host, "http://")
+			host = strings.TrimPrefix(host, "https://")
+			if colonIndex := strings.LastIndex(host, ":"); colonIndex > 0 {
+				// Only remove port if it's not an IPv6 address
+				if !strings.Contains(host, "]") {
+					host = host[:colonIndex]
+				}
+			}
+		}
+		names = append(names, host)
+	}
+
+	// Out of scope hosts are not even resolved
+	names, skipped := targets.Scope.Filter(names)
+	if skipped > 0 {
+		e.logger.Warn("skipping out of scope hosts", "count", skipped)
+	}
+
+	answers := resolver.ResolveAll(names)
+	if e.DB != nil {
+		if err := dns.Save(e.DB, answers, e.ScanSessionID); err != nil {
+			e.logger.Warn("failed to save dns records", "err", err)
+		}
+	}
+
+	var result []string
+	ipSet := make(map[string]bool)
+	for _, answer := range answers {
+		if answer.Err != nil {
+			e.logger.Warn("failed to resolve host", "host", answer.Host, "err", answer.Err)
+			continue
+		}
+		// a scope of only domains allows the addresses they resolve to
+		targets.Scope.AddResolved(answer.Host, answer.IPv4()...)
+
+		// Only include IPv4 addresses
+		for _, ip := range answer.IPv4() {
+			if !ipSet[ip] {
+				ipSet[ip] = true
+				result = append(result, ip)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,114 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// defectDojoSeverities are the DefectDojo severities of finding severities
+var defectDojoSeverities = map[string]string{
+	"critical": "Critical",
+	"high":     "High",
+	"medium":   "Medium",
+	"low":      "Low",
+	"info":     "Info",
+}
+
+// defectDojoReport is a DefectDojo "Generic Findings Import" report
+type defectDojoReport struct {
+	Findings []defectDojoFinding `json:"findings"`
+}
+
+type defectDojoFinding struct {
+	Title          string               `json:"title"`
+	Description    string               `json:"description"`
+	Severity       string               `json:"severity"`
+	Date           string               `json:"date"`
+	CVE            string               `json:"cve,omitempty"`
+	CVSSv3Score    float64              `json:"cvssv3_score,omitempty"`
+	UniqueID       string               `json:"unique_id_from_tool"`
+	VulnIDFromTool string               `json:"vuln_id_from_tool,omitempty"`
+	Tags           []string             `json:"tags"`
+	Endpoints      []defectDojoEndpoint `json:"endpoints,omitempty"`
+	Active         bool                 `json:"active"`
+	Verified       bool                 `json:"verified"`
+	StaticFinding  bool                 `json:"static_finding"`
+	DynamicFinding bool                 `json:"dynamic_finding"`
+}
+
+type defectDojoEndpoint struct {
+	Protocol string `json:"protocol,omitempty"`
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// WriteDefectDojo writes findings as a DefectDojo "Generic Findings Import"
+// JSON report. Findings keep their fingerprint as unique_id_from_tool, so
+// DefectDojo deduplicates them across imports.
+func WriteDefectDojo(w io.Writer, findings []*Finding) error {
+	report := defectDojoReport{Findings: make([]defectDojoFinding, 0, len(findings))}
+	for _, finding := range findings {
+		severity, ok := defectDojoSeverities[finding.Severity]
+		if !ok {
+			severity = "Info"
+		}
+
+		f := defectDojoFinding{
+			Title:          finding.Title,
+			Description:    defectDojoDescription(finding),
+			Severity:       severity,
+			Date:           finding.Date.Format("2006-01-02"),
+			CVE:            finding.CVE,
+			CVSSv3Score:    finding.CVSS,
+			UniqueID:       finding.Fingerprint(),
+			VulnIDFromTool: finding.CVE,
+			Tags:           []string{"gowitness", finding.Source},
+			Active:         true,
+			DynamicFinding: true,
+		}
+
+		if finding.URL != "" {
+			if u, err := url.Parse(finding.URL); err == nil {
+				f.Endpoints = append(f.Endpoints, defectDojoEndpoint{
+					Protocol: u.Scheme,
+					Host:     finding.Host,
+					Port:     finding.Port,
+					Path:     strings.TrimPrefix(u.Path, "/"),
+				})
+			}
+		} else if finding.Host != "" {
+			f.Endpoints = append(f.Endpoints, defectDojoEndpoint{Host: finding.Host})
+		}
+
+		report.Findings = append(report.Findings, f)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(report)
+}
+
+// defectDojoDescription returns the description of a finding, with where
+// it was found, as DefectDojo requires one
+func defectDojoDescription(finding *Finding) string {
+	var b strings.Builder
+	if finding.Description != "" {
+		b.WriteString(finding.Description + "\n\n")
+	}
+	if finding.URL != "" {
+		b.WriteString("**URL:** " + finding.URL + "\n")
+	}
+	if finding.Host != "" {
+		b.WriteString("**Host:** " + finding.Host + "\n")
+	}
+	if finding.IPAddress != "" && finding.IPAddress != finding.Host {
+		b.WriteString("**IP address:** " + finding.IPAddress + "\n")
+	}
+	b.WriteString("**Source:** " + finding.Source)
+
+	return b.String()
+}
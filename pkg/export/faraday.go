@@ -0,0 +1,185 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// faradaySeverities are the Faraday severities of finding severities
+var faradaySeverities = map[string]string{
+	"critical": "critical",
+	"high":     "high",
+	"medium":   "medium",
+	"low":      "low",
+	"info":     "informational",
+}
+
+// faradayReport is a Faraday XML report of hosts, with their services and
+// vulnerabilities, as Faraday models them
+type faradayReport struct {
+	XMLName xml.Name      `xml:"faraday"`
+	Hosts   []faradayHost `xml:"hosts>host"`
+}
+
+type faradayHost struct {
+	IP              string                 `xml:"ip"`
+	OS              string                 `xml:"os,omitempty"`
+	Description     string                 `xml:"description,omitempty"`
+	Hostnames       []string               `xml:"hostnames>hostname"`
+	Services        []*faradayService      `xml:"services>service"`
+	Vulnerabilities []faradayVulnerability `xml:"vulnerabilities>vulnerability"`
+}
+
+type faradayService struct {
+	Name            string                 `xml:"name"`
+	Port            int                    `xml:"port"`
+	Protocol        string                 `xml:"protocol"`
+	Status          string                 `xml:"status"`
+	Version         string                 `xml:"version,omitempty"`
+	Vulnerabilities []faradayVulnerability `xml:"vulnerabilities>vulnerability"`
+}
+
+type faradayVulnerability struct {
+	// Type is vulnerability, or vulnerability_web for findings on a URL
+	Type        string   `xml:"type,attr"`
+	Name        string   `xml:"name"`
+	Description string   `xml:"desc"`
+	Severity    string   `xml:"severity"`
+	ExternalID  string   `xml:"external_id"`
+	Refs        []string `xml:"refs>ref,omitempty"`
+	Website     string   `xml:"website,omitempty"`
+	Path        string   `xml:"path,omitempty"`
+	Data        string   `xml:"data,omitempty"`
+}
+
+// WriteFaraday writes ips, with their open ports as services, and findings
+// as a Faraday XML report. Findings on a URL are web vulnerabilities of the
+// service of its port, and other findings vulnerabilities of their host.
+// Hosts are added for findings on addresses or domains not in ips.
+func WriteFaraday(w io.Writer, ips []*IP, findings []*Finding) error {
+	var hosts []*faradayHost
+	byAddress := make(map[string]*faradayHost)
+	add := func(address string) *faradayHost {
+		host, ok := byAddress[address]
+		if !ok {
+			host = &faradayHost{IP: address, Hostnames: []string{}}
+			byAddress[address] = host
+			hosts = append(hosts, host)
+		}
+		return host
+	}
+
+	for _, ip := range ips {
+		host := add(ip.IPAddress)
+		host.OS = ip.OS
+		if ip.Organization != "" {
+			host.Description = ip.Organization
+			if ip.ASN != "" {
+				host.Description += " (" + ip.ASN + ")"
+			}
+		}
+		for _, name := range slices.Concat(ip.Domains, ip.ReverseDNS) {
+			host.Hostnames = appendUnique(host.Hostnames, name)
+		}
+		for _, port := range ip.Ports {
+			service := port.Service
+			if service == "" {
+				service = strconv.Itoa(port.Port)
+			}
+			status := port.State
+			if status == "" {
+				status = "open"
+			}
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			host.Services = append(host.Services, &faradayService{
+				Name:     service,
+				Port:     port.Port,
+				Protocol: protocol,
+				Status:   status,
+				Version:  firstLine(port.Banner),
+			})
+		}
+	}
+
+	for _, finding := range findings {
+		address := finding.IPAddress
+		if address == "" {
+			address = finding.Host
+		}
+		if address == "" {
+			continue
+		}
+		host := add(address)
+		if finding.Host != "" && finding.Host != address {
+			host.Hostnames = appendUnique(host.Hostnames, finding.Host)
+		}
+
+		severity, ok := faradaySeverities[finding.Severity]
+		if !ok {
+			severity = "informational"
+		}
+		vulnerability := faradayVulnerability{
+			Type:        "vulnerability",
+			Name:        finding.Title,
+			Description: finding.Description,
+			Severity:    severity,
+			ExternalID:  finding.Fingerprint(),
+			Data:        "Reported by " + finding.Source,
+		}
+		if finding.CVE != "" {
+			vulnerability.Refs = []string{finding.CVE}
+		}
+
+		if finding.URL == "" || finding.Port == 0 {
+			host.Vulnerabilities = append(host.Vulnerabilities, vulnerability)
+			continue
+		}
+
+		vulnerability.Type = "vulnerability_web"
+		vulnerability.Website = finding.Host
+		vulnerability.Path = finding.URL
+
+		i := slices.IndexFunc(host.Services, func(s *faradayService) bool {
+			return s.Port == finding.Port && s.Protocol == "tcp"
+		})
+		if i < 0 {
+			host.Services = append(host.Services, &faradayService{
+				Name:     finding.Scheme,
+				Port:     finding.Port,
+				Protocol: "tcp",
+				Status:   "open",
+			})
+			i = len(host.Services) - 1
+		}
+		host.Services[i].Vulnerabilities = append(host.Services[i].Vulnerabilities, vulnerability)
+	}
+
+	report := faradayReport{Hosts: make([]faradayHost, 0, len(hosts))}
+	for _, host := range hosts {
+		report.Hosts = append(report.Hosts, *host)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// firstLine returns the first line of s, trimmed
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(line)
+}
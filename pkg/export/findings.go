@@ -0,0 +1,146 @@
+package export
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"gorm.io/gorm"
+)
+
+// Finding is a finding joined with the result or address it is about
+type Finding struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Severity    string    `json:"severity"`
+	Description string    `json:"description"`
+	Source      string    `json:"source"`
+	CVE         string    `json:"cve,omitempty"`
+	CVSS        float64   `json:"cvss,omitempty"`
+	Date        time.Time `json:"date"`
+	// URL is the result the finding is about, if any
+	URL string `json:"url,omitempty"`
+	// Host is the hostname or IP address the finding is about
+	Host      string `json:"host"`
+	IPAddress string `json:"ip_address,omitempty"`
+	// Port and Scheme are those of URL, if it is set
+	Port          int    `json:"port,omitempty"`
+	Scheme        string `json:"scheme,omitempty"`
+	ScanSessionID *uint  `json:"scan_session_id,omitempty"`
+}
+
+// Fingerprint identifies what a finding reports, stable across scans, so
+// that platforms importing it can tell a finding they have seen before
+func (f *Finding) Fingerprint() string {
+	target := f.URL
+	if target == "" {
+		target = f.Host
+	}
+
+	return strings.Join([]string{f.Source, f.Title, target}, "|")
+}
+
+// Findings builds the joined view of all findings, most recent last. If
+// scanSessionID is set, only findings from that scan session are included.
+func Findings(db *gorm.DB, scanSessionID *uint) ([]*Finding, error) {
+	query := db.Model(&models.Finding{})
+	if scanSessionID != nil {
+		query = query.Where("scan_session_id = ?", *scanSessionID)
+	}
+
+	var findings []models.Finding
+	if err := query.Order("id").Find(&findings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get findings: %w", err)
+	}
+
+	// the results findings are about, by id
+	var ids []uint
+	for _, finding := range findings {
+		if finding.ResultID != nil {
+			ids = appendUnique(ids, *finding.ResultID)
+		}
+	}
+	results := make(map[uint]models.Result)
+	if len(ids) > 0 {
+		var rows []models.Result
+		if err := db.Model(&models.Result{}).Select("id", "url", "ip_address").
+			Where("id IN ?", ids).Find(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to get results: %w", err)
+		}
+		for _, row := range rows {
+			results[row.ID] = row
+		}
+	}
+
+	view := make([]*Finding, 0, len(findings))
+	for _, finding := range findings {
+		f := &Finding{
+			ID:            finding.ID,
+			Title:         finding.Title,
+			Severity:      finding.Severity,
+			Description:   finding.Description,
+			Source:        finding.Source,
+			CVE:           finding.CVE,
+			CVSS:          finding.CVSS,
+			Date:          finding.CreatedAt,
+			Host:          finding.IPAddress,
+			IPAddress:     finding.IPAddress,
+			ScanSessionID: finding.ScanSessionID,
+		}
+		if finding.Domain != "" {
+			f.Host = finding.Domain
+		}
+
+		if finding.ResultID != nil {
+			if result, ok := results[*finding.ResultID]; ok {
+				f.URL = result.URL
+				if result.IPAddress != "" {
+					f.IPAddress = result.IPAddress
+				}
+				if u, err := url.Parse(result.URL); err == nil && u.Hostname() != "" {
+					f.Host = strings.ToLower(u.Hostname())
+					f.Scheme = u.Scheme
+					f.Port = urlPort(u)
+				}
+			}
+		}
+
+		view = append(view, f)
+	}
+
+	return view, nil
+}
+
+// urlPort returns the port of a URL, or the default port of its scheme
+func urlPort(u *url.URL) int {
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		return port
+	}
+
+	switch u.Scheme {
+	case "https":
+		return 443
+	case "http":
+		return 80
+	}
+
+	return 0
+}
+
+// FilterSeverity returns the findings at least as severe as minSeverity,
+// or all of them if it is empty
+func FilterSeverity(findings []*Finding, minSeverity string) []*Finding {
+	if minSeverity == "" {
+		return findings
+	}
+
+	least := slices.Index(plugins.Severities, strings.ToLower(minSeverity))
+	return slices.DeleteFunc(slices.Clone(findings), func(f *Finding) bool {
+		return slices.Index(plugins.Severities, f.Severity) < least
+	})
+}
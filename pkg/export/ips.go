@@ -0,0 +1,291 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/assets"
+	"github.com/sensepost/gowitness/pkg/cloud"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// IP is the joined view of everything known about an IP address. It
+// matches what the web interface shows for an IP.
+type IP struct {
+	IPAddress    string   `json:"ip_address"`
+	Organization string   `json:"organization"`
+	ISP          string   `json:"isp"`
+	ASN          string   `json:"asn"`
+	Country      string   `json:"country"`
+	CountryCode  string   `json:"country_code"`
+	City         string   `json:"city"`
+	OS           string   `json:"os"`
+	Cloud        string   `json:"cloud_provider"` // empty if self hosted
+	Ports        []IPPort `json:"ports"`
+	Domains      []string `json:"domains"`
+	ReverseDNS   []string `json:"reverse_dns"`
+	Vulns        []string `json:"vulns"`
+	Tags         []string `json:"tags"`
+	ScanSessions []uint   `json:"scan_sessions"`
+
+	// Asset is the imported asset the IP, or one of its domains, is
+	Asset *models.Asset `json:"asset,omitempty"`
+}
+
+// IPPort is an open port on an IP address
+type IPPort struct {
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service"`
+	State    string `json:"state"`
+	Banner   string `json:"banner"`
+	IsCDN    bool   `json:"is_cdn"`
+	CDNName  string `json:"cdn_name"`
+}
+
+// String returns the port as port/protocol, with the service if known
+func (p IPPort) String() string {
+	s := fmt.Sprintf("%d/%s", p.Port, p.Protocol)
+	if p.Service != "" {
+		s += " (" + p.Service + ")"
+	}
+
+	return s
+}
+
+// IPs builds the joined view of all IP addresses, sorted by address. If
+// scanSessionID is set, only data from that scan session is included.
+func IPs(db *gorm.DB, scanSessionID *uint) ([]*IP, error) {
+	scoped := func(q *gorm.DB) *gorm.DB {
+		if scanSessionID != nil {
+			return q.Where("scan_session_id = ?", *scanSessionID)
+		}
+		return q
+	}
+
+	ips := make(map[string]*IP)
+	get := func(address string) *IP {
+		ip, ok := ips[address]
+		if !ok {
+			ip = &IP{
+				IPAddress:    address,
+				Ports:        []IPPort{},
+				Domains:      []string{},
+				ReverseDNS:   []string{},
+				Vulns:        []string{},
+				Tags:         []string{},
+				ScanSessions: []uint{},
+			}
+			ips[address] = ip
+		}
+
+		return ip
+	}
+
+	var ports []models.IPPort
+	if err := scoped(db.Model(&models.IPPort{})).Order("ip_address, port").Find(&ports).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ports: %w", err)
+	}
+	for _, port := range ports {
+		ip := get(port.IPAddress)
+		ip.Ports = append(ip.Ports, IPPort{
+			Port:     port.Port,
+			Protocol: port.Protocol,
+			Service:  port.Service,
+			State:    port.State,
+			Banner:   port.Banner,
+			IsCDN:    port.IsCDN,
+			CDNName:  port.CDNName,
+		})
+		if port.OriginalHost != "" {
+			ip.Domains = appendUnique(ip.Domains, strings.ToLower(port.OriginalHost))
+		}
+		if port.ScanSessionID != nil {
+			ip.ScanSessions = appendUnique(ip.ScanSessions, *port.ScanSessionID)
+		}
+	}
+
+	var results []models.Result
+	if err := scoped(db.Model(&models.Result{})).Select("ip_address", "url", "scan_session_id").
+		Where("ip_address <> ''").Find(&results).Error; err != nil {
+		return nil, fmt.Errorf("failed to get results: %w", err)
+	}
+	for _, result := range results {
+		ip := get(result.IPAddress)
+		if u, err := url.Parse(result.URL); err == nil && u.Hostname() != "" && u.Hostname() != result.IPAddress {
+			ip.Domains = appendUnique(ip.Domains, strings.ToLower(u.Hostname()))
+		}
+		if result.ScanSessionID != nil {
+			ip.ScanSessions = appendUnique(ip.ScanSessions, *result.ScanSessionID)
+		}
+	}
+
+	// ip info is looked up for the addresses we have, unless there is no
+	// session filter, in which case every known ip is included
+	var infos []models.IPInfo
+	infoQuery := db.Model(&models.IPInfo{})
+	if scanSessionID != nil {
+		addresses := make([]string, 0, len(ips))
+		for address := range ips {
+			addresses = append(addresses, address)
+		}
+		infoQuery = infoQuery.Where("ip_address IN ? OR scan_session_id = ?", addresses, *scanSessionID)
+	}
+	if err := infoQuery.Find(&infos).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ip info: %w", err)
+	}
+	for _, info := range infos {
+		ip := get(info.IPAddress)
+		ip.Organization = info.Organization
+		ip.ISP = info.ISP
+		ip.ASN = info.ASN
+		ip.Country = info.Country
+		ip.CountryCode = info.CountryCode
+		ip.City = info.City
+		ip.OS = info.OS
+		ip.Cloud = info.CloudProvider
+
+		if vulns, err := info.GetVulns(); err == nil && vulns != nil {
+			ip.Vulns = vulns
+		}
+		if tags, err := info.GetTags(); err == nil && tags != nil {
+			ip.Tags = tags
+		}
+		if names, err := info.GetReverseDNS(); err == nil && names != nil {
+			ip.ReverseDNS = names
+		}
+		if info.ScanSessionID != nil {
+			ip.ScanSessions = appendUnique(ip.ScanSessions, *info.ScanSessionID)
+		}
+	}
+
+	inventory, err := assets.Load(db)
+	if err != nil {
+		return nil, err
+	}
+
+	view := make([]*IP, 0, len(ips))
+	for _, ip := range ips {
+		// the assets of its domains are more specific than a range it is in
+		for _, domain := range ip.Domains {
+			if ip.Asset = inventory.Match(domain, ""); ip.Asset != nil {
+				break
+			}
+		}
+		if ip.Asset == nil {
+			ip.Asset = inventory.Match(ip.IPAddress, ip.IPAddress)
+		}
+
+		// addresses without ip info are attributed with the ranges in use
+		if ip.Cloud == "" {
+			ip.Cloud = cloud.Provider(ip.IPAddress)
+		}
+		slices.Sort(ip.Domains)
+		slices.Sort(ip.Vulns)
+		slices.Sort(ip.ScanSessions)
+		view = append(view, ip)
+	}
+	slices.SortFunc(view, func(a, b *IP) int {
+		return strings.Compare(a.IPAddress, b.IPAddress)
+	})
+
+	return view, nil
+}
+
+// FilterHosting returns the IPs with a hosting: "cloud" for any cloud
+// provider, cloud.SelfHosted, or the name of a provider such as aws
+func FilterHosting(ips []*IP, hosting string) []*IP {
+	hosting = strings.ToLower(strings.TrimSpace(hosting))
+	if hosting == "" {
+		return ips
+	}
+
+	filtered := make([]*IP, 0, len(ips))
+	for _, ip := range ips {
+		switch {
+		case hosting == "cloud" && ip.Cloud != "",
+			hosting == cloud.SelfHosted && ip.Cloud == "",
+			hosting == ip.Cloud:
+			filtered = append(filtered, ip)
+		}
+	}
+
+	return filtered
+}
+
+// ValidHosting checks if hosting can be filtered by
+func ValidHosting(hosting string) bool {
+	hosting = strings.ToLower(strings.TrimSpace(hosting))
+	return hosting == "" || hosting == "cloud" || hosting == cloud.SelfHosted || slices.Contains(cloud.Providers, hosting)
+}
+
+// WriteIPsJSON writes the ip view as a JSON array
+func WriteIPsJSON(w io.Writer, ips []*IP) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(ips)
+}
+
+// WriteIPsCSV writes the ip view as CSV, one row per IP. Fields with
+// multiple values are separated by semicolons.
+func WriteIPsCSV(w io.Writer, ips []*IP) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{
+		"ip_address", "organization", "isp", "asn", "country", "country_code", "city", "os",
+		"cloud_provider", "open_ports", "cdn", "domains", "reverse_dns", "vulns", "tags", "scan_sessions",
+		"asset", "owner", "environment", "criticality",
+	}); err != nil {
+		return err
+	}
+
+	for _, ip := range ips {
+		ports := make([]string, 0, len(ip.Ports))
+		var cdns []string
+		for _, port := range ip.Ports {
+			ports = append(ports, port.String())
+			if port.IsCDN && port.CDNName != "" {
+				cdns = appendUnique(cdns, port.CDNName)
+			}
+		}
+
+		sessions := make([]string, 0, len(ip.ScanSessions))
+		for _, id := range ip.ScanSessions {
+			sessions = append(sessions, strconv.FormatUint(uint64(id), 10))
+		}
+
+		var asset models.Asset
+		if ip.Asset != nil {
+			asset = *ip.Asset
+		}
+
+		if err := writer.Write([]string{
+			ip.IPAddress, ip.Organization, ip.ISP, ip.ASN, ip.Country, ip.CountryCode, ip.City, ip.OS,
+			ip.Cloud, strings.Join(ports, ";"), strings.Join(cdns, ";"), strings.Join(ip.Domains, ";"),
+			strings.Join(ip.ReverseDNS, ";"), strings.Join(ip.Vulns, ";"), strings.Join(ip.Tags, ";"),
+			strings.Join(sessions, ";"), asset.Label, asset.Owner, asset.Environment, asset.Criticality,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// appendUnique appends v to s if s does not contain it yet
+func appendUnique[T comparable](s []T, v T) []T {
+	if slices.Contains(s, v) {
+		return s
+	}
+
+	return append(s, v)
+}
@@ -0,0 +1,223 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// stixNamespace is the namespace of the deterministic identifiers of STIX
+// cyber-observables, as set by the STIX 2.1 specification
+var stixNamespace = uuid.MustParse("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+// STIXBundle is a STIX 2.1 bundle of cyber-observables, and the
+// relationships between them
+type STIXBundle struct {
+	Type    string           `json:"type"`
+	ID      string           `json:"id"`
+	Objects []map[string]any `json:"objects"`
+}
+
+// stixBuilder collects the objects of a bundle, once each
+type stixBuilder struct {
+	objects []map[string]any
+	seen    map[string]bool
+	now     string
+}
+
+// add adds an object, unless an object with its id was added already, and
+// returns the id
+func (b *stixBuilder) add(object map[string]any) string {
+	id := object["id"].(string)
+	if !b.seen[id] {
+		b.seen[id] = true
+		b.objects = append(b.objects, object)
+	}
+
+	return id
+}
+
+// observable adds a cyber-observable, identified by its contributing
+// properties, and returns its id
+func (b *stixBuilder) observable(kind string, contributing map[string]any, properties map[string]any) string {
+	// encoding/json sorts map keys, which canonicalises these simple values
+	canonical, _ := json.Marshal(contributing)
+	object := map[string]any{
+		"type":         kind,
+		"spec_version": "2.1",
+		"id":           kind + "--" + uuid.NewSHA1(stixNamespace, canonical).String(),
+	}
+	for k, v := range contributing {
+		object[k] = v
+	}
+	for k, v := range properties {
+		object[k] = v
+	}
+
+	return b.add(object)
+}
+
+// relate adds a relationship between two objects
+func (b *stixBuilder) relate(source, kind, target string) {
+	id := uuid.NewSHA1(stixNamespace, []byte(source+"|"+kind+"|"+target))
+	b.add(map[string]any{
+		"type":              "relationship",
+		"spec_version":      "2.1",
+		"id":                "relationship--" + id.String(),
+		"created":           b.now,
+		"modified":          b.now,
+		"relationship_type": kind,
+		"source_ref":        source,
+		"target_ref":        target,
+	})
+}
+
+// address adds the observable of an IP address
+func (b *stixBuilder) address(ip string) string {
+	kind := "ipv4-addr"
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		kind = "ipv6-addr"
+	}
+
+	return b.observable(kind, map[string]any{"value": ip}, nil)
+}
+
+// domain adds the observable of a domain name
+func (b *stixBuilder) domain(name string) string {
+	return b.observable("domain-name", map[string]any{"value": strings.ToLower(name)}, nil)
+}
+
+// STIX builds a STIX 2.1 bundle of the domains, IP addresses, open ports
+// and certificates known to the database, for threat-intel platforms such
+// as OpenCTI. Domains resolve to their IP addresses, open ports are network
+// traffic to them, and certificates are related to the domains they were
+// served for. If scanSessionID is set, only data from that scan session is
+// included. Observables have deterministic ids, so exports of the same data
+// merge in the platform rather than duplicate.
+func STIX(db *gorm.DB, scanSessionID *uint) (*STIXBundle, error) {
+	scoped := func(q *gorm.DB) *gorm.DB {
+		if scanSessionID != nil {
+			return q.Where("scan_session_id = ?", *scanSessionID)
+		}
+		return q
+	}
+
+	b := &stixBuilder{seen: make(map[string]bool), now: time.Now().UTC().Format("2006-01-02T15:04:05.000Z")}
+
+	ips, err := IPs(db, scanSessionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		addressRef := b.address(ip.IPAddress)
+
+		for _, name := range ip.Domains {
+			b.relate(b.domain(name), "resolves-to", addressRef)
+		}
+
+		for _, port := range ip.Ports {
+			if port.State != "" && port.State != "open" {
+				continue
+			}
+			protocols := []string{"tcp"}
+			if port.Protocol != "" {
+				protocols = []string{strings.ToLower(port.Protocol)}
+			}
+			if port.Service != "" {
+				protocols = append(protocols, strings.ToLower(port.Service))
+			}
+			b.observable("network-traffic", map[string]any{
+				"dst_ref":   addressRef,
+				"dst_port":  port.Port,
+				"protocols": protocols,
+			}, nil)
+		}
+	}
+
+	var domains []string
+	if err := scoped(db.Model(&models.Domain{})).Where("historical = ?", false).
+		Distinct().Pluck("name", &domains).Error; err != nil {
+		return nil, fmt.Errorf("failed to get domains: %w", err)
+	}
+	for _, name := range domains {
+		b.domain(name)
+	}
+
+	var results []models.Result
+	if err := scoped(db.Model(&models.Result{})).Select("id", "url").
+		Preload("TLS.SanList").Find(&results).Error; err != nil {
+		return nil, fmt.Errorf("failed to get certificates: %w", err)
+	}
+	for _, result := range results {
+		tls := result.TLS
+		if tls.SubjectName == "" && tls.Issuer == "" {
+			continue
+		}
+
+		properties := map[string]any{
+			"subject": tls.SubjectName,
+			"issuer":  tls.Issuer,
+		}
+		if !tls.ValidFrom.IsZero() {
+			properties["validity_not_before"] = tls.ValidFrom.UTC().Format(time.RFC3339)
+		}
+		if !tls.ValidTo.IsZero() {
+			properties["validity_not_after"] = tls.ValidTo.UTC().Format(time.RFC3339)
+		}
+		// certificates are not stored with their hashes or serial numbers,
+		// which identify them in STIX, so what is stored identifies them
+		certificateRef := b.observable("x509-certificate", properties, nil)
+
+		names := []string{}
+		if u, err := url.Parse(result.URL); err == nil && u.Hostname() != "" && net.ParseIP(u.Hostname()) == nil {
+			names = append(names, u.Hostname())
+		}
+		for _, san := range tls.SanList {
+			// wildcards are not domain names
+			if !strings.Contains(san.Value, "*") && net.ParseIP(san.Value) == nil {
+				names = appendUnique(names, san.Value)
+			}
+		}
+		for _, name := range names {
+			b.relate(b.domain(name), "related-to", certificateRef)
+		}
+	}
+
+	// observables first, so that the bundle reads well
+	slices.SortStableFunc(b.objects, func(x, y map[string]any) int {
+		return strings.Compare(stixOrder(x), stixOrder(y))
+	})
+
+	return &STIXBundle{
+		Type:    "bundle",
+		ID:      "bundle--" + uuid.NewString(),
+		Objects: b.objects,
+	}, nil
+}
+
+// stixOrder returns the key an object is sorted by in a bundle
+func stixOrder(object map[string]any) string {
+	kind := object["type"].(string)
+	if kind == "relationship" {
+		return "~" + kind
+	}
+
+	return kind
+}
+
+// WriteSTIX writes a STIX bundle as JSON
+func WriteSTIX(w io.Writer, bundle *STIXBundle) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(bundle)
+}
@@ -0,0 +1,229 @@
+package export
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Kinds of target lists
+const (
+	// TargetURLs are live URLs, for tools such as nuclei and ffuf
+	TargetURLs = "urls"
+	// TargetIPPorts are ip:port pairs of open ports, for tools such as nmap
+	TargetIPPorts = "ip-ports"
+	// TargetHostnames are hostnames, for tools such as amass
+	TargetHostnames = "hostnames"
+)
+
+// TargetKinds are all of the target list kinds
+var TargetKinds = []string{TargetURLs, TargetIPPorts, TargetHostnames}
+
+// TargetOptions filter a target list
+type TargetOptions struct {
+	// Kind is the kind of target list to build
+	Kind string
+	// ScanSessionID limits targets to a scan session
+	ScanSessionID *uint
+	// Tag limits targets to IP addresses with this IP information tag
+	Tag string
+	// Status limits URLs to these response codes. See ParseStatus.
+	Status *StatusFilter
+	// IncludeFailed includes URLs that failed to load
+	IncludeFailed bool
+}
+
+// StatusFilter matches HTTP response codes, either exactly or by class
+type StatusFilter struct {
+	codes   []int
+	classes []int
+}
+
+// ParseStatus parses a comma separated list of response codes and classes,
+// such as "200,301,4xx"
+func ParseStatus(s string) (*StatusFilter, error) {
+	filter := &StatusFilter{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+
+		if len(part) == 3 && strings.HasSuffix(part, "xx") && part[0] >= '1' && part[0] <= '5' {
+			filter.classes = append(filter.classes, int(part[0]-'0'))
+			continue
+		}
+
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status %q. use codes such as 200 or classes such as 2xx", part)
+		}
+		filter.codes = append(filter.codes, code)
+	}
+
+	if len(filter.codes) == 0 && len(filter.classes) == 0 {
+		return nil, errors.New("no statuses given")
+	}
+
+	return filter, nil
+}
+
+// Match checks if a response code matches the filter
+func (f *StatusFilter) Match(code int) bool {
+	return slices.Contains(f.codes, code) || slices.Contains(f.classes, code/100)
+}
+
+// Targets builds a sorted, de-duplicated target list
+func Targets(db *gorm.DB, opts TargetOptions) ([]string, error) {
+	scoped := func(q *gorm.DB) *gorm.DB {
+		if opts.ScanSessionID != nil {
+			return q.Where("scan_session_id = ?", *opts.ScanSessionID)
+		}
+		return q
+	}
+
+	// tagged is the set of addresses with the tag. nil means no tag filter.
+	var tagged map[string]bool
+	if opts.Tag != "" {
+		var err error
+		if tagged, err = taggedIPs(db, opts.Tag); err != nil {
+			return nil, err
+		}
+	}
+	allowed := func(ip string) bool {
+		return tagged == nil || tagged[ip]
+	}
+
+	var targets []string
+	switch opts.Kind {
+	case TargetURLs:
+		var results []models.Result
+		q := scoped(db.Model(&models.Result{})).Select("url", "ip_address", "response_code", "failed")
+		if !opts.IncludeFailed {
+			q = q.Where("failed = ?", false)
+		}
+		if err := q.Find(&results).Error; err != nil {
+			return nil, fmt.Errorf("failed to get results: %w", err)
+		}
+
+		for _, result := range results {
+			if !allowed(result.IPAddress) {
+				continue
+			}
+			if opts.Status != nil && !opts.Status.Match(result.ResponseCode) {
+				continue
+			}
+			targets = append(targets, result.URL)
+		}
+
+	case TargetIPPorts:
+		var ports []models.IPPort
+		if err := scoped(db.Model(&models.IPPort{})).Select("ip_address", "port").
+			Where("state = ? OR state = ''", "open").Find(&ports).Error; err != nil {
+			return nil, fmt.Errorf("failed to get ports: %w", err)
+		}
+
+		for _, port := range ports {
+			if !allowed(port.IPAddress) {
+				continue
+			}
+			targets = append(targets, net.JoinHostPort(port.IPAddress, strconv.Itoa(port.Port)))
+		}
+
+	case TargetHostnames:
+		add := func(hostname string) {
+			hostname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+			if hostname != "" && net.ParseIP(hostname) == nil {
+				targets = append(targets, hostname)
+			}
+		}
+
+		var results []models.Result
+		if err := scoped(db.Model(&models.Result{})).Select("url", "ip_address").Find(&results).Error; err != nil {
+			return nil, fmt.Errorf("failed to get results: %w", err)
+		}
+		for _, result := range results {
+			if !allowed(result.IPAddress) {
+				continue
+			}
+			if u, err := url.Parse(result.URL); err == nil {
+				add(u.Hostname())
+			}
+		}
+
+		var ports []models.IPPort
+		if err := scoped(db.Model(&models.IPPort{})).Select("ip_address", "original_host").
+			Where("original_host <> ''").Find(&ports).Error; err != nil {
+			return nil, fmt.Errorf("failed to get ports: %w", err)
+		}
+		for _, port := range ports {
+			if allowed(port.IPAddress) {
+				add(port.OriginalHost)
+			}
+		}
+
+		// domains are not linked to an address, so a tag filter skips them
+		if tagged == nil {
+			var domains []models.Domain
+			if err := scoped(db.Model(&models.Domain{})).Select("name").
+				Where("historical = ?", false).Find(&domains).Error; err != nil {
+				return nil, fmt.Errorf("failed to get domains: %w", err)
+			}
+			for _, domain := range domains {
+				add(domain.Name)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("invalid target kind %q. valid kinds are: %s", opts.Kind, strings.Join(TargetKinds, ", "))
+	}
+
+	slices.Sort(targets)
+	return slices.Compact(targets), nil
+}
+
+// taggedIPs returns the addresses whose IP information has a tag
+func taggedIPs(db *gorm.DB, tag string) (map[string]bool, error) {
+	var infos []models.IPInfo
+	if err := db.Model(&models.IPInfo{}).Select("ip_address", "tags").
+		Where("tags LIKE ?", "%"+tag+"%").Find(&infos).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ip info: %w", err)
+	}
+
+	tagged := make(map[string]bool)
+	for _, info := range infos {
+		tags, err := info.GetTags()
+		if err != nil {
+			continue
+		}
+		for _, t := range tags {
+			if strings.EqualFold(t, tag) {
+				tagged[info.IPAddress] = true
+				break
+			}
+		}
+	}
+
+	return tagged, nil
+}
+
+// WriteTargets writes a target list, one target per line
+func WriteTargets(w io.Writer, targets []string) error {
+	writer := bufio.NewWriter(w)
+	for _, target := range targets {
+		if _, err := writer.WriteString(target + "\n"); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// taxiiMediaType is the media type of TAXII 2.1 requests and responses
+const taxiiMediaType = "application/taxii+json;version=2.1"
+
+// taxiiBatch is the most objects pushed to a TAXII collection at once,
+// as servers limit the size of requests
+const taxiiBatch = 500
+
+// taxiiStatus is the status resource a TAXII server returns for a push
+type taxiiStatus struct {
+	Status       string `json:"status"`
+	SuccessCount int    `json:"success_count"`
+	FailureCount int    `json:"failure_count"`
+	PendingCount int    `json:"pending_count"`
+}
+
+// PushTAXII adds the objects of a bundle to a TAXII 2.1 collection, whose
+// URL is that of the collection, e.g.
+// https://taxii.example.com/api1/collections/{id}/. The server is
+// authenticated with HTTP basic auth if user is set. It returns the number
+// of objects the server accepted.
+func PushTAXII(collectionURL, user, password string, bundle *STIXBundle) (int, error) {
+	endpoint := strings.TrimSuffix(collectionURL, "/") + "/objects/"
+	client := islazy.NewHTTPClient(60 * time.Second)
+
+	var accepted int
+	for start := 0; start < len(bundle.Objects); start += taxiiBatch {
+		end := min(start+taxiiBatch, len(bundle.Objects))
+
+		body, err := json.Marshal(map[string]any{"objects": bundle.Objects[start:end]})
+		if err != nil {
+			return accepted, fmt.Errorf("failed to marshal objects: %w", err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return accepted, err
+		}
+		req.Header.Set("Content-Type", taxiiMediaType)
+		req.Header.Set("Accept", taxiiMediaType)
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return accepted, fmt.Errorf("failed to push to TAXII collection: %w", err)
+		}
+		response, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return accepted, fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return accepted, fmt.Errorf("TAXII server error (status %d): %s", resp.StatusCode, string(response))
+		}
+
+		var status taxiiStatus
+		if err := json.Unmarshal(response, &status); err != nil {
+			return accepted, fmt.Errorf("failed to parse TAXII status: %w", err)
+		}
+		if status.FailureCount > 0 {
+			return accepted + status.SuccessCount, fmt.Errorf("TAXII server rejected %d objects", status.FailureCount)
+		}
+		// objects pending are accepted, and added once the server is done
+		accepted += status.SuccessCount + status.PendingCount
+	}
+
+	return accepted, nil
+}
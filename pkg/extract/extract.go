@@ -0,0 +1,247 @@
+// Package extract pulls API endpoints, third-party domains and strings that
+// look like secrets out of the HTML and captured network responses of a
+// result.
+package extract
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// Kinds of extracted URLs
+const (
+	// KindEndpoint is a path or URL on the result's own site, such as an
+	// API route referenced by a script
+	KindEndpoint = "endpoint"
+	// KindThirdParty is a host outside the result's own domain
+	KindThirdParty = "third-party"
+)
+
+// Sources extracted data is found in
+const (
+	SourceHTML    = "html"
+	SourceNetwork = "network"
+	SourceScript  = "script"
+)
+
+// maxContentSize is how much of a document is searched
+const maxContentSize = 2 << 20
+
+// maxPerResult caps how many URLs and secrets are kept per result, so a
+// minified bundle can't flood the database
+const maxPerResult = 500
+
+var (
+	// quoted absolute URLs and root relative paths, after LinkFinder
+	quotedURLRe = regexp.MustCompile(`["'\x60]((?:https?:)?//[a-zA-Z0-9.\-]+(?::\d+)?[^"'\x60\s<>]*|/[a-zA-Z0-9_\-][^"'\x60\s<>]*)["'\x60]`)
+	// paths that look like API routes
+	apiPathRe = regexp.MustCompile(`(?i)(^|/)(api|rest|graphql|v[0-9]+|services?|rpc|oauth2?|auth|internal|admin)(/|$|\?)|\.(json|php|aspx?|jsp|action|do)(\?|$)`)
+	// static assets, which are not interesting as endpoints
+	staticRe = regexp.MustCompile(`(?i)\.(css|js|mjs|map|png|jpe?g|gif|svg|ico|webp|woff2?|ttf|eot|otf|mp4|webm)(\?|$)`)
+)
+
+// secretPattern is a kind of secret and how to recognise it
+type secretPattern struct {
+	Kind string
+	Re   *regexp.Regexp
+	// Group is the submatch holding the secret, 0 for the whole match
+	Group int
+}
+
+// secretPatterns are secrets that are recognisable by their format
+var secretPatterns = []secretPattern{
+	{Kind: "aws-access-key", Re: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`), Group: 1},
+	{Kind: "google-api-key", Re: regexp.MustCompile(`\b(AIza[0-9A-Za-z_\-]{35})\b`), Group: 1},
+	{Kind: "github-token", Re: regexp.MustCompile(`\b(gh[pousr]_[0-9A-Za-z]{36,255})\b`), Group: 1},
+	{Kind: "slack-token", Re: regexp.MustCompile(`\b(xox[abposr]-[0-9A-Za-z\-]{10,72})\b`), Group: 1},
+	{Kind: "slack-webhook", Re: regexp.MustCompile(`https://hooks\.slack\.com/services/T[0-9A-Za-z]+/B[0-9A-Za-z]+/[0-9A-Za-z]+`)},
+	{Kind: "stripe-key", Re: regexp.MustCompile(`\b((?:sk|rk)_live_[0-9A-Za-z]{24,99})\b`), Group: 1},
+	{Kind: "sendgrid-key", Re: regexp.MustCompile(`\b(SG\.[0-9A-Za-z_\-]{22}\.[0-9A-Za-z_\-]{43})\b`), Group: 1},
+	{Kind: "jwt", Re: regexp.MustCompile(`\b(eyJ[0-9A-Za-z_\-]{8,}\.eyJ[0-9A-Za-z_\-]{8,}\.[0-9A-Za-z_\-]{8,})\b`), Group: 1},
+	{Kind: "private-key", Re: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP )?PRIVATE KEY( BLOCK)?-----`)},
+	{
+		Kind:  "generic-secret",
+		Re:    regexp.MustCompile(`(?i)["']?(?:api[_-]?key|api[_-]?secret|access[_-]?token|auth[_-]?token|client[_-]?secret|secret[_-]?key)["']?\s*[:=]\s*["']([0-9A-Za-z_\-./+=]{16,128})["']`),
+		Group: 1,
+	},
+}
+
+// Analyze returns the endpoints, third-party domains and secrets referenced
+// by a result's HTML and the content of its captured network responses
+func Analyze(result *models.Result) ([]models.ExtractedURL, []models.Secret) {
+	target := result.FinalURL
+	if target == "" {
+		target = result.URL
+	}
+	base, err := url.Parse(target)
+	if err != nil || base.Host == "" {
+		return nil, nil
+	}
+
+	e := &extractor{
+		base: base,
+		urls: make(map[string]models.ExtractedURL),
+		seen: make(map[string]bool),
+	}
+	if net.ParseIP(base.Hostname()) == nil {
+		e.apex = islazy.ApexDomain(base.Hostname())
+	}
+
+	e.document(result.HTML, target, SourceHTML)
+
+	for _, entry := range result.Network {
+		e.network(entry.URL)
+
+		if len(entry.Content) > 0 && isText(entry.MIMEType) {
+			source := SourceNetwork
+			if strings.Contains(strings.ToLower(entry.MIMEType), "javascript") {
+				source = SourceScript
+			}
+			e.document(string(entry.Content), entry.URL, source)
+		}
+	}
+
+	urls := make([]models.ExtractedURL, 0, len(e.urls))
+	for _, u := range e.urls {
+		urls = append(urls, u)
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		if urls[i].Kind != urls[j].Kind {
+			return urls[i].Kind < urls[j].Kind
+		}
+		return urls[i].URL < urls[j].URL
+	})
+
+	return urls, e.secrets
+}
+
+// extractor collects what is extracted from the documents of a result
+type extractor struct {
+	base    *url.URL
+	apex    string
+	urls    map[string]models.ExtractedURL
+	secrets []models.Secret
+	seen    map[string]bool
+}
+
+// document extracts from a document found at location
+func (e *extractor) document(content string, location string, source string) {
+	if content == "" {
+		return
+	}
+	if len(content) > maxContentSize {
+		content = content[:maxContentSize]
+	}
+
+	for _, match := range quotedURLRe.FindAllStringSubmatch(content, -1) {
+		e.reference(match[1], source)
+	}
+
+	for _, pattern := range secretPatterns {
+		for _, match := range pattern.Re.FindAllStringSubmatch(content, -1) {
+			value := match[pattern.Group]
+			key := pattern.Kind + "|" + value
+			if e.seen[key] || len(e.secrets) >= maxPerResult {
+				continue
+			}
+			e.seen[key] = true
+
+			e.secrets = append(e.secrets, models.Secret{
+				Kind:     pattern.Kind,
+				Value:    value,
+				Source:   source,
+				Location: location,
+			})
+		}
+	}
+}
+
+// reference records a URL referenced in a document
+func (e *extractor) reference(raw string, source string) {
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return
+	}
+	resolved := e.base.ResolveReference(ref)
+	if resolved.Hostname() == "" {
+		return
+	}
+
+	if !e.ownHost(resolved.Hostname()) {
+		e.thirdParty(resolved.Hostname(), source)
+		return
+	}
+
+	if staticRe.MatchString(resolved.Path) || !apiPathRe.MatchString(resolved.Path) {
+		return
+	}
+
+	resolved.Fragment = ""
+	e.add(models.ExtractedURL{
+		Kind:   KindEndpoint,
+		URL:    resolved.String(),
+		Host:   resolved.Hostname(),
+		Source: source,
+	})
+}
+
+// network records the host of a request the page made
+func (e *extractor) network(raw string) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+
+	if !e.ownHost(u.Hostname()) {
+		e.thirdParty(u.Hostname(), SourceNetwork)
+	}
+}
+
+// thirdParty records a host outside of the result's domain
+func (e *extractor) thirdParty(host string, source string) {
+	host = strings.ToLower(host)
+	e.add(models.ExtractedURL{
+		Kind:   KindThirdParty,
+		URL:    host,
+		Host:   host,
+		Source: source,
+	})
+}
+
+// add records an extracted URL, keeping the first source it was seen in
+func (e *extractor) add(u models.ExtractedURL) {
+	key := u.Kind + "|" + u.URL
+	if _, ok := e.urls[key]; ok || len(e.urls) >= maxPerResult {
+		return
+	}
+
+	e.urls[key] = u
+}
+
+// ownHost checks if a host is on the result's own domain
+func (e *extractor) ownHost(host string) bool {
+	host = strings.ToLower(host)
+	if strings.EqualFold(host, e.base.Hostname()) {
+		return true
+	}
+
+	return e.apex != "" && net.ParseIP(host) == nil && islazy.ApexDomain(host) == e.apex
+}
+
+// isText checks if a MIME type is a text type worth searching
+func isText(mimeType string) bool {
+	mimeType = strings.ToLower(mimeType)
+	for _, t := range []string{"javascript", "json", "html", "text/", "xml"} {
+		if strings.Contains(mimeType, t) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,162 @@
+// Package fallback gathers information on IP addresses Shodan has none
+// on: geolocation and ownership from IP-API, and open ports from a naabu
+// scan of the top 100 ports.
+package fallback
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/metrics"
+)
+
+var lookups = metrics.NewCounter("gowitness_fallback_lookups_total",
+	"Fallback IP intelligence lookups, by source (ip-api or naabu) and outcome", "source", "outcome")
+
+// observe records the outcome of a lookup from a source
+func observe(source string, err error) {
+	if err != nil {
+		lookups.Inc(source, "error")
+		return
+	}
+
+	lookups.Inc(source, "ok")
+}
+
+// IPAPIResponse represents response from ip-api.com
+type IPAPIResponse struct {
+	Query       string  `json:"query"`
+	Status      string  `json:"status"`
+	Country     string  `json:"country"`
+	CountryCode string  `json:"countryCode"`
+	Region      string  `json:"region"`
+	RegionName  string  `json:"regionName"`
+	City        string  `json:"city"`
+	Zip         string  `json:"zip"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	Timezone    string  `json:"timezone"`
+	ISP         string  `json:"isp"`
+	Org         string  `json:"org"`
+	AS          string  `json:"as"`
+	Message     string  `json:"message,omitempty"`
+}
+
+// naabuResult represents naabu port scan result
+type naabuResult struct {
+	Host string `json:"host"`
+	IP   string `json:"ip"`
+	Port int    `json:"port"`
+}
+
+// FetchIPAPI fetches geolocation data for an IP from ip-api.com
+func FetchIPAPI(ip string) (*IPAPIResponse, error) {
+	response, err := fetchIPAPI(ip)
+	observe("ip-api", err)
+
+	return response, err
+}
+
+// fetchIPAPI queries ip-api.com
+func fetchIPAPI(ip string) (*IPAPIResponse, error) {
+	url := fmt.Sprintf("http://ip-api.com/json/%s?fields=status,message,country,countryCode,region,regionName,city,zip,lat,lon,timezone,isp,org,as,query", ip)
+
+	client := islazy.NewHTTPClient(10 * time.Second)
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from IP-API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IP-API response: %w", err)
+	}
+
+	var ipApiResp IPAPIResponse
+	if err := json.Unmarshal(body, &ipApiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse IP-API response: %w", err)
+	}
+
+	if ipApiResp.Status == "fail" {
+		return nil, fmt.Errorf("IP-API error: %s", ipApiResp.Message)
+	}
+
+	return &ipApiResp, nil
+}
+
+// NaabuScan runs the naabu port scanner against the top 100 ports of an IP,
+// returning the open ports
+func NaabuScan(ctx context.Context, ip string) ([]int, error) {
+	ports, err := naabuScan(ctx, ip)
+	observe("naabu", err)
+
+	return ports, err
+}
+
+// naabuScan runs naabu
+func naabuScan(ctx context.Context, ip string) ([]int, error) {
+	// Check if naabu is available
+	if _, err := exec.LookPath("naabu"); err != nil {
+		return nil, fmt.Errorf("naabu not found: %w", err)
+	}
+
+	// Run naabu with top 100 ports and JSON output
+	cmd := exec.CommandContext(ctx, "naabu", "-host", ip, "-top-ports", "100", "-json", "-silent")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("naabu execution failed: %w", err)
+	}
+
+	// Parse naabu output (JSON lines)
+	ports := []int{}
+	lines := strings.Split(string(output), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var result naabuResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			log.Warn("failed to parse naabu line", "line", line, "err", err)
+			continue
+		}
+
+		if result.IP == ip {
+			ports = append(ports, result.Port)
+		}
+	}
+
+	return ports, nil
+}
+
+// Gather fetches IP-API data for an IP, and runs a naabu scan for its open
+// ports if scanPorts is set. A failed naabu scan is logged, not returned,
+// as IP-API data is useful on its own.
+func Gather(ctx context.Context, ip string, scanPorts bool) (*IPAPIResponse, []int, error) {
+	log.Info("attempting fallback IP intelligence gathering", "ip", ip)
+
+	ipApiData, err := FetchIPAPI(ip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ports []int
+	if scanPorts {
+		if ports, err = NaabuScan(ctx, ip); err != nil {
+			log.Warn("failed to run naabu scan", "ip", ip, "err", err)
+		} else {
+			log.Info("naabu scan completed", "ip", ip, "ports_found", len(ports))
+		}
+	}
+
+	return ipApiData, ports, nil
+}
@@ -0,0 +1,76 @@
+// Package gowitness is the programmatic interface to gowitness, for Go
+// programs that embed it as a library. A Client screenshots URLs, port scans
+// and enriches hosts, and runs the complete scan workflow of 'scan run',
+// writing everything it finds to a gowitness database.
+//
+//	client, err := gowitness.Open(slog.Default(), "sqlite://gowitness.sqlite3")
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//
+//	results, err := client.Screenshot(ctx, []string{"example.com"}, gowitness.ScreenshotOptions{})
+package gowitness
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"github.com/sensepost/gowitness/pkg/scope"
+	"github.com/sensepost/gowitness/pkg/shodan"
+	"gorm.io/gorm"
+)
+
+// Client scans targets, writing the results to a database
+type Client struct {
+	// DB is the database results are written to
+	DB *gorm.DB
+	// Shodan queries the Shodan API when enriching IPs, if it is set, such
+	// as with shodan.InitFromEnv. Without it, only InternetDB and the
+	// fallback methods are used.
+	Shodan *shodan.Client
+	// Scope leaves out of scope targets out of every scan, if it is set
+	Scope *scope.Scope
+	// Plugins enrich results and IP information once they are saved, if it
+	// is set
+	Plugins *plugins.Manager
+
+	uri    string
+	logger *slog.Logger
+}
+
+// Open returns a client writing to the database at uri, such as
+// sqlite://gowitness.sqlite3, which is created and migrated if needed.
+// logger may be nil to use slog's default logger.
+func Open(logger *slog.Logger, uri string) (*Client, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	db, err := database.Connection(uri, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return &Client{
+		DB:     db,
+		uri:    uri,
+		logger: logger,
+	}, nil
+}
+
+// Close closes the database and the plugins of the client
+func (c *Client) Close() error {
+	if c.Plugins != nil {
+		c.Plugins.Close()
+	}
+
+	conn, err := c.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
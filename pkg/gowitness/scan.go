@@ -0,0 +1,139 @@
+package gowitness
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/enrich"
+	"github.com/sensepost/gowitness/pkg/naabu"
+	"github.com/sensepost/gowitness/pkg/portprofile"
+)
+
+// PortScanOptions configure PortScan
+type PortScanOptions struct {
+	naabu.Options
+	// Policy sets the ports of every target with port profiles. Without
+	// it, every target is scanned with the ports of Options.
+	Policy        *portprofile.Policy
+	ScanSessionID *uint
+}
+
+// PortScan scans the ports of targets, which are hostnames, IPs or CIDRs,
+// with naabu, saving the open ports to the database as they are found. The
+// ports found before naabu fails or ctx is done are kept.
+func (c *Client) PortScan(ctx context.Context, targets []string, opts PortScanOptions) (naabu.Summary, error) {
+	if !naabu.Installed() {
+		return naabu.Summary{}, errors.New("naabu is not installed")
+	}
+
+	targets, skipped := c.Scope.Filter(targets)
+	if skipped > 0 {
+		c.logger.Warn("skipping out of scope targets", "count", skipped)
+	}
+	if len(targets) == 0 {
+		return naabu.Summary{}, nil
+	}
+
+	file, err := naabu.WriteTargets("targets", targets)
+	if err != nil {
+		return naabu.Summary{}, err
+	}
+	defer os.Remove(file)
+
+	saver := naabu.NewSaver(c.logger, c.DB, nil)
+	saver.ScanSessionID = opts.ScanSessionID
+
+	scanner := naabu.NewScanner(c.logger, opts.Options)
+	if opts.Policy != nil {
+		scanner.Policy = opts.Policy
+	}
+
+	err = scanner.Scan(ctx, file, saver)
+	return saver.Summary(), err
+}
+
+// EnrichOptions configure Enrich
+type EnrichOptions struct {
+	// RateLimit is the most Shodan API queries a minute, 60 if it is 0
+	RateLimit int
+	// Threads is the number of IPs enriched at once, 4 if it is 0
+	Threads int
+	// Full queries complete Shodan host records, and stores the services
+	// seen on each port
+	Full bool
+	// SkipInternetDB queries the Shodan API without trying InternetDB
+	SkipInternetDB bool
+	// Refresh queries IPs that were enriched before again, if their
+	// information is older than MaxAge
+	Refresh bool
+	MaxAge  time.Duration
+	// ASNs and CIDRs are enumerated for IPs to enrich as well
+	ASNs  []string
+	CIDRs []string
+	// MaxIPs is the most IPs enumerated from ASNs and CIDRs, 0 for no limit
+	MaxIPs int
+	// Resolvers resolve hostnames, the system resolver if it is empty
+	Resolvers     []string
+	ScanSessionID *uint
+}
+
+// Enrich resolves hosts, which are hostnames, IPs or URLs, and enriches
+// their IPs with Shodan, falling back to IP-API and naabu. The information
+// and the open ports that were found are saved to the database. IPs that
+// were enriched before are skipped. If ctx is done, the IPs being enriched
+// are finished and the rest left alone.
+func (c *Client) Enrich(ctx context.Context, hosts []string, opts EnrichOptions) (enrich.Summary, error) {
+	rateLimit := opts.RateLimit
+	if rateLimit <= 0 {
+		rateLimit = 60
+	}
+	threads := opts.Threads
+	if threads <= 0 {
+		threads = 4
+	}
+
+	enricher := enrich.New(c.logger, c.Shodan, rateLimit)
+	enricher.DB = c.DB
+	enricher.Full = opts.Full
+	enricher.Refresh = opts.Refresh
+	enricher.MaxAge = opts.MaxAge
+	enricher.Threads = threads
+	enricher.ScanSessionID = opts.ScanSessionID
+	enricher.Plugins = c.Plugins
+	if providers := append(enrich.Registered(), enrich.PluginProviders(c.Plugins)...); len(providers) > 0 {
+		enricher.Providers = enrich.NewProviders(c.logger, providers)
+	}
+	if opts.SkipInternetDB {
+		enricher.InternetDB = nil
+	}
+
+	var scanSessionID uint
+	if opts.ScanSessionID != nil {
+		scanSessionID = *opts.ScanSessionID
+	}
+	apexDomains, err := enrich.ApexDomains(c.DB, scanSessionID)
+	if err != nil {
+		c.logger.Warn("failed to get apex domains of scan session", "err", err)
+	}
+	enricher.ApexDomains = apexDomains
+
+	ips, unverified, err := enricher.Collect(enrich.Targets{
+		Hosts:     hosts,
+		ASNs:      opts.ASNs,
+		CIDRs:     opts.CIDRs,
+		MaxIPs:    opts.MaxIPs,
+		Resolvers: opts.Resolvers,
+		Scope:     c.Scope,
+	})
+	if err != nil {
+		return enrich.Summary{}, err
+	}
+	enricher.Unverified = unverified
+
+	var summary enrich.Summary
+	enricher.EnrichAll(ctx, ips, summary.Add)
+
+	return summary, nil
+}
@@ -0,0 +1,103 @@
+package gowitness
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/readers"
+	"github.com/sensepost/gowitness/pkg/runner"
+	driver "github.com/sensepost/gowitness/pkg/runner/drivers"
+	"github.com/sensepost/gowitness/pkg/writers"
+)
+
+// ScreenshotOptions configure Screenshot
+type ScreenshotOptions struct {
+	// Options configure the browser and the probes. runner.NewDefaultOptions
+	// is used if it is nil, which does not save screenshots to disk unless
+	// Scan.ScreenshotPath is set.
+	Options *runner.Options
+	// Reader sets the URLs probed for a target. Targets without a scheme
+	// are probed over both http and https by default.
+	Reader readers.FileReaderOptions
+	// RateLimit is the most URLs to start probing per second, 0 for no limit
+	RateLimit int
+	// OnFailure is called for every URL that failed, if it is set
+	OnFailure func(target string, err error)
+}
+
+// Screenshot probes and screenshots targets, which are URLs, hostnames or
+// IPs, saving the results to the database. The results are returned too.
+// URLs that were not started yet when ctx is done are skipped.
+func (c *Client) Screenshot(ctx context.Context, targets []string, opts ScreenshotOptions) ([]*models.Result, error) {
+	options := runner.NewDefaultOptions()
+	if opts.Options != nil {
+		options = opts.Options
+	}
+
+	var urls []string
+	for _, url := range readers.NewFileReader(&opts.Reader).URLs(targets) {
+		if err := c.Scope.CheckURL(url); err != nil {
+			c.logger.Debug("skipping out of scope url", "url", url, "err", err)
+			continue
+		}
+		urls = append(urls, url)
+	}
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	dbWriter, err := writers.NewDbWriter(c.uri, false)
+	if err != nil {
+		return nil, err
+	}
+	dbWriter.Plugins = c.Plugins
+
+	scanDriver, err := newDriver(c.logger, *options)
+	if err != nil {
+		return nil, err
+	}
+
+	collector := &resultCollector{}
+	err = runner.Probe(ctx, c.logger, scanDriver, *options, urls, opts.RateLimit,
+		[]writers.Writer{dbWriter, collector}, opts.OnFailure)
+
+	return collector.results, err
+}
+
+// newDriver starts the scan driver of options
+func newDriver(logger *slog.Logger, options runner.Options) (runner.Driver, error) {
+	switch options.Scan.Driver {
+	case "gorod":
+		d, err := driver.NewGorod(logger, options)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	case "chromedp", "":
+		d, err := driver.NewChromedp(logger, options)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	default:
+		return nil, errors.New("invalid scan driver chosen")
+	}
+}
+
+// resultCollector keeps the results of a probe
+type resultCollector struct {
+	mu      sync.Mutex
+	results []*models.Result
+}
+
+func (rc *resultCollector) Write(result *models.Result) error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.results = append(rc.results, result)
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package gowitness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// The phases of a workflow, as recorded on a failed scan session
+const (
+	PhaseTargets     = "Targets"
+	PhaseEnrich      = "Shodan Intelligence"
+	PhaseScreenshots = "Screenshot Collection"
+)
+
+// Workflow is a complete scan of a project, as 'scan run' does. Its IPs
+// are enriched first, and then every target is screenshotted.
+type Workflow struct {
+	// Targets are domains, IPs and CIDRs. Their domains are recorded as
+	// manually added domains, and every domain the database knows is
+	// scanned along with them.
+	Targets []string
+	// ScanSessionID is the scan session that is marked as completed, or
+	// failed in a phase, once the workflow ends, if it is set
+	ScanSessionID *uint
+
+	SkipEnrich      bool
+	SkipScreenshots bool
+	// ProbeDiscoveredPorts screenshots http and https on the open ports
+	// the database knows, for each hostname of their IP, as well
+	ProbeDiscoveredPorts bool
+
+	Enrich     EnrichOptions
+	Screenshot ScreenshotOptions
+
+	// OnPhase is called as each phase starts, if it is set
+	OnPhase func(phase string)
+}
+
+// Run runs a workflow. If ctx is done, the phase that is running stops and
+// the scan session is marked as cancelled.
+func (c *Client) Run(ctx context.Context, workflow Workflow) error {
+	phase := func(name string) {
+		c.logger.Info("starting scan phase", "phase", name)
+		if workflow.OnPhase != nil {
+			workflow.OnPhase(name)
+		}
+	}
+
+	phase(PhaseTargets)
+	targets, err := ProjectTargets(c.DB, workflow.Targets, workflow.ScanSessionID)
+	if err != nil {
+		return c.finish(workflow, PhaseTargets, err)
+	}
+
+	if !workflow.SkipEnrich {
+		phase(PhaseEnrich)
+
+		options := workflow.Enrich
+		if options.ScanSessionID == nil {
+			options.ScanSessionID = workflow.ScanSessionID
+		}
+		if _, err := c.Enrich(ctx, targets, options); err != nil {
+			return c.finish(workflow, PhaseEnrich, err)
+		}
+		if ctx.Err() != nil {
+			return c.finish(workflow, PhaseEnrich, ctx.Err())
+		}
+	}
+
+	if !workflow.SkipScreenshots {
+		phase(PhaseScreenshots)
+
+		if workflow.ProbeDiscoveredPorts {
+			urls, err := database.PortURLs(c.DB)
+			if err != nil {
+				return c.finish(workflow, PhaseScreenshots, fmt.Errorf("failed to get discovered ports: %w", err))
+			}
+			targets = append(targets, urls...)
+		}
+
+		if _, err := c.Screenshot(ctx, targets, workflow.Screenshot); err != nil {
+			return c.finish(workflow, PhaseScreenshots, err)
+		}
+		if ctx.Err() != nil {
+			return c.finish(workflow, PhaseScreenshots, ctx.Err())
+		}
+	}
+
+	return c.finish(workflow, "", nil)
+}
+
+// finish marks the scan session of a workflow as completed, cancelled or
+// failed in phase, depending on err, and returns err
+func (c *Client) finish(workflow Workflow, phase string, err error) error {
+	if workflow.ScanSessionID == nil {
+		return err
+	}
+	id := *workflow.ScanSessionID
+
+	var statusErr error
+	switch {
+	case err == nil:
+		statusErr = database.CompleteSession(c.DB, id)
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		statusErr = database.CancelSession(c.DB, id, phase)
+	default:
+		statusErr = database.FailSession(c.DB, id, phase, err.Error())
+	}
+	if statusErr != nil {
+		c.logger.Warn("could not update scan session status", "session-id", id, "err", statusErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("scan phase '%s' failed: %w", phase, err)
+	}
+
+	return nil
+}
+
+// ProjectTargets records the hostnames among targets as manually added
+// domains, and returns every domain the database knows along with the IPs
+// and CIDRs of targets. Empty lines and lines starting with # are ignored.
+func ProjectTargets(db *gorm.DB, targets []string, scanSessionID *uint) ([]string, error) {
+	var addresses []string
+	for _, line := range targets {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		target := line
+		if !strings.Contains(target, "://") {
+			target = "http://" + target
+		}
+		u, err := url.Parse(target)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+
+		host := strings.ToLower(u.Hostname())
+		if net.ParseIP(host) != nil {
+			addresses = append(addresses, line)
+			continue
+		}
+
+		if err := database.SaveDomain(db, &models.Domain{
+			Name:          host,
+			Source:        models.DomainSourceManual,
+			ScanSessionID: scanSessionID,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to record domain %s: %w", host, err)
+		}
+	}
+
+	domains, err := database.KnownDomains(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project domains: %w", err)
+	}
+
+	targets = append(domains, addresses...)
+	if len(targets) == 0 {
+		return nil, errors.New("the project has no domains to scan")
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,197 @@
+// Package headers grades the security headers and cookie flags of probed
+// responses.
+package headers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// Kinds of security issues
+const (
+	// KindMissingHSTS is an HTTPS response without Strict-Transport-Security
+	KindMissingHSTS = "missing-hsts"
+	// KindWeakHSTS is a Strict-Transport-Security max-age shorter than
+	// MinHSTSMaxAge
+	KindWeakHSTS = "weak-hsts"
+	// KindMissingCSP is a response without a Content-Security-Policy
+	KindMissingCSP = "missing-csp"
+	// KindUnsafeCSP is a Content-Security-Policy that allows inline or
+	// eval'd scripts
+	KindUnsafeCSP = "unsafe-csp"
+	// KindMissingFrameOptions is a response that may be framed, as it has
+	// neither X-Frame-Options nor a CSP frame-ancestors directive
+	KindMissingFrameOptions = "missing-frame-options"
+	// KindMissingNoSniff is a response without X-Content-Type-Options: nosniff
+	KindMissingNoSniff = "missing-nosniff"
+	// KindMissingReferrerPolicy is a response without a Referrer-Policy
+	KindMissingReferrerPolicy = "missing-referrer-policy"
+	// KindInsecureCookie is a cookie set over HTTPS without the Secure flag
+	KindInsecureCookie = "insecure-cookie"
+	// KindScriptableCookie is a cookie without the HttpOnly flag
+	KindScriptableCookie = "scriptable-cookie"
+)
+
+// MinHSTSMaxAge is the shortest HSTS max-age that is not weak, 180 days
+const MinHSTSMaxAge = 180 * 24 * 60 * 60
+
+// MaxCookiePenalty caps the points cookie issues cost a result, so that a
+// response setting many cookies is not graded on cookies alone
+const MaxCookiePenalty = 20
+
+// maxAgeRe matches the max-age directive of a Strict-Transport-Security header
+var maxAgeRe = regexp.MustCompile(`(?i)max-age\s*=\s*"?(\d+)`)
+
+// Report is the security grade of a result
+type Report struct {
+	Score  int
+	Grade  string
+	Issues []models.SecurityIssue
+}
+
+// Analyze grades the security headers and cookies of a result. Scores start
+// at 100, and every issue deducts its penalty.
+func Analyze(result *models.Result) *Report {
+	report := &Report{Score: 100}
+	add := func(kind, severity string, penalty int, detail string) {
+		report.Issues = append(report.Issues, models.SecurityIssue{
+			Kind:     kind,
+			Severity: severity,
+			Detail:   detail,
+			Penalty:  penalty,
+		})
+		report.Score -= penalty
+	}
+
+	headers := make(map[string]string)
+	for _, header := range result.Headers {
+		headers[strings.ToLower(header.Key)] = header.Value
+	}
+
+	https := isHTTPS(result)
+
+	if https {
+		if hsts, ok := headers["strict-transport-security"]; !ok {
+			add(KindMissingHSTS, "medium", 20, "no Strict-Transport-Security header")
+		} else if maxAge := hstsMaxAge(hsts); maxAge < MinHSTSMaxAge {
+			add(KindWeakHSTS, "low", 5, fmt.Sprintf("Strict-Transport-Security max-age is %d seconds", maxAge))
+		}
+	}
+
+	csp, hasCSP := headers["content-security-policy"]
+	if !hasCSP {
+		add(KindMissingCSP, "medium", 20, "no Content-Security-Policy header")
+	} else if directive := unsafeDirective(csp); directive != "" {
+		add(KindUnsafeCSP, "low", 5, fmt.Sprintf("Content-Security-Policy allows %s", directive))
+	}
+
+	if _, ok := headers["x-frame-options"]; !ok && !strings.Contains(strings.ToLower(csp), "frame-ancestors") {
+		add(KindMissingFrameOptions, "medium", 15, "no X-Frame-Options header or CSP frame-ancestors directive")
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(headers["x-content-type-options"]), "nosniff") {
+		add(KindMissingNoSniff, "low", 10, "no X-Content-Type-Options: nosniff header")
+	}
+
+	if _, ok := headers["referrer-policy"]; !ok {
+		add(KindMissingReferrerPolicy, "info", 5, "no Referrer-Policy header")
+	}
+
+	cookiePenalty := 0
+	for _, cookie := range result.Cookies {
+		if https && !cookie.Secure {
+			penalty := min(5, MaxCookiePenalty-cookiePenalty)
+			cookiePenalty += penalty
+			add(KindInsecureCookie, "low", penalty, fmt.Sprintf("cookie %s is not Secure", cookie.Name))
+		}
+		if !cookie.HTTPOnly {
+			penalty := min(5, MaxCookiePenalty-cookiePenalty)
+			cookiePenalty += penalty
+			add(KindScriptableCookie, "low", penalty, fmt.Sprintf("cookie %s is not HttpOnly", cookie.Name))
+		}
+	}
+
+	report.Score = max(report.Score, 0)
+	report.Grade = Grade(report.Score)
+
+	return report
+}
+
+// Grade returns the letter grade of a security score
+func Grade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// isHTTPS checks if a result was served over HTTPS, after redirects
+func isHTTPS(result *models.Result) bool {
+	url := result.FinalURL
+	if url == "" {
+		url = result.URL
+	}
+
+	return strings.HasPrefix(strings.ToLower(url), "https://")
+}
+
+// hstsMaxAge returns the max-age of a Strict-Transport-Security header, or
+// 0 if it has none
+func hstsMaxAge(value string) int {
+	match := maxAgeRe.FindStringSubmatch(value)
+	if match == nil {
+		return 0
+	}
+
+	maxAge, err := strconv.Atoi(match[1])
+	if err != nil {
+		// too large to parse is long enough
+		return MinHSTSMaxAge
+	}
+
+	return maxAge
+}
+
+// unsafeDirective returns the unsafe source a CSP allows scripts from, if
+// scripts are governed by script-src, or by default-src without it
+func unsafeDirective(csp string) string {
+	var scriptSrc, defaultSrc string
+	for _, directive := range strings.Split(csp, ";") {
+		fields := strings.Fields(strings.ToLower(directive))
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "script-src":
+			scriptSrc = directive
+		case "default-src":
+			defaultSrc = directive
+		}
+	}
+
+	sources := scriptSrc
+	if sources == "" {
+		sources = defaultSrc
+	}
+	sources = strings.ToLower(sources)
+
+	for _, unsafe := range []string{"'unsafe-inline'", "'unsafe-eval'"} {
+		if strings.Contains(sources, unsafe) {
+			return unsafe
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// MaxErrors is the most failures recorded against a job. Failures past it
+// are only counted.
+const MaxErrors = 100
+
+// Job is a claimed job, that its progress is reported through. Progress is
+// counted in the database, so that remote workers can report it
+// concurrently.
+type Job struct {
+	*models.Job
+
+	queue *Queue
+}
+
+// newJob wraps a claimed job
+func newJob(queue *Queue, model *models.Job) *Job {
+	return &Job{Job: model, queue: queue}
+}
+
+// Decode decodes the payload of the job into v
+func (j *Job) Decode(v any) error {
+	if err := json.Unmarshal([]byte(j.Payload), v); err != nil {
+		return fmt.Errorf("failed to decode job payload: %w", err)
+	}
+
+	return nil
+}
+
+// SetTotal sets how many items the job works through
+func (j *Job) SetTotal(total int) {
+	j.Total = total
+	j.update(map[string]any{"total": total})
+}
+
+// Complete counts an item that was done
+func (j *Job) Complete() {
+	j.update(map[string]any{"completed": gorm.Expr("completed + 1")})
+}
+
+// Fail counts an item, such as a URL, that failed
+func (j *Job) Fail(item string, failure error) {
+	jobItemFailures.Inc(j.Kind)
+
+	if err := j.queue.db.Transaction(func(tx *gorm.DB) error {
+		var current models.Job
+		if err := tx.Select("id", "errors").First(&current, j.ID).Error; err != nil {
+			return err
+		}
+
+		jobErrors, err := current.GetErrors()
+		if err != nil {
+			return err
+		}
+		if len(jobErrors) < MaxErrors {
+			jobErrors = append(jobErrors, models.JobError{URL: item, Error: failure.Error()})
+			if err := current.SetErrors(jobErrors); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.Job{}).Where("id = ?", j.ID).
+			Updates(map[string]any{"failed": gorm.Expr("failed + 1"), "errors": current.Errors}).Error
+	}); err != nil {
+		log.Error("failed to update job", "id", j.ID, "err", err)
+	}
+}
+
+// Write counts a written result, so that jobs can be passed to runners as
+// the last of their writers
+func (j *Job) Write(result *models.Result) error {
+	j.Complete()
+	return nil
+}
+
+// Finish records the outcome of an attempt. Failed attempts are retried
+// after a backoff if the job has attempts left. Jobs that are no longer
+// running, such as those of remote workers whose lease expired, are left
+// as they are.
+func (j *Job) Finish(err error) {
+	retry := err != nil && j.Attempts < j.MaxAttempts
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		j.Status = models.JobCompleted
+		j.Error = ""
+		j.FinishedAt = &now
+	case retry:
+		j.Status = models.JobQueued
+		j.Error = err.Error()
+		j.RunAt = now.Add(j.queue.Backoff * time.Duration(1<<(j.Attempts-1)))
+	default:
+		j.Status = models.JobFailed
+		j.Error = err.Error()
+		j.FinishedAt = &now
+	}
+	result := j.queue.db.Model(&models.Job{}).Where("id = ? AND status = ?", j.ID, models.JobRunning).
+		Updates(map[string]any{"status": j.Status, "error": j.Error, "run_at": j.RunAt, "finished_at": j.FinishedAt})
+	if result.Error != nil {
+		log.Error("failed to update job", "id", j.ID, "err", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	switch {
+	case err == nil:
+		observeFinish(j.Job, outcomeCompleted)
+	case retry:
+		observeFinish(j.Job, outcomeRetried)
+	default:
+		observeFinish(j.Job, outcomeFailed)
+	}
+
+	if err != nil {
+		log.Error("job failed", "id", j.ID, "kind", j.Kind, "worker", j.Worker, "attempt", j.Attempts,
+			"retry", retry, "err", err)
+		return
+	}
+	log.Info("job completed", "id", j.ID, "kind", j.Kind, "worker", j.Worker)
+}
+
+// update updates columns of the job
+func (j *Job) update(columns map[string]any) {
+	if err := j.queue.db.Model(&models.Job{}).Where("id = ?", j.ID).Updates(columns).Error; err != nil {
+		log.Error("failed to update job", "id", j.ID, "err", err)
+	}
+}
@@ -0,0 +1,37 @@
+package jobs
+
+import "github.com/sensepost/gowitness/pkg/runner"
+
+// Job kinds, that the server and agents both run
+const (
+	KindProbe    = "probe"
+	KindRetake   = "retake"
+	KindIPEnrich = "ip-enrich"
+)
+
+// Kinds are all job kinds
+var Kinds = []string{KindProbe, KindRetake, KindIPEnrich}
+
+// Probe is the payload of a job probing URLs
+type Probe struct {
+	URLs    []string       `json:"urls"`
+	Options runner.Options `json:"options"`
+	// RateLimit is the most URLs to start probing per second, 0 for no limit
+	RateLimit int `json:"rate_limit"`
+}
+
+// Retake is the payload of a job retaking the screenshot of a result
+type Retake struct {
+	ResultID      uint           `json:"result_id"`
+	URL           string         `json:"url"`
+	ScanSessionID *uint          `json:"scan_session_id,omitempty"`
+	Options       runner.Options `json:"options"`
+}
+
+// IPEnrich is the payload of a job gathering fallback information for an
+// IP address without Shodan information
+type IPEnrich struct {
+	IPAddress string `json:"ip_address"`
+	// ScanPorts runs a port scan too, for IP addresses without known ports
+	ScanPorts bool `json:"scan_ports"`
+}
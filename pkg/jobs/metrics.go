@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/metrics"
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+var (
+	jobDuration = metrics.NewHistogram("gowitness_job_duration_seconds",
+		"How long job attempts ran, by kind and outcome", nil, "kind", "outcome")
+	jobItemFailures = metrics.NewCounter("gowitness_job_item_failures_total",
+		"Items of jobs, such as URLs, that failed, by kind", "kind")
+	jobsQueued = metrics.NewGaugeFunc("gowitness_jobs",
+		"Jobs in the queue, by status", "status")
+)
+
+// Outcomes of job attempts, as recorded in metrics
+const (
+	outcomeCompleted = "completed"
+	outcomeRetried   = "retried"
+	outcomeFailed    = "failed"
+)
+
+// observeFinish records a finished attempt of a job
+func observeFinish(job *models.Job, outcome string) {
+	duration := 0.0
+	if job.StartedAt != nil {
+		duration = time.Since(*job.StartedAt).Seconds()
+	}
+
+	jobDuration.Observe(duration, job.Kind, outcome)
+}
+
+// depth counts the queued and running jobs, for the queue depth gauge
+func (q *Queue) depth() map[string]float64 {
+	var counts []struct {
+		Status string
+		Count  int64
+	}
+	if err := q.db.Model(&models.Job{}).
+		Select("status, count(*) AS count").
+		Where("status IN ?", []string{models.JobQueued, models.JobRunning}).
+		Group("status").Scan(&counts).Error; err != nil {
+		log.Error("failed to count jobs", "err", err)
+		return nil
+	}
+
+	depth := map[string]float64{models.JobQueued: 0, models.JobRunning: 0}
+	for _, count := range counts {
+		depth[count.Status] = float64(count.Count)
+	}
+
+	return depth
+}
@@ -0,0 +1,418 @@
+// Package jobs is a database backed job queue.
+//
+// Work that runs in the background, such as probing URLs submitted through
+// the API, is enqueued as a job of a kind, with a JSON payload. Workers
+// claim queued jobs by priority, highest first, and run the handler
+// registered for their kind. A handler that returns an error is retried
+// with an exponential backoff until the job runs out of attempts.
+//
+// Jobs may also be claimed by remote workers, such as agents, that report
+// their progress back and send heartbeats while they run a job. Kinds that
+// are registered as remote are left to them. Jobs of remote workers that
+// stop sending heartbeats are failed, and retried if they have attempts
+// left.
+//
+// As jobs live in the database, they survive restarts. Jobs that were
+// running locally when the queue stopped are queued again once it starts.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultMaxAttempts is how often a job is attempted by default
+	DefaultMaxAttempts = 3
+	// DefaultPollInterval is how often idle workers look for jobs
+	DefaultPollInterval = 2 * time.Second
+	// DefaultBackoff is how long a failed job waits before its first retry.
+	// It doubles with every attempt.
+	DefaultBackoff = 30 * time.Second
+	// DefaultLease is how long a remote worker may go without a heartbeat
+	// before its job is failed
+	DefaultLease = 5 * time.Minute
+
+	// LocalWorker is the worker name of jobs run by the queue itself
+	LocalWorker = "local"
+)
+
+var (
+	// ErrUnknownKind is returned when enqueuing a job without a handler
+	ErrUnknownKind = errors.New("no handler registered for job kind")
+	// ErrNotClaimed is returned for jobs a remote worker has not claimed, or
+	// that are no longer running
+	ErrNotClaimed = errors.New("job is not running for this worker")
+)
+
+// Handler runs a job. Returning an error fails the attempt.
+type Handler func(ctx context.Context, job *Job) error
+
+// Options are the options of an enqueued job
+type Options struct {
+	// Priority orders queued jobs, highest first
+	Priority int
+	// MaxAttempts defaults to DefaultMaxAttempts
+	MaxAttempts int
+	// RunAt delays the job until then
+	RunAt time.Time
+	// ScanSessionID is the scan session the job is for
+	ScanSessionID *uint
+	// Unique skips enqueuing if an unfinished job of the same kind and
+	// payload exists, returning that job instead
+	Unique bool
+}
+
+// Queue is a job queue
+type Queue struct {
+	// PollInterval is how often idle workers look for jobs
+	PollInterval time.Duration
+	// Backoff is how long a failed job waits before its first retry
+	Backoff time.Duration
+	// Lease is how long a remote worker may go without a heartbeat
+	Lease time.Duration
+
+	db       *gorm.DB
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	remote   map[string]bool
+	wake     chan struct{}
+}
+
+// NewQueue returns a new job queue on a database
+func NewQueue(db *gorm.DB) *Queue {
+	return &Queue{
+		PollInterval: DefaultPollInterval,
+		Backoff:      DefaultBackoff,
+		Lease:        DefaultLease,
+		db:           db,
+		handlers:     make(map[string]Handler),
+		remote:       make(map[string]bool),
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Register registers the handler for a job kind. Handlers should be
+// registered before the queue is started.
+func (q *Queue) Register(kind string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.handlers[kind] = handler
+	delete(q.remote, kind)
+}
+
+// RegisterRemote registers a job kind that is only run by remote workers
+func (q *Queue) RegisterRemote(kind string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.remote[kind] = true
+	delete(q.handlers, kind)
+}
+
+// localKinds returns the job kinds with a handler
+func (q *Queue) localKinds() []string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	return kinds
+}
+
+// known checks if a job kind was registered, with a handler or as remote
+func (q *Queue) known(kind string) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	_, ok := q.handlers[kind]
+	return ok || q.remote[kind]
+}
+
+// handler returns the handler of a job kind
+func (q *Queue) handler(kind string) (Handler, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	handler, ok := q.handlers[kind]
+	return handler, ok
+}
+
+// Enqueue adds a job of a kind to the queue, with payload as its JSON
+// encoded arguments
+func (q *Queue) Enqueue(kind string, payload any, opts Options) (*models.Job, error) {
+	if !q.known(kind) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	if opts.Unique {
+		var existing models.Job
+		err := q.db.Where("kind = ? AND payload = ? AND status IN ?",
+			kind, string(data), []string{models.JobQueued, models.JobRunning}).First(&existing).Error
+		if err == nil {
+			return &existing, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to check for existing job: %w", err)
+		}
+	}
+
+	job := &models.Job{
+		Kind:          kind,
+		Status:        models.JobQueued,
+		Priority:      opts.Priority,
+		Payload:       string(data),
+		MaxAttempts:   opts.MaxAttempts,
+		RunAt:         opts.RunAt,
+		ScanSessionID: opts.ScanSessionID,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+
+	if err := q.db.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	// wake an idle worker, if there is one
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// Start queues jobs that were interrupted again, and starts workers that
+// run jobs until ctx is done
+func (q *Queue) Start(ctx context.Context, workers int) error {
+	if err := q.requeueInterrupted(); err != nil {
+		return err
+	}
+
+	for range max(workers, 1) {
+		go q.work(ctx)
+	}
+	go q.expireLeases(ctx)
+	jobsQueued.SetCollector(q.depth)
+
+	return nil
+}
+
+// requeueInterrupted queues jobs that were running locally when the queue
+// last stopped again, or fails them if they are out of attempts
+func (q *Queue) requeueInterrupted() error {
+	// jobs claimed before workers were recorded ran locally
+	localWorkers := []string{LocalWorker, ""}
+
+	if err := q.db.Model(&models.Job{}).
+		Where("status = ? AND (worker IN ? OR worker IS NULL) AND attempts >= max_attempts", models.JobRunning, localWorkers).
+		Updates(map[string]any{"status": models.JobFailed, "error": "interrupted", "finished_at": time.Now()}).Error; err != nil {
+		return fmt.Errorf("failed to fail interrupted jobs: %w", err)
+	}
+
+	result := q.db.Model(&models.Job{}).
+		Where("status = ? AND (worker IN ? OR worker IS NULL)", models.JobRunning, localWorkers).
+		Updates(map[string]any{"status": models.JobQueued, "error": "interrupted"})
+	if result.Error != nil {
+		return fmt.Errorf("failed to requeue interrupted jobs: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Info("requeued interrupted jobs", "count", result.RowsAffected)
+	}
+
+	return nil
+}
+
+// expireLeases fails the jobs of remote workers that stopped sending
+// heartbeats, until ctx is done
+func (q *Queue) expireLeases(ctx context.Context) {
+	ticker := time.NewTicker(q.Lease / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var expired []models.Job
+		if err := q.db.Where("status = ? AND worker != ? AND heartbeat_at < ?",
+			models.JobRunning, LocalWorker, time.Now().Add(-q.Lease)).Find(&expired).Error; err != nil {
+			log.Error("failed to get expired jobs", "err", err)
+			continue
+		}
+
+		for i := range expired {
+			newJob(q, &expired[i]).Finish(fmt.Errorf("worker %s stopped sending heartbeats", expired[i].Worker))
+		}
+	}
+}
+
+// work claims and runs jobs until ctx is done
+func (q *Queue) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := q.Claim(LocalWorker, q.localKinds())
+		if err != nil {
+			log.Error("failed to claim job", "err", err)
+		}
+		if job != nil {
+			q.run(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-q.wake:
+		case <-time.After(q.PollInterval):
+		}
+	}
+}
+
+// Claim marks the next job of one of kinds that is due as running for a
+// worker and returns it, or nil if there is none
+func (q *Queue) Claim(worker string, kinds []string) (*models.Job, error) {
+	var claimable []string
+	for _, kind := range kinds {
+		if q.known(kind) {
+			claimable = append(claimable, kind)
+		}
+	}
+	if len(claimable) == 0 {
+		return nil, nil
+	}
+
+	var job models.Job
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("status = ? AND run_at <= ? AND kind IN ?", models.JobQueued, time.Now(), claimable).
+			Order("priority DESC, id").First(&job).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		result := tx.Model(&models.Job{}).Where("id = ? AND status = ?", job.ID, models.JobQueued).
+			Updates(map[string]any{"status": models.JobRunning, "attempts": gorm.Expr("attempts + 1"),
+				"worker": worker, "started_at": now, "heartbeat_at": now, "completed": 0, "failed": 0, "errors": ""})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// another worker got to it first
+			return gorm.ErrRecordNotFound
+		}
+
+		// progress starts over on every attempt
+		job.Status = models.JobRunning
+		job.Attempts++
+		job.Worker = worker
+		job.StartedAt = &now
+		job.HeartbeatAt = &now
+		job.Completed, job.Failed, job.Errors = 0, 0, ""
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Remote returns a job a remote worker claimed, to report its progress
+// through. Getting it counts as a heartbeat.
+func (q *Queue) Remote(id uint, worker string) (*Job, error) {
+	var job models.Job
+	if err := q.db.Where("status = ? AND worker = ?", models.JobRunning, worker).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotClaimed
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	job.HeartbeatAt = &now
+	if err := q.db.Model(&models.Job{}).Where("id = ?", job.ID).Update("heartbeat_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	return newJob(q, &job), nil
+}
+
+// run runs a claimed job and records how it went
+func (q *Queue) run(ctx context.Context, model *models.Job) {
+	job := newJob(q, model)
+	handler, ok := q.handler(model.Kind)
+	if !ok {
+		job.Finish(fmt.Errorf("%w: %s", ErrUnknownKind, model.Kind))
+		return
+	}
+
+	log.Info("running job", "id", model.ID, "kind", model.Kind, "attempt", model.Attempts)
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+
+		return handler(ctx, job)
+	}()
+
+	job.Finish(err)
+}
+
+// Get returns a job
+func (q *Queue) Get(id uint) (*models.Job, error) {
+	var job models.Job
+	if err := q.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Wait waits for a job to complete or fail, returning it once it did
+func (q *Queue) Wait(ctx context.Context, id uint) (*models.Job, error) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		job, err := q.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status == models.JobCompleted || job.Status == models.JobFailed {
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
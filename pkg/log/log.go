@@ -1,12 +1,20 @@
 package log
 
 import (
+	"fmt"
+	"io"
 	"os"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
 )
 
+// Formats logs can be written in
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
 // LLogger is a charmbracelet logger type redefinition
 type LLogger = log.Logger
 
@@ -31,6 +39,48 @@ func EnableDebug() {
 	Logger.SetReportCaller(true)
 }
 
+// SetLevel sets the least severe level that is logged. The debug level also
+// enables caller reporting.
+func SetLevel(level string) error {
+	l, err := log.ParseLevel(level)
+	if err != nil || l == log.FatalLevel {
+		return fmt.Errorf("invalid log level %q, valid levels are: debug, info, warn, error", level)
+	}
+
+	Logger.SetLevel(l)
+	Logger.SetReportCaller(l == log.DebugLevel)
+
+	return nil
+}
+
+// SetFormat sets the format logs are written in, text or json
+func SetFormat(format string) error {
+	switch format {
+	case FormatText:
+		Logger.SetFormatter(log.TextFormatter)
+	case FormatJSON:
+		Logger.SetFormatter(log.JSONFormatter)
+	default:
+		return fmt.Errorf("invalid log format %q, valid formats are: text, json", format)
+	}
+
+	return nil
+}
+
+// SetOutput sets where logs are written, such as through a progress
+// display. Logs keep the colours they would have on stderr.
+func SetOutput(w io.Writer) {
+	profile := lipgloss.NewRenderer(os.Stderr).ColorProfile()
+
+	Logger.SetOutput(w)
+	Logger.SetColorProfile(profile)
+}
+
+// DebugEnabled checks if debug messages are logged
+func DebugEnabled() bool {
+	return Logger.GetLevel() <= log.DebugLevel
+}
+
 // EnableSilence will silence most logs, except this written with Print
 func EnableSilence() {
 	Logger.SetLevel(log.FatalLevel + 100)
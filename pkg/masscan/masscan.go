@@ -0,0 +1,94 @@
+// Package masscan reads the JSON output of masscan.
+package masscan
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Port is an open port masscan found
+type Port struct {
+	IP       string
+	Port     int
+	Protocol string
+	Status   string
+	Service  string // set if masscan grabbed a banner
+	Banner   string
+}
+
+// record is a line of masscan -oJ output. A port shows up once when it is
+// found, and again for each banner grabbed from it.
+type record struct {
+	IP    string `json:"ip"`
+	Ports []struct {
+		Port    int    `json:"port"`
+		Proto   string `json:"proto"`
+		Status  string `json:"status"`
+		Service struct {
+			Name   string `json:"name"`
+			Banner string `json:"banner"`
+		} `json:"service"`
+	} `json:"ports"`
+}
+
+// Parse reads masscan -oJ output. masscan writes one record per line
+// between the brackets of an array, with commas before or after records
+// depending on its version, so the lines are read one by one instead of as
+// a single document. Records for the same port are merged.
+func Parse(r io.Reader) ([]Port, error) {
+	var ports []Port
+	index := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.Trim(strings.TrimSpace(scanner.Text()), ",")
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		var rec record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			// e.g. the {finished: 1} trailer of older versions
+			continue
+		}
+		if rec.IP == "" {
+			continue
+		}
+
+		for _, p := range rec.Ports {
+			protocol := p.Proto
+			if protocol == "" {
+				protocol = "tcp"
+			}
+
+			key := fmt.Sprintf("%s/%d/%s", rec.IP, p.Port, protocol)
+			i, ok := index[key]
+			if !ok {
+				i = len(ports)
+				index[key] = i
+				ports = append(ports, Port{IP: rec.IP, Port: p.Port, Protocol: protocol, Status: "open"})
+			}
+
+			if p.Status != "" {
+				ports[i].Status = p.Status
+			}
+			if p.Service.Name != "" && ports[i].Service == "" {
+				ports[i].Service = p.Service.Name
+			}
+			if p.Service.Banner != "" && ports[i].Banner == "" {
+				ports[i].Banner = p.Service.Banner
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read masscan output: %w", err)
+	}
+
+	return ports, nil
+}
@@ -0,0 +1,369 @@
+// Package metrics collects counters, gauges and histograms and exposes
+// them in the Prometheus text exposition format, so that long running
+// servers and scans can be scraped by Prometheus.
+//
+// Metrics are registered with the Default registry as package variables,
+// and are always collected. They are only exposed when a command serves
+// the registry's Handler.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of duration histograms
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600}
+
+// Default is the registry metrics are registered with
+var Default = NewRegistry()
+
+// metric is a registered metric
+type metric interface {
+	// describe returns the name, help and type of the metric
+	describe() (name, help, kind string)
+	// write writes the samples of the metric
+	write(w io.Writer)
+}
+
+// Registry is a set of metrics
+type Registry struct {
+	mu      sync.RWMutex
+	metrics []metric
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// register adds a metric to the registry. Registering a name twice is a
+// programming error, and panics.
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name, _, _ := m.describe()
+	for _, existing := range r.metrics {
+		if existingName, _, _ := existing.describe(); existingName == name {
+			panic(fmt.Sprintf("metric %s registered twice", name))
+		}
+	}
+
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in the text exposition format
+func (r *Registry) Write(w io.Writer) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, m := range r.metrics {
+		name, help, kind := m.describe()
+		fmt.Fprintf(w, "# HELP %s %s\n", name, strings.ReplaceAll(help, "\n", " "))
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+		m.write(w)
+	}
+}
+
+// Handler returns a handler serving the registry
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// Handler returns a handler serving the Default registry
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// desc is the description shared by all metric types
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// key joins label values into a map key, checking their count
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", d.name, len(d.labels), len(values)))
+	}
+
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs formats label values, with extra pairs appended, as
+// {name="value",...}
+func (d *desc) labelPairs(key string, extra ...string) string {
+	var pairs []string
+	if len(d.labels) > 0 {
+		for i, value := range strings.Split(key, "\xff") {
+			pairs = append(pairs, d.labels[i]+`="`+escape(value)+`"`)
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escape(extra[i+1])+`"`)
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escape escapes a label value
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat formats a sample value
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of a map of samples in order, so that
+// scrapes list samples consistently
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// Counter is a value that only goes up, per set of label values
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter registers a counter with the Default registry
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]float64)}
+	Default.register(c)
+
+	return c
+}
+
+// Inc adds one to the counter of the label values
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds v, which must not be negative, to the counter of the label
+// values
+func (c *Counter) Add(v float64, values ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(values)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] += v
+}
+
+func (c *Counter) describe() (string, string, string) {
+	return c.name, c.help, "counter"
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(key), formatFloat(c.values[key]))
+	}
+}
+
+// Gauge is a value that goes up and down, per set of label values
+type Gauge struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewGauge registers a gauge with the Default registry
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]float64)}
+	Default.register(g)
+
+	return g
+}
+
+// Set sets the gauge of the label values
+func (g *Gauge) Set(v float64, values ...string) {
+	key := g.key(values)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[key] = v
+}
+
+// Add adds v to the gauge of the label values
+func (g *Gauge) Add(v float64, values ...string) {
+	key := g.key(values)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[key] += v
+}
+
+func (g *Gauge) describe() (string, string, string) {
+	return g.name, g.help, "gauge"
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, key := range sortedKeys(g.values) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, g.labelPairs(key), formatFloat(g.values[key]))
+	}
+}
+
+// GaugeFunc is a gauge whose values are collected when scraped, such as
+// the depth of a queue. Collect returns the values by the value of its
+// single label, or by "" without a label.
+type GaugeFunc struct {
+	desc
+	mu      sync.Mutex
+	collect func() map[string]float64
+}
+
+// NewGaugeFunc registers a gauge collected by a function with the Default
+// registry. The function is set with SetCollector, as it usually depends
+// on state, like a database, that only exists once a command runs.
+func NewGaugeFunc(name, help string, label string) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name: name, help: help}}
+	if label != "" {
+		g.labels = []string{label}
+	}
+	Default.register(g)
+
+	return g
+}
+
+// SetCollector sets the function the gauge's values are collected with
+func (g *GaugeFunc) SetCollector(collect func() map[string]float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.collect = collect
+}
+
+func (g *GaugeFunc) describe() (string, string, string) {
+	return g.name, g.help, "gauge"
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	g.mu.Lock()
+	collect := g.collect
+	g.mu.Unlock()
+
+	if collect == nil {
+		return
+	}
+
+	values := collect()
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, g.labelPairs(key), formatFloat(values[key]))
+	}
+}
+
+// Histogram counts observations, such as durations, in buckets, per set
+// of label values
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+// histogramValue are the observations of a set of label values
+type histogramValue struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with the Default registry. Buckets
+// are the upper bounds of its buckets, DefaultBuckets if nil.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	h := &Histogram{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+	}
+	Default.register(h)
+
+	return h
+}
+
+// Observe records an observation for the label values
+func (h *Histogram) Observe(v float64, values ...string) {
+	key := h.key(values)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	value, ok := h.values[key]
+	if !ok {
+		value = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = value
+	}
+
+	if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+		value.counts[i]++
+	}
+	value.count++
+	value.sum += v
+}
+
+func (h *Histogram) describe() (string, string, string) {
+	return h.name, h.help, "histogram"
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, key := range sortedKeys(h.values) {
+		value := h.values[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += value.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(key, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(key, "le", "+Inf"), value.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(key), formatFloat(value.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(key), value.count)
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"github.com/sensepost/gowitness/pkg/cloud"
+	"gorm.io/gorm"
+)
+
+// BeforeSave attributes the address to its cloud provider, if that was not
+// done yet
+func (ip *IPInfo) BeforeSave(tx *gorm.DB) error {
+	if ip.CloudProvider == "" {
+		ip.CloudProvider = cloud.Provider(ip.IPAddress)
+	}
+
+	return nil
+}
+
+// BeforeSave attributes the address to its cloud provider, if that was not
+// done yet
+func (port *IPPort) BeforeSave(tx *gorm.DB) error {
+	if port.CloudProvider == "" {
+		port.CloudProvider = cloud.Provider(port.IPAddress)
+	}
+
+	return nil
+}
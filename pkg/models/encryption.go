@@ -0,0 +1,301 @@
+package models
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// encryptedPrefix marks column values encrypted by the encrypted serializer
+const encryptedPrefix = "enc:v1:"
+
+var (
+	// ErrEncryptionKeyRequired is returned when reading an encrypted value
+	// without an encryption key
+	ErrEncryptionKeyRequired = errors.New("value is encrypted, a database key is required to read it")
+	// ErrDecryptionFailed is returned for encrypted values that can not be
+	// decrypted with the encryption key
+	ErrDecryptionFailed = errors.New("failed to decrypt value, the database key is wrong or the value is corrupt")
+)
+
+// EncryptionKey records how the key of an encrypted database is derived
+// from its passphrase, and a value encrypted with it to check passphrases
+// against. A database has at most one.
+type EncryptionKey struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	Salt       string    `json:"-"` // base64 encoded
+	Iterations int       `json:"iterations"`
+	Verifier   string    `json:"-"` // a known value, encrypted with the key
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// encryptionPluginName is the name of the plugin holding the cipher of a
+// database
+const encryptionPluginName = "gowitness:encryption"
+
+// cipherContextKey is the statement context key of the cipher of the
+// encrypted serializer
+type cipherContextKey struct{}
+
+// encryptionPlugin holds the cipher of a database. It adds the cipher to
+// the context of the database's statements, which is the only state the
+// encrypted serializer gets, so that each database has its own key.
+type encryptionPlugin struct {
+	mu   sync.RWMutex
+	aead cipher.AEAD
+}
+
+func (p *encryptionPlugin) Name() string {
+	return encryptionPluginName
+}
+
+func (p *encryptionPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, register := range []error{
+		callbacks.Create().Before("gorm:create").Register(encryptionPluginName, p.withCipher),
+		callbacks.Query().Before("gorm:query").Register(encryptionPluginName, p.withCipher),
+		callbacks.Update().Before("gorm:update").Register(encryptionPluginName, p.withCipher),
+		callbacks.Row().Before("gorm:row").Register(encryptionPluginName, p.withCipher),
+		callbacks.Raw().Before("gorm:raw").Register(encryptionPluginName, p.withCipher),
+	} {
+		if register != nil {
+			return register
+		}
+	}
+
+	return nil
+}
+
+// withCipher adds the cipher to the context of a statement
+func (p *encryptionPlugin) withCipher(db *gorm.DB) {
+	if aead := p.cipher(); aead != nil {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		db.Statement.Context = context.WithValue(ctx, cipherContextKey{}, aead)
+	}
+}
+
+func (p *encryptionPlugin) cipher() cipher.AEAD {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.aead
+}
+
+// plugin returns the encryption plugin of a database, if it has one
+func plugin(db *gorm.DB) *encryptionPlugin {
+	if p, ok := db.Config.Plugins[encryptionPluginName].(*encryptionPlugin); ok {
+		return p
+	}
+
+	return nil
+}
+
+// dbCipher returns the cipher of a database, or nil
+func dbCipher(db *gorm.DB) cipher.AEAD {
+	if p := plugin(db); p != nil {
+		return p.cipher()
+	}
+
+	return nil
+}
+
+// SetEncryptionKey sets the AES-256 key that the columns of a database
+// using the encrypted serializer are encrypted with. A nil key disables
+// encryption. The key applies to db and the sessions derived from it only.
+func SetEncryptionKey(db *gorm.DB, key []byte) error {
+	var aead cipher.AEAD
+	if key != nil {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("invalid encryption key: %w", err)
+		}
+		if aead, err = cipher.NewGCM(block); err != nil {
+			return fmt.Errorf("invalid encryption key: %w", err)
+		}
+	}
+
+	p := plugin(db)
+	if p == nil {
+		if aead == nil {
+			return nil
+		}
+		p = &encryptionPlugin{}
+		if err := db.Use(p); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	p.aead = aead
+	p.mu.Unlock()
+
+	return nil
+}
+
+// EncryptionEnabled checks if a database has an encryption key set
+func EncryptionEnabled(db *gorm.DB) bool {
+	return dbCipher(db) != nil
+}
+
+// Encrypt encrypts a value with the encryption key of a database, if it
+// has one
+func Encrypt(db *gorm.DB, value string) (string, error) {
+	return encrypt(dbCipher(db), value)
+}
+
+// Decrypt decrypts a value encrypted with the encryption key of a
+// database. Values that are not encrypted, like those stored before a
+// database was encrypted, are returned as is.
+func Decrypt(db *gorm.DB, value string) (string, error) {
+	return decrypt(dbCipher(db), value)
+}
+
+// encrypt encrypts a value with aead, if it is not nil
+func encrypt(aead cipher.AEAD, value string) (string, error) {
+	if aead == nil || value == "" {
+		return value, nil
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), nil)
+
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt decrypts a value encrypted by encrypt
+func decrypt(aead cipher.AEAD, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	if aead == nil {
+		return "", ErrEncryptionKeyRequired
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrDecryptionFailed
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrDecryptionFailed
+	}
+
+	return string(plain), nil
+}
+
+// contextCipher returns the cipher a statement context was given, or nil
+func contextCipher(ctx context.Context) cipher.AEAD {
+	if ctx == nil {
+		return nil
+	}
+	aead, _ := ctx.Value(cipherContextKey{}).(cipher.AEAD)
+
+	return aead
+}
+
+func init() {
+	schema.RegisterSerializer("encrypted", encryptedSerializer{})
+	schema.RegisterSerializer("credential", credentialSerializer{})
+}
+
+// encryptedSerializer encrypts string columns tagged serializer:encrypted
+// at rest, with the key of the database they are read from or written to,
+// if it has one. Encrypted columns can not be
+// searched with SQL.
+type encryptedSerializer struct{}
+
+func (encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("unsupported type %T for encrypted column %s", dbValue, field.Name)
+	}
+
+	plain, err := decrypt(contextCipher(ctx), value)
+	if err != nil {
+		return err
+	}
+
+	return field.Set(ctx, dst, plain)
+}
+
+func (encryptedSerializer) Value(ctx context.Context, field *schema.Field, _ reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %T for encrypted column %s", fieldValue, field.Name)
+	}
+
+	return encrypt(contextCipher(ctx), value)
+}
+
+// credentialHeaders are the headers whose values carry credentials, such
+// as session cookies and tokens
+var credentialHeaders = map[string]bool{
+	"authorization":       true,
+	"cookie":              true,
+	"proxy-authorization": true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+	"x-auth-token":        true,
+	"x-csrf-token":        true,
+	"x-xsrf-token":        true,
+}
+
+// IsCredentialHeader checks if the values of a header carry credentials,
+// which are encrypted along with the other sensitive columns
+func IsCredentialHeader(key string) bool {
+	return credentialHeaders[strings.ToLower(strings.TrimSpace(key))]
+}
+
+// CredentialHeaders returns the lowercase names of the headers that carry
+// credentials
+func CredentialHeaders() []string {
+	return slices.Sorted(maps.Keys(credentialHeaders))
+}
+
+// credentialSerializer encrypts the value columns of headers tagged
+// serializer:credential like encryptedSerializer does, if the Key of their
+// row is a credential header. Other values are stored as is, so that they
+// can still be grouped and searched on.
+type credentialSerializer struct {
+	encryptedSerializer
+}
+
+func (s credentialSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	// values whose key is not known are encrypted, to be safe
+	if key := field.Schema.LookUpField("Key"); key != nil && dst.IsValid() {
+		if k, _ := key.ValueOf(ctx, dst); !IsCredentialHeader(fmt.Sprint(k)) {
+			if value, ok := fieldValue.(string); ok {
+				return value, nil
+			}
+		}
+	}
+
+	return s.encryptedSerializer.Value(ctx, field, dst, fieldValue)
+}
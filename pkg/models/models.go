@@ -2,6 +2,8 @@ package models
 
 import (
 	"encoding/json"
+	"net/netip"
+	"strings"
 	"time"
 )
 
@@ -20,26 +22,51 @@ type Result struct {
 	URL                   string    `json:"url"`
 	IPAddress             string    `json:"ip_address" gorm:"index"`
 	ScanSessionID         *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	PreviousResultID      *uint     `json:"previous_result_id,omitempty" gorm:"index"` // the result this result is a retake of
 	ProbedAt              time.Time `json:"probed_at"`
+	UpdatedAt             time.Time `json:"updated_at" gorm:"index"` // when the result was last written, to reindex it for search
 	FinalURL              string    `json:"final_url"`
 	ResponseCode          int       `json:"response_code"`
 	ResponseReason        string    `json:"response_reason"`
 	Protocol              string    `json:"protocol"`
 	ContentLength         int64     `json:"content_length"`
-	HTML                  string    `json:"html" gorm:"index"`
+	HTML                  string    `json:"html" gorm:"index;serializer:encrypted"`
 	Title                 string    `json:"title" gorm:"index"`
 	PerceptionHash        string    `json:"perception_hash" gorm:"index"`
 	PerceptionHashGroupId uint      `json:"perception_hash_group_id" gorm:"index"`
 	Screenshot            string    `json:"screenshot"`
 
-	// Name of the screenshot file
+	// Path of the screenshot file, relative to the screenshot path
 	Filename string `json:"file_name"`
-	IsPDF    bool   `json:"is_pdf"`
+	// Path of the screenshot thumbnail file, relative to the thumbs directory
+	Thumbnail string `json:"thumbnail"`
+	IsPDF     bool   `json:"is_pdf"`
+	// Paths of the additional full page and element screenshots, if taken,
+	// relative to the screenshot path
+	FullPageFilename string `json:"full_page_file_name,omitempty"`
+	ElementFilename  string `json:"element_file_name,omitempty"`
 
 	// Failed flag set if the result should be considered failed
 	Failed       bool   `json:"failed"`
 	FailedReason string `json:"failed_reason"`
 
+	// Noise flag set if the result matched a signature of a boring page,
+	// such as a parked domain, named by NoiseReason
+	Noise       bool   `json:"noise" gorm:"index"`
+	NoiseReason string `json:"noise_reason,omitempty"`
+
+	// SecurityScore grades the response's security headers and cookies out
+	// of 100, and SecurityGrade turns it into a letter from A to F
+	SecurityScore int    `json:"security_score" gorm:"index"`
+	SecurityGrade string `json:"security_grade" gorm:"index"`
+
+	// Triage state, set by analysts working through results
+	Reviewed    bool       `json:"reviewed" gorm:"index"`
+	ReviewedBy  string     `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	Interesting bool       `json:"interesting" gorm:"index"`
+	AssignedTo  string     `json:"assigned_to,omitempty" gorm:"index"` // username of the analyst the result is assigned to
+
 	TLS          TLS          `json:"tls" gorm:"constraint:OnDelete:CASCADE"`
 	Technologies []Technology `json:"technologies" gorm:"constraint:OnDelete:CASCADE"`
 
@@ -47,6 +74,22 @@ type Result struct {
 	Network []NetworkLog `json:"network" gorm:"constraint:OnDelete:CASCADE"`
 	Console []ConsoleLog `json:"console" gorm:"constraint:OnDelete:CASCADE"`
 	Cookies []Cookie     `json:"cookies" gorm:"constraint:OnDelete:CASCADE"`
+
+	Tags        []ResultTag  `json:"tags,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	Findings    []Finding    `json:"findings,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	Enrichments []Enrichment `json:"enrichments,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	Annotations []Annotation `json:"annotations,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+
+	// Asset is the imported asset the result's host is, if any. It is not
+	// stored with the result, but matched when it is shown.
+	Asset *Asset `json:"asset,omitempty" gorm:"-"`
+
+	SecurityIssues []SecurityIssue  `json:"security_issues,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	Notes          []Note           `json:"notes,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	Paths          []DiscoveredPath `json:"paths,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	SiteFiles      []SiteFile       `json:"site_files,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	ExtractedURLs  []ExtractedURL   `json:"extracted_urls,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	Secrets        []Secret         `json:"secrets,omitempty" gorm:"constraint:OnDelete:CASCADE"`
 }
 
 func (r *Result) HeaderMap() map[string][]string {
@@ -86,7 +129,10 @@ type Technology struct {
 	ID       uint `json:"id" gorm:"primarykey"`
 	ResultID uint `json:"result_id"`
 
-	Value string `json:"value" gorm:"index"`
+	Value   string `json:"value" gorm:"index"` // Wappalyzer fingerprint, e.g. Nginx:1.25.3
+	Name    string `json:"name" gorm:"index"`
+	Version string `json:"version,omitempty"`
+	CPE     string `json:"cpe,omitempty"` // CPE 2.3 name, with the version if known
 }
 
 type Header struct {
@@ -94,7 +140,7 @@ type Header struct {
 	ResultID uint `json:"result_id"`
 
 	Key   string `json:"key"`
-	Value string `json:"value" gorm:"index"`
+	Value string `json:"value" gorm:"index;serializer:credential"` // encrypted if Key carries credentials
 }
 
 type NetworkLog struct {
@@ -109,6 +155,9 @@ type NetworkLog struct {
 	Time        time.Time   `json:"time"`
 	Content     []byte      `json:"content"`
 	Error       string      `json:"error"`
+	// ContentHash is the SHA-256 hash the response body is archived under,
+	// if bodies are archived
+	ContentHash string `json:"content_hash,omitempty" gorm:"index"`
 }
 
 type ConsoleLog struct {
@@ -124,7 +173,7 @@ type Cookie struct {
 	ResultID uint `json:"result_id"`
 
 	Name         string    `json:"name"`
-	Value        string    `json:"value"`
+	Value        string    `json:"value" gorm:"serializer:encrypted"`
 	Domain       string    `json:"domain"`
 	Path         string    `json:"path"`
 	Expires      time.Time `json:"expires"`
@@ -132,6 +181,7 @@ type Cookie struct {
 	HTTPOnly     bool      `json:"http_only"`
 	Secure       bool      `json:"secure"`
 	Session      bool      `json:"session"`
+	SameSite     string    `json:"same_site,omitempty"` // Strict, Lax or None, empty if not set
 	Priority     string    `json:"priority"`
 	SourceScheme string    `json:"source_scheme"`
 	SourcePort   int64     `json:"source_port"`
@@ -139,14 +189,193 @@ type Cookie struct {
 
 // ScanSession represents a scan session for a target company
 type ScanSession struct {
-	ID          uint       `json:"id" gorm:"primarykey"`
-	CompanyName string     `json:"company_name" gorm:"index"`
-	MainDomain  string     `json:"main_domain" gorm:"index"`
-	LogoPath    string     `json:"logo_path,omitempty"` // Path to company logo file
-	StartTime   time.Time  `json:"start_time"`
-	EndTime     *time.Time `json:"end_time,omitempty"`
-	Status      string     `json:"status" gorm:"default:'active'"` // active, completed, cancelled
-	Notes       string     `json:"notes"`
+	ID             uint       `json:"id" gorm:"primarykey"`
+	CompanyName    string     `json:"company_name" gorm:"index"`
+	MainDomain     string     `json:"main_domain" gorm:"index"`  // The first of the session's apex domains
+	LogoPath       string     `json:"logo_path,omitempty"`       // Path to company logo file
+	ScreenshotPath string     `json:"screenshot_path,omitempty"` // Directory screenshots for this session are stored in
+	Timezone       string     `json:"timezone,omitempty"`        // IANA timezone the session is run from, e.g. Europe/Copenhagen
+	StartTime      time.Time  `json:"start_time"`
+	EndTime        *time.Time `json:"end_time,omitempty"`
+	Status         string     `json:"status" gorm:"default:'active'"` // active, completed, failed, cancelled
+	FailedPhase    string     `json:"failed_phase,omitempty"`         // Pipeline phase that failed, for failed sessions
+	FailureReason  string     `json:"failure_reason,omitempty"`       // Why the failed phase failed
+	Notes          string     `json:"notes"`
+
+	// Company profile, optionally enriched at scan init
+	Industry         string `json:"industry,omitempty"`
+	EmployeeCount    int    `json:"employee_count,omitempty"`
+	Netblocks        string `json:"netblocks,omitempty"`         // JSON string array of CIDRs
+	Domains          string `json:"domains,omitempty"`           // JSON string array of official domains
+	EnrichmentSource string `json:"enrichment_source,omitempty"` // Where the company profile came from
+
+	// ApexDomains are the main domains of the company's brands, which
+	// define the session's scope
+	ApexDomains []ApexDomain `json:"apex_domains,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// ApexDomain is a main domain of a scan session
+type ApexDomain struct {
+	ID            uint   `json:"id" gorm:"primarykey"`
+	ScanSessionID uint   `json:"scan_session_id" gorm:"index"`
+	Domain        string `json:"domain" gorm:"index"`
+}
+
+// ScopeDomains returns the apex domains of the session. Sessions created
+// before sessions had several domains only have their main domain.
+func (s *ScanSession) ScopeDomains() []string {
+	if len(s.ApexDomains) == 0 {
+		if s.MainDomain == "" {
+			return []string{}
+		}
+		return []string{strings.ToLower(s.MainDomain)}
+	}
+
+	domains := make([]string, 0, len(s.ApexDomains))
+	for _, apex := range s.ApexDomains {
+		domains = append(domains, apex.Domain)
+	}
+
+	return domains
+}
+
+// InScope checks if a hostname is one of the session's apex domains or a
+// subdomain of one. ApexDomains must be loaded.
+func (s *ScanSession) InScope(hostname string) bool {
+	hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+	for _, domain := range s.ScopeDomains() {
+		if hostname == domain || strings.HasSuffix(hostname, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetNetblocks sets the netblocks field from a string slice
+func (s *ScanSession) SetNetblocks(netblocks []string) error {
+	if netblocks == nil {
+		s.Netblocks = ""
+		return nil
+	}
+	data, err := json.Marshal(netblocks)
+	if err != nil {
+		return err
+	}
+	s.Netblocks = string(data)
+	return nil
+}
+
+// GetNetblocks returns the netblocks as a string slice
+func (s *ScanSession) GetNetblocks() ([]string, error) {
+	if s.Netblocks == "" {
+		return []string{}, nil
+	}
+	var netblocks []string
+	err := json.Unmarshal([]byte(s.Netblocks), &netblocks)
+	return netblocks, err
+}
+
+// SetDomains sets the domains field from a string slice
+func (s *ScanSession) SetDomains(domains []string) error {
+	if domains == nil {
+		s.Domains = ""
+		return nil
+	}
+	data, err := json.Marshal(domains)
+	if err != nil {
+		return err
+	}
+	s.Domains = string(data)
+	return nil
+}
+
+// GetDomains returns the official domains as a string slice
+func (s *ScanSession) GetDomains() ([]string, error) {
+	if s.Domains == "" {
+		return []string{}, nil
+	}
+	var domains []string
+	err := json.Unmarshal([]byte(s.Domains), &domains)
+	return domains, err
+}
+
+// ResultHost is a materialized summary of the host a result was probed on.
+// It allows statistics to be grouped by apex domain in SQL. Rows are derived
+// from results, and can be rebuilt at any time.
+type ResultHost struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	ResultID   uint      `json:"result_id" gorm:"uniqueIndex"`
+	URL        string    `json:"url"`
+	Hostname   string    `json:"hostname" gorm:"index"`
+	ApexDomain string    `json:"apex_domain" gorm:"index"`
+	Protocol   string    `json:"protocol"`
+	Port       string    `json:"port"`
+	IPAddress  string    `json:"ip_address" gorm:"index"`
+	ProbedAt   time.Time `json:"probed_at"`
+}
+
+// SearchDocument is a piece of searchable text, like a response body or a
+// header value. Documents belong to either a result or an IPPort, and are
+// indexed for full-text search.
+type SearchDocument struct {
+	ID       uint   `json:"id" gorm:"primarykey"`
+	ResultID uint   `json:"result_id" gorm:"index"`
+	IPPortID uint   `json:"ip_port_id" gorm:"index"`
+	Field    string `json:"field" gorm:"index"` // e.g., "body", "header", "console", "banner"
+	Name     string `json:"name" gorm:"index"`  // header name, or the ip address of a banner
+	Content  string `json:"content"`
+	// SourceUpdatedAt is when the result or port was last written, as of
+	// indexing it. Rows written since are indexed again.
+	SourceUpdatedAt time.Time `json:"source_updated_at"`
+}
+
+// Domain sources that are not a passive dns or certificate transparency
+// provider, which use their provider name
+const (
+	DomainSourceManual     = "manual"     // listed by hand, such as in a project's domains.txt
+	DomainSourceBruteforce = "bruteforce" // guessed from a wordlist
+	DomainSourceShodan     = "shodan"     // a hostname Shodan knows for an IP
+	DomainSourceTLSSAN     = "tls-san"    // a subject alternative name of a certificate
+)
+
+// Domain resolution statuses. Domains that were never resolved have an
+// empty status.
+const (
+	DomainResolved   = "resolved"
+	DomainUnresolved = "unresolved"
+)
+
+// Domain represents a hostname discovered for a target, and where it came
+// from. A hostname found by several sources has a row per source.
+type Domain struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	Name          string    `json:"name" gorm:"index;not null"`
+	Source        string    `json:"source" gorm:"index"` // e.g., "securitytrails", "crtsh" or "manual"
+	Historical    bool      `json:"historical"`          // the source no longer considers the hostname active
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	// Resolution is whether the hostname resolved when last resolved, and
+	// ResolvedAt when that was
+	Resolution string     `json:"resolution" gorm:"index"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// DNSRecord is an A, AAAA or CNAME record a host resolved to. Records are
+// kept once they stop resolving, so LastSeen tells current records apart
+// from historical ones.
+type DNSRecord struct {
+	ID    uint   `json:"id" gorm:"primarykey"`
+	Host  string `json:"host" gorm:"index;not null"`
+	Type  string `json:"type" gorm:"index"` // A, AAAA or CNAME
+	Value string `json:"value" gorm:"index"`
+	// Dangling is set on CNAME records whose target did not resolve when
+	// last seen, which may allow a subdomain takeover
+	Dangling      bool      `json:"dangling" gorm:"index"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
 }
 
 // IPPort represents an IP address and its open port mapping
@@ -160,6 +389,17 @@ type IPPort struct {
 	Banner        string    `json:"banner"`                        // service banner if available
 	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
 	DiscoveredAt  time.Time `json:"discovered_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"index"` // when the port was last written, to reindex its banner for search
+
+	// Service details, if a source such as a full Shodan lookup knows them
+	Product     string     `json:"product,omitempty"`
+	Version     string     `json:"version,omitempty"`
+	HTTPStatus  int        `json:"http_status,omitempty"`
+	HTTPTitle   string     `json:"http_title,omitempty"`
+	TLSSubject  string     `json:"tls_subject,omitempty"`
+	TLSIssuer   string     `json:"tls_issuer,omitempty"`
+	TLSVersions string     `json:"tls_versions,omitempty"` // comma separated supported versions
+	TLSExpires  *time.Time `json:"tls_expires,omitempty"`
 
 	// CDN Detection Information
 	IsCDN        bool   `json:"is_cdn" gorm:"default:false"`       // Whether this IP/host is detected as CDN
@@ -167,10 +407,482 @@ type IPPort struct {
 	CDNDetected  bool   `json:"cdn_detected" gorm:"default:false"` // Whether CDN detection was performed
 	OriginalHost string `json:"original_host"`                     // Original hostname that resolved to this IP
 
+	// CloudProvider is the cloud provider or CDN the address belongs to,
+	// empty if it is self hosted
+	CloudProvider string `json:"cloud_provider,omitempty" gorm:"index"`
+
 	// Unique constraint on IP+Port combination within a scan session
 	// This prevents duplicate entries for the same IP:port
 }
 
+// HostStatus records whether an IP address answered host discovery, the
+// ICMP and TCP probes run before port scans to skip dead addresses
+type HostStatus struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	IPAddress     string    `json:"ip_address" gorm:"uniqueIndex;not null"`
+	Alive         bool      `json:"alive" gorm:"index"`
+	Method        string    `json:"method,omitempty"` // how the host answered, e.g. icmp or tcp/443
+	CheckedAt     time.Time `json:"checked_at"`
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+}
+
+// Tag is a tag results can be tagged with. Tags are created when first
+// used, by analysts, plugins or the classifier.
+type Tag struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Name        string    `json:"name" gorm:"uniqueIndex;not null"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ResultTag is a tag on a result
+type ResultTag struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	ResultID  uint      `json:"result_id" gorm:"index"`
+	Name      string    `json:"name" gorm:"index;not null"`
+	Source    string    `json:"source"` // what added the tag, e.g. a plugin name
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Annotation flags a noteworthy characteristic of a result's response, such
+// as a basic auth challenge, to guide manual review
+type Annotation struct {
+	ID       uint   `json:"id" gorm:"primarykey"`
+	ResultID uint   `json:"result_id" gorm:"index"`
+	Kind     string `json:"kind" gorm:"index"`
+	Detail   string `json:"detail"`
+}
+
+// SecurityIssue is a missing or weak security header or cookie flag on a
+// result, that cost it points of its security score
+type SecurityIssue struct {
+	ID       uint   `json:"id" gorm:"primarykey"`
+	ResultID uint   `json:"result_id" gorm:"index"`
+	Kind     string `json:"kind" gorm:"index"` // e.g. missing-hsts
+	Severity string `json:"severity"`          // info, low, medium, high, critical
+	Detail   string `json:"detail"`
+	Penalty  int    `json:"penalty"` // points deducted from the security score
+}
+
+// Finding is a noteworthy issue found on a result or IP address
+type Finding struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	ResultID      *uint     `json:"result_id,omitempty" gorm:"index"`
+	IPAddress     string    `json:"ip_address" gorm:"index"`
+	Domain        string    `json:"domain,omitempty" gorm:"index"` // for findings of an enrichment provider about a domain
+	Source        string    `json:"source" gorm:"index"`           // what reported the finding, e.g. a plugin name
+	Title         string    `json:"title"`
+	Severity      string    `json:"severity" gorm:"index"` // info, low, medium, high, critical
+	Description   string    `json:"description" gorm:"serializer:encrypted"`
+	CVE           string    `json:"cve,omitempty" gorm:"index"` // CVE identifier, for known vulnerabilities
+	CVSS          float64   `json:"cvss,omitempty"`             // CVSS base score of the CVE
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Alert is a change an alert rule fired on, such as a newly exposed port.
+// A rule fires once per subject.
+type Alert struct {
+	ID            uint       `json:"id" gorm:"primarykey"`
+	Rule          string     `json:"rule" gorm:"uniqueIndex:idx_alert_rule_subject"`
+	Subject       string     `json:"subject" gorm:"uniqueIndex:idx_alert_rule_subject"` // what changed, e.g. 192.0.2.1:3389/tcp or a hostname
+	Severity      string     `json:"severity" gorm:"index"`                             // info, low, medium, high, critical
+	Message       string     `json:"message"`
+	IPAddress     string     `json:"ip_address,omitempty" gorm:"index"`
+	ScanSessionID *uint      `json:"scan_session_id,omitempty" gorm:"index"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"` // nil if it was not delivered, e.g. when recording a baseline
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Asset criticalities, from least to most critical
+var AssetCriticalities = []string{"low", "medium", "high", "critical"}
+
+// Asset is the business context of a host, imported from a CMDB, that
+// follows it through scanning. Host is a hostname, an IP address, a CIDR,
+// or a wildcard such as *.example.com that covers a domain's hosts.
+type Asset struct {
+	ID          uint   `json:"id" gorm:"primarykey"`
+	Host        string `json:"host" gorm:"uniqueIndex;not null"`
+	Label       string `json:"label,omitempty"`
+	Owner       string `json:"owner,omitempty" gorm:"index"`
+	Environment string `json:"environment,omitempty" gorm:"index"` // e.g. production or staging
+	Criticality string `json:"criticality,omitempty" gorm:"index"` // low, medium, high or critical
+	// Metadata is a JSON object of the other columns the asset was
+	// imported with
+	Metadata      string    `json:"metadata,omitempty"`
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// GetMetadata returns the metadata of the asset
+func (a *Asset) GetMetadata() (map[string]string, error) {
+	if a.Metadata == "" {
+		return map[string]string{}, nil
+	}
+	var metadata map[string]string
+	err := json.Unmarshal([]byte(a.Metadata), &metadata)
+	return metadata, err
+}
+
+// SetMetadata sets the metadata of the asset
+func (a *Asset) SetMetadata(metadata map[string]string) error {
+	if len(metadata) == 0 {
+		a.Metadata = ""
+		return nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	a.Metadata = string(data)
+	return nil
+}
+
+// Ticket is an issue created in a ticketing system, such as JIRA, for a
+// finding or a newly exposed service. It keeps the same issue from being
+// created again on later runs.
+type Ticket struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	Provider      string    `json:"provider" gorm:"uniqueIndex:idx_ticket_provider_fingerprint"`    // e.g. jira
+	Fingerprint   string    `json:"fingerprint" gorm:"uniqueIndex:idx_ticket_provider_fingerprint"` // what the ticket is about, stable across scans
+	Key           string    `json:"key"`                                                            // the issue key, e.g. SEC-123
+	URL           string    `json:"url"`
+	Summary       string    `json:"summary"`
+	FindingID     *uint     `json:"finding_id,omitempty" gorm:"index"`
+	AlertID       *uint     `json:"alert_id,omitempty" gorm:"index"`
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ExtractedURL is an API endpoint or third-party domain referenced by the
+// HTML, scripts or network requests of a result
+type ExtractedURL struct {
+	ID       uint   `json:"id" gorm:"primarykey"`
+	ResultID uint   `json:"result_id" gorm:"index"`
+	Kind     string `json:"kind" gorm:"index"` // endpoint or third-party
+	URL      string `json:"url" gorm:"index"`  // the hostname for third-party domains
+	Host     string `json:"host" gorm:"index"`
+	Source   string `json:"source"` // html, script or network
+}
+
+// Secret is a string in the HTML or scripts of a result that looks like an
+// API key, token or other credential
+type Secret struct {
+	ID       uint   `json:"id" gorm:"primarykey"`
+	ResultID uint   `json:"result_id" gorm:"index"`
+	Kind     string `json:"kind" gorm:"index"` // e.g. aws-access-key, jwt
+	Value    string `json:"value" gorm:"serializer:encrypted"`
+	Source   string `json:"source"`   // html, script or network
+	Location string `json:"location"` // URL of the document it was found in
+}
+
+// SiteFile is a robots.txt or sitemap.xml file collected from the server of
+// a result
+type SiteFile struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	ResultID   uint      `json:"result_id" gorm:"index"`
+	Kind       string    `json:"kind" gorm:"index"` // robots.txt or sitemap.xml
+	URL        string    `json:"url"`
+	StatusCode int       `json:"status_code"`
+	Content    string    `json:"content"`
+	URLCount   int       `json:"url_count"` // URLs on the server the files of the result named
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DiscoveredPath is a path found by brute forcing the server of a result
+type DiscoveredPath struct {
+	ID            uint   `json:"id" gorm:"primarykey"`
+	ResultID      uint   `json:"result_id" gorm:"uniqueIndex:idx_discovered_path"`
+	Path          string `json:"path" gorm:"uniqueIndex:idx_discovered_path"`
+	URL           string `json:"url"`
+	StatusCode    int    `json:"status_code" gorm:"index"`
+	ContentLength int    `json:"content_length"`
+	ContentType   string `json:"content_type"`
+	Title         string `json:"title"`
+	Location      string `json:"location,omitempty"` // where redirects point to
+	// ScreenshotResultID is the result of the path's screenshot, if it
+	// was screenshotted
+	ScreenshotResultID *uint     `json:"screenshot_result_id,omitempty"`
+	ScanSessionID      *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// Note is an analyst's note on a result, an IP address or a scan session.
+// Exactly one of ResultID, IPInfoID and ScanSessionID is set.
+type Note struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	ResultID      *uint     `json:"result_id,omitempty" gorm:"index"`
+	IPInfoID      *uint     `json:"ip_info_id,omitempty" gorm:"index"`
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	Author        string    `json:"author" gorm:"index"`
+	Body          string    `json:"body"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// BreachExposure is how many accounts of an apex domain a breach data
+// provider has seen in public data breaches
+type BreachExposure struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	Domain        string    `json:"domain" gorm:"uniqueIndex:idx_breach_exposure"`
+	Source        string    `json:"source" gorm:"uniqueIndex:idx_breach_exposure"`
+	Accounts      int       `json:"accounts"`
+	Breaches      int       `json:"breaches"`
+	BreachNames   string    `json:"breach_names,omitempty"` // JSON string array of breach names
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	CheckedAt     time.Time `json:"checked_at"`
+}
+
+// SetBreachNames sets the breach names field from a string slice
+func (b *BreachExposure) SetBreachNames(names []string) error {
+	if names == nil {
+		b.BreachNames = ""
+		return nil
+	}
+	data, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+	b.BreachNames = string(data)
+	return nil
+}
+
+// GetBreachNames returns the breach names as a string slice
+func (b *BreachExposure) GetBreachNames() ([]string, error) {
+	if b.BreachNames == "" {
+		return []string{}, nil
+	}
+	var names []string
+	err := json.Unmarshal([]byte(b.BreachNames), &names)
+	return names, err
+}
+
+// DomainRegistration is the registration of an apex domain, from RDAP
+type DomainRegistration struct {
+	ID            uint       `json:"id" gorm:"primarykey"`
+	Domain        string     `json:"domain" gorm:"uniqueIndex;not null"`
+	Registrar     string     `json:"registrar"`
+	Status        string     `json:"status"`      // JSON string array of EPP statuses
+	Nameservers   string     `json:"nameservers"` // JSON string array
+	CreatedDate   *time.Time `json:"created_date,omitempty"`
+	ExpiryDate    *time.Time `json:"expiry_date,omitempty" gorm:"index"`
+	UpdatedDate   *time.Time `json:"updated_date,omitempty"`
+	ScanSessionID *uint      `json:"scan_session_id,omitempty" gorm:"index"`
+	CheckedAt     time.Time  `json:"checked_at"`
+}
+
+// GetStatus returns the statuses as a string slice
+func (d *DomainRegistration) GetStatus() ([]string, error) {
+	if d.Status == "" {
+		return []string{}, nil
+	}
+	var status []string
+	err := json.Unmarshal([]byte(d.Status), &status)
+	return status, err
+}
+
+// GetNameservers returns the nameservers as a string slice
+func (d *DomainRegistration) GetNameservers() ([]string, error) {
+	if d.Nameservers == "" {
+		return []string{}, nil
+	}
+	var nameservers []string
+	err := json.Unmarshal([]byte(d.Nameservers), &nameservers)
+	return nameservers, err
+}
+
+// Netblock is an IP range a regional internet registry assigned, and who
+// it was assigned to, from RDAP
+type Netblock struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	Handle        string    `json:"handle" gorm:"uniqueIndex;not null"`
+	Name          string    `json:"name"`
+	StartAddress  string    `json:"start_address"`
+	EndAddress    string    `json:"end_address"`
+	CIDRs         string    `json:"cidrs"` // JSON string array
+	Owner         string    `json:"owner"`
+	Country       string    `json:"country"`
+	Type          string    `json:"type"` // e.g. DIRECT ALLOCATION
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	CheckedAt     time.Time `json:"checked_at"`
+}
+
+// GetCIDRs returns the CIDRs as a string slice
+func (n *Netblock) GetCIDRs() ([]string, error) {
+	if n.CIDRs == "" {
+		return []string{}, nil
+	}
+	var cidrs []string
+	err := json.Unmarshal([]byte(n.CIDRs), &cidrs)
+	return cidrs, err
+}
+
+// Contains checks if an IP address is in the netblock
+func (n *Netblock) Contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	start, err := netip.ParseAddr(n.StartAddress)
+	if err != nil {
+		return false
+	}
+	end, err := netip.ParseAddr(n.EndAddress)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	return addr.BitLen() == start.BitLen() && start.Compare(addr) <= 0 && addr.Compare(end) <= 0
+}
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// Job is a job in the job queue, such as probing a list of URLs. Jobs are
+// claimed by priority, highest first, and retried until MaxAttempts. Total,
+// Completed and Failed are the progress of jobs that work through a list.
+type Job struct {
+	ID            uint       `json:"id" gorm:"primarykey"`
+	Kind          string     `json:"kind" gorm:"index"`
+	Status        string     `json:"status" gorm:"index"`
+	Priority      int        `json:"priority" gorm:"index"`
+	Payload       string     `json:"-"` // JSON encoded job arguments
+	Attempts      int        `json:"attempts"`
+	MaxAttempts   int        `json:"max_attempts"`
+	RunAt         time.Time  `json:"run_at" gorm:"index"` // the job is not run before this time
+	Total         int        `json:"total"`
+	Completed     int        `json:"completed"`
+	Failed        int        `json:"failed"`
+	Errors        string     `json:"errors,omitempty"` // JSON array of JobErrors, the first few failures
+	Error         string     `json:"error,omitempty"`  // why the last attempt failed
+	ScanSessionID *uint      `json:"scan_session_id,omitempty" gorm:"index"`
+	Worker        string     `json:"worker,omitempty"`       // who runs the job, the server or an agent's name
+	HeartbeatAt   *time.Time `json:"heartbeat_at,omitempty"` // when the agent running the job was last heard from
+	CreatedAt     time.Time  `json:"created_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobError is an item of a job, such as a URL, that failed
+type JobError struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// SetErrors sets the errors field from a JobError slice
+func (j *Job) SetErrors(errors []JobError) error {
+	if errors == nil {
+		j.Errors = ""
+		return nil
+	}
+	data, err := json.Marshal(errors)
+	if err != nil {
+		return err
+	}
+	j.Errors = string(data)
+	return nil
+}
+
+// GetErrors returns the errors as a JobError slice
+func (j *Job) GetErrors() ([]JobError, error) {
+	if j.Errors == "" {
+		return []JobError{}, nil
+	}
+	var errors []JobError
+	err := json.Unmarshal([]byte(j.Errors), &errors)
+	return errors, err
+}
+
+// Enrichment is an extra field a plugin added to a result or IP address
+type Enrichment struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	ResultID      *uint     `json:"result_id,omitempty" gorm:"index"`
+	IPAddress     string    `json:"ip_address" gorm:"index"`
+	Domain        string    `json:"domain,omitempty" gorm:"index"`
+	Source        string    `json:"source" gorm:"index"`
+	Key           string    `json:"key"`
+	Value         string    `json:"value"`
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// User is a user that can log in to the web interface
+type User struct {
+	ID           uint       `json:"id" gorm:"primarykey"`
+	Username     string     `json:"username" gorm:"uniqueIndex;not null"`
+	PasswordHash string     `json:"-" gorm:"not null"`
+	Role         string     `json:"role" gorm:"not null"` // admin, analyst, viewer
+	Disabled     bool       `json:"disabled"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastLogin    *time.Time `json:"last_login,omitempty"`
+}
+
+// UserSession is a login session of a User
+type UserSession struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex;not null"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	User      User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserIdentity links a User to their account at an identity provider. Users
+// logging in through a provider are found by its issuer and their subject
+// there, never by username, so a provider account can't take over a local
+// user with the same name.
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Issuer    string    `json:"issuer" gorm:"uniqueIndex:idx_user_identities_subject;not null"`
+	Subject   string    `json:"subject" gorm:"uniqueIndex:idx_user_identities_subject;not null"`
+	UserID    uint      `json:"user_id" gorm:"index"`
+	User      User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OriginCandidate is an IP address that may be the origin server of a
+// host that is fronted by a CDN
+type OriginCandidate struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	IPPortID      uint      `json:"ip_port_id" gorm:"index;not null"` // the CDN fronted IPPort
+	Hostname      string    `json:"hostname" gorm:"index"`
+	IPAddress     string    `json:"ip_address" gorm:"index;not null"`
+	Source        string    `json:"source"`   // historical-dns, favicon-hash, ssl-cert
+	Evidence      string    `json:"evidence"` // why the candidate was suggested
+	Probed        bool      `json:"probed"`   // whether the candidate was probed directly
+	Verified      bool      `json:"verified"` // the candidate served the same page as the CDN
+	ProbeEvidence string    `json:"probe_evidence"`
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	DiscoveredAt  time.Time `json:"discovered_at" gorm:"autoCreateTime"`
+}
+
+// ServiceScreenshot is a screenshot of a non-HTTP service on an open port,
+// such as a VNC server without authentication. A port has at most one, from
+// the latest attempt.
+type ServiceScreenshot struct {
+	ID            uint      `json:"id" gorm:"primarykey"`
+	IPPortID      uint      `json:"ip_port_id" gorm:"uniqueIndex;not null"`
+	IPAddress     string    `json:"ip_address" gorm:"index;not null"`
+	Port          int       `json:"port"`
+	Protocol      string    `json:"protocol"`  // vnc or x11
+	Filename      string    `json:"file_name"` // in the screenshot path
+	Width         int       `json:"width"`
+	Height        int       `json:"height"`
+	Name          string    `json:"name,omitempty"` // the desktop name, if the service announces one
+	Failed        bool      `json:"failed"`
+	FailedReason  string    `json:"failed_reason,omitempty"`
+	ScanSessionID *uint     `json:"scan_session_id,omitempty" gorm:"index"`
+	CapturedAt    time.Time `json:"captured_at"`
+}
+
 // IPInfo represents comprehensive IP address information from Shodan
 type IPInfo struct {
 	ID           uint      `json:"id" gorm:"primarykey"`
@@ -186,14 +898,20 @@ type IPInfo struct {
 	Latitude     float64   `json:"latitude"`
 	Longitude    float64   `json:"longitude"`
 	OS           string    `json:"os"`
-	Tags         string    `json:"tags"`      // JSON string array
-	Ports        string    `json:"ports"`     // JSON int array
-	Hostnames    string    `json:"hostnames"` // JSON string array
-	Domains      string    `json:"domains"`   // JSON string array
-	Vulns        string    `json:"vulns"`     // JSON string array
+	Tags         string    `json:"tags"`        // JSON string array
+	Ports        string    `json:"ports"`       // JSON int array
+	Hostnames    string    `json:"hostnames"`   // JSON string array
+	Domains      string    `json:"domains"`     // JSON string array
+	Vulns        string    `json:"vulns"`       // JSON string array
+	ReverseDNS   string    `json:"reverse_dns"` // JSON string array of PTR records
 	LastUpdate   time.Time `json:"last_update"`
+	FirstSeen    time.Time `json:"first_seen"` // When the IP was first enriched, kept when it is refreshed
 	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 
+	// CloudProvider is the cloud provider or CDN the address belongs to,
+	// empty if it is self hosted
+	CloudProvider string `json:"cloud_provider,omitempty" gorm:"index"`
+
 	// Relations to existing models
 	ScanSessionID *uint `json:"scan_session_id,omitempty" gorm:"index"`
 }
@@ -317,3 +1035,94 @@ func (ip *IPInfo) GetVulns() ([]string, error) {
 	err := json.Unmarshal([]byte(ip.Vulns), &vulns)
 	return vulns, err
 }
+
+// SetReverseDNS sets the reverse DNS field from a string slice
+func (ip *IPInfo) SetReverseDNS(names []string) error {
+	if names == nil {
+		ip.ReverseDNS = ""
+		return nil
+	}
+	data, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+	ip.ReverseDNS = string(data)
+	return nil
+}
+
+// GetReverseDNS returns the reverse DNS (PTR) names as a string slice
+func (ip *IPInfo) GetReverseDNS() ([]string, error) {
+	if ip.ReverseDNS == "" {
+		return []string{}, nil
+	}
+	var names []string
+	err := json.Unmarshal([]byte(ip.ReverseDNS), &names)
+	return names, err
+}
+
+// IPInfoHistory is a snapshot of the information of an IP, recorded every
+// time the IP is enriched. Where IPInfo only holds the latest information,
+// the snapshots show what changed across scan sessions.
+type IPInfoHistory struct {
+	ID           uint      `json:"id" gorm:"primarykey"`
+	IPAddress    string    `json:"ip_address" gorm:"index;not null"`
+	Source       string    `json:"source"` // Where the information came from, e.g. shodan or ip-api+naabu
+	Organization string    `json:"organization"`
+	ISP          string    `json:"isp"`
+	ASN          string    `json:"asn"`
+	Country      string    `json:"country"`
+	CountryCode  string    `json:"country_code"`
+	City         string    `json:"city"`
+	OS           string    `json:"os"`
+	Tags         string    `json:"tags"`      // JSON string array
+	Ports        string    `json:"ports"`     // JSON int array
+	Hostnames    string    `json:"hostnames"` // JSON string array
+	Vulns        string    `json:"vulns"`     // JSON string array
+	LastUpdate   time.Time `json:"last_update"`
+	RecordedAt   time.Time `json:"recorded_at" gorm:"index"`
+
+	ScanSessionID *uint `json:"scan_session_id,omitempty" gorm:"index"`
+}
+
+// NewIPInfoHistory returns a snapshot of the current information of an IP
+func NewIPInfoHistory(ip *IPInfo, source string) *IPInfoHistory {
+	return &IPInfoHistory{
+		IPAddress:     ip.IPAddress,
+		Source:        source,
+		Organization:  ip.Organization,
+		ISP:           ip.ISP,
+		ASN:           ip.ASN,
+		Country:       ip.Country,
+		CountryCode:   ip.CountryCode,
+		City:          ip.City,
+		OS:            ip.OS,
+		Tags:          ip.Tags,
+		Ports:         ip.Ports,
+		Hostnames:     ip.Hostnames,
+		Vulns:         ip.Vulns,
+		LastUpdate:    ip.LastUpdate,
+		RecordedAt:    time.Now(),
+		ScanSessionID: ip.ScanSessionID,
+	}
+}
+
+// IPInfo returns the snapshot as IP info, to read its list fields with the
+// IPInfo getters
+func (h *IPInfoHistory) IPInfo() *IPInfo {
+	return &IPInfo{
+		IPAddress:     h.IPAddress,
+		Organization:  h.Organization,
+		ISP:           h.ISP,
+		ASN:           h.ASN,
+		Country:       h.Country,
+		CountryCode:   h.CountryCode,
+		City:          h.City,
+		OS:            h.OS,
+		Tags:          h.Tags,
+		Ports:         h.Ports,
+		Hostnames:     h.Hostnames,
+		Vulns:         h.Vulns,
+		LastUpdate:    h.LastUpdate,
+		ScanSessionID: h.ScanSessionID,
+	}
+}
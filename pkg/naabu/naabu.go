@@ -0,0 +1,334 @@
+// Package naabu runs the naabu port scanner, scanning the ports of the port
+// profile each target gets, and saves the open ports it reports.
+package naabu
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/discovery"
+	"github.com/sensepost/gowitness/pkg/portprofile"
+)
+
+// DetectPorts are the ports scanned on every target to detect the CDNs in
+// front of them, when port profiles match on CDN detection
+var DetectPorts = []int{80, 443}
+
+// Result is a port naabu found, a line of its JSON output
+type Result struct {
+	Host     string `json:"host"`
+	IP       string `json:"ip"`
+	Port     int    `json:"port"`
+	CDN      bool   `json:"cdn"`
+	CDNName  string `json:"cdn-name"`
+	Protocol string `json:"protocol"`
+}
+
+// Options are the naabu settings of a scan
+type Options struct {
+	// TopPorts is scanned if CustomPorts is empty, e.g. 100, 1000 or full
+	TopPorts    string
+	CustomPorts string
+	// Rate is the packets to send per second
+	Rate    int
+	Threads int
+	// Timeout is in milliseconds
+	Timeout int
+	// ExcludeFile lists addresses naabu skips, such as those in CIDR
+	// targets or that domains resolve to
+	ExcludeFile string
+	Verbose     bool
+}
+
+// Args builds the arguments of naabu, which scans ports, or the ports of
+// the options if it is empty. Results are read from naabu's JSON lines on
+// stdout.
+func (o Options) Args(targetsFile string, ports string, resume bool) []string {
+	args := []string{
+		"-l", targetsFile,
+		"-json",
+		"-display-cdn", // Always enable CDN detection for database storage
+	}
+
+	if o.Verbose {
+		args = append(args, "-verbose")
+	}
+
+	if o.ExcludeFile != "" {
+		args = append(args, "-exclude-file", o.ExcludeFile)
+	}
+
+	// naabu keeps its own resume state when interrupted
+	if resume {
+		args = append(args, "-resume")
+	}
+
+	// Port selection
+	if ports != "" {
+		args = append(args, "-p", ports)
+	} else if o.CustomPorts != "" {
+		args = append(args, "-p", o.CustomPorts)
+	} else if o.TopPorts != "" {
+		args = append(args, "-top-ports", o.TopPorts)
+	}
+
+	// Performance settings
+	if o.Rate > 0 {
+		args = append(args, "-rate", fmt.Sprintf("%d", o.Rate))
+	}
+
+	if o.Threads > 0 {
+		args = append(args, "-c", fmt.Sprintf("%d", o.Threads))
+	}
+
+	if o.Timeout > 0 {
+		args = append(args, "-timeout", fmt.Sprintf("%d", o.Timeout))
+	}
+
+	return args
+}
+
+// Installed checks if naabu is in the PATH
+func Installed() bool {
+	_, err := exec.LookPath("naabu")
+	return err == nil
+}
+
+// Execute runs naabu. If ctx is cancelled, naabu is interrupted too, so that
+// it writes its resume state before exiting. Every JSON line naabu prints is
+// passed to result as it arrives, and its output is written to stdout and
+// stderr.
+func Execute(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer, result func(line []byte)) error {
+	out := &output{w: stdout, result: result}
+	cmd := exec.CommandContext(ctx, "naabu", args...)
+	cmd.Stdout = out
+	cmd.Stderr = stderr
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = 30 * time.Second
+
+	err := cmd.Run()
+	out.flush()
+
+	return err
+}
+
+// output passes the lines naabu prints to a result callback as they
+// complete, while passing its output on
+type output struct {
+	w       io.Writer
+	result  func(line []byte)
+	partial []byte
+}
+
+func (o *output) Write(b []byte) (int, error) {
+	o.partial = append(o.partial, b...)
+	for {
+		i := bytes.IndexByte(o.partial, '\n')
+		if i < 0 {
+			break
+		}
+		o.line(o.partial[:i])
+		o.partial = o.partial[i+1:]
+	}
+
+	return o.w.Write(b)
+}
+
+// flush passes on the last line, if naabu did not end it
+func (o *output) flush() {
+	if len(o.partial) > 0 {
+		o.line(o.partial)
+		o.partial = nil
+	}
+}
+
+func (o *output) line(line []byte) {
+	if line = bytes.TrimSpace(line); len(line) > 0 && line[0] == '{' {
+		o.result(line)
+	}
+}
+
+// WriteTargets writes targets to a new file for naabu to read, returning
+// its path
+func WriteTargets(name string, targets []string) (string, error) {
+	file := fmt.Sprintf("gowitness-naabu-%s-%d.txt", islazy.SafeFileName(name), time.Now().UnixNano())
+	if err := os.WriteFile(file, []byte(strings.Join(targets, "\n")+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write targets: %w", err)
+	}
+
+	return file, nil
+}
+
+// JoinPorts formats ports in naabu's -p syntax
+func JoinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Scanner runs naabu over a targets file, once for every port profile
+type Scanner struct {
+	Options Options
+	// Policy sets the ports of every target. Without profiles, all targets
+	// are scanned in a single run with the ports of Options.
+	Policy *portprofile.Policy
+	// Resume continues from naabu's resume state
+	Resume bool
+	// Pass is called before every run of naabu with its title, if it is
+	// set. It returns the writers naabu's output goes to, and a function
+	// that is called once the run ends. Without it, the output is dropped.
+	Pass func(title string) (stdout io.Writer, stderr io.Writer, done func())
+
+	logger *slog.Logger
+}
+
+// NewScanner returns a scanner running naabu with opts
+func NewScanner(logger *slog.Logger, opts Options) *Scanner {
+	return &Scanner{
+		Options: opts,
+		Policy:  &portprofile.Policy{},
+		logger:  logger,
+	}
+}
+
+// Scan runs naabu over the targets in targetsFile, saving the ports it
+// finds with saver as they are reported. If the profiles match on CDN
+// detection, the web ports of every target are scanned first to detect them.
+func (s *Scanner) Scan(ctx context.Context, targetsFile string, saver *Saver) error {
+	// naabu's resume state belongs to the first run
+	resume := s.Resume
+	run := func(title string, file string, ports string) error {
+		defer func() { resume = false }()
+		return s.run(ctx, title, s.Options.Args(file, ports, resume), saver)
+	}
+
+	policy := s.Policy
+	if policy == nil || len(policy.Profiles) == 0 {
+		return run("naabu", targetsFile, "")
+	}
+
+	data, err := os.ReadFile(targetsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read targets: %w", err)
+	}
+	var targets []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			targets = append(targets, line)
+		}
+	}
+
+	// ranges are expanded, so that every address gets its own profile
+	ips, hostnames, err := discovery.Expand(targets)
+	if err != nil {
+		return fmt.Errorf("failed to expand targets: %w", err)
+	}
+	targets = append(hostnames, ips...)
+
+	detected := make(map[string]portprofile.Host)
+	if policy.NeedsCDN() {
+		file, err := WriteTargets("detect", targets)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(file)
+
+		// the ports of hosts that are skipped are not saved
+		saver.setFilter(func(result *Result) bool {
+			host := portprofile.Host{Target: result.Host, IP: result.IP, CDN: result.CDN, Provider: result.CDNName}
+			if _, ok := detected[result.Host]; !ok {
+				detected[result.Host] = host
+			}
+			profile := policy.Match(host)
+			return profile == nil || !profile.Skip
+		})
+		err = run("naabu cdn detection", file, JoinPorts(DetectPorts))
+		saver.setFilter(nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	// group the targets by the profile they get
+	type pass struct {
+		ports   string
+		targets []string
+	}
+	var order []string
+	passes := make(map[string]*pass)
+	var skipped int
+	for _, target := range targets {
+		host, ok := detected[target]
+		if !ok {
+			host = portprofile.Host{Target: target}
+		}
+
+		name, ports := "default", ""
+		if profile := policy.Match(host); profile != nil {
+			if profile.Skip {
+				skipped++
+				continue
+			}
+			// the detection scan covered the ports of the profile already
+			if policy.NeedsCDN() && profile.Covered(DetectPorts) {
+				continue
+			}
+			name, ports = profile.Name, profile.Ports
+		}
+
+		if _, ok := passes[name]; !ok {
+			passes[name] = &pass{ports: ports}
+			order = append(order, name)
+		}
+		passes[name].targets = append(passes[name].targets, target)
+	}
+
+	if skipped > 0 {
+		s.logger.Info("skipping targets by port profile", "count", skipped)
+	}
+
+	for _, name := range order {
+		pass := passes[name]
+		s.logger.Info("scanning targets with port profile", "profile", name, "targets", len(pass.targets), "ports", pass.ports)
+
+		file, err := WriteTargets(name, pass.targets)
+		if err != nil {
+			return err
+		}
+		err = run("naabu "+name, file, pass.ports)
+		os.Remove(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// run runs naabu once with args, saving its results with saver
+func (s *Scanner) run(ctx context.Context, title string, args []string, saver *Saver) error {
+	stdout, stderr := io.Discard, io.Discard
+	if s.Pass != nil {
+		var done func()
+		stdout, stderr, done = s.Pass(title)
+		defer done()
+	}
+
+	s.logger.Info("executing naabu", "args", strings.Join(args, " "))
+
+	return Execute(ctx, args, stdout, stderr, saver.Save)
+}
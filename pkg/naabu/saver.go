@@ -0,0 +1,154 @@
+package naabu
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/writers"
+	"gorm.io/gorm"
+)
+
+// Summary counts the ports of naabu's results
+type Summary struct {
+	Found   int
+	Saved   int
+	Skipped int
+}
+
+// Saver saves the ports naabu reports to the database and writes them as
+// JSON line records, if either is set. It is safe to use from several
+// goroutines.
+type Saver struct {
+	DB      *gorm.DB
+	Records *writers.RecordWriter
+	// Output gets a copy of naabu's JSON lines, if it is set
+	Output        io.Writer
+	ScanSessionID *uint
+	// Count is called with "ports", "saved" or "skipped" for every port
+	// that is counted, if it is set. It is called with the lock held.
+	Count func(name string)
+
+	// filter decides if a result is saved, if it is set. It is called
+	// with mu held.
+	filter func(result *Result) bool
+
+	logger  *slog.Logger
+	mu      sync.Mutex
+	summary Summary
+}
+
+// NewSaver returns a saver saving ports to db and writing them to records.
+// Either may be nil.
+func NewSaver(logger *slog.Logger, db *gorm.DB, records *writers.RecordWriter) *Saver {
+	return &Saver{
+		DB:      db,
+		Records: records,
+		logger:  logger,
+	}
+}
+
+// Summary returns the ports counted so far
+func (s *Saver) Summary() Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.summary
+}
+
+func (s *Saver) setFilter(filter func(result *Result) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.filter = filter
+}
+
+// Save saves a port from a JSON line of naabu's output
+func (s *Saver) Save(line []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.summary.Found++
+	s.count("ports")
+
+	if s.Output != nil {
+		if _, err := s.Output.Write(append(line, '\n')); err != nil {
+			s.logger.Warn("failed to write naabu result to the output file", "err", err)
+		}
+	}
+
+	var result Result
+	if err := json.Unmarshal(line, &result); err != nil {
+		s.logger.Warn("failed to parse naabu result line", "line", string(line), "err", err)
+		s.skip()
+		return
+	}
+
+	if s.filter != nil && !s.filter(&result) {
+		s.skip()
+		return
+	}
+
+	// Create IPPort entry
+	ipPort := models.IPPort{
+		IPAddress:     result.IP,
+		Port:          result.Port,
+		Protocol:      result.Protocol, // Use protocol from naabu result
+		State:         "open",
+		ScanSessionID: s.ScanSessionID,
+		IsCDN:         result.CDN,
+		CDNName:       result.CDNName,
+		CDNDetected:   true, // We always run CDN detection
+		OriginalHost:  result.Host,
+	}
+
+	if s.Records != nil {
+		if err := s.Records.Write("ip_port", "naabu", &ipPort); err != nil {
+			s.logger.Warn("failed to write port record", "ip", result.IP, "port", result.Port, "err", err)
+		}
+	}
+	if s.DB == nil {
+		s.summary.Saved++
+		s.count("saved")
+		return
+	}
+
+	// Check if this IP:Port combination already exists
+	var existing models.IPPort
+	if err := s.DB.Where("ip_address = ? AND port = ?", result.IP, result.Port).First(&existing).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warn("database error checking for existing port", "ip", result.IP, "port", result.Port, "err", err)
+			s.skip()
+			return
+		}
+
+		// Not found, create new record
+		if err := s.DB.Create(&ipPort).Error; err != nil {
+			s.logger.Warn("failed to save port result", "ip", result.IP, "port", result.Port, "err", err)
+			s.skip()
+			return
+		}
+		s.summary.Saved++
+		s.count("saved")
+		return
+	}
+
+	// Record already exists, skip
+	s.skip()
+}
+
+// skip counts a port that was not saved. s.mu must be held.
+func (s *Saver) skip() {
+	s.summary.Skipped++
+	s.count("skipped")
+}
+
+// count passes a counted port on to Count. s.mu must be held.
+func (s *Saver) count(name string) {
+	if s.Count != nil {
+		s.Count(name)
+	}
+}
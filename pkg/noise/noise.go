@@ -0,0 +1,170 @@
+// Package noise recognises boring pages, such as parked domains and default
+// web server pages, so that they can be hidden during screenshot triage.
+package noise
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// maxBodySize is how much of a response body is searched
+const maxBodySize = 512 << 10
+
+// defaultMaxDistance is how many bits the perception hash of a result may
+// differ from a signature's by default
+const defaultMaxDistance = 6
+
+// Signature identifies a boring page. Every pattern that is set must match
+// for a result to match the signature.
+type Signature struct {
+	// Name is recorded as the noise reason of matching results
+	Name string `json:"name"`
+	// Title is a regular expression matched against the page title
+	Title string `json:"title,omitempty"`
+	// Body is a regular expression matched against the response body
+	Body string `json:"body,omitempty"`
+	// PerceptionHash is the perception hash of a screenshot of the page,
+	// as "p:<hex>", and MaxDistance how many bits a result's may differ
+	PerceptionHash string `json:"perception_hash,omitempty"`
+	MaxDistance    int    `json:"max_distance,omitempty"`
+
+	title *regexp.Regexp
+	body  *regexp.Regexp
+	hash  []byte
+}
+
+// compile parses the patterns of a signature
+func (s *Signature) compile() error {
+	if s.Name == "" {
+		return errors.New("signature has no name")
+	}
+	if s.Title == "" && s.Body == "" && s.PerceptionHash == "" {
+		return fmt.Errorf("signature %s has no title, body or perception hash", s.Name)
+	}
+
+	var err error
+	if s.Title != "" {
+		if s.title, err = regexp.Compile(s.Title); err != nil {
+			return fmt.Errorf("signature %s has an invalid title pattern: %w", s.Name, err)
+		}
+	}
+	if s.Body != "" {
+		if s.body, err = regexp.Compile(s.Body); err != nil {
+			return fmt.Errorf("signature %s has an invalid body pattern: %w", s.Name, err)
+		}
+	}
+	if s.PerceptionHash != "" {
+		if s.hash, err = islazy.ParsePerceptionHash(s.PerceptionHash); err != nil {
+			return fmt.Errorf("signature %s has an invalid perception hash: %w", s.Name, err)
+		}
+		if s.MaxDistance <= 0 {
+			s.MaxDistance = defaultMaxDistance
+		}
+	}
+
+	return nil
+}
+
+// matches checks if a result matches every pattern of the signature
+func (s *Signature) matches(result *models.Result, body string) bool {
+	if s.title != nil && !s.title.MatchString(result.Title) {
+		return false
+	}
+	if s.body != nil && !s.body.MatchString(body) {
+		return false
+	}
+	if s.hash != nil {
+		hash, err := islazy.ParsePerceptionHash(result.PerceptionHash)
+		if err != nil {
+			return false
+		}
+		distance, err := islazy.HammingDistance(s.hash, hash)
+		if err != nil || distance > s.MaxDistance {
+			return false
+		}
+	}
+
+	return true
+}
+
+// defaults are the signatures of common parked domains and default pages
+var defaults = []Signature{
+	{Name: "parked-domain", Body: `(?i)(this domain (name )?(is|may be) for sale|buy this domain|domain is parked|parked (free|domain)|parkingcrew|sedoparking|bodis\.com|above\.com/marketing)`},
+	{Name: "parked-domain", Title: `(?i)(domain (name )?for sale|parked domain|this domain is (parked|for sale)|^\s*coming soon\s*$)`},
+	{Name: "godaddy-parked", Body: `(?i)(img\d?\.wsimg\.com/parking-lander|parking-lander)`},
+	{Name: "suspended-account", Title: `(?i)(account suspended|this account has been suspended)`},
+	{Name: "default-page", Title: `(?i)^\s*(welcome to nginx!?|apache2 (ubuntu|debian) default page.*|test page for the (apache|nginx) http server.*|iis windows server|iis\d* welcome|it works!?|welcome to centos|default web site page)\s*$`},
+	{Name: "cpanel-default", Title: `(?i)^\s*default (web site|domain) page\s*$`},
+}
+
+// List is a list of signatures of boring pages
+type List struct {
+	signatures []Signature
+}
+
+// Default returns the list of the default signatures
+func Default() *List {
+	list := &List{}
+	for _, signature := range defaults {
+		// the defaults are known to compile
+		_ = signature.compile()
+		list.signatures = append(list.signatures, signature)
+	}
+
+	return list
+}
+
+// Load returns the default signatures along with those in a JSON file,
+// which holds an array of signatures. An empty path only loads the
+// defaults.
+func Load(path string) (*List, error) {
+	list := Default()
+	if path == "" {
+		return list, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read noise signatures: %w", err)
+	}
+
+	var signatures []Signature
+	if err := json.Unmarshal(data, &signatures); err != nil {
+		return nil, fmt.Errorf("failed to parse noise signatures: %w", err)
+	}
+
+	for _, signature := range signatures {
+		if err := signature.compile(); err != nil {
+			return nil, err
+		}
+		list.signatures = append(list.signatures, signature)
+	}
+
+	return list, nil
+}
+
+// Match returns the name of the first signature a result matches
+func (l *List) Match(result *models.Result) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+
+	body := result.HTML
+	if len(body) > maxBodySize {
+		body = body[:maxBodySize]
+	}
+
+	for i := range l.signatures {
+		if l.signatures[i].matches(result, body) {
+			return l.signatures[i].Name, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,465 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// clockSkew is how far the clocks of the provider and gowitness may differ
+const clockSkew = time.Minute
+
+// Config configures an OpenID Connect provider
+type Config struct {
+	// Issuer is the issuer URL of the provider, e.g. https://accounts.google.com
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Scopes requested in addition to openid
+	Scopes []string
+}
+
+// Claims are the ID token claims gowitness uses
+type Claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	Expiry            int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	PreferredUsername string   `json:"preferred_username"`
+	Name              string   `json:"name"`
+	Groups            []string `json:"groups"`
+}
+
+// Username returns the name a user is known by. A verified email address is
+// preferred, falling back to the subject, which is unique per provider.
+func (c *Claims) Username() string {
+	if c.Email != "" && c.EmailVerified {
+		return strings.ToLower(c.Email)
+	}
+
+	return c.Subject
+}
+
+// Allowed checks if a user may log in. With domains, their verified email
+// address must be in one of them, and with groups they must be a member of
+// one of them. Both are checked if both are given.
+func (c *Claims) Allowed(domains []string, groups []string) error {
+	if len(domains) > 0 {
+		_, domain, ok := strings.Cut(strings.ToLower(c.Email), "@")
+		if !ok || !c.EmailVerified {
+			return errors.New("no verified email address to check the domain of")
+		}
+		if !slices.ContainsFunc(domains, func(d string) bool { return strings.EqualFold(d, domain) }) {
+			return fmt.Errorf("email domain %s is not allowed", domain)
+		}
+	}
+
+	if len(groups) > 0 && !slices.ContainsFunc(c.Groups, func(g string) bool { return slices.Contains(groups, g) }) {
+		return errors.New("not a member of an allowed group")
+	}
+
+	return nil
+}
+
+// audience is an aud claim, which may be a string or an array
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = list
+
+	return nil
+}
+
+// discovery is the provider metadata gowitness uses
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID Connect provider, used for the authorization code
+// flow
+type Provider struct {
+	config   Config
+	metadata discovery
+	client   *http.Client
+
+	mu   sync.Mutex
+	keys map[string]crypto.PublicKey
+}
+
+// NewProvider discovers the endpoints of a provider
+func NewProvider(ctx context.Context, config Config) (*Provider, error) {
+	if config.Issuer == "" || config.ClientID == "" {
+		return nil, errors.New("an issuer and client id are required")
+	}
+
+	p := &Provider{
+		config: config,
+		client: islazy.NewHTTPClient(15 * time.Second),
+		keys:   make(map[string]crypto.PublicKey),
+	}
+
+	wellKnown := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, &p.metadata); err != nil {
+		return nil, fmt.Errorf("failed to discover provider: %w", err)
+	}
+	if strings.TrimSuffix(p.metadata.Issuer, "/") != strings.TrimSuffix(config.Issuer, "/") {
+		return nil, fmt.Errorf("provider reports issuer %q, expected %q", p.metadata.Issuer, config.Issuer)
+	}
+	if p.metadata.AuthorizationEndpoint == "" || p.metadata.TokenEndpoint == "" || p.metadata.JWKSURI == "" {
+		return nil, errors.New("provider metadata is missing endpoints")
+	}
+
+	return p, nil
+}
+
+// AuthCodeURL returns the URL users are sent to to log in
+func (p *Provider) AuthCodeURL(state string, nonce string, redirectURL string) string {
+	scopes := append([]string{"openid"}, p.config.Scopes...)
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", redirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+
+	sep := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return p.metadata.AuthorizationEndpoint + sep + params.Encode()
+}
+
+// Exchange exchanges an authorization code for an ID token, returning its
+// verified claims
+func (p *Provider) Exchange(ctx context.Context, code string, nonce string, redirectURL string) (*Claims, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	form.Set("client_id", p.config.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return p.Verify(ctx, token.IDToken, nonce)
+}
+
+// Verify verifies the signature and claims of an ID token
+func (p *Provider) Verify(ctx context.Context, idToken string, nonce string) (*Claims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed id token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed id token signature: %w", err)
+	}
+
+	key, err := p.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed id token claims: %w", err)
+	}
+
+	now := time.Now()
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != strings.TrimSuffix(p.config.Issuer, "/"):
+		return nil, fmt.Errorf("id token issued by %q", claims.Issuer)
+	case !slices.Contains(claims.Audience, p.config.ClientID):
+		return nil, errors.New("id token is not for this client")
+	case now.After(time.Unix(claims.Expiry, 0).Add(clockSkew)):
+		return nil, errors.New("id token expired")
+	case claims.IssuedAt > 0 && time.Unix(claims.IssuedAt, 0).After(now.Add(clockSkew)):
+		return nil, errors.New("id token issued in the future")
+	case claims.Nonce != nonce:
+		return nil, errors.New("id token nonce mismatch")
+	case claims.Subject == "":
+		return nil, errors.New("id token has no subject")
+	}
+
+	return &claims, nil
+}
+
+// key returns the signing key with a key id, refreshing the key set if the
+// key is unknown, as providers rotate keys
+func (p *Provider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key := p.lookup(kid); key != nil {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.metadata.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to get provider keys: %w", err)
+	}
+
+	p.keys = make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		p.keys[k.Kid] = key
+	}
+
+	if key := p.lookup(kid); key != nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("no provider key with id %q", kid)
+}
+
+// lookup finds a cached key. Tokens without a key id match the only key.
+func (p *Provider) lookup(kid string) crypto.PublicKey {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+
+	return p.keys[kid]
+}
+
+// getJSON gets a JSON document
+func (p *Provider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, u)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// jwk is a JSON web key
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey returns the public key of an RSA or EC key
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid ec key")
+		}
+
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// verifySignature verifies a JWS signature. Only asymmetric algorithms are
+// accepted.
+func verifySignature(alg string, key crypto.PublicKey, signed []byte, signature []byte) error {
+	var h hash.Hash
+	var hashType crypto.Hash
+	switch alg {
+	case "RS256", "ES256", "PS256":
+		h, hashType = sha256.New(), crypto.SHA256
+	case "RS384", "ES384", "PS384":
+		h, hashType = sha512.New384(), crypto.SHA384
+	case "RS512", "ES512", "PS512":
+		h, hashType = sha512.New(), crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported id token algorithm %q", alg)
+	}
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg[0] {
+	case 'R', 'P':
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("id token algorithm does not match key")
+		}
+		var err error
+		if alg[0] == 'R' {
+			err = rsa.VerifyPKCS1v15(rsaKey, hashType, digest, signature)
+		} else {
+			err = rsa.VerifyPSS(rsaKey, hashType, digest, signature, nil)
+		}
+		if err != nil {
+			return errors.New("invalid id token signature")
+		}
+
+	case 'E':
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("id token algorithm does not match key")
+		}
+		// each ES algorithm has its own curve
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if ecKey.Curve.Params().BitSize != map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}[alg] {
+			return errors.New("id token algorithm does not match key")
+		}
+		if len(signature) != 2*size {
+			return errors.New("invalid id token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return errors.New("invalid id token signature")
+		}
+	}
+
+	return nil
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a JWT
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
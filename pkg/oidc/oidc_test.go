@@ -0,0 +1,339 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testProvider is an identity provider serving discovery and its keys
+type testProvider struct {
+	server *httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := &testProvider{rsaKey: rsaKey, ecKey: ecKey}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(discovery{
+			Issuer:                tp.server.URL,
+			AuthorizationEndpoint: tp.server.URL + "/authorize",
+			TokenEndpoint:         tp.server.URL + "/token",
+			JWKSURI:               tp.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		json.NewEncoder(w).Encode(map[string][]jwk{"keys": {
+			{
+				Kty: "RSA", Kid: "rsa", Use: "sig",
+				N: b64(rsaKey.N.Bytes()),
+				E: b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				Kty: "EC", Kid: "ec", Use: "sig", Crv: "P-256",
+				X: b64(ecKey.X.FillBytes(make([]byte, size))),
+				Y: b64(ecKey.Y.FillBytes(make([]byte, size))),
+			},
+		}})
+	})
+	tp.server = httptest.NewServer(mux)
+	t.Cleanup(tp.server.Close)
+
+	return tp
+}
+
+// claims returns valid claims for a token for client gowitness
+func (tp *testProvider) claims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss":   tp.server.URL,
+		"sub":   "user-1",
+		"aud":   "gowitness",
+		"exp":   now.Add(time.Hour).Unix(),
+		"iat":   now.Unix(),
+		"nonce": "n0nce",
+	}
+}
+
+// sign returns a token of claims with a header, signed with alg
+func (tp *testProvider) sign(t *testing.T, header map[string]any, claims map[string]any) string {
+	t.Helper()
+
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := b64(h) + "." + b64(c)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	var err error
+	switch header["alg"] {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, tp.rsaKey, crypto.SHA256, digest[:])
+	case "PS256":
+		signature, err = rsa.SignPSS(rand.Reader, tp.rsaKey, crypto.SHA256, digest[:], nil)
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, tp.ecKey, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case "HS256":
+		// keyed with the public key, as in key confusion attacks
+		mac := hmac.New(sha256.New, tp.rsaKey.N.Bytes())
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + b64(signature)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func TestVerify(t *testing.T) {
+	tp := newTestProvider(t)
+	provider, err := NewProvider(context.Background(), Config{Issuer: tp.server.URL, ClientID: "gowitness"})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	rs256 := map[string]any{"alg": "RS256", "kid": "rsa"}
+	with := func(key string, value any) map[string]any {
+		claims := tp.claims()
+		claims[key] = value
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		nonce   string
+		wantErr string
+	}{
+		{
+			name:  "Test valid RS256 token",
+			token: tp.sign(t, rs256, tp.claims()),
+			nonce: "n0nce",
+		},
+		{
+			name:  "Test valid PS256 token",
+			token: tp.sign(t, map[string]any{"alg": "PS256", "kid": "rsa"}, tp.claims()),
+			nonce: "n0nce",
+		},
+		{
+			name:  "Test valid ES256 token",
+			token: tp.sign(t, map[string]any{"alg": "ES256", "kid": "ec"}, tp.claims()),
+			nonce: "n0nce",
+		},
+		{
+			name:  "Test audience array",
+			token: tp.sign(t, rs256, with("aud", []string{"other", "gowitness"})),
+			nonce: "n0nce",
+		},
+		{
+			name: "Test bad signature",
+			token: func() string {
+				token := tp.sign(t, rs256, tp.claims())
+				parts := strings.Split(token, ".")
+				forged, _ := json.Marshal(with("sub", "admin"))
+				return parts[0] + "." + b64(forged) + "." + parts[2]
+			}(),
+			nonce:   "n0nce",
+			wantErr: "invalid id token signature",
+		},
+		{
+			name:    "Test unsigned token",
+			token:   b64([]byte(`{"alg":"none","kid":"rsa"}`)) + "." + b64(must(json.Marshal(tp.claims()))) + ".",
+			nonce:   "n0nce",
+			wantErr: "unsupported id token algorithm",
+		},
+		{
+			name:    "Test HMAC keyed with the public key",
+			token:   tp.sign(t, map[string]any{"alg": "HS256", "kid": "rsa"}, tp.claims()),
+			nonce:   "n0nce",
+			wantErr: "unsupported id token algorithm",
+		},
+		{
+			name:    "Test RSA algorithm with EC key",
+			token:   tp.sign(t, map[string]any{"alg": "RS256", "kid": "ec"}, tp.claims()),
+			nonce:   "n0nce",
+			wantErr: "algorithm does not match key",
+		},
+		{
+			name:    "Test EC algorithm with RSA key",
+			token:   tp.sign(t, map[string]any{"alg": "ES256", "kid": "rsa"}, tp.claims()),
+			nonce:   "n0nce",
+			wantErr: "algorithm does not match key",
+		},
+		{
+			name:    "Test unknown key id",
+			token:   tp.sign(t, map[string]any{"alg": "RS256", "kid": "gone"}, tp.claims()),
+			nonce:   "n0nce",
+			wantErr: "no provider key",
+		},
+		{
+			name:    "Test wrong issuer",
+			token:   tp.sign(t, rs256, with("iss", "https://evil.example.com")),
+			nonce:   "n0nce",
+			wantErr: "id token issued by",
+		},
+		{
+			name:    "Test wrong audience",
+			token:   tp.sign(t, rs256, with("aud", "other")),
+			nonce:   "n0nce",
+			wantErr: "not for this client",
+		},
+		{
+			name:    "Test expired token",
+			token:   tp.sign(t, rs256, with("exp", time.Now().Add(-2*clockSkew).Unix())),
+			nonce:   "n0nce",
+			wantErr: "id token expired",
+		},
+		{
+			name:    "Test token issued in the future",
+			token:   tp.sign(t, rs256, with("iat", time.Now().Add(2*clockSkew).Unix())),
+			nonce:   "n0nce",
+			wantErr: "issued in the future",
+		},
+		{
+			name:    "Test nonce mismatch",
+			token:   tp.sign(t, rs256, tp.claims()),
+			nonce:   "other",
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "Test missing subject",
+			token:   tp.sign(t, rs256, with("sub", "")),
+			nonce:   "n0nce",
+			wantErr: "no subject",
+		},
+		{
+			name:    "Test malformed token",
+			token:   "not-a-token",
+			nonce:   "n0nce",
+			wantErr: "malformed id token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := provider.Verify(context.Background(), tt.token, tt.nonce)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				if claims.Subject != "user-1" {
+					t.Errorf("Verify() subject = %q, want %q", claims.Subject, "user-1")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewProviderIssuerMismatch(t *testing.T) {
+	tp := newTestProvider(t)
+	_, err := NewProvider(context.Background(), Config{Issuer: tp.server.URL + "/other", ClientID: "gowitness"})
+	if err == nil {
+		t.Fatal("NewProvider() error = nil, want an error for a mismatched issuer")
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  Claims
+		domains []string
+		groups  []string
+		want    bool
+	}{
+		{
+			name:   "Test no restrictions",
+			claims: Claims{Subject: "user-1"},
+			want:   true,
+		},
+		{
+			name:    "Test allowed domain",
+			claims:  Claims{Email: "Bob@Example.com", EmailVerified: true},
+			domains: []string{"example.com"},
+			want:    true,
+		},
+		{
+			name:    "Test unverified email",
+			claims:  Claims{Email: "bob@example.com"},
+			domains: []string{"example.com"},
+		},
+		{
+			name:    "Test other domain",
+			claims:  Claims{Email: "bob@example.com.evil.net", EmailVerified: true},
+			domains: []string{"example.com"},
+		},
+		{
+			name:   "Test allowed group",
+			claims: Claims{Groups: []string{"staff", "gowitness"}},
+			groups: []string{"gowitness"},
+			want:   true,
+		},
+		{
+			name:   "Test other group",
+			claims: Claims{Groups: []string{"staff"}},
+			groups: []string{"gowitness"},
+		},
+		{
+			name:    "Test domain and group both required",
+			claims:  Claims{Email: "bob@example.com", EmailVerified: true, Groups: []string{"staff"}},
+			domains: []string{"example.com"},
+			groups:  []string{"gowitness"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.claims.Allowed(tt.domains, tt.groups)
+			if (err == nil) != tt.want {
+				t.Errorf("Allowed() error = %v, want allowed %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func must(b []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
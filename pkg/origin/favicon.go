@@ -0,0 +1,74 @@
+package origin
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math/bits"
+	"strings"
+)
+
+// FaviconHash calculates the favicon hash Shodan indexes as
+// http.favicon.hash. It is the signed 32 bit murmur3 hash of the favicon,
+// base64 encoded with a newline every 76 characters.
+func FaviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var builder strings.Builder
+	for len(encoded) > 76 {
+		builder.WriteString(encoded[:76])
+		builder.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	builder.WriteString(encoded)
+	builder.WriteByte('\n')
+
+	return int32(murmur3([]byte(builder.String()), 0))
+}
+
+// murmur3 is the 32 bit x86 variant of murmur3
+func murmur3(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	h := seed
+	n := len(data) / 4
+
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	tail := data[n*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
@@ -0,0 +1,275 @@
+package origin
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/passivedns"
+	"github.com/sensepost/gowitness/pkg/shodan"
+)
+
+// Sources that can suggest origin candidates
+const (
+	SourceHistoricalDNS = "historical-dns"
+	SourceFavicon       = "favicon-hash"
+	SourceCertificate   = "ssl-cert"
+)
+
+// maxBodySize is the most of a response body read when probing
+const maxBodySize = 1 << 20
+
+var titleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// Candidate is an IP address that may be the origin of a CDN fronted host
+type Candidate struct {
+	Hostname string
+	IP       string
+	Source   string
+	// Evidence describes why the candidate was suggested
+	Evidence string
+}
+
+// Probe is the outcome of requesting a host's page from a candidate directly
+type Probe struct {
+	// Verified is true if the candidate served the same page as the CDN
+	Verified bool
+	Evidence string
+}
+
+// Discoverer suggests origin candidates for CDN fronted hosts
+type Discoverer struct {
+	// Shodan is used for favicon and certificate pivots. Nil disables them.
+	Shodan *shodan.Client
+	// History are the passive DNS providers used for historical records
+	History []passivedns.HistoryProvider
+	// MaxPerSource is the most candidates a Shodan pivot may return
+	MaxPerSource int
+	// Timeout is the timeout of http requests made to hosts and candidates
+	Timeout time.Duration
+
+	log *slog.Logger
+}
+
+// NewDiscoverer returns a new Discoverer
+func NewDiscoverer(logger *slog.Logger, shodanClient *shodan.Client, history []passivedns.HistoryProvider) *Discoverer {
+	return &Discoverer{
+		Shodan:       shodanClient,
+		History:      history,
+		MaxPerSource: 50,
+		Timeout:      10 * time.Second,
+		log:          logger,
+	}
+}
+
+// Discover suggests origin candidates for a hostname. IPs in exclude, such
+// as the CDN edges the hostname resolves to, are never suggested.
+func (d *Discoverer) Discover(hostname string, exclude map[string]bool) []Candidate {
+	var candidates []Candidate
+	seen := make(map[string]bool)
+
+	add := func(ip, source, evidence string) {
+		key := ip + "|" + source
+		if exclude[ip] || seen[key] || net.ParseIP(ip) == nil {
+			return
+		}
+		seen[key] = true
+		candidates = append(candidates, Candidate{
+			Hostname: hostname,
+			IP:       ip,
+			Source:   source,
+			Evidence: evidence,
+		})
+	}
+
+	for _, provider := range d.History {
+		records, err := provider.History(hostname)
+		if err != nil {
+			d.log.Warn("historical dns lookup failed", "hostname", hostname, "provider", provider.Name(), "err", err)
+			continue
+		}
+
+		for _, record := range records {
+			evidence := fmt.Sprintf("%s A record", provider.Name())
+			if !record.LastSeen.IsZero() {
+				evidence += " last seen " + record.LastSeen.Format(time.DateOnly)
+			}
+			add(record.IP, SourceHistoricalDNS, evidence)
+		}
+	}
+
+	if d.Shodan == nil {
+		return candidates
+	}
+
+	if favicon, err := d.favicon(hostname); err != nil {
+		d.log.Debug("could not get favicon", "hostname", hostname, "err", err)
+	} else {
+		hash := FaviconHash(favicon)
+		ips, err := d.Shodan.SearchIPs(fmt.Sprintf("http.favicon.hash:%d", hash), d.MaxPerSource)
+		if err != nil {
+			d.log.Warn("favicon hash pivot failed", "hostname", hostname, "err", err)
+		}
+		for _, ip := range ips {
+			add(ip, SourceFavicon, fmt.Sprintf("shares favicon hash %d", hash))
+		}
+	}
+
+	ips, err := d.Shodan.SearchIPs(fmt.Sprintf("ssl:%q", hostname), d.MaxPerSource)
+	if err != nil {
+		d.log.Warn("certificate pivot failed", "hostname", hostname, "err", err)
+	}
+	for _, ip := range ips {
+		add(ip, SourceCertificate, fmt.Sprintf("serves a certificate naming %s", hostname))
+	}
+
+	return candidates
+}
+
+// Verify requests a hostname's page from each candidate directly,
+// comparing it to the page served through the CDN. Probes are keyed by
+// candidate IP.
+func (d *Discoverer) Verify(hostname string, candidates []Candidate) (map[string]*Probe, error) {
+	baseline, err := d.fetch(hostname, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page through cdn: %w", err)
+	}
+
+	probes := make(map[string]*Probe)
+	for _, candidate := range candidates {
+		if _, ok := probes[candidate.IP]; ok {
+			continue
+		}
+		probes[candidate.IP] = d.compare(hostname, candidate.IP, baseline)
+	}
+
+	return probes, nil
+}
+
+// compare fetches a hostname's page from ip and compares it to baseline
+func (d *Discoverer) compare(hostname string, ip string, baseline *page) *Probe {
+	direct, err := d.fetch(hostname, ip)
+	if err != nil {
+		return &Probe{Evidence: fmt.Sprintf("not reachable directly: %s", err)}
+	}
+
+	switch {
+	case baseline.title != "" && baseline.title == direct.title:
+		return &Probe{Verified: true, Evidence: fmt.Sprintf("serves the same title %q", direct.title)}
+	case baseline.title == "" && direct.title == "" && baseline.status == direct.status &&
+		similarSize(baseline.size, direct.size):
+		return &Probe{Verified: true, Evidence: fmt.Sprintf("serves a similar page (status %d, %d bytes)", direct.status, direct.size)}
+	}
+
+	return &Probe{Evidence: fmt.Sprintf("serves a different page (status %d, title %q)", direct.status, direct.title)}
+}
+
+// page is a summary of a fetched page
+type page struct {
+	status int
+	title  string
+	size   int
+}
+
+// fetch fetches https://hostname/, falling back to http. If ip is set the
+// connection is made to ip instead of resolving hostname, with the
+// hostname still used for SNI and the Host header.
+func (d *Discoverer) fetch(hostname string, ip string) (*page, error) {
+	var err error
+	for _, scheme := range []string{"https", "http"} {
+		var resp *http.Response
+		resp, err = d.client(ip).Get(scheme + "://" + hostname + "/")
+		if err != nil {
+			continue
+		}
+
+		body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		resp.Body.Close()
+		if readErr != nil {
+			err = readErr
+			continue
+		}
+
+		p := &page{status: resp.StatusCode, size: len(body)}
+		if match := titleRegex.FindSubmatch(body); match != nil {
+			p.title = strings.TrimSpace(string(match[1]))
+		}
+
+		return p, nil
+	}
+
+	return nil, err
+}
+
+// favicon fetches the favicon of a hostname
+func (d *Discoverer) favicon(hostname string) ([]byte, error) {
+	resp, err := d.client("").Get("https://" + hostname + "/favicon.ico")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty favicon")
+	}
+
+	return data, nil
+}
+
+// client returns an http client that does not follow redirects. If ip is
+// set, all connections are made to ip.
+func (d *Discoverer) client(ip string) *http.Client {
+	dialer := &net.Dialer{Timeout: d.Timeout}
+	transport := &http.Transport{
+		// origins rarely have valid certificates for the hostname
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if ip != "" {
+				_, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				addr = net.JoinHostPort(ip, port)
+			}
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+
+	return &http.Client{
+		Timeout:   d.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// similarSize checks if two body sizes are within 10% of each other
+func similarSize(a, b int) bool {
+	if a == b {
+		return true
+	}
+
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return diff*10 <= max(a, b)
+}
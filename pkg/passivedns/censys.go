@@ -0,0 +1,122 @@
+package passivedns
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// censysMaxPages is the most result pages fetched for a domain
+const censysMaxPages = 10
+
+// Censys is a Censys certificate search client
+type Censys struct {
+	apiID      string
+	apiSecret  string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// censysCertificates is the response from the certificate search endpoint
+type censysCertificates struct {
+	Result struct {
+		Hits []struct {
+			Names    []string `json:"names"`
+			Validity struct {
+				End string `json:"end"`
+			} `json:"validity"`
+		} `json:"hits"`
+		Links struct {
+			Next string `json:"next"`
+		} `json:"links"`
+	} `json:"result"`
+}
+
+// NewCensys returns a new Censys client
+func NewCensys(apiID, apiSecret string) *Censys {
+	return &Censys{
+		apiID:      apiID,
+		apiSecret:  apiSecret,
+		baseURL:    "https://search.censys.io/api/v2",
+		httpClient: islazy.NewHTTPClient(30 * time.Second),
+	}
+}
+
+// Name returns the provider name
+func (c *Censys) Name() string {
+	return "censys"
+}
+
+// Subdomains returns the hostnames in certificates Censys has seen for a
+// domain. Hostnames only seen in expired certificates are reported as
+// historical.
+func (c *Censys) Subdomains(domain string) ([]Subdomain, error) {
+	now := time.Now()
+	names := make(certificateNames)
+
+	cursor := ""
+	for page := 0; page < censysMaxPages; page++ {
+		response, err := c.search("names: "+domain, cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hit := range response.Result.Hits {
+			end, _ := time.Parse(time.RFC3339, hit.Validity.End)
+			for _, name := range hit.Names {
+				names.add(domain, name, end.After(now))
+			}
+		}
+
+		cursor = response.Result.Links.Next
+		if cursor == "" {
+			break
+		}
+	}
+
+	return names.subdomains(c.Name(), now), nil
+}
+
+// search fetches a page of certificate search results
+func (c *Censys) search(q string, cursor string) (*censysCertificates, error) {
+	query := url.Values{}
+	query.Set("q", q)
+	query.Set("per_page", "100")
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/certificates/search?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.apiID, c.apiSecret)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Censys API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Censys API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response censysCertificates
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse Censys response: %w", err)
+	}
+
+	return &response, nil
+}
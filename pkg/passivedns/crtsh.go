@@ -0,0 +1,129 @@
+package passivedns
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// CrtSh is a crt.sh certificate transparency log search client. It needs
+// no API key.
+type CrtSh struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// crtShEntry is a certificate in a crt.sh search response
+type crtShEntry struct {
+	CommonName string `json:"common_name"`
+	// NameValue holds the certificate's names, one per line
+	NameValue string `json:"name_value"`
+	NotAfter  string `json:"not_after"`
+}
+
+// NewCrtSh returns a new crt.sh client
+func NewCrtSh() *CrtSh {
+	return &CrtSh{
+		baseURL: "https://crt.sh",
+		// crt.sh is slow for large domains
+		httpClient: islazy.NewHTTPClient(120 * time.Second),
+	}
+}
+
+// Name returns the provider name
+func (c *CrtSh) Name() string {
+	return "crtsh"
+}
+
+// Subdomains returns the hostnames in certificates logged for a domain.
+// Hostnames only seen in expired certificates are reported as historical.
+func (c *CrtSh) Subdomains(domain string) ([]Subdomain, error) {
+	query := url.Values{}
+	query.Set("q", "%."+domain)
+	query.Set("output", "json")
+
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crt.sh: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("crt.sh error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var entries []crtShEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse crt.sh response: %w", err)
+	}
+
+	now := time.Now()
+	names := make(certificateNames)
+	for _, entry := range entries {
+		// not_after is reported without a timezone, in UTC
+		notAfter, _ := time.Parse("2006-01-02T15:04:05", entry.NotAfter)
+		valid := notAfter.After(now)
+
+		names.add(domain, entry.CommonName, valid)
+		for _, name := range strings.Split(entry.NameValue, "\n") {
+			names.add(domain, name, valid)
+		}
+	}
+
+	return names.subdomains(c.Name(), now), nil
+}
+
+// certificateNames collects the in scope hostnames found in certificates,
+// and whether any currently valid certificate names them
+type certificateNames map[string]bool
+
+// add adds a certificate name if it is a hostname of domain. Wildcard
+// names and email addresses are skipped.
+func (n certificateNames) add(domain string, name string, valid bool) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	if name == "" || strings.ContainsAny(name, "*@ ") {
+		return
+	}
+	if name != domain && !strings.HasSuffix(name, "."+domain) {
+		return
+	}
+
+	n[name] = n[name] || valid
+}
+
+// subdomains returns the collected names as subdomains, sorted by name
+func (n certificateNames) subdomains(source string, seenAt time.Time) []Subdomain {
+	results := make([]Subdomain, 0, len(n))
+	for name, valid := range n {
+		results = append(results, Subdomain{
+			Hostname: name,
+			Source:   source,
+			Current:  valid,
+			SeenAt:   seenAt,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Hostname < results[j].Hostname
+	})
+
+	return results
+}
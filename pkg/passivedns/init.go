@@ -0,0 +1,53 @@
+package passivedns
+
+import (
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+// ProvidersFromEnv returns all of the providers that have API keys
+// configured in the environment. It attempts to load from a .env file
+// first, then falls back to the system environment.
+func ProvidersFromEnv() []Provider {
+	// Try to load .env file (ignore errors as it may not exist)
+	_ = godotenv.Load()
+
+	var providers []Provider
+
+	if apiKey := os.Getenv("SECURITYTRAILS_API_KEY"); apiKey != "" {
+		providers = append(providers, NewSecurityTrails(apiKey))
+	}
+
+	return providers
+}
+
+// HistoryProvidersFromEnv returns the configured providers that can list
+// historical DNS records
+func HistoryProvidersFromEnv() []HistoryProvider {
+	var providers []HistoryProvider
+
+	for _, provider := range ProvidersFromEnv() {
+		if history, ok := provider.(HistoryProvider); ok {
+			providers = append(providers, history)
+		}
+	}
+
+	return providers
+}
+
+// CertificateProvidersFromEnv returns the certificate transparency
+// providers. crt.sh needs no API key and is always returned, while Censys
+// is added when CENSYS_API_ID and CENSYS_API_SECRET are configured.
+func CertificateProvidersFromEnv() []Provider {
+	_ = godotenv.Load()
+
+	providers := []Provider{NewCrtSh()}
+
+	apiID, apiSecret := os.Getenv("CENSYS_API_ID"), os.Getenv("CENSYS_API_SECRET")
+	if apiID != "" && apiSecret != "" {
+		providers = append(providers, NewCensys(apiID, apiSecret))
+	}
+
+	return providers
+}
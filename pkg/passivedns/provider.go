@@ -0,0 +1,43 @@
+package passivedns
+
+import "time"
+
+// Provider is a passive DNS data source that can list known subdomains
+// for a domain.
+type Provider interface {
+	// Name returns the name of the provider, used as the provenance
+	// source for domains it found.
+	Name() string
+	// Subdomains returns the known subdomains for a domain
+	Subdomains(domain string) ([]Subdomain, error)
+}
+
+// Subdomain is a hostname reported by a Provider
+type Subdomain struct {
+	Hostname string
+	// Source is the name of the provider that reported the hostname
+	Source string
+	// Current is true if the provider considers the hostname active.
+	// Historical (inactive) hostnames have it set to false.
+	Current bool
+	// SeenAt is when the provider reported the hostname
+	SeenAt time.Time
+}
+
+// HistoryProvider is a Provider that can also list the historical DNS A
+// records of a hostname
+type HistoryProvider interface {
+	Provider
+	// History returns the IP addresses a hostname has resolved to
+	History(hostname string) ([]Record, error)
+}
+
+// Record is a historical DNS A record reported by a HistoryProvider
+type Record struct {
+	Hostname string
+	IP       string
+	// Source is the name of the provider that reported the record
+	Source    string
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
@@ -0,0 +1,194 @@
+package passivedns
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// SecurityTrails is a SecurityTrails API client
+type SecurityTrails struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// securityTrailsSubdomains is the response from the subdomains endpoint
+type securityTrailsSubdomains struct {
+	Subdomains     []string `json:"subdomains"`
+	SubdomainCount int      `json:"subdomain_count"`
+}
+
+// NewSecurityTrails returns a new SecurityTrails client
+func NewSecurityTrails(apiKey string) *SecurityTrails {
+	return &SecurityTrails{
+		apiKey:     apiKey,
+		baseURL:    "https://api.securitytrails.com/v1",
+		httpClient: islazy.NewHTTPClient(30 * time.Second),
+	}
+}
+
+// Name returns the provider name
+func (s *SecurityTrails) Name() string {
+	return "securitytrails"
+}
+
+// Subdomains returns current and historical subdomains for a domain.
+// Two queries are made, one that includes inactive subdomains and one that
+// does not, so that historical entries can be told apart from current ones.
+func (s *SecurityTrails) Subdomains(domain string) ([]Subdomain, error) {
+	current, err := s.subdomains(domain, false)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := s.subdomains(domain, true)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	var results []Subdomain
+
+	for _, label := range current {
+		hostname := label + "." + domain
+		seen[hostname] = true
+		results = append(results, Subdomain{
+			Hostname: hostname,
+			Source:   s.Name(),
+			Current:  true,
+			SeenAt:   now,
+		})
+	}
+
+	for _, label := range all {
+		hostname := label + "." + domain
+		if seen[hostname] {
+			continue
+		}
+		seen[hostname] = true
+		results = append(results, Subdomain{
+			Hostname: hostname,
+			Source:   s.Name(),
+			Current:  false,
+			SeenAt:   now,
+		})
+	}
+
+	return results, nil
+}
+
+// subdomains queries the subdomains endpoint, returning subdomain labels
+func (s *SecurityTrails) subdomains(domain string, includeInactive bool) ([]string, error) {
+	url := fmt.Sprintf("%s/domain/%s/subdomains?children_only=false&include_inactive=%t",
+		s.baseURL, domain, includeInactive)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("APIKEY", s.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SecurityTrails API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SecurityTrails API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response securityTrailsSubdomains
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse SecurityTrails response: %w", err)
+	}
+
+	var labels []string
+	for _, label := range response.Subdomains {
+		label = strings.TrimSpace(strings.ToLower(label))
+		if label == "" {
+			continue
+		}
+		labels = append(labels, label)
+	}
+
+	return labels, nil
+}
+
+// securityTrailsHistory is the response from the dns history endpoint
+type securityTrailsHistory struct {
+	Records []struct {
+		Values []struct {
+			IP string `json:"ip"`
+		} `json:"values"`
+		FirstSeen string `json:"first_seen"`
+		LastSeen  string `json:"last_seen"`
+	} `json:"records"`
+}
+
+// History returns the historical A records of a hostname
+func (s *SecurityTrails) History(hostname string) ([]Record, error) {
+	url := fmt.Sprintf("%s/history/%s/dns/a", s.baseURL, hostname)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("APIKEY", s.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query SecurityTrails API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SecurityTrails API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response securityTrailsHistory
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse SecurityTrails response: %w", err)
+	}
+
+	var records []Record
+	for _, record := range response.Records {
+		// dates are reported as yyyy-mm-dd
+		firstSeen, _ := time.Parse(time.DateOnly, record.FirstSeen)
+		lastSeen, _ := time.Parse(time.DateOnly, record.LastSeen)
+
+		for _, value := range record.Values {
+			if value.IP == "" {
+				continue
+			}
+			records = append(records, Record{
+				Hostname:  hostname,
+				IP:        value.IP,
+				Source:    s.Name(),
+				FirstSeen: firstSeen,
+				LastSeen:  lastSeen,
+			})
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,173 @@
+package passivedns
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Subfinder runs a locally installed subfinder to list subdomains
+type Subfinder struct {
+	// Path is the subfinder binary, looked up in PATH by default
+	Path string
+}
+
+// subfinderResult is a JSON line of subfinder's output
+type subfinderResult struct {
+	Host   string `json:"host"`
+	Source string `json:"source"`
+}
+
+// NewSubfinder returns a provider running subfinder
+func NewSubfinder() *Subfinder {
+	return &Subfinder{Path: "subfinder"}
+}
+
+// Name returns the provider name
+func (s *Subfinder) Name() string {
+	return "subfinder"
+}
+
+// Subdomains runs subfinder against a domain, returning the hostnames it
+// found
+func (s *Subfinder) Subdomains(domain string) ([]Subdomain, error) {
+	output, err := runTool(s.Path, "-d", domain, "-silent", "-oJ")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var subdomains []Subdomain
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		var result subfinderResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			continue
+		}
+
+		hostname := normalizeHostname(result.Host)
+		if !underDomain(hostname, domain) || seen[hostname] {
+			continue
+		}
+		seen[hostname] = true
+
+		subdomains = append(subdomains, Subdomain{
+			Hostname: hostname,
+			Source:   s.Name(),
+			Current:  true,
+			SeenAt:   now,
+		})
+	}
+
+	return subdomains, scanner.Err()
+}
+
+// Amass runs a locally installed amass to list subdomains
+type Amass struct {
+	// Path is the amass binary, looked up in PATH by default
+	Path string
+	// Active enables amass' active techniques, such as zone transfers and
+	// certificate grabbing. Passive enumeration is used by default.
+	Active bool
+}
+
+// NewAmass returns a provider running amass passively
+func NewAmass() *Amass {
+	return &Amass{Path: "amass"}
+}
+
+// Name returns the provider name
+func (a *Amass) Name() string {
+	return "amass"
+}
+
+// Subdomains runs an amass enumeration of a domain, returning the
+// hostnames it found
+func (a *Amass) Subdomains(domain string) ([]Subdomain, error) {
+	args := []string{"enum", "-d", domain, "-silent", "-nocolor"}
+	if !a.Active {
+		args = append(args, "-passive")
+	}
+
+	output, err := runTool(a.Path, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var subdomains []Subdomain
+	seen := make(map[string]bool)
+
+	for _, hostname := range parseAmassOutput(output) {
+		if !underDomain(hostname, domain) || seen[hostname] {
+			continue
+		}
+		seen[hostname] = true
+
+		subdomains = append(subdomains, Subdomain{
+			Hostname: hostname,
+			Source:   a.Name(),
+			Current:  true,
+			SeenAt:   now,
+		})
+	}
+
+	return subdomains, nil
+}
+
+// parseAmassOutput returns the hostnames amass printed. Older versions
+// print a hostname per line, while newer ones print graph relations such
+// as "www.example.com (FQDN) --> a_record --> 192.0.2.1 (IPAddress)".
+func parseAmassOutput(output []byte) []string {
+	var hostnames []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 1 {
+			hostnames = append(hostnames, normalizeHostname(fields[0]))
+			continue
+		}
+
+		for i := 1; i < len(fields); i++ {
+			if fields[i] == "(FQDN)" {
+				hostnames = append(hostnames, normalizeHostname(fields[i-1]))
+			}
+		}
+	}
+
+	return hostnames
+}
+
+// runTool runs a tool, returning its output
+func runTool(path string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w: %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return output, nil
+}
+
+// normalizeHostname lowercases a hostname and strips its trailing dot
+func normalizeHostname(hostname string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+}
+
+// underDomain checks if a hostname is a subdomain of domain, or domain
+// itself
+func underDomain(hostname, domain string) bool {
+	domain = normalizeHostname(domain)
+
+	return hostname != "" && (hostname == domain || strings.HasSuffix(hostname, "."+domain))
+}
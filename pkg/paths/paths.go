@@ -0,0 +1,301 @@
+// Package paths brute forces common paths on web servers. Servers that
+// answer every path, so called soft 404s, are told apart from real hits by
+// comparing hits to the response for a path that can't exist.
+package paths
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBodySize is the most of a response body read when probing
+const maxBodySize = 1 << 20
+
+var titleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// DefaultWordlist are paths that often expose something interesting
+var DefaultWordlist = []string{
+	".git/",
+	".git/config",
+	".svn/",
+	".hg/",
+	".env",
+	".DS_Store",
+	".htaccess",
+	".htpasswd",
+	"admin",
+	"administrator",
+	"admin.php",
+	"login",
+	"wp-admin/",
+	"wp-login.php",
+	"phpmyadmin/",
+	"phpinfo.php",
+	"info.php",
+	"server-status",
+	"server-info",
+	"manager/html",
+	"console",
+	"actuator",
+	"actuator/env",
+	"actuator/health",
+	"api",
+	"api/swagger.json",
+	"swagger-ui.html",
+	"swagger/index.html",
+	"openapi.json",
+	"graphql",
+	"debug",
+	"metrics",
+	"backup",
+	"backup.zip",
+	"backup.tar.gz",
+	"backup.sql",
+	"db.sql",
+	"dump.sql",
+	"database.sql",
+	"site.zip",
+	"www.zip",
+	"config.php.bak",
+	"web.config",
+	"config.json",
+	"crossdomain.xml",
+	"test",
+	"old",
+	"dev",
+	"uploads/",
+	"jenkins",
+	"jmx-console",
+	"elmah.axd",
+	"trace.axd",
+}
+
+// DefaultStatusCodes are the response codes reported as hits
+var DefaultStatusCodes = []int{200, 204, 301, 302, 307, 308, 401, 403}
+
+// Hit is a path that exists on a server
+type Hit struct {
+	URL           string
+	Path          string
+	StatusCode    int
+	ContentLength int
+	ContentType   string
+	Title         string
+	// Location is where redirects point to
+	Location string
+}
+
+// Prober brute forces paths on web servers
+type Prober struct {
+	// Timeout is the timeout of every request
+	Timeout time.Duration
+	// Concurrency is the number of paths requested at once per server
+	Concurrency int
+	// UserAgent is the user agent of every request
+	UserAgent string
+	// StatusCodes are the response codes reported as hits
+	StatusCodes []int
+
+	client *http.Client
+	log    *slog.Logger
+}
+
+// NewProber returns a new Prober
+func NewProber(logger *slog.Logger) *Prober {
+	return &Prober{
+		Timeout:     10 * time.Second,
+		Concurrency: 10,
+		StatusCodes: DefaultStatusCodes,
+		log:         logger,
+	}
+}
+
+// Probe requests every path on base, a URL such as https://example.com,
+// returning the paths that exist
+func (p *Prober) Probe(base string, paths []string) ([]Hit, error) {
+	base = strings.TrimSuffix(base, "/")
+	if p.client == nil {
+		p.client = p.newClient()
+	}
+
+	// a path that can't exist shows what the server answers for missing paths
+	canary, err := p.fetch(base, randomPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach %s: %w", base, err)
+	}
+	if slices.Contains(p.StatusCodes, canary.StatusCode) {
+		p.log.Debug("server answers missing paths", "url", base, "status", canary.StatusCode)
+	}
+
+	concurrency := max(p.Concurrency, 1)
+	jobs := make(chan string)
+	var (
+		hits []Hit
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+	)
+
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				hit, err := p.fetch(base, path)
+				if err != nil {
+					p.log.Debug("failed to probe path", "url", base, "path", path, "err", err)
+					continue
+				}
+
+				if !slices.Contains(p.StatusCodes, hit.StatusCode) || softNotFound(hit, canary) {
+					continue
+				}
+
+				mu.Lock()
+				hits = append(hits, *hit)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, path := range paths {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+
+	slices.SortFunc(hits, func(a, b Hit) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return hits, nil
+}
+
+// fetch requests a path on base
+func (p *Prober) fetch(base string, path string) (*Hit, error) {
+	path = "/" + strings.TrimPrefix(path, "/")
+	target := base + path
+
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.UserAgent != "" {
+		req.Header.Set("User-Agent", p.UserAgent)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	hit := &Hit{
+		URL:           target,
+		Path:          path,
+		StatusCode:    resp.StatusCode,
+		ContentLength: len(body),
+		ContentType:   resp.Header.Get("Content-Type"),
+		Location:      resp.Header.Get("Location"),
+	}
+	if match := titleRegex.FindSubmatch(body); match != nil {
+		hit.Title = strings.TrimSpace(string(match[1]))
+	}
+
+	return hit, nil
+}
+
+// newClient returns an http client that does not follow redirects
+func (p *Prober) newClient() *http.Client {
+	return &http.Client{
+		Timeout: p.Timeout,
+		Transport: &http.Transport{
+			// internal hosts rarely have valid certificates
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+			MaxIdleConnsPerHost: max(p.Concurrency, 1),
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// softNotFound checks if a hit looks like the server's answer for a path
+// that does not exist
+func softNotFound(hit *Hit, canary *Hit) bool {
+	if hit.StatusCode != canary.StatusCode {
+		return false
+	}
+
+	// redirects of missing paths usually go to the same place, such as a
+	// login page
+	if canary.Location != "" {
+		return hit.Location == canary.Location
+	}
+
+	return hit.Title == canary.Title && similarSize(hit.ContentLength, canary.ContentLength)
+}
+
+// similarSize checks if two body sizes are within 10% of each other
+func similarSize(a, b int) bool {
+	if a == b {
+		return true
+	}
+
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return diff*10 <= max(a, b)
+}
+
+// randomPath returns a path that won't exist on any server
+func randomPath() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// LoadWordlist reads paths from a file, one per line. Empty lines and lines
+// starting with # are skipped.
+func LoadWordlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
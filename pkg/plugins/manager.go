@@ -0,0 +1,195 @@
+package plugins
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// Manager runs a set of plugins and saves what they add
+type Manager struct {
+	plugins []*Plugin
+	log     *slog.Logger
+}
+
+// Load starts the plugin executables at paths
+func Load(logger *slog.Logger, paths []string) (*Manager, error) {
+	m := &Manager{log: logger}
+	for _, path := range paths {
+		p, err := Start(logger, path)
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+
+		logger.Info("loaded plugin", "plugin", p.Name, "hooks", p.Hooks)
+		m.plugins = append(m.plugins, p)
+	}
+
+	return m, nil
+}
+
+// EnrichResult runs the result hook of every plugin for a saved result
+func (m *Manager) EnrichResult(db *gorm.DB, result *models.Result) {
+	for _, p := range m.plugins {
+		if !p.Subscribed(HookResult) {
+			continue
+		}
+
+		out, err := p.Enrich(HookResult, result)
+		if err != nil {
+			m.log.Warn("plugin failed to enrich result", "plugin", p.Name, "url", result.URL, "err", err)
+			continue
+		}
+
+		if err := Save(db, p.Name, &result.ID, result.IPAddress, result.ScanSessionID, out); err != nil {
+			m.log.Warn("failed to save plugin output", "plugin", p.Name, "url", result.URL, "err", err)
+		}
+	}
+}
+
+// EnrichIPInfo runs the ip_info hook of every plugin for saved IP
+// information. Tags are added to the IP information's own tags.
+func (m *Manager) EnrichIPInfo(db *gorm.DB, info *models.IPInfo) {
+	for _, p := range m.plugins {
+		if !p.Subscribed(HookIPInfo) {
+			continue
+		}
+
+		out, err := p.Enrich(HookIPInfo, info)
+		if err != nil {
+			m.log.Warn("plugin failed to enrich ip info", "plugin", p.Name, "ip", info.IPAddress, "err", err)
+			continue
+		}
+
+		if len(out.Tags) > 0 {
+			tags, _ := info.GetTags()
+			for _, tag := range out.Tags {
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+			if err := info.SetTags(tags); err == nil {
+				if err := db.Model(info).UpdateColumn("tags", info.Tags).Error; err != nil {
+					m.log.Warn("failed to save plugin tags", "plugin", p.Name, "ip", info.IPAddress, "err", err)
+				}
+			}
+			out.Tags = nil
+		}
+
+		if err := Save(db, p.Name, nil, info.IPAddress, info.ScanSessionID, out); err != nil {
+			m.log.Warn("failed to save plugin output", "plugin", p.Name, "ip", info.IPAddress, "err", err)
+		}
+	}
+}
+
+// Subscribed returns the plugins that subscribed to a hook
+func (m *Manager) Subscribed(hook string) []*Plugin {
+	var subscribed []*Plugin
+	for _, p := range m.plugins {
+		if p.Subscribed(hook) {
+			subscribed = append(subscribed, p)
+		}
+	}
+
+	return subscribed
+}
+
+// Close stops all plugins
+func (m *Manager) Close() {
+	for _, p := range m.plugins {
+		if err := p.Close(); err != nil {
+			m.log.Debug("plugin exited with an error", "plugin", p.Name, "err", err)
+		}
+	}
+}
+
+// Save stores plugin output for a result, or for an IP address if resultID
+// is nil. Tags can only be stored for results.
+func Save(db *gorm.DB, source string, resultID *uint, ip string, scanSessionID *uint, out *Output) error {
+	if out.Empty() {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for key, value := range out.Fields {
+			if err := tx.Create(&models.Enrichment{
+				ResultID:      resultID,
+				IPAddress:     ip,
+				Source:        source,
+				Key:           key,
+				Value:         value,
+				ScanSessionID: scanSessionID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if resultID != nil {
+			for _, tag := range out.Tags {
+				if err := tx.Create(&models.ResultTag{
+					ResultID: *resultID,
+					Name:     tag,
+					Source:   source,
+				}).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, finding := range out.Findings {
+			if err := tx.Create(&models.Finding{
+				ResultID:      resultID,
+				IPAddress:     ip,
+				Source:        source,
+				Title:         finding.Title,
+				Severity:      finding.Severity,
+				Description:   finding.Description,
+				ScanSessionID: scanSessionID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SaveDomain stores the output of an enrichment provider for a domain. Tags
+// can only be stored for results, so they are dropped.
+func SaveDomain(db *gorm.DB, source string, domain string, scanSessionID *uint, out *Output) error {
+	if out.Empty() {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for key, value := range out.Fields {
+			if err := tx.Create(&models.Enrichment{
+				Domain:        domain,
+				Source:        source,
+				Key:           key,
+				Value:         value,
+				ScanSessionID: scanSessionID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, finding := range out.Findings {
+			if err := tx.Create(&models.Finding{
+				Domain:        domain,
+				Source:        source,
+				Title:         finding.Title,
+				Severity:      finding.Severity,
+				Description:   finding.Description,
+				ScanSessionID: scanSessionID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,358 @@
+// Package plugins runs enrichment plugins.
+//
+// A plugin is an executable that gowitness starts once and talks to with
+// JSON lines over stdin and stdout. Every request has an id and a hook, and
+// the plugin answers each request with a single line carrying the same id.
+// Anything a plugin writes to stderr is logged.
+//
+// The first request is always the init hook:
+//
+//	{"id":0,"hook":"init","version":1}
+//	{"id":0,"name":"my-plugin","hooks":["result","ip_info"]}
+//
+// After that, the plugin receives the hooks it asked for. The result hook
+// carries a probed result, and the ip_info hook carries IP information:
+//
+//	{"id":1,"hook":"result","result":{"url":"https://example.com",...}}
+//	{"id":1,"fields":{"owner":"marketing"},"tags":["wordpress"],"findings":[{"title":"Exposed admin panel","severity":"high","description":"..."}]}
+//
+// A response may set error instead, which is logged.
+//
+// Plugins can also be enrichment providers, intel sources looked up for IP
+// addresses and domains by 'scan providers'. These subscribe to the ip or
+// domain hooks, which carry the bare target, and may ask for their lookups to
+// be paced with a rate limit a minute in their init response:
+//
+//	{"id":0,"name":"my-intel","hooks":["ip","domain"],"rate_limit":30}
+//	{"id":2,"hook":"domain","target":"example.com"}
+//	{"id":2,"fields":{"registrant":"Example Inc"}}
+package plugins
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hooks a plugin can subscribe to
+const (
+	HookInit   = "init"
+	HookResult = "result"
+	HookIPInfo = "ip_info"
+	HookIP     = "ip"
+	HookDomain = "domain"
+)
+
+// ProtocolVersion is the plugin protocol version gowitness speaks
+const ProtocolVersion = 1
+
+// Severities are the valid finding severities, from least to most severe
+var Severities = []string{"info", "low", "medium", "high", "critical"}
+
+// DefaultTimeout is how long a plugin has to answer a request
+const DefaultTimeout = 30 * time.Second
+
+// maxLineSize is the longest response line a plugin may write
+const maxLineSize = 16 << 20
+
+// ErrPluginStopped is returned once a plugin has exited or was stopped
+var ErrPluginStopped = errors.New("plugin is not running")
+
+// request is a request sent to a plugin
+type request struct {
+	ID      uint64 `json:"id"`
+	Hook    string `json:"hook"`
+	Version int    `json:"version,omitempty"`
+	Result  any    `json:"result,omitempty"`
+	IPInfo  any    `json:"ip_info,omitempty"`
+	Target  string `json:"target,omitempty"`
+}
+
+// response is a response read from a plugin
+type response struct {
+	ID    uint64   `json:"id"`
+	Name  string   `json:"name"`
+	Hooks []string `json:"hooks"`
+	// RateLimit is only read from the init response
+	RateLimit int `json:"rate_limit"`
+	Output
+	Error string `json:"error"`
+}
+
+// Output is what a plugin adds to a result or IP address
+type Output struct {
+	Fields   map[string]string `json:"fields"`
+	Tags     []string          `json:"tags"`
+	Findings []Finding         `json:"findings"`
+}
+
+// Empty checks if the output adds nothing
+func (o *Output) Empty() bool {
+	return len(o.Fields) == 0 && len(o.Tags) == 0 && len(o.Findings) == 0
+}
+
+// Finding is a noteworthy issue reported by a plugin
+type Finding struct {
+	Title       string `json:"title"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// Plugin is a running plugin process
+type Plugin struct {
+	// Name is the name the plugin reported, or the executable's name
+	Name string
+	// Hooks are the hooks the plugin subscribed to
+	Hooks []string
+	// Timeout is how long the plugin has to answer a request
+	Timeout time.Duration
+	// RateLimit is the most ip and domain hook requests a minute the
+	// plugin asked for, 0 for no limit
+	RateLimit int
+
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	responses chan response
+	done      chan struct{}
+	log       *slog.Logger
+
+	mu     sync.Mutex
+	nextID uint64
+	closed bool
+}
+
+// Start starts a plugin executable and runs the init hook
+func Start(logger *slog.Logger, path string) (*Plugin, error) {
+	cmd := exec.Command(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Plugin{
+		Name:      filepath.Base(path),
+		Timeout:   DefaultTimeout,
+		cmd:       cmd,
+		stdin:     stdin,
+		responses: make(chan response),
+		done:      make(chan struct{}),
+		log:       logger,
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", path, err)
+	}
+
+	go p.readResponses(stdout)
+	go p.readStderr(stderr)
+
+	var init response
+	if err := p.call(request{Hook: HookInit, Version: ProtocolVersion}, &init); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("plugin %s failed to initialise: %w", path, err)
+	}
+	if init.Name != "" {
+		p.Name = init.Name
+	}
+	p.Hooks = init.Hooks
+	p.RateLimit = max(0, init.RateLimit)
+
+	return p, nil
+}
+
+// Subscribed checks if the plugin subscribed to a hook
+func (p *Plugin) Subscribed(hook string) bool {
+	return slices.Contains(p.Hooks, hook)
+}
+
+// Enrich sends a result or IP information, or an IP address or domain as a
+// string, to the plugin's hook, returning what the plugin adds
+func (p *Plugin) Enrich(hook string, v any) (*Output, error) {
+	req := request{Hook: hook}
+	switch hook {
+	case HookResult:
+		req.Result = v
+	case HookIPInfo:
+		req.IPInfo = v
+	case HookIP, HookDomain:
+		target, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("the %s hook takes a string", hook)
+		}
+		req.Target = target
+	default:
+		return nil, fmt.Errorf("unknown hook %q", hook)
+	}
+
+	var resp response
+	if err := p.call(req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	out := resp.Output
+	out.Normalise()
+
+	return &out, nil
+}
+
+// call sends a request and waits for its response. Requests are sent one at
+// a time. A plugin that does not answer in time is stopped.
+func (p *Plugin) call(req request, resp *response) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrPluginStopped
+	}
+
+	req.ID = p.nextID
+	p.nextID++
+
+	line, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write to plugin: %w", err)
+	}
+
+	timeout := time.NewTimer(p.Timeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case r := <-p.responses:
+			if r.ID != req.ID {
+				// a late answer to a request that timed out
+				continue
+			}
+			*resp = r
+			return nil
+		case <-p.done:
+			p.closed = true
+			return ErrPluginStopped
+		case <-timeout.C:
+			p.stop()
+			return fmt.Errorf("plugin did not answer within %s and was stopped", p.Timeout)
+		}
+	}
+}
+
+// readResponses reads response lines until the plugin exits
+func (p *Plugin) readResponses(stdout io.Reader) {
+	defer close(p.done)
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var r response
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			p.log.Warn("plugin wrote an invalid response", "plugin", p.Name, "err", err)
+			continue
+		}
+
+		select {
+		case p.responses <- r:
+		case <-time.After(time.Second):
+			// nobody is waiting for this response anymore
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		p.log.Warn("failed to read from plugin", "plugin", p.Name, "err", err)
+	}
+}
+
+// readStderr logs what the plugin writes to stderr
+func (p *Plugin) readStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		p.log.Debug("plugin", "plugin", p.Name, "stderr", scanner.Text())
+	}
+}
+
+// stop kills the plugin. The caller holds p.mu.
+func (p *Plugin) stop() {
+	if p.closed {
+		return
+	}
+	p.closed = true
+
+	p.stdin.Close()
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+	p.cmd.Wait()
+}
+
+// Close stops the plugin. Closing stdin asks it to exit, and it is killed
+// if it does not.
+func (p *Plugin) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	p.stdin.Close()
+	select {
+	case <-p.done:
+	case <-time.After(5 * time.Second):
+		p.cmd.Process.Kill()
+	}
+
+	return p.cmd.Wait()
+}
+
+// Normalise cleans up output, dropping empty tags and findings without a
+// title
+func (o *Output) Normalise() {
+	var tags []string
+	for _, tag := range o.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	o.Tags = tags
+
+	findings := o.Findings[:0]
+	for _, finding := range o.Findings {
+		if strings.TrimSpace(finding.Title) == "" {
+			continue
+		}
+		finding.Severity = strings.ToLower(strings.TrimSpace(finding.Severity))
+		if !slices.Contains(Severities, finding.Severity) {
+			finding.Severity = "info"
+		}
+		findings = append(findings, finding)
+	}
+	o.Findings = findings
+}
@@ -0,0 +1,262 @@
+// Package portprofile decides how much of each host a port scan covers.
+// Profiles match hosts by network, or by the CDN naabu detects in front of
+// them, and set the ports scanned on them: a full scan of origin servers,
+// only the web ports of CDN edges, or nothing at all for WAF ranges.
+package portprofile
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ProjectFile is the file next to a project database that profiles are
+// loaded from by default
+const ProjectFile = "port-profiles.json"
+
+// Profile sets the ports scanned on the hosts it matches. A profile
+// matches hosts in any of Networks, hosts that are (or are not) behind a
+// CDN, and hosts behind any of the CDN Providers, with every condition
+// that is set having to hold. A profile without conditions matches every
+// host.
+type Profile struct {
+	Name      string   `json:"name"`
+	Networks  []string `json:"networks,omitempty"`  // CIDRs or IP addresses
+	CDN       *bool    `json:"cdn,omitempty"`       // as detected by naabu
+	Providers []string `json:"providers,omitempty"` // CDN names naabu reports, e.g. cloudflare
+	Ports     string   `json:"ports,omitempty"`     // in naabu's -p syntax, the scan's own ports if empty
+	Skip      bool     `json:"skip,omitempty"`      // do not scan the hosts at all
+
+	networks []*net.IPNet
+}
+
+// Host is a scan target, with what is known about it
+type Host struct {
+	Target   string // the target as given, a hostname or an IP address
+	IP       string // the address the target resolved to, if known
+	CDN      bool
+	Provider string
+}
+
+// Policy is an ordered list of profiles. A host gets the first profile
+// that matches it.
+type Policy struct {
+	Profiles []*Profile `json:"profiles"`
+}
+
+// Default returns the policy used without a profiles file: hosts behind
+// cloud WAFs are skipped, and only the web ports of other CDN edges are
+// scanned. Every other host gets the scan's own ports.
+func Default() *Policy {
+	cdn := true
+	policy := &Policy{Profiles: []*Profile{
+		{Name: "cloud-waf", Providers: []string{"incapsula", "imperva", "sucuri"}, Skip: true},
+		{Name: "cdn", CDN: &cdn, Ports: "80,443"},
+	}}
+	policy.compile()
+
+	return policy
+}
+
+// Load reads a policy from a JSON file, e.g.
+//
+//	{"profiles": [
+//	  {"name": "datacenter", "networks": ["203.0.113.0/24"], "ports": "1-65535"},
+//	  {"name": "cloud-waf", "providers": ["incapsula", "sucuri"], "skip": true},
+//	  {"name": "cdn", "cdn": true, "ports": "80,443,8080,8443"}
+//	]}
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read port profiles: %w", err)
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse port profiles: %w", err)
+	}
+
+	for i, profile := range policy.Profiles {
+		if profile.Name == "" {
+			return nil, fmt.Errorf("port profile %d has no name", i+1)
+		}
+		for _, network := range profile.Networks {
+			if _, err := parseNetwork(network); err != nil {
+				return nil, fmt.Errorf("port profile %s: %w", profile.Name, err)
+			}
+		}
+		if profile.Ports != "" {
+			if _, err := ParsePorts(profile.Ports); err != nil {
+				return nil, fmt.Errorf("port profile %s: %w", profile.Name, err)
+			}
+		}
+	}
+	policy.compile()
+
+	return &policy, nil
+}
+
+// Save writes a policy to a JSON file
+func (p *Policy) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// FindProjectFile returns the profiles file next to a SQLite project
+// database, or an empty string if there is none
+func FindProjectFile(dbURI string) string {
+	path, ok := strings.CutPrefix(dbURI, "sqlite://")
+	if !ok {
+		return ""
+	}
+
+	file := filepath.Join(filepath.Dir(path), ProjectFile)
+	if info, err := os.Stat(file); err != nil || info.IsDir() {
+		return ""
+	}
+
+	return file
+}
+
+// compile parses the networks of the profiles
+func (p *Policy) compile() {
+	for _, profile := range p.Profiles {
+		profile.networks = nil
+		for _, network := range profile.Networks {
+			if ipnet, err := parseNetwork(network); err == nil {
+				profile.networks = append(profile.networks, ipnet)
+			}
+		}
+	}
+}
+
+// NeedsCDN checks if any profile matches on CDN detection, which needs the
+// web ports of every host to be scanned first
+func (p *Policy) NeedsCDN() bool {
+	for _, profile := range p.Profiles {
+		if profile.CDN != nil || len(profile.Providers) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Match returns the first profile that matches a host, or nil if none do
+func (p *Policy) Match(host Host) *Profile {
+	for _, profile := range p.Profiles {
+		if profile.matches(host) {
+			return profile
+		}
+	}
+
+	return nil
+}
+
+func (profile *Profile) matches(host Host) bool {
+	if len(profile.networks) > 0 {
+		ip := net.ParseIP(host.IP)
+		if ip == nil {
+			ip = net.ParseIP(host.Target)
+		}
+		if ip == nil || !slices.ContainsFunc(profile.networks, func(n *net.IPNet) bool { return n.Contains(ip) }) {
+			return false
+		}
+	}
+
+	if profile.CDN != nil && *profile.CDN != host.CDN {
+		return false
+	}
+
+	if len(profile.Providers) > 0 && !slices.ContainsFunc(profile.Providers, func(provider string) bool {
+		return host.Provider != "" && strings.Contains(strings.ToLower(host.Provider), strings.ToLower(provider))
+	}) {
+		return false
+	}
+
+	return true
+}
+
+// Covered checks if the ports of a profile were all scanned already
+func (profile *Profile) Covered(scanned []int) bool {
+	if profile.Ports == "" {
+		return false
+	}
+
+	ports, err := ParsePorts(profile.Ports)
+	if err != nil {
+		return false
+	}
+	for _, port := range ports {
+		if !slices.Contains(scanned, port) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParsePorts parses a port list in naabu's -p syntax, such as 22,80-90
+func ParsePorts(ports string) ([]int, error) {
+	var parsed []int
+	for _, part := range strings.Split(ports, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		low, high, isRange := strings.Cut(part, "-")
+		if !isRange {
+			high = low
+		}
+		from, err := strconv.Atoi(low)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", part)
+		}
+		to, err := strconv.Atoi(high)
+		if err != nil || from < 1 || to > 65535 || from > to {
+			return nil, fmt.Errorf("invalid port %q", part)
+		}
+
+		for port := from; port <= to; port++ {
+			parsed = append(parsed, port)
+		}
+	}
+
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("no ports in %q", ports)
+	}
+
+	return parsed, nil
+}
+
+// parseNetwork parses a CIDR, or an IP address as a single address network
+func parseNetwork(network string) (*net.IPNet, error) {
+	if !strings.Contains(network, "/") {
+		ip := net.ParseIP(network)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid network %q", network)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, ipnet, err := net.ParseCIDR(network)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network %q", network)
+	}
+
+	return ipnet, nil
+}
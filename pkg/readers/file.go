@@ -72,6 +72,22 @@ func (fr *FileReader) Read(ch chan<- string) error {
 	return scanner.Err()
 }
 
+// URLs returns the URLs to probe for candidates, as Read does for the lines
+// of a file
+func (fr *FileReader) URLs(candidates []string) []string {
+	ports := fr.ports()
+
+	var urls []string
+	for _, candidate := range candidates {
+		if strings.TrimSpace(candidate) == "" {
+			continue
+		}
+		urls = append(urls, fr.urlsFor(candidate, ports)...)
+	}
+
+	return urls
+}
+
 // urlsFor returns URLs for a scanning candidate.
 //
 // For candidates with no protocol, (and none of http/https is ignored), the
@@ -0,0 +1,242 @@
+// Package robots collects the robots.txt and sitemap.xml files of web
+// servers, and extracts the URLs they reveal.
+package robots
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// Kinds of files that are collected
+const (
+	KindRobots  = "robots.txt"
+	KindSitemap = "sitemap.xml"
+)
+
+// maxFileSize is the most of a file that is read
+const maxFileSize = 1 << 20
+
+// maxSitemaps is the most sitemaps fetched per server, counting the ones
+// named in robots.txt and nested sitemap indexes
+const maxSitemaps = 5
+
+// Collector fetches the robots.txt and sitemap.xml files of servers, once
+// per server
+type Collector struct {
+	// MaxURLs is the most URLs extracted per server. 0 means no limit.
+	MaxURLs int
+
+	client    *http.Client
+	userAgent string
+	seen      sync.Map
+}
+
+// NewCollector returns a new Collector
+func NewCollector(timeout time.Duration, userAgent string) *Collector {
+	return &Collector{
+		MaxURLs:   50,
+		userAgent: userAgent,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		},
+	}
+}
+
+// Collect fetches the robots.txt and sitemap.xml files of the server of
+// target, returning the files that exist and the URLs they name on that
+// server. Servers that were collected before return nothing.
+func (c *Collector) Collect(target string) ([]models.SiteFile, []string) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, nil
+	}
+
+	base := u.Scheme + "://" + u.Host
+	if _, loaded := c.seen.LoadOrStore(base, true); loaded {
+		return nil, nil
+	}
+
+	var files []models.SiteFile
+	var urls []string
+	seen := make(map[string]bool)
+	add := func(raw string) {
+		resolved := resolve(u, raw)
+		if resolved == "" || seen[resolved] || (c.MaxURLs > 0 && len(urls) >= c.MaxURLs) {
+			return
+		}
+		seen[resolved] = true
+		urls = append(urls, resolved)
+	}
+
+	sitemaps := []string{base + "/sitemap.xml"}
+	if file, ok := c.fetch(base + "/robots.txt"); ok {
+		file.Kind = KindRobots
+		paths, named := ParseRobots(file.Content)
+		for _, path := range paths {
+			add(path)
+		}
+		for _, sitemap := range named {
+			if resolved := resolve(u, sitemap); resolved != "" && resolved != sitemaps[0] {
+				sitemaps = append(sitemaps, resolved)
+			}
+		}
+		files = append(files, *file)
+	}
+
+	for i := 0; i < len(sitemaps) && i < maxSitemaps; i++ {
+		file, ok := c.fetch(sitemaps[i])
+		if !ok {
+			continue
+		}
+
+		file.Kind = KindSitemap
+		locs, nested := ParseSitemap(file.Content)
+		for _, loc := range locs {
+			add(loc)
+		}
+		for _, sitemap := range nested {
+			if resolved := resolve(u, sitemap); resolved != "" {
+				sitemaps = append(sitemaps, resolved)
+			}
+		}
+		files = append(files, *file)
+	}
+
+	for i := range files {
+		files[i].URLCount = len(urls)
+	}
+
+	return files, urls
+}
+
+// fetch fetches a file, if it exists
+func (c *Collector) fetch(target string) (*models.SiteFile, bool) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, false
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFileSize))
+	if err != nil || len(body) == 0 {
+		return nil, false
+	}
+
+	// servers that answer every path often serve their index page instead
+	if strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/html") {
+		return nil, false
+	}
+
+	return &models.SiteFile{
+		URL:        target,
+		StatusCode: resp.StatusCode,
+		Content:    string(body),
+	}, true
+}
+
+// ParseRobots returns the paths a robots.txt file allows or disallows, and
+// the sitemaps it names. Paths with wildcards are skipped.
+func ParseRobots(content string) ([]string, []string) {
+	var paths, sitemaps []string
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "allow", "disallow":
+			if value == "" || value == "/" || strings.ContainsAny(value, "*$") {
+				continue
+			}
+			paths = append(paths, value)
+		case "sitemap":
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		}
+	}
+
+	return paths, sitemaps
+}
+
+// sitemap is a sitemap.xml urlset or sitemapindex
+type sitemap struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// ParseSitemap returns the URLs a sitemap lists, and the sitemaps a sitemap
+// index lists
+func ParseSitemap(content string) ([]string, []string) {
+	var doc sitemap
+	if err := xml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, nil
+	}
+
+	var urls, sitemaps []string
+	for _, u := range doc.URLs {
+		if loc := strings.TrimSpace(u.Loc); loc != "" {
+			urls = append(urls, loc)
+		}
+	}
+	for _, s := range doc.Sitemaps {
+		if loc := strings.TrimSpace(s.Loc); loc != "" {
+			sitemaps = append(sitemaps, loc)
+		}
+	}
+
+	return urls, sitemaps
+}
+
+// resolve resolves a path or URL against the server of base, returning ""
+// for URLs on other servers
+func resolve(base *url.URL, raw string) string {
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	resolved := base.ResolveReference(ref)
+	if !strings.EqualFold(resolved.Hostname(), base.Hostname()) {
+		return ""
+	}
+	resolved.Fragment = ""
+
+	return resolved.String()
+}
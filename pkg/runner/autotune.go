@@ -0,0 +1,230 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// tunerWindow is the number of probe outcomes considered before the
+	// concurrency limit is re-evaluated
+	tunerWindow = 20
+	// tunerBackoffRatio is the failure ratio in a window that causes the
+	// concurrency limit to be halved
+	tunerBackoffRatio = 0.5
+	// tunerRampRatio is the failure ratio in a window under which the
+	// concurrency limit is increased by one
+	tunerRampRatio = 0.1
+	// tunerPollInterval is how long Acquire waits before checking for a
+	// free slot again
+	tunerPollInterval = 250 * time.Millisecond
+	// tunerSampleInterval is how often the resource limits are checked.
+	// Reading the heap size stops the world, so it is not done per probe.
+	tunerSampleInterval = time.Second
+)
+
+// ResourceLimits are process wide limits a Tuner will respect before
+// allowing new work to start.
+type ResourceLimits struct {
+	// MaxMemory is the maximum heap size, in megabytes. 0 disables the check.
+	MaxMemory int
+	// MaxOpenFiles is the maximum number of open file descriptors.
+	// 0 disables the check.
+	MaxOpenFiles int
+}
+
+// enabled checks if any resource limit is set
+func (l ResourceLimits) enabled() bool {
+	return l.MaxMemory > 0 || l.MaxOpenFiles > 0
+}
+
+// exceeded checks if any resource limit has been reached, returning the
+// name of the limit that was hit.
+func (l ResourceLimits) exceeded() (bool, string) {
+	if l.MaxMemory > 0 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+
+		if stats.HeapAlloc/1024/1024 >= uint64(l.MaxMemory) {
+			return true, "memory"
+		}
+	}
+
+	if l.MaxOpenFiles > 0 {
+		// only linux exposes this cheaply. other platforms simply
+		// don't get this guard.
+		if fds, err := os.ReadDir("/proc/self/fd"); err == nil && len(fds) >= l.MaxOpenFiles {
+			return true, "open-files"
+		}
+	}
+
+	return false, ""
+}
+
+// Tuner is an adaptive concurrency limiter. Workers Acquire a slot before
+// doing work and Release it afterwards, reporting if the work was healthy.
+// When failures spike the limit backs off, and when things look healthy it
+// ramps back up towards the maximum.
+type Tuner struct {
+	min     int
+	max     int
+	limit   int
+	active  int
+	enabled bool
+
+	successes int
+	failures  int
+
+	limits ResourceLimits
+	// exceeded is the name of the resource limit that was reached when the
+	// limits were last sampled, or an empty string
+	exceeded atomic.Value
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	log   *slog.Logger
+	mutex sync.Mutex
+}
+
+// NewTuner returns a new Tuner. If enabled is false, the limit is fixed at
+// max and only the resource limits are enforced.
+func NewTuner(logger *slog.Logger, min, max int, enabled bool, limits ResourceLimits) *Tuner {
+	if max < 1 {
+		max = 1
+	}
+	if min < 1 || min > max {
+		min = 1
+	}
+
+	limit := max
+	if enabled {
+		// start in the middle and let the scan find its feet
+		limit = min + (max-min)/2
+	}
+
+	t := &Tuner{
+		min:     min,
+		max:     max,
+		limit:   limit,
+		enabled: enabled,
+		limits:  limits,
+		stop:    make(chan struct{}),
+		log:     logger,
+	}
+	t.sample()
+
+	if limits.enabled() {
+		go t.sampler()
+	}
+
+	return t
+}
+
+// sample checks the resource limits, keeping the result for Acquire
+func (t *Tuner) sample() {
+	_, which := t.limits.exceeded()
+	t.exceeded.Store(which)
+}
+
+// sampler samples the resource limits until the Tuner is stopped
+func (t *Tuner) sampler() {
+	ticker := time.NewTicker(tunerSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.sample()
+		}
+	}
+}
+
+// Stop stops sampling the resource limits
+func (t *Tuner) Stop() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+// Limit returns the current concurrency limit
+func (t *Tuner) Limit() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.limit
+}
+
+// Acquire blocks until a slot is available and resource limits allow new
+// work to start. It returns false if the context is cancelled first.
+func (t *Tuner) Acquire(ctx context.Context) bool {
+	warned := false
+
+	for {
+		which := t.exceeded.Load().(string)
+		exceeded := which != ""
+
+		t.mutex.Lock()
+		if !exceeded && t.active < t.limit {
+			t.active++
+			t.mutex.Unlock()
+			return true
+		}
+		t.mutex.Unlock()
+
+		if exceeded && !warned {
+			t.log.Warn("resource limit reached, waiting for work to drain", "limit", which)
+			warned = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(tunerPollInterval):
+		}
+	}
+}
+
+// Release frees a slot, recording if the work done was healthy
+func (t *Tuner) Release(healthy bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.active--
+
+	if !t.enabled {
+		return
+	}
+
+	if healthy {
+		t.successes++
+	} else {
+		t.failures++
+	}
+
+	total := t.successes + t.failures
+	if total < tunerWindow {
+		return
+	}
+
+	ratio := float64(t.failures) / float64(total)
+	previous := t.limit
+
+	switch {
+	case ratio >= tunerBackoffRatio:
+		t.limit = max(t.min, t.limit/2)
+	case ratio <= tunerRampRatio:
+		t.limit = min(t.max, t.limit+1)
+	}
+
+	if t.limit != previous {
+		t.log.Info("adjusted concurrency", "from", previous, "to", t.limit, "failure-ratio", ratio)
+	}
+
+	t.successes = 0
+	t.failures = 0
+}
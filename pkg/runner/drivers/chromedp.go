@@ -25,6 +25,7 @@ import (
 	"github.com/sensepost/gowitness/internal/islazy"
 	"github.com/sensepost/gowitness/pkg/models"
 	"github.com/sensepost/gowitness/pkg/runner"
+	"github.com/sensepost/gowitness/pkg/thumbnail"
 )
 
 // Chromedp is a driver that probes web targets using chromedp
@@ -300,7 +301,7 @@ func (run *Chromedp) Witness(target string, thisRunner *runner.Runner) (*models.
 
 				// if we need to write the body, do that
 				// https://github.com/chromedp/chromedp/issues/543
-				if run.options.Scan.SaveContent {
+				if run.options.Scan.SaveContent || run.options.Scan.ArchivePath != "" {
 					go func(index int) {
 						c := chromedp.FromContext(navigationCtx)
 						p := network.GetResponseBody(e.RequestID)
@@ -384,6 +385,7 @@ func (run *Chromedp) Witness(target string, thisRunner *runner.Runner) (*models.
 				HTTPOnly:     cookie.HTTPOnly,
 				Secure:       cookie.Secure,
 				Session:      cookie.Session,
+				SameSite:     cookie.SameSite.String(),
 				Priority:     cookie.Priority.String(),
 				SourceScheme: cookie.SourceScheme.String(),
 				SourcePort:   cookie.SourcePort,
@@ -408,13 +410,7 @@ func (run *Chromedp) Witness(target string, thisRunner *runner.Runner) (*models.
 	}
 
 	// fingerprint technologies in the first response
-	if fingerprints := thisRunner.Wappalyzer.Fingerprint(result.HeaderMap(), []byte(result.HTML)); fingerprints != nil {
-		for tech := range fingerprints {
-			result.Technologies = append(result.Technologies, models.Technology{
-				Value: tech,
-			})
-		}
-	}
+	result.Technologies = runner.Technologies(thisRunner.Wappalyzer, result.HeaderMap(), []byte(result.HTML))
 
 	// grab a screenshot
 	var img []byte
@@ -451,12 +447,13 @@ func (run *Chromedp) Witness(target string, thisRunner *runner.Runner) (*models.
 
 		// write the screenshot to disk if we have a path
 		if !run.options.Scan.ScreenshotSkipSave {
-			result.Filename = islazy.SafeFileName(target) + "." + run.options.Scan.ScreenshotFormat
-			result.Filename = islazy.LeftTrucate(result.Filename, 200)
-			if err := os.WriteFile(
-				filepath.Join(run.options.Scan.ScreenshotPath, result.Filename),
-				img, os.FileMode(0664),
-			); err != nil {
+			result.Filename = runner.ScreenshotFilename(run.options.Scan.ScreenshotTemplate,
+				target, run.options.Scan.ScreenshotFormat, time.Now())
+			file := filepath.Join(run.options.Scan.ScreenshotPath, filepath.FromSlash(result.Filename))
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				return nil, fmt.Errorf("could not create screenshot directory: %w", err)
+			}
+			if err := os.WriteFile(file, img, os.FileMode(0664)); err != nil {
 				return nil, fmt.Errorf("could not write screenshot to disk: %w", err)
 			}
 		}
@@ -472,11 +469,67 @@ func (run *Chromedp) Witness(target string, thisRunner *runner.Runner) (*models.
 			return nil, fmt.Errorf("failed to calculate image perception hash: %w", err)
 		}
 		result.PerceptionHash = hash.ToString()
+
+		// thumbnails are a nice to have, so failing to write one is not fatal
+		if !run.options.Scan.ScreenshotSkipSave && run.options.Scan.ThumbnailWidth > 0 {
+			result.Thumbnail, err = thumbnail.Write(run.options.Scan.ScreenshotPath,
+				result.Filename, decoded, run.options.Scan.ThumbnailWidth)
+			if err != nil {
+				logger.Warn("could not write screenshot thumbnail", "err", err)
+			}
+		}
+
+		// as are the additional screenshots
+		if !run.options.Scan.ScreenshotSkipSave {
+			run.extraScreenshots(navigationCtx, logger, result)
+		}
 	}
 
 	return result, nil
 }
 
+// extraScreenshots saves the additional full page and element screenshots
+// of a page, if they are enabled
+func (run *Chromedp) extraScreenshots(ctx context.Context, logger *slog.Logger, result *models.Result) {
+	if run.options.Scan.ScreenshotFullPageExtra && !run.options.Scan.ScreenshotFullPage {
+		// FullScreenshot takes a png at 100% quality, and a jpeg otherwise
+		quality := 80
+		if run.options.Scan.ScreenshotFormat == "png" {
+			quality = 100
+		}
+
+		var img []byte
+		if err := chromedp.Run(ctx, chromedp.FullScreenshot(&img, quality)); err != nil {
+			logger.Warn("could not grab full page screenshot", "err", err)
+		} else {
+			filename := runner.ExtraScreenshotFilename(result.Filename, runner.ScreenshotFullPage, run.options.Scan.ScreenshotFormat)
+			if err := runner.WriteScreenshot(run.options.Scan.ScreenshotPath, filename, img); err != nil {
+				logger.Warn("could not write full page screenshot", "err", err)
+			} else {
+				result.FullPageFilename = filename
+			}
+		}
+	}
+
+	if selector := run.options.Scan.ScreenshotSelector; selector != "" {
+		elementCtx, cancel := context.WithTimeout(ctx, runner.ElementTimeout)
+		defer cancel()
+
+		// element screenshots are always png
+		var img []byte
+		if err := chromedp.Run(elementCtx, chromedp.Screenshot(selector, &img, chromedp.ByQuery, chromedp.NodeVisible)); err != nil {
+			logger.Debug("could not grab element screenshot", "selector", selector, "err", err)
+		} else {
+			filename := runner.ExtraScreenshotFilename(result.Filename, runner.ScreenshotElement, "png")
+			if err := runner.WriteScreenshot(run.options.Scan.ScreenshotPath, filename, img); err != nil {
+				logger.Warn("could not write element screenshot", "err", err)
+			} else {
+				result.ElementFilename = filename
+			}
+		}
+	}
+}
+
 func (run *Chromedp) Close() {
 	run.log.Debug("closing browser allocation context")
 }
@@ -20,6 +20,7 @@ import (
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/models"
 	"github.com/sensepost/gowitness/pkg/runner"
+	"github.com/sensepost/gowitness/pkg/thumbnail"
 	"github.com/ysmood/gson"
 )
 
@@ -107,7 +108,7 @@ func NewGorod(logger *slog.Logger, opts runner.Options) (*Gorod, error) {
 
 // witness does the work of probing a url.
 // This is where everything comes together as far as the runner is concerned.
-func (run *Gorod) Witness(target string, runner *runner.Runner) (*models.Result, error) {
+func (run *Gorod) Witness(target string, thisRunner *runner.Runner) (*models.Result, error) {
 	logger := run.log.With("target", target)
 	logger.Debug("witnessing 👀")
 
@@ -284,7 +285,7 @@ func (run *Gorod) Witness(target string, runner *runner.Runner) (*models.Result,
 				resultMutex.Unlock()
 
 				// if we need to write the body, do that
-				if run.options.Scan.SaveContent {
+				if run.options.Scan.SaveContent || run.options.Scan.ArchivePath != "" {
 					go func(index int) {
 						body, err := proto.NetworkGetResponseBody{RequestID: e.RequestID}.Call(page)
 						if err != nil {
@@ -368,6 +369,7 @@ func (run *Gorod) Witness(target string, runner *runner.Runner) (*models.Result,
 				HTTPOnly:     cookie.HTTPOnly,
 				Secure:       cookie.Secure,
 				Session:      cookie.Session,
+				SameSite:     string(cookie.SameSite),
 				Priority:     string(cookie.Priority),
 				SourceScheme: string(cookie.SourceScheme),
 				SourcePort:   int64(cookie.SourcePort),
@@ -400,13 +402,7 @@ func (run *Gorod) Witness(target string, runner *runner.Runner) (*models.Result,
 	dismissEvents = true
 
 	// fingerprint technologies in the first response
-	if fingerprints := runner.Wappalyzer.Fingerprint(result.HeaderMap(), []byte(result.HTML)); fingerprints != nil {
-		for tech := range fingerprints {
-			result.Technologies = append(result.Technologies, models.Technology{
-				Value: tech,
-			})
-		}
-	}
+	result.Technologies = runner.Technologies(thisRunner.Wappalyzer, result.HeaderMap(), []byte(result.HTML))
 
 	// take the screenshot. getting here often means the page responded and we have
 	// some information. sometimes though, and im not sure why, page.Screenshot()
@@ -438,12 +434,13 @@ func (run *Gorod) Witness(target string, runner *runner.Runner) (*models.Result,
 
 		// write the screenshot to disk if we have a path
 		if !run.options.Scan.ScreenshotSkipSave {
-			result.Filename = islazy.SafeFileName(target) + "." + run.options.Scan.ScreenshotFormat
-			result.Filename = islazy.LeftTrucate(result.Filename, 200)
-			if err := os.WriteFile(
-				filepath.Join(run.options.Scan.ScreenshotPath, result.Filename),
-				img, os.FileMode(0664),
-			); err != nil {
+			result.Filename = runner.ScreenshotFilename(run.options.Scan.ScreenshotTemplate,
+				target, run.options.Scan.ScreenshotFormat, time.Now())
+			file := filepath.Join(run.options.Scan.ScreenshotPath, filepath.FromSlash(result.Filename))
+			if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+				return nil, fmt.Errorf("could not create screenshot directory: %w", err)
+			}
+			if err := os.WriteFile(file, img, os.FileMode(0664)); err != nil {
 				return nil, fmt.Errorf("could not write screenshot to disk: %w", err)
 			}
 		}
@@ -459,11 +456,62 @@ func (run *Gorod) Witness(target string, runner *runner.Runner) (*models.Result,
 			return nil, fmt.Errorf("failed to calculate image perception hash: %w", err)
 		}
 		result.PerceptionHash = hash.ToString()
+
+		// thumbnails are a nice to have, so failing to write one is not fatal
+		if !run.options.Scan.ScreenshotSkipSave && run.options.Scan.ThumbnailWidth > 0 {
+			result.Thumbnail, err = thumbnail.Write(run.options.Scan.ScreenshotPath,
+				result.Filename, decoded, run.options.Scan.ThumbnailWidth)
+			if err != nil {
+				logger.Warn("could not write screenshot thumbnail", "err", err)
+			}
+		}
+
+		// as are the additional screenshots
+		if !run.options.Scan.ScreenshotSkipSave {
+			run.extraScreenshots(page, screenshotOptions, logger, result)
+		}
 	}
 
 	return result, nil
 }
 
+// extraScreenshots saves the additional full page and element screenshots
+// of a page, if they are enabled
+func (run *Gorod) extraScreenshots(page *rod.Page, screenshotOptions *proto.PageCaptureScreenshot, logger *slog.Logger, result *models.Result) {
+	if run.options.Scan.ScreenshotFullPageExtra && !run.options.Scan.ScreenshotFullPage {
+		img, err := page.Screenshot(true, screenshotOptions)
+		if err != nil {
+			logger.Warn("could not grab full page screenshot", "err", err)
+		} else {
+			filename := runner.ExtraScreenshotFilename(result.Filename, runner.ScreenshotFullPage, run.options.Scan.ScreenshotFormat)
+			if err := runner.WriteScreenshot(run.options.Scan.ScreenshotPath, filename, img); err != nil {
+				logger.Warn("could not write full page screenshot", "err", err)
+			} else {
+				result.FullPageFilename = filename
+			}
+		}
+	}
+
+	if selector := run.options.Scan.ScreenshotSelector; selector != "" {
+		// element screenshots are always png
+		element, err := page.Timeout(runner.ElementTimeout).Element(selector)
+		var img []byte
+		if err == nil {
+			img, err = element.Screenshot(proto.PageCaptureScreenshotFormatPng, 0)
+		}
+		if err != nil {
+			logger.Debug("could not grab element screenshot", "selector", selector, "err", err)
+		} else {
+			filename := runner.ExtraScreenshotFilename(result.Filename, runner.ScreenshotElement, "png")
+			if err := runner.WriteScreenshot(run.options.Scan.ScreenshotPath, filename, img); err != nil {
+				logger.Warn("could not write element screenshot", "err", err)
+			} else {
+				result.ElementFilename = filename
+			}
+		}
+	}
+}
+
 // Close cleans up the Browser runner. The caller needs
 // to close the Targets channel
 func (run *Gorod) Close() {
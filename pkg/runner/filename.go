@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// maxFilenameLength is the longest a single screenshot path component may be
+const maxFilenameLength = 200
+
+// placeholderRe matches {{name}} placeholders in a filename template
+var placeholderRe = regexp.MustCompile(`\{\{\s*([a-z]+)\s*\}\}`)
+
+// filenamePlaceholders are the placeholders a filename template may use
+var filenamePlaceholders = []string{
+	"url", "scheme", "host", "apex", "port", "path", "timestamp", "date", "ext",
+}
+
+// ValidateFilenameTemplate checks that a screenshot filename template only
+// uses known placeholders and stays inside the screenshot path
+func ValidateFilenameTemplate(tmpl string) error {
+	if strings.TrimSpace(tmpl) == "" {
+		return errors.New("screenshot template is empty")
+	}
+	if strings.HasPrefix(tmpl, "/") || strings.HasPrefix(tmpl, `\`) {
+		return errors.New("screenshot template must be a relative path")
+	}
+
+	for _, match := range placeholderRe.FindAllStringSubmatch(tmpl, -1) {
+		if !islazy.SliceHasStr(filenamePlaceholders, match[1]) {
+			return fmt.Errorf("unknown screenshot template placeholder %q, valid placeholders are: %s",
+				match[1], strings.Join(filenamePlaceholders, ", "))
+		}
+	}
+
+	return nil
+}
+
+// ScreenshotFilename returns the path, relative to the screenshot path, a
+// screenshot of target is saved at. Without a template, this is the target
+// made safe as a flat file name. With a template, placeholders are replaced
+// with parts of the target and every directory in the result is made safe,
+// so the path can never leave the screenshot path. The screenshot format is
+// always used as the extension.
+func ScreenshotFilename(tmpl string, target string, format string, now time.Time) string {
+	if tmpl == "" {
+		return islazy.LeftTrucate(islazy.SafeFileName(target)+"."+format, maxFilenameLength)
+	}
+
+	values := filenameValues(target, format, now)
+	rendered := placeholderRe.ReplaceAllStringFunc(tmpl, func(s string) string {
+		return values[placeholderRe.FindStringSubmatch(s)[1]]
+	})
+
+	// drop an image extension from the template, the format decides it
+	switch strings.ToLower(path.Ext(rendered)) {
+	case ".png", ".jpeg", ".jpg":
+		rendered = strings.TrimSuffix(rendered, path.Ext(rendered))
+	}
+
+	var parts []string
+	for _, part := range strings.FieldsFunc(rendered, func(r rune) bool { return r == '/' || r == '\\' }) {
+		part = safePathComponent(part)
+		if strings.Trim(part, ".") == "" {
+			continue
+		}
+		parts = append(parts, islazy.LeftTrucate(part, maxFilenameLength))
+	}
+	if len(parts) == 0 {
+		parts = []string{islazy.SafeFileName(target)}
+	}
+
+	last := len(parts) - 1
+	parts[last] = islazy.LeftTrucate(parts[last]+"."+format, maxFilenameLength)
+
+	return path.Join(parts...)
+}
+
+// safePathComponent is islazy.SafeFileName, but keeping the underscores and
+// dashes templates commonly use as separators
+func safePathComponent(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, s)
+}
+
+// filenameValues returns the values of the filename template placeholders
+// for a target
+func filenameValues(target string, format string, now time.Time) map[string]string {
+	values := map[string]string{
+		"url":       target,
+		"timestamp": now.Format("20060102-150405"),
+		"date":      now.Format("2006-01-02"),
+		"ext":       format,
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return values
+	}
+
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+
+	apex := host
+	if net.ParseIP(host) == nil {
+		apex = islazy.ApexDomain(host)
+	}
+
+	values["scheme"] = u.Scheme
+	values["host"] = host
+	values["apex"] = apex
+	values["port"] = port
+	// the path is a single component, not a directory tree
+	values["path"] = strings.ReplaceAll(strings.Trim(u.Path, "/"), "/", "-")
+
+	return values
+}
@@ -0,0 +1,19 @@
+package runner
+
+import "github.com/sensepost/gowitness/pkg/metrics"
+
+var (
+	probes = metrics.NewCounter("gowitness_probes_total",
+		"Probed targets, by outcome (ok, failed or skipped)", "outcome")
+	probeDuration = metrics.NewHistogram("gowitness_probe_duration_seconds",
+		"How long drivers took to witness targets", nil)
+	probesActive = metrics.NewGauge("gowitness_probes_active",
+		"Targets being witnessed")
+)
+
+// Outcomes of probes, as recorded in metrics
+const (
+	probeOK      = "ok"
+	probeFailed  = "failed"
+	probeSkipped = "skipped"
+)
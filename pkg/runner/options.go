@@ -1,5 +1,7 @@
 package runner
 
+import "github.com/sensepost/gowitness/pkg/thumbnail"
+
 // Options are global gowitness options
 type Options struct {
 	// Logging is logging options
@@ -10,11 +12,17 @@ type Options struct {
 	Writer Writer
 	// Scan is typically Scan options
 	Scan Scan
+	// Scope is the targets that may be touched
+	Scope Scope
 }
 
 // Logging is log related options
 type Logging struct {
-	// Debug display debug level logging
+	// Level is the least severe level that is logged
+	Level string
+	// Format is the format logs are written in, text or json
+	Format string
+	// Debug display debug level logging, overriding Level
 	Debug bool
 	// LogScanErrors log errors related to scanning
 	LogScanErrors bool
@@ -79,16 +87,78 @@ type Scan struct {
 	ScreenshotFormat string
 	// ScreenshotFullPage saves full, scrolled web pages
 	ScreenshotFullPage bool
+	// ScreenshotFullPageExtra saves a full page screenshot in addition to
+	// the viewport screenshot
+	ScreenshotFullPageExtra bool
+	// ScreenshotSelector is a CSS selector of an element to save an
+	// additional screenshot of, if the page has it
+	ScreenshotSelector string
 	// ScreenshotToWriter passes screenshots as a model property to writers
 	ScreenshotToWriter bool
 	// ScreenshotSkipSave skips saving screenshots to disk
 	ScreenshotSkipSave bool
+	// ScreenshotTemplate is a template for the path screenshots are saved
+	// at, relative to ScreenshotPath. Empty saves them flat, named after
+	// the target.
+	ScreenshotTemplate string
+	// WappalyzerFingerprints is a wappalyzer fingerprints file loaded over
+	// the embedded fingerprints, to detect with a fresher set
+	WappalyzerFingerprints string
+	// ThumbnailWidth is the width of thumbnails saved next to screenshots.
+	// 0 disables thumbnails.
+	ThumbnailWidth int
+	// Plugins are enrichment plugin executables that results are passed to
+	// once they are written to the database
+	Plugins []string
+	// ScriptsDir is a directory of Starlark check scripts to run against
+	// results once they are written to the database
+	ScriptsDir string
 	// JavaScript to evaluate on every page
 	JavaScript     string
 	JavaScriptFile string
 	// Save content stores content from network requests (warning) this
 	// could make written artefacts huge
 	SaveContent bool
+	// ArchivePath is a directory response bodies are archived in, gzip
+	// compressed and deduplicated by their hash. Empty disables archiving.
+	ArchivePath string
+	// AutoTune adjusts the number of active threads based on how many
+	// probes are failing. Threads becomes the upper bound.
+	AutoTune bool
+	// AutoTuneMinThreads is the lower bound AutoTune will back off to
+	AutoTuneMinThreads int
+	// MaxMemory is the heap size, in megabytes, at which new probes will
+	// wait for running ones to finish. 0 means no limit.
+	MaxMemory int
+	// MaxOpenFiles is the number of open file descriptors at which new
+	// probes will wait for running ones to finish. 0 means no limit.
+	MaxOpenFiles int
+	// Robots collects the robots.txt and sitemap.xml files of every
+	// server that is probed
+	Robots bool
+	// RobotsEnqueue probes the URLs robots.txt and sitemap.xml files name
+	// as well
+	RobotsEnqueue bool
+	// RobotsMaxURLs is the most URLs taken from the files of a server
+	RobotsMaxURLs int
+	// SANExpand probes the in scope hostnames named in the subject
+	// alternative names of certificates as well
+	SANExpand bool
+	// NoiseSignatures is a JSON file of signatures of boring pages, used
+	// along with the default signatures to mark results as noise
+	NoiseSignatures string
+}
+
+// Scope limits the targets that are probed. Empty values do not limit
+// anything.
+type Scope struct {
+	// Domains are the apex domains that, with their subdomains, are in
+	// scope
+	Domains []string
+	// CIDRs are the networks whose IPs are in scope
+	CIDRs []string
+	// ExcludeFile lists a domain, IP or CIDR that is out of scope per line
+	ExcludeFile string
 }
 
 // NewDefaultOptions returns Options with some default values
@@ -100,13 +170,18 @@ func NewDefaultOptions() *Options {
 			WindowY:   1080,
 		},
 		Scan: Scan{
-			Driver:           "chromedp",
-			Threads:          6,
-			Timeout:          60,
-			UriFilter:        []string{"http", "https"},
-			ScreenshotFormat: "jpeg",
+			Driver:             "chromedp",
+			Threads:            6,
+			AutoTuneMinThreads: 1,
+			Timeout:            60,
+			UriFilter:          []string{"http", "https"},
+			ScreenshotFormat:   "jpeg",
+			ThumbnailWidth:     thumbnail.DefaultWidth,
+			RobotsMaxURLs:      50,
 		},
 		Logging: Logging{
+			Level:         "info",
+			Format:        "text",
 			Debug:         true,
 			LogScanErrors: true,
 		},
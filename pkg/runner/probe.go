@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/writers"
+)
+
+// Probe probes a list of URLs, starting at most rateLimit of them per second
+// if it is above 0, and writes results to resultWriters. onFailure, if set,
+// is called for every URL that failed. URLs that were not started yet when
+// ctx is done are skipped. The driver is closed once done.
+func Probe(ctx context.Context, logger *slog.Logger, driver Driver, opts Options, urls []string, rateLimit int,
+	resultWriters []writers.Writer, onFailure func(target string, err error)) error {
+
+	runner, err := NewRunner(logger, driver, opts, resultWriters)
+	if err != nil {
+		driver.Close()
+		return err
+	}
+	runner.OnFailure = onFailure
+
+	go func() {
+		defer close(runner.Targets)
+
+		var tick <-chan time.Time
+		if rateLimit > 0 {
+			ticker := time.NewTicker(time.Second / time.Duration(rateLimit))
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for i, url := range urls {
+			if tick != nil && i > 0 {
+				select {
+				case <-tick:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			runner.Targets <- url
+		}
+	}()
+
+	runner.Run()
+	runner.Close()
+
+	return nil
+}
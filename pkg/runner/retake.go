@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/writers"
+)
+
+// retakeWriter links retaken results to the result they replace before
+// passing them on to writers
+type retakeWriter struct {
+	previous *models.Result
+	writers  []writers.Writer
+	result   *models.Result
+}
+
+// Write links a result and writes it
+func (rw *retakeWriter) Write(result *models.Result) error {
+	result.PreviousResultID = &rw.previous.ID
+	result.ScanSessionID = rw.previous.ScanSessionID
+	rw.result = result
+
+	for _, writer := range rw.writers {
+		if err := writer.Write(result); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Retake probes the URL of a previous result again, capturing a fresh
+// screenshot. The new result is linked to the previous one and is in the
+// same scan session, and is written to resultWriters before it is returned.
+// The driver is closed once done.
+func Retake(logger *slog.Logger, driver Driver, opts Options, previous *models.Result, resultWriters []writers.Writer) (*models.Result, error) {
+	// a retake is of the one URL only
+	opts.Scan.RobotsEnqueue = false
+	opts.Scan.SANExpand = false
+
+	writer := &retakeWriter{previous: previous, writers: resultWriters}
+	runner, err := NewRunner(logger, driver, opts, []writers.Writer{writer})
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		runner.Targets <- previous.URL
+		close(runner.Targets)
+	}()
+
+	runner.Run()
+	runner.Close()
+
+	if writer.result == nil {
+		return nil, fmt.Errorf("failed to retake %s", previous.URL)
+	}
+
+	return writer.result, nil
+}
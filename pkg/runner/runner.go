@@ -3,14 +3,27 @@ package runner
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/url"
 	"os"
+	"strings"
 	"sync"
+	"time"
 
 	wappalyzer "github.com/projectdiscovery/wappalyzergo"
 	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/annotations"
+	"github.com/sensepost/gowitness/pkg/archive"
+	"github.com/sensepost/gowitness/pkg/classify"
+	"github.com/sensepost/gowitness/pkg/cookies"
+	"github.com/sensepost/gowitness/pkg/extract"
+	"github.com/sensepost/gowitness/pkg/headers"
 	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/noise"
+	"github.com/sensepost/gowitness/pkg/robots"
+	"github.com/sensepost/gowitness/pkg/scope"
 	"github.com/sensepost/gowitness/pkg/writers"
 )
 
@@ -25,11 +38,34 @@ type Runner struct {
 	writers []writers.Writer
 	// log handler
 	log *slog.Logger
+	// tuner limits how many workers are active at once
+	tuner *Tuner
+	// robots collects robots.txt and sitemap.xml files. nil if disabled.
+	robots *robots.Collector
+	// scope is the targets that may be probed. nil if not limited.
+	scope *scope.Scope
+	// noise are the signatures of boring pages
+	noise *noise.List
+	// archive stores response bodies. nil if disabled.
+	archive *archive.Archive
+
+	// discovered are URLs found while probing that are still to be
+	// probed, and probed are the targets that were
+	mu         sync.Mutex
+	discovered []string
+	probed     map[string]bool
 
 	// Targets to scan.
 	// This would typically be fed from a gowitness/pkg/reader.
 	Targets chan string
 
+	// OnFailure is called with targets that could not be probed or
+	// written, if set
+	OnFailure func(target string, err error)
+	// OnSANs is called with the in scope hostnames found in the
+	// certificate of a target with Scan.SANExpand, if set
+	OnSANs func(target string, hostnames []string)
+
 	// in case we need to bail
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -54,6 +90,12 @@ func NewRunner(logger *slog.Logger, driver Driver, opts Options, writers []write
 		return nil, errors.New("invalid screenshot format")
 	}
 
+	if opts.Scan.ScreenshotTemplate != "" {
+		if err := ValidateFilenameTemplate(opts.Scan.ScreenshotTemplate); err != nil {
+			return nil, err
+		}
+	}
+
 	// javascript file containing javascript to eval on each page.
 	// just read it in and set Scan.JavaScript to the value.
 	if opts.Scan.JavaScriptFile != "" {
@@ -66,11 +108,41 @@ func NewRunner(logger *slog.Logger, driver Driver, opts Options, writers []write
 	}
 
 	// get a wappalyzer instance
-	wap, err := wappalyzer.New()
+	wap, err := NewWappalyzer(opts.Scan.WappalyzerFingerprints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load wappalyzer fingerprints: %w", err)
+	}
+
+	// concurrency limits. without autotune, this only enforces the
+	// resource limits.
+	tuner := NewTuner(logger, opts.Scan.AutoTuneMinThreads, opts.Scan.Threads, opts.Scan.AutoTune, ResourceLimits{
+		MaxMemory:    opts.Scan.MaxMemory,
+		MaxOpenFiles: opts.Scan.MaxOpenFiles,
+	})
+
+	var collector *robots.Collector
+	if opts.Scan.Robots {
+		collector = robots.NewCollector(time.Duration(opts.Scan.Timeout)*time.Second, opts.Chrome.UserAgent)
+		collector.MaxURLs = opts.Scan.RobotsMaxURLs
+	}
+
+	targetScope, err := scope.New(opts.Scope.Domains, opts.Scope.CIDRs, opts.Scope.ExcludeFile)
 	if err != nil {
 		return nil, err
 	}
 
+	noiseList, err := noise.Load(opts.Scan.NoiseSignatures)
+	if err != nil {
+		return nil, err
+	}
+
+	var bodyArchive *archive.Archive
+	if opts.Scan.ArchivePath != "" {
+		if bodyArchive, err = archive.New(opts.Scan.ArchivePath); err != nil {
+			return nil, err
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Runner{
@@ -80,6 +152,12 @@ func NewRunner(logger *slog.Logger, driver Driver, opts Options, writers []write
 		writers:    writers,
 		Targets:    make(chan string),
 		log:        logger,
+		tuner:      tuner,
+		robots:     collector,
+		scope:      targetScope,
+		noise:      noiseList,
+		archive:    bodyArchive,
+		probed:     make(map[string]bool),
 		ctx:        ctx,
 		cancel:     cancel,
 	}, nil
@@ -111,8 +189,41 @@ func (run *Runner) checkUrl(target string) error {
 }
 
 // Run executes the runner, processing targets as they arrive
-// in the Targets channel
+// in the Targets channel. URLs discovered along the way, such as those in
+// robots.txt files or certificates, are probed once the Targets channel is
+// closed.
 func (run *Runner) Run() {
+	run.work(run.Targets)
+
+	for run.ctx.Err() == nil {
+		run.mu.Lock()
+		discovered := run.discovered
+		run.discovered = nil
+		run.mu.Unlock()
+
+		if len(discovered) == 0 {
+			return
+		}
+
+		run.log.Info("probing discovered urls", "count", len(discovered))
+		targets := make(chan string)
+		go func() {
+			defer close(targets)
+			for _, target := range discovered {
+				select {
+				case targets <- target:
+				case <-run.ctx.Done():
+					return
+				}
+			}
+		}()
+
+		run.work(targets)
+	}
+}
+
+// work probes targets with Scan.Threads workers until targets is closed
+func (run *Runner) work(targets <-chan string) {
 	wg := sync.WaitGroup{}
 
 	// will spawn Scan.Theads number of "workers" as goroutines
@@ -126,7 +237,7 @@ func (run *Runner) Run() {
 				select {
 				case <-run.ctx.Done():
 					return
-				case target, ok := <-run.Targets:
+				case target, ok := <-targets:
 					if !ok {
 						return
 					}
@@ -136,10 +247,36 @@ func (run *Runner) Run() {
 						if run.options.Logging.LogScanErrors {
 							run.log.Error("invalid target to scan", "target", target, "err", err)
 						}
+						run.fail(target, err)
+						probes.Inc(probeSkipped)
 						continue
 					}
 
+					// out of scope targets, such as URLs discovered in a
+					// sitemap that point to third parties, are never touched
+					if err := run.scope.CheckURL(target); err != nil {
+						run.log.Warn("skipping out of scope target", "target", target, "err", err)
+						run.fail(target, err)
+						probes.Inc(probeSkipped)
+						continue
+					}
+
+					// discovered urls are often already probed targets
+					if (run.options.Scan.RobotsEnqueue || run.options.Scan.SANExpand) && !run.markProbed(target) {
+						continue
+					}
+
+					// wait for the tuner to give us a slot
+					if !run.tuner.Acquire(run.ctx) {
+						return
+					}
+
+					probesActive.Add(1)
+					start := time.Now()
 					result, err := run.Driver.Witness(target, run)
+					probeDuration.Observe(time.Since(start).Seconds())
+					probesActive.Add(-1)
+					run.tuner.Release(err == nil && result.ResponseCode != 0)
 					if err != nil {
 						// is this a chrome not found error?
 						var chromeErr *ChromeNotFoundError
@@ -152,6 +289,8 @@ func (run *Runner) Run() {
 						if run.options.Logging.LogScanErrors {
 							run.log.Error("failed to witness target", "target", target, "err", err)
 						}
+						run.fail(target, err)
+						probes.Inc(probeFailed)
 						continue
 					}
 
@@ -161,11 +300,44 @@ func (run *Runner) Run() {
 						if run.options.Logging.LogScanErrors {
 							run.log.Error("failed to witness target, status code was 0", "target", target)
 						}
+						run.fail(target, errors.New("status code was 0"))
+						probes.Inc(probeFailed)
 						continue
 					}
 
+					result.Annotations = annotations.Annotate(result)
+					result.Tags = append(result.Tags, classify.Classify(result)...)
+
+					security := headers.Analyze(result)
+					result.SecurityScore = security.Score
+					result.SecurityGrade = security.Grade
+					result.SecurityIssues = security.Issues
+					result.Findings = append(result.Findings, cookies.Findings(result)...)
+					result.ExtractedURLs, result.Secrets = extract.Analyze(result)
+					result.NoiseReason, result.Noise = run.noise.Match(result)
+
+					if run.archive != nil {
+						run.archiveBodies(result)
+					}
+
+					if run.robots != nil {
+						files, urls := run.robots.Collect(target)
+						result.SiteFiles = files
+						if run.options.Scan.RobotsEnqueue {
+							run.enqueue(urls)
+						}
+					}
+
+					if run.options.Scan.SANExpand {
+						run.expandSANs(target, result)
+					}
+
 					if err := run.runWriters(result); err != nil {
 						run.log.Error("failed to write result for target", "target", target, "err", err)
+						run.fail(target, err)
+						probes.Inc(probeFailed)
+					} else {
+						probes.Inc(probeOK)
 					}
 
 					run.log.Info("result 🤖", "target", target, "status-code", result.ResponseCode,
@@ -180,7 +352,113 @@ func (run *Runner) Run() {
 	wg.Wait()
 }
 
+// fail reports a target that failed to OnFailure
+func (run *Runner) fail(target string, err error) {
+	if run.OnFailure != nil {
+		run.OnFailure(target, err)
+	}
+}
+
+// markProbed records that a target is being probed, returning false if it
+// was probed before
+func (run *Runner) markProbed(target string) bool {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	if run.probed[target] {
+		return false
+	}
+	run.probed[target] = true
+
+	return true
+}
+
+// enqueue queues discovered URLs that were not probed yet for probing
+func (run *Runner) enqueue(urls []string) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	for _, target := range urls {
+		if !run.probed[target] {
+			run.discovered = append(run.discovered, target)
+		}
+	}
+}
+
+// archiveBodies archives the response bodies of a result, recording their
+// hashes on the network log. Without Scan.SaveContent, the bodies are only
+// kept in the archive.
+func (run *Runner) archiveBodies(result *models.Result) {
+	for i := range result.Network {
+		entry := &result.Network[i]
+		if len(entry.Content) == 0 {
+			continue
+		}
+
+		hash, err := run.archive.Store(entry.Content)
+		if err != nil {
+			run.log.Warn("could not archive response body", "url", entry.URL, "err", err)
+			continue
+		}
+
+		entry.ContentHash = hash
+		if !run.options.Scan.SaveContent {
+			entry.Content = nil
+		}
+	}
+}
+
+// expandSANs queues the in scope hostnames in the certificate of a result
+// for probing, over https on the port of the target. Without a domain
+// scope, only hostnames under the apex domain of the target are in scope,
+// as certificates shared by CDNs name many unrelated domains.
+func (run *Runner) expandSANs(target string, result *models.Result) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return
+	}
+
+	host := strings.ToLower(u.Hostname())
+	apex := islazy.ApexDomain(host)
+
+	var hostnames, urls []string
+	seen := map[string]bool{host: true}
+	for _, san := range result.TLS.SanList {
+		hostname := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(san.Value)), ".")
+		if hostname == "" || strings.HasPrefix(hostname, "*") || seen[hostname] {
+			continue
+		}
+		seen[hostname] = true
+
+		if run.scope.Check(hostname) != nil {
+			continue
+		}
+		if !run.scope.HasDomains() && islazy.ApexDomain(hostname) != apex {
+			continue
+		}
+
+		address := hostname
+		if port := u.Port(); port != "" && port != "443" {
+			address = net.JoinHostPort(hostname, port)
+		}
+
+		hostnames = append(hostnames, hostname)
+		urls = append(urls, "https://"+address)
+	}
+	if len(hostnames) == 0 {
+		return
+	}
+
+	run.log.Debug("found hostnames in certificate", "target", target, "hostnames", hostnames)
+	if run.OnSANs != nil {
+		run.OnSANs(target, hostnames)
+	}
+	run.enqueue(urls)
+}
+
 func (run *Runner) Close() {
+	run.tuner.Stop()
+
 	// close the driver
 	run.Driver.Close()
 }
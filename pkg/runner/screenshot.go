@@ -0,0 +1,40 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Kinds of additional screenshots, used as the suffix of their file names
+const (
+	ScreenshotFullPage = "fullpage"
+	ScreenshotElement  = "element"
+)
+
+// ElementTimeout is how long drivers wait for the element of
+// Scan.ScreenshotSelector to show up
+const ElementTimeout = 5 * time.Second
+
+// ExtraScreenshotFilename returns the file name of an additional screenshot
+// of a kind, saved next to the screenshot at filename
+func ExtraScreenshotFilename(filename string, kind string, format string) string {
+	return strings.TrimSuffix(filename, path.Ext(filename)) + "-" + kind + "." + format
+}
+
+// WriteScreenshot writes a screenshot to its file name, relative to the
+// screenshot path
+func WriteScreenshot(screenshotPath string, filename string, img []byte) error {
+	file := filepath.Join(screenshotPath, filepath.FromSlash(filename))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("could not create screenshot directory: %w", err)
+	}
+	if err := os.WriteFile(file, img, os.FileMode(0664)); err != nil {
+		return fmt.Errorf("could not write screenshot to disk: %w", err)
+	}
+
+	return nil
+}
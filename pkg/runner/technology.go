@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"strings"
+
+	wappalyzer "github.com/projectdiscovery/wappalyzergo"
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// NewWappalyzer returns a wappalyzer instance. If path is set, the
+// fingerprints in it are loaded over the embedded ones, so that newer
+// fingerprints can be used without a new release.
+func NewWappalyzer(path string) (*wappalyzer.Wappalyze, error) {
+	if path == "" {
+		return wappalyzer.New()
+	}
+
+	return wappalyzer.NewFromFile(path, true, true)
+}
+
+// Technologies fingerprints the technologies of a response, along with the
+// version and CPE name wappalyzer could determine for each
+func Technologies(wap *wappalyzer.Wappalyze, headers map[string][]string, body []byte) []models.Technology {
+	apps := wap.GetCompiledFingerprints().Apps
+
+	var technologies []models.Technology
+	for value := range wap.Fingerprint(headers, body) {
+		name, version, _ := strings.Cut(value, ":")
+		technology := models.Technology{
+			Value:   value,
+			Name:    name,
+			Version: version,
+		}
+
+		if fingerprint, ok := apps[name]; ok {
+			technology.CPE = cpeWithVersion(wappalyzer.AppInfoFromFingerprint(fingerprint).CPE, version)
+		}
+
+		technologies = append(technologies, technology)
+	}
+
+	return technologies
+}
+
+// cpeWithVersion fills the version of a CPE 2.3 name like
+// cpe:2.3:a:nginx:nginx:*:*:*:*:*:*:*:* if it has none
+func cpeWithVersion(cpe string, version string) string {
+	parts := strings.Split(cpe, ":")
+	if version == "" || len(parts) < 6 || parts[5] != "*" {
+		return cpe
+	}
+
+	parts[5] = version
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,303 @@
+// Package scope decides which targets may be touched, so that assets
+// outside of an engagement's scope, such as shared hosting neighbours or
+// third party CDNs, are never resolved, port scanned or screenshotted.
+package scope
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrOutOfScope is returned for targets that are not in scope
+var ErrOutOfScope = errors.New("target is out of scope")
+
+// Scope is a set of allowed apex domains and networks, and excluded hosts
+// and networks. Domains are checked against the allowed domains and IPs
+// against the allowed networks, while exclusions apply to both. A scope of
+// only domains allows only the IPs its domains were seen resolving to, see
+// AddResolved, and no CIDRs, so that the neighbours sharing a host or CDN
+// are never touched. A nil Scope allows everything.
+type Scope struct {
+	domains  []string
+	networks []*net.IPNet
+
+	excludedDomains  []string
+	excludedNetworks []*net.IPNet
+
+	// resolved maps the IPs of in scope domains to the domain they came from
+	mu       sync.RWMutex
+	resolved map[string]string
+}
+
+// New returns a scope allowing the apex domains and their subdomains, and
+// the IPs in cidrs. excludeFile, if set, lists a domain, IP or CIDR to
+// exclude per line. Excluded domains exclude their subdomains too. If
+// nothing is configured, nil is returned.
+func New(domains []string, cidrs []string, excludeFile string) (*Scope, error) {
+	if len(domains) == 0 && len(cidrs) == 0 && excludeFile == "" {
+		return nil, nil
+	}
+
+	s := &Scope{}
+	for _, domain := range domains {
+		s.domains = append(s.domains, normaliseDomain(domain))
+	}
+
+	for _, cidr := range cidrs {
+		network, err := parseNetwork(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scope cidr %q: %w", cidr, err)
+		}
+		s.networks = append(s.networks, network)
+	}
+
+	if excludeFile != "" {
+		if err := s.loadExclusions(excludeFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Check checks if a host, a domain, IP or CIDR, is in scope, returning an
+// error wrapping ErrOutOfScope if not. A CIDR is in scope if it is within an
+// allowed network and not entirely excluded. Parts of it may still be
+// excluded, which tools scanning it must skip themselves.
+func (s *Scope) Check(host string) error {
+	if s == nil {
+		return nil
+	}
+
+	host = strings.Trim(strings.ToLower(host), "[]")
+	if ip := net.ParseIP(host); ip != nil {
+		return s.checkIP(ip)
+	}
+	if _, network, err := net.ParseCIDR(host); err == nil {
+		return s.checkNetwork(network)
+	}
+
+	return s.checkDomain(normaliseDomain(host))
+}
+
+// AddResolved records the IPs a host resolved to. If the host is an in
+// scope domain, its IPs are in scope of a scope limited to domains.
+func (s *Scope) AddResolved(host string, ips ...string) {
+	if s == nil || len(s.domains) == 0 || net.ParseIP(host) != nil || s.Check(host) != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.resolved == nil {
+		s.resolved = make(map[string]string)
+	}
+	for _, ip := range ips {
+		if parsed := net.ParseIP(ip); parsed != nil {
+			s.resolved[parsed.String()] = host
+		}
+	}
+}
+
+// Resolved returns the IPs recorded with AddResolved, by the host they
+// were resolved from
+func (s *Scope) Resolved() map[string][]string {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var resolved map[string][]string
+	for ip, host := range s.resolved {
+		if resolved == nil {
+			resolved = make(map[string][]string)
+		}
+		resolved[host] = append(resolved[host], ip)
+	}
+
+	return resolved
+}
+
+// HasDomains reports if the scope is limited to allowed domains
+func (s *Scope) HasDomains() bool {
+	return s != nil && len(s.domains) > 0
+}
+
+// CheckURL checks if the host of a URL is in scope
+func (s *Scope) CheckURL(target string) error {
+	if s == nil {
+		return nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Errorf("%w: %s has no host", ErrOutOfScope, target)
+	}
+
+	return s.Check(u.Hostname())
+}
+
+// Filter returns the hosts that are in scope, and the number of hosts that
+// were not
+func (s *Scope) Filter(hosts []string) ([]string, int) {
+	if s == nil {
+		return hosts, 0
+	}
+
+	var allowed []string
+	for _, host := range hosts {
+		if s.Check(host) == nil {
+			allowed = append(allowed, host)
+		}
+	}
+
+	return allowed, len(hosts) - len(allowed)
+}
+
+// checkIP checks if an IP is in scope
+func (s *Scope) checkIP(ip net.IP) error {
+	for _, network := range s.excludedNetworks {
+		if network.Contains(ip) {
+			return fmt.Errorf("%w: %s is excluded by %s", ErrOutOfScope, ip, network)
+		}
+	}
+
+	if len(s.networks) == 0 {
+		if len(s.domains) == 0 {
+			return nil
+		}
+
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		if _, ok := s.resolved[ip.String()]; ok {
+			return nil
+		}
+		return fmt.Errorf("%w: %s is not an address of an allowed domain, and no network is allowed", ErrOutOfScope, ip)
+	}
+	for _, network := range s.networks {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s is not in an allowed network", ErrOutOfScope, ip)
+}
+
+// checkNetwork checks if a network is in scope
+func (s *Scope) checkNetwork(network *net.IPNet) error {
+	for _, excluded := range s.excludedNetworks {
+		if within(network, excluded) {
+			return fmt.Errorf("%w: %s is excluded by %s", ErrOutOfScope, network, excluded)
+		}
+	}
+
+	if len(s.networks) == 0 {
+		if len(s.domains) == 0 {
+			return nil
+		}
+		return fmt.Errorf("%w: %s is not within an allowed network, and only domains are allowed", ErrOutOfScope, network)
+	}
+	for _, allowed := range s.networks {
+		if within(network, allowed) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s is not within an allowed network", ErrOutOfScope, network)
+}
+
+// checkDomain checks if a normalised domain is in scope
+func (s *Scope) checkDomain(domain string) error {
+	for _, excluded := range s.excludedDomains {
+		if underDomain(domain, excluded) {
+			return fmt.Errorf("%w: %s is excluded by %s", ErrOutOfScope, domain, excluded)
+		}
+	}
+
+	if len(s.domains) == 0 {
+		return nil
+	}
+	for _, allowed := range s.domains {
+		if underDomain(domain, allowed) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s is not under an allowed domain", ErrOutOfScope, domain)
+}
+
+// loadExclusions reads the domains, IPs and CIDRs to exclude from a file
+func (s *Scope) loadExclusions(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open scope exclusions: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.Contains(line, "/") || net.ParseIP(line) != nil {
+			network, err := parseNetwork(line)
+			if err != nil {
+				return fmt.Errorf("invalid scope exclusion %q: %w", line, err)
+			}
+			s.excludedNetworks = append(s.excludedNetworks, network)
+			continue
+		}
+
+		s.excludedDomains = append(s.excludedDomains, normaliseDomain(line))
+	}
+
+	return scanner.Err()
+}
+
+// parseNetwork parses a CIDR, or a single IP as a network of one address
+func parseNetwork(value string) (*net.IPNet, error) {
+	if ip := net.ParseIP(value); ip != nil {
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(value)
+	return network, err
+}
+
+// normaliseDomain lowercases a domain, dropping wildcard prefixes and the
+// trailing dot of fully qualified names
+func normaliseDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(domain, "*.")
+	domain = strings.TrimPrefix(domain, ".")
+
+	return strings.TrimSuffix(domain, ".")
+}
+
+// within checks if network is part of parent
+func within(network *net.IPNet, parent *net.IPNet) bool {
+	ones, bits := network.Mask.Size()
+	parentOnes, parentBits := parent.Mask.Size()
+
+	return bits == parentBits && parentOnes <= ones && parent.Contains(network.IP)
+}
+
+// underDomain checks if domain is parent or one of its subdomains
+func underDomain(domain string, parent string) bool {
+	return domain == parent || strings.HasSuffix(domain, "."+parent)
+}
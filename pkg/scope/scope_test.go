@@ -0,0 +1,127 @@
+package scope
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		domains  []string
+		cidrs    []string
+		excluded string
+		resolved map[string][]string
+		host     string
+		wantErr  bool
+	}{
+		{name: "Test subdomain", domains: []string{"example.com"}, host: "www.example.com"},
+		{name: "Test other domain", domains: []string{"example.com"}, host: "example.org", wantErr: true},
+		{name: "Test unresolved ip with only domains", domains: []string{"example.com"}, host: "93.184.216.34", wantErr: true},
+		{name: "Test cidr with only domains", domains: []string{"example.com"}, host: "93.184.216.0/24", wantErr: true},
+		{
+			name:     "Test resolved ip with only domains",
+			domains:  []string{"example.com"},
+			resolved: map[string][]string{"www.example.com": {"93.184.216.34"}},
+			host:     "93.184.216.34",
+		},
+		{
+			name:     "Test ip resolved from another domain",
+			domains:  []string{"example.com"},
+			resolved: map[string][]string{"cdn.example.org": {"93.184.216.34"}},
+			host:     "93.184.216.34",
+			wantErr:  true,
+		},
+		{
+			name:     "Test ip resolved from an ip",
+			domains:  []string{"example.com"},
+			resolved: map[string][]string{"10.0.0.1": {"93.184.216.34"}},
+			host:     "93.184.216.34",
+			wantErr:  true,
+		},
+		{
+			name:     "Test excluded resolved ip",
+			domains:  []string{"example.com"},
+			excluded: "93.184.216.34\n",
+			resolved: map[string][]string{"www.example.com": {"93.184.216.34"}},
+			host:     "93.184.216.34",
+			wantErr:  true,
+		},
+		{name: "Test ip in cidr", cidrs: []string{"10.0.0.0/8"}, host: "10.1.2.3"},
+		{name: "Test ip outside cidr", cidrs: []string{"10.0.0.0/8"}, host: "192.168.1.1", wantErr: true},
+		{name: "Test cidr within cidr", cidrs: []string{"10.0.0.0/8"}, host: "10.1.0.0/16"},
+		{name: "Test ip in cidr with domains", domains: []string{"example.com"}, cidrs: []string{"10.0.0.0/8"}, host: "10.1.2.3"},
+		{name: "Test domain with only cidrs", cidrs: []string{"10.0.0.0/8"}, host: "example.org"},
+		{name: "Test excluded subdomain", domains: []string{"example.com"}, excluded: "dev.example.com\n", host: "api.dev.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var excludeFile string
+			if tt.excluded != "" {
+				excludeFile = filepath.Join(t.TempDir(), "exclude.txt")
+				if err := os.WriteFile(excludeFile, []byte(tt.excluded), 0o644); err != nil {
+					t.Fatalf("failed to write exclusions: %v", err)
+				}
+			}
+
+			s, err := New(tt.domains, tt.cidrs, excludeFile)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			for host, ips := range tt.resolved {
+				s.AddResolved(host, ips...)
+			}
+
+			err = s.Check(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrOutOfScope) {
+				t.Errorf("Check(%q) error = %v, want it to wrap ErrOutOfScope", tt.host, err)
+			}
+		})
+	}
+}
+
+func TestNilScope(t *testing.T) {
+	s, err := New(nil, nil, "")
+	if err != nil || s != nil {
+		t.Fatalf("New() = %v, %v, want a nil scope", s, err)
+	}
+
+	for _, host := range []string{"example.com", "10.1.2.3", "10.0.0.0/8"} {
+		if err := s.Check(host); err != nil {
+			t.Errorf("Check(%q) error = %v, want nil", host, err)
+		}
+	}
+	s.AddResolved("example.com", "10.1.2.3")
+	if resolved := s.Resolved(); resolved != nil {
+		t.Errorf("Resolved() = %v, want nil", resolved)
+	}
+}
+
+func TestResolved(t *testing.T) {
+	s, err := New([]string{"example.com"}, nil, "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	s.AddResolved("www.example.com", "93.184.216.34")
+	s.AddResolved("example.org", "10.1.2.3")
+
+	// a resumed scan restores the resolved IPs into a new scope
+	restored, err := New([]string{"example.com"}, nil, "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for host, ips := range s.Resolved() {
+		restored.AddResolved(host, ips...)
+	}
+
+	allowed, skipped := restored.Filter([]string{"93.184.216.34", "10.1.2.3"})
+	if len(allowed) != 1 || allowed[0] != "93.184.216.34" || skipped != 1 {
+		t.Errorf("Filter() = %v, %d, want [93.184.216.34], 1", allowed, skipped)
+	}
+}
@@ -0,0 +1,90 @@
+package scripting
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"gorm.io/gorm"
+)
+
+// ProjectDir is the directory next to a project database that scripts are
+// loaded from by default
+const ProjectDir = "scripts"
+
+// Runner runs scripts against saved results
+type Runner struct {
+	scripts []*Script
+	log     *slog.Logger
+}
+
+// NewRunner returns a new Runner
+func NewRunner(logger *slog.Logger, scripts []*Script) *Runner {
+	return &Runner{scripts: scripts, log: logger}
+}
+
+// FindProjectDir returns the scripts directory next to a SQLite project
+// database, or an empty string if there is none
+func FindProjectDir(dbURI string) string {
+	path, ok := strings.CutPrefix(dbURI, "sqlite://")
+	if !ok {
+		return ""
+	}
+
+	dir := filepath.Join(filepath.Dir(path), ProjectDir)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+
+	return dir
+}
+
+// RunResult runs every script against a saved result, saving what they add
+func (r *Runner) RunResult(db *gorm.DB, result *models.Result) {
+	host := hostFromResult(db, result)
+
+	for _, script := range r.scripts {
+		out, err := script.Run(host)
+		if err != nil {
+			r.log.Warn("script failed", "script", script.Name, "url", result.URL, "err", err)
+			continue
+		}
+
+		if err := plugins.Save(db, "script:"+script.Name, &result.ID, result.IPAddress, result.ScanSessionID, out); err != nil {
+			r.log.Warn("failed to save script output", "script", script.Name, "url", result.URL, "err", err)
+		}
+	}
+}
+
+// hostFromResult builds the host scripts see from a result
+func hostFromResult(db *gorm.DB, result *models.Result) *Host {
+	host := &Host{
+		URL:      result.URL,
+		FinalURL: result.FinalURL,
+		Status:   result.ResponseCode,
+		Title:    result.Title,
+		IP:       result.IPAddress,
+		Headers:  make(map[string]string, len(result.Headers)),
+		Body:     result.HTML,
+	}
+
+	for _, header := range result.Headers {
+		host.Headers[strings.ToLower(header.Key)] = header.Value
+	}
+	for _, technology := range result.Technologies {
+		host.Technologies = append(host.Technologies, technology.Value)
+	}
+
+	if result.IPAddress != "" {
+		var ports []int
+		if err := db.Model(&models.IPPort{}).Where("ip_address = ?", result.IPAddress).
+			Distinct().Order("port").Pluck("port", &ports).Error; err == nil {
+			host.Ports = ports
+		}
+	}
+
+	return host
+}
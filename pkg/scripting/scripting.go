@@ -0,0 +1,268 @@
+// Package scripting runs small Starlark scripts against probed hosts.
+//
+// A script is a .star file defining a check function, which is called with
+// each probed host. It uses the tag, finding and field builtins to add to
+// the host's result:
+//
+//	def check(host):
+//	    if host.status == 200 and "x-jenkins" in host.headers:
+//	        tag("jenkins")
+//	        field("jenkins_version", host.headers["x-jenkins"])
+//	        if "/login" not in host.final_url:
+//	            finding("Jenkins without login", severity="high")
+//
+// The host has url, final_url, status, title, ip, headers (a dict keyed by
+// lowercase header name), body, ports (open ports on the host's IP) and
+// technologies. The matches(pattern, s) builtin checks s against a Go
+// regular expression.
+package scripting
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// Extension is the file extension of scripts
+const Extension = ".star"
+
+const (
+	// maxSteps is the most execution steps a check may take
+	maxSteps = 10_000_000
+	// timeout is how long a check may run
+	timeout = 10 * time.Second
+	// outputKey is the thread local holding a check's output
+	outputKey = "output"
+)
+
+// Host is what a script sees of a probed host
+type Host struct {
+	URL          string
+	FinalURL     string
+	Status       int
+	Title        string
+	IP           string
+	Headers      map[string]string
+	Body         string
+	Ports        []int
+	Technologies []string
+}
+
+// Script is a loaded script
+type Script struct {
+	// Name is the script's file name, without its extension
+	Name string
+
+	check starlark.Callable
+}
+
+// builtins are the functions scripts can call
+var builtins = starlark.StringDict{
+	"tag":     starlark.NewBuiltin("tag", tagBuiltin),
+	"finding": starlark.NewBuiltin("finding", findingBuiltin),
+	"field":   starlark.NewBuiltin("field", fieldBuiltin),
+	"matches": starlark.NewBuiltin("matches", matchesBuiltin),
+}
+
+// Load loads a script file
+func Load(path string) (*Script, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), Extension)
+	thread := &starlark.Thread{Name: name, Print: func(*starlark.Thread, string) {}}
+	thread.SetMaxExecutionSteps(maxSteps)
+
+	globals, err := starlark.ExecFile(thread, path, src, builtins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load script %s: %w", path, err)
+	}
+
+	check, ok := globals["check"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("script %s does not define a check(host) function", path)
+	}
+
+	return &Script{Name: name, check: check}, nil
+}
+
+// LoadDir loads every script in a directory, in name order
+func LoadDir(dir string) ([]*Script, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+Extension))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var scripts []*Script
+	for _, path := range paths {
+		script, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, script)
+	}
+
+	return scripts, nil
+}
+
+// Run runs the script's check against a host
+func (s *Script) Run(host *Host) (*plugins.Output, error) {
+	out := &plugins.Output{Fields: make(map[string]string)}
+
+	thread := &starlark.Thread{Name: s.Name, Print: func(*starlark.Thread, string) {}}
+	thread.SetMaxExecutionSteps(maxSteps)
+	thread.SetLocal(outputKey, out)
+
+	timer := time.AfterFunc(timeout, func() {
+		thread.Cancel("check timed out")
+	})
+	defer timer.Stop()
+
+	if _, err := starlark.Call(thread, s.check, starlark.Tuple{host.value()}, nil); err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			return nil, errors.New(evalErr.Backtrace())
+		}
+		return nil, err
+	}
+
+	out.Normalise()
+	return out, nil
+}
+
+// value converts the host to a frozen starlark struct
+func (h *Host) value() starlark.Value {
+	headers := starlark.NewDict(len(h.Headers))
+	for key, value := range h.Headers {
+		headers.SetKey(starlark.String(strings.ToLower(key)), starlark.String(value))
+	}
+
+	ports := make([]starlark.Value, 0, len(h.Ports))
+	for _, port := range h.Ports {
+		ports = append(ports, starlark.MakeInt(port))
+	}
+
+	technologies := make([]starlark.Value, 0, len(h.Technologies))
+	for _, technology := range h.Technologies {
+		technologies = append(technologies, starlark.String(technology))
+	}
+
+	host := starlarkstruct.FromStringDict(starlark.String("host"), starlark.StringDict{
+		"url":          starlark.String(h.URL),
+		"final_url":    starlark.String(h.FinalURL),
+		"status":       starlark.MakeInt(h.Status),
+		"title":        starlark.String(h.Title),
+		"ip":           starlark.String(h.IP),
+		"headers":      headers,
+		"body":         starlark.String(h.Body),
+		"ports":        starlark.NewList(ports),
+		"technologies": starlark.NewList(technologies),
+	})
+	host.Freeze()
+
+	return host
+}
+
+// output returns the output of the check running on thread
+func output(thread *starlark.Thread) (*plugins.Output, error) {
+	out, ok := thread.Local(outputKey).(*plugins.Output)
+	if !ok {
+		return nil, errors.New("only available inside check(host)")
+	}
+
+	return out, nil
+}
+
+// tagBuiltin implements tag(name)
+func tagBuiltin(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name); err != nil {
+		return nil, err
+	}
+
+	out, err := output(thread)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	out.Tags = append(out.Tags, name)
+
+	return starlark.None, nil
+}
+
+// findingBuiltin implements finding(title, severity="info", description="")
+func findingBuiltin(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var title, severity, description string
+	severity = "info"
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs,
+		"title", &title, "severity?", &severity, "description?", &description); err != nil {
+		return nil, err
+	}
+
+	out, err := output(thread)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	out.Findings = append(out.Findings, plugins.Finding{
+		Title:       title,
+		Severity:    severity,
+		Description: description,
+	})
+
+	return starlark.None, nil
+}
+
+// fieldBuiltin implements field(key, value)
+func fieldBuiltin(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key string
+	var value starlark.Value
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "key", &key, "value", &value); err != nil {
+		return nil, err
+	}
+
+	out, err := output(thread)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	if s, ok := starlark.AsString(value); ok {
+		out.Fields[key] = s
+	} else {
+		out.Fields[key] = value.String()
+	}
+
+	return starlark.None, nil
+}
+
+// patterns caches compiled matches() patterns
+var patterns sync.Map
+
+// matchesBuiltin implements matches(pattern, s)
+func matchesBuiltin(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, s string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "pattern", &pattern, "s", &s); err != nil {
+		return nil, err
+	}
+
+	re, ok := patterns.Load(pattern)
+	if !ok {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+		re, _ = patterns.LoadOrStore(pattern, compiled)
+	}
+
+	return starlark.Bool(re.(*regexp.Regexp).MatchString(s)), nil
+}
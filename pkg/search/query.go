@@ -0,0 +1,86 @@
+package search
+
+import (
+	"slices"
+	"strings"
+)
+
+// Operators are the search operators we support. Everything else is
+// "free text".
+var Operators = []string{"title", "body", "tech", "header", "console", "banner", "port", "url", "p", "session"}
+
+// Term is a single search term, like header:server=nginx
+type Term struct {
+	// Field is the operator, or "text" for free text
+	Field string
+	// Key is an optional key, like a header name
+	Key string
+	// Value is the value to search for
+	Value string
+}
+
+// tokenize splits a query on whitespace, keeping quoted strings together
+// and removing the quotes
+func tokenize(query string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && (r == ' ' || r == '\t' || r == '\n'):
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
+
+// Parse parses a query into terms. Operators are written as operator:value,
+// with values containing spaces quoted, e.g. body:"index of". Header terms
+// may name the header, e.g. header:server=nginx. Any words that are not
+// operators are joined into a single free text term.
+func Parse(query string) []Term {
+	var terms []Term
+	var freeText []string
+
+	for _, token := range tokenize(query) {
+		operator, value, found := strings.Cut(token, ":")
+		if !found || !slices.Contains(Operators, strings.ToLower(operator)) {
+			freeText = append(freeText, token)
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		term := Term{Field: strings.ToLower(operator), Value: value}
+		if term.Field == "header" {
+			if key, headerValue, ok := strings.Cut(value, "="); ok {
+				term.Key = strings.ToLower(strings.TrimSpace(key))
+				term.Value = strings.TrimSpace(headerValue)
+			}
+		}
+
+		terms = append(terms, term)
+	}
+
+	if len(freeText) > 0 {
+		terms = append(terms, Term{Field: "text", Value: strings.Join(freeText, " ")})
+	}
+
+	return terms
+}
@@ -0,0 +1,67 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []Term
+	}{
+		{
+			name:  "Test free text",
+			query: "admin login",
+			want:  []Term{{Field: "text", Value: "admin login"}},
+		},
+		{
+			name:  "Test header with name",
+			query: "header:server=nginx",
+			want:  []Term{{Field: "header", Key: "server", Value: "nginx"}},
+		},
+		{
+			name:  "Test header without name",
+			query: "header:nginx",
+			want:  []Term{{Field: "header", Value: "nginx"}},
+		},
+		{
+			name:  "Test quoted body",
+			query: `body:"index of /"`,
+			want:  []Term{{Field: "body", Value: "index of /"}},
+		},
+		{
+			name:  "Test quoted header value",
+			query: `header:Server="Apache httpd"`,
+			want:  []Term{{Field: "header", Key: "server", Value: "Apache httpd"}},
+		},
+		{
+			name:  "Test operators and free text",
+			query: `port:8080 tech:jenkins dashboard`,
+			want: []Term{
+				{Field: "port", Value: "8080"},
+				{Field: "tech", Value: "jenkins"},
+				{Field: "text", Value: "dashboard"},
+			},
+		},
+		{
+			name:  "Test unknown operator is free text",
+			query: "foo:bar",
+			want:  []Term{{Field: "text", Value: "foo:bar"}},
+		},
+		{
+			name:  "Test empty operator is ignored",
+			query: "title:",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
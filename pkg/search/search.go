@@ -0,0 +1,116 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEmptyQuery is returned when a query has no usable terms
+	ErrEmptyQuery = errors.New("empty search query")
+	// ErrInvalidQuery is returned when a query term can't be used
+	ErrInvalidQuery = errors.New("invalid search query")
+)
+
+// documentResults returns a query for the ids of results with a search
+// document of field matching the term
+func documentResults(db *gorm.DB, field string, term Term) *gorm.DB {
+	q := db.Model(&models.SearchDocument{}).Select("result_id").
+		Where("field = ?", field).
+		Where("id IN (?)", database.SearchDocuments(db, term.Value))
+
+	if term.Key != "" {
+		q = q.Where("name = ?", term.Key)
+	}
+
+	return q
+}
+
+// apply adds the condition for a term to a results query
+func apply(db *gorm.DB, q *gorm.DB, term Term) (*gorm.DB, error) {
+	like := "%" + strings.ToLower(term.Value) + "%"
+
+	switch term.Field {
+	case "title":
+		return q.Where("LOWER(title) LIKE ?", like), nil
+	case "url":
+		return q.Where("(LOWER(url) LIKE ? OR LOWER(final_url) LIKE ?)", like, like), nil
+	case "text":
+		return q.Where("(LOWER(url) LIKE ? OR LOWER(final_url) LIKE ? OR LOWER(title) LIKE ?)", like, like, like), nil
+	case "body", "header", "console":
+		return q.Where("id IN (?)", documentResults(db, term.Field, term)), nil
+	case "banner":
+		// banners belong to ports, so match results on the same ip
+		return q.Where("ip_address IN (?)", db.Model(&models.SearchDocument{}).Select("name").
+			Where("field = ?", "banner").
+			Where("id IN (?)", database.SearchDocuments(db, term.Value))), nil
+	case "port":
+		port, err := strconv.Atoi(term.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid port %q", ErrInvalidQuery, term.Value)
+		}
+
+		return q.Where("(id IN (?) OR ip_address IN (?))",
+			db.Model(&models.ResultHost{}).Select("result_id").Where("port = ?", strconv.Itoa(port)),
+			db.Model(&models.IPPort{}).Select("ip_address").Where("port = ?", port)), nil
+	case "session":
+		id, err := strconv.ParseUint(term.Value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid scan session %q", ErrInvalidQuery, term.Value)
+		}
+
+		return q.Where("scan_session_id = ?", id), nil
+	case "tech":
+		return q.Where("id IN (?)", db.Model(&models.Technology{}).Select("result_id").
+			Where("LOWER(value) LIKE ?", like)), nil
+	case "p":
+		// p: was used as the operator trigger, but we need it back to
+		// resolve the group_id.
+		return q.Where("perception_hash_group_id IN (?)", db.Model(&models.Result{}).
+			Select("perception_hash_group_id").
+			Where("perception_hash = ?", "p:"+term.Value)), nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidQuery, term.Field)
+}
+
+// Search returns results matching all of the terms in a query, newest
+// first. The search index is brought up to date before searching.
+func Search(db *gorm.DB, query string, limit int) ([]models.Result, []Term, error) {
+	terms := Parse(query)
+	if len(terms) == 0 {
+		return nil, nil, ErrEmptyQuery
+	}
+
+	if err := database.RefreshSearchIndex(db); err != nil {
+		return nil, nil, err
+	}
+	if err := database.RefreshResultHosts(db); err != nil {
+		return nil, nil, err
+	}
+
+	q := db.Model(&models.Result{})
+	for _, term := range terms {
+		var err error
+		if q, err = apply(db, q, term); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	var results []models.Result
+	if err := q.Order("id DESC").Find(&results).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to search results: %w", err)
+	}
+
+	return results, terms, nil
+}
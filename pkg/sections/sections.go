@@ -0,0 +1,332 @@
+// Package sections renders custom report sections from templates kept in a
+// project directory, so that reports can be branded and structured per
+// engagement.
+//
+// Files ending in .html or .tmpl are html/template templates. Files ending
+// in .md are text/template templates whose output is rendered as markdown.
+// The values markdown templates print are escaped, as they hold data from
+// scanned hosts, and raw HTML is left out of them.
+// Sections are rendered in the order of their file names, and titled after
+// them, so 10-executive-summary.md becomes "Executive summary".
+package sections
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/tlsaudit"
+	"github.com/sensepost/gowitness/pkg/whois"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"gorm.io/gorm"
+)
+
+// ProjectDir is the directory next to a project database that report
+// section templates are loaded from
+const ProjectDir = "report-templates"
+
+// FindProjectDir returns the report templates directory next to a SQLite
+// project database, or an empty string if there is none
+func FindProjectDir(dbURI string) string {
+	path, ok := strings.CutPrefix(dbURI, "sqlite://")
+	if !ok {
+		return ""
+	}
+
+	dir := filepath.Join(filepath.Dir(path), ProjectDir)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return ""
+	}
+
+	return dir
+}
+
+// Stats are the numbers of a report
+type Stats struct {
+	Results   int `json:"results"`
+	Failed    int `json:"failed"`
+	Hosts     int `json:"hosts"`
+	Domains   int `json:"domains"`
+	OpenPorts int `json:"open_ports"`
+	// Findings are the number of findings of each severity
+	Findings map[string]int `json:"findings"`
+}
+
+// Data is what section templates are rendered with
+type Data struct {
+	// Session is the scan session the report is for, if there is one
+	Session  *models.ScanSession
+	Stats    Stats
+	Results  []models.Result
+	Findings []models.Finding
+	TLS      *tlsaudit.Report
+	// Domains are the apex domains whose registration expires soon
+	Domains     []whois.Expiry
+	GeneratedAt time.Time
+}
+
+// NewData returns the data of a report on results
+func NewData(results []models.Result) *Data {
+	data := &Data{
+		Results:     results,
+		Stats:       Stats{Results: len(results), Findings: map[string]int{}},
+		GeneratedAt: time.Now(),
+	}
+
+	hosts := make(map[string]bool)
+	for _, result := range results {
+		if result.Failed {
+			data.Stats.Failed++
+		}
+		if result.IPAddress != "" {
+			hosts[result.IPAddress] = true
+		}
+	}
+	data.Stats.Hosts = len(hosts)
+
+	return data
+}
+
+// Load adds the scan session, findings, domains and open ports of a
+// database to the data, limited to a scan session if scanSessionID is not 0
+func (d *Data) Load(db *gorm.DB, scanSessionID uint) error {
+	inSession := func(tx *gorm.DB) *gorm.DB {
+		if scanSessionID > 0 {
+			return tx.Where("scan_session_id = ?", scanSessionID)
+		}
+		return tx
+	}
+
+	if scanSessionID > 0 {
+		var session models.ScanSession
+		if err := db.Preload("ApexDomains").First(&session, scanSessionID).Error; err != nil {
+			return fmt.Errorf("failed to get scan session: %w", err)
+		}
+		d.Session = &session
+	}
+
+	if err := inSession(db.Model(&models.Finding{})).Order("id").Find(&d.Findings).Error; err != nil {
+		return fmt.Errorf("failed to get findings: %w", err)
+	}
+	for _, finding := range d.Findings {
+		d.Stats.Findings[finding.Severity]++
+	}
+
+	var domains, ports int64
+	if err := inSession(db.Model(&models.Domain{})).Where("historical = ?", false).
+		Distinct("name").Count(&domains).Error; err != nil {
+		return fmt.Errorf("failed to count domains: %w", err)
+	}
+	if err := inSession(db.Model(&models.IPPort{})).Where("state = ?", "open").
+		Count(&ports).Error; err != nil {
+		return fmt.Errorf("failed to count open ports: %w", err)
+	}
+	d.Stats.Domains, d.Stats.OpenPorts = int(domains), int(ports)
+
+	return nil
+}
+
+// Section is a custom report section
+type Section struct {
+	// Name is the file name of the template, without its extension
+	Name  string
+	Title string
+
+	html     *template.Template
+	markdown *texttemplate.Template
+}
+
+// funcs are the functions section templates can use
+var funcs = map[string]any{
+	// screenshot returns the path of a result's screenshot in the report
+	"screenshot": func(result models.Result) string {
+		return "./screenshots/" + result.Filename
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// escapeFunc is the name of the function the actions of markdown templates
+// are piped to, kept out of the way of the functions templates use
+const escapeFunc = "_sections_escape_markdown"
+
+// markdownPunctuation are the characters markdown may treat as syntax.
+// Colons are left alone, as goldmark only drops javascript: and other
+// dangerous links when their scheme is written plainly.
+const markdownPunctuation = "!\"#$%&'()*+,-./;<=>?@[\\]^_`{|}~"
+
+// escapeMarkdown escapes the markdown syntax in the value of an action, so
+// that it renders as the text it is
+func escapeMarkdown(v any) string {
+	text := fmt.Sprint(v)
+
+	var b strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune(markdownPunctuation, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// escapeActions pipes every action of a markdown template that prints a
+// value to escapeMarkdown, like html/template does with its escapers
+func escapeActions(t *texttemplate.Template) {
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			escapeNode(tmpl.Tree.Root)
+		}
+	}
+}
+
+func escapeNode(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeNode(child)
+		}
+	case *parse.ActionNode:
+		// actions that only declare or assign variables print nothing
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(escapeFunc).SetPos(n.Pos)},
+		})
+	case *parse.IfNode:
+		escapeNode(n.List)
+		escapeNode(n.ElseList)
+	case *parse.RangeNode:
+		escapeNode(n.List)
+		escapeNode(n.ElseList)
+	case *parse.WithNode:
+		escapeNode(n.List)
+		escapeNode(n.ElseList)
+	}
+}
+
+// Load parses the section templates in dir, in the order of their names
+func Load(dir string) ([]*Section, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var sections []*Section
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		ext := filepath.Ext(entry.Name())
+		if ext != ".html" && ext != ".tmpl" && ext != ".md" {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ext)
+		section := &Section{Name: name, Title: title(name)}
+		if ext == ".md" {
+			section.markdown, err = texttemplate.New(name).Funcs(funcs).
+				Funcs(texttemplate.FuncMap{escapeFunc: escapeMarkdown}).Parse(string(content))
+			if err == nil {
+				escapeActions(section.markdown)
+			}
+		} else {
+			section.html, err = template.New(name).Funcs(funcs).Parse(string(content))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse report section %s: %w", entry.Name(), err)
+		}
+
+		sections = append(sections, section)
+	}
+
+	return sections, nil
+}
+
+// sortPrefix is the number file names start with to order sections
+var sortPrefix = regexp.MustCompile(`^\d+[-_. ]*`)
+
+// title returns the title of a section named name
+func title(name string) string {
+	name = sortPrefix.ReplaceAllString(name, "")
+	name = strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(name))
+	if name == "" {
+		return ""
+	}
+
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// markdown renders markdown to HTML. Raw HTML and links to dangerous URLs,
+// such as javascript: ones, are left out; sections that need HTML are
+// written as .html templates instead.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+)
+
+// Render renders the section with data
+func (s *Section) Render(data *Data) (template.HTML, error) {
+	var buf bytes.Buffer
+	if s.html != nil {
+		if err := s.html.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to render report section %s: %w", s.Name, err)
+		}
+		return template.HTML(buf.String()), nil
+	}
+
+	if err := s.markdown.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render report section %s: %w", s.Name, err)
+	}
+
+	var out bytes.Buffer
+	if err := markdown.Convert(buf.Bytes(), &out); err != nil {
+		return "", fmt.Errorf("failed to render markdown of report section %s: %w", s.Name, err)
+	}
+
+	return template.HTML(out.String()), nil
+}
+
+// Rendered is a section rendered for a report
+type Rendered struct {
+	Name  string
+	Title string
+	HTML  template.HTML
+}
+
+// RenderAll renders sections with data, in order
+func RenderAll(sections []*Section, data *Data) ([]Rendered, error) {
+	rendered := make([]Rendered, 0, len(sections))
+	for _, section := range sections {
+		html, err := section.Render(data)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, Rendered{Name: section.Name, Title: section.Title, HTML: html})
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,117 @@
+package sections
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		result   models.Result
+		want     []string
+		wantNot  []string
+	}{
+		{
+			name:     "Test plain values",
+			template: "# Hosts\n\n{{range .Results}}- {{.URL}} ({{.ResponseCode}})\n{{end}}",
+			result:   models.Result{URL: "https://example.com/a_b", ResponseCode: 200},
+			want:     []string{"<h1>Hosts</h1>", "<li>https://example.com/a_b (200)</li>"},
+		},
+		{
+			name:     "Test html in a value",
+			template: "{{range .Results}}{{.Title}}{{end}}",
+			result:   models.Result{Title: `<script>alert(1)</script><img src=x onerror="alert(1)">`},
+			want:     []string{"&lt;script&gt;alert(1)&lt;/script&gt;&lt;img src=x onerror=&quot;alert(1)&quot;&gt;"},
+			wantNot:  []string{"<script", "<img"},
+		},
+		{
+			name:     "Test value at the start of a line",
+			template: "{{range .Results}}\n{{.Title}}\n{{end}}",
+			result:   models.Result{Title: "<div onmouseover=alert(1)>"},
+			wantNot:  []string{"<div"},
+		},
+		{
+			name:     "Test markdown link in a value",
+			template: "Title: {{(index .Results 0).Title}}",
+			result:   models.Result{Title: "[click](javascript:alert(1)) ![x](javascript:alert(2))"},
+			want:     []string{"[click](javascript:alert(1))"},
+			wantNot:  []string{"<a", "<img", `href="javascript`},
+		},
+		{
+			name:     "Test value in a link",
+			template: "{{range .Results}}[{{.Title}}]({{.URL}}){{end}}",
+			result:   models.Result{Title: "home", URL: "javascript:alert(1)"},
+			wantNot:  []string{"javascript"},
+		},
+		{
+			name:     "Test value in raw html",
+			template: `{{range .Results}}<a href="{{.URL}}">link</a>{{end}}`,
+			result:   models.Result{URL: `x" onclick="alert(1)`},
+			wantNot:  []string{"<a "},
+		},
+		{
+			name:     "Test entity in a value",
+			template: "{{range .Results}}{{.Title}}{{end}}",
+			result:   models.Result{Title: "&lt;b&gt; &#60;i&#62;"},
+			want:     []string{"&amp;lt;b&amp;gt; &amp;#60;i&amp;#62;"},
+		},
+		{
+			name:     "Test escaped table cell",
+			template: "| URL | Title |\n| --- | --- |\n{{range .Results}}| {{.URL}} | {{.Title}} |\n{{end}}",
+			result:   models.Result{URL: "https://example.com", Title: "a | b"},
+			want:     []string{"<td>https://example.com</td>", "<td>a | b</td>"},
+		},
+		{
+			name:     "Test variables and functions",
+			template: "{{$r := index .Results 0}}{{$r.Title | upper}} {{screenshot $r}}{{with $r.Title}} {{lower .}}{{end}}",
+			result:   models.Result{Title: "*Admin*", Filename: "a.png"},
+			want:     []string{"<p>*ADMIN* ./screenshots/a.png *admin*</p>"},
+			wantNot:  []string{"<em>"},
+		},
+		{
+			name:     "Test defined templates",
+			template: `{{define "row"}}- {{.Title}}{{end}}{{range .Results}}{{template "row" .}}{{end}}`,
+			result:   models.Result{Title: "<b>bold</b>"},
+			want:     []string{"<li>&lt;b&gt;bold&lt;/b&gt;</li>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "10-hosts.md"), []byte(tt.template), 0o644); err != nil {
+				t.Fatalf("failed to write template: %v", err)
+			}
+
+			sections, err := Load(dir)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if len(sections) != 1 {
+				t.Fatalf("Load() returned %d sections, want 1", len(sections))
+			}
+
+			got, err := sections[0].Render(NewData([]models.Result{tt.result}))
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("Render() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, wantNot := range tt.wantNot {
+				if strings.Contains(string(got), wantNot) {
+					t.Errorf("Render() = %q, want it not to contain %q", got, wantNot)
+				}
+			}
+		})
+	}
+}
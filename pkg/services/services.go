@@ -0,0 +1,122 @@
+// Package services screenshots non-HTTP services that show a screen to
+// anyone who connects: VNC servers without authentication, and X11
+// displays that accept connections from anywhere. The protocols are spoken
+// natively, so no viewer needs to be installed.
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// Protocols that can be screenshotted
+const (
+	VNC = "vnc"
+	X11 = "x11"
+	// RDP is recognised, but needs a full RDP client to render its login
+	// screen, so it is not captured
+	RDP = "rdp"
+)
+
+var (
+	// ErrUnsupported is returned for protocols that can not be captured
+	ErrUnsupported = errors.New("screenshots of this protocol are not supported")
+	// ErrAuthRequired is returned when a service needs credentials before
+	// it shows its screen
+	ErrAuthRequired = errors.New("the service requires authentication")
+)
+
+// maxPixels caps the screen size accepted from a service
+const maxPixels = 8192 * 8192
+
+// Protocol returns the protocol of a port that shows a screen, from its
+// service name or its well known port, or an empty string
+func Protocol(port int, service string) string {
+	service = strings.ToLower(service)
+
+	switch {
+	case strings.Contains(service, "vnc") || (port >= 5900 && port <= 5909):
+		return VNC
+	case strings.Contains(service, "x11") || (port >= 6000 && port <= 6009):
+		return X11
+	case strings.Contains(service, "rdp") || service == "ms-wbt-server" || port == 3389:
+		return RDP
+	}
+
+	return ""
+}
+
+// Capture is a screenshot of a service
+type Capture struct {
+	Image image.Image
+	Name  string // the desktop name a VNC server announces, if any
+}
+
+// Screenshot connects to a service and captures its screen
+func Screenshot(ctx context.Context, protocol string, ip string, port int, timeout time.Duration) (*Capture, error) {
+	switch protocol {
+	case VNC, X11:
+	default:
+		return nil, ErrUnsupported
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if protocol == VNC {
+		return captureVNC(conn)
+	}
+	return captureX11(conn)
+}
+
+// Filename returns the screenshot file name of a service
+func Filename(protocol string, ip string, port int) string {
+	return islazy.SafeFileName(fmt.Sprintf("%s-%s-%d", protocol, ip, port)) + ".png"
+}
+
+// Save writes a capture as a PNG in dir
+func Save(capture *Capture, dir string, filename string) error {
+	path, err := islazy.CreateFileWithDir(filepath.Join(dir, filename))
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return png.Encode(file, capture.Image)
+}
+
+// checkSize checks a screen size announced by a service
+func checkSize(width, height int) error {
+	if width <= 0 || height <= 0 || width*height > maxPixels {
+		return fmt.Errorf("invalid screen size %dx%d", width, height)
+	}
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"net"
+	"slices"
+)
+
+// RFB security types
+const (
+	rfbSecurityInvalid = 0
+	rfbSecurityNone    = 1
+)
+
+// RFB messages
+const (
+	rfbSetPixelFormat           = 0
+	rfbSetEncodings             = 2
+	rfbFramebufferUpdateRequest = 3
+
+	rfbFramebufferUpdate   = 0
+	rfbSetColourMapEntries = 1
+	rfbBell                = 2
+	rfbServerCutText       = 3
+
+	rfbEncodingRaw = 0
+)
+
+// captureVNC speaks RFB to a VNC server, asking for its whole framebuffer
+// in raw encoding as 32 bit little endian pixels. Only servers that allow
+// connections without authentication show their screen.
+func captureVNC(conn net.Conn) (*Capture, error) {
+	r := bufio.NewReader(conn)
+
+	// ProtocolVersion, answered with the highest version both speak
+	version := make([]byte, 12)
+	if _, err := io.ReadFull(r, version); err != nil {
+		return nil, fmt.Errorf("failed to read rfb version: %w", err)
+	}
+	var major, minor int
+	if _, err := fmt.Sscanf(string(version), "RFB %03d.%03d\n", &major, &minor); err != nil {
+		return nil, fmt.Errorf("not an rfb server: %q", version)
+	}
+	switch {
+	case major > 3 || minor >= 8:
+		minor = 8
+	case minor == 7:
+	default:
+		minor = 3
+	}
+	if _, err := fmt.Fprintf(conn, "RFB 003.%03d\n", minor); err != nil {
+		return nil, err
+	}
+
+	if err := rfbSecurity(conn, r, minor); err != nil {
+		return nil, err
+	}
+
+	// ClientInit, sharing the desktop with other viewers
+	if _, err := conn.Write([]byte{1}); err != nil {
+		return nil, err
+	}
+
+	// ServerInit
+	var init struct {
+		Width, Height uint16
+		PixelFormat   [16]byte
+		NameLength    uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &init); err != nil {
+		return nil, fmt.Errorf("failed to read rfb server init: %w", err)
+	}
+	if init.NameLength > 1<<16 {
+		return nil, errors.New("invalid rfb desktop name")
+	}
+	name := make([]byte, init.NameLength)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return nil, err
+	}
+
+	width, height := int(init.Width), int(init.Height)
+	if err := checkSize(width, height); err != nil {
+		return nil, err
+	}
+
+	// SetPixelFormat: 32 bits per pixel, depth 24, little endian, true
+	// colour with 8 bits per channel, red in the third byte
+	request := []byte{rfbSetPixelFormat, 0, 0, 0, 32, 24, 0, 1, 0, 255, 0, 255, 0, 255, 16, 8, 0, 0, 0, 0}
+	// SetEncodings: raw only
+	request = append(request, rfbSetEncodings, 0, 0, 1, 0, 0, 0, rfbEncodingRaw)
+	// FramebufferUpdateRequest for the whole screen
+	request = append(request, rfbFramebufferUpdateRequest, 0, 0, 0, 0, 0)
+	request = binary.BigEndian.AppendUint16(request, init.Width)
+	request = binary.BigEndian.AppendUint16(request, init.Height)
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	screen := image.NewRGBA(image.Rect(0, 0, width, height))
+	for {
+		messageType, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rfb message: %w", err)
+		}
+
+		switch messageType {
+		case rfbFramebufferUpdate:
+			if err := rfbReadUpdate(r, screen); err != nil {
+				return nil, err
+			}
+			return &Capture{Image: screen, Name: string(name)}, nil
+		case rfbSetColourMapEntries:
+			var header struct {
+				Padding    uint8
+				FirstColor uint16
+				Colors     uint16
+			}
+			if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+				return nil, err
+			}
+			if _, err := r.Discard(int(header.Colors) * 6); err != nil {
+				return nil, err
+			}
+		case rfbBell:
+		case rfbServerCutText:
+			var header struct {
+				Padding [3]byte
+				Length  uint32
+			}
+			if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+				return nil, err
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(header.Length)); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected rfb message %d", messageType)
+		}
+	}
+}
+
+// rfbSecurity picks the None security type, failing if the server does not
+// offer it
+func rfbSecurity(conn net.Conn, r *bufio.Reader, minor int) error {
+	// version 3.3 servers pick the security type themselves
+	if minor == 3 {
+		var securityType uint32
+		if err := binary.Read(r, binary.BigEndian, &securityType); err != nil {
+			return fmt.Errorf("failed to read rfb security type: %w", err)
+		}
+		switch securityType {
+		case rfbSecurityNone:
+			return nil
+		case rfbSecurityInvalid:
+			return rfbReason(r)
+		}
+		return ErrAuthRequired
+	}
+
+	count, err := r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("failed to read rfb security types: %w", err)
+	}
+	if count == 0 {
+		return rfbReason(r)
+	}
+	types := make([]byte, count)
+	if _, err := io.ReadFull(r, types); err != nil {
+		return err
+	}
+	if !slices.Contains(types, rfbSecurityNone) {
+		return ErrAuthRequired
+	}
+	if _, err := conn.Write([]byte{rfbSecurityNone}); err != nil {
+		return err
+	}
+
+	// version 3.8 confirms even the None security type
+	if minor >= 8 {
+		var result uint32
+		if err := binary.Read(r, binary.BigEndian, &result); err != nil {
+			return fmt.Errorf("failed to read rfb security result: %w", err)
+		}
+		if result != 0 {
+			return rfbReason(r)
+		}
+	}
+
+	return nil
+}
+
+// rfbReason reads the reason an rfb server refused the connection
+func rfbReason(r *bufio.Reader) error {
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil || length > 1<<16 {
+		return errors.New("rfb server refused the connection")
+	}
+	reason := make([]byte, length)
+	if _, err := io.ReadFull(r, reason); err != nil {
+		return errors.New("rfb server refused the connection")
+	}
+
+	return fmt.Errorf("rfb server refused the connection: %s", reason)
+}
+
+// rfbReadUpdate reads the rectangles of a FramebufferUpdate into screen
+func rfbReadUpdate(r *bufio.Reader, screen *image.RGBA) error {
+	var header struct {
+		Padding    uint8
+		Rectangles uint16
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return err
+	}
+
+	for range header.Rectangles {
+		var rect struct {
+			X, Y, Width, Height uint16
+			Encoding            int32
+		}
+		if err := binary.Read(r, binary.BigEndian, &rect); err != nil {
+			return err
+		}
+		if rect.Encoding != rfbEncodingRaw {
+			return fmt.Errorf("unexpected rfb encoding %d", rect.Encoding)
+		}
+
+		row := make([]byte, int(rect.Width)*4)
+		for y := range int(rect.Height) {
+			if _, err := io.ReadFull(r, row); err != nil {
+				return fmt.Errorf("failed to read rfb pixels: %w", err)
+			}
+			for x := range int(rect.Width) {
+				pixel := binary.LittleEndian.Uint32(row[x*4:])
+				screen.SetRGBA(int(rect.X)+x, int(rect.Y)+y, color.RGBA{
+					R: uint8(pixel >> 16),
+					G: uint8(pixel >> 8),
+					B: uint8(pixel),
+					A: 255,
+				})
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+	"net"
+)
+
+// X11 requests and replies
+const (
+	x11SetupFailed       = 0
+	x11SetupSuccess      = 1
+	x11SetupAuthenticate = 2
+
+	x11GetImage    = 73
+	x11ZPixmap     = 2
+	x11ReplyError  = 0
+	x11ReplyResult = 1
+)
+
+// x11Screen is the root window of an X11 display, with how its pixels are
+// laid out
+type x11Screen struct {
+	root          uint32
+	width, height int
+	depth         uint8
+	bitsPerPixel  int
+	scanlinePad   int
+	bigEndian     bool
+	masks         [3]uint32 // red, green and blue
+}
+
+// captureX11 connects to an X11 display without credentials and reads the
+// image of its root window. Only displays with access control disabled,
+// such as after 'xhost +', let anyone connect.
+func captureX11(conn net.Conn) (*Capture, error) {
+	r := bufio.NewReader(conn)
+
+	// connection setup, little endian, protocol 11.0, no authorization
+	if _, err := conn.Write([]byte{'l', 0, 11, 0, 0, 0, 0, 0, 0, 0, 0, 0}); err != nil {
+		return nil, err
+	}
+
+	screen, err := x11Setup(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// GetImage of the whole root window
+	request := []byte{x11GetImage, x11ZPixmap}
+	request = binary.LittleEndian.AppendUint16(request, 5)
+	request = binary.LittleEndian.AppendUint32(request, screen.root)
+	request = binary.LittleEndian.AppendUint16(request, 0)
+	request = binary.LittleEndian.AppendUint16(request, 0)
+	request = binary.LittleEndian.AppendUint16(request, uint16(screen.width))
+	request = binary.LittleEndian.AppendUint16(request, uint16(screen.height))
+	request = binary.LittleEndian.AppendUint32(request, 0xffffffff)
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	// events may arrive before the reply
+	reply := make([]byte, 32)
+	for {
+		if _, err := io.ReadFull(r, reply); err != nil {
+			return nil, fmt.Errorf("failed to read x11 reply: %w", err)
+		}
+		if reply[0] == x11ReplyError {
+			return nil, fmt.Errorf("x11 error %d reading the screen", reply[1])
+		}
+		if reply[0] == x11ReplyResult {
+			break
+		}
+	}
+
+	length := int(binary.LittleEndian.Uint32(reply[4:])) * 4
+	if length > maxPixels*4 {
+		return nil, errors.New("x11 image is too large")
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("failed to read x11 image: %w", err)
+	}
+
+	img, err := screen.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Capture{Image: img}, nil
+}
+
+// x11Setup reads the connection setup reply, returning the first screen
+func x11Setup(r *bufio.Reader) (*x11Screen, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read x11 setup: %w", err)
+	}
+	length := int(binary.LittleEndian.Uint16(header[6:])) * 4
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("failed to read x11 setup: %w", err)
+	}
+
+	switch header[0] {
+	case x11SetupSuccess:
+	case x11SetupAuthenticate:
+		return nil, ErrAuthRequired
+	case x11SetupFailed:
+		reason := data[:min(int(header[1]), len(data))]
+		return nil, fmt.Errorf("%w: %s", ErrAuthRequired, reason)
+	default:
+		return nil, errors.New("not an x11 server")
+	}
+
+	if len(data) < 32 {
+		return nil, errors.New("short x11 setup")
+	}
+	vendorLength := int(binary.LittleEndian.Uint16(data[16:]))
+	screens, formats := int(data[20]), int(data[21])
+	bigEndian := data[22] == 1
+	if screens == 0 {
+		return nil, errors.New("x11 display has no screens")
+	}
+
+	offset := 32 + (vendorLength+3)/4*4
+	formatsAt := offset
+	offset += formats * 8
+	if len(data) < offset+40 {
+		return nil, errors.New("short x11 setup")
+	}
+
+	s := data[offset:]
+	screen := &x11Screen{
+		root:      binary.LittleEndian.Uint32(s[0:]),
+		width:     int(binary.LittleEndian.Uint16(s[20:])),
+		height:    int(binary.LittleEndian.Uint16(s[22:])),
+		depth:     s[38],
+		bigEndian: bigEndian,
+	}
+	rootVisual := binary.LittleEndian.Uint32(s[32:])
+	if err := checkSize(screen.width, screen.height); err != nil {
+		return nil, err
+	}
+
+	for i := range formats {
+		format := data[formatsAt+i*8:]
+		if format[0] == screen.depth {
+			screen.bitsPerPixel, screen.scanlinePad = int(format[1]), int(format[2])
+		}
+	}
+	if screen.bitsPerPixel != 16 && screen.bitsPerPixel != 24 && screen.bitsPerPixel != 32 {
+		return nil, fmt.Errorf("unsupported x11 pixel size of %d bits", screen.bitsPerPixel)
+	}
+
+	// the colour masks of the root visual, in the depths of the screen
+	depths := int(s[39])
+	offset = 40
+	for range depths {
+		if len(s) < offset+8 {
+			break
+		}
+		visuals := int(binary.LittleEndian.Uint16(s[offset+2:]))
+		offset += 8
+		for range visuals {
+			if len(s) < offset+24 {
+				break
+			}
+			visual := s[offset:]
+			if binary.LittleEndian.Uint32(visual[0:]) == rootVisual {
+				screen.masks = [3]uint32{
+					binary.LittleEndian.Uint32(visual[8:]),
+					binary.LittleEndian.Uint32(visual[12:]),
+					binary.LittleEndian.Uint32(visual[16:]),
+				}
+			}
+			offset += 24
+		}
+	}
+	if screen.masks[0] == 0 || screen.masks[1] == 0 || screen.masks[2] == 0 {
+		return nil, errors.New("x11 display does not use true colour")
+	}
+
+	return screen, nil
+}
+
+// decode converts ZPixmap image data to an image
+func (screen *x11Screen) decode(data []byte) (image.Image, error) {
+	bytesPerPixel := screen.bitsPerPixel / 8
+	pad := max(screen.scanlinePad, 8)
+	stride := (screen.width*screen.bitsPerPixel + pad - 1) / pad * pad / 8
+	if len(data) < stride*screen.height {
+		return nil, errors.New("short x11 image")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, screen.width, screen.height))
+	for y := range screen.height {
+		row := data[y*stride:]
+		for x := range screen.width {
+			var pixel uint32
+			for i := range bytesPerPixel {
+				b := uint32(row[x*bytesPerPixel+i])
+				if screen.bigEndian {
+					pixel = pixel<<8 | b
+				} else {
+					pixel |= b << (8 * i)
+				}
+			}
+
+			img.SetRGBA(x, y, color.RGBA{
+				R: channel(pixel, screen.masks[0]),
+				G: channel(pixel, screen.masks[1]),
+				B: channel(pixel, screen.masks[2]),
+				A: 255,
+			})
+		}
+	}
+
+	return img, nil
+}
+
+// channel extracts a colour channel from a pixel, scaled to 8 bits
+func channel(pixel uint32, mask uint32) uint8 {
+	shift := bits.TrailingZeros32(mask)
+	width := bits.OnesCount32(mask)
+	value := (pixel & mask) >> shift
+
+	if width >= 8 {
+		return uint8(value >> (width - 8))
+	}
+	return uint8(value * 255 / (1<<width - 1))
+}
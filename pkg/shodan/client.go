@@ -2,105 +2,320 @@ package shodan
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
 )
 
-// Client represents a Shodan API client
+// ErrKeysExhausted is returned when every API key is rate limited or out of
+// credits
+var ErrKeysExhausted = errors.New("all Shodan API keys are rate limited or out of credits")
+
+// Client represents a Shodan API client. It rotates through several API
+// keys, moving on to the next key when one is rate limited or runs out of
+// credits.
 type Client struct {
-	apiKey     string
 	baseURL    string
 	httpClient *http.Client
+
+	mu        sync.Mutex
+	apiKeys   []string
+	current   int
+	exhausted map[string]bool
+	statuses  []KeyStatus
 }
 
-// NewClient creates a new Shodan API client
-func NewClient(apiKey string) *Client {
+// KeyStatus is the account information of an API key
+type KeyStatus struct {
+	// Key is the masked API key
+	Key  string
+	Info *APIInfo
+	Err  error
+}
+
+// NewClient creates a new Shodan API client, using apiKeys in order
+func NewClient(apiKeys ...string) *Client {
 	return &Client{
-		apiKey:  apiKey,
-		baseURL: "https://api.shodan.io",
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		baseURL:    "https://api.shodan.io",
+		httpClient: islazy.NewHTTPClient(30 * time.Second),
+		apiKeys:    apiKeys,
+		exhausted:  make(map[string]bool),
 	}
 }
 
 // GetHost queries Shodan for information about a specific IP address
 func (c *Client) GetHost(ip string) (*Host, error) {
-	url := fmt.Sprintf("%s/shodan/host/%s?key=%s", c.baseURL, ip, c.apiKey)
+	var host Host
+	if err := c.get("/shodan/host/"+ip, nil, &host); err != nil {
+		return nil, err
+	}
+
+	return &host, nil
+}
+
+// GetHostMinimal queries Shodan for basic information about a specific IP address
+// This is a lighter version that returns less data and consumes fewer API credits
+func (c *Client) GetHostMinimal(ip string) (*Host, error) {
+	var host Host
+	if err := c.get("/shodan/host/"+ip, url.Values{"minify": {"true"}}, &host); err != nil {
+		return nil, err
+	}
 
-	resp, err := c.httpClient.Get(url)
+	return &host, nil
+}
+
+// Search queries Shodan's host search for a single page of results.
+// Note that search queries with filters consume query credits.
+func (c *Client) Search(query string, page int) (*SearchResult, error) {
+	var result SearchResult
+	if err := c.get("/shodan/host/search", url.Values{
+		"query":  {query},
+		"page":   {fmt.Sprint(page)},
+		"minify": {"true"},
+	}, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// SearchIPs returns the unique IP addresses matching a search query, up to
+// max results. Shodan returns 100 matches per page.
+func (c *Client) SearchIPs(query string, max int) ([]string, error) {
+	seen := make(map[string]bool)
+	var ips []string
+
+	for page := 1; ; page++ {
+		result, err := c.Search(query, page)
+		if err != nil {
+			return ips, err
+		}
+
+		for _, match := range result.Matches {
+			if match.IP == "" || seen[match.IP] {
+				continue
+			}
+			seen[match.IP] = true
+			ips = append(ips, match.IP)
+
+			if max > 0 && len(ips) >= max {
+				return ips, nil
+			}
+		}
+
+		// stop when we run out of pages
+		if len(result.Matches) == 0 || page*100 >= result.Total {
+			break
+		}
+	}
+
+	return ips, nil
+}
+
+// APIInfo returns the account information of an API key
+func (c *Client) APIInfo(apiKey string) (*APIInfo, error) {
+	resp, err := c.request(apiKey, "/api-info", nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query Shodan API: %w", err)
+		return nil, fmt.Errorf("failed to validate API key: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Shodan API error (status %d): %s", resp.StatusCode, string(body))
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("invalid Shodan API key")
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API key validation failed (status %d): %s", resp.StatusCode, string(body))
+	}
 
-	var host Host
-	if err := json.Unmarshal(body, &host); err != nil {
+	var info APIInfo
+	if err := json.Unmarshal(body, &info); err != nil {
 		return nil, fmt.Errorf("failed to parse Shodan response: %w", err)
 	}
 
-	return &host, nil
+	return &info, nil
 }
 
-// GetHostMinimal queries Shodan for basic information about a specific IP address
-// This is a lighter version that returns less data and consumes fewer API credits
-func (c *Client) GetHostMinimal(ip string) (*Host, error) {
-	url := fmt.Sprintf("%s/shodan/host/%s?key=%s&minify=true", c.baseURL, ip, c.apiKey)
+// IsValidAPIKey checks if the API keys are valid. Invalid keys are dropped,
+// and an error is returned if none are left. The account information of the
+// keys is kept, see Keys.
+func (c *Client) IsValidAPIKey() error {
+	c.mu.Lock()
+	keys := c.apiKeys
+	c.mu.Unlock()
 
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query Shodan API: %w", err)
+	var valid []string
+	var statuses []KeyStatus
+	var lastErr error
+	for _, key := range keys {
+		info, err := c.APIInfo(key)
+		statuses = append(statuses, KeyStatus{Key: maskKey(key), Info: info, Err: err})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		valid = append(valid, key)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Shodan API error (status %d): %s", resp.StatusCode, string(body))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.apiKeys = valid
+	c.current = 0
+	c.statuses = statuses
+
+	if len(valid) == 0 {
+		if lastErr == nil {
+			lastErr = errors.New("no Shodan API keys configured")
+		}
+		return lastErr
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	return nil
+}
+
+// Keys returns the account information of the API keys, as of the last
+// IsValidAPIKey check
+func (c *Client) Keys() []KeyStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.statuses
+}
+
+// QueryCredits returns the query credits left on the valid API keys, as of
+// the last IsValidAPIKey check
+func (c *Client) QueryCredits() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var credits int
+	for _, status := range c.statuses {
+		if status.Info != nil {
+			credits += status.Info.QueryCredits
+		}
 	}
 
-	var host Host
-	if err := json.Unmarshal(body, &host); err != nil {
-		return nil, fmt.Errorf("failed to parse Shodan response: %w", err)
+	return credits
+}
+
+// get queries an API path, decoding the response into v. Keys that are
+// rate limited or out of credits are rotated through until one works.
+func (c *Client) get(path string, query url.Values, v any) error {
+	tried := make(map[string]bool)
+
+	for {
+		key, ok := c.key(tried)
+		if !ok {
+			return ErrKeysExhausted
+		}
+		tried[key] = true
+
+		resp, err := c.request(key, path, query)
+		if err != nil {
+			requests.Inc(apiShodan, outcomeError)
+			return fmt.Errorf("failed to query Shodan API: %w", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			requests.Inc(apiShodan, outcomeError)
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			// rate limits are per key, so the next key may still work
+			requests.Inc(apiShodan, outcomeRateLimited)
+			c.rotate(key, false)
+			continue
+		}
+		if outOfCredits(resp.StatusCode, body) {
+			requests.Inc(apiShodan, outcomeOutOfCredits)
+			c.rotate(key, true)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			requests.Inc(apiShodan, outcomeError)
+			return fmt.Errorf("Shodan API error (status %d): %s", resp.StatusCode, string(body))
+		}
+
+		if err := json.Unmarshal(body, v); err != nil {
+			requests.Inc(apiShodan, outcomeError)
+			return fmt.Errorf("failed to parse Shodan response: %w", err)
+		}
+
+		requests.Inc(apiShodan, outcomeOK)
+		return nil
+	}
+}
+
+// request sends a GET request for an API path with a key
+func (c *Client) request(apiKey string, path string, query url.Values) (*http.Response, error) {
+	values := url.Values{}
+	for k, v := range query {
+		values[k] = v
 	}
+	values.Set("key", apiKey)
 
-	return &host, nil
+	return c.httpClient.Get(c.baseURL + path + "?" + values.Encode())
 }
 
-// IsValidAPIKey checks if the provided API key is valid
-func (c *Client) IsValidAPIKey() error {
-	url := fmt.Sprintf("%s/api-info?key=%s", c.baseURL, c.apiKey)
+// key returns the current key, skipping keys that are out of credits or
+// were tried already
+func (c *Client) key(tried map[string]bool) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to validate API key: %w", err)
+	for i := range c.apiKeys {
+		key := c.apiKeys[(c.current+i)%len(c.apiKeys)]
+		if !c.exhausted[key] && !tried[key] {
+			return key, true
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("invalid Shodan API key")
+	return "", false
+}
+
+// rotate moves on from a key to the next one, for good if it is exhausted
+func (c *Client) rotate(key string, exhausted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if exhausted {
+		c.exhausted[key] = true
+	}
+	if len(c.apiKeys) > 0 && c.apiKeys[c.current%len(c.apiKeys)] == key {
+		c.current = (c.current + 1) % len(c.apiKeys)
 	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API key validation failed (status %d): %s", resp.StatusCode, string(body))
+// outOfCredits checks if a response says the key ran out of credits
+func outOfCredits(status int, body []byte) bool {
+	if status == http.StatusPaymentRequired {
+		return true
 	}
 
-	return nil
+	return (status == http.StatusForbidden || status == http.StatusUnauthorized) &&
+		strings.Contains(strings.ToLower(string(body)), "credits")
+}
+
+// maskKey hides most of an API key, for logging
+func maskKey(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+
+	return key[:4] + "****"
 }
@@ -3,27 +3,47 @@ package shodan
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 // InitFromEnv initializes a Shodan client from environment variables
-// It attempts to load from .env file first, then falls back to system environment
+// It attempts to load from .env file first, then falls back to system environment.
+// Several API keys can be set as a comma separated SHODAN_API_KEYS list, which
+// are rotated through as they are rate limited or run out of credits.
 func InitFromEnv() (*Client, error) {
 	// Try to load .env file (ignore errors as it may not exist)
 	_ = godotenv.Load()
 
-	apiKey := os.Getenv("SHODAN_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("SHODAN_API_KEY environment variable is required")
+	apiKeys := KeysFromEnv()
+	if len(apiKeys) == 0 {
+		return nil, fmt.Errorf("SHODAN_API_KEYS or SHODAN_API_KEY environment variable is required")
 	}
 
-	client := NewClient(apiKey)
+	client := NewClient(apiKeys...)
 
-	// Validate the API key
+	// Validate the API keys
 	if err := client.IsValidAPIKey(); err != nil {
 		return nil, fmt.Errorf("failed to validate Shodan API key: %w", err)
 	}
 
 	return client, nil
 }
+
+// KeysFromEnv returns the API keys in SHODAN_API_KEYS, followed by the one
+// in SHODAN_API_KEY, without duplicates
+func KeysFromEnv() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range append(strings.Split(os.Getenv("SHODAN_API_KEYS"), ","), os.Getenv("SHODAN_API_KEY")) {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	return keys
+}
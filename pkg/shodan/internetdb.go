@@ -0,0 +1,75 @@
+package shodan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// ErrNotInInternetDB is returned when InternetDB has no information on an IP
+var ErrNotInInternetDB = errors.New("no information available in InternetDB")
+
+// InternetDBHost is the information InternetDB has on an IP address
+type InternetDBHost struct {
+	IP        string   `json:"ip"`
+	Ports     []int    `json:"ports"`
+	Hostnames []string `json:"hostnames"`
+	CPEs      []string `json:"cpes"`
+	Tags      []string `json:"tags"`
+	Vulns     []string `json:"vulns"`
+}
+
+// InternetDB is a client of Shodan's free InternetDB. It knows the open
+// ports, hostnames and vulnerabilities of IPs Shodan crawled, and needs no
+// API key or credits, but has no geolocation or ownership information.
+type InternetDB struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewInternetDB returns a new InternetDB client
+func NewInternetDB() *InternetDB {
+	return &InternetDB{
+		baseURL:    "https://internetdb.shodan.io",
+		httpClient: islazy.NewHTTPClient(15 * time.Second),
+	}
+}
+
+// GetHost queries InternetDB for information about an IP address
+func (i *InternetDB) GetHost(ip string) (*InternetDBHost, error) {
+	resp, err := i.httpClient.Get(i.baseURL + "/" + ip)
+	if err != nil {
+		requests.Inc(apiInternetDB, outcomeError)
+		return nil, fmt.Errorf("failed to query InternetDB: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		requests.Inc(apiInternetDB, outcomeError)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		requests.Inc(apiInternetDB, outcomeNotFound)
+		return nil, ErrNotInInternetDB
+	}
+	if resp.StatusCode != http.StatusOK {
+		requests.Inc(apiInternetDB, outcomeError)
+		return nil, fmt.Errorf("InternetDB error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var host InternetDBHost
+	if err := json.Unmarshal(body, &host); err != nil {
+		requests.Inc(apiInternetDB, outcomeError)
+		return nil, fmt.Errorf("failed to parse InternetDB response: %w", err)
+	}
+
+	requests.Inc(apiInternetDB, outcomeOK)
+	return &host, nil
+}
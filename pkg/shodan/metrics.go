@@ -0,0 +1,21 @@
+package shodan
+
+import "github.com/sensepost/gowitness/pkg/metrics"
+
+var requests = metrics.NewCounter("gowitness_shodan_requests_total",
+	"Shodan API and InternetDB requests, by API and outcome", "api", "outcome")
+
+// Outcomes of requests, as recorded in metrics
+const (
+	outcomeOK           = "ok"
+	outcomeError        = "error"
+	outcomeNotFound     = "not_found"
+	outcomeRateLimited  = "rate_limited"
+	outcomeOutOfCredits = "out_of_credits"
+)
+
+// APIs requests are made to, as recorded in metrics
+const (
+	apiShodan     = "shodan"
+	apiInternetDB = "internetdb"
+)
@@ -22,12 +22,13 @@ func (st *ShodanTime) UnmarshalJSON(data []byte) error {
 
 	// Try different timestamp formats that Shodan might use
 	formats := []string{
-		"2006-01-02T15:04:05.000000",      // Shodan's typical format
-		"2006-01-02T15:04:05",             // Without microseconds
-		time.RFC3339,                      // Standard RFC3339
-		time.RFC3339Nano,                  // RFC3339 with nanoseconds
-		"2006-01-02T15:04:05Z",           // UTC format
-		"2006-01-02T15:04:05.000000Z",    // UTC with microseconds
+		"2006-01-02T15:04:05.000000",  // Shodan's typical format
+		"2006-01-02T15:04:05",         // Without microseconds
+		time.RFC3339,                  // Standard RFC3339
+		time.RFC3339Nano,              // RFC3339 with nanoseconds
+		"2006-01-02T15:04:05Z",        // UTC format
+		"2006-01-02T15:04:05.000000Z", // UTC with microseconds
+		"20060102150405Z",             // Certificate validity dates
 	}
 
 	for _, format := range formats {
@@ -73,16 +74,23 @@ type Host struct {
 
 // Service represents a service running on a port
 type Service struct {
-	Port      int               `json:"port"`
-	Transport string            `json:"transport"`
-	Product   string            `json:"product,omitempty"`
-	Version   string            `json:"version,omitempty"`
-	Banner    string            `json:"data,omitempty"`
-	Timestamp ShodanTime        `json:"timestamp,omitempty"`
-	Location  ServiceLocation   `json:"location,omitempty"`
-	HTTP      *HTTPInfo         `json:"http,omitempty"`
-	SSL       *SSLInfo          `json:"ssl,omitempty"`
-	Opts      map[string]string `json:"opts,omitempty"`
+	Port      int             `json:"port"`
+	Transport string          `json:"transport"`
+	Product   string          `json:"product,omitempty"`
+	Version   string          `json:"version,omitempty"`
+	Banner    string          `json:"data,omitempty"`
+	Timestamp ShodanTime      `json:"timestamp,omitempty"`
+	Location  ServiceLocation `json:"location,omitempty"`
+	HTTP      *HTTPInfo       `json:"http,omitempty"`
+	SSL       *SSLInfo        `json:"ssl,omitempty"`
+	Opts      map[string]any  `json:"opts,omitempty"`
+	Shodan    ServiceMeta     `json:"_shodan,omitempty"`
+}
+
+// ServiceMeta is Shodan's information on how a service was crawled
+type ServiceMeta struct {
+	// Module is the crawler module that identified the service, e.g. https
+	Module string `json:"module,omitempty"`
 }
 
 // ServiceLocation represents the geolocation of a service
@@ -98,21 +106,36 @@ type ServiceLocation struct {
 
 // HTTPInfo represents HTTP-specific information
 type HTTPInfo struct {
-	Status     int               `json:"status,omitempty"`
-	Title      string            `json:"title,omitempty"`
-	Server     string            `json:"server,omitempty"`
-	Headers    map[string]string `json:"headers,omitempty"`
-	HTML       string            `json:"html,omitempty"`
-	Redirects  []string          `json:"redirects,omitempty"`
-	Components map[string]string `json:"components,omitempty"`
+	Status     int                      `json:"status,omitempty"`
+	Title      string                   `json:"title,omitempty"`
+	Server     string                   `json:"server,omitempty"`
+	Host       string                   `json:"host,omitempty"`
+	Location   string                   `json:"location,omitempty"`
+	HTML       string                   `json:"html,omitempty"`
+	Redirects  []HTTPRedirect           `json:"redirects,omitempty"`
+	Components map[string]HTTPComponent `json:"components,omitempty"`
+}
+
+// HTTPRedirect represents a redirect Shodan followed
+type HTTPRedirect struct {
+	Host     string `json:"host,omitempty"`
+	Location string `json:"location,omitempty"`
+}
+
+// HTTPComponent represents a web technology Shodan detected
+type HTTPComponent struct {
+	Categories []string `json:"categories,omitempty"`
 }
 
 // SSLInfo represents SSL/TLS certificate information
 type SSLInfo struct {
-	Versions    []string         `json:"versions,omitempty"`
-	Cipher      SSLCipher        `json:"cipher,omitempty"`
-	Certificate SSLCertificate   `json:"cert,omitempty"`
-	Chain       []SSLCertificate `json:"chain,omitempty"`
+	// Versions lists the protocol versions, prefixed with a - if they are
+	// not supported, e.g. -SSLv3
+	Versions    []string       `json:"versions,omitempty"`
+	Cipher      SSLCipher      `json:"cipher,omitempty"`
+	Certificate SSLCertificate `json:"cert,omitempty"`
+	// Chain holds the PEM encoded certificate chain
+	Chain []string `json:"chain,omitempty"`
 }
 
 // SSLCipher represents SSL cipher information
@@ -124,13 +147,19 @@ type SSLCipher struct {
 
 // SSLCertificate represents SSL certificate information
 type SSLCertificate struct {
-	Subject     SSLSubject `json:"subject,omitempty"`
-	Issuer      SSLSubject `json:"issuer,omitempty"`
-	Serial      string     `json:"serial,omitempty"`
-	Fingerprint string     `json:"fingerprint,omitempty"`
-	Expired     bool       `json:"expired,omitempty"`
-	ValidFrom   ShodanTime `json:"valid_from,omitempty"`
-	ValidUntil  ShodanTime `json:"valid_until,omitempty"`
+	Subject     SSLSubject     `json:"subject,omitempty"`
+	Issuer      SSLSubject     `json:"issuer,omitempty"`
+	Serial      json.Number    `json:"serial,omitempty"`
+	Fingerprint SSLFingerprint `json:"fingerprint,omitempty"`
+	Expired     bool           `json:"expired,omitempty"`
+	ValidFrom   ShodanTime     `json:"issued,omitempty"`
+	ValidUntil  ShodanTime     `json:"expires,omitempty"`
+}
+
+// SSLFingerprint represents the fingerprints of a certificate
+type SSLFingerprint struct {
+	SHA1   string `json:"sha1,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
 }
 
 // SSLSubject represents SSL certificate subject/issuer information
@@ -143,6 +172,21 @@ type SSLSubject struct {
 	ST string `json:"ST,omitempty"`
 }
 
+// SearchResult represents a page of results from the Shodan host search
+type SearchResult struct {
+	Matches []SearchMatch `json:"matches"`
+	Total   int           `json:"total"`
+}
+
+// SearchMatch is a single service banner returned by a search
+type SearchMatch struct {
+	IP        string `json:"ip_str"`
+	Port      int    `json:"port"`
+	Transport string `json:"transport,omitempty"`
+	Org       string `json:"org,omitempty"`
+	ASN       string `json:"asn,omitempty"`
+}
+
 // APIInfo represents Shodan API account information
 type APIInfo struct {
 	QueryCredits int    `json:"query_credits"`
@@ -0,0 +1,163 @@
+// Package takeover detects hosts that may be vulnerable to a subdomain
+// takeover, by matching their CNAMEs and responses against fingerprints of
+// services that let anyone claim an unused name.
+package takeover
+
+import (
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/dns"
+)
+
+// Fingerprint describes how an unclaimed resource of a service looks
+type Fingerprint struct {
+	Service string
+	// CNAMEs are the domain suffixes of the service's CNAME targets
+	CNAMEs []string
+	// Body are strings the service responds with for unclaimed resources
+	Body []string
+	// NXDomain is true if a CNAME to the service that does not resolve is
+	// claimable on its own
+	NXDomain bool
+}
+
+// Fingerprints are the known takeover fingerprints, after the community
+// maintained can-i-take-over-xyz list
+var Fingerprints = []Fingerprint{
+	{
+		Service: "GitHub Pages",
+		CNAMEs:  []string{"github.io"},
+		Body:    []string{"There isn't a GitHub Pages site here."},
+	},
+	{
+		Service: "AWS S3",
+		CNAMEs:  []string{"amazonaws.com"},
+		Body:    []string{"NoSuchBucket", "The specified bucket does not exist"},
+	},
+	{
+		Service: "Microsoft Azure",
+		CNAMEs: []string{
+			"azurewebsites.net", "cloudapp.net", "cloudapp.azure.com", "trafficmanager.net",
+			"blob.core.windows.net", "azure-api.net", "azureedge.net", "azurefd.net",
+		},
+		NXDomain: true,
+	},
+	{
+		Service: "Heroku",
+		CNAMEs:  []string{"herokuapp.com", "herokudns.com"},
+		Body:    []string{"No such app", "herokucdn.com/error-pages/no-such-app.html"},
+	},
+	{
+		Service: "Shopify",
+		CNAMEs:  []string{"myshopify.com"},
+		Body:    []string{"Sorry, this shop is currently unavailable."},
+	},
+	{
+		Service: "Fastly",
+		CNAMEs:  []string{"fastly.net"},
+		Body:    []string{"Fastly error: unknown domain"},
+	},
+	{
+		Service: "Ghost",
+		CNAMEs:  []string{"ghost.io"},
+		Body:    []string{"The thing you were looking for is no longer here, or never was"},
+	},
+	{
+		Service: "Pantheon",
+		CNAMEs:  []string{"pantheonsite.io"},
+		Body:    []string{"The gods are wise, but do not know of the site which you seek."},
+	},
+	{
+		Service: "Tumblr",
+		CNAMEs:  []string{"domains.tumblr.com"},
+		Body:    []string{"Whatever you were looking for doesn't currently exist at this address"},
+	},
+	{
+		Service: "Zendesk",
+		CNAMEs:  []string{"zendesk.com"},
+		Body:    []string{"Help Center Closed"},
+	},
+	{
+		Service: "Surge.sh",
+		CNAMEs:  []string{"surge.sh"},
+		Body:    []string{"project not found"},
+	},
+	{
+		Service: "Bitbucket",
+		CNAMEs:  []string{"bitbucket.io"},
+		Body:    []string{"Repository not found"},
+	},
+	{
+		Service: "Netlify",
+		CNAMEs:  []string{"netlify.app", "netlify.com"},
+		Body:    []string{"Not Found - Request ID"},
+	},
+	{
+		Service: "Readme.io",
+		CNAMEs:  []string{"readme.io"},
+		Body:    []string{"Project doesnt exist... yet!"},
+	},
+	{
+		Service: "Unbounce",
+		CNAMEs:  []string{"unbouncepages.com"},
+		Body:    []string{"The requested URL was not found on this server."},
+	},
+}
+
+// Match is a host that matched a takeover fingerprint
+type Match struct {
+	Host    string
+	Service string
+	CNAME   string
+	// Evidence describes what matched
+	Evidence string
+}
+
+// Check matches a host's DNS answer and, if it was probed, its response
+// body against the fingerprints. A host only matches a fingerprint if it is
+// a CNAME to the service, and the service either responded with an
+// unclaimed body or the CNAME is dangling where that is enough to claim it.
+func Check(answer *dns.Answer, body string) *Match {
+	if answer == nil {
+		return nil
+	}
+
+	dangling := answer.Dangling()
+	for _, record := range answer.Records {
+		if record.Type != dns.TypeCNAME {
+			continue
+		}
+
+		for _, fingerprint := range Fingerprints {
+			if !fingerprint.matchesCNAME(record.Value) {
+				continue
+			}
+
+			match := &Match{Host: answer.Host, Service: fingerprint.Service, CNAME: record.Value}
+			if dangling && fingerprint.NXDomain {
+				match.Evidence = "CNAME target " + record.Value + " does not resolve"
+				return match
+			}
+			for _, needle := range fingerprint.Body {
+				if body != "" && strings.Contains(body, needle) {
+					match.Evidence = "response contains \"" + needle + "\""
+					return match
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchesCNAME checks if a CNAME target belongs to the service
+func (f *Fingerprint) matchesCNAME(target string) bool {
+	target = strings.TrimSuffix(strings.ToLower(target), ".")
+	for _, suffix := range f.CNAMEs {
+		if target == suffix || strings.HasSuffix(target, "."+suffix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,196 @@
+package thumbnail
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	// decoders for the screenshot formats
+	_ "image/png"
+)
+
+const (
+	// DefaultWidth is the width thumbnails are generated at
+	DefaultWidth = 400
+	// Dir is the directory, inside a screenshot path, thumbnails are stored in
+	Dir = "thumbs"
+
+	// quality is the jpeg quality thumbnails are encoded with
+	quality = 75
+)
+
+// Widths are the widths thumbnails may be requested at from the web
+// server. Each is cached on disk, so the set is kept small.
+var Widths = []int{200, DefaultWidth, 800}
+
+// Clean returns a screenshot path relative to its screenshot directory,
+// with any leading or .. elements removed so that it can not leave it.
+// Screenshots may be stored in subdirectories, using forward slashes.
+func Clean(screenshot string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(screenshot)), "/")
+}
+
+// Filename returns the filename of the thumbnail of a screenshot at a
+// width, relative to the thumbnail directory. Thumbnails mirror the
+// subdirectories of their screenshots and are always jpeg encoded.
+func Filename(screenshot string, width int) string {
+	name := Clean(screenshot)
+	name = strings.TrimSuffix(name, path.Ext(name))
+
+	return fmt.Sprintf("%s.w%d.jpeg", name, width)
+}
+
+// Path returns the path to the thumbnail of a screenshot at a width, for
+// screenshots stored in dir
+func Path(dir string, screenshot string, width int) string {
+	return filepath.Join(dir, Dir, filepath.FromSlash(Filename(screenshot, width)))
+}
+
+// Resize scales an image down to width, keeping its aspect ratio. Each
+// target pixel is the average of the source pixels it covers, which is
+// good enough for screenshots. Images narrower than width are returned
+// as is.
+func Resize(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if width <= 0 || srcW <= width || srcH == 0 {
+		return img
+	}
+
+	height := max(1, srcH*width/srcW)
+
+	// work on rgba pixels directly, At() is too slow for this
+	src, ok := img.(*image.RGBA)
+	if !ok || src.Rect.Min != (image.Point{}) {
+		src = image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+		draw.Draw(src, src.Rect, img, bounds.Min, draw.Src)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := max(y0+1, (y+1)*srcH/height)
+
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := max(x0+1, (x+1)*srcW/width)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					b += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+// Write resizes a decoded screenshot and writes it as a thumbnail for
+// screenshots stored in dir, returning the thumbnail filename.
+func Write(dir string, screenshot string, img image.Image, width int) (string, error) {
+	path := Path(dir, screenshot, width)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create thumbnail directory: %w", err)
+	}
+
+	// write to a temporary file first so that readers never see a
+	// partially written thumbnail
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".thumb-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create thumbnail file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := jpeg.Encode(tmp, Resize(img, width), &jpeg.Options{Quality: quality}); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0664); err != nil {
+		return "", fmt.Errorf("failed to set thumbnail permissions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to move thumbnail into place: %w", err)
+	}
+
+	return Filename(screenshot, width), nil
+}
+
+// Generate returns the path to the thumbnail of a screenshot stored in
+// dir, creating it if it does not exist or is older than the screenshot.
+func Generate(dir string, screenshot string, width int) (string, error) {
+	source := filepath.Join(dir, filepath.FromSlash(Clean(screenshot)))
+	sourceInfo, err := os.Stat(source)
+	if err != nil {
+		return "", err
+	}
+
+	path := Path(dir, screenshot, width)
+	if info, err := os.Stat(path); err == nil && !info.ModTime().Before(sourceInfo.ModTime()) {
+		return path, nil
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+
+	if _, err := Write(dir, screenshot, img, width); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// Remove deletes all thumbnails of a screenshot stored in dir, returning
+// the number of files removed.
+func Remove(dir string, screenshot string) (int, error) {
+	name := Clean(screenshot)
+	name = strings.TrimSuffix(name, path.Ext(name))
+
+	// screenshot filenames are made safe when written, so there are no
+	// glob meta characters to worry about
+	matches, err := filepath.Glob(filepath.Join(dir, Dir, filepath.FromSlash(name)+".w*.jpeg"))
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, match := range matches {
+		if err := os.Remove(match); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
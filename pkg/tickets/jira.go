@@ -0,0 +1,197 @@
+package tickets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// jiraPriorities are the JIRA priorities of finding severities, as named
+// in the default priority scheme
+var jiraPriorities = map[string]string{
+	"critical": "Highest",
+	"high":     "High",
+	"medium":   "Medium",
+	"low":      "Low",
+	"info":     "Lowest",
+}
+
+// Jira creates issues with the JIRA REST API
+type Jira struct {
+	// Project is the key of the project issues are created in
+	Project string
+	// IssueType is the name of the type of issues created, e.g. Bug
+	IssueType string
+	// Priorities sets the priority of issues from their severity. Projects
+	// whose screens have no priority field need it off.
+	Priorities bool
+
+	baseURL    string
+	user       string
+	token      string
+	httpClient *http.Client
+}
+
+// jiraIssue is the response from the create issue endpoint
+type jiraIssue struct {
+	ID  string `json:"id"`
+	Key string `json:"key"`
+}
+
+// NewJira returns a new JIRA client for the instance at baseURL. JIRA Cloud
+// authenticates with the email address of user and an API token, and JIRA
+// Data Center with a personal access token and no user.
+func NewJira(baseURL, project, user, token string) *Jira {
+	return &Jira{
+		Project:    project,
+		IssueType:  "Bug",
+		Priorities: true,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		user:       user,
+		token:      token,
+		httpClient: islazy.NewHTTPClient(30 * time.Second),
+	}
+}
+
+// JiraFromEnv returns a JIRA client configured by the JIRA_URL,
+// JIRA_PROJECT, JIRA_USER, JIRA_API_TOKEN and JIRA_ISSUE_TYPE environment
+// variables. It attempts to load from a .env file first, then falls back to
+// the system environment.
+func JiraFromEnv() (*Jira, error) {
+	// Try to load .env file (ignore errors as it may not exist)
+	_ = godotenv.Load()
+
+	baseURL, project, token := os.Getenv("JIRA_URL"), os.Getenv("JIRA_PROJECT"), os.Getenv("JIRA_API_TOKEN")
+	if baseURL == "" || project == "" || token == "" {
+		return nil, errors.New("JIRA_URL, JIRA_PROJECT and JIRA_API_TOKEN environment variables are required")
+	}
+
+	jira := NewJira(baseURL, project, os.Getenv("JIRA_USER"), token)
+	if issueType := os.Getenv("JIRA_ISSUE_TYPE"); issueType != "" {
+		jira.IssueType = issueType
+	}
+	if os.Getenv("JIRA_NO_PRIORITY") != "" {
+		jira.Priorities = false
+	}
+
+	return jira, nil
+}
+
+// Name returns the provider name
+func (j *Jira) Name() string {
+	return "jira"
+}
+
+// Create creates an issue
+func (j *Jira) Create(issue Issue) (string, string, error) {
+	fields := map[string]any{
+		"project":     map[string]string{"key": j.Project},
+		"issuetype":   map[string]string{"name": j.IssueType},
+		"summary":     truncate(issue.Summary, 255),
+		"description": issue.Description,
+		"labels":      slices.Concat(issue.Labels, []string{"severity-" + issue.Severity}),
+	}
+	if priority, ok := jiraPriorities[issue.Severity]; ok && j.Priorities {
+		fields["priority"] = map[string]string{"name": priority}
+	}
+
+	body, err := json.Marshal(map[string]any{"fields": fields})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal issue: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, j.baseURL+"/rest/api/2/issue", bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var created jiraIssue
+	if err := j.do(req, &created); err != nil {
+		return "", "", err
+	}
+
+	return created.Key, j.baseURL + "/browse/" + created.Key, nil
+}
+
+// Attach uploads a file to an issue
+func (j *Jira) Attach(key string, filename string, content io.Reader) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, j.baseURL+"/rest/api/2/issue/"+key+"/attachments", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	// JIRA rejects attachments without it, as a CSRF protection
+	req.Header.Set("X-Atlassian-Token", "no-check")
+
+	return j.do(req, nil)
+}
+
+// do sends an authenticated request, decoding the response into v if it
+// is set
+func (j *Jira) do(req *http.Request, v any) error {
+	if j.user != "" {
+		req.SetBasicAuth(j.user, j.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query JIRA API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("JIRA API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse JIRA response: %w", err)
+	}
+
+	return nil
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n-1]) + "…"
+}
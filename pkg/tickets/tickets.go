@@ -0,0 +1,307 @@
+// Package tickets creates issues in ticketing systems, such as JIRA, for
+// severe findings and newly exposed services, with the screenshot of the
+// affected host attached. Every issue created is recorded, so running it
+// again only creates issues for what is new.
+package tickets
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"github.com/sensepost/gowitness/pkg/thumbnail"
+	"gorm.io/gorm"
+)
+
+// ErrUnknownProvider is returned for a ticketing provider that is not
+// supported
+var ErrUnknownProvider = errors.New("unknown ticketing provider")
+
+// Providers are the supported ticketing providers
+var Providers = []string{"jira"}
+
+// Issue is an issue to create
+type Issue struct {
+	Summary     string
+	Description string
+	// Severity is a finding severity, from info to critical
+	Severity string
+	Labels   []string
+}
+
+// Tracker is a ticketing system issues are created in
+type Tracker interface {
+	// Name returns the name of the provider
+	Name() string
+	// Create creates an issue, returning its key and the URL it is shown at
+	Create(issue Issue) (key string, url string, err error)
+	// Attach uploads a file to an issue
+	Attach(key string, filename string, content io.Reader) error
+}
+
+// New returns the tracker of a provider, configured from the environment
+func New(provider string) (Tracker, error) {
+	switch provider {
+	case "jira":
+		return JiraFromEnv()
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, provider)
+	}
+}
+
+// Options configure Run
+type Options struct {
+	// MinSeverity is the least severe finding or alert a ticket is created
+	// for, high if it is empty
+	MinSeverity string
+	// SkipFindings and SkipServices leave findings, and the port alerts of
+	// newly exposed services, alone
+	SkipFindings bool
+	SkipServices bool
+	// ScanSessionID limits tickets to a scan session, if it is set
+	ScanSessionID *uint
+	// ScreenshotPath is where screenshots are, if their scan session does
+	// not say
+	ScreenshotPath string
+	// DryRun reports the tickets that would be created without creating
+	// them
+	DryRun bool
+}
+
+// Summary is the outcome of Run
+type Summary struct {
+	Created int `json:"created"`
+	// Existing were ticketed before
+	Existing int `json:"existing"`
+	Failed   int `json:"failed"`
+	// Tickets are the tickets that were created, or would be on a dry run
+	Tickets []models.Ticket `json:"tickets"`
+}
+
+// candidate is a finding or alert to ticket
+type candidate struct {
+	ticket models.Ticket
+	issue  Issue
+	// resultID is the result whose screenshot is attached, if any
+	resultID *uint
+}
+
+// Run creates a ticket in tracker for every finding and newly exposed
+// service at or above the minimum severity that was not ticketed before
+func Run(logger *slog.Logger, db *gorm.DB, tracker Tracker, opts Options) (*Summary, error) {
+	minSeverity := opts.MinSeverity
+	if minSeverity == "" {
+		minSeverity = "high"
+	}
+	if !slices.Contains(plugins.Severities, minSeverity) {
+		return nil, fmt.Errorf("invalid severity %q", minSeverity)
+	}
+	severities := plugins.Severities[slices.Index(plugins.Severities, minSeverity):]
+
+	var candidates []candidate
+	if !opts.SkipFindings {
+		found, err := findingCandidates(db, severities, opts.ScanSessionID)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, found...)
+	}
+	if !opts.SkipServices {
+		found, err := serviceCandidates(db, severities, opts.ScanSessionID)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, found...)
+	}
+
+	summary := &Summary{Tickets: []models.Ticket{}}
+	for _, c := range candidates {
+		c.ticket.Provider = tracker.Name()
+
+		var existing int64
+		if err := db.Model(&models.Ticket{}).
+			Where("provider = ? AND fingerprint = ?", c.ticket.Provider, c.ticket.Fingerprint).
+			Count(&existing).Error; err != nil {
+			return summary, fmt.Errorf("failed to check existing tickets: %w", err)
+		}
+		if existing > 0 {
+			summary.Existing++
+			continue
+		}
+
+		if opts.DryRun {
+			summary.Created++
+			summary.Tickets = append(summary.Tickets, c.ticket)
+			continue
+		}
+
+		key, url, err := tracker.Create(c.issue)
+		if err != nil {
+			logger.Warn("failed to create ticket", "provider", tracker.Name(), "summary", c.ticket.Summary, "err", err)
+			summary.Failed++
+			continue
+		}
+		c.ticket.Key, c.ticket.URL = key, url
+
+		if err := db.Create(&c.ticket).Error; err != nil {
+			return summary, fmt.Errorf("failed to record ticket %s: %w", key, err)
+		}
+		summary.Created++
+		summary.Tickets = append(summary.Tickets, c.ticket)
+		logger.Info("created ticket", "key", key, "summary", c.ticket.Summary)
+
+		if c.resultID != nil {
+			if err := attachScreenshot(db, tracker, key, *c.resultID, opts.ScreenshotPath); err != nil {
+				logger.Warn("failed to attach screenshot to ticket", "key", key, "err", err)
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// findingCandidates returns the findings of severities
+func findingCandidates(db *gorm.DB, severities []string, scanSessionID *uint) ([]candidate, error) {
+	query := db.Where("severity IN ?", severities)
+	if scanSessionID != nil {
+		query = query.Where("scan_session_id = ?", *scanSessionID)
+	}
+
+	var findings []models.Finding
+	if err := query.Order("id").Find(&findings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get findings: %w", err)
+	}
+
+	var candidates []candidate
+	for _, finding := range findings {
+		target := finding.IPAddress
+		if finding.Domain != "" {
+			target = finding.Domain
+		}
+
+		resultID := finding.ResultID
+		if resultID != nil {
+			var result models.Result
+			if err := db.Select("id", "url").First(&result, *resultID).Error; err == nil {
+				target = result.URL
+			}
+		} else {
+			resultID = latestResult(db, finding.IPAddress)
+		}
+
+		id := finding.ID
+		summary := fmt.Sprintf("[%s] %s", finding.Severity, finding.Title)
+		if target != "" {
+			summary += " on " + target
+		}
+
+		var description strings.Builder
+		fmt.Fprintf(&description, "%s\n\n", finding.Description)
+		fmt.Fprintf(&description, "Target: %s\nSeverity: %s\nSource: %s\n", target, finding.Severity, finding.Source)
+		if finding.CVE != "" {
+			fmt.Fprintf(&description, "CVE: %s (CVSS %.1f)\n", finding.CVE, finding.CVSS)
+		}
+
+		candidates = append(candidates, candidate{
+			ticket: models.Ticket{
+				Fingerprint:   strings.Join([]string{"finding", finding.Source, finding.Title, target}, "|"),
+				Summary:       summary,
+				FindingID:     &id,
+				ScanSessionID: finding.ScanSessionID,
+			},
+			issue: Issue{
+				Summary:     summary,
+				Description: strings.TrimSpace(description.String()),
+				Severity:    finding.Severity,
+				Labels:      []string{"gowitness", "finding"},
+			},
+			resultID: resultID,
+		})
+	}
+
+	return candidates, nil
+}
+
+// serviceCandidates returns the alerts of severities raised for newly
+// exposed services, which are the alerts on an IP address
+func serviceCandidates(db *gorm.DB, severities []string, scanSessionID *uint) ([]candidate, error) {
+	query := db.Where("severity IN ? AND ip_address != ''", severities)
+	if scanSessionID != nil {
+		query = query.Where("scan_session_id = ?", *scanSessionID)
+	}
+
+	var alerts []models.Alert
+	if err := query.Order("id").Find(&alerts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get alerts: %w", err)
+	}
+
+	var candidates []candidate
+	for _, alert := range alerts {
+		id := alert.ID
+		summary := fmt.Sprintf("[%s] Newly exposed service %s", alert.Severity, alert.Subject)
+
+		candidates = append(candidates, candidate{
+			ticket: models.Ticket{
+				Fingerprint:   strings.Join([]string{"alert", alert.Rule, alert.Subject}, "|"),
+				Summary:       summary,
+				AlertID:       &id,
+				ScanSessionID: alert.ScanSessionID,
+			},
+			issue: Issue{
+				Summary: summary,
+				Description: fmt.Sprintf("%s\n\nService: %s\nSeverity: %s\nRule: %s\nFirst seen: %s",
+					alert.Message, alert.Subject, alert.Severity, alert.Rule, alert.CreatedAt.Format("2006-01-02 15:04")),
+				Severity: alert.Severity,
+				Labels:   []string{"gowitness", "exposed-service"},
+			},
+			resultID: latestResult(db, alert.IPAddress),
+		})
+	}
+
+	return candidates, nil
+}
+
+// latestResult returns the ID of the latest result with a screenshot for
+// an IP address, or nil if there is none
+func latestResult(db *gorm.DB, ip string) *uint {
+	if ip == "" {
+		return nil
+	}
+
+	var result models.Result
+	if err := db.Select("id").Where("ip_address = ? AND filename != '' AND failed = ?", ip, false).
+		Order("probed_at DESC").First(&result).Error; err != nil {
+		return nil
+	}
+
+	return &result.ID
+}
+
+// attachScreenshot attaches the screenshot of a result to an issue
+func attachScreenshot(db *gorm.DB, tracker Tracker, key string, resultID uint, screenshotPath string) error {
+	var result models.Result
+	if err := db.Select("id", "filename", "scan_session_id").First(&result, resultID).Error; err != nil {
+		return err
+	}
+	if result.Filename == "" {
+		return nil
+	}
+
+	// thumbnail.Clean keeps us inside the screenshot path
+	dir := database.ScreenshotPath(db, result.ScanSessionID, screenshotPath)
+	file, err := os.Open(filepath.Join(dir, filepath.FromSlash(thumbnail.Clean(result.Filename))))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return tracker.Attach(key, filepath.Base(result.Filename), file)
+}
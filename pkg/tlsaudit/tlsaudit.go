@@ -0,0 +1,151 @@
+// Package tlsaudit reports certificates that expired or are about to,
+// self-signed certificates, and deprecated TLS protocols and ciphers.
+package tlsaudit
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// DefaultDays is how many days ahead certificates are reported as expiring
+const DefaultDays = 30
+
+// deprecatedProtocols are protocol versions browsers no longer accept by
+// default, as Chrome reports them
+var deprecatedProtocols = []string{"ssl 2.0", "ssl 3.0", "tls 1.0", "tls 1.1"}
+
+// weakCiphers are fragments of cipher suite names that are broken or close
+// to it
+var weakCiphers = []string{"rc4", "3des", "des_cbc", "des-cbc", "null", "export", "md5"}
+
+// Entry is the certificate and connection of a host
+type Entry struct {
+	ResultID uint      `json:"result_id"`
+	URL      string    `json:"url"`
+	Host     string    `json:"host"`
+	Subject  string    `json:"subject_name"`
+	Issuer   string    `json:"issuer"`
+	Protocol string    `json:"protocol"`
+	Cipher   string    `json:"cipher"`
+	ValidTo  time.Time `json:"valid_to"`
+	// DaysLeft is the number of days until the certificate expires, and
+	// negative if it already expired
+	DaysLeft int `json:"days_left"`
+}
+
+// Report lists the hosts with TLS issues
+type Report struct {
+	// Expiring certificates expire within the report's days, or expired
+	Expiring    []Entry `json:"expiring"`
+	SelfSigned  []Entry `json:"self_signed"`
+	Deprecated  []Entry `json:"deprecated_protocols"`
+	WeakCiphers []Entry `json:"weak_ciphers"`
+}
+
+// Audit reports the TLS issues of results, which need their TLS loaded.
+// Hosts probed more than once are reported once per certificate.
+func Audit(results []models.Result, days int, now time.Time) *Report {
+	report := &Report{
+		Expiring:    []Entry{},
+		SelfSigned:  []Entry{},
+		Deprecated:  []Entry{},
+		WeakCiphers: []Entry{},
+	}
+
+	seen := make(map[string]bool)
+	for i := range results {
+		tls := &results[i].TLS
+		if tls.Protocol == "" && tls.SubjectName == "" {
+			continue
+		}
+
+		entry := newEntry(&results[i], now)
+		key := entry.Host + "\x00" + entry.Subject + "\x00" + entry.ValidTo.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if !entry.ValidTo.IsZero() && entry.DaysLeft <= days {
+			report.Expiring = append(report.Expiring, entry)
+		}
+		if IsSelfSigned(tls) {
+			report.SelfSigned = append(report.SelfSigned, entry)
+		}
+		if IsDeprecatedProtocol(tls.Protocol) {
+			report.Deprecated = append(report.Deprecated, entry)
+		}
+		if IsWeakCipher(tls.Cipher) {
+			report.WeakCiphers = append(report.WeakCiphers, entry)
+		}
+	}
+
+	sort.SliceStable(report.Expiring, func(i, j int) bool {
+		return report.Expiring[i].ValidTo.Before(report.Expiring[j].ValidTo)
+	})
+
+	return report
+}
+
+// IsSelfSigned checks if a certificate was issued by its own subject
+func IsSelfSigned(tls *models.TLS) bool {
+	return tls.Issuer != "" && strings.EqualFold(tls.Issuer, tls.SubjectName)
+}
+
+// IsDeprecatedProtocol checks if a protocol version is deprecated
+func IsDeprecatedProtocol(protocol string) bool {
+	protocol = strings.ToLower(strings.TrimSpace(protocol))
+	for _, deprecated := range deprecatedProtocols {
+		if protocol == deprecated {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsWeakCipher checks if a cipher suite is weak
+func IsWeakCipher(cipher string) bool {
+	cipher = strings.ToLower(cipher)
+	for _, weak := range weakCiphers {
+		if strings.Contains(cipher, weak) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newEntry returns the entry of a result
+func newEntry(result *models.Result, now time.Time) Entry {
+	target := result.FinalURL
+	if target == "" {
+		target = result.URL
+	}
+
+	var host string
+	if u, err := url.Parse(target); err == nil {
+		host = u.Host
+	}
+
+	entry := Entry{
+		ResultID: result.ID,
+		URL:      target,
+		Host:     host,
+		Subject:  result.TLS.SubjectName,
+		Issuer:   result.TLS.Issuer,
+		Protocol: result.TLS.Protocol,
+		Cipher:   result.TLS.Cipher,
+		ValidTo:  result.TLS.ValidTo,
+	}
+	if !entry.ValidTo.IsZero() {
+		entry.DaysLeft = int(math.Floor(entry.ValidTo.Sub(now).Hours() / 24))
+	}
+
+	return entry
+}
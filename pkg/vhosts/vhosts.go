@@ -0,0 +1,259 @@
+// Package vhosts brute forces virtual hosts on web servers, requesting a
+// server's address directly with candidate Host headers. Hostnames the
+// server answers differently from a hostname that can't exist are virtual
+// hosts it serves, whether or not DNS points them at it.
+package vhosts
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBodySize is the most of a response body read when probing
+const maxBodySize = 1 << 20
+
+var titleRegex = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// DefaultStatusCodes are the response codes reported as virtual hosts
+var DefaultStatusCodes = []int{200, 204, 301, 302, 307, 308, 401, 403}
+
+// Hit is a virtual host a server serves
+type Hit struct {
+	Host          string
+	URL           string // the virtual host's URL on the server's port
+	StatusCode    int
+	Protocol      string
+	ContentLength int
+	ContentType   string
+	Title         string
+	HTML          string
+	// Location is where redirects point to, with the requested host left
+	// out so that redirects to the host itself compare equal
+	Location string
+}
+
+// Prober brute forces virtual hosts on web servers
+type Prober struct {
+	// Timeout is the timeout of every request
+	Timeout time.Duration
+	// Concurrency is the number of hostnames requested at once per server
+	Concurrency int
+	// UserAgent is the user agent of every request
+	UserAgent string
+	// StatusCodes are the response codes reported as virtual hosts
+	StatusCodes []int
+
+	client *http.Client
+	log    *slog.Logger
+}
+
+// NewProber returns a new Prober
+func NewProber(logger *slog.Logger) *Prober {
+	return &Prober{
+		Timeout:     10 * time.Second,
+		Concurrency: 10,
+		StatusCodes: DefaultStatusCodes,
+		log:         logger,
+	}
+}
+
+// Probe requests base, a URL such as https://192.0.2.10:8443, with every
+// hostname as its Host header (and TLS server name), returning the
+// hostnames that are virtual hosts of the server
+func (p *Prober) Probe(base string, hosts []string) ([]Hit, error) {
+	base = strings.TrimSuffix(base, "/")
+	if p.client == nil {
+		p.client = p.newClient()
+	}
+
+	// a hostname that can't exist shows what the server answers for hosts
+	// it does not serve, and the address itself what its default site is
+	canary, err := p.fetch(base, randomHost())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach %s: %w", base, err)
+	}
+	fallback, err := p.fetch(base, "")
+	if err != nil {
+		fallback = canary
+	}
+
+	concurrency := max(p.Concurrency, 1)
+	jobs := make(chan string)
+	var (
+		hits []Hit
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+	)
+
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range jobs {
+				hit, err := p.fetch(base, host)
+				if err != nil {
+					p.log.Debug("failed to probe virtual host", "url", base, "host", host, "err", err)
+					continue
+				}
+
+				if !slices.Contains(p.StatusCodes, hit.StatusCode) || sameSite(hit, canary) || sameSite(hit, fallback) {
+					continue
+				}
+
+				mu.Lock()
+				hits = append(hits, *hit)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, host := range hosts {
+		jobs <- host
+	}
+	close(jobs)
+	wg.Wait()
+
+	slices.SortFunc(hits, func(a, b Hit) int {
+		return strings.Compare(a.Host, b.Host)
+	})
+
+	return hits, nil
+}
+
+// serverNameKey carries the TLS server name of a request to the dialer
+type serverNameKey struct{}
+
+// fetch requests base with host as its Host header, or as is if host is
+// empty
+func (p *Prober) fetch(base string, host string) (*Hit, error) {
+	req, err := http.NewRequest(http.MethodGet, base+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	if host != "" {
+		req.Host = host
+		req = req.WithContext(context.WithValue(req.Context(), serverNameKey{}, host))
+	}
+	if p.UserAgent != "" {
+		req.Header.Set("User-Agent", p.UserAgent)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	hit := &Hit{
+		Host:          host,
+		URL:           hostURL(req.URL.Scheme, host, req.URL.Port()),
+		StatusCode:    resp.StatusCode,
+		Protocol:      resp.Proto,
+		ContentLength: len(body),
+		ContentType:   resp.Header.Get("Content-Type"),
+		HTML:          string(body),
+		Location:      resp.Header.Get("Location"),
+	}
+	if host != "" {
+		hit.Location = strings.ReplaceAll(hit.Location, host, "")
+	}
+	if match := titleRegex.FindSubmatch(body); match != nil {
+		hit.Title = strings.TrimSpace(string(match[1]))
+	}
+
+	return hit, nil
+}
+
+// newClient returns an http client that does not follow redirects, and
+// sends the Host header of each request as its TLS server name too.
+// Connections are not reused, as they are bound to a server name.
+func (p *Prober) newClient() *http.Client {
+	dialer := &net.Dialer{Timeout: p.Timeout}
+
+	return &http.Client{
+		Timeout: p.Timeout,
+		Transport: &http.Transport{
+			DialContext: dialer.DialContext,
+			DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				serverName, _ := ctx.Value(serverNameKey{}).(string)
+				if serverName == "" {
+					serverName, _, _ = net.SplitHostPort(addr)
+				}
+
+				// internal hosts rarely have valid certificates
+				return tls.DialWithDialer(dialer, network, addr, &tls.Config{
+					ServerName:         serverName,
+					InsecureSkipVerify: true,
+				})
+			},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// sameSite checks if a hit looks like another response of the server, such
+// as its answer for hosts it does not serve
+func sameSite(hit *Hit, other *Hit) bool {
+	if hit.StatusCode != other.StatusCode {
+		return false
+	}
+
+	// redirects of hosts a server does not serve usually go to the same
+	// place, such as its default site
+	if other.Location != "" {
+		return hit.Location == other.Location
+	}
+
+	return hit.Title == other.Title && similarSize(hit.ContentLength, other.ContentLength)
+}
+
+// similarSize checks if two body sizes are within 10% of each other
+func similarSize(a, b int) bool {
+	if a == b {
+		return true
+	}
+
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+
+	return diff*10 <= max(a, b)
+}
+
+// hostURL returns the URL of a virtual host, leaving out default ports
+func hostURL(scheme string, host string, port string) string {
+	if port == "" || (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		return scheme + "://" + host + "/"
+	}
+
+	return scheme + "://" + net.JoinHostPort(host, port) + "/"
+}
+
+// randomHost returns a hostname that won't be served by any server
+func randomHost() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b) + ".invalid"
+}
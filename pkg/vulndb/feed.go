@@ -0,0 +1,112 @@
+package vulndb
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Feed is an offline source, loaded from NVD JSON 2.0 data feed files such
+// as nvdcve-2.0-2024.json.gz
+type Feed struct {
+	cves map[string]*CVE
+	// products indexes the CVEs by the part:vendor:product they affect
+	products map[string][]*CVE
+}
+
+// LoadFeed loads feed files. Files ending in .gz are decompressed.
+func LoadFeed(paths ...string) (*Feed, error) {
+	feed := &Feed{
+		cves:     make(map[string]*CVE),
+		products: make(map[string][]*CVE),
+	}
+
+	for _, path := range paths {
+		if err := feed.load(path); err != nil {
+			return nil, fmt.Errorf("failed to load feed %s: %w", path, err)
+		}
+	}
+
+	return feed, nil
+}
+
+// load adds the CVEs in a feed file
+func (f *Feed) load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var data nvdResponse
+	if err := json.NewDecoder(reader).Decode(&data); err != nil {
+		return err
+	}
+
+	for _, vulnerability := range data.Vulnerabilities {
+		cve := vulnerability.CVE.toCVE()
+		_, seen := f.cves[cve.ID]
+		f.cves[cve.ID] = cve
+		if seen {
+			// a newer feed replaced the CVE, which is indexed already
+			continue
+		}
+
+		indexed := make(map[string]bool)
+		for _, config := range cve.configurations {
+			for _, node := range config.Nodes {
+				for _, match := range node.CPEMatch {
+					name, _ := product(match.Criteria)
+					if name != "" && !indexed[name] {
+						indexed[name] = true
+						f.products[name] = append(f.products[name], cve)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Name returns the source name
+func (f *Feed) Name() string {
+	return "feed"
+}
+
+// Len returns the number of CVEs in the feed
+func (f *Feed) Len() int {
+	return len(f.cves)
+}
+
+// CVE returns the details of a CVE
+func (f *Feed) CVE(id string) (*CVE, error) {
+	return f.cves[strings.ToUpper(id)], nil
+}
+
+// CVEsAffecting returns the CVEs affecting a versioned CPE 2.3 name
+func (f *Feed) CVEsAffecting(cpe string) ([]*CVE, error) {
+	name, _ := product(cpe)
+
+	var affecting []*CVE
+	for _, cve := range f.products[name] {
+		if cve.Affects(cpe) {
+			affecting = append(affecting, cve)
+		}
+	}
+
+	return affecting, nil
+}
@@ -0,0 +1,277 @@
+package vulndb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// nvdResponse is a page of the NVD CVE API, which is also the format of the
+// NVD JSON 2.0 data feeds
+type nvdResponse struct {
+	ResultsPerPage  int `json:"resultsPerPage"`
+	StartIndex      int `json:"startIndex"`
+	TotalResults    int `json:"totalResults"`
+	Vulnerabilities []struct {
+		CVE nvdCVE `json:"cve"`
+	} `json:"vulnerabilities"`
+}
+
+type nvdCVE struct {
+	ID           string `json:"id"`
+	Published    string `json:"published"`
+	Descriptions []struct {
+		Lang  string `json:"lang"`
+		Value string `json:"value"`
+	} `json:"descriptions"`
+	Metrics struct {
+		CVSSV31 []nvdMetric `json:"cvssMetricV31"`
+		CVSSV30 []nvdMetric `json:"cvssMetricV30"`
+		CVSSV2  []nvdMetric `json:"cvssMetricV2"`
+	} `json:"metrics"`
+	Configurations []configuration `json:"configurations"`
+}
+
+type nvdMetric struct {
+	Type     string `json:"type"`
+	CVSSData struct {
+		BaseScore float64 `json:"baseScore"`
+	} `json:"cvssData"`
+}
+
+type configuration struct {
+	Nodes []struct {
+		Operator string     `json:"operator"`
+		Negate   bool       `json:"negate"`
+		CPEMatch []cpeMatch `json:"cpeMatch"`
+	} `json:"nodes"`
+}
+
+type cpeMatch struct {
+	Vulnerable            bool   `json:"vulnerable"`
+	Criteria              string `json:"criteria"`
+	VersionStartIncluding string `json:"versionStartIncluding"`
+	VersionStartExcluding string `json:"versionStartExcluding"`
+	VersionEndIncluding   string `json:"versionEndIncluding"`
+	VersionEndExcluding   string `json:"versionEndExcluding"`
+}
+
+// matches checks if a versioned CPE 2.3 name falls in the match
+func (m *cpeMatch) matches(cpe string) bool {
+	name, version := product(cpe)
+	criteriaName, criteriaVersion := product(m.Criteria)
+	if name == "" || name != criteriaName {
+		return false
+	}
+
+	if criteriaVersion != "*" && criteriaVersion != "-" {
+		return CompareVersions(version, criteriaVersion) == 0
+	}
+
+	if m.VersionStartIncluding != "" && CompareVersions(version, m.VersionStartIncluding) < 0 {
+		return false
+	}
+	if m.VersionStartExcluding != "" && CompareVersions(version, m.VersionStartExcluding) <= 0 {
+		return false
+	}
+	if m.VersionEndIncluding != "" && CompareVersions(version, m.VersionEndIncluding) > 0 {
+		return false
+	}
+	if m.VersionEndExcluding != "" && CompareVersions(version, m.VersionEndExcluding) >= 0 {
+		return false
+	}
+
+	return true
+}
+
+// toCVE converts an NVD CVE
+func (n *nvdCVE) toCVE() *CVE {
+	cve := &CVE{
+		ID:             n.ID,
+		configurations: n.Configurations,
+	}
+
+	for _, description := range n.Descriptions {
+		if description.Lang == "en" {
+			cve.Description = description.Value
+			break
+		}
+	}
+
+	// prefer the newest CVSS version, and the primary score of it
+	for _, metrics := range [][]nvdMetric{n.Metrics.CVSSV31, n.Metrics.CVSSV30, n.Metrics.CVSSV2} {
+		for _, metric := range metrics {
+			if cve.CVSS == 0 || metric.Type == "Primary" {
+				cve.CVSS = metric.CVSSData.BaseScore
+			}
+		}
+		if cve.CVSS > 0 {
+			break
+		}
+	}
+
+	cve.Published, _ = time.Parse("2006-01-02T15:04:05.000", n.Published)
+
+	return cve
+}
+
+// NVD is a client of the NVD CVE API. Requests are spaced out to respect the
+// API's rate limit, which is higher with an API key.
+type NVD struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	interval time.Duration
+	last     time.Time
+	// products caches the CVEs of products, by part:vendor:product
+	products map[string][]*CVE
+}
+
+// NewNVD returns a new NVD client. The API key is optional.
+func NewNVD(apiKey string) *NVD {
+	// 5 requests per 30 seconds without a key, 50 with one
+	interval := 6 * time.Second
+	if apiKey != "" {
+		interval = 600 * time.Millisecond
+	}
+
+	return &NVD{
+		apiKey:     apiKey,
+		baseURL:    "https://services.nvd.nist.gov/rest/json/cves/2.0",
+		httpClient: islazy.NewHTTPClient(60 * time.Second),
+		interval:   interval,
+		products:   make(map[string][]*CVE),
+	}
+}
+
+// Name returns the source name
+func (n *NVD) Name() string {
+	return "nvd"
+}
+
+// CVE returns the details of a CVE
+func (n *NVD) CVE(id string) (*CVE, error) {
+	query := url.Values{}
+	query.Set("cveId", strings.ToUpper(id))
+
+	cves, err := n.search(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(cves) == 0 {
+		return nil, nil
+	}
+
+	return cves[0], nil
+}
+
+// CVEsAffecting returns the CVEs affecting a versioned CPE 2.3 name. The
+// CVEs of every version of the product are fetched once, and the version is
+// matched against their configurations.
+func (n *NVD) CVEsAffecting(cpe string) ([]*CVE, error) {
+	name, _ := product(cpe)
+	if name == "" {
+		return nil, fmt.Errorf("invalid cpe name: %s", cpe)
+	}
+
+	n.mu.Lock()
+	cves, ok := n.products[name]
+	n.mu.Unlock()
+
+	if !ok {
+		query := url.Values{}
+		query.Set("virtualMatchString", "cpe:2.3:"+name)
+
+		var err error
+		cves, err = n.search(query)
+		if err != nil {
+			return nil, err
+		}
+
+		n.mu.Lock()
+		n.products[name] = cves
+		n.mu.Unlock()
+	}
+
+	var affecting []*CVE
+	for _, cve := range cves {
+		if cve.Affects(cpe) {
+			affecting = append(affecting, cve)
+		}
+	}
+
+	return affecting, nil
+}
+
+// search fetches every page of a CVE search
+func (n *NVD) search(query url.Values) ([]*CVE, error) {
+	var cves []*CVE
+	for start := 0; ; {
+		query.Set("startIndex", fmt.Sprint(start))
+
+		response, err := n.get(query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, vulnerability := range response.Vulnerabilities {
+			cves = append(cves, vulnerability.CVE.toCVE())
+		}
+
+		start += len(response.Vulnerabilities)
+		if len(response.Vulnerabilities) == 0 || start >= response.TotalResults {
+			break
+		}
+	}
+
+	return cves, nil
+}
+
+// get fetches a page of CVEs, waiting for the rate limit
+func (n *NVD) get(query url.Values) (*nvdResponse, error) {
+	n.mu.Lock()
+	if wait := n.interval - time.Since(n.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	n.last = time.Now()
+	n.mu.Unlock()
+
+	req, err := http.NewRequest(http.MethodGet, n.baseURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if n.apiKey != "" {
+		req.Header.Set("apiKey", n.apiKey)
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query NVD API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("NVD API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response nvdResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse NVD response: %w", err)
+	}
+
+	return &response, nil
+}
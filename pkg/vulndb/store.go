@@ -0,0 +1,190 @@
+package vulndb
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// FindingSource is the source of findings saved by a Correlator
+const FindingSource = "vulndb"
+
+// Correlator correlates stored technologies and IP vulnerabilities with CVE
+// details, saving what it finds as findings
+type Correlator struct {
+	Source Source
+
+	db  *gorm.DB
+	log *slog.Logger
+
+	// cves caches CVE lookups by id, including unknown ids
+	cves map[string]*CVE
+	// affecting caches the CVEs affecting a versioned CPE name
+	affecting map[string][]*CVE
+}
+
+// NewCorrelator returns a new Correlator
+func NewCorrelator(logger *slog.Logger, db *gorm.DB, source Source) *Correlator {
+	return &Correlator{
+		Source:    source,
+		db:        db,
+		log:       logger,
+		cves:      make(map[string]*CVE),
+		affecting: make(map[string][]*CVE),
+	}
+}
+
+// Technologies correlates the versioned technologies of the results of a
+// scan session, or of all results if scanSessionID is nil. It returns the
+// number of findings saved.
+func (c *Correlator) Technologies(scanSessionID *uint) (int, error) {
+	var rows []struct {
+		ResultID      uint
+		Name          string
+		Version       string
+		CPE           string
+		IPAddress     string
+		ScanSessionID *uint
+	}
+
+	query := c.db.Model(&models.Technology{}).
+		Select("technologies.result_id, technologies.name, technologies.version, technologies.cpe, results.ip_address, results.scan_session_id").
+		Joins("JOIN results ON results.id = technologies.result_id").
+		Where("technologies.cpe <> '' AND technologies.version <> ''")
+	if scanSessionID != nil {
+		query = query.Where("results.scan_session_id = ?", *scanSessionID)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return 0, fmt.Errorf("failed to get technologies: %w", err)
+	}
+
+	var saved int
+	for _, row := range rows {
+		if !HasVersion(row.CPE) {
+			continue
+		}
+
+		cves, ok := c.affecting[row.CPE]
+		if !ok {
+			var err error
+			cves, err = c.Source.CVEsAffecting(row.CPE)
+			if err != nil {
+				c.log.Warn("failed to get cves affecting technology", "cpe", row.CPE, "err", err)
+				continue
+			}
+			c.affecting[row.CPE] = cves
+			c.log.Debug("correlated technology", "cpe", row.CPE, "cves", len(cves))
+		}
+
+		for _, cve := range cves {
+			resultID := row.ResultID
+			if err := c.save(&models.Finding{
+				ResultID:  &resultID,
+				IPAddress: row.IPAddress,
+				Title:     fmt.Sprintf("%s in %s %s", cve.ID, row.Name, row.Version),
+				Severity:  cve.Severity(),
+				Description: fmt.Sprintf("%s %s is affected by %s. %s",
+					row.Name, row.Version, cve.ID, cve.Description),
+				CVE:           cve.ID,
+				CVSS:          cve.CVSS,
+				ScanSessionID: row.ScanSessionID,
+			}); err != nil {
+				return saved, err
+			}
+			saved++
+		}
+	}
+
+	return saved, nil
+}
+
+// IPVulns adds CVE details to the vulnerabilities Shodan reported for the IP
+// addresses of a scan session, or of all IP addresses if scanSessionID is
+// nil. It returns the number of findings saved.
+func (c *Correlator) IPVulns(scanSessionID *uint) (int, error) {
+	query := c.db.Select("ip_address", "vulns", "scan_session_id").Where("vulns <> '' AND vulns <> '[]'")
+	if scanSessionID != nil {
+		query = query.Where("scan_session_id = ?", *scanSessionID)
+	}
+
+	var infos []models.IPInfo
+	if err := query.Find(&infos).Error; err != nil {
+		return 0, fmt.Errorf("failed to get ip vulnerabilities: %w", err)
+	}
+
+	var saved int
+	for _, info := range infos {
+		ids, err := info.GetVulns()
+		if err != nil {
+			c.log.Warn("failed to parse ip vulnerabilities", "ip", info.IPAddress, "err", err)
+			continue
+		}
+
+		for _, id := range ids {
+			cve, ok := c.cves[id]
+			if !ok {
+				cve, err = c.Source.CVE(id)
+				if err != nil {
+					c.log.Warn("failed to get cve", "cve", id, "err", err)
+					continue
+				}
+				c.cves[id] = cve
+			}
+
+			finding := &models.Finding{
+				IPAddress:     info.IPAddress,
+				Title:         fmt.Sprintf("%s on %s", id, info.IPAddress),
+				Severity:      "info",
+				Description:   fmt.Sprintf("Shodan reports %s on %s. Its details are unknown to %s.", id, info.IPAddress, c.Source.Name()),
+				CVE:           id,
+				ScanSessionID: info.ScanSessionID,
+			}
+			if cve != nil {
+				finding.Severity = cve.Severity()
+				finding.Description = fmt.Sprintf("Shodan reports %s on %s. %s", id, info.IPAddress, cve.Description)
+				finding.CVSS = cve.CVSS
+			}
+
+			if err := c.save(finding); err != nil {
+				return saved, err
+			}
+			saved++
+		}
+	}
+
+	return saved, nil
+}
+
+// save stores a finding, updating the CVE details of the finding if the
+// same CVE was saved for the same result or IP address before
+func (c *Correlator) save(finding *models.Finding) error {
+	finding.Source = FindingSource
+
+	query := c.db.Where("source = ? AND cve = ? AND ip_address = ?", FindingSource, finding.CVE, finding.IPAddress)
+	if finding.ResultID != nil {
+		query = query.Where("result_id = ?", *finding.ResultID)
+	} else {
+		query = query.Where("result_id IS NULL")
+	}
+
+	var existing models.Finding
+	err := query.First(&existing).Error
+	if err == nil {
+		// a struct update, so that the description is encrypted in
+		// encrypted databases
+		return c.db.Model(&existing).Select("title", "severity", "description", "cvss").Updates(&models.Finding{
+			Title:       finding.Title,
+			Severity:    finding.Severity,
+			Description: finding.Description,
+			CVSS:        finding.CVSS,
+		}).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return c.db.Create(finding).Error
+}
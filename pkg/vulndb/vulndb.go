@@ -0,0 +1,113 @@
+// Package vulndb correlates detected software versions and reported CVE
+// identifiers with CVE details, such as their descriptions and CVSS scores.
+package vulndb
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CVE is a known vulnerability
+type CVE struct {
+	ID          string
+	Description string
+	// CVSS is the base score of the newest CVSS version scored, or 0 if the
+	// CVE has not been scored yet
+	CVSS      float64
+	Published time.Time
+
+	configurations []configuration
+}
+
+// Severity returns the severity of the CVE, as a finding severity
+func (c *CVE) Severity() string {
+	switch {
+	case c.CVSS >= 9:
+		return "critical"
+	case c.CVSS >= 7:
+		return "high"
+	case c.CVSS >= 4:
+		return "medium"
+	case c.CVSS > 0:
+		return "low"
+	default:
+		return "info"
+	}
+}
+
+// Affects checks if a versioned CPE 2.3 name, like
+// cpe:2.3:a:nginx:nginx:1.18.0:*:*:*:*:*:*:*, is vulnerable to the CVE
+func (c *CVE) Affects(cpe string) bool {
+	for _, config := range c.configurations {
+		for _, node := range config.Nodes {
+			if node.Negate {
+				continue
+			}
+			for _, match := range node.CPEMatch {
+				if match.Vulnerable && match.matches(cpe) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Source looks up CVE details
+type Source interface {
+	// Name returns the name of the source
+	Name() string
+	// CVE returns the details of a CVE, or nil if the source does not know it
+	CVE(id string) (*CVE, error)
+	// CVEsAffecting returns the CVEs affecting a versioned CPE 2.3 name
+	CVEsAffecting(cpe string) ([]*CVE, error)
+}
+
+// product returns the part, vendor and product of a CPE 2.3 name, e.g.
+// a:nginx:nginx, and its version
+func product(cpe string) (string, string) {
+	parts := strings.Split(cpe, ":")
+	if len(parts) < 6 || parts[0] != "cpe" || parts[1] != "2.3" {
+		return "", ""
+	}
+
+	return strings.Join(parts[2:5], ":"), parts[5]
+}
+
+// HasVersion checks if a CPE 2.3 name names a specific version, as only
+// those can be correlated without drowning in false positives
+func HasVersion(cpe string) bool {
+	name, version := product(cpe)
+	return name != "" && version != "" && version != "*" && version != "-"
+}
+
+// CompareVersions compares dotted versions like 1.25.3 part by part,
+// numerically where both parts are numbers. It returns a positive number if
+// a is newer than b, a negative one if it is older, and 0 if they are equal.
+func CompareVersions(a string, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		var aPart, bPart string
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+
+		aNum, aErr := strconv.Atoi(aPart)
+		bNum, bErr := strconv.Atoi(bPart)
+		switch {
+		case aErr == nil && bErr == nil:
+			if aNum != bNum {
+				return aNum - bNum
+			}
+		case aPart != bPart:
+			return strings.Compare(aPart, bPart)
+		}
+	}
+
+	return 0
+}
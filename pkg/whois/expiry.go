@@ -0,0 +1,54 @@
+package whois
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// DefaultDays is how many days ahead domains are reported as expiring
+const DefaultDays = 30
+
+// Expiry is a domain registration that is about to expire
+type Expiry struct {
+	Domain     string    `json:"domain"`
+	Registrar  string    `json:"registrar"`
+	ExpiryDate time.Time `json:"expiry_date"`
+	// DaysLeft is the number of days until the domain expires, and negative
+	// if it already expired
+	DaysLeft      int   `json:"days_left"`
+	ScanSessionID *uint `json:"scan_session_id,omitempty"`
+}
+
+// Expiring returns the registrations that expire within days of now, or
+// already expired, soonest first. Registrations without an expiry date are
+// skipped.
+func Expiring(registrations []models.DomainRegistration, days int, now time.Time) []Expiry {
+	expiring := []Expiry{}
+	for _, registration := range registrations {
+		if registration.ExpiryDate == nil {
+			continue
+		}
+
+		daysLeft := int(math.Floor(registration.ExpiryDate.Sub(now).Hours() / 24))
+		if daysLeft > days {
+			continue
+		}
+
+		expiring = append(expiring, Expiry{
+			Domain:        registration.Domain,
+			Registrar:     registration.Registrar,
+			ExpiryDate:    *registration.ExpiryDate,
+			DaysLeft:      daysLeft,
+			ScanSessionID: registration.ScanSessionID,
+		})
+	}
+
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].ExpiryDate.Before(expiring[j].ExpiryDate)
+	})
+
+	return expiring
+}
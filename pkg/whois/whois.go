@@ -0,0 +1,277 @@
+// Package whois looks up the registration of domains and the ownership of
+// IP netblocks using RDAP, the JSON successor of WHOIS.
+package whois
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// ErrNotFound is returned when the registry has no record of a query
+var ErrNotFound = errors.New("no rdap record found")
+
+// maxBodySize is the most of a response that is read
+const maxBodySize = 1 << 20
+
+// rdapEvent is a dated event of an RDAP object
+type rdapEvent struct {
+	Action string `json:"eventAction"`
+	Date   string `json:"eventDate"`
+}
+
+// rdapEntity is a contact of an RDAP object, such as its registrar
+type rdapEntity struct {
+	Roles      []string      `json:"roles"`
+	VCardArray []interface{} `json:"vcardArray"`
+	Entities   []rdapEntity  `json:"entities"`
+	Handle     string        `json:"handle"`
+}
+
+// rdapDomain is an RDAP domain response
+type rdapDomain struct {
+	LDHName     string       `json:"ldhName"`
+	Status      []string     `json:"status"`
+	Events      []rdapEvent  `json:"events"`
+	Entities    []rdapEntity `json:"entities"`
+	Nameservers []struct {
+		LDHName string `json:"ldhName"`
+	} `json:"nameservers"`
+}
+
+// rdapNetwork is an RDAP ip network response
+type rdapNetwork struct {
+	Handle       string       `json:"handle"`
+	Name         string       `json:"name"`
+	Type         string       `json:"type"`
+	Country      string       `json:"country"`
+	StartAddress string       `json:"startAddress"`
+	EndAddress   string       `json:"endAddress"`
+	Entities     []rdapEntity `json:"entities"`
+	CIDRs        []struct {
+		V4Prefix string `json:"v4prefix"`
+		V6Prefix string `json:"v6prefix"`
+		Length   int    `json:"length"`
+	} `json:"cidr0_cidrs"`
+}
+
+// Client is an RDAP client
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient returns a new RDAP client. Queries go through the rdap.org
+// bootstrap service, which redirects them to the authoritative registry.
+func NewClient(timeout time.Duration) *Client {
+	return &Client{
+		baseURL:    "https://rdap.org",
+		httpClient: islazy.NewHTTPClient(timeout),
+	}
+}
+
+// Domain returns the registration of a domain
+func (c *Client) Domain(domain string) (*models.DomainRegistration, error) {
+	var response rdapDomain
+	if err := c.query("domain", domain, &response); err != nil {
+		return nil, err
+	}
+
+	registration := &models.DomainRegistration{
+		Domain:      strings.ToLower(domain),
+		Registrar:   entityName(response.Entities, "registrar"),
+		CreatedDate: eventDate(response.Events, "registration"),
+		ExpiryDate:  eventDate(response.Events, "expiration"),
+		UpdatedDate: eventDate(response.Events, "last changed"),
+		CheckedAt:   time.Now(),
+	}
+
+	var nameservers []string
+	for _, ns := range response.Nameservers {
+		if ns.LDHName != "" {
+			nameservers = append(nameservers, strings.ToLower(strings.TrimSuffix(ns.LDHName, ".")))
+		}
+	}
+
+	var err error
+	if registration.Status, err = jsonList(response.Status); err != nil {
+		return nil, err
+	}
+	if registration.Nameservers, err = jsonList(nameservers); err != nil {
+		return nil, err
+	}
+
+	return registration, nil
+}
+
+// Network returns the netblock an IP address is in
+func (c *Client) Network(ip string) (*models.Netblock, error) {
+	var response rdapNetwork
+	if err := c.query("ip", ip, &response); err != nil {
+		return nil, err
+	}
+	if response.Handle == "" {
+		return nil, ErrNotFound
+	}
+
+	netblock := &models.Netblock{
+		Handle:       response.Handle,
+		Name:         response.Name,
+		StartAddress: response.StartAddress,
+		EndAddress:   response.EndAddress,
+		Country:      response.Country,
+		Type:         response.Type,
+		CheckedAt:    time.Now(),
+	}
+
+	// the registrant owns the block, falling back to whoever the registry
+	// lists for abuse
+	netblock.Owner = entityName(response.Entities, "registrant")
+	if netblock.Owner == "" {
+		netblock.Owner = entityName(response.Entities, "abuse")
+	}
+
+	var cidrs []string
+	for _, cidr := range response.CIDRs {
+		prefix := cidr.V4Prefix
+		if prefix == "" {
+			prefix = cidr.V6Prefix
+		}
+		if prefix != "" {
+			cidrs = append(cidrs, fmt.Sprintf("%s/%d", prefix, cidr.Length))
+		}
+	}
+
+	var err error
+	if netblock.CIDRs, err = jsonList(cidrs); err != nil {
+		return nil, err
+	}
+
+	return netblock, nil
+}
+
+// query queries an RDAP object, decoding the response into v
+func (c *Client) query(kind string, value string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/"+kind+"/"+url.PathEscape(value), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	req.Header.Set("User-Agent", "gowitness")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query RDAP: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return fmt.Errorf("failed to read RDAP response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("RDAP error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse RDAP response: %w", err)
+	}
+
+	return nil
+}
+
+// jsonList encodes a list for a JSON string array field, leaving the field
+// empty if the list is
+func jsonList(list []string) (string, error) {
+	if len(list) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// eventDate returns the date of an event, if the object has it
+func eventDate(events []rdapEvent, action string) *time.Time {
+	for _, event := range events {
+		if !strings.EqualFold(event.Action, action) {
+			continue
+		}
+
+		date, err := time.Parse(time.RFC3339, event.Date)
+		if err != nil {
+			return nil
+		}
+		return &date
+	}
+
+	return nil
+}
+
+// entityName returns the name of the first entity with a role, searching
+// nested entities too
+func entityName(entities []rdapEntity, role string) string {
+	for _, entity := range entities {
+		for _, r := range entity.Roles {
+			if !strings.EqualFold(r, role) {
+				continue
+			}
+			if name := vcardName(entity.VCardArray); name != "" {
+				return name
+			}
+			if entity.Handle != "" {
+				return entity.Handle
+			}
+		}
+	}
+
+	for _, entity := range entities {
+		if name := entityName(entity.Entities, role); name != "" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// vcardName returns the formatted name of a jCard, which is an array like
+// ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Name"]]]
+func vcardName(vcard []interface{}) string {
+	if len(vcard) < 2 {
+		return ""
+	}
+	properties, ok := vcard[1].([]interface{})
+	if !ok {
+		return ""
+	}
+
+	for _, p := range properties {
+		property, ok := p.([]interface{})
+		if !ok || len(property) < 4 {
+			continue
+		}
+		if name, _ := property[0].(string); name != "fn" {
+			continue
+		}
+		if value, ok := property[3].(string); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/sensepost/gowitness/pkg/database"
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"github.com/sensepost/gowitness/pkg/scripting"
 	"gorm.io/gorm"
 )
 
@@ -14,7 +16,12 @@ var hammingThreshold = 10
 
 // DbWriter is a Database writer
 type DbWriter struct {
-	URI           string
+	URI string
+	// Plugins enrich results once they are saved
+	Plugins *plugins.Manager
+	// Scripts run checks against results once they are saved
+	Scripts *scripting.Runner
+
 	conn          *gorm.DB
 	mutex         sync.Mutex
 	hammingGroups []islazy.HammingGroup
@@ -37,6 +44,41 @@ func NewDbWriter(uri string, debug bool) (*DbWriter, error) {
 
 // Write results to the database
 func (dw *DbWriter) Write(result *models.Result) error {
+	if err := dw.create(result); err != nil {
+		return err
+	}
+
+	// plugins and scripts run outside of the lock, as they may be slow
+	if dw.Plugins != nil {
+		dw.Plugins.EnrichResult(dw.conn, result)
+	}
+	if dw.Scripts != nil {
+		dw.Scripts.RunResult(dw.conn, result)
+	}
+
+	return nil
+}
+
+// SaveDomains records hostnames found by a source, such as the subject
+// alternative names of a certificate, as discovered domains
+func (dw *DbWriter) SaveDomains(hostnames []string, source string) error {
+	dw.mutex.Lock()
+	defer dw.mutex.Unlock()
+
+	for _, hostname := range hostnames {
+		if err := database.SaveDomain(dw.conn, &models.Domain{
+			Name:   hostname,
+			Source: source,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// create saves a result
+func (dw *DbWriter) create(result *models.Result) error {
 	dw.mutex.Lock()
 	defer dw.mutex.Unlock()
 
@@ -50,6 +92,14 @@ func (dw *DbWriter) Write(result *models.Result) error {
 		log.Debug("could not get group id for perception hash", "hash", result.PerceptionHash)
 	}
 
+	// findings of the result, such as insecure cookies, belong to its
+	// session too
+	for i := range result.Findings {
+		if result.Findings[i].ScanSessionID == nil {
+			result.Findings[i].ScanSessionID = result.ScanSessionID
+		}
+	}
+
 	return dw.conn.Create(result).Error
 }
 
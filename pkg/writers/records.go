@@ -0,0 +1,66 @@
+package writers
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sensepost/gowitness/internal/islazy"
+)
+
+// Record is a JSON line written by a RecordWriter
+type Record struct {
+	// Record is the kind of record, e.g. ip_info or ip_port
+	Record string    `json:"record"`
+	Source string    `json:"source,omitempty"`
+	Time   time.Time `json:"time"`
+	Data   any       `json:"data"`
+}
+
+// RecordWriter is a JSON lines writer for records other than results, such
+// as the IP information and open ports found by the shodan and naabu scans.
+// It is safe for concurrent use.
+type RecordWriter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecordWriter returns a new record writer, appending to destination
+func NewRecordWriter(destination string) (*RecordWriter, error) {
+	dst, err := islazy.CreateFileWithDir(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(dst, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecordWriter{file: file}, nil
+}
+
+// Write writes data as a record of a kind, found by source
+func (rw *RecordWriter) Write(record string, source string, data any) error {
+	j, err := json.Marshal(&Record{
+		Record: record,
+		Source: source,
+		Time:   time.Now(),
+		Data:   data,
+	})
+	if err != nil {
+		return err
+	}
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	_, err = rw.file.Write(append(j, '\n'))
+	return err
+}
+
+// Close closes the file records are written to
+func (rw *RecordWriter) Close() error {
+	return rw.file.Close()
+}
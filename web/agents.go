@@ -0,0 +1,30 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/web/api"
+)
+
+// agentAuthMiddleware checks that agent requests carry the agent token as a
+// bearer token, and name the agent
+func (s *Server) agentAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.AgentToken)) != 1 {
+			log.Warn("rejected agent request with a missing or invalid token", "remote", r.RemoteAddr, "path", r.URL.Path)
+			http.Error(w, "Invalid agent token", http.StatusUnauthorized)
+			return
+		}
+
+		if r.Header.Get(api.AgentHeader) == "" {
+			http.Error(w, "Missing "+api.AgentHeader+" header", http.StatusBadRequest)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
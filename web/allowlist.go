@@ -0,0 +1,87 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/log"
+)
+
+// ParseAllowedIPs parses a list of CIDRs and/or bare IP addresses into
+// prefixes that can be used as the server's IP allowlist.
+func ParseAllowedIPs(entries []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowed cidr %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed ip %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// ipAllowed checks if a remote address (as found in http.Request.RemoteAddr)
+// falls within any of the allowed prefixes
+func ipAllowed(remoteAddr string, allowed []netip.Prefix) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowedIPsMiddleware rejects requests from addresses outside of the
+// configured allowlist.
+//
+// This needs to run before middleware.RealIP, so that the decision is made
+// on the address of the connecting peer and not on spoofable headers. When
+// running behind a reverse proxy, add the proxy's address to the allowlist.
+func (s *Server) allowedIPsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.AllowedIPs) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !ipAllowed(r.RemoteAddr, s.AllowedIPs) {
+			log.Warn("rejected request from address outside the allowlist", "remote", r.RemoteAddr, "path", r.URL.Path)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
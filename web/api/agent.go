@@ -0,0 +1,423 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/corona10/goimagehash"
+	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/pkg/fallback"
+	"github.com/sensepost/gowitness/pkg/jobs"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/runner"
+	"github.com/sensepost/gowitness/pkg/thumbnail"
+	"github.com/sensepost/gowitness/pkg/writers"
+)
+
+// AgentHeader is the header agents send their name in
+const AgentHeader = "X-Gowitness-Agent"
+
+// agentMaxBody is the largest request body an agent may send, which is
+// mostly a screenshot
+const agentMaxBody = 64 << 20
+
+type agentClaimRequest struct {
+	// Kinds are the job kinds the agent runs
+	Kinds []string `json:"kinds"`
+}
+
+type agentJobResponse struct {
+	ID       uint            `json:"id"`
+	Kind     string          `json:"kind"`
+	Attempts int             `json:"attempts"`
+	Payload  json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+type agentFailureRequest struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+type agentIPInfoRequest struct {
+	IPAPI *fallback.IPAPIResponse `json:"ip_api"`
+	Ports []int                   `json:"ports"`
+}
+
+type agentFinishRequest struct {
+	// Error fails the attempt if set
+	Error string `json:"error"`
+}
+
+// AgentClaimHandler hands the next queued job to an agent
+//
+//	@Summary		Claim a job
+//	@Description	Claims the next queued job of one of the kinds an agent runs. The agent must send heartbeats while it runs the job, report its results and finish it. Authenticated with the agent token.
+//	@Tags			Agents
+//	@Accept			json
+//	@Produce		json
+//	@Param			query	body		agentClaimRequest	true	"The job kinds the agent runs"
+//	@Success		200		{object}	agentJobResponse
+//	@Success		204		"No job is queued"
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/agent/claim [post]
+func (h *ApiHandler) AgentClaimHandler(w http.ResponseWriter, r *http.Request) {
+	var request agentClaimRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+
+	agent := r.Header.Get(AgentHeader)
+	if agent == "" || agent == jobs.LocalWorker {
+		writeError(w, "Invalid agent name", http.StatusBadRequest)
+		return
+	}
+
+	model, err := h.Jobs.Claim(agent, request.Kinds)
+	if err != nil {
+		log.Error("failed to claim job for agent", "agent", agent, "err", err)
+		writeError(w, "Error claiming job", http.StatusInternalServerError)
+		return
+	}
+	if model == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	log.Info("agent claimed job", "agent", agent, "id", model.ID, "kind", model.Kind, "attempt", model.Attempts)
+
+	// the agent reports progress, but the server knows how much there is
+	job, err := h.Jobs.Remote(model.ID, agent)
+	if err != nil {
+		log.Error("failed to get claimed job", "agent", agent, "id", model.ID, "err", err)
+		writeError(w, "Error claiming job", http.StatusInternalServerError)
+		return
+	}
+	job.SetTotal(1)
+	if model.Kind == jobs.KindProbe {
+		var payload jobs.Probe
+		if err := job.Decode(&payload); err == nil {
+			job.SetTotal(len(payload.URLs))
+		}
+	}
+
+	jsonData, err := json.Marshal(agentJobResponse{
+		ID:       model.ID,
+		Kind:     model.Kind,
+		Attempts: model.Attempts,
+		Payload:  json.RawMessage(model.Payload),
+	})
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// AgentHeartbeatHandler records that an agent is still running a job
+//
+//	@Summary		Job heartbeat
+//	@Description	Records that an agent is still running a job. Jobs of agents that stop sending heartbeats are failed. Authenticated with the agent token.
+//	@Tags			Agents
+//	@Param			id	path	int	true	"The job ID."
+//	@Success		204
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
+//	@Router			/agent/jobs/{id}/heartbeat [post]
+func (h *ApiHandler) AgentHeartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.agentJob(w, r); !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AgentResultHandler saves a result an agent probed
+//
+//	@Summary		Upload a result
+//	@Description	Saves a result of a job an agent runs. The screenshot is sent base64 encoded in the screenshot field, and is saved to the screenshot path of the server. Authenticated with the agent token.
+//	@Tags			Agents
+//	@Accept			json
+//	@Param			id		path	int				true	"The job ID."
+//	@Param			query	body	models.Result	true	"The result, with a base64 encoded screenshot"
+//	@Success		204
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
+//	@Router			/agent/jobs/{id}/results [post]
+func (h *ApiHandler) AgentResultHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.agentJob(w, r)
+	if !ok {
+		return
+	}
+
+	var result models.Result
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, agentMaxBody)).Decode(&result); err != nil {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+	if result.URL == "" {
+		writeError(w, "No URL provided", http.StatusBadRequest)
+		return
+	}
+
+	// the options the job was queued with say how screenshots are stored
+	var options runner.Options
+	switch job.Kind {
+	case jobs.KindProbe:
+		var payload jobs.Probe
+		if err := job.Decode(&payload); err != nil {
+			writeError(w, "Error decoding job", http.StatusInternalServerError)
+			return
+		}
+		options = payload.Options
+	case jobs.KindRetake:
+		var payload jobs.Retake
+		if err := job.Decode(&payload); err != nil {
+			writeError(w, "Error decoding job", http.StatusInternalServerError)
+			return
+		}
+		options = payload.Options
+
+		// retakes are only ever of the result they were queued for
+		result.PreviousResultID = &payload.ResultID
+		result.ScanSessionID = payload.ScanSessionID
+	default:
+		writeError(w, "Job does not take results", http.StatusBadRequest)
+		return
+	}
+
+	result.ID = 0
+	if err := saveAgentScreenshot(&options, &result); err != nil {
+		log.Error("failed to save agent screenshot", "id", job.ID, "url", result.URL, "err", err)
+		writeError(w, "Error saving screenshot", http.StatusBadRequest)
+		return
+	}
+
+	writer, err := h.agentWriter()
+	if err != nil {
+		log.Error("failed to connect to db for writer", "err", err)
+		writeError(w, "Error saving result", http.StatusInternalServerError)
+		return
+	}
+	if err := writer.Write(&result); err != nil {
+		log.Error("failed to save agent result", "id", job.ID, "url", result.URL, "err", err)
+		job.Fail(result.URL, err)
+		writeError(w, "Error saving result", http.StatusInternalServerError)
+		return
+	}
+	job.Complete()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AgentFailureHandler records a URL of a job that an agent failed to probe
+//
+//	@Summary		Report a failure
+//	@Description	Records an item of a job, such as a URL, that an agent failed to probe. Authenticated with the agent token.
+//	@Tags			Agents
+//	@Accept			json
+//	@Param			id		path	int					true	"The job ID."
+//	@Param			query	body	agentFailureRequest	true	"The failure"
+//	@Success		204
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Router			/agent/jobs/{id}/failures [post]
+func (h *ApiHandler) AgentFailureHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.agentJob(w, r)
+	if !ok {
+		return
+	}
+
+	var request agentFailureRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+
+	job.Fail(request.URL, errors.New(request.Error))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AgentIPInfoHandler saves fallback information an agent gathered for an IP
+// address
+//
+//	@Summary		Upload IP information
+//	@Description	Saves the IP-API information and open ports an agent gathered for the IP address of an ip-enrich job. Authenticated with the agent token.
+//	@Tags			Agents
+//	@Accept			json
+//	@Param			id		path	int					true	"The job ID."
+//	@Param			query	body	agentIPInfoRequest	true	"The IP information"
+//	@Success		204
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
+//	@Router			/agent/jobs/{id}/ip-info [post]
+func (h *ApiHandler) AgentIPInfoHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.agentJob(w, r)
+	if !ok {
+		return
+	}
+	if job.Kind != jobs.KindIPEnrich {
+		writeError(w, "Job does not take IP information", http.StatusBadRequest)
+		return
+	}
+
+	var request agentIPInfoRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.IPAPI == nil {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+
+	var payload jobs.IPEnrich
+	if err := job.Decode(&payload); err != nil {
+		writeError(w, "Error decoding job", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.storeFallbackIPData(payload.IPAddress, request.IPAPI, request.Ports); err != nil {
+		log.Error("failed to store agent IP information", "ip", payload.IPAddress, "err", err)
+		writeError(w, "Error saving IP information", http.StatusInternalServerError)
+		return
+	}
+	job.Complete()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AgentFinishHandler records the outcome of a job an agent ran
+//
+//	@Summary		Finish a job
+//	@Description	Completes a job an agent ran, or fails the attempt if an error is set. Failed attempts are retried if the job has attempts left. Authenticated with the agent token.
+//	@Tags			Agents
+//	@Accept			json
+//	@Param			id		path	int					true	"The job ID."
+//	@Param			query	body	agentFinishRequest	true	"The outcome"
+//	@Success		204
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Router			/agent/jobs/{id}/finish [post]
+func (h *ApiHandler) AgentFinishHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.agentJob(w, r)
+	if !ok {
+		return
+	}
+
+	var request agentFinishRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if request.Error != "" {
+		err = errors.New(request.Error)
+	}
+	job.Finish(err)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// agentJob gets the job of an agent request, writing an error if it is not
+// running for the agent. Getting it counts as a heartbeat.
+func (h *ApiHandler) agentJob(w http.ResponseWriter, r *http.Request) (*jobs.Job, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		writeError(w, "Invalid job id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	job, err := h.Jobs.Remote(uint(id), r.Header.Get(AgentHeader))
+	if err != nil {
+		if errors.Is(err, jobs.ErrNotClaimed) {
+			writeError(w, "Job is not running for this agent", http.StatusConflict)
+			return nil, false
+		}
+		log.Error("failed to get agent job", "id", id, "err", err)
+		writeError(w, "Error retrieving job", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return job, true
+}
+
+// agentWriter returns the writer agent results are saved with
+func (h *ApiHandler) agentWriter() (*writers.DbWriter, error) {
+	h.agentMu.Lock()
+	defer h.agentMu.Unlock()
+
+	if h.agentDbWriter == nil {
+		writer, err := writers.NewDbWriter(h.DbURI, false)
+		if err != nil {
+			return nil, err
+		}
+		h.agentDbWriter = writer
+	}
+
+	return h.agentDbWriter, nil
+}
+
+// saveAgentScreenshot saves the base64 encoded screenshot of a result an
+// agent probed, and its thumbnail, like the driver would have on the server
+func saveAgentScreenshot(options *runner.Options, result *models.Result) error {
+	if result.Failed || result.Screenshot == "" {
+		result.Screenshot = ""
+		return nil
+	}
+
+	img, err := base64.StdEncoding.DecodeString(result.Screenshot)
+	if err != nil {
+		return fmt.Errorf("failed to decode screenshot: %w", err)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return fmt.Errorf("failed to decode screenshot image: %w", err)
+	}
+
+	if !options.Scan.ScreenshotToWriter {
+		result.Screenshot = ""
+	}
+	if options.Scan.ScreenshotSkipSave {
+		return nil
+	}
+
+	result.Filename = runner.ScreenshotFilename(options.Scan.ScreenshotTemplate,
+		result.URL, options.Scan.ScreenshotFormat, time.Now())
+	file := filepath.Join(options.Scan.ScreenshotPath, filepath.FromSlash(result.Filename))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("could not create screenshot directory: %w", err)
+	}
+	if err := os.WriteFile(file, img, os.FileMode(0664)); err != nil {
+		return fmt.Errorf("could not write screenshot to disk: %w", err)
+	}
+
+	if result.PerceptionHash == "" {
+		if hash, err := goimagehash.PerceptionHash(decoded); err == nil {
+			result.PerceptionHash = hash.ToString()
+		}
+	}
+
+	// thumbnails are a nice to have, so failing to write one is not fatal
+	if options.Scan.ThumbnailWidth > 0 {
+		result.Thumbnail, err = thumbnail.Write(options.Scan.ScreenshotPath,
+			result.Filename, decoded, options.Scan.ThumbnailWidth)
+		if err != nil {
+			log.Warn("could not write screenshot thumbnail", "err", err)
+		}
+	}
+
+	return nil
+}
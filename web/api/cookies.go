@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/cookies"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// insecureCookie is a session cookie of a result that is missing the
+// Secure or HttpOnly flag
+type insecureCookie struct {
+	ResultID uint   `json:"result_id"`
+	URL      string `json:"url"`
+	cookies.Issue
+}
+
+// InsecureCookiesHandler lists session cookies missing security flags
+//
+//	@Summary		List insecure session cookies
+//	@Description	Lists the cookies that look like they hold a session, set by in scope hosts without the Secure or HttpOnly flag. Hosts are in scope if they are under an apex domain of their result's scan session, or if the session has none.
+//	@Tags			Results
+//	@Produce		json
+//	@Param			session		query	int		false	"Only list cookies of results from this scan session."
+//	@Param			severity	query	string	false	"A comma seperated list of severities to filter by, e.g. medium."
+//	@Success		200			{array}		insecureCookie
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		500			{object}	ErrorResponse
+//	@Router			/cookies/insecure [get]
+func (h *ApiHandler) InsecureCookiesHandler(w http.ResponseWriter, r *http.Request) {
+	session, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var severities []string
+	if severity := r.URL.Query().Get("severity"); severity != "" {
+		severities = strings.Split(strings.ToLower(severity), ",")
+	}
+
+	var sessions []models.ScanSession
+	sessionQuery := h.DB.Preload("ApexDomains")
+	if session != nil {
+		sessionQuery = sessionQuery.Where("id = ?", *session)
+	}
+	if err := sessionQuery.Find(&sessions).Error; err != nil {
+		log.Error("failed to get scan sessions", "err", err)
+		writeError(w, "Error retrieving scan sessions", http.StatusInternalServerError)
+		return
+	}
+	scopes := make(map[uint]*models.ScanSession, len(sessions))
+	for i := range sessions {
+		scopes[sessions[i].ID] = &sessions[i]
+	}
+
+	var results []models.Result
+	q := h.DB.Model(&models.Result{}).
+		Select("id", "url", "final_url", "ip_address", "scan_session_id").
+		Where("id IN (?)", h.DB.Model(&models.Cookie{}).Select("result_id")).
+		Preload("Cookies")
+	if err := inSession(q, session).Order("id").Find(&results).Error; err != nil {
+		log.Error("failed to get results with cookies", "err", err)
+		writeError(w, "Error retrieving cookies", http.StatusInternalServerError)
+		return
+	}
+
+	insecure := []insecureCookie{}
+	for i := range results {
+		result := &results[i]
+
+		var scope *models.ScanSession
+		if result.ScanSessionID != nil {
+			scope = scopes[*result.ScanSessionID]
+		}
+
+		for _, issue := range cookies.Analyze(result) {
+			if scope != nil && len(scope.ScopeDomains()) > 0 && !scope.InScope(issue.Host) {
+				continue
+			}
+			if len(severities) > 0 && !slices.Contains(severities, issue.Severity) {
+				continue
+			}
+
+			insecure = append(insecure, insecureCookie{
+				ResultID: result.ID,
+				URL:      result.URL,
+				Issue:    issue,
+			})
+		}
+	}
+
+	jsonData, err := json.Marshal(insecure)
+	if err != nil {
+		log.Error("failed to marshal insecure cookies", "err", err)
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
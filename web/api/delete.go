@@ -21,12 +21,13 @@ type deleteResultRequest struct {
 //	@Produce		json
 //	@Param			query	body		deleteResultRequest	true	"The result ID to delete"
 //	@Success		200		{string}	string				"ok"
+//	@Failure		500		{object}	ErrorResponse
 //	@Router			/results/delete [post]
 func (h *ApiHandler) DeleteResultHandler(w http.ResponseWriter, r *http.Request) {
 	var request deleteResultRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		log.Error("failed to read json request", "err", err)
-		http.Error(w, "Error reading JSON request", http.StatusInternalServerError)
+		writeError(w, "Error reading JSON request", http.StatusInternalServerError)
 		return
 	}
 
@@ -34,13 +35,14 @@ func (h *ApiHandler) DeleteResultHandler(w http.ResponseWriter, r *http.Request)
 
 	if err := h.DB.Delete(&models.Result{}, request.ID).Error; err != nil {
 		log.Error("failed to delete result", "err", err)
+		writeError(w, "Error deleting result", http.StatusInternalServerError)
 		return
 	}
 
 	response := `ok`
 	jsonData, err := json.Marshal(response)
 	if err != nil {
-		http.Error(w, "Error creating JSON response", http.StatusInternalServerError)
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
 		return
 	}
 
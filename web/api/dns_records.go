@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// DNSRecordsHandler lists stored DNS records
+//
+//	@Summary		List DNS records
+//	@Description	Lists the A, AAAA and CNAME records hosts resolved to, oldest first per host, so that DNS changes can be followed over time. Dangling CNAMEs point to targets that did not resolve, and may allow a subdomain takeover.
+//	@Tags			Results
+//	@Produce		json
+//	@Param			host			query	string	false	"Only list records of this host."
+//	@Param			type			query	string	false	"Only list records of this type, A, AAAA or CNAME."
+//	@Param			dangling		query	bool	false	"Only list dangling CNAME records."
+//	@Param			session			query	int		false	"Only list records from this scan session."
+//	@Success		200				{array}	models.DNSRecord
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/dns-records [get]
+func (h *ApiHandler) DNSRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := h.DB.Model(&models.DNSRecord{})
+
+	if host := query.Get("host"); host != "" {
+		q = q.Where("host = ?", strings.ToLower(host))
+	}
+
+	if recordType := query.Get("type"); recordType != "" {
+		recordType = strings.ToUpper(recordType)
+		if recordType != "A" && recordType != "AAAA" && recordType != "CNAME" {
+			writeError(w, "type must be A, AAAA or CNAME", http.StatusBadRequest)
+			return
+		}
+		q = q.Where("type = ?", recordType)
+	}
+
+	if query.Get("dangling") == "true" {
+		q = q.Where("dangling = ?", true)
+	}
+
+	session, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q = inSession(q, session)
+
+	var records []models.DNSRecord
+	if err := q.Order("host, type, first_seen").Find(&records).Error; err != nil {
+		log.Error("failed to get dns records", "err", err)
+		writeError(w, "Error retrieving DNS records", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(records)
+	if err != nil {
+		log.Error("failed to marshal dns records", "err", err)
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
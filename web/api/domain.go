@@ -0,0 +1,491 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/assets"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/whois"
+	"gorm.io/gorm"
+)
+
+// DomainRegistrationInfo is the registration of an apex domain
+type DomainRegistrationInfo struct {
+	Registrar   string   `json:"registrar"`
+	Status      []string `json:"status"`
+	Nameservers []string `json:"nameservers"`
+	CreatedDate string   `json:"created_date,omitempty"`
+	ExpiryDate  string   `json:"expiry_date,omitempty"`
+	UpdatedDate string   `json:"updated_date,omitempty"`
+	// DaysUntilExpiry is negative once the domain expired
+	DaysUntilExpiry *int   `json:"days_until_expiry,omitempty"`
+	CheckedAt       string `json:"checked_at"`
+}
+
+// NetblockInfo is an IP range and who it is assigned to
+type NetblockInfo struct {
+	Handle       string   `json:"handle"`
+	Name         string   `json:"name"`
+	StartAddress string   `json:"start_address"`
+	EndAddress   string   `json:"end_address"`
+	CIDRs        []string `json:"cidrs"`
+	Owner        string   `json:"owner"`
+	Country      string   `json:"country"`
+	Type         string   `json:"type"`
+	CheckedAt    string   `json:"checked_at"`
+
+	// IPAddresses are the addresses of the domain in the netblock
+	IPAddresses []string `json:"ip_addresses,omitempty"`
+}
+
+// DomainTLSInfo is a certificate a host presented, and when it was seen
+type DomainTLSInfo struct {
+	SubjectName string   `json:"subject_name"`
+	Issuer      string   `json:"issuer"`
+	SanList     []string `json:"san_list"`
+	ValidFrom   string   `json:"valid_from"`
+	ValidTo     string   `json:"valid_to"`
+	Protocol    string   `json:"protocol"`
+	Cipher      string   `json:"cipher"`
+	FirstSeen   string   `json:"first_seen"`
+	LastSeen    string   `json:"last_seen"`
+}
+
+// DomainTechnologyInfo is a technology a host ran, and when it was last seen
+type DomainTechnologyInfo struct {
+	Value    string `json:"value"`
+	Name     string `json:"name"`
+	Version  string `json:"version,omitempty"`
+	LastSeen string `json:"last_seen"`
+}
+
+// DomainPortInfo is an open port of an IP address a host resolved to
+type DomainPortInfo struct {
+	IPAddress string `json:"ip_address"`
+	IPPortInfo
+}
+
+// DomainInfoResponse represents the complete response for domain information
+type DomainInfoResponse struct {
+	Domain       string                  `json:"domain"`
+	ApexDomain   string                  `json:"apex_domain"`
+	Registration *DomainRegistrationInfo `json:"registration,omitempty"`
+
+	// Results are every probe of the host, newest first
+	Results      []DomainInfo `json:"results"`
+	TotalResults int          `json:"total_results"`
+	ScanSessions []uint       `json:"scan_sessions"` // List of scan session IDs the host was seen in
+
+	// IPAddresses are the addresses the host resolved to, from its DNS
+	// records and the addresses it was probed on
+	IPAddresses  []string               `json:"ip_addresses"`
+	DNSRecords   []models.DNSRecord     `json:"dns_records"`
+	TLSHistory   []DomainTLSInfo        `json:"tls_history"`
+	Technologies []DomainTechnologyInfo `json:"technologies"`
+	OpenPorts    []DomainPortInfo       `json:"open_ports"`
+	Findings     []models.Finding       `json:"findings"`
+	Netblocks    []NetblockInfo         `json:"netblocks"`
+
+	// Asset is the imported asset the host is, with its business context
+	Asset *models.Asset `json:"asset,omitempty"`
+}
+
+// DomainInfoHandler handles domain information requests
+//
+//	@Summary		Get information about a domain
+//	@Description	Returns everything known about a hostname across scan sessions: its results, the IP addresses it resolved to, the certificates and technologies it was seen with, the open ports of its IP addresses, its findings, the netblocks its addresses are in, the imported asset it is and the RDAP registration of its apex domain.
+//	@Tags			Domain Information
+//	@Produce		json
+//	@Param			domain	path		string	true	"The hostname to get information for"
+//	@Param			tz		query		string	false	"IANA timezone to display times in. Defaults to UTC."
+//	@Success		200		{object}	DomainInfoResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/domain/{domain} [get]
+func (h *ApiHandler) DomainInfoHandler(w http.ResponseWriter, r *http.Request) {
+	domain := strings.TrimSuffix(strings.ToLower(chi.URLParam(r, "domain")), ".")
+	apex := islazy.ApexDomain(domain)
+	if apex == "" {
+		writeError(w, "Invalid domain", http.StatusBadRequest)
+		return
+	}
+
+	loc, err := displayLocation(r, time.UTC)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := DomainInfoResponse{
+		Domain:       domain,
+		ApexDomain:   apex,
+		Results:      []DomainInfo{},
+		ScanSessions: []uint{},
+		IPAddresses:  []string{},
+		DNSRecords:   []models.DNSRecord{},
+		TLSHistory:   []DomainTLSInfo{},
+		Technologies: []DomainTechnologyInfo{},
+		OpenPorts:    []DomainPortInfo{},
+		Findings:     []models.Finding{},
+		Netblocks:    []NetblockInfo{},
+	}
+
+	var registration models.DomainRegistration
+	if err := h.DB.Where("domain = ?", apex).First(&registration).Error; err == nil {
+		response.Registration = domainRegistrationInfo(&registration, loc)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("failed to get domain registration", "err", err, "domain", apex)
+		writeError(w, "Error retrieving domain registration", http.StatusInternalServerError)
+		return
+	}
+
+	if err := database.RefreshResultHosts(h.DB); err != nil {
+		log.Warn("failed to refresh result hosts", "err", err)
+	}
+
+	// Get the results probed on this host
+	var results []models.Result
+	if err := h.DB.Select("id", "url", "final_url", "title", "response_code", "response_reason", "protocol",
+		"screenshot", "filename", "failed", "failed_reason", "probed_at", "scan_session_id", "ip_address").
+		Where("id IN (?)", h.DB.Model(&models.ResultHost{}).Select("result_id").Where("hostname = ?", domain)).
+		Preload("TLS").Preload("TLS.SanList").Preload("Technologies").
+		Order("probed_at DESC").Find(&results).Error; err != nil {
+		log.Error("failed to get results for domain", "err", err, "domain", domain)
+		writeError(w, "Error retrieving results", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.DB.Where("host = ?", domain).Order("type, first_seen").Find(&response.DNSRecords).Error; err != nil {
+		log.Error("failed to get dns records for domain", "err", err, "domain", domain)
+		writeError(w, "Error retrieving DNS records", http.StatusInternalServerError)
+		return
+	}
+
+	if response.Registration == nil && len(results) == 0 && len(response.DNSRecords) == 0 {
+		writeError(w, "Domain not found", http.StatusNotFound)
+		return
+	}
+
+	scanSessionSet := make(map[uint]bool)
+	ipSet := make(map[string]bool)
+	certificates := make(map[string]int)
+	technologies := make(map[string]bool)
+	resultIDs := make([]uint, 0, len(results))
+
+	// results are newest first, so the first certificate and technology
+	// seen is the latest
+	for _, result := range results {
+		resultIDs = append(resultIDs, result.ID)
+		response.Results = append(response.Results, DomainInfo{
+			ID:             result.ID,
+			URL:            result.URL,
+			FinalURL:       result.FinalURL,
+			Title:          result.Title,
+			ResponseCode:   result.ResponseCode,
+			ResponseReason: result.ResponseReason,
+			Protocol:       result.Protocol,
+			Screenshot:     result.Screenshot,
+			Filename:       result.Filename,
+			Failed:         result.Failed,
+			FailedReason:   result.FailedReason,
+			ProbedAt:       formatTime(result.ProbedAt, loc),
+			ScanSessionID:  result.ScanSessionID,
+		})
+
+		if result.ScanSessionID != nil {
+			scanSessionSet[*result.ScanSessionID] = true
+		}
+		if result.IPAddress != "" {
+			ipSet[result.IPAddress] = true
+		}
+
+		if result.TLS.SubjectName != "" {
+			key := result.TLS.SubjectName + "\x00" + result.TLS.Issuer + "\x00" + result.TLS.ValidTo.String()
+			if i, ok := certificates[key]; ok {
+				response.TLSHistory[i].FirstSeen = formatTime(result.ProbedAt, loc)
+			} else {
+				sans := make([]string, 0, len(result.TLS.SanList))
+				for _, san := range result.TLS.SanList {
+					sans = append(sans, san.Value)
+				}
+
+				certificates[key] = len(response.TLSHistory)
+				response.TLSHistory = append(response.TLSHistory, DomainTLSInfo{
+					SubjectName: result.TLS.SubjectName,
+					Issuer:      result.TLS.Issuer,
+					SanList:     sans,
+					ValidFrom:   formatTime(result.TLS.ValidFrom, loc),
+					ValidTo:     formatTime(result.TLS.ValidTo, loc),
+					Protocol:    result.TLS.Protocol,
+					Cipher:      result.TLS.Cipher,
+					FirstSeen:   formatTime(result.ProbedAt, loc),
+					LastSeen:    formatTime(result.ProbedAt, loc),
+				})
+			}
+		}
+
+		for _, technology := range result.Technologies {
+			if technologies[technology.Value] {
+				continue
+			}
+			technologies[technology.Value] = true
+			response.Technologies = append(response.Technologies, DomainTechnologyInfo{
+				Value:    technology.Value,
+				Name:     technology.Name,
+				Version:  technology.Version,
+				LastSeen: formatTime(result.ProbedAt, loc),
+			})
+		}
+	}
+	response.TotalResults = len(results)
+
+	for _, record := range response.DNSRecords {
+		if record.Type == "A" || record.Type == "AAAA" {
+			ipSet[record.Value] = true
+		}
+		if record.ScanSessionID != nil {
+			scanSessionSet[*record.ScanSessionID] = true
+		}
+	}
+
+	for ip := range ipSet {
+		response.IPAddresses = append(response.IPAddresses, ip)
+	}
+	slices.SortFunc(response.IPAddresses, func(a, b string) int {
+		x, _ := netip.ParseAddr(a)
+		y, _ := netip.ParseAddr(b)
+		return x.Compare(y)
+	})
+
+	for sessionID := range scanSessionSet {
+		response.ScanSessions = append(response.ScanSessions, sessionID)
+	}
+	slices.Sort(response.ScanSessions)
+
+	// Get the open ports of the host's IP addresses
+	if len(response.IPAddresses) > 0 {
+		var ipPorts []models.IPPort
+		if err := h.DB.Where("ip_address IN ? AND state = ?", response.IPAddresses, "open").
+			Order("ip_address, port").Find(&ipPorts).Error; err != nil {
+			log.Error("failed to get ports for domain", "err", err, "domain", domain)
+			writeError(w, "Error retrieving port information", http.StatusInternalServerError)
+			return
+		}
+
+		for _, port := range ipPorts {
+			response.OpenPorts = append(response.OpenPorts, DomainPortInfo{
+				IPAddress: port.IPAddress,
+				IPPortInfo: IPPortInfo{
+					ID:            port.ID,
+					Port:          port.Port,
+					Protocol:      port.Protocol,
+					Service:       port.Service,
+					State:         port.State,
+					Banner:        port.Banner,
+					ScanSessionID: port.ScanSessionID,
+					DiscoveredAt:  formatTime(port.DiscoveredAt, loc),
+					IsCDN:         port.IsCDN,
+					CDNName:       port.CDNName,
+					CDNDetected:   port.CDNDetected,
+					OriginalHost:  port.OriginalHost,
+				},
+			})
+		}
+	}
+
+	// Get the findings of the host's results, and of its IP addresses
+	if len(resultIDs) > 0 || len(response.IPAddresses) > 0 {
+		findings := h.DB.Where("result_id IN ?", resultIDs)
+		if len(response.IPAddresses) > 0 {
+			findings = findings.Or("result_id IS NULL AND ip_address IN ?", response.IPAddresses)
+		}
+		if err := findings.Order("created_at DESC").Find(&response.Findings).Error; err != nil {
+			log.Error("failed to get findings for domain", "err", err, "domain", domain)
+			writeError(w, "Error retrieving findings", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var netblocks []models.Netblock
+	if err := h.DB.Find(&netblocks).Error; err != nil {
+		log.Error("failed to get netblocks", "err", err)
+		writeError(w, "Error retrieving netblocks", http.StatusInternalServerError)
+		return
+	}
+
+	for _, netblock := range netblocks {
+		var ips []string
+		for _, ip := range response.IPAddresses {
+			if netblock.Contains(ip) {
+				ips = append(ips, ip)
+			}
+		}
+		if len(ips) == 0 {
+			continue
+		}
+
+		info := netblockInfo(&netblock, loc)
+		info.IPAddresses = ips
+		response.Netblocks = append(response.Netblocks, *info)
+	}
+	slices.SortFunc(response.Netblocks, func(a, b NetblockInfo) int {
+		x, _ := netip.ParseAddr(a.StartAddress)
+		y, _ := netip.ParseAddr(b.StartAddress)
+		return x.Compare(y)
+	})
+
+	inventory, err := assets.Load(h.DB)
+	if err != nil {
+		// Log error but don't fail the request
+		log.Warn("failed to get assets", "err", err, "domain", domain)
+	} else {
+		response.Asset = inventory.Match(domain, "")
+		for _, ip := range response.IPAddresses {
+			if response.Asset != nil {
+				break
+			}
+			response.Asset = inventory.Match(domain, ip)
+		}
+	}
+
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		log.Error("failed to marshal domain info response", "err", err)
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+// ExpiringDomainsHandler lists domains that are about to expire
+//
+//	@Summary		Expiring domains
+//	@Description	Lists the apex domains whose registration expires within the given number of days, or already expired, soonest first. Registrations are looked up with 'scan whois'.
+//	@Tags			Domain Information
+//	@Produce		json
+//	@Param			days			query	int	false	"Days ahead to list expiring domains for. Defaults to 30."
+//	@Param			session			query	int	false	"Only list domains from this scan session."
+//	@Success		200				{array}		whois.Expiry
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/domains/expiring [get]
+func (h *ApiHandler) ExpiringDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	days := whois.DefaultDays
+	if raw := query.Get("days"); raw != "" {
+		var err error
+		if days, err = strconv.Atoi(raw); err != nil || days < 0 {
+			writeError(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+	}
+
+	q := h.DB.Model(&models.DomainRegistration{})
+	session, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q = inSession(q, session)
+
+	var registrations []models.DomainRegistration
+	if err := q.Find(&registrations).Error; err != nil {
+		log.Error("failed to get domain registrations", "err", err)
+		writeError(w, "Error retrieving domain registrations", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(whois.Expiring(registrations, days, time.Now()))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// domainRegistrationInfo converts a registration for a response
+func domainRegistrationInfo(registration *models.DomainRegistration, loc *time.Location) *DomainRegistrationInfo {
+	info := &DomainRegistrationInfo{
+		Registrar: registration.Registrar,
+		CheckedAt: formatTime(registration.CheckedAt, loc),
+	}
+
+	if status, err := registration.GetStatus(); err == nil {
+		info.Status = status
+	}
+	if nameservers, err := registration.GetNameservers(); err == nil {
+		info.Nameservers = nameservers
+	}
+	if registration.CreatedDate != nil {
+		info.CreatedDate = formatTime(*registration.CreatedDate, loc)
+	}
+	if registration.UpdatedDate != nil {
+		info.UpdatedDate = formatTime(*registration.UpdatedDate, loc)
+	}
+	if registration.ExpiryDate != nil {
+		info.ExpiryDate = formatTime(*registration.ExpiryDate, loc)
+		days := int(time.Until(*registration.ExpiryDate).Hours() / 24)
+		info.DaysUntilExpiry = &days
+	}
+
+	return info
+}
+
+// netblockInfo converts a netblock for a response
+func netblockInfo(netblock *models.Netblock, loc *time.Location) *NetblockInfo {
+	info := &NetblockInfo{
+		Handle:       netblock.Handle,
+		Name:         netblock.Name,
+		StartAddress: netblock.StartAddress,
+		EndAddress:   netblock.EndAddress,
+		Owner:        netblock.Owner,
+		Country:      netblock.Country,
+		Type:         netblock.Type,
+		CheckedAt:    formatTime(netblock.CheckedAt, loc),
+	}
+
+	if cidrs, err := netblock.GetCIDRs(); err == nil {
+		info.CIDRs = cidrs
+	}
+
+	return info
+}
+
+// ipNetblock returns the most specific known netblock an IP address is in,
+// or nil if it is in none
+func ipNetblock(db *gorm.DB, ip string) (*models.Netblock, error) {
+	var netblocks []models.Netblock
+	if err := db.Find(&netblocks).Error; err != nil {
+		return nil, err
+	}
+
+	// registries return the smallest assignment, but a broader allocation
+	// may have been stored from another lookup
+	var found *models.Netblock
+	for i := range netblocks {
+		if !netblocks[i].Contains(ip) {
+			continue
+		}
+		if found == nil || found.Contains(netblocks[i].StartAddress) && found.Contains(netblocks[i].EndAddress) {
+			found = &netblocks[i]
+		}
+	}
+
+	return found, nil
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ErrorResponse is the body of an api error
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError writes a JSON error response. It mirrors http.Error.
+func writeError(w http.ResponseWriter, message string, code int) {
+	jsonData, err := json.Marshal(ErrorResponse{Error: message})
+	if err != nil {
+		http.Error(w, message, code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	w.Write(jsonData)
+}
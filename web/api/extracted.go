@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// extractedURLEntry is an extracted URL and the result it was found on
+type extractedURLEntry struct {
+	models.ExtractedURL
+	ResultURL string `json:"result_url"`
+}
+
+// secretEntry is a secret and the result it was found on
+type secretEntry struct {
+	models.Secret
+	ResultURL string `json:"result_url"`
+}
+
+// ExtractedURLsHandler lists extracted endpoints and third-party domains
+//
+//	@Summary		Extracted URLs
+//	@Description	Lists the API endpoints and third-party domains referenced by the HTML, scripts and network requests of results.
+//	@Tags			Results
+//	@Produce		json
+//	@Param			kind			query	string	false	"Only list this kind of URL, endpoint or third-party."
+//	@Param			host			query	string	false	"Only list URLs on this host."
+//	@Param			result_id		query	int		false	"Only list URLs extracted from this result."
+//	@Param			session			query	int		false	"Only list URLs extracted from results of this scan session."
+//	@Success		200				{array}		extractedURLEntry
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/extracted/urls [get]
+func (h *ApiHandler) ExtractedURLsHandler(w http.ResponseWriter, r *http.Request) {
+	q, ok := extractedQuery(w, r, h.DB.Model(&models.ExtractedURL{}), "extracted_urls")
+	if !ok {
+		return
+	}
+
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		q = q.Where("extracted_urls.kind = ?", kind)
+	}
+	if host := r.URL.Query().Get("host"); host != "" {
+		q = q.Where("extracted_urls.host = ?", host)
+	}
+
+	var urls []extractedURLEntry
+	if err := q.Select("extracted_urls.*, results.url AS result_url").
+		Order("extracted_urls.kind, extracted_urls.url").Scan(&urls).Error; err != nil {
+		log.Error("failed to get extracted urls", "err", err)
+		writeError(w, "Error retrieving extracted urls", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(urls)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// SecretsHandler lists strings that look like secrets
+//
+//	@Summary		Extracted secrets
+//	@Description	Lists the strings in the HTML and scripts of results that look like API keys, tokens and other credentials.
+//	@Tags			Results
+//	@Produce		json
+//	@Param			kind			query	string	false	"Only list this kind of secret, e.g. aws-access-key."
+//	@Param			result_id		query	int		false	"Only list secrets extracted from this result."
+//	@Param			session			query	int		false	"Only list secrets extracted from results of this scan session."
+//	@Success		200				{array}		secretEntry
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/extracted/secrets [get]
+func (h *ApiHandler) SecretsHandler(w http.ResponseWriter, r *http.Request) {
+	q, ok := extractedQuery(w, r, h.DB.Model(&models.Secret{}), "secrets")
+	if !ok {
+		return
+	}
+
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		q = q.Where("secrets.kind = ?", kind)
+	}
+
+	var secrets []secretEntry
+	if err := q.Select("secrets.*, results.url AS result_url").
+		Order("secrets.kind, secrets.id").Scan(&secrets).Error; err != nil {
+		log.Error("failed to get secrets", "err", err)
+		writeError(w, "Error retrieving secrets", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(secrets)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// extractedQuery joins the results of extracted data to q and applies the
+// result filters of a request, writing an error response if they are invalid
+func extractedQuery(w http.ResponseWriter, r *http.Request, q *gorm.DB, table string) (*gorm.DB, bool) {
+	q = q.Joins("JOIN results ON results.id = " + table + ".result_id")
+
+	if raw := r.URL.Query().Get("result_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			writeError(w, "Invalid result_id", http.StatusBadRequest)
+			return nil, false
+		}
+		q = q.Where(table+".result_id = ?", id)
+	}
+
+	session, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if session != nil {
+		q = q.Where("results.scan_session_id = ?", *session)
+	}
+
+	return q, true
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/plugins"
+)
+
+// FindingsHandler lists findings, most severe first
+//
+//	@Summary		List findings
+//	@Description	Lists the findings of plugins, takeover detection, bucket detection, CVE correlation and cookie analysis, ranked by severity and then CVSS score, most severe first.
+//	@Tags			Results
+//	@Produce		json
+//	@Param			session			query	int		false	"Only list findings from this scan session."
+//	@Param			source			query	string	false	"Only list findings reported by this source, e.g. vulndb."
+//	@Param			severity		query	string	false	"A comma seperated list of severities to filter by."
+//	@Param			cve				query	string	false	"Only list findings of this CVE."
+//	@Success		200				{array}	models.Finding
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/findings [get]
+func (h *ApiHandler) FindingsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := h.DB.Model(&models.Finding{})
+
+	session, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q = inSession(q, session)
+
+	if source := query.Get("source"); source != "" {
+		q = q.Where("source = ?", source)
+	}
+
+	if severity := query.Get("severity"); severity != "" {
+		q = q.Where("severity IN ?", strings.Split(strings.ToLower(severity), ","))
+	}
+
+	if cve := query.Get("cve"); cve != "" {
+		q = q.Where("cve = ?", strings.ToUpper(cve))
+	}
+
+	var findings []models.Finding
+	if err := q.Order("cvss DESC, created_at DESC").Find(&findings).Error; err != nil {
+		log.Error("failed to get findings", "err", err)
+		writeError(w, "Error retrieving findings", http.StatusInternalServerError)
+		return
+	}
+
+	// rank by severity, keeping the cvss order within a severity
+	sort.SliceStable(findings, func(i, j int) bool {
+		return slices.Index(plugins.Severities, findings[i].Severity) > slices.Index(plugins.Severities, findings[j].Severity)
+	})
+
+	jsonData, err := json.Marshal(findings)
+	if err != nil {
+		log.Error("failed to marshal findings", "err", err)
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -25,9 +26,16 @@ type galleryContent struct {
 	ResponseCode int       `json:"response_code"`
 	Title        string    `json:"title"`
 	Filename     string    `json:"file_name"`
+	Thumbnail    string    `json:"thumbnail"`
 	Screenshot   string    `json:"screenshot"`
 	Failed       bool      `json:"failed"`
+	Noise        bool      `json:"noise"`
 	Technologies []string  `json:"technologies"`
+	Annotations  []string  `json:"annotations"`
+	Tags         []string  `json:"tags"`
+	Reviewed     bool      `json:"reviewed"`
+	Interesting  bool      `json:"interesting"`
+	AssignedTo   string    `json:"assigned_to,omitempty"`
 }
 
 // GalleryHandler gets a paginated gallery
@@ -41,9 +49,18 @@ type galleryContent struct {
 //	@Param			limit			query		int		false	"Number of results per page."
 //	@Param			technologies	query		string	false	"A comma seperated list of technologies to filter by."
 //	@Param			status			query		string	false	"A comma seperated list of HTTP status codes to filter by."
+//	@Param			annotations		query		string	false	"A comma seperated list of annotation kinds to filter by."
+//	@Param			tags			query		string	false	"A comma seperated list of tags to filter by, e.g. login-panel."
+//	@Param			reviewed		query		boolean	false	"Only include reviewed, or unreviewed, results."
+//	@Param			interesting		query		boolean	false	"Only include results marked as interesting, or not."
+//	@Param			assigned_to		query		string	false	"Only include results assigned to this analyst."
 //	@Param			perception		query		boolean	false	"Order the results by perception hash."
 //	@Param			failed			query		boolean	false	"Include failed screenshots in the results."
+//	@Param			noise			query		boolean	false	"Include results marked as noise, such as parked domains. Defaults to false."
+//	@Param			session			query		int		false	"Only include results of this scan session."
 //	@Success		200				{object}	galleryResponse
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
 //	@Router			/results/gallery [get]
 func (h *ApiHandler) GalleryHandler(w http.ResponseWriter, r *http.Request) {
 	var results = &galleryResponse{
@@ -91,6 +108,23 @@ func (h *ApiHandler) GalleryHandler(w http.ResponseWriter, r *http.Request) {
 		technologies = append(technologies, strings.Split(technologyFilterValue, ",")...)
 	}
 
+	// annotation filtering
+	var annotationKinds []string
+	if annotationFilterValue := r.URL.Query().Get("annotations"); annotationFilterValue != "" {
+		annotationKinds = strings.Split(annotationFilterValue, ",")
+	}
+
+	// tag filtering
+	var tagNames []string
+	if tagFilterValue := r.URL.Query().Get("tags"); tagFilterValue != "" {
+		tagNames = strings.Split(tagFilterValue, ",")
+	}
+
+	// triage filtering
+	reviewed, reviewedErr := strconv.ParseBool(r.URL.Query().Get("reviewed"))
+	interesting, interestingErr := strconv.ParseBool(r.URL.Query().Get("interesting"))
+	assignedTo := r.URL.Query().Get("assigned_to")
+
 	// failed result filtering
 	var showFailed bool
 	showFailed, err = strconv.ParseBool(r.URL.Query().Get("failed"))
@@ -98,10 +132,23 @@ func (h *ApiHandler) GalleryHandler(w http.ResponseWriter, r *http.Request) {
 		showFailed = true
 	}
 
+	// noise filtering
+	showNoise, err := strconv.ParseBool(r.URL.Query().Get("noise"))
+	if err != nil {
+		showNoise = false
+	}
+
+	// scan session filtering
+	session, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// query the db
 	var queryResults []*models.Result
-	query := h.DB.Model(&models.Result{}).Limit(results.Limit).
-		Offset(offset).Preload("Technologies")
+	query := inSession(h.DB.Model(&models.Result{}), session).Limit(results.Limit).
+		Offset(offset).Preload("Technologies").Preload("Annotations").Preload("Tags")
 
 	if perceptionSort {
 		query.Order("perception_hash_group_id DESC")
@@ -117,13 +164,42 @@ func (h *ApiHandler) GalleryHandler(w http.ResponseWriter, r *http.Request) {
 			Where("value IN (?)", technologies))
 	}
 
+	if len(annotationKinds) > 0 {
+		query.Where("id in (?)", h.DB.Model(&models.Annotation{}).
+			Select("result_id").Distinct("result_id").
+			Where("kind IN (?)", annotationKinds))
+	}
+
+	if len(tagNames) > 0 {
+		query.Where("id in (?)", h.DB.Model(&models.ResultTag{}).
+			Select("result_id").Distinct("result_id").
+			Where("name IN (?)", tagNames))
+	}
+
+	if reviewedErr == nil {
+		query.Where("reviewed = ?", reviewed)
+	}
+
+	if interestingErr == nil {
+		query.Where("interesting = ?", interesting)
+	}
+
+	if assignedTo != "" {
+		query.Where("assigned_to = ?", assignedTo)
+	}
+
 	if !showFailed {
 		query.Where("failed = ?", showFailed)
 	}
 
+	if !showNoise {
+		query.Where("noise = ?", false)
+	}
+
 	// run the query
 	if err := query.Find(&queryResults).Error; err != nil {
 		log.Error("could not get gallery", "err", err)
+		writeError(w, "Error retrieving gallery", http.StatusInternalServerError)
 		return
 	}
 
@@ -134,6 +210,18 @@ func (h *ApiHandler) GalleryHandler(w http.ResponseWriter, r *http.Request) {
 			technologies = append(technologies, tech.Value)
 		}
 
+		var annotationKinds []string
+		for _, annotation := range result.Annotations {
+			annotationKinds = append(annotationKinds, annotation.Kind)
+		}
+
+		var resultTags []string
+		for _, tag := range result.Tags {
+			if !slices.Contains(resultTags, tag.Name) {
+				resultTags = append(resultTags, tag.Name)
+			}
+		}
+
 		// Append the processed data to the response
 		results.Results = append(results.Results, &galleryContent{
 			ID:           result.ID,
@@ -142,20 +230,28 @@ func (h *ApiHandler) GalleryHandler(w http.ResponseWriter, r *http.Request) {
 			ResponseCode: result.ResponseCode,
 			Title:        result.Title,
 			Filename:     result.Filename,
+			Thumbnail:    result.Thumbnail,
 			Screenshot:   result.Screenshot,
 			Failed:       result.Failed,
+			Noise:        result.Noise,
 			Technologies: technologies,
+			Annotations:  annotationKinds,
+			Tags:         resultTags,
+			Reviewed:     result.Reviewed,
+			Interesting:  result.Interesting,
+			AssignedTo:   result.AssignedTo,
 		})
 	}
 
-	if err := h.DB.Model(&models.Result{}).Count(&results.TotalCount).Error; err != nil {
+	if err := inSession(h.DB.Model(&models.Result{}), session).Count(&results.TotalCount).Error; err != nil {
 		log.Error("could not count total results", "err", err)
+		writeError(w, "Error counting results", http.StatusInternalServerError)
 		return
 	}
 
 	jsonData, err := json.Marshal(results)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/pkg/assets"
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/models"
 	"gorm.io/gorm/clause"
@@ -19,6 +20,8 @@ import (
 //	@Produce		json
 //	@Param			id	path		int	true	"The screenshot ID to load."
 //	@Success		200	{object}	models.Result
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
 //	@Router			/results/detail/{id} [get]
 func (h *ApiHandler) DetailHandler(w http.ResponseWriter, r *http.Request) {
 	var response = &models.Result{}
@@ -29,12 +32,21 @@ func (h *ApiHandler) DetailHandler(w http.ResponseWriter, r *http.Request) {
 		First(&response, chi.URLParam(r, "id")).Error; err != nil {
 
 		log.Error("could not get detail for id", "err", err)
+		writeError(w, "Result not found", http.StatusNotFound)
 		return
 	}
 
+	// the asset is looked up, as it is matched on the host and not stored
+	asset, err := assets.ForHost(h.DB, response.URL, response.IPAddress)
+	if err != nil {
+		// Log error but don't fail the request
+		log.Warn("failed to get asset", "err", err, "id", response.ID)
+	}
+	response.Asset = asset
+
 	jsonData, err := json.Marshal(response)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
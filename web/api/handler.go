@@ -1,8 +1,12 @@
 package api
 
 import (
+	"sync"
+
 	wappalyzer "github.com/projectdiscovery/wappalyzergo"
 	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/jobs"
+	"github.com/sensepost/gowitness/pkg/writers"
 	"gorm.io/gorm"
 )
 
@@ -12,6 +16,22 @@ type ApiHandler struct {
 	ScreenshotPath string
 	DB             *gorm.DB
 	Wappalyzer     *wappalyzer.Wappalyze
+	// Jobs runs background work, such as probing submitted URLs. It is
+	// started with StartJobs.
+	Jobs *jobs.Queue
+	// agentDbWriter saves the results agents upload
+	agentMu       sync.Mutex
+	agentDbWriter *writers.DbWriter
+
+	// Security is the security configuration of the web server, as
+	// reported by the security status endpoint
+	Security SecurityConfig
+
+	// ProjectsPath is the directory scan init creates projects in
+	ProjectsPath string
+	// projectDBs are connections to project databases, by project name
+	projectMu  sync.Mutex
+	projectDBs map[string]*gorm.DB
 }
 
 // NewApiHandler returns a new ApiHandler
@@ -30,5 +50,6 @@ func NewApiHandler(uri string, screenshotPath string) (*ApiHandler, error) {
 		ScreenshotPath: screenshotPath,
 		DB:             conn,
 		Wappalyzer:     wap,
+		Jobs:           jobs.NewQueue(conn),
 	}, nil
 }
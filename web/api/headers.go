@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// fingerprintHeaders are the response headers that identify the
+// infrastructure a host runs on, summarised by default
+var fingerprintHeaders = []string{"server", "x-powered-by", "via"}
+
+// headerSummary is the distinct values of a response header, with the
+// number of hosts that sent each
+type headerSummary struct {
+	Header string         `json:"header"`
+	Hosts  int64          `json:"hosts"`
+	Values []*headerValue `json:"values"`
+}
+
+type headerValue struct {
+	Value   string `json:"value"`
+	Hosts   int64  `json:"hosts"`
+	Results int64  `json:"results"`
+}
+
+// HeaderSummaryHandler summarises infrastructure response headers
+//
+//	@Summary		Summarise infrastructure headers
+//	@Description	Groups results by the values of response headers that fingerprint infrastructure, such as Server, X-Powered-By and Via, counting the distinct hosts and results that sent each value. Values are ordered by their host count.
+//	@Tags			Results
+//	@Produce		json
+//	@Param			session	query	int		false	"Only summarise results from this scan session."
+//	@Param			header	query	string	false	"A comma seperated list of headers to summarise. Defaults to server,x-powered-by,via."
+//	@Success		200		{array}		headerSummary
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/headers/summary [get]
+func (h *ApiHandler) HeaderSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	session, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	headers := fingerprintHeaders
+	if header := r.URL.Query().Get("header"); header != "" {
+		headers = nil
+		for _, name := range strings.Split(header, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				headers = append(headers, name)
+			}
+		}
+	}
+
+	// hosts are counted from the result host summary
+	if err := database.RefreshResultHosts(h.DB); err != nil {
+		log.Error("failed refreshing result host summary", "err", err)
+		writeError(w, "Error summarising headers", http.StatusInternalServerError)
+		return
+	}
+
+	var rows []struct {
+		Header  string
+		Value   string
+		Hosts   int64
+		Results int64
+	}
+	if err := h.headerHosts(headers, session).
+		Select("LOWER(headers.key) AS header, TRIM(headers.value) AS value, " +
+			"COUNT(DISTINCT result_hosts.hostname) AS hosts, COUNT(DISTINCT headers.result_id) AS results").
+		Group("LOWER(headers.key), TRIM(headers.value)").
+		Order("hosts DESC, results DESC, value").
+		Scan(&rows).Error; err != nil {
+		log.Error("failed summarising headers", "err", err)
+		writeError(w, "Error summarising headers", http.StatusInternalServerError)
+		return
+	}
+
+	// keep the order the headers were asked for
+	summaries := make([]*headerSummary, 0, len(headers))
+	byHeader := make(map[string]*headerSummary, len(headers))
+	for _, header := range headers {
+		if _, ok := byHeader[header]; ok {
+			continue
+		}
+		summary := &headerSummary{Header: header, Values: make([]*headerValue, 0)}
+		byHeader[header] = summary
+		summaries = append(summaries, summary)
+	}
+	for _, row := range rows {
+		summary := byHeader[row.Header]
+		summary.Values = append(summary.Values, &headerValue{
+			Value:   row.Value,
+			Hosts:   row.Hosts,
+			Results: row.Results,
+		})
+	}
+
+	// the hosts that sent a header at all, which is not the sum of its
+	// values as a host may have sent different values over time
+	var totals []struct {
+		Header string
+		Hosts  int64
+	}
+	if err := h.headerHosts(headers, session).
+		Select("LOWER(headers.key) AS header, COUNT(DISTINCT result_hosts.hostname) AS hosts").
+		Group("LOWER(headers.key)").Scan(&totals).Error; err != nil {
+		log.Error("failed counting header hosts", "err", err)
+		writeError(w, "Error summarising headers", http.StatusInternalServerError)
+		return
+	}
+	for _, total := range totals {
+		byHeader[total.Header].Hosts = total.Hosts
+	}
+
+	jsonData, err := json.Marshal(summaries)
+	if err != nil {
+		log.Error("failed to marshal header summary", "err", err)
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// headerHosts queries the non empty headers of results, joined with the
+// hosts they were sent by. Only results of session are included, if it is
+// set.
+func (h *ApiHandler) headerHosts(headers []string, session *uint) *gorm.DB {
+	q := h.DB.Model(&models.Header{}).
+		Joins("LEFT JOIN result_hosts ON result_hosts.result_id = headers.result_id").
+		Where("LOWER(headers.key) IN ?", headers).
+		Where("TRIM(headers.value) != ''")
+	if session != nil {
+		q = q.Where("headers.result_id IN (?)", h.DB.Model(&models.Result{}).Select("id").
+			Where("scan_session_id = ?", *session))
+	}
+
+	return q
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/sensepost/gowitness/pkg/export"
+	"github.com/sensepost/gowitness/pkg/log"
+)
+
+// IPExportHandler exports the joined view of all IP addresses
+//
+//	@Summary		Export IP addresses
+//	@Description	Exports every known IP address together with its organisation, ASN, country, cloud provider, open ports and services, associated domains and vulnerabilities, as CSV or JSON.
+//	@Tags			IP Information
+//	@Produce		json
+//	@Produce		text/csv
+//	@Param			format			query		string	false	"The export format, json or csv. Defaults to json."
+//	@Param			session			query		int		false	"Only export data from this scan session. scan_session_id is accepted too."
+//	@Param			hosting			query		string	false	"Only export addresses with this hosting: cloud, self-hosted, or a provider such as aws, gcp, azure, cloudflare or fastly."
+//	@Success		200				{array}		export.IP
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/ips/export [get]
+func (h *ApiHandler) IPExportHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		writeError(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	scanSessionID, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hosting := r.URL.Query().Get("hosting")
+	if !export.ValidHosting(hosting) {
+		writeError(w, "hosting must be cloud, self-hosted or a cloud provider", http.StatusBadRequest)
+		return
+	}
+
+	ips, err := export.IPs(h.DB, scanSessionID)
+	if err != nil {
+		log.Error("failed to build ip export", "err", err)
+		writeError(w, "Error exporting IP addresses", http.StatusInternalServerError)
+		return
+	}
+	ips = export.FilterHosting(ips, hosting)
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="gowitness-ips.csv"`)
+		err = export.WriteIPsCSV(w, ips)
+	} else {
+		w.Header().Set("Content-Disposition", `attachment; filename="gowitness-ips.json"`)
+		err = export.WriteIPsJSON(w, ips)
+	}
+	if err != nil {
+		log.Error("failed to write ip export", "err", err)
+	}
+}
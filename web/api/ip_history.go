@@ -0,0 +1,146 @@
+package api
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// IPInfoSnapshot is the information an IP had when it was enriched, with
+// what changed since it was enriched before that
+type IPInfoSnapshot struct {
+	RecordedAt    string         `json:"recorded_at"`
+	ScanSessionID *uint          `json:"scan_session_id,omitempty"`
+	Source        string         `json:"source,omitempty"`
+	Organization  string         `json:"organization,omitempty"`
+	ISP           string         `json:"isp,omitempty"`
+	ASN           string         `json:"asn,omitempty"`
+	Country       string         `json:"country,omitempty"`
+	OS            string         `json:"os,omitempty"`
+	Ports         []int          `json:"ports"`
+	Vulns         []string       `json:"vulns"`
+	Tags          []string       `json:"tags"`
+	Hostnames     []string       `json:"hostnames"`
+	Changes       []IPInfoChange `json:"changes,omitempty"`
+}
+
+// IPInfoChange is a field of the information of an IP that changed between
+// two snapshots. Single values have from and to set, lists added and
+// removed.
+type IPInfoChange struct {
+	Field   string   `json:"field"`
+	From    string   `json:"from,omitempty"`
+	To      string   `json:"to,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// ipInfoHistory returns the snapshots of an IP, newest first
+func ipInfoHistory(db *gorm.DB, ip string, loc *time.Location) ([]IPInfoSnapshot, error) {
+	var history []models.IPInfoHistory
+	if err := db.Where("ip_address = ?", ip).Order("recorded_at, id").Find(&history).Error; err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]IPInfoSnapshot, 0, len(history))
+	for i := range history {
+		snapshot := newIPInfoSnapshot(&history[i], loc)
+		if i > 0 {
+			snapshot.Changes = snapshotChanges(&snapshots[i-1], &snapshot)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	slices.Reverse(snapshots)
+	return snapshots, nil
+}
+
+// newIPInfoSnapshot converts a stored snapshot, ignoring list fields that
+// fail to parse
+func newIPInfoSnapshot(history *models.IPInfoHistory, loc *time.Location) IPInfoSnapshot {
+	info := history.IPInfo()
+
+	snapshot := IPInfoSnapshot{
+		RecordedAt:    formatTime(history.RecordedAt, loc),
+		ScanSessionID: history.ScanSessionID,
+		Source:        history.Source,
+		Organization:  history.Organization,
+		ISP:           history.ISP,
+		ASN:           history.ASN,
+		Country:       history.Country,
+		OS:            history.OS,
+	}
+	snapshot.Ports, _ = info.GetPorts()
+	snapshot.Vulns, _ = info.GetVulns()
+	snapshot.Tags, _ = info.GetTags()
+	snapshot.Hostnames, _ = info.GetHostnames()
+
+	return snapshot
+}
+
+// snapshotChanges lists the fields that changed from one snapshot to the next
+func snapshotChanges(before, after *IPInfoSnapshot) []IPInfoChange {
+	var changes []IPInfoChange
+
+	for _, field := range []struct {
+		name          string
+		before, after string
+	}{
+		{"organization", before.Organization, after.Organization},
+		{"isp", before.ISP, after.ISP},
+		{"asn", before.ASN, after.ASN},
+		{"country", before.Country, after.Country},
+		{"os", before.OS, after.OS},
+	} {
+		if field.before != field.after {
+			changes = append(changes, IPInfoChange{Field: field.name, From: field.before, To: field.after})
+		}
+	}
+
+	for _, field := range []struct {
+		name          string
+		before, after []string
+	}{
+		{"ports", portStrings(before.Ports), portStrings(after.Ports)},
+		{"vulns", before.Vulns, after.Vulns},
+		{"tags", before.Tags, after.Tags},
+		{"hostnames", before.Hostnames, after.Hostnames},
+	} {
+		added, removed := listDifference(field.before, field.after)
+		if len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, IPInfoChange{Field: field.name, Added: added, Removed: removed})
+		}
+	}
+
+	return changes
+}
+
+// listDifference returns the values in after that are not in before, and
+// the values in before that are not in after
+func listDifference(before, after []string) (added []string, removed []string) {
+	for _, value := range after {
+		if !slices.Contains(before, value) {
+			added = append(added, value)
+		}
+	}
+	for _, value := range before {
+		if !slices.Contains(after, value) {
+			removed = append(removed, value)
+		}
+	}
+
+	return added, removed
+}
+
+// portStrings converts ports to strings
+func portStrings(ports []int) []string {
+	values := make([]string, len(ports))
+	for i, port := range ports {
+		values[i] = strconv.Itoa(port)
+	}
+
+	return values
+}
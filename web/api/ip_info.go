@@ -1,48 +1,24 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
-	"os/exec"
-	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/internal/islazy"
+	"github.com/sensepost/gowitness/pkg/assets"
+	"github.com/sensepost/gowitness/pkg/fallback"
+	"github.com/sensepost/gowitness/pkg/jobs"
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/models"
 	"gorm.io/gorm"
 )
 
-// IPAPIResponse represents response from ip-api.com
-type IPAPIResponse struct {
-	Query       string  `json:"query"`
-	Status      string  `json:"status"`
-	Country     string  `json:"country"`
-	CountryCode string  `json:"countryCode"`
-	Region      string  `json:"region"`
-	RegionName  string  `json:"regionName"`
-	City        string  `json:"city"`
-	Zip         string  `json:"zip"`
-	Lat         float64 `json:"lat"`
-	Lon         float64 `json:"lon"`
-	Timezone    string  `json:"timezone"`
-	ISP         string  `json:"isp"`
-	Org         string  `json:"org"`
-	AS          string  `json:"as"`
-	Message     string  `json:"message,omitempty"`
-}
-
-// NaabuResult represents naabu port scan result
-type NaabuResult struct {
-	Host string `json:"host"`
-	IP   string `json:"ip"`
-	Port int    `json:"port"`
-}
-
 // IPPortInfo represents port information for an IP
 type IPPortInfo struct {
 	ID            uint   `json:"id"`
@@ -57,6 +33,34 @@ type IPPortInfo struct {
 	CDNName       string `json:"cdn_name"`
 	CDNDetected   bool   `json:"cdn_detected"`
 	OriginalHost  string `json:"original_host"`
+
+	// OriginCandidates are possible origin servers, if the port is CDN fronted
+	OriginCandidates []OriginCandidateInfo `json:"origin_candidates,omitempty"`
+	// Screenshot is the latest screenshot of a VNC or X11 service on the port
+	Screenshot *ServiceScreenshotInfo `json:"screenshot,omitempty"`
+}
+
+// ServiceScreenshotInfo represents a screenshot of a non-HTTP service
+type ServiceScreenshotInfo struct {
+	Protocol     string `json:"protocol"`
+	Filename     string `json:"file_name"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Name         string `json:"name,omitempty"`
+	Failed       bool   `json:"failed"`
+	FailedReason string `json:"failed_reason,omitempty"`
+	CapturedAt   string `json:"captured_at"`
+}
+
+// OriginCandidateInfo represents a possible origin server of a CDN fronted port
+type OriginCandidateInfo struct {
+	IPAddress     string `json:"ip_address"`
+	Source        string `json:"source"`
+	Evidence      string `json:"evidence"`
+	Probed        bool   `json:"probed"`
+	Verified      bool   `json:"verified"`
+	ProbeEvidence string `json:"probe_evidence"`
+	DiscoveredAt  string `json:"discovered_at"`
 }
 
 // DomainInfo represents domain information associated with an IP
@@ -84,9 +88,24 @@ type IPInfoResponse struct {
 	Domains      []DomainInfo `json:"domains"`
 	TotalDomains int          `json:"total_domains"`
 	ScanSessions []uint       `json:"scan_sessions"` // List of scan session IDs this IP was seen in
+	ReverseDNS   []string     `json:"reverse_dns"`   // PTR records for the IP
 
 	// Enhanced Shodan information
 	ShodanInfo *ShodanInfo `json:"shodan_info,omitempty"`
+
+	// Netblock is the registered IP range the IP is in, from RDAP
+	Netblock *NetblockInfo `json:"netblock,omitempty"`
+
+	// Asset is the imported asset the IP is, with its business context
+	Asset *models.Asset `json:"asset,omitempty"`
+
+	// History is the information the IP had each time it was enriched,
+	// newest first
+	History []IPInfoSnapshot `json:"history"`
+
+	// EnrichmentJobID is the job gathering fallback information for an IP
+	// without Shodan information, if one is queued or running
+	EnrichmentJobID *uint `json:"enrichment_job_id,omitempty"`
 }
 
 // ShodanInfo represents Shodan data for an IP address
@@ -107,73 +126,26 @@ type ShodanInfo struct {
 	Hostnames     []string `json:"hostnames,omitempty"`
 	ShodanDomains []string `json:"shodan_domains,omitempty"`
 	Vulns         []string `json:"vulns,omitempty"`
+	ReverseDNS    []string `json:"reverse_dns,omitempty"`
 	LastUpdate    string   `json:"last_update,omitempty"`
+	FirstSeen     string   `json:"first_seen,omitempty"`
 	UpdatedAt     string   `json:"updated_at,omitempty"`
 }
 
-// fetchIPAPIData fetches geolocation data from ip-api.com as fallback
-func (h *ApiHandler) fetchIPAPIData(ip string) (*IPAPIResponse, error) {
-	url := fmt.Sprintf("http://ip-api.com/json/%s?fields=status,message,country,countryCode,region,regionName,city,zip,lat,lon,timezone,isp,org,as,query", ip)
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch from IP-API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read IP-API response: %w", err)
-	}
-
-	var ipApiResp IPAPIResponse
-	if err := json.Unmarshal(body, &ipApiResp); err != nil {
-		return nil, fmt.Errorf("failed to parse IP-API response: %w", err)
-	}
-
-	if ipApiResp.Status == "fail" {
-		return nil, fmt.Errorf("IP-API error: %s", ipApiResp.Message)
-	}
-
-	return &ipApiResp, nil
-}
-
-// runNaabuScan runs naabu port scanner for the given IP
-func (h *ApiHandler) runNaabuScan(ip string) ([]int, error) {
-	// Check if naabu is available
-	if _, err := exec.LookPath("naabu"); err != nil {
-		return nil, fmt.Errorf("naabu not found: %w", err)
+// runIPEnrichJob gathers fallback information for an IP address without
+// Shodan information, from IP-API and a naabu port scan
+func (h *ApiHandler) runIPEnrichJob(ctx context.Context, job *jobs.Job) error {
+	var payload jobs.IPEnrich
+	if err := job.Decode(&payload); err != nil {
+		return err
 	}
 
-	// Run naabu with top 100 ports and JSON output
-	cmd := exec.Command("naabu", "-host", ip, "-top-ports", "100", "-json", "-silent")
-	output, err := cmd.Output()
+	ipApiData, ports, err := fallback.Gather(ctx, payload.IPAddress, payload.ScanPorts)
 	if err != nil {
-		return nil, fmt.Errorf("naabu execution failed: %w", err)
+		return err
 	}
 
-	// Parse naabu output (JSON lines)
-	ports := []int{}
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		var result NaabuResult
-		if err := json.Unmarshal([]byte(line), &result); err != nil {
-			log.Warn("failed to parse naabu line", "line", line, "err", err)
-			continue
-		}
-
-		if result.IP == ip {
-			ports = append(ports, result.Port)
-		}
-	}
-
-	return ports, nil
+	return h.storeFallbackIPData(payload.IPAddress, ipApiData, ports)
 }
 
 // isValidIPAddress checks if the given string is a valid IP address
@@ -182,7 +154,7 @@ func isValidIPAddress(ip string) bool {
 }
 
 // storeFallbackIPData stores IP information gathered from fallback sources
-func (h *ApiHandler) storeFallbackIPData(ipAddress string, ipApiData *IPAPIResponse, ports []int) error {
+func (h *ApiHandler) storeFallbackIPData(ipAddress string, ipApiData *fallback.IPAPIResponse, ports []int) error {
 	// Check if IP info already exists
 	var existingIPInfo models.IPInfo
 	if err := h.DB.Where("ip_address = ?", ipAddress).First(&existingIPInfo).Error; err == nil {
@@ -207,6 +179,7 @@ func (h *ApiHandler) storeFallbackIPData(ipAddress string, ipApiData *IPAPIRespo
 		Latitude:     ipApiData.Lat,
 		Longitude:    ipApiData.Lon,
 		LastUpdate:   time.Now(),
+		FirstSeen:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
@@ -217,10 +190,20 @@ func (h *ApiHandler) storeFallbackIPData(ipAddress string, ipApiData *IPAPIRespo
 		}
 	}
 
+	// Add reverse DNS names
+	if names, err := islazy.ReverseLookup(ipAddress); err == nil {
+		if err := ipInfo.SetReverseDNS(names); err != nil {
+			log.Warn("failed to set reverse dns for IP info", "ip", ipAddress, "err", err)
+		}
+	}
+
 	// Save to database
 	if err := h.DB.Create(&ipInfo).Error; err != nil {
 		return fmt.Errorf("failed to save fallback IP info: %w", err)
 	}
+	if err := h.DB.Create(models.NewIPInfoHistory(&ipInfo, "ip-api+naabu")).Error; err != nil {
+		log.Warn("failed to save IP info history", "ip", ipAddress, "err", err)
+	}
 
 	log.Info("stored fallback IP data", "ip", ipAddress, "source", "ip-api+naabu")
 	return nil
@@ -229,17 +212,33 @@ func (h *ApiHandler) storeFallbackIPData(ipAddress string, ipApiData *IPAPIRespo
 // IPInfoHandler handles IP information requests
 //
 //	@Summary		Get information about an IP address
-//	@Description	Returns comprehensive information about an IP address including open ports and associated domains
+//	@Description	Returns comprehensive information about an IP address including open ports, associated domains and the history of its information. IP addresses without Shodan information get a background job gathering fallback information queued.
 //	@Tags			IP Information
 //	@Accept			json
 //	@Produce		json
 //	@Param			ip	path		string	true	"The IP address to get information for"
+//	@Param			tz		query		string	false	"IANA timezone to display times in. Defaults to UTC."
+//	@Param			session	query		int		false	"Only include ports and domains of this scan session."
 //	@Success		200	{object}	IPInfoResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
 //	@Router			/ip/{ip} [get]
 func (h *ApiHandler) IPInfoHandler(w http.ResponseWriter, r *http.Request) {
 	ipAddress := chi.URLParam(r, "ip")
 	if ipAddress == "" {
-		http.Error(w, "IP address parameter is required", http.StatusBadRequest)
+		writeError(w, "IP address parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	loc, err := displayLocation(r, time.UTC)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -248,12 +247,65 @@ func (h *ApiHandler) IPInfoHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Get open ports for this IP
 	var ipPorts []models.IPPort
-	if err := h.DB.Where("ip_address = ?", ipAddress).Find(&ipPorts).Error; err != nil {
+	if err := inSession(h.DB, session).Where("ip_address = ?", ipAddress).Find(&ipPorts).Error; err != nil {
 		log.Error("failed to get IP ports", "err", err, "ip", ipAddress)
-		http.Error(w, "Error retrieving port information", http.StatusInternalServerError)
+		writeError(w, "Error retrieving port information", http.StatusInternalServerError)
+		return
+	}
+
+	// Get origin candidates of CDN fronted ports
+	var portIDs []uint
+	for _, port := range ipPorts {
+		if port.IsCDN {
+			portIDs = append(portIDs, port.ID)
+		}
+	}
+
+	origins := make(map[uint][]OriginCandidateInfo)
+	if len(portIDs) > 0 {
+		var candidates []models.OriginCandidate
+		if err := h.DB.Where("ip_port_id IN ?", portIDs).
+			Order("verified DESC, ip_address").Find(&candidates).Error; err != nil {
+			log.Error("failed to get origin candidates", "err", err, "ip", ipAddress)
+			writeError(w, "Error retrieving origin candidates", http.StatusInternalServerError)
+			return
+		}
+
+		for _, candidate := range candidates {
+			origins[candidate.IPPortID] = append(origins[candidate.IPPortID], OriginCandidateInfo{
+				IPAddress:     candidate.IPAddress,
+				Source:        candidate.Source,
+				Evidence:      candidate.Evidence,
+				Probed:        candidate.Probed,
+				Verified:      candidate.Verified,
+				ProbeEvidence: candidate.ProbeEvidence,
+				DiscoveredAt:  formatTime(candidate.DiscoveredAt, loc),
+			})
+		}
+	}
+
+	// Get screenshots of services on the ports
+	var screenshots []models.ServiceScreenshot
+	if err := h.DB.Where("ip_address = ?", ipAddress).Find(&screenshots).Error; err != nil {
+		log.Error("failed to get service screenshots", "err", err, "ip", ipAddress)
+		writeError(w, "Error retrieving service screenshots", http.StatusInternalServerError)
 		return
 	}
 
+	serviceScreenshots := make(map[uint]*ServiceScreenshotInfo)
+	for _, screenshot := range screenshots {
+		serviceScreenshots[screenshot.IPPortID] = &ServiceScreenshotInfo{
+			Protocol:     screenshot.Protocol,
+			Filename:     screenshot.Filename,
+			Width:        screenshot.Width,
+			Height:       screenshot.Height,
+			Name:         screenshot.Name,
+			Failed:       screenshot.Failed,
+			FailedReason: screenshot.FailedReason,
+			CapturedAt:   formatTime(screenshot.CapturedAt, loc),
+		}
+	}
+
 	// Convert to response format
 	response.OpenPorts = make([]IPPortInfo, len(ipPorts))
 	scanSessionSet := make(map[uint]bool)
@@ -267,11 +319,14 @@ func (h *ApiHandler) IPInfoHandler(w http.ResponseWriter, r *http.Request) {
 			State:         port.State,
 			Banner:        port.Banner,
 			ScanSessionID: port.ScanSessionID,
-			DiscoveredAt:  port.DiscoveredAt.Format("2006-01-02 15:04:05"),
+			DiscoveredAt:  formatTime(port.DiscoveredAt, loc),
 			IsCDN:         port.IsCDN,
 			CDNName:       port.CDNName,
 			CDNDetected:   port.CDNDetected,
 			OriginalHost:  port.OriginalHost,
+
+			OriginCandidates: origins[port.ID],
+			Screenshot:       serviceScreenshots[port.ID],
 		}
 
 		// Track scan sessions
@@ -283,9 +338,9 @@ func (h *ApiHandler) IPInfoHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Get domains associated with this IP
 	var domains []models.Result
-	if err := h.DB.Where("ip_address = ?", ipAddress).Find(&domains).Error; err != nil {
+	if err := inSession(h.DB, session).Where("ip_address = ?", ipAddress).Find(&domains).Error; err != nil {
 		log.Error("failed to get domains for IP", "err", err, "ip", ipAddress)
-		http.Error(w, "Error retrieving domain information", http.StatusInternalServerError)
+		writeError(w, "Error retrieving domain information", http.StatusInternalServerError)
 		return
 	}
 
@@ -304,7 +359,7 @@ func (h *ApiHandler) IPInfoHandler(w http.ResponseWriter, r *http.Request) {
 			Filename:       domain.Filename,
 			Failed:         domain.Failed,
 			FailedReason:   domain.FailedReason,
-			ProbedAt:       domain.ProbedAt.Format("2006-01-02 15:04:05"),
+			ProbedAt:       formatTime(domain.ProbedAt, loc),
 			ScanSessionID:  domain.ScanSessionID,
 		}
 
@@ -340,43 +395,19 @@ func (h *ApiHandler) IPInfoHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// If we need fallback data, try to gather it
-	if needsFallback {
-		log.Info("attempting fallback IP intelligence gathering", "ip", ipAddress)
-
-		// Validate IP address
-		if !isValidIPAddress(ipAddress) {
-			log.Warn("invalid IP address for fallback lookup", "ip", ipAddress)
+	// gathering fallback data is slow, so it is done by a background job.
+	// the information shows up once the job is done.
+	if needsFallback && isValidIPAddress(ipAddress) {
+		// only scan for open ports if none are known
+		var portCount int64
+		h.DB.Model(&models.IPPort{}).Where("ip_address = ?", ipAddress).Count(&portCount)
+
+		job, err := h.Jobs.Enqueue(jobs.KindIPEnrich, jobs.IPEnrich{IPAddress: ipAddress, ScanPorts: portCount == 0},
+			jobs.Options{Priority: jobPriorityIPEnrich, MaxAttempts: 1, Unique: true})
+		if err != nil {
+			log.Warn("failed to queue fallback IP intelligence gathering", "ip", ipAddress, "err", err)
 		} else {
-			// Try IP-API for geolocation
-			ipApiData, err := h.fetchIPAPIData(ipAddress)
-			if err != nil {
-				log.Warn("failed to fetch IP-API data", "ip", ipAddress, "err", err)
-			}
-
-			// Try naabu for port scanning (only if no ports already exist)
-			var ports []int
-			var existingPorts []models.IPPort
-			if err := h.DB.Where("ip_address = ?", ipAddress).Find(&existingPorts).Error; err == nil && len(existingPorts) == 0 {
-				if scanPorts, err := h.runNaabuScan(ipAddress); err != nil {
-					log.Warn("failed to run naabu scan", "ip", ipAddress, "err", err)
-				} else {
-					ports = scanPorts
-					log.Info("naabu scan completed", "ip", ipAddress, "ports_found", len(ports))
-				}
-			}
-
-			// Store fallback data if we got any
-			if ipApiData != nil {
-				if err := h.storeFallbackIPData(ipAddress, ipApiData, ports); err != nil {
-					log.Error("failed to store fallback IP data", "ip", ipAddress, "err", err)
-				} else {
-					// Re-fetch the newly stored data
-					if err := h.DB.Where("ip_address = ?", ipAddress).First(&ipInfo).Error; err != nil {
-						log.Warn("failed to re-fetch stored IP info", "err", err, "ip", ipAddress)
-					}
-				}
-			}
+			response.EnrichmentJobID = &job.ID
 		}
 	}
 
@@ -394,8 +425,9 @@ func (h *ApiHandler) IPInfoHandler(w http.ResponseWriter, r *http.Request) {
 			Latitude:     ipInfo.Latitude,
 			Longitude:    ipInfo.Longitude,
 			OS:           ipInfo.OS,
-			LastUpdate:   ipInfo.LastUpdate.Format("2006-01-02 15:04:05"),
-			UpdatedAt:    ipInfo.UpdatedAt.Format("2006-01-02 15:04:05"),
+			LastUpdate:   formatTime(ipInfo.LastUpdate, loc),
+			FirstSeen:    formatTime(ipInfo.FirstSeen, loc),
+			UpdatedAt:    formatTime(ipInfo.UpdatedAt, loc),
 		}
 
 		// Get array fields using helper methods
@@ -414,15 +446,53 @@ func (h *ApiHandler) IPInfoHandler(w http.ResponseWriter, r *http.Request) {
 		if vulns, err := ipInfo.GetVulns(); err == nil {
 			shodanInfo.Vulns = vulns
 		}
+		if names, err := ipInfo.GetReverseDNS(); err == nil {
+			shodanInfo.ReverseDNS = names
+			response.ReverseDNS = names
+		}
 
 		response.ShodanInfo = shodanInfo
 	}
 
+	// No stored PTR records (e.g. no Shodan data at all), so look them up live
+	if len(response.ReverseDNS) == 0 && isValidIPAddress(ipAddress) {
+		if names, err := islazy.ReverseLookup(ipAddress); err == nil {
+			response.ReverseDNS = names
+		}
+	}
+	if response.ReverseDNS == nil {
+		response.ReverseDNS = []string{}
+	}
+
+	netblock, err := ipNetblock(h.DB, ipAddress)
+	if err != nil {
+		// Log error but don't fail the request
+		log.Warn("failed to get netblock", "err", err, "ip", ipAddress)
+	} else if netblock != nil {
+		response.Netblock = netblockInfo(netblock, loc)
+	}
+
+	asset, err := assets.ForHost(h.DB, ipAddress, ipAddress)
+	if err != nil {
+		// Log error but don't fail the request
+		log.Warn("failed to get asset", "err", err, "ip", ipAddress)
+	}
+	response.Asset = asset
+
+	// The history shows when ports, organisations or vulns changed
+	history, err := ipInfoHistory(h.DB, ipAddress, loc)
+	if err != nil {
+		// Log error but don't fail the request
+		log.Warn("failed to get IP info history", "err", err, "ip", ipAddress)
+		history = []IPInfoSnapshot{}
+	}
+	response.History = history
+
 	// Return JSON response
 	jsonData, err := json.Marshal(response)
 	if err != nil {
 		log.Error("failed to marshal IP info response", "err", err)
-		http.Error(w, "Error creating JSON response", http.StatusInternalServerError)
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
 		return
 	}
 
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/pkg/jobs"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/runner"
+	driver "github.com/sensepost/gowitness/pkg/runner/drivers"
+	"github.com/sensepost/gowitness/pkg/writers"
+	"gorm.io/gorm"
+)
+
+// Job priorities. Jobs someone is waiting on go first.
+const (
+	jobPriorityProbe    = 0
+	jobPriorityIPEnrich = 5
+	jobPriorityRetake   = 10
+)
+
+const (
+	// jobWorkers is the number of jobs the API runs at once
+	jobWorkers = 2
+	// jobMaxThreads is the most threads a job may probe with
+	jobMaxThreads = 64
+	// jobListLimit is the most jobs listed
+	jobListLimit = 100
+)
+
+type submitJobRequest struct {
+	URLs    []string              `json:"urls"`
+	Options *submitRequestOptions `json:"options"`
+	// Threads is the number of URLs probed at once, defaulting to the
+	// runner default
+	Threads int `json:"threads"`
+	// RateLimit is the most URLs to start probing per second, 0 for no limit
+	RateLimit int `json:"rate_limit"`
+	// Priority orders queued jobs, highest first
+	Priority int `json:"priority"`
+}
+
+type jobResponse struct {
+	ID          uint              `json:"id"`
+	Kind        string            `json:"kind"`
+	Status      string            `json:"status"`
+	Priority    int               `json:"priority"`
+	Attempts    int               `json:"attempts"`
+	MaxAttempts int               `json:"max_attempts"`
+	Total       int               `json:"total"`
+	Completed   int               `json:"completed"`
+	Failed      int               `json:"failed"`
+	Progress    float64           `json:"progress"` // percentage of items done
+	Errors      []models.JobError `json:"errors"`
+	Error       string            `json:"error,omitempty"`
+	Worker      string            `json:"worker,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	RunAt       time.Time         `json:"run_at"`
+	StartedAt   *time.Time        `json:"started_at,omitempty"`
+	HeartbeatAt *time.Time        `json:"heartbeat_at,omitempty"`
+	FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+}
+
+// StartJobs registers the API's job handlers and starts its job queue.
+// Jobs of remoteKinds are left to agents.
+func (h *ApiHandler) StartJobs(remoteKinds []string) error {
+	h.Jobs.Register(jobs.KindProbe, h.runProbeJob)
+	h.Jobs.Register(jobs.KindRetake, h.runRetakeJob)
+	h.Jobs.Register(jobs.KindIPEnrich, h.runIPEnrichJob)
+
+	for _, kind := range remoteKinds {
+		if !slices.Contains(jobs.Kinds, kind) {
+			return fmt.Errorf("unknown job kind %q", kind)
+		}
+		h.Jobs.RegisterRemote(kind)
+	}
+
+	return h.Jobs.Start(context.Background(), jobWorkers)
+}
+
+// SubmitJobHandler submits URL's as a job, probing them in the background.
+//
+//	@Summary		Submit a probing job
+//	@Description	Queues a job probing a list of URL's in the background, writing results to the database. The returned job ID can be polled for progress and errors. Threads, the rate limit and priority are set per job.
+//	@Tags			Jobs
+//	@Accept			json
+//	@Produce		json
+//	@Param			query	body		submitJobRequest	true	"The job request object"
+//	@Success		200		{object}	jobResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/jobs [post]
+func (h *ApiHandler) SubmitJobHandler(w http.ResponseWriter, r *http.Request) {
+	var request submitJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if len(request.URLs) == 0 {
+		writeError(w, "No URLs provided", http.StatusBadRequest)
+		return
+	}
+	if request.Threads < 0 || request.Threads > jobMaxThreads {
+		writeError(w, fmt.Sprintf("Threads must be between 1 and %d", jobMaxThreads), http.StatusBadRequest)
+		return
+	}
+	if request.RateLimit < 0 {
+		writeError(w, "Invalid rate_limit", http.StatusBadRequest)
+		return
+	}
+
+	options := runner.NewDefaultOptions()
+	options.Scan.ScreenshotPath = h.ScreenshotPath
+	applySubmitOptions(options, request.Options)
+	if request.Threads > 0 {
+		options.Scan.Threads = request.Threads
+	}
+
+	job, err := h.Jobs.Enqueue(jobs.KindProbe, jobs.Probe{
+		URLs:      request.URLs,
+		Options:   *options,
+		RateLimit: request.RateLimit,
+	}, jobs.Options{Priority: request.Priority})
+	if err != nil {
+		log.Error("failed to queue job", "err", err)
+		writeError(w, "Error queueing job", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(newJobResponse(job))
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// JobsHandler lists jobs
+//
+//	@Summary		List jobs
+//	@Description	Lists the most recent jobs, newest first.
+//	@Tags			Jobs
+//	@Produce		json
+//	@Param			kind	query		string	false	"Only list jobs of this kind, such as probe, retake or ip-enrich."
+//	@Param			status	query		string	false	"Only list jobs with this status, such as queued, running, completed or failed."
+//	@Success		200		{array}		jobResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/jobs [get]
+func (h *ApiHandler) JobsHandler(w http.ResponseWriter, r *http.Request) {
+	query := h.DB.Model(&models.Job{})
+	for _, column := range []string{"kind", "status"} {
+		if value := r.URL.Query().Get(column); value != "" {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+
+	var list []models.Job
+	if err := query.Order("id DESC").Limit(jobListLimit).Find(&list).Error; err != nil {
+		log.Error("failed to list jobs", "err", err)
+		writeError(w, "Error listing jobs", http.StatusInternalServerError)
+		return
+	}
+
+	response := []jobResponse{}
+	for i := range list {
+		response = append(response, newJobResponse(&list[i]))
+	}
+
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// JobHandler returns the progress of a job
+//
+//	@Summary		Job progress
+//	@Description	Get the status, progress and errors of a job.
+//	@Tags			Jobs
+//	@Produce		json
+//	@Param			id	path		int	true	"The job ID."
+//	@Success		200	{object}	jobResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
+//	@Router			/jobs/{id} [get]
+func (h *ApiHandler) JobHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		writeError(w, "Invalid job id", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.Jobs.Get(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		log.Error("failed to get job", "id", id, "err", err)
+		writeError(w, "Error retrieving job", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(newJobResponse(job))
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// newJobResponse converts a job to its API response
+func newJobResponse(job *models.Job) jobResponse {
+	jobErrors, _ := job.GetErrors()
+
+	var progress float64
+	if job.Total > 0 {
+		progress = min(100, float64(job.Completed+job.Failed)/float64(job.Total)*100)
+	}
+
+	return jobResponse{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Status:      job.Status,
+		Priority:    job.Priority,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		Total:       job.Total,
+		Completed:   job.Completed,
+		Failed:      job.Failed,
+		Progress:    progress,
+		Errors:      jobErrors,
+		Error:       job.Error,
+		Worker:      job.Worker,
+		CreatedAt:   job.CreatedAt,
+		RunAt:       job.RunAt,
+		StartedAt:   job.StartedAt,
+		HeartbeatAt: job.HeartbeatAt,
+		FinishedAt:  job.FinishedAt,
+	}
+}
+
+// runProbeJob probes the URLs of a probe job, writing results to the
+// database
+func (h *ApiHandler) runProbeJob(ctx context.Context, job *jobs.Job) error {
+	var payload jobs.Probe
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	job.SetTotal(len(payload.URLs))
+
+	writer, err := writers.NewDbWriter(h.DbURI, false)
+	if err != nil {
+		return fmt.Errorf("failed to connect to db for writer: %w", err)
+	}
+
+	logger := slog.New(log.Logger)
+
+	driver, err := driver.NewChromedp(logger, payload.Options)
+	if err != nil {
+		return fmt.Errorf("failed to start driver: %w", err)
+	}
+
+	// the job comes last, so results are only counted once written
+	return runner.Probe(ctx, logger, driver, payload.Options, payload.URLs, payload.RateLimit,
+		[]writers.Writer{writer, job}, job.Fail)
+}
@@ -22,6 +22,9 @@ type listResponse struct {
 	// Failed flag set if the result should be considered failed
 	Failed       bool   `json:"failed"`
 	FailedReason string `json:"failed_reason"`
+
+	SecurityScore int    `json:"security_score"`
+	SecurityGrade string `json:"security_grade"`
 }
 
 // ListHandler returns a simple list of results
@@ -31,19 +34,29 @@ type listResponse struct {
 //	@Tags			Results
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	listResponse
+//	@Param			session	query		int	false	"Only list results of this scan session."
+//	@Success		200		{object}	listResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
 //	@Router			/results/list [get]
 func (h *ApiHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
 	var results = []*listResponse{}
 
-	if err := h.DB.Model(&models.Result{}).Find(&results).Error; err != nil {
+	session, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := inSession(h.DB.Model(&models.Result{}), session).Find(&results).Error; err != nil {
 		log.Error("could not get list", "err", err)
+		writeError(w, "Error retrieving results", http.StatusInternalServerError)
 		return
 	}
 
 	jsonData, err := json.Marshal(results)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
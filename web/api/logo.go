@@ -5,47 +5,93 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/pkg/database"
 	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
 )
 
 // LogoHandler returns the company logo if available
 //
 //	@Summary		Get company logo
-//	@Description	Get the company logo for the current scan session
+//	@Description	Get the company logo for the most recent scan session
 //	@Tags			Results
 //	@Produce		png
 //	@Produce		jpeg
 //	@Success		200	{file}		binary
-//	@Failure		404	{string}	string	"Logo not found"
+//	@Failure		404	{object}	ErrorResponse
 //	@Router			/logo [get]
 func (h *ApiHandler) LogoHandler(w http.ResponseWriter, r *http.Request) {
-	// The screenshot path is typically targets/<target>/screenshots/
-	// We need to go up one level to find the logo in targets/<target>/
-	targetDir := filepath.Dir(h.ScreenshotPath)
+	var session models.ScanSession
+	if err := h.DB.Order("start_time DESC").First(&session).Error; err != nil {
+		// no sessions, so the best we can do is the server's screenshot path
+		h.serveLogo(w, r, "", h.ScreenshotPath)
+		return
+	}
 
-	// List of possible logo filenames to check
-	possibleLogos := []string{
-		filepath.Join(targetDir, "logo.png"),
-		filepath.Join(targetDir, "logo.jpg"),
-		filepath.Join(targetDir, "logo.jpeg"),
-		filepath.Join(targetDir, "logo.svg"),
+	h.serveLogo(w, r, session.LogoPath, database.ScreenshotPath(h.DB, &session.ID, h.ScreenshotPath))
+}
+
+// ScanSessionLogoHandler returns the company logo of a scan session
+//
+//	@Summary		Get scan session logo
+//	@Description	Get the company logo for a scan session, by id
+//	@Tags			Scan Sessions
+//	@Produce		png
+//	@Produce		jpeg
+//	@Param			id	path		int	true	"The scan session ID."
+//	@Success		200	{file}		binary
+//	@Failure		404	{object}	ErrorResponse
+//	@Router			/scan-sessions/{id}/logo [get]
+func (h *ApiHandler) ScanSessionLogoHandler(w http.ResponseWriter, r *http.Request) {
+	var session models.ScanSession
+	if err := h.DB.First(&session, chi.URLParam(r, "id")).Error; err != nil {
+		writeError(w, "Scan session not found", http.StatusNotFound)
+		return
 	}
 
-	var logoPath string
-	var found bool
+	h.serveLogo(w, r, session.LogoPath, database.ScreenshotPath(h.DB, &session.ID, h.ScreenshotPath))
+}
 
-	// Check each possible logo file
-	for _, path := range possibleLogos {
-		if _, err := os.Stat(path); err == nil {
-			logoPath = path
+// serveLogo serves logoPath if it exists, otherwise it looks for a logo in
+// the target directory of screenshotPath.
+func (h *ApiHandler) serveLogo(w http.ResponseWriter, r *http.Request, logoPath string, screenshotPath string) {
+	found := false
+	if logoPath != "" {
+		if _, err := os.Stat(logoPath); err == nil {
 			found = true
-			break
 		}
 	}
 
 	if !found {
-		log.Debug("no logo file found in target directory", "target_dir", targetDir)
-		http.Error(w, "Logo file not found", http.StatusNotFound)
+		// The screenshot path is typically targets/<target>/screenshots/
+		// We need to go up one level to find the logo in targets/<target>/
+		targetDir := filepath.Dir(screenshotPath)
+
+		// List of possible logo filenames to check
+		possibleLogos := []string{
+			filepath.Join(targetDir, "logo.png"),
+			filepath.Join(targetDir, "logo.jpg"),
+			filepath.Join(targetDir, "logo.jpeg"),
+			filepath.Join(targetDir, "logo.svg"),
+		}
+
+		// Check each possible logo file
+		for _, path := range possibleLogos {
+			if _, err := os.Stat(path); err == nil {
+				logoPath = path
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			log.Debug("no logo file found in target directory", "target_dir", targetDir)
+		}
+	}
+
+	if !found {
+		writeError(w, "Logo file not found", http.StatusNotFound)
 		return
 	}
 
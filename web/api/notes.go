@@ -0,0 +1,289 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/pkg/auth"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// maxNoteLength is the longest note body analysts can write
+const maxNoteLength = 64 << 10
+
+type createNoteRequest struct {
+	// Exactly one of these is set
+	ResultID      *uint `json:"result_id,omitempty"`
+	IPInfoID      *uint `json:"ip_info_id,omitempty"`
+	ScanSessionID *uint `json:"scan_session_id,omitempty"`
+
+	Body string `json:"body"`
+}
+
+type updateNoteRequest struct {
+	Body string `json:"body"`
+}
+
+// NotesHandler lists notes
+//
+//	@Summary		List notes
+//	@Description	Lists the notes on a result, an IP address or a scan session, oldest first. Without a filter, all notes are listed.
+//	@Tags			Notes
+//	@Produce		json
+//	@Param			result_id		query	int	false	"Only list notes on this result."
+//	@Param			ip_info_id		query	int	false	"Only list notes on this IP address."
+//	@Param			scan_session_id	query	int	false	"Only list notes on this scan session."
+//	@Success		200				{array}		models.Note
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/notes [get]
+func (h *ApiHandler) NotesHandler(w http.ResponseWriter, r *http.Request) {
+	query := h.DB.Model(&models.Note{})
+	for _, column := range []string{"result_id", "ip_info_id", "scan_session_id"} {
+		raw := r.URL.Query().Get(column)
+		if raw == "" {
+			continue
+		}
+
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			writeError(w, "Invalid "+column, http.StatusBadRequest)
+			return
+		}
+		query = query.Where(column+" = ?", id)
+	}
+
+	var notes []models.Note
+	if err := query.Order("created_at").Find(&notes).Error; err != nil {
+		log.Error("failed to list notes", "err", err)
+		writeError(w, "Error listing notes", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(notes)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// CreateNoteHandler adds a note
+//
+//	@Summary		Add a note
+//	@Description	Adds a note to a result, an IP address or a scan session. The note's author is the logged in user.
+//	@Tags			Notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			query	body		createNoteRequest	true	"The note to add"
+//	@Success		200		{object}	models.Note
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/notes [post]
+func (h *ApiHandler) CreateNoteHandler(w http.ResponseWriter, r *http.Request) {
+	var request createNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+
+	body, ok := noteBody(w, request.Body)
+	if !ok {
+		return
+	}
+
+	// find what the note is on, making sure it exists
+	var target any
+	var targetID uint
+	set := 0
+	if request.ResultID != nil {
+		target, targetID = &models.Result{}, *request.ResultID
+		set++
+	}
+	if request.IPInfoID != nil {
+		target, targetID = &models.IPInfo{}, *request.IPInfoID
+		set++
+	}
+	if request.ScanSessionID != nil {
+		target, targetID = &models.ScanSession{}, *request.ScanSessionID
+		set++
+	}
+	if set != 1 {
+		writeError(w, "Set exactly one of result_id, ip_info_id and scan_session_id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Select("id").First(target, targetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, "Nothing to add a note to found", http.StatusNotFound)
+			return
+		}
+		log.Error("failed to look up note target", "err", err)
+		writeError(w, "Error adding note", http.StatusInternalServerError)
+		return
+	}
+
+	note := models.Note{
+		ResultID:      request.ResultID,
+		IPInfoID:      request.IPInfoID,
+		ScanSessionID: request.ScanSessionID,
+		Author:        noteAuthor(r),
+		Body:          body,
+	}
+	if err := h.DB.Create(&note).Error; err != nil {
+		log.Error("failed to add note", "err", err)
+		writeError(w, "Error adding note", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(note)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// UpdateNoteHandler edits a note
+//
+//	@Summary		Edit a note
+//	@Description	Replaces the body of a note. With per-user logins, only the note's author and admins can edit it.
+//	@Tags			Notes
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int					true	"The note ID"
+//	@Param			query	body		updateNoteRequest	true	"The new note body"
+//	@Success		200		{object}	models.Note
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/notes/{id} [put]
+func (h *ApiHandler) UpdateNoteHandler(w http.ResponseWriter, r *http.Request) {
+	note, ok := h.editableNote(w, r)
+	if !ok {
+		return
+	}
+
+	var request updateNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+
+	body, ok := noteBody(w, request.Body)
+	if !ok {
+		return
+	}
+
+	note.Body = body
+	if err := h.DB.Save(note).Error; err != nil {
+		log.Error("failed to edit note", "id", note.ID, "err", err)
+		writeError(w, "Error editing note", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(note)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// DeleteNoteHandler deletes a note
+//
+//	@Summary		Delete a note
+//	@Description	Deletes a note. With per-user logins, only the note's author and admins can delete it.
+//	@Tags			Notes
+//	@Produce		json
+//	@Param			id	path		int		true	"The note ID"
+//	@Success		200	{string}	string	"ok"
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
+//	@Router			/notes/{id} [delete]
+func (h *ApiHandler) DeleteNoteHandler(w http.ResponseWriter, r *http.Request) {
+	note, ok := h.editableNote(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.DB.Delete(note).Error; err != nil {
+		log.Error("failed to delete note", "id", note.ID, "err", err)
+		writeError(w, "Error deleting note", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(`ok`)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// editableNote returns the note a request is for, writing an error response
+// if it does not exist or the logged in user may not change it
+func (h *ApiHandler) editableNote(w http.ResponseWriter, r *http.Request) (*models.Note, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		writeError(w, "Invalid note id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var note models.Note
+	if err := h.DB.First(&note, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, "Note not found", http.StatusNotFound)
+			return nil, false
+		}
+		log.Error("failed to get note", "id", id, "err", err)
+		writeError(w, "Error retrieving note", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if user := RequestUser(r); user != nil && user.Role != auth.RoleAdmin && user.Username != note.Author {
+		writeError(w, "Only the author of a note can change it", http.StatusForbidden)
+		return nil, false
+	}
+
+	return &note, true
+}
+
+// noteBody returns a trimmed note body, writing an error response if it is
+// empty or too long
+func noteBody(w http.ResponseWriter, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		writeError(w, "Notes can't be empty", http.StatusBadRequest)
+		return "", false
+	}
+	if len(body) > maxNoteLength {
+		writeError(w, "Notes can't be longer than 64KB", http.StatusBadRequest)
+		return "", false
+	}
+
+	return body, true
+}
+
+// noteAuthor returns the author of notes written in a request
+func noteAuthor(r *http.Request) string {
+	if user := RequestUser(r); user != nil {
+		return user.Username
+	}
+
+	return anonymousAnalyst
+}
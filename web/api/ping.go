@@ -13,13 +13,14 @@ import (
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{string}	string	"pong"
-//	@Router			/api/ping [get]
+//	@Failure		500	{object}	ErrorResponse
+//	@Router			/ping [get]
 func (h *ApiHandler) PingHandler(w http.ResponseWriter, r *http.Request) {
 	response := `pong`
 
 	jsonData, err := json.Marshal(response)
 	if err != nil {
-		http.Error(w, "Error creating JSON response", http.StatusInternalServerError)
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
 		return
 	}
 
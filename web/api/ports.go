@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+type portsResponse struct {
+	Ports      []models.IPPort `json:"ports"`
+	Page       int             `json:"page"`
+	Limit      int             `json:"limit"`
+	TotalCount int64           `json:"total_count"`
+}
+
+// PortsHandler returns a paginated list of discovered open ports
+//
+//	@Summary		Discovered ports
+//	@Description	Get a paginated list of open ports discovered by port scans and Shodan enrichment.
+//	@Tags			IP Information
+//	@Accept			json
+//	@Produce		json
+//	@Param			page			query		int		false	"The page to load."
+//	@Param			limit			query		int		false	"Number of ports per page."
+//	@Param			ip				query		string	false	"Only return ports for this IP address."
+//	@Param			port			query		int		false	"Only return this port number."
+//	@Param			session			query		int		false	"Only return ports from this scan session."
+//	@Success		200				{object}	portsResponse
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/ports [get]
+func (h *ApiHandler) PortsHandler(w http.ResponseWriter, r *http.Request) {
+	var response = &portsResponse{
+		Page:  1,
+		Limit: 100,
+	}
+
+	// pagination
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		response.Page = p
+	}
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		response.Limit = l
+	}
+
+	query := h.DB.Model(&models.IPPort{})
+
+	if ip := r.URL.Query().Get("ip"); ip != "" {
+		query = query.Where("ip_address = ?", ip)
+	}
+	if raw := r.URL.Query().Get("port"); raw != "" {
+		port, err := strconv.Atoi(raw)
+		if err != nil {
+			writeError(w, "Invalid port", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("port = ?", port)
+	}
+	session, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query = inSession(query, session)
+
+	// the filtered query is used for both the count and the page
+	query = query.Session(&gorm.Session{})
+
+	if err := query.Count(&response.TotalCount).Error; err != nil {
+		log.Error("could not count ports", "err", err)
+		writeError(w, "Error counting ports", http.StatusInternalServerError)
+		return
+	}
+
+	if err := query.Order("ip_address, port").
+		Limit(response.Limit).Offset((response.Page - 1) * response.Limit).
+		Find(&response.Ports).Error; err != nil {
+		log.Error("could not get ports", "err", err)
+		writeError(w, "Error retrieving ports", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
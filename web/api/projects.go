@@ -0,0 +1,243 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// validProjectName matches the target names `scan init` accepts, which keeps
+// project names from escaping the projects directory
+var validProjectName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// errProjectNotFound is returned for projects without a database
+var errProjectNotFound = errors.New("project not found")
+
+// ProjectSummary is a summary of a project, for the landing page
+type ProjectSummary struct {
+	Name        string `json:"name"`
+	CompanyName string `json:"company_name,omitempty"`
+	MainDomain  string `json:"main_domain,omitempty"`
+	// Status is the status of the project's latest scan session
+	Status string `json:"status"`
+	// Phase is the phase that is running, or that failed or was interrupted
+	Phase string `json:"phase,omitempty"`
+
+	Domains         int64            `json:"domains"`
+	IPs             int64            `json:"ips"`
+	OpenPorts       int64            `json:"open_ports"`
+	Screenshots     int64            `json:"screenshots"`
+	Vulns           int64            `json:"vulns"` // findings more severe than info
+	VulnsBySeverity map[string]int64 `json:"vulns_by_severity"`
+	LastScanAt      string           `json:"last_scan_at,omitempty"`
+}
+
+// ProjectSummaryHandler summarises a project
+//
+//	@Summary		Project summary
+//	@Description	Summarises a project created with scan init: the domains found, IPs resolved, open ports, screenshots captured and vulnerabilities, when it was last scanned and which phase it is in.
+//	@Tags			Results
+//	@Produce		json
+//	@Param			name	path		string	true	"The project (target) name."
+//	@Param			tz		query		string	false	"IANA timezone to display times in. Defaults to the timezone of the project's scan session."
+//	@Success		200		{object}	ProjectSummary
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/projects/{name}/summary [get]
+func (h *ApiHandler) ProjectSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if !validProjectName.MatchString(name) {
+		writeError(w, "Invalid project name", http.StatusBadRequest)
+		return
+	}
+
+	db, err := h.projectDB(name)
+	if err != nil {
+		if errors.Is(err, errProjectNotFound) {
+			writeError(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		log.Error("failed to open project database", "project", name, "err", err)
+		writeError(w, "Error opening project database", http.StatusInternalServerError)
+		return
+	}
+
+	summary := &ProjectSummary{
+		Name:            name,
+		VulnsBySeverity: make(map[string]int64),
+	}
+
+	var sessions []models.ScanSession
+	if err := db.Order("start_time DESC").Limit(1).Find(&sessions).Error; err != nil {
+		log.Error("failed to get project scan session", "project", name, "err", err)
+		writeError(w, "Error retrieving scan session", http.StatusInternalServerError)
+		return
+	}
+	var session *models.ScanSession
+	if len(sessions) > 0 {
+		session = &sessions[0]
+	}
+
+	loc, err := displayLocation(r, sessionLocation(session))
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := projectCounts(db, summary); err != nil {
+		log.Error("failed to count project results", "project", name, "err", err)
+		writeError(w, "Error retrieving project counts", http.StatusInternalServerError)
+		return
+	}
+
+	summary.Status = "not started"
+	if session != nil {
+		summary.CompanyName = session.CompanyName
+		summary.MainDomain = session.MainDomain
+		summary.Status = session.Status
+		summary.Phase = projectPhase(filepath.Join(h.ProjectsPath, name), session)
+
+		if last, err := database.LastActivity(db, session); err == nil {
+			summary.LastScanAt = formatTime(last, loc)
+		} else {
+			log.Warn("failed to get last project activity", "project", name, "err", err)
+		}
+	}
+
+	jsonData, err := json.Marshal(summary)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// projectDB returns a connection to the database of a project. Connections
+// are kept for later requests, and the server's own database is reused if
+// it is the project's.
+func (h *ApiHandler) projectDB(name string) (*gorm.DB, error) {
+	path := filepath.Join(h.ProjectsPath, name, name+".sqlite3")
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errProjectNotFound
+		}
+		return nil, err
+	}
+
+	if sameFile(path, strings.TrimPrefix(h.DbURI, "sqlite://")) {
+		return h.DB, nil
+	}
+
+	h.projectMu.Lock()
+	defer h.projectMu.Unlock()
+
+	if db, ok := h.projectDBs[name]; ok {
+		return db, nil
+	}
+
+	db, err := database.Connection(fmt.Sprintf("sqlite://%s", path), true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.projectDBs == nil {
+		h.projectDBs = make(map[string]*gorm.DB)
+	}
+	h.projectDBs[name] = db
+
+	return db, nil
+}
+
+// projectCounts fills in the counters of a project summary
+func projectCounts(db *gorm.DB, summary *ProjectSummary) error {
+	if err := db.Model(&models.Domain{}).Distinct("name").Count(&summary.Domains).Error; err != nil {
+		return err
+	}
+
+	if err := db.Raw(`SELECT COUNT(*) FROM (
+		SELECT ip_address FROM results WHERE ip_address <> ''
+		UNION SELECT ip_address FROM ip_infos) ips`).Scan(&summary.IPs).Error; err != nil {
+		return err
+	}
+
+	if err := db.Model(&models.IPPort{}).Where("state = ?", "open").Count(&summary.OpenPorts).Error; err != nil {
+		return err
+	}
+
+	if err := db.Model(&models.Result{}).Where("failed = ? AND filename <> ''", false).
+		Count(&summary.Screenshots).Error; err != nil {
+		return err
+	}
+
+	var severities []struct {
+		Severity string
+		Count    int64
+	}
+	if err := db.Model(&models.Finding{}).Select("severity, COUNT(*) AS count").
+		Where("severity <> ?", "info").Group("severity").Scan(&severities).Error; err != nil {
+		return err
+	}
+	for _, severity := range severities {
+		summary.VulnsBySeverity[severity.Severity] = severity.Count
+		summary.Vulns += severity.Count
+	}
+
+	return nil
+}
+
+// projectPhase returns the phase a project's scan session is in. Running
+// sessions are in the phase `scan run` last started writing a log for,
+// failed and cancelled ones in the phase they stopped in.
+func projectPhase(dir string, session *models.ScanSession) string {
+	if session.Status != database.SessionActive {
+		return session.FailedPhase
+	}
+
+	logs, err := filepath.Glob(filepath.Join(dir, "logs", "*.log"))
+	if err != nil {
+		return ""
+	}
+
+	var phase string
+	var latest time.Time
+	for _, path := range logs {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Before(session.StartTime) {
+			continue
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+			phase = strings.TrimSuffix(filepath.Base(path), ".log")
+		}
+	}
+
+	return phase
+}
+
+// sameFile checks if two paths point to the same file
+func sameFile(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+)
+
+type purgeResultsRequest struct {
+	OlderThanDays int   `json:"older_than_days"`
+	FailedOnly    bool  `json:"failed_only"`
+	ScanSessionID *uint `json:"scan_session_id"`
+	DryRun        bool  `json:"dry_run"`
+}
+
+// PurgeResultsHandler bulk deletes results from the database
+//
+//	@Summary		Purge results
+//	@Description	Deletes all results matching the given filters, together with their associated data and screenshots. At least one filter is required.
+//	@Tags			Results
+//	@Accept			json
+//	@Produce		json
+//	@Param			query	body		purgeResultsRequest		true	"The filters results must match to be purged"
+//	@Success		200		{object}	database.PruneSummary
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/results/purge [post]
+func (h *ApiHandler) PurgeResultsHandler(w http.ResponseWriter, r *http.Request) {
+	var request purgeResultsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("failed to read json request", "err", err)
+		writeError(w, "Error reading JSON request", http.StatusInternalServerError)
+		return
+	}
+
+	if request.OlderThanDays < 0 {
+		writeError(w, "older_than_days must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	opts := database.PruneOptions{
+		OlderThan:      time.Duration(request.OlderThanDays) * 24 * time.Hour,
+		FailedOnly:     request.FailedOnly,
+		ScanSessionID:  request.ScanSessionID,
+		ScreenshotPath: h.ScreenshotPath,
+		DryRun:         request.DryRun,
+	}
+
+	log.Info("purging results", "older-than-days", request.OlderThanDays, "failed-only", request.FailedOnly,
+		"scan-session-id", request.ScanSessionID, "dry-run", request.DryRun)
+
+	summary, err := database.Prune(h.DB, opts)
+	if errors.Is(err, database.ErrNoPruneFilter) {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Error("failed to purge results", "err", err)
+		writeError(w, "Error purging results", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(summary)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
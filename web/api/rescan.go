@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/pkg/jobs"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/runner"
+	driver "github.com/sensepost/gowitness/pkg/runner/drivers"
+	"github.com/sensepost/gowitness/pkg/writers"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type rescanRequest struct {
+	Options *submitRequestOptions `json:"options"`
+}
+
+// RescanResultHandler retakes the screenshot of a result
+//
+//	@Summary		Retake a result
+//	@Description	Queues a retake of a result, probing its URL again for a fresh screenshot, and waits for it. The new result is linked to the old one. The request body is optional.
+//	@Tags			Results
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"The result ID to retake."
+//	@Param			query	body		rescanRequest	false	"Options for the probe"
+//	@Success		200		{object}	models.Result	"The new Result object"
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/results/{id}/rescan [post]
+func (h *ApiHandler) RescanResultHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		writeError(w, "Invalid result id", http.StatusBadRequest)
+		return
+	}
+
+	var request rescanRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+
+	var previous models.Result
+	if err := h.DB.Select("id", "url", "scan_session_id").First(&previous, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, "Result not found", http.StatusNotFound)
+			return
+		}
+		log.Error("failed to get result", "id", id, "err", err)
+		writeError(w, "Error retrieving result", http.StatusInternalServerError)
+		return
+	}
+
+	options := runner.NewDefaultOptions()
+	options.Scan.ScreenshotPath = h.ScreenshotPath
+	applySubmitOptions(options, request.Options)
+
+	queued, err := h.Jobs.Enqueue(jobs.KindRetake, jobs.Retake{ResultID: previous.ID, URL: previous.URL,
+		ScanSessionID: previous.ScanSessionID, Options: *options},
+		jobs.Options{Priority: jobPriorityRetake, MaxAttempts: 1, ScanSessionID: previous.ScanSessionID})
+	if err != nil {
+		log.Error("failed to queue retake", "id", id, "err", err)
+		writeError(w, "Error queueing retake", http.StatusInternalServerError)
+		return
+	}
+
+	job, err := h.Jobs.Wait(r.Context(), queued.ID)
+	if err != nil {
+		log.Error("failed to wait for retake", "id", id, "job", queued.ID, "err", err)
+		writeError(w, "Error waiting for retake", http.StatusInternalServerError)
+		return
+	}
+	if job.Status != models.JobCompleted {
+		writeError(w, "Error retaking result: "+job.Error, http.StatusInternalServerError)
+		return
+	}
+
+	var result models.Result
+	if err := h.DB.Preload(clause.Associations).Preload("TLS.SanList").
+		Where("previous_result_id = ?", previous.ID).Order("id DESC").First(&result).Error; err != nil {
+		log.Error("failed to get retaken result", "id", id, "err", err)
+		writeError(w, "Error retrieving retaken result", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// runRetakeJob retakes the screenshot of a result
+func (h *ApiHandler) runRetakeJob(ctx context.Context, job *jobs.Job) error {
+	var payload jobs.Retake
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	job.SetTotal(1)
+
+	writer, err := writers.NewDbWriter(h.DbURI, false)
+	if err != nil {
+		return fmt.Errorf("failed to connect to db for writer: %w", err)
+	}
+
+	logger := slog.New(log.Logger)
+
+	driver, err := driver.NewChromedp(logger, payload.Options)
+	if err != nil {
+		return fmt.Errorf("failed to start driver: %w", err)
+	}
+
+	previous := &models.Result{ID: payload.ResultID, URL: payload.URL, ScanSessionID: payload.ScanSessionID}
+	if _, err := runner.Retake(logger, driver, payload.Options, previous, []writers.Writer{writer, job}); err != nil {
+		return err
+	}
+
+	return nil
+}
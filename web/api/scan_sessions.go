@@ -3,59 +3,106 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
+	"github.com/sensepost/gowitness/pkg/database"
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/models"
 )
 
 // ScanSessionResponse represents scan session information
 type ScanSessionResponse struct {
-	ID          uint   `json:"id"`
-	CompanyName string `json:"company_name"`
-	MainDomain  string `json:"main_domain"`
-	StartTime   string `json:"start_time"`
-	EndTime     string `json:"end_time,omitempty"`
-	Status      string `json:"status"`
-	Notes       string `json:"notes"`
+	ID          uint     `json:"id"`
+	CompanyName string   `json:"company_name"`
+	MainDomain  string   `json:"main_domain"`
+	ApexDomains []string `json:"apex_domains"`
+	StartTime   string   `json:"start_time"`
+	EndTime     string   `json:"end_time,omitempty"`
+	Timezone    string   `json:"timezone"`
+	Status      string   `json:"status"`
+	Notes       string   `json:"notes"`
+
+	FailedPhase   string `json:"failed_phase,omitempty"`
+	FailureReason string `json:"failure_reason,omitempty"`
+	LastActivity  string `json:"last_activity"`
+	// Stale is true for active sessions that have not written anything
+	// for longer than the stale_after threshold
+	Stale bool `json:"stale"`
 }
 
 // ScanSessionsHandler handles requests for scan session information
 //
 //	@Summary		Get scan sessions information
-//	@Description	Returns information about all scan sessions including target details
+//	@Description	Returns information about all scan sessions including target details. Active sessions that have not written anything within stale_after are flagged as stale.
 //	@Tags			Scan Sessions
 //	@Accept			json
 //	@Produce		json
+//	@Param			tz			query	string	false	"IANA timezone to display times in. Defaults to each session's own timezone."
+//	@Param			stale_after	query	string	false	"How long an active session may go without activity before it is stale, as a Go duration. Defaults to 24h."
 //	@Success		200	{array}	ScanSessionResponse
+//	@Failure		500	{object}	ErrorResponse
 //	@Router			/scan-sessions [get]
 func (h *ApiHandler) ScanSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	// nil means each session's own timezone
+	loc, err := displayLocation(r, nil)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	staleAfter := database.DefaultStaleAfter
+	if raw := r.URL.Query().Get("stale_after"); raw != "" {
+		staleAfter, err = time.ParseDuration(raw)
+		if err != nil || staleAfter <= 0 {
+			writeError(w, "Invalid stale_after duration", http.StatusBadRequest)
+			return
+		}
+	}
+
 	var sessions []models.ScanSession
-	if err := h.DB.Find(&sessions).Error; err != nil {
+	if err := h.DB.Preload("ApexDomains").Find(&sessions).Error; err != nil {
 		log.Error("failed to get scan sessions", "err", err)
-		http.Error(w, "Error retrieving scan sessions", http.StatusInternalServerError)
+		writeError(w, "Error retrieving scan sessions", http.StatusInternalServerError)
 		return
 	}
 
 	response := make([]ScanSessionResponse, len(sessions))
 	for i, session := range sessions {
+		sessionLoc := loc
+		if sessionLoc == nil {
+			sessionLoc = sessionLocation(&session)
+		}
+
 		response[i] = ScanSessionResponse{
 			ID:          session.ID,
 			CompanyName: session.CompanyName,
 			MainDomain:  session.MainDomain,
-			StartTime:   session.StartTime.Format("2006-01-02 15:04:05"),
+			ApexDomains: session.ScopeDomains(),
+			StartTime:   formatTime(session.StartTime, sessionLoc),
+			Timezone:    sessionLocation(&session).String(),
 			Status:      session.Status,
 			Notes:       session.Notes,
+
+			FailedPhase:   session.FailedPhase,
+			FailureReason: session.FailureReason,
 		}
 
 		if session.EndTime != nil {
-			response[i].EndTime = session.EndTime.Format("2006-01-02 15:04:05")
+			response[i].EndTime = formatTime(*session.EndTime, sessionLoc)
+		}
+
+		lastActivity, err := database.LastActivity(h.DB, &session)
+		if err != nil {
+			log.Warn("failed to get scan session activity", "session-id", session.ID, "err", err)
 		}
+		response[i].LastActivity = formatTime(lastActivity, sessionLoc)
+		response[i].Stale = database.IsStale(&session, lastActivity, staleAfter)
 	}
 
 	jsonData, err := json.Marshal(response)
 	if err != nil {
 		log.Error("failed to marshal scan sessions response", "err", err)
-		http.Error(w, "Error creating JSON response", http.StatusInternalServerError)
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
 		return
 	}
 
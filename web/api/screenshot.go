@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/runner"
+	"github.com/sensepost/gowitness/pkg/thumbnail"
+)
+
+// ScreenshotHandler returns the screenshot of a result
+//
+//	@Summary		Get a screenshot
+//	@Description	Get the screenshot of a result, by id. The screenshot is resolved from the result's scan session, falling back to the server's screenshot path.
+//	@Tags			Results
+//	@Produce		jpeg
+//	@Produce		png
+//	@Param			id		path		int		true	"The result ID."
+//	@Param			kind	query		string	false	"An additional screenshot to get instead: fullpage or element."
+//	@Success		200		{file}		binary
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Router			/results/screenshot/{id} [get]
+func (h *ApiHandler) ScreenshotHandler(w http.ResponseWriter, r *http.Request) {
+	var result models.Result
+	if err := h.DB.Select("id", "filename", "full_page_filename", "element_filename", "scan_session_id").
+		First(&result, chi.URLParam(r, "id")).Error; err != nil {
+		writeError(w, "Result not found", http.StatusNotFound)
+		return
+	}
+
+	filename := result.Filename
+	switch r.URL.Query().Get("kind") {
+	case "":
+	case runner.ScreenshotFullPage:
+		filename = result.FullPageFilename
+	case runner.ScreenshotElement:
+		filename = result.ElementFilename
+	default:
+		writeError(w, "Unknown screenshot kind", http.StatusBadRequest)
+		return
+	}
+
+	if filename == "" {
+		writeError(w, "Screenshot not found", http.StatusNotFound)
+		return
+	}
+
+	// thumbnail.Clean keeps us inside the screenshot path
+	dir := database.ScreenshotPath(h.DB, result.ScanSessionID, h.ScreenshotPath)
+	file := filepath.Join(dir, filepath.FromSlash(thumbnail.Clean(filename)))
+
+	if _, err := os.Stat(file); err != nil {
+		log.Debug("screenshot file not found", "file", file, "err", err)
+		writeError(w, "Screenshot not found", http.StatusNotFound)
+		return
+	}
+
+	// isJSON sets this for all api routes
+	w.Header().Del("Content-Type")
+	http.ServeFile(w, r, file)
+}
@@ -5,30 +5,56 @@ import (
 	"net/http"
 )
 
+// Authentication modes the web server can run in
+const (
+	AuthModeNone     = "none"
+	AuthModePassword = "password"
+	AuthModeUsers    = "users"
+	AuthModeOIDC     = "oidc"
+)
+
+// SecurityConfig is the security configuration the web server runs with
+type SecurityConfig struct {
+	AuthMode       string
+	TLSEnabled     bool
+	ReadOnly       bool
+	IPAllowlist    bool
+	CSRFProtection bool
+}
+
 // SecurityStatus represents the current security configuration
 type SecurityStatus struct {
 	PasswordEnabled bool   `json:"password_enabled"`
+	AuthMode        string `json:"auth_mode"`
+	TLSEnabled      bool   `json:"tls_enabled"`
+	ReadOnly        bool   `json:"read_only"`
+	IPAllowlist     bool   `json:"ip_allowlist_enabled"`
+	CSRFProtection  bool   `json:"csrf_protection"`
 	ServerInfo      string `json:"server_info,omitempty"`
 }
 
 // SecurityStatusHandler returns the current security status
-// @Summary Get Security Status
-// @Description Get the current security configuration of the server
-// @Tags security
-// @Accept json
-// @Produce json
-// @Success 200 {object} SecurityStatus
-// @Router /security/status [get]
+//
+//	@Summary		Get Security Status
+//	@Description	Get the current security configuration of the server
+//	@Tags			Security
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	SecurityStatus
+//	@Router			/security/status [get]
 func (api *ApiHandler) SecurityStatusHandler(w http.ResponseWriter, r *http.Request) {
-	// Check if we have password protection enabled by looking for the auth cookie requirement
-	// In a real implementation, this would check server configuration
-
-	// For now, we'll check if the request has an auth cookie to determine if password protection is active
-	_, err := r.Cookie("gowitness_auth")
-	hasPassword := err == nil // If cookie exists, password protection is likely enabled
+	authMode := api.Security.AuthMode
+	if authMode == "" {
+		authMode = AuthModeNone
+	}
 
 	status := SecurityStatus{
-		PasswordEnabled: hasPassword,
+		PasswordEnabled: authMode == AuthModePassword,
+		AuthMode:        authMode,
+		TLSEnabled:      api.Security.TLSEnabled,
+		ReadOnly:        api.Security.ReadOnly,
+		IPAllowlist:     api.Security.IPAllowlist,
+		CSRFProtection:  api.Security.CSRFProtection,
 		ServerInfo:      "gowitness v3 web interface",
 	}
 
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// errInvalidSession is returned for session parameters that are not a
+// scan session id
+var errInvalidSession = errors.New("Invalid session")
+
+// sessionFilter returns the scan session a request is limited to, from its
+// session query parameter, so that the ui can show one engagement at a
+// time. The older scan_session_id parameter is honoured too. nil means all
+// sessions.
+func sessionFilter(r *http.Request) (*uint, error) {
+	raw := r.URL.Query().Get("session")
+	if raw == "" {
+		raw = r.URL.Query().Get("scan_session_id")
+	}
+	if raw == "" {
+		return nil, nil
+	}
+
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return nil, errInvalidSession
+	}
+	session := uint(id)
+
+	return &session, nil
+}
+
+// inSession limits a query of a table with a scan_session_id column to a
+// session, if one is set
+func inSession(q *gorm.DB, session *uint) *gorm.DB {
+	if session == nil {
+		return q
+	}
+
+	return q.Where("scan_session_id = ?", *session)
+}
+
+// resultsInSession limits a query of a table with a result_id column to
+// the results of a session, if one is set
+func (h *ApiHandler) resultsInSession(q *gorm.DB, session *uint) *gorm.DB {
+	if session == nil {
+		return q
+	}
+
+	return q.Where("result_id IN (?)", h.DB.Model(&models.Result{}).Select("id").
+		Where("scan_session_id = ?", *session))
+}
+
+// scanSession returns a scan session with its apex domains, or the most
+// recent one if session is nil
+func (h *ApiHandler) scanSession(session *uint) (*models.ScanSession, error) {
+	var scanSession models.ScanSession
+	q := h.DB.Preload("ApexDomains")
+	if session != nil {
+		q = q.Where("id = ?", *session)
+	}
+	if err := q.Order("start_time DESC").First(&scanSession).Error; err != nil {
+		return nil, err
+	}
+
+	return &scanSession, nil
+}
@@ -3,12 +3,15 @@ package api
 import (
 	"encoding/json"
 	"net/http"
-	"net/url"
-	"strings"
+	"slices"
+	"strconv"
+	"time"
 
+	"github.com/sensepost/gowitness/pkg/database"
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/models"
-	"golang.org/x/net/publicsuffix"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type statisticsResponse struct {
@@ -24,12 +27,33 @@ type statisticsResponse struct {
 }
 
 type targetInformation struct {
-	CompanyName   string `json:"company_name"`
-	MainDomain    string `json:"main_domain"`
-	LogoPath      string `json:"logo_path,omitempty"`
-	ScanStartTime string `json:"scan_start_time"`
-	ScanStatus    string `json:"scan_status"`
-	Notes         string `json:"notes"`
+	CompanyName   string   `json:"company_name"`
+	MainDomain    string   `json:"main_domain"`
+	ApexDomains   []string `json:"apex_domains"`
+	LogoPath      string   `json:"logo_path,omitempty"`
+	ScanStartTime string   `json:"scan_start_time"`
+	Timezone      string   `json:"timezone"`
+	ScanStatus    string   `json:"scan_status"`
+	Notes         string   `json:"notes"`
+
+	Industry         string   `json:"industry,omitempty"`
+	EmployeeCount    int      `json:"employee_count,omitempty"`
+	Netblocks        []string `json:"netblocks,omitempty"`
+	Domains          []string `json:"domains,omitempty"`
+	EnrichmentSource string   `json:"enrichment_source,omitempty"`
+
+	Breaches         []breachSummary `json:"breaches,omitempty"`
+	BreachedAccounts int             `json:"breached_accounts"`
+}
+
+// breachSummary is how many accounts of an apex domain a provider has seen
+// in data breaches
+type breachSummary struct {
+	Domain    string `json:"domain"`
+	Source    string `json:"source"`
+	Accounts  int    `json:"accounts"`
+	Breaches  int    `json:"breaches"`
+	CheckedAt string `json:"checked_at"`
 }
 
 type statisticsResponseCode struct {
@@ -47,6 +71,7 @@ type domainStatistics struct {
 type apexDomain struct {
 	Domain     string       `json:"domain"`
 	IsApex     bool         `json:"is_apex"`
+	InScope    bool         `json:"in_scope"` // one of the scan session's apex domains
 	ResultID   uint         `json:"result_id,omitempty"`
 	Subdomains []*subdomain `json:"subdomains"`
 	Count      int64        `json:"count"`
@@ -84,32 +109,29 @@ type ipDomainEntry struct {
 	Port     string `json:"port"`
 }
 
-// extractApexDomain extracts the apex domain from a URL using the public suffix list
-// This properly handles country-code TLDs like .co.uk, .com.au, etc.
-func extractApexDomain(inputURL string) string {
-	parsedURL, err := url.Parse(inputURL)
-	if err != nil {
-		return ""
-	}
+// statistics list pagination defaults
+const (
+	defaultStatisticsLimit = 100
+	maxStatisticsLimit     = 1000
+)
 
-	hostname := parsedURL.Hostname()
-	if hostname == "" {
-		return ""
+// statisticsPage reads limit and offset query parameters with the given
+// prefix, e.g. apex_limit and apex_offset
+func statisticsPage(r *http.Request, prefix string) (int, int) {
+	limit, err := strconv.Atoi(r.URL.Query().Get(prefix + "_limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultStatisticsLimit
+	}
+	if limit > maxStatisticsLimit {
+		limit = maxStatisticsLimit
 	}
 
-	// Use the public suffix list to get the effective TLD (eTLD)
-	// This handles complex TLDs like .co.uk, .com.au properly
-	etld, err := publicsuffix.EffectiveTLDPlusOne(hostname)
-	if err != nil {
-		// If parsing fails, fall back to simple logic for basic cases
-		parts := strings.Split(hostname, ".")
-		if len(parts) >= 2 {
-			return strings.Join(parts[len(parts)-2:], ".")
-		}
-		return hostname
+	offset, err := strconv.Atoi(r.URL.Query().Get(prefix + "_offset"))
+	if err != nil || offset < 0 {
+		offset = 0
 	}
 
-	return etld
+	return limit, offset
 }
 
 // StatisticsHandler returns database statistics
@@ -119,66 +141,108 @@ func extractApexDomain(inputURL string) string {
 //	@Tags			Results
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	statisticsResponse
+//	@Param			apex_limit	query		int	false	"The number of apex domains to return. Default 100, max 1000."
+//	@Param			apex_offset	query		int	false	"The number of apex domains to skip."
+//	@Param			ip_limit	query		int	false	"The number of IP addresses to return. Default 100, max 1000."
+//	@Param			ip_offset	query		int	false	"The number of IP addresses to skip."
+//	@Param			tz			query		string	false	"IANA timezone to display times in. Defaults to UTC, or the scan session's timezone for target information."
+//	@Param			session		query		int		false	"Only count results of this scan session, and describe its target. Defaults to all results, and the most recent session's target."
+//	@Success		200			{object}	statisticsResponse
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		500			{object}	ErrorResponse
 //	@Router			/statistics [get]
 func (h *ApiHandler) StatisticsHandler(w http.ResponseWriter, r *http.Request) {
 	response := &statisticsResponse{}
 
+	// nil means the scan session's own timezone, where it applies
+	loc, err := displayLocation(r, nil)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err := sessionFilter(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if err := h.DB.Raw("SELECT page_count * page_size as size FROM pragma_page_count(), pragma_page_size()").
 		Take(&response.DbSize).Error; err != nil {
 
 		log.Error("an error occured getting database size", "err", err)
+		writeError(w, "Error calculating statistics", http.StatusInternalServerError)
 		return
 	}
 
-	if err := h.DB.Model(&models.Result{}).Count(&response.Results).Error; err != nil {
+	if err := inSession(h.DB.Model(&models.Result{}), session).Count(&response.Results).Error; err != nil {
 		log.Error("an error occured counting results", "err", err)
+		writeError(w, "Error calculating statistics", http.StatusInternalServerError)
 		return
 	}
 
-	if err := h.DB.Model(&models.Header{}).Count(&response.Headers).Error; err != nil {
+	if err := h.resultsInSession(h.DB.Model(&models.Header{}), session).Count(&response.Headers).Error; err != nil {
 		log.Error("an error occured counting headers", "err", err)
+		writeError(w, "Error calculating statistics", http.StatusInternalServerError)
 		return
 	}
 
-	if err := h.DB.Model(&models.NetworkLog{}).Count(&response.NetworkLogs).Error; err != nil {
+	if err := h.resultsInSession(h.DB.Model(&models.NetworkLog{}), session).Count(&response.NetworkLogs).Error; err != nil {
 		log.Error("an error occured counting network logs", "err", err)
+		writeError(w, "Error calculating statistics", http.StatusInternalServerError)
 		return
 	}
 
-	if err := h.DB.Model(&models.ConsoleLog{}).Count(&response.ConsoleLogs).Error; err != nil {
+	if err := h.resultsInSession(h.DB.Model(&models.ConsoleLog{}), session).Count(&response.ConsoleLogs).Error; err != nil {
 		log.Error("an error occured counting console logs", "err", err)
+		writeError(w, "Error calculating statistics", http.StatusInternalServerError)
 		return
 	}
 
 	var counts []*statisticsResponseCode
-	if err := h.DB.Model(&models.Result{}).
+	if err := inSession(h.DB.Model(&models.Result{}), session).
 		Select("response_code as code, count(*) as count").
 		Group("response_code").Scan(&counts).Error; err != nil {
 		log.Error("failed counting response codes", "err", err)
+		writeError(w, "Error calculating statistics", http.StatusInternalServerError)
 		return
 	}
 
 	response.ResponseCodes = counts
 
+	// Domain and IP statistics are grouped from the result host summary
+	if err := database.RefreshResultHosts(h.DB); err != nil {
+		log.Error("failed refreshing result host summary", "err", err)
+		writeError(w, "Error calculating statistics", http.StatusInternalServerError)
+		return
+	}
+
 	// Calculate domain statistics
-	domainStats, err := h.calculateDomainStatistics()
+	apexLimit, apexOffset := statisticsPage(r, "apex")
+	domainStats, err := h.calculateDomainStatistics(apexLimit, apexOffset, h.scopeDomains(session), session)
 	if err != nil {
 		log.Error("failed calculating domain statistics", "err", err)
+		writeError(w, "Error calculating statistics", http.StatusInternalServerError)
 		return
 	}
 	response.DomainStats = domainStats
 
 	// Calculate IP statistics
-	ipStats, err := h.calculateIPStatistics()
+	ipLimit, ipOffset := statisticsPage(r, "ip")
+	ipLoc := loc
+	if ipLoc == nil {
+		ipLoc = time.UTC
+	}
+	ipStats, err := h.calculateIPStatistics(ipLimit, ipOffset, ipLoc, session)
 	if err != nil {
 		log.Error("failed calculating IP statistics", "err", err)
+		writeError(w, "Error calculating statistics", http.StatusInternalServerError)
 		return
 	}
 	response.IPStats = ipStats
 
-	// Get target information from the most recent scan session
-	targetInfo, err := h.getTargetInformation()
+	// Get target information from the scan session, or the most recent
+	targetInfo, err := h.getTargetInformation(loc, session)
 	if err != nil {
 		log.Warn("failed getting target information", "err", err)
 		// Don't fail the entire request, just leave target info empty
@@ -188,248 +252,256 @@ func (h *ApiHandler) StatisticsHandler(w http.ResponseWriter, r *http.Request) {
 
 	jsonData, err := json.Marshal(response)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Write(jsonData)
 }
 
-// calculateDomainStatistics calculates comprehensive domain statistics
-func (h *ApiHandler) calculateDomainStatistics() (*domainStatistics, error) {
-	var results []models.Result
-	if err := h.DB.Select("id, url").Find(&results).Error; err != nil {
-		return nil, err
+// scopeDomains returns the apex domains of a scan session, or the most
+// recent one if session is nil. None are returned if there is no session.
+func (h *ApiHandler) scopeDomains(session *uint) []string {
+	scanSession, err := h.scanSession(session)
+	if err != nil {
+		return []string{}
 	}
 
-	// Map to group domains by apex domain
-	apexDomainMap := make(map[string]*apexDomain)
-	totalSubdomains := int64(0)
+	return scanSession.ScopeDomains()
+}
 
-	for _, result := range results {
-		parsedURL, err := url.Parse(result.URL)
-		if err != nil {
-			continue
-		}
+// calculateDomainStatistics calculates domain statistics, returning a page
+// of apex domains ordered by their result count. The apex domains in scope
+// come first, so that the session's brands are grouped at the top. Only
+// results of session are counted, if it is set.
+func (h *ApiHandler) calculateDomainStatistics(limit, offset int, scope []string, session *uint) (*domainStatistics, error) {
+	stats := &domainStatistics{ApexDomains: make([]*apexDomain, 0)}
+	hosts := h.resultsInSession(h.DB.Model(&models.ResultHost{}), session).Where("apex_domain != ''")
 
-		hostname := parsedURL.Hostname()
-		if hostname == "" {
-			continue
-		}
+	if err := hosts.Session(&gorm.Session{}).Distinct("apex_domain").Count(&stats.UniqueApexDomains).Error; err != nil {
+		return nil, err
+	}
+	if err := hosts.Session(&gorm.Session{}).Where("hostname != apex_domain").Count(&stats.TotalSubdomains).Error; err != nil {
+		return nil, err
+	}
+	stats.TotalDomains = stats.UniqueApexDomains + stats.TotalSubdomains
 
-		apexDomainName := extractApexDomain(result.URL)
-		if apexDomainName == "" {
-			continue
-		}
+	var page []struct {
+		Domain      string
+		ResultCount int64
+		IsApex      int
+		ResultID    uint
+	}
+	if err := hosts.Session(&gorm.Session{}).
+		Select("apex_domain as domain, count(*) as result_count, " +
+			"MAX(CASE WHEN hostname = apex_domain THEN 1 ELSE 0 END) as is_apex, " +
+			"COALESCE(MIN(CASE WHEN hostname = apex_domain THEN result_id END), 0) as result_id").
+		Group("apex_domain").
+		Order(domainOrder(scope)).
+		Limit(limit).Offset(offset).
+		Scan(&page).Error; err != nil {
+		return nil, err
+	}
 
-		// Initialize apex domain if not exists
-		if _, exists := apexDomainMap[apexDomainName]; !exists {
-			apexDomainMap[apexDomainName] = &apexDomain{
-				Domain:     apexDomainName,
-				IsApex:     false,
-				Subdomains: make([]*subdomain, 0),
-				Count:      0,
-			}
-		}
+	if len(page) == 0 {
+		return stats, nil
+	}
 
-		apex := apexDomainMap[apexDomainName]
-		apex.Count++
-
-		// Check if this is the apex domain itself or a subdomain
-		if hostname == apexDomainName {
-			// This is the apex domain - add it as a "subdomain" entry for protocol/port grouping
-
-			// Extract protocol and port from URL
-			protocol := parsedURL.Scheme
-			port := parsedURL.Port()
-			if port == "" {
-				// Set default ports for common schemes
-				switch protocol {
-				case "http":
-					port = "80"
-				case "https":
-					port = "443"
-				default:
-					port = "unknown"
-				}
-			}
-
-			// Add apex domain as a subdomain entry for protocol/port display
-			apex.Subdomains = append(apex.Subdomains, &subdomain{
-				Domain:   hostname,
-				ResultID: result.ID,
-				URL:      result.URL,
-				Protocol: protocol,
-				Port:     port,
-			})
-
-			// Mark as apex and set a result ID if not already set
-			apex.IsApex = true
-			if apex.ResultID == 0 {
-				apex.ResultID = result.ID
-			}
-		} else {
-			// This is a subdomain
-			totalSubdomains++
-
-			// Extract protocol and port from URL
-			protocol := parsedURL.Scheme
-			port := parsedURL.Port()
-			if port == "" {
-				// Set default ports for common schemes
-				switch protocol {
-				case "http":
-					port = "80"
-				case "https":
-					port = "443"
-				default:
-					port = "unknown"
-				}
-			}
-
-			apex.Subdomains = append(apex.Subdomains, &subdomain{
-				Domain:   hostname,
-				ResultID: result.ID,
-				URL:      result.URL,
-				Protocol: protocol,
-				Port:     port,
-			})
+	names := make([]string, 0, len(page))
+	apexes := make(map[string]*apexDomain, len(page))
+	for _, row := range page {
+		apex := &apexDomain{
+			Domain:     row.Domain,
+			IsApex:     row.IsApex == 1,
+			InScope:    slices.Contains(scope, row.Domain),
+			ResultID:   row.ResultID,
+			Subdomains: make([]*subdomain, 0),
+			Count:      row.ResultCount,
 		}
+		stats.ApexDomains = append(stats.ApexDomains, apex)
+		apexes[row.Domain] = apex
+		names = append(names, row.Domain)
 	}
 
-	// Convert map to slice and sort by count (descending)
-	apexDomains := make([]*apexDomain, 0, len(apexDomainMap))
-	for _, apex := range apexDomainMap {
-		apexDomains = append(apexDomains, apex)
+	// the apex domain itself is included as a "subdomain" entry for
+	// protocol/port display
+	var rows []models.ResultHost
+	if err := h.resultsInSession(h.DB, session).Where("apex_domain IN ?", names).Order("result_id").Find(&rows).Error; err != nil {
+		return nil, err
 	}
 
-	// Simple bubble sort by count (descending)
-	for i := 0; i < len(apexDomains)-1; i++ {
-		for j := 0; j < len(apexDomains)-i-1; j++ {
-			if apexDomains[j].Count < apexDomains[j+1].Count {
-				apexDomains[j], apexDomains[j+1] = apexDomains[j+1], apexDomains[j]
-			}
-		}
+	for _, row := range rows {
+		apex := apexes[row.ApexDomain]
+		apex.Subdomains = append(apex.Subdomains, &subdomain{
+			Domain:   row.Hostname,
+			ResultID: row.ResultID,
+			URL:      row.URL,
+			Protocol: row.Protocol,
+			Port:     row.Port,
+		})
 	}
 
-	return &domainStatistics{
-		UniqueApexDomains: int64(len(apexDomainMap)),
-		TotalSubdomains:   totalSubdomains,
-		TotalDomains:      int64(len(apexDomainMap)) + totalSubdomains,
-		ApexDomains:       apexDomains,
-	}, nil
+	return stats, nil
 }
 
-// calculateIPStatistics calculates comprehensive IP address statistics
-func (h *ApiHandler) calculateIPStatistics() (*ipStatistics, error) {
-	var results []models.Result
-	if err := h.DB.Select("id, url, ip_address, probed_at").Where("ip_address != ''").Find(&results).Error; err != nil {
-		return nil, err
+// domainOrder orders apex domains in scope first, then by result count
+func domainOrder(scope []string) interface{} {
+	if len(scope) == 0 {
+		return "result_count DESC, apex_domain"
 	}
 
-	// Map to group results by IP address
-	ipMap := make(map[string]*ipEntry)
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:  "CASE WHEN apex_domain IN ? THEN 0 ELSE 1 END, result_count DESC, apex_domain",
+		Vars: []interface{}{scope},
+	}}
+}
 
-	for _, result := range results {
-		if result.IPAddress == "" {
-			continue
-		}
+// calculateIPStatistics calculates IP address statistics, returning a page
+// of IP addresses ordered by their domain count. Times are shown in loc.
+// Only results of session are counted, if it is set.
+func (h *ApiHandler) calculateIPStatistics(limit, offset int, loc *time.Location, session *uint) (*ipStatistics, error) {
+	stats := &ipStatistics{IPList: make([]*ipEntry, 0)}
+	hosts := h.resultsInSession(h.DB.Model(&models.ResultHost{}), session).Where("ip_address != '' AND hostname != ''")
 
-		parsedURL, err := url.Parse(result.URL)
-		if err != nil {
-			continue
-		}
+	if err := hosts.Session(&gorm.Session{}).Distinct("ip_address").Count(&stats.UniqueIPs).Error; err != nil {
+		return nil, err
+	}
+	if err := hosts.Session(&gorm.Session{}).Count(&stats.TotalResults).Error; err != nil {
+		return nil, err
+	}
 
-		hostname := parsedURL.Hostname()
-		if hostname == "" {
-			continue
-		}
+	var page []struct {
+		IPAddress   string
+		DomainCount int64
+	}
+	if err := hosts.Session(&gorm.Session{}).
+		Select("ip_address, count(*) as domain_count").
+		Group("ip_address").
+		Order("domain_count DESC, ip_address").
+		Limit(limit).Offset(offset).
+		Scan(&page).Error; err != nil {
+		return nil, err
+	}
 
-		// Extract protocol and port from URL
-		protocol := parsedURL.Scheme
-		port := parsedURL.Port()
-		if port == "" {
-			// Set default ports for common schemes
-			switch protocol {
-			case "http":
-				port = "80"
-			case "https":
-				port = "443"
-			default:
-				port = "unknown"
-			}
-		}
+	if len(page) == 0 {
+		return stats, nil
+	}
 
-		// Initialize IP entry if not exists
-		if _, exists := ipMap[result.IPAddress]; !exists {
-			ipMap[result.IPAddress] = &ipEntry{
-				IPAddress:    result.IPAddress,
-				DomainCount:  0,
-				FirstSeen:    result.ProbedAt.Format("2006-01-02 15:04:05"),
-				LastSeen:     result.ProbedAt.Format("2006-01-02 15:04:05"),
-				SampleDomain: hostname,
-				ResultID:     result.ID,
-				Domains:      make([]*ipDomainEntry, 0),
-			}
+	ips := make([]string, 0, len(page))
+	entries := make(map[string]*ipEntry, len(page))
+	for _, row := range page {
+		entry := &ipEntry{
+			IPAddress:   row.IPAddress,
+			DomainCount: row.DomainCount,
+			Domains:     make([]*ipDomainEntry, 0),
 		}
+		stats.IPList = append(stats.IPList, entry)
+		entries[row.IPAddress] = entry
+		ips = append(ips, row.IPAddress)
+	}
 
-		ipEntry := ipMap[result.IPAddress]
-		ipEntry.DomainCount++
+	var rows []models.ResultHost
+	if err := h.resultsInSession(h.DB, session).Where("ip_address IN ? AND hostname != ''", ips).Order("result_id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	firstSeen := make(map[string]time.Time, len(page))
+	lastSeen := make(map[string]time.Time, len(page))
+	for _, row := range rows {
+		entry := entries[row.IPAddress]
+		// rows are ordered by result id, so the first is the sample
+		if len(entry.Domains) == 0 {
+			entry.SampleDomain = row.Hostname
+			entry.ResultID = row.ResultID
+			firstSeen[row.IPAddress] = row.ProbedAt
+			lastSeen[row.IPAddress] = row.ProbedAt
+		}
 
-		// Add domain entry
-		ipEntry.Domains = append(ipEntry.Domains, &ipDomainEntry{
-			Domain:   hostname,
-			ResultID: result.ID,
-			URL:      result.URL,
-			Protocol: protocol,
-			Port:     port,
+		entry.Domains = append(entry.Domains, &ipDomainEntry{
+			Domain:   row.Hostname,
+			ResultID: row.ResultID,
+			URL:      row.URL,
+			Protocol: row.Protocol,
+			Port:     row.Port,
 		})
 
 		// Update first/last seen times
-		currentProbed := result.ProbedAt.Format("2006-01-02 15:04:05")
-		if currentProbed < ipEntry.FirstSeen {
-			ipEntry.FirstSeen = currentProbed
+		if row.ProbedAt.Before(firstSeen[row.IPAddress]) {
+			firstSeen[row.IPAddress] = row.ProbedAt
 		}
-		if currentProbed > ipEntry.LastSeen {
-			ipEntry.LastSeen = currentProbed
+		if row.ProbedAt.After(lastSeen[row.IPAddress]) {
+			lastSeen[row.IPAddress] = row.ProbedAt
 		}
 	}
 
-	// Convert map to slice and sort by domain count (descending)
-	ipList := make([]*ipEntry, 0, len(ipMap))
-	for _, ip := range ipMap {
-		ipList = append(ipList, ip)
+	for ip, entry := range entries {
+		entry.FirstSeen = formatTime(firstSeen[ip], loc)
+		entry.LastSeen = formatTime(lastSeen[ip], loc)
 	}
 
-	// Simple bubble sort by domain count (descending)
-	for i := 0; i < len(ipList)-1; i++ {
-		for j := 0; j < len(ipList)-i-1; j++ {
-			if ipList[j].DomainCount < ipList[j+1].DomainCount {
-				ipList[j], ipList[j+1] = ipList[j+1], ipList[j]
-			}
-		}
+	return stats, nil
+}
+
+// getTargetInformation retrieves target information from a scan session,
+// or the most recent one if id is nil. Times are shown in loc, or the
+// session's timezone if loc is nil.
+func (h *ApiHandler) getTargetInformation(loc *time.Location, id *uint) (*targetInformation, error) {
+	session, err := h.scanSession(id)
+	if err != nil {
+		return nil, err
 	}
 
-	return &ipStatistics{
-		UniqueIPs:    int64(len(ipMap)),
-		TotalResults: int64(len(results)),
-		IPList:       ipList,
-	}, nil
-}
+	if loc == nil {
+		loc = sessionLocation(session)
+	}
 
-// getTargetInformation retrieves target information from the most recent scan session
-func (h *ApiHandler) getTargetInformation() (*targetInformation, error) {
-	var session models.ScanSession
-	if err := h.DB.Order("start_time DESC").First(&session).Error; err != nil {
+	netblocks, err := session.GetNetblocks()
+	if err != nil {
+		return nil, err
+	}
+	domains, err := session.GetDomains()
+	if err != nil {
 		return nil, err
 	}
 
-	return &targetInformation{
-		CompanyName:   session.CompanyName,
-		MainDomain:    session.MainDomain,
-		LogoPath:      session.LogoPath,
-		ScanStartTime: session.StartTime.Format("2006-01-02 15:04:05"),
-		ScanStatus:    session.Status,
-		Notes:         session.Notes,
-	}, nil
+	var exposures []models.BreachExposure
+	if err := h.DB.Where("domain IN ?", session.ScopeDomains()).
+		Order("domain, source").Find(&exposures).Error; err != nil {
+		return nil, err
+	}
+
+	info := &targetInformation{
+		CompanyName:      session.CompanyName,
+		MainDomain:       session.MainDomain,
+		ApexDomains:      session.ScopeDomains(),
+		LogoPath:         session.LogoPath,
+		ScanStartTime:    formatTime(session.StartTime, loc),
+		Timezone:         sessionLocation(session).String(),
+		ScanStatus:       session.Status,
+		Notes:            session.Notes,
+		Industry:         session.Industry,
+		EmployeeCount:    session.EmployeeCount,
+		Netblocks:        netblocks,
+		Domains:          domains,
+		EnrichmentSource: session.EnrichmentSource,
+	}
+
+	// providers overlap, so the most accounts any of them saw for a domain
+	// is counted
+	mostAccounts := make(map[string]int)
+	for _, exposure := range exposures {
+		info.Breaches = append(info.Breaches, breachSummary{
+			Domain:    exposure.Domain,
+			Source:    exposure.Source,
+			Accounts:  exposure.Accounts,
+			Breaches:  exposure.Breaches,
+			CheckedAt: formatTime(exposure.CheckedAt, loc),
+		})
+		mostAccounts[exposure.Domain] = max(mostAccounts[exposure.Domain], exposure.Accounts)
+	}
+	for _, accounts := range mostAccounts {
+		info.BreachedAccounts += accounts
+	}
+
+	return info, nil
 }
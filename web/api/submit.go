@@ -2,13 +2,11 @@ package api
 
 import (
 	"encoding/json"
-	"log/slog"
 	"net/http"
 
+	"github.com/sensepost/gowitness/pkg/jobs"
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/runner"
-	driver "github.com/sensepost/gowitness/pkg/runner/drivers"
-	"github.com/sensepost/gowitness/pkg/writers"
 )
 
 type submitRequest struct {
@@ -28,95 +26,72 @@ type submitRequestOptions struct {
 // SubmitHandler submits URL's for scans, writing them to the database.
 //
 //	@Summary		Submit URL's for scanning
-//	@Description	Starts a new scanning routine for a list of URL's and options, writing results to the database.
+//	@Description	Queues a probe job for a list of URL's and options, writing results to the database. Use the jobs endpoints to follow its progress.
 //	@Tags			Results
 //	@Accept			json
 //	@Produce		json
 //	@Param			query	body		submitRequest	true	"The URL scanning request object"
 //	@Success		200		{string}	string			"Probing started"
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
 //	@Router			/submit [post]
 func (h *ApiHandler) SubmitHandler(w http.ResponseWriter, r *http.Request) {
 	var request submitRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		log.Error("failed to read json request", "err", err)
-		http.Error(w, "Error reading JSON request", http.StatusInternalServerError)
+		writeError(w, "Error reading JSON request", http.StatusInternalServerError)
 		return
 	}
 
 	if len(request.URLs) == 0 {
-		http.Error(w, "No URLs provided", http.StatusBadRequest)
+		writeError(w, "No URLs provided", http.StatusBadRequest)
 		return
 	}
 
 	options := runner.NewDefaultOptions()
 	options.Scan.ScreenshotPath = h.ScreenshotPath
+	applySubmitOptions(options, request.Options)
 
-	// Override default values with request options
-	if request.Options != nil {
-		if request.Options.X != 0 {
-			options.Chrome.WindowX = request.Options.X
-		}
-		if request.Options.Y != 0 {
-			options.Chrome.WindowY = request.Options.Y
-		}
-		if request.Options.UserAgent != "" {
-			options.Chrome.UserAgent = request.Options.UserAgent
-		}
-		if request.Options.Timeout != 0 {
-			options.Scan.Timeout = request.Options.Timeout
-		}
-		if request.Options.Delay != 0 {
-			options.Scan.Delay = request.Options.Delay
-		}
-		if request.Options.Format != "" {
-			options.Scan.ScreenshotFormat = request.Options.Format
-		}
-	}
-
-	writer, err := writers.NewDbWriter(h.DbURI, false)
-	if err != nil {
-		http.Error(w, "Error connecting to DB for writer", http.StatusInternalServerError)
+	if _, err := h.Jobs.Enqueue(jobs.KindProbe, jobs.Probe{URLs: request.URLs, Options: *options},
+		jobs.Options{Priority: jobPriorityProbe}); err != nil {
+		log.Error("failed to queue job", "err", err)
+		writeError(w, "Error queueing job", http.StatusInternalServerError)
 		return
 	}
 
-	logger := slog.New(log.Logger)
-
-	driver, err := driver.NewChromedp(logger, *options)
-	if err != nil {
-		http.Error(w, "Error sarting driver", http.StatusInternalServerError)
-		return
-	}
-
-	runner, err := runner.NewRunner(logger, driver, *options, []writers.Writer{writer})
-	if err != nil {
-		log.Error("error starting runner", "err", err)
-		http.Error(w, "Error starting runner", http.StatusInternalServerError)
-		return
-	}
-
-	// have everything we need! start ther runner goroutine
-	go dispatchRunner(runner, request.URLs)
-
 	response := `Probing started`
 	jsonData, err := json.Marshal(response)
 	if err != nil {
-		http.Error(w, "Error creating JSON response", http.StatusInternalServerError)
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
 		return
 	}
 
 	w.Write(jsonData)
 }
 
-// dispatchRunner run's a runner in a separate goroutine
-func dispatchRunner(runner *runner.Runner, targets []string) {
-	// feed in targets
-	go func() {
-		for _, url := range targets {
-			runner.Targets <- url
-		}
-		close(runner.Targets)
-	}()
+// applySubmitOptions overrides default runner options with the options of
+// a submit request
+func applySubmitOptions(options *runner.Options, request *submitRequestOptions) {
+	if request == nil {
+		return
+	}
 
-	runner.Run()
-	runner.Close()
+	if request.X != 0 {
+		options.Chrome.WindowX = request.X
+	}
+	if request.Y != 0 {
+		options.Chrome.WindowY = request.Y
+	}
+	if request.UserAgent != "" {
+		options.Chrome.UserAgent = request.UserAgent
+	}
+	if request.Timeout != 0 {
+		options.Scan.Timeout = request.Timeout
+	}
+	if request.Delay != 0 {
+		options.Scan.Delay = request.Delay
+	}
+	if request.Format != "" {
+		options.Scan.ScreenshotFormat = request.Format
+	}
 }
@@ -0,0 +1,386 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/jobs"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/readers"
+	"github.com/sensepost/gowitness/pkg/runner"
+	"gorm.io/gorm"
+)
+
+const (
+	// submitFileMaxSize is the largest target file that may be uploaded
+	submitFileMaxSize = 10 << 20
+	// submitFileMaxTargets is the most targets a single upload may contain
+	submitFileMaxTargets = 100000
+	// submitFileMaxInvalid is the most invalid lines echoed back in a response
+	submitFileMaxInvalid = 50
+	// submitFileSource is the domain source recorded for uploaded hostnames
+	submitFileSource = "upload"
+)
+
+// unsafeFileChars matches characters not allowed in stored upload names
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+type submitFileResponse struct {
+	File          string   `json:"file"`
+	Targets       int      `json:"targets"`
+	URLs          int      `json:"urls"`
+	Invalid       []string `json:"invalid,omitempty"`
+	InvalidCount  int      `json:"invalid_count"`
+	ScanSessionID *uint    `json:"scan_session_id,omitempty"`
+	JobID         uint     `json:"job_id"`
+}
+
+// SubmitFileHandler submits a file of targets for scanning.
+//
+//	@Summary		Submit a target file for scanning
+//	@Description	Accepts a multipart upload of a newline delimited (.txt) or CSV (.csv, first column) file of hostnames, IPs or URLs. Targets are validated, stored against the scan session and queued for probing as a job, writing results to the database.
+//	@Tags			Results
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			file			formData	file	true	"The target file"
+//	@Param			scan_session_id	formData	int		false	"The scan session to store targets against"
+//	@Param			ports			formData	string	false	"Comma separated ports to probe targets without a port on"
+//	@Param			options			formData	string	false	"JSON encoded scan options, as in the submit endpoint"
+//	@Success		200				{object}	submitFileResponse
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		404				{object}	ErrorResponse
+//	@Failure		413				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/submit/file [post]
+func (h *ApiHandler) SubmitFileHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, submitFileMaxSize+(1<<20))
+	if err := r.ParseMultipartForm(submitFileMaxSize); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, "Uploaded file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		writeError(w, "Error reading multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, "No file provided", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > submitFileMaxSize {
+		writeError(w, "Uploaded file is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var isCSV bool
+	switch strings.ToLower(filepath.Ext(header.Filename)) {
+	case ".csv":
+		isCSV = true
+	case ".txt", "":
+	default:
+		writeError(w, "Only .txt and .csv files are supported", http.StatusBadRequest)
+		return
+	}
+
+	var scanSessionID *uint
+	if raw := r.FormValue("scan_session_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			writeError(w, "Invalid scan_session_id", http.StatusBadRequest)
+			return
+		}
+
+		var session models.ScanSession
+		if err := h.DB.Select("id").First(&session, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				writeError(w, "Scan session not found", http.StatusNotFound)
+				return
+			}
+
+			log.Error("failed to get scan session", "err", err)
+			writeError(w, "Error getting scan session", http.StatusInternalServerError)
+			return
+		}
+		scanSessionID = &session.ID
+	}
+
+	var ports []int
+	if raw := r.FormValue("ports"); raw != "" {
+		for _, p := range strings.Split(raw, ",") {
+			port, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || port < 1 || port > 65535 {
+				writeError(w, "Invalid ports", http.StatusBadRequest)
+				return
+			}
+			ports = append(ports, port)
+		}
+	}
+
+	var scanOptions *submitRequestOptions
+	if raw := r.FormValue("options"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &scanOptions); err != nil {
+			writeError(w, "Invalid options", http.StatusBadRequest)
+			return
+		}
+	}
+
+	targets, invalid, err := readSubmitTargets(file, isCSV)
+	if err != nil {
+		writeError(w, fmt.Sprintf("Error reading file: %s", err), http.StatusBadRequest)
+		return
+	}
+	if len(targets) == 0 {
+		writeError(w, "No valid targets in file", http.StatusBadRequest)
+		return
+	}
+	if len(targets) > submitFileMaxTargets {
+		writeError(w, fmt.Sprintf("Too many targets, the maximum is %d", submitFileMaxTargets), http.StatusBadRequest)
+		return
+	}
+
+	// uploads live next to the screenshots of the session they belong to
+	screenshotPath := database.ScreenshotPath(h.DB, scanSessionID, h.ScreenshotPath)
+	stored, err := storeSubmitTargets(filepath.Join(filepath.Dir(screenshotPath), "uploads"), header.Filename, targets)
+	if err != nil {
+		log.Error("failed to store uploaded targets", "err", err)
+		writeError(w, "Error storing uploaded targets", http.StatusInternalServerError)
+		return
+	}
+
+	if err := saveSubmitDomains(h.DB, targets, scanSessionID); err != nil {
+		log.Error("failed to save uploaded domains", "err", err)
+		writeError(w, "Error saving uploaded domains", http.StatusInternalServerError)
+		return
+	}
+
+	// expand targets to urls the same way the file scanner does
+	reader := readers.NewFileReader(&readers.FileReaderOptions{
+		Source: stored,
+		Ports:  ports,
+	})
+	urls, err := readSubmitURLs(reader)
+	if err != nil {
+		log.Error("failed to read stored targets", "err", err)
+		writeError(w, "Error reading stored targets", http.StatusInternalServerError)
+		return
+	}
+
+	options := runner.NewDefaultOptions()
+	options.Scan.ScreenshotPath = screenshotPath
+	applySubmitOptions(options, scanOptions)
+
+	job, err := h.Jobs.Enqueue(jobs.KindProbe, jobs.Probe{URLs: urls, Options: *options},
+		jobs.Options{Priority: jobPriorityProbe, ScanSessionID: scanSessionID})
+	if err != nil {
+		log.Error("failed to queue job", "err", err)
+		writeError(w, "Error queueing job", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("queued uploaded targets", "file", stored, "targets", len(targets),
+		"urls", len(urls), "invalid", len(invalid), "scan-session-id", scanSessionID, "job", job.ID)
+
+	response := submitFileResponse{
+		File:          filepath.Base(stored),
+		Targets:       len(targets),
+		URLs:          len(urls),
+		InvalidCount:  len(invalid),
+		ScanSessionID: scanSessionID,
+		JobID:         job.ID,
+	}
+	if len(invalid) > submitFileMaxInvalid {
+		response.Invalid = invalid[:submitFileMaxInvalid]
+	} else {
+		response.Invalid = invalid
+	}
+
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// readSubmitTargets reads the targets in an uploaded file, returning the
+// valid, de-duplicated targets and the lines that were invalid. For CSV
+// files the first column is used, and a header row is skipped.
+func readSubmitTargets(file io.Reader, isCSV bool) ([]string, []string, error) {
+	var candidates []string
+
+	if isCSV {
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for i, record := range records {
+			if len(record) == 0 {
+				continue
+			}
+			// a header is a first row that isn't a target
+			if i == 0 && !validSubmitTarget(strings.TrimSpace(record[0])) {
+				continue
+			}
+			candidates = append(candidates, record[0])
+		}
+	} else {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			candidates = append(candidates, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var targets, invalid []string
+	seen := make(map[string]bool)
+
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" || strings.HasPrefix(candidate, "#") {
+			continue
+		}
+
+		if !validSubmitTarget(candidate) {
+			invalid = append(invalid, candidate)
+			continue
+		}
+
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		targets = append(targets, candidate)
+	}
+
+	return targets, invalid, nil
+}
+
+// validSubmitTarget checks if a candidate is a hostname, IP or http(s) URL
+func validSubmitTarget(candidate string) bool {
+	if candidate == "" || strings.ContainsAny(candidate, " \t") {
+		return false
+	}
+
+	if !strings.Contains(candidate, "://") {
+		candidate = "http://" + candidate
+	}
+
+	u, err := url.Parse(candidate)
+	if err != nil {
+		return false
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	hostname := u.Hostname()
+	if net.ParseIP(hostname) != nil {
+		return true
+	}
+
+	return hostname != "" && strings.Contains(hostname, ".")
+}
+
+// storeSubmitTargets writes validated targets to a new file in dir,
+// returning the path to the file
+func storeSubmitTargets(dir string, name string, targets []string) (string, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	base = strings.Trim(unsafeFileChars.ReplaceAllString(base, "_"), "._")
+	if base == "" {
+		base = "targets"
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.txt", time.Now().Format("20060102-150405"), base))
+	if err := os.WriteFile(path, []byte(strings.Join(targets, "\n")+"\n"), 0640); err != nil {
+		return "", fmt.Errorf("failed to write upload file: %w", err)
+	}
+
+	return path, nil
+}
+
+// saveSubmitDomains records the hostnames of uploaded targets as domains
+// of the scan session
+func saveSubmitDomains(db *gorm.DB, targets []string, scanSessionID *uint) error {
+	now := time.Now()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		seen := make(map[string]bool)
+
+		for _, target := range targets {
+			if !strings.Contains(target, "://") {
+				target = "http://" + target
+			}
+			u, err := url.Parse(target)
+			if err != nil {
+				continue
+			}
+
+			hostname := strings.ToLower(u.Hostname())
+			if hostname == "" || net.ParseIP(hostname) != nil || seen[hostname] {
+				continue
+			}
+			seen[hostname] = true
+
+			if err := database.SaveDomain(tx, &models.Domain{
+				Name:          hostname,
+				Source:        submitFileSource,
+				LastSeen:      now,
+				ScanSessionID: scanSessionID,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// readSubmitURLs collects the urls a file reader produces
+func readSubmitURLs(reader *readers.FileReader) ([]string, error) {
+	ch := make(chan string)
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- reader.Read(ch)
+	}()
+
+	var urls []string
+	for u := range ch {
+		urls = append(urls, u)
+	}
+
+	return urls, <-errCh
+}
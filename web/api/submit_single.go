@@ -25,17 +25,19 @@ type submitSingleRequest struct {
 //	@Produce		json
 //	@Param			query	body		submitSingleRequest	true	"The URL scanning request object"
 //	@Success		200		{object}	models.Result		"The URL Result object"
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
 //	@Router			/submit/single [post]
 func (h *ApiHandler) SubmitSingleHandler(w http.ResponseWriter, r *http.Request) {
 	var request submitSingleRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		log.Error("failed to read json request", "err", err)
-		http.Error(w, "Error reading JSON request", http.StatusInternalServerError)
+		writeError(w, "Error reading JSON request", http.StatusInternalServerError)
 		return
 	}
 
 	if request.URL == "" {
-		http.Error(w, "No URL provided", http.StatusBadRequest)
+		writeError(w, "No URL provided", http.StatusBadRequest)
 		return
 	}
 
@@ -67,7 +69,7 @@ func (h *ApiHandler) SubmitSingleHandler(w http.ResponseWriter, r *http.Request)
 
 	writer, err := writers.NewMemoryWriter(1)
 	if err != nil {
-		http.Error(w, "Error getting a memory writer", http.StatusInternalServerError)
+		writeError(w, "Error getting a memory writer", http.StatusInternalServerError)
 		return
 	}
 
@@ -75,14 +77,14 @@ func (h *ApiHandler) SubmitSingleHandler(w http.ResponseWriter, r *http.Request)
 
 	driver, err := driver.NewChromedp(logger, *options)
 	if err != nil {
-		http.Error(w, "Error sarting driver", http.StatusInternalServerError)
+		writeError(w, "Error sarting driver", http.StatusInternalServerError)
 		return
 	}
 
 	runner, err := runner.NewRunner(logger, driver, *options, []writers.Writer{writer})
 	if err != nil {
 		log.Error("error starting runner", "err", err)
-		http.Error(w, "Error starting runner", http.StatusInternalServerError)
+		writeError(w, "Error starting runner", http.StatusInternalServerError)
 		return
 	}
 
@@ -96,7 +98,7 @@ func (h *ApiHandler) SubmitSingleHandler(w http.ResponseWriter, r *http.Request)
 
 	jsonData, err := json.Marshal(writer.GetLatest())
 	if err != nil {
-		http.Error(w, "Error creating JSON response", http.StatusInternalServerError)
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
 		return
 	}
 
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/sensepost/gowitness/pkg/export"
+	"github.com/sensepost/gowitness/pkg/log"
+)
+
+// TargetsHandler exports a target list for other tools
+//
+//	@Summary		Export a target list
+//	@Description	Exports a plain text target list, one target per line. Kinds are live URLs (for nuclei or ffuf), ip:port pairs (for nmap) and hostnames (for amass).
+//	@Tags			Results
+//	@Produce		plain
+//	@Param			kind			query		string	false	"The kind of target list, urls, ip-ports or hostnames. Defaults to urls."
+//	@Param			scan_session_id	query		int		false	"Only export targets from this scan session."
+//	@Param			tag				query		string	false	"Only export targets on IP addresses with this IP information tag."
+//	@Param			status			query		string	false	"Only export URLs with these response codes, comma separated (e.g. 200,3xx)."
+//	@Param			include_failed	query		bool	false	"Include URLs that failed to load."
+//	@Success		200				{string}	string	"One target per line"
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/targets [get]
+func (h *ApiHandler) TargetsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	options := export.TargetOptions{
+		Kind:          query.Get("kind"),
+		Tag:           query.Get("tag"),
+		IncludeFailed: query.Get("include_failed") == "true",
+	}
+	if options.Kind == "" {
+		options.Kind = export.TargetURLs
+	}
+	if !slices.Contains(export.TargetKinds, options.Kind) {
+		writeError(w, "kind must be urls, ip-ports or hostnames", http.StatusBadRequest)
+		return
+	}
+
+	if raw := query.Get("scan_session_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			writeError(w, "Invalid scan_session_id", http.StatusBadRequest)
+			return
+		}
+		sid := uint(id)
+		options.ScanSessionID = &sid
+	}
+
+	if raw := query.Get("status"); raw != "" {
+		if options.Kind != export.TargetURLs {
+			writeError(w, "status can only be used with the urls kind", http.StatusBadRequest)
+			return
+		}
+		status, err := export.ParseStatus(raw)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		options.Status = status
+	}
+
+	targets, err := export.Targets(h.DB, options)
+	if err != nil {
+		log.Error("failed to build target list", "err", err)
+		writeError(w, "Error exporting targets", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="gowitness-`+options.Kind+`.txt"`)
+	if err := export.WriteTargets(w, targets); err != nil {
+		log.Error("failed to write target list", "err", err)
+	}
+}
@@ -3,23 +3,59 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/sensepost/gowitness/pkg/log"
 	"github.com/sensepost/gowitness/pkg/models"
 )
 
 type technologyListResponse struct {
-	Value []string `json:"technologies"`
+	Value   []string            `json:"technologies"`
+	Stats   []*technologyStat   `json:"stats"`
+	Results []*technologyResult `json:"results,omitempty"`
+}
+
+type technologyStat struct {
+	Name     string               `json:"name"`
+	Count    int64                `json:"count"`
+	Versions []*technologyVersion `json:"versions"`
+}
+
+type technologyVersion struct {
+	Version string `json:"version"`
+	Count   int64  `json:"count"`
+}
+
+type technologyResult struct {
+	ID           uint     `json:"id"`
+	URL          string   `json:"url"`
+	Title        string   `json:"title"`
+	ResponseCode int      `json:"response_code"`
+	IPAddress    string   `json:"ip_address"`
+	Technology   string   `json:"technology"`
+	Version      string   `json:"version,omitempty"`
+	Vulns        []string `json:"vulns"`
+}
+
+// splitTechnology splits a wappalyzer fingerprint like Nginx:1.25.3 into
+// its name and version
+func splitTechnology(value string) (string, string) {
+	name, version, _ := strings.Cut(value, ":")
+	return name, version
 }
 
 // TechnologyListHandler lists technologies
 //
 //	@Summary		Get technology results
-//	@Description	Get all the unique technology detected.
+//	@Description	Get all the unique technology detected, with result counts per technology and version.
+//	@Description	When filtering by technology, the matching results are returned too, with known vulnerabilities of their IP address.
 //	@Tags			Results
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	technologyListResponse
+//	@Param			tech	query		string	false	"Only return results running this technology, e.g. Jenkins. Matched case-insensitively, on any version."
+//	@Success		200		{object}	technologyListResponse
+//	@Failure		500		{object}	ErrorResponse
 //	@Router			/results/technology [get]
 func (h *ApiHandler) TechnologyListHandler(w http.ResponseWriter, r *http.Request) {
 	var results = &technologyListResponse{}
@@ -28,14 +64,143 @@ func (h *ApiHandler) TechnologyListHandler(w http.ResponseWriter, r *http.Reques
 		Find(&results.Value).Error; err != nil {
 
 		log.Error("could not find distinct technologies", "err", err)
+		writeError(w, "Error retrieving technologies", http.StatusInternalServerError)
+		return
+	}
+
+	var counts []struct {
+		Value string
+		Count int64
+	}
+	if err := h.DB.Model(&models.Technology{}).
+		Select("value, count(DISTINCT result_id) as count").
+		Group("value").Scan(&counts).Error; err != nil {
+
+		log.Error("could not count technologies", "err", err)
+		writeError(w, "Error counting technologies", http.StatusInternalServerError)
 		return
 	}
 
+	stats := make(map[string]*technologyStat)
+	for _, c := range counts {
+		name, version := splitTechnology(c.Value)
+		stat, ok := stats[name]
+		if !ok {
+			stat = &technologyStat{Name: name, Versions: make([]*technologyVersion, 0)}
+			stats[name] = stat
+		}
+
+		// a result only has one version of a technology, so these add up
+		stat.Count += c.Count
+		if version != "" {
+			stat.Versions = append(stat.Versions, &technologyVersion{Version: version, Count: c.Count})
+		}
+	}
+
+	results.Stats = make([]*technologyStat, 0, len(stats))
+	for _, stat := range stats {
+		sort.Slice(stat.Versions, func(i, j int) bool {
+			return stat.Versions[i].Count > stat.Versions[j].Count
+		})
+		results.Stats = append(results.Stats, stat)
+	}
+	sort.Slice(results.Stats, func(i, j int) bool {
+		if results.Stats[i].Count == results.Stats[j].Count {
+			return results.Stats[i].Name < results.Stats[j].Name
+		}
+		return results.Stats[i].Count > results.Stats[j].Count
+	})
+
+	if tech := strings.TrimSpace(r.URL.Query().Get("tech")); tech != "" {
+		techResults, err := h.technologyResults(tech)
+		if err != nil {
+			log.Error("could not get results for technology", "tech", tech, "err", err)
+			writeError(w, "Error retrieving results for technology", http.StatusInternalServerError)
+			return
+		}
+		results.Results = techResults
+	}
+
 	jsonData, err := json.Marshal(results)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Write(jsonData)
 }
+
+// technologyResults returns the results running a technology, cross
+// referenced against the known vulnerabilities of their IP addresses
+func (h *ApiHandler) technologyResults(tech string) ([]*technologyResult, error) {
+	lower := strings.ToLower(tech)
+
+	var technologies []models.Technology
+	if err := h.DB.Where("LOWER(value) = ? OR LOWER(value) LIKE ?", lower, lower+":%").
+		Find(&technologies).Error; err != nil {
+		return nil, err
+	}
+
+	if len(technologies) == 0 {
+		return []*technologyResult{}, nil
+	}
+
+	ids := make([]uint, 0, len(technologies))
+	values := make(map[uint]string, len(technologies))
+	for _, t := range technologies {
+		ids = append(ids, t.ResultID)
+		values[t.ResultID] = t.Value
+	}
+
+	var matched []models.Result
+	if err := h.DB.Select("id", "url", "title", "response_code", "ip_address").
+		Order("id").Find(&matched, ids).Error; err != nil {
+		return nil, err
+	}
+
+	// known vulnerabilities, by ip address
+	ips := make([]string, 0, len(matched))
+	for _, result := range matched {
+		if result.IPAddress != "" {
+			ips = append(ips, result.IPAddress)
+		}
+	}
+
+	vulns := make(map[string][]string)
+	if len(ips) > 0 {
+		var infos []models.IPInfo
+		if err := h.DB.Select("ip_address", "vulns").Where("ip_address IN ?", ips).
+			Find(&infos).Error; err != nil {
+			return nil, err
+		}
+
+		for _, info := range infos {
+			if v, err := info.GetVulns(); err == nil {
+				vulns[info.IPAddress] = v
+			}
+		}
+	}
+
+	techResults := make([]*technologyResult, 0, len(matched))
+	for _, result := range matched {
+		name, version := splitTechnology(values[result.ID])
+
+		resultVulns := vulns[result.IPAddress]
+		if resultVulns == nil {
+			resultVulns = []string{}
+		}
+
+		techResults = append(techResults, &technologyResult{
+			ID:           result.ID,
+			URL:          result.URL,
+			Title:        result.Title,
+			ResponseCode: result.ResponseCode,
+			IPAddress:    result.IPAddress,
+			Technology:   name,
+			Version:      version,
+			Vulns:        resultVulns,
+		})
+	}
+
+	return techResults, nil
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/plugins"
+	"github.com/sensepost/gowitness/pkg/tickets"
+)
+
+type createTicketsRequest struct {
+	Provider      string `json:"provider"`
+	MinSeverity   string `json:"min_severity"`
+	ScanSessionID *uint  `json:"scan_session_id"`
+	SkipFindings  bool   `json:"skip_findings"`
+	SkipServices  bool   `json:"skip_services"`
+	DryRun        bool   `json:"dry_run"`
+}
+
+// CreateTicketsHandler creates tickets for findings and exposed services
+//
+//	@Summary		Create tickets
+//	@Description	Creates a ticket for every finding and newly exposed service at or above the minimum severity (high by default) that was not ticketed before, attaching the screenshot of the affected host. The ticketing system is configured with environment variables on the server, e.g. JIRA_URL, JIRA_PROJECT and JIRA_API_TOKEN.
+//	@Tags			Results
+//	@Accept			json
+//	@Produce		json
+//	@Param			query	body		createTicketsRequest	true	"The provider to create tickets in, e.g. jira, and what to ticket"
+//	@Success		200		{object}	tickets.Summary
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/tickets [post]
+func (h *ApiHandler) CreateTicketsHandler(w http.ResponseWriter, r *http.Request) {
+	var request createTicketsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Error("failed to read json request", "err", err)
+		writeError(w, "Error reading JSON request", http.StatusInternalServerError)
+		return
+	}
+
+	if request.Provider == "" {
+		request.Provider = "jira"
+	}
+	if request.MinSeverity != "" && !slices.Contains(plugins.Severities, request.MinSeverity) {
+		writeError(w, "Invalid min_severity", http.StatusBadRequest)
+		return
+	}
+
+	tracker, err := tickets.New(request.Provider)
+	if errors.Is(err, tickets.ErrUnknownProvider) {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Error("failed to configure ticketing provider", "provider", request.Provider, "err", err)
+		writeError(w, "The ticketing provider is not configured on the server", http.StatusBadRequest)
+		return
+	}
+
+	log.Info("creating tickets", "provider", request.Provider, "min-severity", request.MinSeverity,
+		"scan-session-id", request.ScanSessionID, "dry-run", request.DryRun)
+
+	summary, err := tickets.Run(slog.New(log.Logger), h.DB, tracker, tickets.Options{
+		MinSeverity:    request.MinSeverity,
+		SkipFindings:   request.SkipFindings,
+		SkipServices:   request.SkipServices,
+		ScanSessionID:  request.ScanSessionID,
+		ScreenshotPath: h.ScreenshotPath,
+		DryRun:         request.DryRun,
+	})
+	if err != nil {
+		log.Error("failed to create tickets", "err", err)
+		writeError(w, "Error creating tickets", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(summary)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
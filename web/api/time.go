@@ -0,0 +1,51 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// displayLocation returns the location timestamps should be converted to for
+// display, taken from the tz query parameter (e.g. ?tz=Europe/Copenhagen).
+// fallback is used when the parameter is not set.
+func displayLocation(r *http.Request, fallback *time.Location) (*time.Location, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return fallback, nil
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", tz)
+	}
+
+	return loc, nil
+}
+
+// sessionLocation returns the timezone a scan session was run in, or UTC
+// if the session did not record one
+func sessionLocation(session *models.ScanSession) *time.Location {
+	if session == nil || session.Timezone == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(session.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// formatTime formats a timestamp as RFC3339 in loc. Zero times are
+// returned as an empty string.
+func formatTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.In(loc).Format(time.RFC3339)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/tlsaudit"
+)
+
+// TLSExpiringHandler lists certificates that are about to expire
+//
+//	@Summary		Expiring certificates
+//	@Description	Lists the certificates of probed hosts that expire within the given number of days, or already expired, soonest first.
+//	@Tags			Results
+//	@Produce		json
+//	@Param			days			query	int	false	"Days ahead to list expiring certificates for. Defaults to 30."
+//	@Param			scan_session_id	query	int	false	"Only list certificates from this scan session."
+//	@Success		200				{array}	tlsaudit.Entry
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/tls/expiring [get]
+func (h *ApiHandler) TLSExpiringHandler(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.tlsReport(w, r)
+	if !ok {
+		return
+	}
+
+	jsonData, err := json.Marshal(report.Expiring)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// TLSReportHandler reports TLS issues
+//
+//	@Summary		TLS report
+//	@Description	Reports the certificates of probed hosts that are about to expire or are self-signed, and hosts that negotiated a deprecated protocol version or a weak cipher.
+//	@Tags			Results
+//	@Produce		json
+//	@Param			days			query		int	false	"Days ahead to list expiring certificates for. Defaults to 30."
+//	@Param			scan_session_id	query		int	false	"Only report on hosts from this scan session."
+//	@Success		200				{object}	tlsaudit.Report
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		500				{object}	ErrorResponse
+//	@Router			/tls/report [get]
+func (h *ApiHandler) TLSReportHandler(w http.ResponseWriter, r *http.Request) {
+	report, ok := h.tlsReport(w, r)
+	if !ok {
+		return
+	}
+
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// tlsReport audits the TLS of the results a request asks for, writing an
+// error response if that fails
+func (h *ApiHandler) tlsReport(w http.ResponseWriter, r *http.Request) (*tlsaudit.Report, bool) {
+	query := r.URL.Query()
+
+	days := tlsaudit.DefaultDays
+	if raw := query.Get("days"); raw != "" {
+		var err error
+		if days, err = strconv.Atoi(raw); err != nil || days < 0 {
+			writeError(w, "Invalid days", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	q := h.DB.Model(&models.Result{}).Select("id", "url", "final_url").Preload("TLS")
+	if raw := query.Get("scan_session_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			writeError(w, "Invalid scan_session_id", http.StatusBadRequest)
+			return nil, false
+		}
+		q = q.Where("scan_session_id = ?", id)
+	}
+
+	var results []models.Result
+	if err := q.Order("probed_at DESC").Find(&results).Error; err != nil {
+		log.Error("failed to get results for tls report", "err", err)
+		writeError(w, "Error retrieving results", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return tlsaudit.Audit(results, days, time.Now()), true
+}
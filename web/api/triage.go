@@ -0,0 +1,278 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"gorm.io/gorm"
+)
+
+// anonymousAnalyst is who tags and notes added through the API are
+// attributed to when the server does not use per-user logins
+const anonymousAnalyst = "analyst"
+
+// maxTagLength is the longest tag name analysts can add
+const maxTagLength = 64
+
+// tagListEntry is a tag and the number of results tagged with it
+type tagListEntry struct {
+	models.Tag
+	Results int64 `json:"results"`
+}
+
+type tagRequest struct {
+	Name string `json:"name"`
+}
+
+type triageRequest struct {
+	IDs []uint `json:"ids"`
+	// Fields that are not set are left as they are
+	Reviewed    *bool   `json:"reviewed,omitempty"`
+	Interesting *bool   `json:"interesting,omitempty"`
+	AssignedTo  *string `json:"assigned_to,omitempty"` // an empty username unassigns
+}
+
+// TagsHandler lists tags
+//
+//	@Summary		List tags
+//	@Description	Lists the tags results can be tagged with, and how many results have each tag.
+//	@Tags			Results
+//	@Produce		json
+//	@Success		200	{array}		tagListEntry
+//	@Failure		500	{object}	ErrorResponse
+//	@Router			/tags [get]
+func (h *ApiHandler) TagsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := database.SyncTags(h.DB); err != nil {
+		log.Warn("failed to sync tags", "err", err)
+	}
+
+	var tags []tagListEntry
+	if err := h.DB.Model(&models.Tag{}).
+		Select("tags.*, COUNT(result_tags.id) AS results").
+		Joins("LEFT JOIN result_tags ON result_tags.name = tags.name").
+		Group("tags.id").Order("tags.name").
+		Scan(&tags).Error; err != nil {
+		log.Error("failed to list tags", "err", err)
+		writeError(w, "Error listing tags", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(tags)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// AddResultTagHandler tags a result
+//
+//	@Summary		Tag a result
+//	@Description	Tags a result, creating the tag if it does not exist yet. Tag names are lowercased.
+//	@Tags			Results
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int			true	"The result ID to tag."
+//	@Param			query	body		tagRequest	true	"The tag to add"
+//	@Success		200		{object}	models.ResultTag
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/results/{id}/tags [post]
+func (h *ApiHandler) AddResultTagHandler(w http.ResponseWriter, r *http.Request) {
+	result, ok := h.triageResult(w, r)
+	if !ok {
+		return
+	}
+
+	var request tagRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.ToLower(strings.TrimSpace(request.Name))
+	if name == "" || len(name) > maxTagLength {
+		writeError(w, "Tag names must be between 1 and 64 characters", http.StatusBadRequest)
+		return
+	}
+
+	source := anonymousAnalyst
+	if user := RequestUser(r); user != nil {
+		source = user.Username
+	}
+
+	tag := models.ResultTag{ResultID: result.ID, Name: name, Source: source}
+	if err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(models.Tag{Name: name}).FirstOrCreate(&models.Tag{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where(models.ResultTag{ResultID: result.ID, Name: name}).
+			Attrs(models.ResultTag{Source: source}).FirstOrCreate(&tag).Error
+	}); err != nil {
+		log.Error("failed to tag result", "id", result.ID, "tag", name, "err", err)
+		writeError(w, "Error tagging result", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(tag)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// RemoveResultTagHandler removes a tag from a result
+//
+//	@Summary		Untag a result
+//	@Description	Removes a tag from a result, whoever added it.
+//	@Tags			Results
+//	@Produce		json
+//	@Param			id		path		int		true	"The result ID to untag."
+//	@Param			name	path		string	true	"The tag to remove."
+//	@Success		200		{string}	string	"ok"
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/results/{id}/tags/{name} [delete]
+func (h *ApiHandler) RemoveResultTagHandler(w http.ResponseWriter, r *http.Request) {
+	result, ok := h.triageResult(w, r)
+	if !ok {
+		return
+	}
+
+	name := strings.ToLower(chi.URLParam(r, "name"))
+	if err := h.DB.Where("result_id = ? AND name = ?", result.ID, name).
+		Delete(&models.ResultTag{}).Error; err != nil {
+		log.Error("failed to untag result", "id", result.ID, "tag", name, "err", err)
+		writeError(w, "Error untagging result", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(`ok`)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// TriageHandler updates the triage state of results
+//
+//	@Summary		Triage results
+//	@Description	Marks results as reviewed or interesting, and assigns them to an analyst. Only the fields that are set are changed, so many results can be triaged at once.
+//	@Tags			Results
+//	@Accept			json
+//	@Produce		json
+//	@Param			query	body		triageRequest	true	"The results and triage state to set"
+//	@Success		200		{string}	string			"ok"
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		500		{object}	ErrorResponse
+//	@Router			/results/triage [post]
+func (h *ApiHandler) TriageHandler(w http.ResponseWriter, r *http.Request) {
+	var request triageRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if len(request.IDs) == 0 {
+		writeError(w, "No result ids given", http.StatusBadRequest)
+		return
+	}
+
+	user := RequestUser(r)
+	updates := make(map[string]interface{})
+
+	if request.Reviewed != nil {
+		updates["reviewed"] = *request.Reviewed
+		if *request.Reviewed {
+			now := time.Now()
+			updates["reviewed_at"] = &now
+			updates["reviewed_by"] = ""
+			if user != nil {
+				updates["reviewed_by"] = user.Username
+			}
+		} else {
+			updates["reviewed_at"] = nil
+			updates["reviewed_by"] = ""
+		}
+	}
+
+	if request.Interesting != nil {
+		updates["interesting"] = *request.Interesting
+	}
+
+	if request.AssignedTo != nil {
+		assignee := strings.TrimSpace(*request.AssignedTo)
+		// with per-user logins, results can only be assigned to users
+		if assignee != "" && user != nil {
+			var count int64
+			if err := h.DB.Model(&models.User{}).Where("username = ?", assignee).Count(&count).Error; err != nil {
+				log.Error("failed to look up assignee", "err", err)
+				writeError(w, "Error looking up user", http.StatusInternalServerError)
+				return
+			}
+			if count == 0 {
+				writeError(w, "Unknown user "+assignee, http.StatusBadRequest)
+				return
+			}
+		}
+		updates["assigned_to"] = assignee
+	}
+
+	if len(updates) == 0 {
+		writeError(w, "Nothing to update", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DB.Model(&models.Result{}).Where("id IN ?", request.IDs).
+		UpdateColumns(updates).Error; err != nil {
+		log.Error("failed to triage results", "err", err)
+		writeError(w, "Error updating results", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(`ok`)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// triageResult returns the result a triage request is for, writing an error
+// response if it does not exist
+func (h *ApiHandler) triageResult(w http.ResponseWriter, r *http.Request) (*models.Result, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		writeError(w, "Invalid result id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var result models.Result
+	if err := h.DB.Select("id").First(&result, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeError(w, "Result not found", http.StatusNotFound)
+			return nil, false
+		}
+		log.Error("failed to get result", "id", id, "err", err)
+		writeError(w, "Error retrieving result", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return &result, true
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/pkg/auth"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// userContextKey is the request context key of the logged in user
+type userContextKey struct{}
+
+// WithUser returns a copy of ctx carrying the logged in user
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// RequestUser returns the logged in user of a request. It is nil when the
+// server does not use per-user logins.
+func RequestUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(userContextKey{}).(*models.User)
+	return user
+}
+
+// MeHandler returns the logged in user
+//
+//	@Summary		Get the current user
+//	@Description	Returns the logged in user. Only available when the server uses per-user logins.
+//	@Tags			Users
+//	@Produce		json
+//	@Success		200	{object}	models.User
+//	@Failure		404	{object}	ErrorResponse
+//	@Router			/users/me [get]
+func (h *ApiHandler) MeHandler(w http.ResponseWriter, r *http.Request) {
+	user := RequestUser(r)
+	if user == nil {
+		writeError(w, "Per-user logins are not enabled", http.StatusNotFound)
+		return
+	}
+
+	jsonData, err := json.Marshal(user)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+type createUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// UsersHandler lists users
+//
+//	@Summary		List users
+//	@Description	Lists the users that can log in. Requires the admin role.
+//	@Tags			Users
+//	@Produce		json
+//	@Success		200	{array}		models.User
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		500	{object}	ErrorResponse
+//	@Router			/users [get]
+func (h *ApiHandler) UsersHandler(w http.ResponseWriter, r *http.Request) {
+	var users []models.User
+	if err := h.DB.Order("username").Find(&users).Error; err != nil {
+		log.Error("failed to list users", "err", err)
+		writeError(w, "Error listing users", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(users)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// CreateUserHandler creates a user
+//
+//	@Summary		Create a user
+//	@Description	Creates a user with a role of admin, analyst or viewer. Requires the admin role.
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			query	body		createUserRequest	true	"The user to create"
+//	@Success		200		{object}	models.User
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Router			/users [post]
+func (h *ApiHandler) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
+	var request createUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, "Error reading JSON request", http.StatusBadRequest)
+		return
+	}
+
+	user, err := auth.CreateUser(h.DB, request.Username, request.Password, request.Role)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Info("created user", "user", user.Username, "role", user.Role, "by", RequestUser(r).Username)
+	jsonData, err := json.Marshal(user)
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+// DeleteUserHandler deletes a user
+//
+//	@Summary		Delete a user
+//	@Description	Deletes a user, ending their sessions. Admins can't delete themselves. Requires the admin role.
+//	@Tags			Users
+//	@Produce		json
+//	@Param			id	path		int	true	"The user ID"
+//	@Success		200	{string}	string	"ok"
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Router			/users/{id} [delete]
+func (h *ApiHandler) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		writeError(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	current := RequestUser(r)
+	if uint(id) == current.ID {
+		writeError(w, "You can't delete yourself", http.StatusBadRequest)
+		return
+	}
+
+	result := h.DB.Delete(&models.User{}, id)
+	if result.Error != nil {
+		log.Error("failed to delete user", "err", result.Error)
+		writeError(w, "Error deleting user", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		writeError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// sessions and identities cascade on postgres and mysql, but sqlite
+	// needs a hand
+	h.DB.Where("user_id = ?", id).Delete(&models.UserSession{})
+	h.DB.Where("user_id = ?", id).Delete(&models.UserIdentity{})
+
+	log.Info("deleted user", "id", id, "by", current.Username)
+	jsonData, err := json.Marshal("ok")
+	if err != nil {
+		writeError(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
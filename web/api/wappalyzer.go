@@ -4,6 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+
+	wappalyzer "github.com/projectdiscovery/wappalyzergo"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/vulndb"
 )
 
 // probably not the smartest idea, but he
@@ -17,6 +23,7 @@ const iconBase = `https://raw.githubusercontent.com/enthec/webappanalyzer/main/s
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{object}	map[string]string
+//	@Failure		500	{object}	ErrorResponse
 //	@Router			/wappalyzer [get]
 func (h *ApiHandler) WappalyzerHandler(w http.ResponseWriter, r *http.Request) {
 	response := make(map[string]string)
@@ -31,7 +38,107 @@ func (h *ApiHandler) WappalyzerHandler(w http.ResponseWriter, r *http.Request) {
 
 	jsonData, err := json.Marshal(response)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
+
+type wappalyzerTechnology struct {
+	Name       string               `json:"name"`
+	Icon       string               `json:"icon,omitempty"`
+	Website    string               `json:"website,omitempty"`
+	Categories []string             `json:"categories"`
+	CPE        string               `json:"cpe,omitempty"`
+	Count      int64                `json:"count"`
+	Versions   []*wappalyzerVersion `json:"versions"`
+}
+
+type wappalyzerVersion struct {
+	Version string `json:"version"`
+	CPE     string `json:"cpe,omitempty"`
+	Count   int64  `json:"count"`
+}
+
+// WappalyzerVersionsHandler returns the detected technologies with the
+// versions seen of each
+//
+//	@Summary		Get detected technology versions
+//	@Description	Get the detected technologies with their wappalyzer details and CPE name, and the versions seen of each with result counts.
+//	@Description	Versions are ordered newest first, so that results running older versions stand out.
+//	@Tags			Results
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]wappalyzerTechnology
+//	@Failure		500	{object}	ErrorResponse
+//	@Router			/wappalyzer/versions [get]
+func (h *ApiHandler) WappalyzerVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	var counts []struct {
+		Name    string
+		Version string
+		CPE     string
+		Count   int64
+	}
+	if err := h.DB.Model(&models.Technology{}).
+		Select("name, version, cpe, count(DISTINCT result_id) as count").
+		Group("name, version, cpe").Scan(&counts).Error; err != nil {
+
+		log.Error("could not count technology versions", "err", err)
+		writeError(w, "Error counting technology versions", http.StatusInternalServerError)
+		return
+	}
+
+	apps := h.Wappalyzer.GetCompiledFingerprints().Apps
+	technologies := make(map[string]*wappalyzerTechnology)
+	for _, c := range counts {
+		technology, ok := technologies[c.Name]
+		if !ok {
+			technology = &wappalyzerTechnology{
+				Name:       c.Name,
+				Categories: []string{},
+				Versions:   make([]*wappalyzerVersion, 0),
+			}
+			if fingerprint, ok := apps[c.Name]; ok {
+				info := wappalyzer.AppInfoFromFingerprint(fingerprint)
+				technology.Website = info.Website
+				technology.Categories = info.Categories
+				technology.CPE = info.CPE
+				if info.Icon != "" {
+					technology.Icon = iconBase + info.Icon
+				}
+			}
+			technologies[c.Name] = technology
+		}
+
+		// a result only has one version of a technology, so these add up
+		technology.Count += c.Count
+		if c.Version != "" {
+			technology.Versions = append(technology.Versions, &wappalyzerVersion{
+				Version: c.Version,
+				CPE:     c.CPE,
+				Count:   c.Count,
+			})
+		}
+	}
+
+	response := make([]*wappalyzerTechnology, 0, len(technologies))
+	for _, technology := range technologies {
+		sort.Slice(technology.Versions, func(i, j int) bool {
+			return vulndb.CompareVersions(technology.Versions[i].Version, technology.Versions[j].Version) > 0
+		})
+		response = append(response, technology)
+	}
+	sort.Slice(response, func(i, j int) bool {
+		if response[i].Count == response[j].Count {
+			return response[i].Name < response[j].Name
+		}
+		return response[i].Count > response[j].Count
+	})
+
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
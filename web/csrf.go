@@ -0,0 +1,121 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/sensepost/gowitness/pkg/log"
+)
+
+const (
+	// csrfCookieName is the cookie holding the csrf token. It is readable
+	// by javascript so that the spa can echo it back in csrfHeaderName.
+	csrfCookieName = "gowitness_csrf"
+	// csrfHeaderName is the header state changing requests need to send
+	// the csrf token in
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// newCSRFToken generates a new random csrf token
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// csrfSafeMethod checks if a request method is one that does not change state
+func csrfSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
+
+// issueCSRFToken returns the csrf token in the request's cookie, or
+// generates a new one and sets it as a cookie on the response.
+func issueCSRFToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(csrfCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+
+	token, err := newCSRFToken()
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: false,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return token, nil
+}
+
+// csrfMiddleware implements double submit cookie csrf protection.
+//
+// Safe requests get a csrf token cookie issued if they don't have one yet.
+// State changing requests (POST, DELETE etc.) must send the same token in
+// the X-CSRF-Token header. A cross-site page can make the browser send the
+// cookie, but it can't read it to set the header.
+func csrfMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if csrfSafeMethod(r.Method) {
+			if _, err := issueCSRFToken(w, r); err != nil {
+				log.Error("failed to issue csrf token", "err", err)
+				http.Error(w, "Error issuing csrf token", http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie(csrfCookieName)
+		header := r.Header.Get(csrfHeaderName)
+		if err != nil || cookie.Value == "" || header == "" ||
+			subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+			log.Warn("rejected request with a missing or invalid csrf token", "remote", r.RemoteAddr, "path", r.URL.Path)
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// csrfTokenHandler returns the current csrf token, issuing one if needed.
+// This is useful for api clients that are not the spa.
+//
+//	@Summary		Get a CSRF token
+//	@Description	Returns a CSRF token that must be sent in the X-CSRF-Token header of state changing requests, together with the gowitness_csrf cookie.
+//	@Tags			Security
+//	@Produce		json
+//	@Success		200	{object}	map[string]string
+//	@Router			/csrf [get]
+func csrfTokenHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := issueCSRFToken(w, r)
+	if err != nil {
+		http.Error(w, "Error issuing csrf token", http.StatusInternalServerError)
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]string{"token": token})
+	if err != nil {
+		http.Error(w, "Error creating JSON response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonData)
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd)
+
+package web
+
+// freeDiskSpace is not supported on this platform
+func freeDiskSpace(path string) (int64, error) {
+	return 0, errDiskSpaceUnsupported
+}
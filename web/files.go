@@ -0,0 +1,33 @@
+package web
+
+import (
+	"net/http"
+	"os"
+)
+
+// fileOnlyFS is an http.FileSystem that refuses to open directories, so
+// that http.FileServer can't be used to list them
+type fileOnlyFS struct {
+	fs http.FileSystem
+}
+
+// Open opens a file, returning os.ErrNotExist for directories
+func (f fileOnlyFS) Open(name string) (http.File, error) {
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if stat.IsDir() {
+		file.Close()
+		return nil, os.ErrNotExist
+	}
+
+	return file, nil
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/web/api"
+	"github.com/sensepost/gowitness/web/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// serveGRPC serves the gRPC machine API on its own port. It is subject to
+// the ip allowlist, and served over TLS if the web interface is.
+func (s *Server) serveGRPC(apih *api.ApiHandler) {
+	server := rpc.NewServer(apih.DB, apih.Jobs, s.ScreenshotPath, s.GRPCToken)
+	server.ReadOnly = s.ReadOnly
+
+	var opts []grpc.ServerOption
+	if s.tlsEnabled() {
+		creds, err := credentials.NewServerTLSFromFile(s.TLSCert, s.TLSKey)
+		if err != nil {
+			log.Error("failed to load grpc tls certificate", "err", err)
+			return
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(s.Host, strconv.Itoa(s.GRPCPort)))
+	if err != nil {
+		log.Error("grpc server listen error", "err", err)
+		return
+	}
+
+	log.Info("starting grpc server", "host", s.Host, "port", s.GRPCPort, "service", rpc.Gowitness_ServiceDesc.ServiceName)
+
+	err = server.GRPCServer(opts...).Serve(&allowedIPsListener{Listener: listener, server: s})
+	log.Error("grpc server listen error", "err", err)
+}
+
+// allowedIPsListener closes connections from addresses outside of the
+// configured allowlist as they are accepted
+type allowedIPsListener struct {
+	net.Listener
+	server *Server
+}
+
+func (l *allowedIPsListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil || len(l.server.AllowedIPs) == 0 || ipAllowed(conn.RemoteAddr().String(), l.server.AllowedIPs) {
+			return conn, err
+		}
+
+		log.Warn("rejected grpc connection from address outside the allowlist", "remote", conn.RemoteAddr())
+		conn.Close()
+	}
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/cors"
+)
+
+// DefaultContentSecurityPolicy is the content security policy of the spa
+// and login pages. The spa only loads its own scripts, but charts and
+// component styles are set inline.
+const DefaultContentSecurityPolicy = "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data: blob:; font-src 'self' data:; connect-src 'self'; object-src 'none'; " +
+	"base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+
+// securityHeadersMiddleware sets headers that harden responses against
+// content sniffing, framing and, through the content security policy,
+// script injection
+func (s *Server) securityHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "same-origin")
+		if s.tlsEnabled() {
+			h.Set("Strict-Transport-Security", "max-age=31536000")
+		}
+
+		// the swagger ui is made of inline scripts, so it is left out
+		if s.ContentSecurityPolicy != "" && !strings.HasPrefix(r.URL.Path, "/swagger/") {
+			h.Set("Content-Security-Policy", s.ContentSecurityPolicy)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsMiddleware allows cross-origin requests from AllowedOrigins.
+// Without allowed origins, no cross-origin requests are allowed.
+func (s *Server) corsMiddleware() func(http.Handler) http.Handler {
+	if len(s.AllowedOrigins) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return cors.Handler(cors.Options{
+		AllowedOrigins: s.AllowedOrigins,
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders: []string{"Accept", "Content-Type", csrfHeaderName},
+		// logins are cookies, which browsers only send to a wildcard
+		// origin without credentials
+		AllowCredentials: !containsWildcard(s.AllowedOrigins),
+		MaxAge:           300,
+	})
+}
+
+// containsWildcard checks if origins allows any origin
+func containsWildcard(origins []string) bool {
+	for _, origin := range origins {
+		if origin == "*" {
+			return true
+		}
+	}
+
+	return false
+}
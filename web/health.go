@@ -0,0 +1,138 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/sensepost/gowitness/pkg/jobs"
+)
+
+// healthCheckTimeout is how long a readiness check may take
+const healthCheckTimeout = 2 * time.Second
+
+// errDiskSpaceUnsupported is returned by freeDiskSpace on platforms where
+// free disk space can not be determined
+var errDiskSpaceUnsupported = errors.New("free disk space is not supported on this platform")
+
+// healthCheck is the outcome of a readiness check
+type healthCheck struct {
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// healthResponse is the response of the health endpoints
+type healthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]healthCheck `json:"checks,omitempty"`
+}
+
+// writeHealth writes a health response, with a 503 if it is not ok
+func writeHealth(w http.ResponseWriter, response healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if response.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// healthzHandler reports that the server is alive. It does no checks, so
+// that a slow database does not get the server restarted.
+func (s *Server) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, healthResponse{Status: "ok"})
+}
+
+// readyzHandler reports if the server can serve requests and run jobs:
+// the database is reachable, the screenshot path has free disk space and,
+// unless agents take screenshots, Chrome is installed
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	checks := map[string]healthCheck{
+		"database": s.checkDatabase(ctx),
+		"disk":     s.checkDiskSpace(),
+	}
+	if s.needsChrome() {
+		checks["chrome"] = checkChrome()
+	}
+
+	response := healthResponse{Status: "ok", Checks: checks}
+	for _, check := range checks {
+		if !check.OK {
+			response.Status = "unavailable"
+		}
+	}
+
+	writeHealth(w, response)
+}
+
+// checkDatabase pings the database
+func (s *Server) checkDatabase(ctx context.Context) healthCheck {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return healthCheck{Detail: err.Error()}
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return healthCheck{Detail: err.Error()}
+	}
+
+	return healthCheck{OK: true}
+}
+
+// checkDiskSpace checks that the screenshot path has MinFreeDisk bytes
+// free
+func (s *Server) checkDiskSpace() healthCheck {
+	// the screenshot path is only created once a screenshot is taken, so
+	// check the closest directory that exists
+	path, err := filepath.Abs(s.ScreenshotPath)
+	if err != nil {
+		return healthCheck{Detail: err.Error()}
+	}
+	for {
+		if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+
+	free, err := freeDiskSpace(path)
+	if errors.Is(err, errDiskSpaceUnsupported) {
+		return healthCheck{OK: true, Detail: err.Error()}
+	}
+	if err != nil {
+		return healthCheck{Detail: err.Error()}
+	}
+
+	detail := fmt.Sprintf("%d MB free", free>>20)
+	if free < s.MinFreeDisk {
+		return healthCheck{Detail: detail + fmt.Sprintf(", %d MB required", s.MinFreeDisk>>20)}
+	}
+
+	return healthCheck{OK: true, Detail: detail}
+}
+
+// needsChrome checks if the server takes screenshots itself, which it does
+// unless probe and retake jobs are left to agents
+func (s *Server) needsChrome() bool {
+	return !slices.Contains(s.AgentKinds, jobs.KindProbe) || !slices.Contains(s.AgentKinds, jobs.KindRetake)
+}
+
+// checkChrome checks that a Chrome or Chromium binary is installed
+func checkChrome() healthCheck {
+	path, ok := launcher.LookPath()
+	if !ok {
+		return healthCheck{Detail: "chrome not found"}
+	}
+
+	return healthCheck{OK: true, Detail: path}
+}
@@ -0,0 +1,30 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sensepost/gowitness/pkg/log"
+)
+
+// requestLogger logs every request with the gowitness logger, so that
+// request logs honour the configured log level and format
+func requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		defer func() {
+			log.Info("request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", ww.Status(),
+				"bytes", ww.BytesWritten(),
+				"duration", time.Since(start).String(),
+				"remote", r.RemoteAddr)
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
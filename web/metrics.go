@@ -0,0 +1,44 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sensepost/gowitness/pkg/metrics"
+)
+
+var (
+	requests = metrics.NewCounter("gowitness_http_requests_total",
+		"Web server requests, by method, route and status", "method", "route", "status")
+	requestDuration = metrics.NewHistogram("gowitness_http_request_duration_seconds",
+		"How long web server requests took, by method and route", nil, "method", "route")
+)
+
+// requestMetrics records the count and latency of requests. Requests are
+// labelled with their route pattern, such as /api/results/detail/{id},
+// rather than their path, to keep the number of series bounded.
+func requestMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		// handlers that write nothing respond with a 200
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		requests.Inc(r.Method, route, strconv.Itoa(status))
+		requestDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
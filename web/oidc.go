@@ -0,0 +1,162 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/auth"
+	"github.com/sensepost/gowitness/pkg/log"
+)
+
+// oidcCookieName is the cookie holding the state and nonce of an OpenID
+// Connect login in progress
+const oidcCookieName = "gowitness_oidc"
+
+// oidcLoginTimeout is how long a user has to log in at the provider
+const oidcLoginTimeout = 10 * time.Minute
+
+// sessionLogins checks if users log in with their own session, either as
+// local users or through an identity provider
+func (s *Server) sessionLogins() bool {
+	return s.multiUser || s.oidc != nil
+}
+
+// oidcRedirectURL returns the URL the provider sends users back to
+func (s *Server) oidcRedirectURL(r *http.Request) string {
+	if s.OIDCRedirectURL != "" {
+		return s.OIDCRedirectURL
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+
+	return scheme + "://" + r.Host + getBasePath(r) + "login/oidc/callback"
+}
+
+// oidcLoginHandler sends the user to the identity provider to log in
+func (s *Server) oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	state, err := randomHex(16)
+	if err != nil {
+		log.Error("failed to generate oidc state", "err", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		log.Error("failed to generate oidc nonce", "err", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// the provider redirects back with a top level GET, which lax cookies
+	// are sent with
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    state + "." + nonce,
+		Path:     cookiePath(getBasePath(r)),
+		MaxAge:   int(oidcLoginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, s.oidc.AuthCodeURL(state, nonce, s.oidcRedirectURL(r)), http.StatusFound)
+}
+
+// oidcCallbackHandler completes a login at the identity provider, starting
+// a login session for the user
+func (s *Server) oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	basePath := getBasePath(r)
+
+	// the state and nonce are single use
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    "",
+		Path:     cookiePath(basePath),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		log.Warn("identity provider rejected login", "err", providerErr, "description", query.Get("error_description"))
+		s.renderLoginPage(w, "Single sign-on failed", basePath)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		s.renderLoginPage(w, "Single sign-on expired, please try again", basePath)
+		return
+	}
+	state, nonce, ok := strings.Cut(cookie.Value, ".")
+	if !ok || state == "" || query.Get("state") != state {
+		log.Warn("oidc state mismatch", "remote", r.RemoteAddr)
+		s.renderLoginPage(w, "Single sign-on failed, please try again", basePath)
+		return
+	}
+
+	claims, err := s.oidc.Exchange(r.Context(), query.Get("code"), nonce, s.oidcRedirectURL(r))
+	if err != nil {
+		log.Error("failed to complete oidc login", "err", err)
+		s.renderLoginPage(w, "Single sign-on failed", basePath)
+		return
+	}
+
+	if err := claims.Allowed(s.OIDCAllowedDomains, s.OIDCAllowedGroups); err != nil {
+		log.Warn("oidc user may not log in", "username", claims.Username(), "err", err)
+		s.renderLoginPage(w, "Your account may not log in", basePath)
+		return
+	}
+
+	user, err := auth.ExternalUser(s.db, claims.Issuer, claims.Subject, claims.Username(), s.OIDCRole)
+	if err != nil {
+		log.Warn("oidc user may not log in", "username", claims.Username(), "err", err)
+		s.renderLoginPage(w, "Your account may not log in", basePath)
+		return
+	}
+
+	token, err := auth.NewSession(s.db, user)
+	if err != nil {
+		log.Error("failed to start login session", "err", err)
+		s.renderLoginPage(w, "Login failed, please try again", basePath)
+		return
+	}
+
+	log.Info("user logged in with oidc", "username", user.Username, "role", user.Role)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     cookiePath(basePath),
+		Expires:  time.Now().Add(auth.SessionLifetime),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	// a strict cookie set on a cross site redirect is not sent with that
+	// redirect's next hop, so land on a same site page that moves on
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte(`<!DOCTYPE html><html><head><meta http-equiv="refresh" content="0;url=` +
+		html.EscapeString(basePath) + `"></head><body></body></html>`))
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,31 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/sensepost/gowitness/pkg/log"
+)
+
+// readOnlyRequest checks if a request only reads data
+func readOnlyRequest(r *http.Request) bool {
+	if csrfSafeMethod(r.Method) {
+		return true
+	}
+
+	// search is a POST, but only reads
+	return r.URL.Path == "/api/search"
+}
+
+// readOnlyMiddleware rejects state changing requests when the server is
+// running in read-only mode
+func (s *Server) readOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.ReadOnly || readOnlyRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log.Warn("rejected state changing request in read-only mode", "remote", r.RemoteAddr, "path", r.URL.Path)
+		http.Error(w, "Server is in read-only mode", http.StatusForbidden)
+	})
+}
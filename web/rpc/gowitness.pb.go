@@ -0,0 +1,892 @@
+// The gowitness machine API, served over gRPC by 'gowitness report server'
+// when --grpc-port is set. Calls authenticate with the server's gRPC token,
+// sent as "authorization: Bearer <token>" metadata.
+//
+// Timestamps are seconds since the Unix epoch, 0 when unset.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: gowitness.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SubmitTargetsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Urls  []string               `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	// URLs probed at once, the server default if 0
+	Threads int32 `protobuf:"varint,2,opt,name=threads,proto3" json:"threads,omitempty"`
+	// the most URLs to start probing per second, 0 for no limit
+	RateLimit int32 `protobuf:"varint,3,opt,name=rate_limit,json=rateLimit,proto3" json:"rate_limit,omitempty"`
+	// orders queued jobs, highest first
+	Priority int32 `protobuf:"varint,4,opt,name=priority,proto3" json:"priority,omitempty"`
+	// seconds before a probe times out, the server default if 0
+	Timeout   int32  `protobuf:"varint,5,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	UserAgent string `protobuf:"bytes,6,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	// the scan session the job is for, none if 0
+	ScanSessionId uint64 `protobuf:"varint,7,opt,name=scan_session_id,json=scanSessionId,proto3" json:"scan_session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitTargetsRequest) Reset() {
+	*x = SubmitTargetsRequest{}
+	mi := &file_gowitness_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitTargetsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitTargetsRequest) ProtoMessage() {}
+
+func (x *SubmitTargetsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gowitness_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitTargetsRequest.ProtoReflect.Descriptor instead.
+func (*SubmitTargetsRequest) Descriptor() ([]byte, []int) {
+	return file_gowitness_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SubmitTargetsRequest) GetUrls() []string {
+	if x != nil {
+		return x.Urls
+	}
+	return nil
+}
+
+func (x *SubmitTargetsRequest) GetThreads() int32 {
+	if x != nil {
+		return x.Threads
+	}
+	return 0
+}
+
+func (x *SubmitTargetsRequest) GetRateLimit() int32 {
+	if x != nil {
+		return x.RateLimit
+	}
+	return 0
+}
+
+func (x *SubmitTargetsRequest) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *SubmitTargetsRequest) GetTimeout() int32 {
+	if x != nil {
+		return x.Timeout
+	}
+	return 0
+}
+
+func (x *SubmitTargetsRequest) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+func (x *SubmitTargetsRequest) GetScanSessionId() uint64 {
+	if x != nil {
+		return x.ScanSessionId
+	}
+	return 0
+}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	mi := &file_gowitness_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gowitness_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_gowitness_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetJobRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type JobError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobError) Reset() {
+	*x = JobError{}
+	mi := &file_gowitness_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobError) ProtoMessage() {}
+
+func (x *JobError) ProtoReflect() protoreflect.Message {
+	mi := &file_gowitness_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobError.ProtoReflect.Descriptor instead.
+func (*JobError) Descriptor() ([]byte, []int) {
+	return file_gowitness_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *JobError) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *JobError) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type Job struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Kind  string                 `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	// queued, running, completed or failed
+	Status    string `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Total     int32  `protobuf:"varint,4,opt,name=total,proto3" json:"total,omitempty"`
+	Completed int32  `protobuf:"varint,5,opt,name=completed,proto3" json:"completed,omitempty"`
+	Failed    int32  `protobuf:"varint,6,opt,name=failed,proto3" json:"failed,omitempty"`
+	// percentage of items done
+	Progress float64 `protobuf:"fixed64,7,opt,name=progress,proto3" json:"progress,omitempty"`
+	// why the last attempt failed
+	Error string `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	// the first few items that failed
+	Errors        []*JobError `protobuf:"bytes,9,rep,name=errors,proto3" json:"errors,omitempty"`
+	CreatedAt     int64       `protobuf:"varint,10,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	StartedAt     int64       `protobuf:"varint,11,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt    int64       `protobuf:"varint,12,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_gowitness_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_gowitness_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_gowitness_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Job) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Job) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Job) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Job) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Job) GetCompleted() int32 {
+	if x != nil {
+		return x.Completed
+	}
+	return 0
+}
+
+func (x *Job) GetFailed() int32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *Job) GetProgress() float64 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetErrors() []*JobError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *Job) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Job) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *Job) GetFinishedAt() int64 {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return 0
+}
+
+type ListResultsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// only results of this scan session, if not 0
+	ScanSessionId uint64 `protobuf:"varint,1,opt,name=scan_session_id,json=scanSessionId,proto3" json:"scan_session_id,omitempty"`
+	// only results whose URL or title contains this
+	Query string `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	// only results with an id above this, to page through results
+	AfterId uint64 `protobuf:"varint,3,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"`
+	// results per page, 100 if 0, at most 1000
+	Limit         int32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResultsRequest) Reset() {
+	*x = ListResultsRequest{}
+	mi := &file_gowitness_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResultsRequest) ProtoMessage() {}
+
+func (x *ListResultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gowitness_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResultsRequest.ProtoReflect.Descriptor instead.
+func (*ListResultsRequest) Descriptor() ([]byte, []int) {
+	return file_gowitness_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListResultsRequest) GetScanSessionId() uint64 {
+	if x != nil {
+		return x.ScanSessionId
+	}
+	return 0
+}
+
+func (x *ListResultsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *ListResultsRequest) GetAfterId() uint64 {
+	if x != nil {
+		return x.AfterId
+	}
+	return 0
+}
+
+func (x *ListResultsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListResultsResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Results []*Result              `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	// the after_id of the next page, 0 on the last page
+	NextAfterId   uint64 `protobuf:"varint,2,opt,name=next_after_id,json=nextAfterId,proto3" json:"next_after_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResultsResponse) Reset() {
+	*x = ListResultsResponse{}
+	mi := &file_gowitness_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResultsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResultsResponse) ProtoMessage() {}
+
+func (x *ListResultsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gowitness_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResultsResponse.ProtoReflect.Descriptor instead.
+func (*ListResultsResponse) Descriptor() ([]byte, []int) {
+	return file_gowitness_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListResultsResponse) GetResults() []*Result {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *ListResultsResponse) GetNextAfterId() uint64 {
+	if x != nil {
+		return x.NextAfterId
+	}
+	return 0
+}
+
+type GetResultRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResultRequest) Reset() {
+	*x = GetResultRequest{}
+	mi := &file_gowitness_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResultRequest) ProtoMessage() {}
+
+func (x *GetResultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gowitness_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResultRequest.ProtoReflect.Descriptor instead.
+func (*GetResultRequest) Descriptor() ([]byte, []int) {
+	return file_gowitness_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetResultRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type Result struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	FinalUrl      string                 `protobuf:"bytes,3,opt,name=final_url,json=finalUrl,proto3" json:"final_url,omitempty"`
+	ResponseCode  int32                  `protobuf:"varint,4,opt,name=response_code,json=responseCode,proto3" json:"response_code,omitempty"`
+	Title         string                 `protobuf:"bytes,5,opt,name=title,proto3" json:"title,omitempty"`
+	IpAddress     string                 `protobuf:"bytes,6,opt,name=ip_address,json=ipAddress,proto3" json:"ip_address,omitempty"`
+	Protocol      string                 `protobuf:"bytes,7,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	ContentLength int64                  `protobuf:"varint,8,opt,name=content_length,json=contentLength,proto3" json:"content_length,omitempty"`
+	Failed        bool                   `protobuf:"varint,9,opt,name=failed,proto3" json:"failed,omitempty"`
+	FailedReason  string                 `protobuf:"bytes,10,opt,name=failed_reason,json=failedReason,proto3" json:"failed_reason,omitempty"`
+	// the screenshot file, relative to the screenshot path
+	FileName      string `protobuf:"bytes,11,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"`
+	ProbedAt      int64  `protobuf:"varint,12,opt,name=probed_at,json=probedAt,proto3" json:"probed_at,omitempty"`
+	ScanSessionId uint64 `protobuf:"varint,13,opt,name=scan_session_id,json=scanSessionId,proto3" json:"scan_session_id,omitempty"`
+	// Wappalyzer fingerprints, e.g. Nginx:1.25.3
+	Technologies  []string `protobuf:"bytes,14,rep,name=technologies,proto3" json:"technologies,omitempty"`
+	Tags          []string `protobuf:"bytes,15,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Result) Reset() {
+	*x = Result{}
+	mi := &file_gowitness_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Result) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result) ProtoMessage() {}
+
+func (x *Result) ProtoReflect() protoreflect.Message {
+	mi := &file_gowitness_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result.ProtoReflect.Descriptor instead.
+func (*Result) Descriptor() ([]byte, []int) {
+	return file_gowitness_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Result) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Result) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Result) GetFinalUrl() string {
+	if x != nil {
+		return x.FinalUrl
+	}
+	return ""
+}
+
+func (x *Result) GetResponseCode() int32 {
+	if x != nil {
+		return x.ResponseCode
+	}
+	return 0
+}
+
+func (x *Result) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Result) GetIpAddress() string {
+	if x != nil {
+		return x.IpAddress
+	}
+	return ""
+}
+
+func (x *Result) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *Result) GetContentLength() int64 {
+	if x != nil {
+		return x.ContentLength
+	}
+	return 0
+}
+
+func (x *Result) GetFailed() bool {
+	if x != nil {
+		return x.Failed
+	}
+	return false
+}
+
+func (x *Result) GetFailedReason() string {
+	if x != nil {
+		return x.FailedReason
+	}
+	return ""
+}
+
+func (x *Result) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+func (x *Result) GetProbedAt() int64 {
+	if x != nil {
+		return x.ProbedAt
+	}
+	return 0
+}
+
+func (x *Result) GetScanSessionId() uint64 {
+	if x != nil {
+		return x.ScanSessionId
+	}
+	return 0
+}
+
+func (x *Result) GetTechnologies() []string {
+	if x != nil {
+		return x.Technologies
+	}
+	return nil
+}
+
+func (x *Result) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type Screenshot struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FileName      string                 `protobuf:"bytes,1,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"`
+	ContentType   string                 `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Screenshot) Reset() {
+	*x = Screenshot{}
+	mi := &file_gowitness_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Screenshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Screenshot) ProtoMessage() {}
+
+func (x *Screenshot) ProtoReflect() protoreflect.Message {
+	mi := &file_gowitness_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Screenshot.ProtoReflect.Descriptor instead.
+func (*Screenshot) Descriptor() ([]byte, []int) {
+	return file_gowitness_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Screenshot) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+func (x *Screenshot) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *Screenshot) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+var File_gowitness_proto protoreflect.FileDescriptor
+
+const file_gowitness_proto_rawDesc = "" +
+	"\n" +
+	"\x0fgowitness.proto\x12\fgowitness.v1\"\xe0\x01\n" +
+	"\x14SubmitTargetsRequest\x12\x12\n" +
+	"\x04urls\x18\x01 \x03(\tR\x04urls\x12\x18\n" +
+	"\athreads\x18\x02 \x01(\x05R\athreads\x12\x1d\n" +
+	"\n" +
+	"rate_limit\x18\x03 \x01(\x05R\trateLimit\x12\x1a\n" +
+	"\bpriority\x18\x04 \x01(\x05R\bpriority\x12\x18\n" +
+	"\atimeout\x18\x05 \x01(\x05R\atimeout\x12\x1d\n" +
+	"\n" +
+	"user_agent\x18\x06 \x01(\tR\tuserAgent\x12&\n" +
+	"\x0fscan_session_id\x18\a \x01(\x04R\rscanSessionId\"\x1f\n" +
+	"\rGetJobRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"2\n" +
+	"\bJobError\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xce\x02\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x14\n" +
+	"\x05total\x18\x04 \x01(\x05R\x05total\x12\x1c\n" +
+	"\tcompleted\x18\x05 \x01(\x05R\tcompleted\x12\x16\n" +
+	"\x06failed\x18\x06 \x01(\x05R\x06failed\x12\x1a\n" +
+	"\bprogress\x18\a \x01(\x01R\bprogress\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error\x12.\n" +
+	"\x06errors\x18\t \x03(\v2\x16.gowitness.v1.JobErrorR\x06errors\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\n" +
+	" \x01(\x03R\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\v \x01(\x03R\tstartedAt\x12\x1f\n" +
+	"\vfinished_at\x18\f \x01(\x03R\n" +
+	"finishedAt\"\x83\x01\n" +
+	"\x12ListResultsRequest\x12&\n" +
+	"\x0fscan_session_id\x18\x01 \x01(\x04R\rscanSessionId\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12\x19\n" +
+	"\bafter_id\x18\x03 \x01(\x04R\aafterId\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"i\n" +
+	"\x13ListResultsResponse\x12.\n" +
+	"\aresults\x18\x01 \x03(\v2\x14.gowitness.v1.ResultR\aresults\x12\"\n" +
+	"\rnext_after_id\x18\x02 \x01(\x04R\vnextAfterId\"\"\n" +
+	"\x10GetResultRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"\xbb\x03\n" +
+	"\x06Result\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x1b\n" +
+	"\tfinal_url\x18\x03 \x01(\tR\bfinalUrl\x12#\n" +
+	"\rresponse_code\x18\x04 \x01(\x05R\fresponseCode\x12\x14\n" +
+	"\x05title\x18\x05 \x01(\tR\x05title\x12\x1d\n" +
+	"\n" +
+	"ip_address\x18\x06 \x01(\tR\tipAddress\x12\x1a\n" +
+	"\bprotocol\x18\a \x01(\tR\bprotocol\x12%\n" +
+	"\x0econtent_length\x18\b \x01(\x03R\rcontentLength\x12\x16\n" +
+	"\x06failed\x18\t \x01(\bR\x06failed\x12#\n" +
+	"\rfailed_reason\x18\n" +
+	" \x01(\tR\ffailedReason\x12\x1b\n" +
+	"\tfile_name\x18\v \x01(\tR\bfileName\x12\x1b\n" +
+	"\tprobed_at\x18\f \x01(\x03R\bprobedAt\x12&\n" +
+	"\x0fscan_session_id\x18\r \x01(\x04R\rscanSessionId\x12\"\n" +
+	"\ftechnologies\x18\x0e \x03(\tR\ftechnologies\x12\x12\n" +
+	"\x04tags\x18\x0f \x03(\tR\x04tags\"`\n" +
+	"\n" +
+	"Screenshot\x12\x1b\n" +
+	"\tfile_name\x18\x01 \x01(\tR\bfileName\x12!\n" +
+	"\fcontent_type\x18\x02 \x01(\tR\vcontentType\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data2\xb3\x03\n" +
+	"\tGowitness\x12F\n" +
+	"\rSubmitTargets\x12\".gowitness.v1.SubmitTargetsRequest\x1a\x11.gowitness.v1.Job\x128\n" +
+	"\x06GetJob\x12\x1b.gowitness.v1.GetJobRequest\x1a\x11.gowitness.v1.Job\x12B\n" +
+	"\x0eStreamProgress\x12\x1b.gowitness.v1.GetJobRequest\x1a\x11.gowitness.v1.Job0\x01\x12R\n" +
+	"\vListResults\x12 .gowitness.v1.ListResultsRequest\x1a!.gowitness.v1.ListResultsResponse\x12A\n" +
+	"\tGetResult\x12\x1e.gowitness.v1.GetResultRequest\x1a\x14.gowitness.v1.Result\x12I\n" +
+	"\rGetScreenshot\x12\x1e.gowitness.v1.GetResultRequest\x1a\x18.gowitness.v1.ScreenshotB,Z*github.com/sensepost/gowitness/web/rpc;rpcb\x06proto3"
+
+var (
+	file_gowitness_proto_rawDescOnce sync.Once
+	file_gowitness_proto_rawDescData []byte
+)
+
+func file_gowitness_proto_rawDescGZIP() []byte {
+	file_gowitness_proto_rawDescOnce.Do(func() {
+		file_gowitness_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gowitness_proto_rawDesc), len(file_gowitness_proto_rawDesc)))
+	})
+	return file_gowitness_proto_rawDescData
+}
+
+var file_gowitness_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_gowitness_proto_goTypes = []any{
+	(*SubmitTargetsRequest)(nil), // 0: gowitness.v1.SubmitTargetsRequest
+	(*GetJobRequest)(nil),        // 1: gowitness.v1.GetJobRequest
+	(*JobError)(nil),             // 2: gowitness.v1.JobError
+	(*Job)(nil),                  // 3: gowitness.v1.Job
+	(*ListResultsRequest)(nil),   // 4: gowitness.v1.ListResultsRequest
+	(*ListResultsResponse)(nil),  // 5: gowitness.v1.ListResultsResponse
+	(*GetResultRequest)(nil),     // 6: gowitness.v1.GetResultRequest
+	(*Result)(nil),               // 7: gowitness.v1.Result
+	(*Screenshot)(nil),           // 8: gowitness.v1.Screenshot
+}
+var file_gowitness_proto_depIdxs = []int32{
+	2, // 0: gowitness.v1.Job.errors:type_name -> gowitness.v1.JobError
+	7, // 1: gowitness.v1.ListResultsResponse.results:type_name -> gowitness.v1.Result
+	0, // 2: gowitness.v1.Gowitness.SubmitTargets:input_type -> gowitness.v1.SubmitTargetsRequest
+	1, // 3: gowitness.v1.Gowitness.GetJob:input_type -> gowitness.v1.GetJobRequest
+	1, // 4: gowitness.v1.Gowitness.StreamProgress:input_type -> gowitness.v1.GetJobRequest
+	4, // 5: gowitness.v1.Gowitness.ListResults:input_type -> gowitness.v1.ListResultsRequest
+	6, // 6: gowitness.v1.Gowitness.GetResult:input_type -> gowitness.v1.GetResultRequest
+	6, // 7: gowitness.v1.Gowitness.GetScreenshot:input_type -> gowitness.v1.GetResultRequest
+	3, // 8: gowitness.v1.Gowitness.SubmitTargets:output_type -> gowitness.v1.Job
+	3, // 9: gowitness.v1.Gowitness.GetJob:output_type -> gowitness.v1.Job
+	3, // 10: gowitness.v1.Gowitness.StreamProgress:output_type -> gowitness.v1.Job
+	5, // 11: gowitness.v1.Gowitness.ListResults:output_type -> gowitness.v1.ListResultsResponse
+	7, // 12: gowitness.v1.Gowitness.GetResult:output_type -> gowitness.v1.Result
+	8, // 13: gowitness.v1.Gowitness.GetScreenshot:output_type -> gowitness.v1.Screenshot
+	8, // [8:14] is the sub-list for method output_type
+	2, // [2:8] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_gowitness_proto_init() }
+func file_gowitness_proto_init() {
+	if File_gowitness_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gowitness_proto_rawDesc), len(file_gowitness_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gowitness_proto_goTypes,
+		DependencyIndexes: file_gowitness_proto_depIdxs,
+		MessageInfos:      file_gowitness_proto_msgTypes,
+	}.Build()
+	File_gowitness_proto = out.File
+	file_gowitness_proto_goTypes = nil
+	file_gowitness_proto_depIdxs = nil
+}
@@ -0,0 +1,337 @@
+// The gowitness machine API, served over gRPC by 'gowitness report server'
+// when --grpc-port is set. Calls authenticate with the server's gRPC token,
+// sent as "authorization: Bearer <token>" metadata.
+//
+// Timestamps are seconds since the Unix epoch, 0 when unset.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: gowitness.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Gowitness_SubmitTargets_FullMethodName  = "/gowitness.v1.Gowitness/SubmitTargets"
+	Gowitness_GetJob_FullMethodName         = "/gowitness.v1.Gowitness/GetJob"
+	Gowitness_StreamProgress_FullMethodName = "/gowitness.v1.Gowitness/StreamProgress"
+	Gowitness_ListResults_FullMethodName    = "/gowitness.v1.Gowitness/ListResults"
+	Gowitness_GetResult_FullMethodName      = "/gowitness.v1.Gowitness/GetResult"
+	Gowitness_GetScreenshot_FullMethodName  = "/gowitness.v1.Gowitness/GetScreenshot"
+)
+
+// GowitnessClient is the client API for Gowitness service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GowitnessClient interface {
+	// SubmitTargets queues a job probing URLs, writing the results to the
+	// server's database
+	SubmitTargets(ctx context.Context, in *SubmitTargetsRequest, opts ...grpc.CallOption) (*Job, error)
+	// GetJob returns the progress of a job
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// StreamProgress sends the progress of a job whenever it changes, until
+	// the job finishes
+	StreamProgress(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Job], error)
+	// ListResults lists results by ascending id, a page at a time
+	ListResults(ctx context.Context, in *ListResultsRequest, opts ...grpc.CallOption) (*ListResultsResponse, error)
+	// GetResult returns a result
+	GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*Result, error)
+	// GetScreenshot returns the screenshot file of a result
+	GetScreenshot(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*Screenshot, error)
+}
+
+type gowitnessClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGowitnessClient(cc grpc.ClientConnInterface) GowitnessClient {
+	return &gowitnessClient{cc}
+}
+
+func (c *gowitnessClient) SubmitTargets(ctx context.Context, in *SubmitTargetsRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Gowitness_SubmitTargets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gowitnessClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Gowitness_GetJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gowitnessClient) StreamProgress(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Job], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Gowitness_ServiceDesc.Streams[0], Gowitness_StreamProgress_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetJobRequest, Job]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Gowitness_StreamProgressClient = grpc.ServerStreamingClient[Job]
+
+func (c *gowitnessClient) ListResults(ctx context.Context, in *ListResultsRequest, opts ...grpc.CallOption) (*ListResultsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResultsResponse)
+	err := c.cc.Invoke(ctx, Gowitness_ListResults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gowitnessClient) GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*Result, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Gowitness_GetResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gowitnessClient) GetScreenshot(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*Screenshot, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Screenshot)
+	err := c.cc.Invoke(ctx, Gowitness_GetScreenshot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GowitnessServer is the server API for Gowitness service.
+// All implementations must embed UnimplementedGowitnessServer
+// for forward compatibility.
+type GowitnessServer interface {
+	// SubmitTargets queues a job probing URLs, writing the results to the
+	// server's database
+	SubmitTargets(context.Context, *SubmitTargetsRequest) (*Job, error)
+	// GetJob returns the progress of a job
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	// StreamProgress sends the progress of a job whenever it changes, until
+	// the job finishes
+	StreamProgress(*GetJobRequest, grpc.ServerStreamingServer[Job]) error
+	// ListResults lists results by ascending id, a page at a time
+	ListResults(context.Context, *ListResultsRequest) (*ListResultsResponse, error)
+	// GetResult returns a result
+	GetResult(context.Context, *GetResultRequest) (*Result, error)
+	// GetScreenshot returns the screenshot file of a result
+	GetScreenshot(context.Context, *GetResultRequest) (*Screenshot, error)
+	mustEmbedUnimplementedGowitnessServer()
+}
+
+// UnimplementedGowitnessServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGowitnessServer struct{}
+
+func (UnimplementedGowitnessServer) SubmitTargets(context.Context, *SubmitTargetsRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitTargets not implemented")
+}
+func (UnimplementedGowitnessServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedGowitnessServer) StreamProgress(*GetJobRequest, grpc.ServerStreamingServer[Job]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamProgress not implemented")
+}
+func (UnimplementedGowitnessServer) ListResults(context.Context, *ListResultsRequest) (*ListResultsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListResults not implemented")
+}
+func (UnimplementedGowitnessServer) GetResult(context.Context, *GetResultRequest) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetResult not implemented")
+}
+func (UnimplementedGowitnessServer) GetScreenshot(context.Context, *GetResultRequest) (*Screenshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetScreenshot not implemented")
+}
+func (UnimplementedGowitnessServer) mustEmbedUnimplementedGowitnessServer() {}
+func (UnimplementedGowitnessServer) testEmbeddedByValue()                   {}
+
+// UnsafeGowitnessServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GowitnessServer will
+// result in compilation errors.
+type UnsafeGowitnessServer interface {
+	mustEmbedUnimplementedGowitnessServer()
+}
+
+func RegisterGowitnessServer(s grpc.ServiceRegistrar, srv GowitnessServer) {
+	// If the following call pancis, it indicates UnimplementedGowitnessServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Gowitness_ServiceDesc, srv)
+}
+
+func _Gowitness_SubmitTargets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitTargetsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GowitnessServer).SubmitTargets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gowitness_SubmitTargets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GowitnessServer).SubmitTargets(ctx, req.(*SubmitTargetsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gowitness_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GowitnessServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gowitness_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GowitnessServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gowitness_StreamProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GowitnessServer).StreamProgress(m, &grpc.GenericServerStream[GetJobRequest, Job]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Gowitness_StreamProgressServer = grpc.ServerStreamingServer[Job]
+
+func _Gowitness_ListResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListResultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GowitnessServer).ListResults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gowitness_ListResults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GowitnessServer).ListResults(ctx, req.(*ListResultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gowitness_GetResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GowitnessServer).GetResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gowitness_GetResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GowitnessServer).GetResult(ctx, req.(*GetResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gowitness_GetScreenshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GowitnessServer).GetScreenshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gowitness_GetScreenshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GowitnessServer).GetScreenshot(ctx, req.(*GetResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Gowitness_ServiceDesc is the grpc.ServiceDesc for Gowitness service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Gowitness_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gowitness.v1.Gowitness",
+	HandlerType: (*GowitnessServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitTargets",
+			Handler:    _Gowitness_SubmitTargets_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _Gowitness_GetJob_Handler,
+		},
+		{
+			MethodName: "ListResults",
+			Handler:    _Gowitness_ListResults_Handler,
+		},
+		{
+			MethodName: "GetResult",
+			Handler:    _Gowitness_GetResult_Handler,
+		},
+		{
+			MethodName: "GetScreenshot",
+			Handler:    _Gowitness_GetScreenshot_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamProgress",
+			Handler:       _Gowitness_StreamProgress_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gowitness.proto",
+}
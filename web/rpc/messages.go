@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/models"
+)
+
+// jobMessage returns a job as a message of gowitness.proto
+func jobMessage(job *models.Job) *Job {
+	var progress float64
+	if job.Total > 0 {
+		progress = min(100, float64(job.Completed+job.Failed)/float64(job.Total)*100)
+	}
+
+	message := &Job{
+		Id:         uint64(job.ID),
+		Kind:       job.Kind,
+		Status:     job.Status,
+		Total:      int32(job.Total),
+		Completed:  int32(job.Completed),
+		Failed:     int32(job.Failed),
+		Progress:   progress,
+		Error:      job.Error,
+		CreatedAt:  unix(&job.CreatedAt),
+		StartedAt:  unix(job.StartedAt),
+		FinishedAt: unix(job.FinishedAt),
+	}
+
+	jobErrors, _ := job.GetErrors()
+	for _, jobError := range jobErrors {
+		message.Errors = append(message.Errors, &JobError{Url: jobError.URL, Error: jobError.Error})
+	}
+
+	return message
+}
+
+// resultMessage returns a result as a message of gowitness.proto
+func resultMessage(result *models.Result) *Result {
+	message := &Result{
+		Id:            uint64(result.ID),
+		Url:           result.URL,
+		FinalUrl:      result.FinalURL,
+		ResponseCode:  int32(result.ResponseCode),
+		Title:         result.Title,
+		IpAddress:     result.IPAddress,
+		Protocol:      result.Protocol,
+		ContentLength: result.ContentLength,
+		Failed:        result.Failed,
+		FailedReason:  result.FailedReason,
+		FileName:      result.Filename,
+		ProbedAt:      unix(&result.ProbedAt),
+	}
+	if result.ScanSessionID != nil {
+		message.ScanSessionId = uint64(*result.ScanSessionID)
+	}
+	for _, technology := range result.Technologies {
+		message.Technologies = append(message.Technologies, technology.Value)
+	}
+	for _, tag := range result.Tags {
+		message.Tags = append(message.Tags, tag.Name)
+	}
+
+	return message
+}
+
+// unix returns a time as seconds since the epoch, 0 if it is unset
+func unix(t *time.Time) int64 {
+	if t == nil || t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
@@ -0,0 +1,318 @@
+// Package rpc serves the machine API over gRPC, for tools that submit
+// targets, query results and follow job progress programmatically. The
+// service is described by gowitness.proto, which the stubs in this package,
+// and those of clients, are generated from.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative gowitness.proto
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/jobs"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/models"
+	"github.com/sensepost/gowitness/pkg/runner"
+	"github.com/sensepost/gowitness/pkg/thumbnail"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxThreads is the most threads a submitted job may probe with
+	maxThreads = 64
+	// defaultLimit and maxLimit are the default and largest page sizes
+	// of ListResults
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// writeMethods are the methods that change data, which a read-only server
+// rejects
+var writeMethods = map[string]bool{
+	Gowitness_SubmitTargets_FullMethodName: true,
+}
+
+// Server serves the gRPC machine API
+type Server struct {
+	UnimplementedGowitnessServer
+
+	DB             *gorm.DB
+	Jobs           *jobs.Queue
+	ScreenshotPath string
+	// Token is the bearer token calls authenticate with
+	Token string
+	// ReadOnly rejects calls that would change data
+	ReadOnly bool
+	// PollInterval is how often streamed job progress is checked
+	PollInterval time.Duration
+}
+
+// NewServer returns a new gRPC server on a database and job queue
+func NewServer(db *gorm.DB, queue *jobs.Queue, screenshotPath string, token string) *Server {
+	return &Server{
+		DB:             db,
+		Jobs:           queue,
+		ScreenshotPath: screenshotPath,
+		Token:          token,
+		PollInterval:   time.Second,
+	}
+}
+
+// GRPCServer returns a grpc.Server serving s, which checks the token and
+// read-only mode of every call before it is handled
+func (s *Server) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+
+	server := grpc.NewServer(opts...)
+	RegisterGowitnessServer(server, s)
+
+	return server
+}
+
+// unaryInterceptor authorizes unary calls
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (any, error) {
+	if err := s.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// streamInterceptor authorizes streaming calls
+func (s *Server) streamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	if err := s.authorize(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(srv, stream)
+}
+
+// authorize checks the token of a call, and that it doesn't change data
+// if the server is read-only
+func (s *Server) authorize(ctx context.Context, method string) error {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token, _ = strings.CutPrefix(values[0], "Bearer ")
+		}
+	}
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+		var remote string
+		if p, ok := peer.FromContext(ctx); ok {
+			remote = p.Addr.String()
+		}
+		log.Warn("rejected grpc call with a missing or invalid token", "remote", remote, "method", method)
+		return status.Error(codes.Unauthenticated, "missing or invalid token")
+	}
+
+	if s.ReadOnly && writeMethods[method] {
+		return status.Error(codes.PermissionDenied, "the server is read-only")
+	}
+
+	return nil
+}
+
+// SubmitTargets queues a probe job
+func (s *Server) SubmitTargets(ctx context.Context, request *SubmitTargetsRequest) (*Job, error) {
+	if len(request.Urls) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no urls provided")
+	}
+	if request.Threads < 0 || request.Threads > maxThreads {
+		return nil, status.Errorf(codes.InvalidArgument, "threads must be between 1 and %d", maxThreads)
+	}
+	if request.RateLimit < 0 || request.Timeout < 0 {
+		return nil, status.Error(codes.InvalidArgument, "rate_limit and timeout can't be negative")
+	}
+
+	options := runner.NewDefaultOptions()
+	options.Scan.ScreenshotPath = s.ScreenshotPath
+	if request.Threads > 0 {
+		options.Scan.Threads = int(request.Threads)
+	}
+	if request.Timeout > 0 {
+		options.Scan.Timeout = int(request.Timeout)
+	}
+	if request.UserAgent != "" {
+		options.Chrome.UserAgent = request.UserAgent
+	}
+
+	jobOptions := jobs.Options{Priority: int(request.Priority)}
+	if request.ScanSessionId > 0 {
+		scanSessionID := uint(request.ScanSessionId)
+		jobOptions.ScanSessionID = &scanSessionID
+	}
+
+	job, err := s.Jobs.Enqueue(jobs.KindProbe, jobs.Probe{
+		URLs:      request.Urls,
+		Options:   *options,
+		RateLimit: int(request.RateLimit),
+	}, jobOptions)
+	if err != nil {
+		log.Error("failed to queue grpc job", "err", err)
+		return nil, status.Error(codes.Internal, "error queueing job")
+	}
+
+	return jobMessage(job), nil
+}
+
+// GetJob returns the progress of a job
+func (s *Server) GetJob(ctx context.Context, request *GetJobRequest) (*Job, error) {
+	job, err := s.job(request.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobMessage(job), nil
+}
+
+// StreamProgress sends the progress of a job whenever it changes, until
+// the job finishes or the client goes away
+func (s *Server) StreamProgress(request *GetJobRequest, stream grpc.ServerStreamingServer[Job]) error {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		job, err := s.job(request.Id)
+		if err != nil {
+			return err
+		}
+
+		current := fmt.Sprintf("%s/%d/%d/%d/%s", job.Status, job.Total, job.Completed, job.Failed, job.Error)
+		if current != last {
+			if err := stream.Send(jobMessage(job)); err != nil {
+				return err
+			}
+			last = current
+		}
+		if job.Status == models.JobCompleted || job.Status == models.JobFailed {
+			return nil
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// job gets a job, as a gRPC status if it fails
+func (s *Server) job(id uint64) (*models.Job, error) {
+	job, err := s.Jobs.Get(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "job %d not found", id)
+		}
+		log.Error("failed to get grpc job", "id", id, "err", err)
+		return nil, status.Error(codes.Internal, "error retrieving job")
+	}
+
+	return job, nil
+}
+
+// ListResults returns a page of results
+func (s *Server) ListResults(ctx context.Context, request *ListResultsRequest) (*ListResultsResponse, error) {
+	limit := int(request.Limit)
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	limit = min(limit, maxLimit)
+
+	query := s.DB.Model(&models.Result{}).Preload("Technologies").Preload("Tags").
+		Where("id > ?", request.AfterId).Order("id").Limit(limit + 1)
+	if request.ScanSessionId > 0 {
+		query = query.Where("scan_session_id = ?", request.ScanSessionId)
+	}
+	if request.Query != "" {
+		like := "%" + request.Query + "%"
+		query = query.Where("url LIKE ? OR title LIKE ?", like, like)
+	}
+
+	var results []models.Result
+	if err := query.Find(&results).Error; err != nil {
+		log.Error("failed to list grpc results", "err", err)
+		return nil, status.Error(codes.Internal, "error listing results")
+	}
+
+	response := &ListResultsResponse{}
+	if len(results) > limit {
+		results = results[:limit]
+		response.NextAfterId = uint64(results[limit-1].ID)
+	}
+	for i := range results {
+		response.Results = append(response.Results, resultMessage(&results[i]))
+	}
+
+	return response, nil
+}
+
+// GetResult returns a result
+func (s *Server) GetResult(ctx context.Context, request *GetResultRequest) (*Result, error) {
+	result, err := s.result(s.DB.Preload("Technologies").Preload("Tags"), request.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return resultMessage(result), nil
+}
+
+// GetScreenshot returns the screenshot file of a result
+func (s *Server) GetScreenshot(ctx context.Context, request *GetResultRequest) (*Screenshot, error) {
+	result, err := s.result(s.DB.Select("id", "filename", "scan_session_id"), request.Id)
+	if err != nil {
+		return nil, err
+	}
+	if result.Filename == "" {
+		return nil, status.Errorf(codes.NotFound, "result %d has no screenshot", request.Id)
+	}
+
+	// thumbnail.Clean keeps us inside the screenshot path
+	dir := database.ScreenshotPath(s.DB, result.ScanSessionID, s.ScreenshotPath)
+	file := filepath.Join(dir, filepath.FromSlash(thumbnail.Clean(result.Filename)))
+
+	screenshot, err := os.ReadFile(file)
+	if err != nil {
+		log.Debug("screenshot file not found", "file", file, "err", err)
+		return nil, status.Errorf(codes.NotFound, "screenshot of result %d not found", request.Id)
+	}
+
+	return &Screenshot{
+		FileName:    result.Filename,
+		ContentType: http.DetectContentType(screenshot),
+		Data:        screenshot,
+	}, nil
+}
+
+// result gets a result, as a gRPC status if it fails
+func (s *Server) result(query *gorm.DB, id uint64) (*models.Result, error) {
+	var result models.Result
+	if err := query.First(&result, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Errorf(codes.NotFound, "result %d not found", id)
+		}
+		log.Error("failed to get grpc result", "id", id, "err", err)
+		return nil, status.Error(codes.Internal, "error retrieving result")
+	}
+
+	return &result, nil
+}
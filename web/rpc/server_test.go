@@ -0,0 +1,398 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/database"
+	"github.com/sensepost/gowitness/pkg/jobs"
+	"github.com/sensepost/gowitness/pkg/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const testToken = "s3cret"
+
+// pngHeader is enough of a PNG for its content type to be detected
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+
+// newTestServer serves a Server on a new database without TLS, returning
+// the server and a client connected to it
+func newTestServer(t *testing.T) (*Server, GowitnessClient) {
+	t.Helper()
+
+	// the database log is written to the working directory
+	t.Chdir(t.TempDir())
+
+	db, err := database.Connection("sqlite://"+filepath.Join(t.TempDir(), "gowitness.sqlite3"), false, false)
+	if err != nil {
+		t.Fatalf("database.Connection() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	// probe jobs are queued but never run
+	queue := jobs.NewQueue(db)
+	queue.RegisterRemote(jobs.KindProbe)
+
+	server := NewServer(db, queue, t.TempDir(), testToken)
+	server.PollInterval = 10 * time.Millisecond
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	grpcServer := server.GRPCServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return server, NewGowitnessClient(conn)
+}
+
+// authorized adds the test token to the metadata of calls made with ctx
+func authorized(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+testToken)
+}
+
+func TestServerCalls(t *testing.T) {
+	server, client := newTestServer(t)
+	ctx := authorized(t.Context())
+
+	probed := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	results := []models.Result{
+		{
+			URL:          "https://example.com",
+			Title:        "Example Domain",
+			ResponseCode: 200,
+			Filename:     "https-example.com.png",
+			ProbedAt:     probed,
+			Technologies: []models.Technology{{Value: "Nginx:1.25.3"}},
+			Tags:         []models.ResultTag{{Name: "login"}},
+		},
+		{URL: "https://admin.example.com", Title: "Admin", ResponseCode: 401, ProbedAt: probed},
+		{URL: "https://down.example.com", Failed: true, FailedReason: "net::ERR_NAME_NOT_RESOLVED", ProbedAt: probed},
+	}
+	if err := server.DB.Create(&results).Error; err != nil {
+		t.Fatalf("failed to create results: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(server.ScreenshotPath, "https-example.com.png"), pngHeader, 0o644); err != nil {
+		t.Fatalf("failed to write screenshot: %v", err)
+	}
+
+	t.Run("Test GetResult", func(t *testing.T) {
+		got, err := client.GetResult(ctx, &GetResultRequest{Id: uint64(results[0].ID)})
+		if err != nil {
+			t.Fatalf("GetResult() error = %v", err)
+		}
+
+		want := &Result{
+			Id:           uint64(results[0].ID),
+			Url:          "https://example.com",
+			ResponseCode: 200,
+			Title:        "Example Domain",
+			FileName:     "https-example.com.png",
+			ProbedAt:     probed.Unix(),
+			Technologies: []string{"Nginx:1.25.3"},
+			Tags:         []string{"login"},
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("GetResult() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Test ListResults pages", func(t *testing.T) {
+		var urls []string
+		var afterID uint64
+		for pages := 0; ; pages++ {
+			if pages > len(results) {
+				t.Fatal("ListResults() did not stop paging")
+			}
+
+			got, err := client.ListResults(ctx, &ListResultsRequest{AfterId: afterID, Limit: 2})
+			if err != nil {
+				t.Fatalf("ListResults() error = %v", err)
+			}
+			for _, result := range got.Results {
+				urls = append(urls, result.Url)
+			}
+
+			afterID = got.NextAfterId
+			if afterID == 0 {
+				break
+			}
+		}
+
+		want := []string{"https://example.com", "https://admin.example.com", "https://down.example.com"}
+		if strings.Join(urls, " ") != strings.Join(want, " ") {
+			t.Errorf("ListResults() urls = %v, want %v", urls, want)
+		}
+	})
+
+	t.Run("Test ListResults query", func(t *testing.T) {
+		got, err := client.ListResults(ctx, &ListResultsRequest{Query: "admin"})
+		if err != nil {
+			t.Fatalf("ListResults() error = %v", err)
+		}
+
+		if len(got.Results) != 1 {
+			t.Fatalf("ListResults() returned %d results, want 1", len(got.Results))
+		}
+		if id := got.Results[0].Id; id != uint64(results[1].ID) {
+			t.Errorf("ListResults() id = %d, want %d", id, results[1].ID)
+		}
+	})
+
+	t.Run("Test GetScreenshot", func(t *testing.T) {
+		got, err := client.GetScreenshot(ctx, &GetResultRequest{Id: uint64(results[0].ID)})
+		if err != nil {
+			t.Fatalf("GetScreenshot() error = %v", err)
+		}
+
+		want := &Screenshot{FileName: "https-example.com.png", ContentType: "image/png", Data: pngHeader}
+		if !proto.Equal(got, want) {
+			t.Errorf("GetScreenshot() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Test SubmitTargets and GetJob", func(t *testing.T) {
+		submitted, err := client.SubmitTargets(ctx, &SubmitTargetsRequest{
+			Urls:     []string{"https://example.com", "https://example.org"},
+			Threads:  2,
+			Priority: 5,
+		})
+		if err != nil {
+			t.Fatalf("SubmitTargets() error = %v", err)
+		}
+		if submitted.Id == 0 || submitted.Status != models.JobQueued {
+			t.Fatalf("SubmitTargets() = %v, want a queued job", submitted)
+		}
+
+		job, err := server.Jobs.Get(uint(submitted.Id))
+		if err != nil {
+			t.Fatalf("Jobs.Get() error = %v", err)
+		}
+		if job.Kind != jobs.KindProbe || job.Priority != 5 {
+			t.Errorf("queued job = %+v, want a probe job of priority 5", job)
+		}
+
+		got, err := client.GetJob(ctx, &GetJobRequest{Id: submitted.Id})
+		if err != nil {
+			t.Fatalf("GetJob() error = %v", err)
+		}
+		if !proto.Equal(got, submitted) {
+			t.Errorf("GetJob() = %v, want %v", got, submitted)
+		}
+	})
+
+	t.Run("Test StreamProgress", func(t *testing.T) {
+		job := models.Job{Kind: jobs.KindProbe, Status: models.JobRunning, Total: 2, RunAt: time.Now()}
+		if err := server.DB.Create(&job).Error; err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+
+		stream, err := client.StreamProgress(ctx, &GetJobRequest{Id: uint64(job.ID)})
+		if err != nil {
+			t.Fatalf("StreamProgress() error = %v", err)
+		}
+
+		var statuses []string
+		for {
+			progress, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Recv() error = %v", err)
+			}
+			statuses = append(statuses, progress.Status)
+
+			// finish the job once its first progress was streamed
+			if len(statuses) == 1 {
+				if err := server.DB.Model(&job).Updates(map[string]any{
+					"status": models.JobCompleted, "completed": 2}).Error; err != nil {
+					t.Fatalf("failed to finish job: %v", err)
+				}
+			}
+		}
+
+		if want := []string{models.JobRunning, models.JobCompleted}; strings.Join(statuses, " ") != strings.Join(want, " ") {
+			t.Errorf("StreamProgress() statuses = %v, want %v", statuses, want)
+		}
+	})
+}
+
+func TestServerStatus(t *testing.T) {
+	server, client := newTestServer(t)
+
+	result := models.Result{URL: "https://example.com"}
+	if err := server.DB.Create(&result).Error; err != nil {
+		t.Fatalf("failed to create result: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		call        func(ctx context.Context) error
+		readOnly    bool
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{
+			name: "Test missing token",
+			ctx:  t.Context(),
+			call: func(ctx context.Context) error {
+				_, err := client.GetResult(ctx, &GetResultRequest{Id: uint64(result.ID)})
+				return err
+			},
+			wantCode:    codes.Unauthenticated,
+			wantMessage: "missing or invalid token",
+		},
+		{
+			name: "Test invalid token",
+			ctx:  metadata.AppendToOutgoingContext(t.Context(), "authorization", "Bearer wrong"),
+			call: func(ctx context.Context) error {
+				_, err := client.GetResult(ctx, &GetResultRequest{Id: uint64(result.ID)})
+				return err
+			},
+			wantCode:    codes.Unauthenticated,
+			wantMessage: "missing or invalid token",
+		},
+		{
+			name: "Test streamed call without a token",
+			ctx:  t.Context(),
+			call: func(ctx context.Context) error {
+				stream, err := client.StreamProgress(ctx, &GetJobRequest{Id: 1})
+				if err != nil {
+					return err
+				}
+				_, err = stream.Recv()
+				return err
+			},
+			wantCode:    codes.Unauthenticated,
+			wantMessage: "missing or invalid token",
+		},
+		{
+			name: "Test result not found",
+			call: func(ctx context.Context) error {
+				_, err := client.GetResult(ctx, &GetResultRequest{Id: 999})
+				return err
+			},
+			wantCode:    codes.NotFound,
+			wantMessage: "result 999 not found",
+		},
+		{
+			name: "Test screenshot not found",
+			call: func(ctx context.Context) error {
+				_, err := client.GetScreenshot(ctx, &GetResultRequest{Id: uint64(result.ID)})
+				return err
+			},
+			wantCode:    codes.NotFound,
+			wantMessage: "has no screenshot",
+		},
+		{
+			name: "Test job not found",
+			call: func(ctx context.Context) error {
+				_, err := client.GetJob(ctx, &GetJobRequest{Id: 999})
+				return err
+			},
+			wantCode:    codes.NotFound,
+			wantMessage: "job 999 not found",
+		},
+		{
+			name: "Test streamed job not found",
+			call: func(ctx context.Context) error {
+				stream, err := client.StreamProgress(ctx, &GetJobRequest{Id: 999})
+				if err != nil {
+					return err
+				}
+				_, err = stream.Recv()
+				return err
+			},
+			wantCode:    codes.NotFound,
+			wantMessage: "job 999 not found",
+		},
+		{
+			name: "Test no urls",
+			call: func(ctx context.Context) error {
+				_, err := client.SubmitTargets(ctx, &SubmitTargetsRequest{})
+				return err
+			},
+			wantCode:    codes.InvalidArgument,
+			wantMessage: "no urls provided",
+		},
+		{
+			name: "Test too many threads",
+			call: func(ctx context.Context) error {
+				_, err := client.SubmitTargets(ctx, &SubmitTargetsRequest{Urls: []string{"https://example.com"}, Threads: maxThreads + 1})
+				return err
+			},
+			wantCode:    codes.InvalidArgument,
+			wantMessage: "threads must be between",
+		},
+		{
+			name: "Test negative timeout",
+			call: func(ctx context.Context) error {
+				_, err := client.SubmitTargets(ctx, &SubmitTargetsRequest{Urls: []string{"https://example.com"}, Timeout: -1})
+				return err
+			},
+			wantCode:    codes.InvalidArgument,
+			wantMessage: "can't be negative",
+		},
+		{
+			name: "Test read-only",
+			call: func(ctx context.Context) error {
+				_, err := client.SubmitTargets(ctx, &SubmitTargetsRequest{Urls: []string{"https://example.com"}})
+				return err
+			},
+			readOnly:    true,
+			wantCode:    codes.PermissionDenied,
+			wantMessage: "the server is read-only",
+		},
+		{
+			name: "Test read-only allows reads",
+			call: func(ctx context.Context) error {
+				_, err := client.GetResult(ctx, &GetResultRequest{Id: uint64(result.ID)})
+				return err
+			},
+			readOnly: true,
+			wantCode: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.ReadOnly = tt.readOnly
+			defer func() { server.ReadOnly = false }()
+
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = authorized(t.Context())
+			}
+
+			s := status.Convert(tt.call(ctx))
+			if s.Code() != tt.wantCode || !strings.Contains(s.Message(), tt.wantMessage) {
+				t.Errorf("status = %v %q, want %v %q", s.Code(), s.Message(), tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
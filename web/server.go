@@ -1,20 +1,27 @@
 package web
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"html/template"
 	"net/http"
+	"net/netip"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sensepost/gowitness/web/docs"
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
+	"github.com/sensepost/gowitness/pkg/auth"
 	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/metrics"
+	"github.com/sensepost/gowitness/pkg/oidc"
 	"github.com/sensepost/gowitness/web/api"
+	"gorm.io/gorm"
 )
 
 // Server is a web server
@@ -24,6 +31,58 @@ type Server struct {
 	DbUri          string
 	ScreenshotPath string
 	Password       string
+
+	// AllowedIPs restricts access to clients in these prefixes.
+	// An empty list allows everyone.
+	AllowedIPs []netip.Prefix
+	// TLSCert and TLSKey are the certificate and key files to serve
+	// HTTPS with. Plain HTTP is served if either is empty.
+	TLSCert string
+	TLSKey  string
+	// ReadOnly rejects requests that would change data
+	ReadOnly bool
+	// AllowedOrigins are the origins allowed to make cross-origin api
+	// requests, "*" for any. Cross-origin requests are refused if empty.
+	AllowedOrigins []string
+	// ContentSecurityPolicy is sent with every response but the swagger
+	// documentation. An empty policy sends none.
+	ContentSecurityPolicy string
+	// OIDCIssuer and OIDCClientID enable logins through an OpenID Connect
+	// identity provider. OIDCRedirectURL is derived from requests if empty.
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	// OIDCRole is the role of users created on their first OIDC login
+	OIDCRole string
+	// OIDCAllowedDomains and OIDCAllowedGroups limit who may log in with
+	// OIDC to users with a verified email address in one of the domains,
+	// and to members of one of the groups
+	OIDCAllowedDomains []string
+	OIDCAllowedGroups  []string
+	// ProjectsPath is the directory projects are summarised from
+	ProjectsPath string
+	// AgentToken enables agents, that authenticate with it, to run jobs
+	AgentToken string
+	// AgentKinds are the job kinds that are left to agents
+	AgentKinds []string
+	// Metrics serves Prometheus metrics on /metrics
+	Metrics bool
+	// MinFreeDisk is how many bytes must be free in the screenshot path
+	// for the server to report that it is ready
+	MinFreeDisk int64
+	// GRPCPort serves the gRPC machine API on this port, if set. Calls
+	// authenticate with GRPCToken.
+	GRPCPort  int
+	GRPCToken string
+
+	// db is the database users are authenticated against
+	db *gorm.DB
+	// multiUser is set when users exist, enabling per-user logins instead
+	// of the shared Password
+	multiUser bool
+	// oidc is the identity provider users log in with, if any
+	oidc *oidc.Provider
 }
 
 // NewServer returns a new server intance
@@ -34,6 +93,36 @@ func NewServer(host string, port int, dburi string, screenshotpath string, passw
 		DbUri:          dburi,
 		ScreenshotPath: screenshotpath,
 		Password:       password,
+		OIDCRole:       auth.RoleViewer,
+		ProjectsPath:   "targets",
+		MinFreeDisk:    100 << 20,
+
+		ContentSecurityPolicy: DefaultContentSecurityPolicy,
+	}
+}
+
+// tlsEnabled checks if the server is configured to serve HTTPS
+func (s *Server) tlsEnabled() bool {
+	return s.TLSCert != "" && s.TLSKey != ""
+}
+
+// securityConfig returns the security configuration the api reports
+func (s *Server) securityConfig() api.SecurityConfig {
+	authMode := api.AuthModeNone
+	if s.oidc != nil {
+		authMode = api.AuthModeOIDC
+	} else if s.multiUser {
+		authMode = api.AuthModeUsers
+	} else if s.Password != "" {
+		authMode = api.AuthModePassword
+	}
+
+	return api.SecurityConfig{
+		AuthMode:       authMode,
+		TLSEnabled:     s.tlsEnabled(),
+		ReadOnly:       s.ReadOnly,
+		IPAllowlist:    len(s.AllowedIPs) > 0,
+		CSRFProtection: true,
 	}
 }
 
@@ -51,10 +140,13 @@ func hashPassword(password string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// getBasePath extracts the base path from X-Forwarded-Prefix header or returns "/"
+// getBasePath extracts the base path from X-Forwarded-Prefix header or returns "/".
+// The header is client controlled, so a prefix that isn't a plain absolute
+// path, and could redirect elsewhere or break out of HTML, is ignored.
 func getBasePath(r *http.Request) string {
 	prefix := r.Header.Get("X-Forwarded-Prefix")
-	if prefix == "" {
+	if prefix == "" || prefix[0] != '/' || strings.Contains(prefix, "//") ||
+		strings.ContainsAny(prefix, "\\\"'<>` \t\r\n") {
 		return "/"
 	}
 	// Ensure prefix ends with /
@@ -91,20 +183,20 @@ func (s *Server) passwordAuthMiddleware(next http.Handler) http.Handler {
 func (s *Server) loginHandler(w http.ResponseWriter, r *http.Request) {
 	basePath := getBasePath(r)
 
+	if r.Method == "POST" && s.sessionLogins() {
+		s.userLogin(w, r, basePath)
+		return
+	}
+
 	if r.Method == "POST" {
 		// Process login form
 		password := r.FormValue("password")
 		if password == s.Password {
 			// Set authentication cookie with the correct path
-			cookiePath := basePath
-			if basePath != "/" {
-				cookiePath = basePath[:len(basePath)-1] // Remove trailing slash for non-root paths
-			}
-
 			cookie := &http.Cookie{
 				Name:     "gowitness_auth",
 				Value:    hashPassword(s.Password),
-				Path:     cookiePath,
+				Path:     cookiePath(basePath),
 				HttpOnly: true,
 				Secure:   r.TLS != nil,
 				SameSite: http.SameSiteStrictMode,
@@ -167,7 +259,7 @@ func (s *Server) renderLoginPage(w http.ResponseWriter, errorMsg string, basePat
             color: #555;
             font-weight: 500;
         }
-        input[type="password"] {
+        input[type="password"], input[type="text"] {
             width: 100%;
             padding: 0.75rem;
             border: 1px solid #ddd;
@@ -175,7 +267,7 @@ func (s *Server) renderLoginPage(w http.ResponseWriter, errorMsg string, basePat
             font-size: 1rem;
             box-sizing: border-box;
         }
-        input[type="password"]:focus {
+        input[type="password"]:focus, input[type="text"]:focus {
             outline: none;
             border-color: #007bff;
         }
@@ -192,6 +284,13 @@ func (s *Server) renderLoginPage(w http.ResponseWriter, errorMsg string, basePat
         .btn:hover {
             background: #0056b3;
         }
+        .sso {
+            display: block;
+            text-align: center;
+            text-decoration: none;
+            box-sizing: border-box;
+            margin-bottom: 1rem;
+        }
         .error {
             color: #dc3545;
             margin-bottom: 1rem;
@@ -208,24 +307,41 @@ func (s *Server) renderLoginPage(w http.ResponseWriter, errorMsg string, basePat
         {{if .Error}}
         <div class="error">{{.Error}}</div>
         {{end}}
+        {{if .SSO}}
+        <a href="{{.BasePath}}login/oidc" class="btn sso">Login with single sign-on</a>
+        {{end}}
+        {{if .PasswordForm}}
         <form method="POST" action="{{.BasePath}}login">
+            {{if .MultiUser}}
+            <div class="form-group">
+                <label for="username">Username:</label>
+                <input type="text" id="username" name="username" required autofocus>
+            </div>
+            {{end}}
             <div class="form-group">
                 <label for="password">Password:</label>
-                <input type="password" id="password" name="password" required autofocus>
+                <input type="password" id="password" name="password" required {{if not .MultiUser}}autofocus{{end}}>
             </div>
             <button type="submit" class="btn">Login</button>
         </form>
+        {{end}}
     </div>
 </body>
 </html>`
 
 	tmpl := template.Must(template.New("login").Parse(loginTemplate))
 	data := struct {
-		Error    string
-		BasePath string
+		Error        string
+		BasePath     string
+		MultiUser    bool
+		PasswordForm bool
+		SSO          bool
 	}{
-		Error:    errorMsg,
-		BasePath: basePath,
+		Error:        errorMsg,
+		BasePath:     basePath,
+		MultiUser:    s.multiUser,
+		PasswordForm: s.multiUser || s.oidc == nil,
+		SSO:          s.oidc != nil,
 	}
 
 	w.Header().Set("Content-Type", "text/html")
@@ -244,52 +360,175 @@ func (s *Server) Run() {
 	// get the router ready
 	r := chi.NewRouter()
 
-	r.Use(middleware.Logger)
+	r.Use(requestLogger)
+	r.Use(requestMetrics)
+	r.Use(s.allowedIPsMiddleware)
 	r.Use(middleware.CleanPath)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Recoverer)
+	r.Use(s.securityHeadersMiddleware)
+	// cors is handled before logins, as preflight requests carry no
+	// credentials
+	r.Use(s.corsMiddleware())
 
 	apih, err := api.NewApiHandler(s.DbUri, s.ScreenshotPath)
 	if err != nil {
 		log.Error("could not get api handler up", "err", err)
 		return
 	}
+	s.db = apih.DB
+
+	s.multiUser, err = auth.HasUsers(s.db)
+	if err != nil {
+		log.Error("could not check for users", "err", err)
+		return
+	}
+	if s.OIDCIssuer != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		s.oidc, err = oidc.NewProvider(ctx, oidc.Config{
+			Issuer:       s.OIDCIssuer,
+			ClientID:     s.OIDCClientID,
+			ClientSecret: s.OIDCClientSecret,
+			Scopes:       []string{"email", "profile"},
+		})
+		cancel()
+		if err != nil {
+			log.Error("could not set up oidc", "issuer", s.OIDCIssuer, "err", err)
+			return
+		}
+	}
+	if s.sessionLogins() {
+		if err := auth.PruneSessions(s.db); err != nil {
+			log.Warn("could not prune expired login sessions", "err", err)
+		}
+	}
+	apih.Security = s.securityConfig()
+	apih.ProjectsPath = s.ProjectsPath
+
+	if err := apih.StartJobs(s.AgentKinds); err != nil {
+		log.Error("could not start job queue", "err", err)
+		return
+	}
+
+	if s.GRPCPort > 0 {
+		go s.serveGRPC(apih)
+	}
 
 	// Add login route (not protected by auth middleware)
-	if s.Password != "" {
+	if s.Password != "" || s.sessionLogins() {
 		r.HandleFunc("/login", s.loginHandler)
 	}
+	if s.oidc != nil {
+		r.Get("/login/oidc", s.oidcLoginHandler)
+		r.Get("/login/oidc/callback", s.oidcCallbackHandler)
+	}
+	if s.sessionLogins() {
+		r.HandleFunc("/logout", s.logoutHandler)
+	}
+
+	// health checks and metrics are not authenticated, so that
+	// orchestrators and scrapers can reach them
+	r.Get("/healthz", s.healthzHandler)
+	r.Get("/readyz", s.readyzHandler)
+	if s.Metrics {
+		r.Handle("/metrics", metrics.Handler())
+	}
+
+	// agents authenticate with their token instead of logins
+	if s.AgentToken != "" {
+		r.Route("/api/agent", func(r chi.Router) {
+			r.Use(s.agentAuthMiddleware)
+			r.Use(s.readOnlyMiddleware)
+			r.Use(isJSON)
+
+			r.Post("/claim", apih.AgentClaimHandler)
+			r.Post("/jobs/{id}/heartbeat", apih.AgentHeartbeatHandler)
+			r.Post("/jobs/{id}/results", apih.AgentResultHandler)
+			r.Post("/jobs/{id}/failures", apih.AgentFailureHandler)
+			r.Post("/jobs/{id}/ip-info", apih.AgentIPInfoHandler)
+			r.Post("/jobs/{id}/finish", apih.AgentFinishHandler)
+		})
+	}
 
 	// Apply authentication middleware to all routes except login
 	r.Route("/", func(r chi.Router) {
-		r.Use(s.passwordAuthMiddleware)
+		if s.sessionLogins() {
+			r.Use(s.userAuthMiddleware)
+			r.Use(s.roleMiddleware)
+		} else {
+			r.Use(s.passwordAuthMiddleware)
+		}
+		r.Use(csrfMiddleware)
+		r.Use(s.readOnlyMiddleware)
 
 		r.Route("/api", func(r chi.Router) {
 			r.Use(isJSON)
-			r.Use(cors.Handler(cors.Options{
-				AllowedOrigins: []string{"*"}, // TODO: flag this
-			}))
 
 			r.Get("/ping", apih.PingHandler)
+			r.Get("/csrf", csrfTokenHandler)
 			r.Get("/statistics", apih.StatisticsHandler)
 			r.Get("/scan-sessions", apih.ScanSessionsHandler)
+			r.Get("/scan-sessions/{id}/logo", apih.ScanSessionLogoHandler)
 			r.Get("/wappalyzer", apih.WappalyzerHandler)
+			r.Get("/wappalyzer/versions", apih.WappalyzerVersionsHandler)
 			r.Get("/security/status", apih.SecurityStatusHandler)
 			r.Get("/ip/{ip}", apih.IPInfoHandler)
+			r.Get("/domain/{domain}", apih.DomainInfoHandler)
+			r.Get("/domains/expiring", apih.ExpiringDomainsHandler)
+			r.Get("/ips/export", apih.IPExportHandler)
+			r.Get("/targets", apih.TargetsHandler)
+			r.Get("/dns-records", apih.DNSRecordsHandler)
+			r.Get("/findings", apih.FindingsHandler)
+			r.Post("/tickets", apih.CreateTicketsHandler)
+			r.Get("/cookies/insecure", apih.InsecureCookiesHandler)
+			r.Get("/headers/summary", apih.HeaderSummaryHandler)
+			r.Get("/projects/{name}/summary", apih.ProjectSummaryHandler)
+			r.Get("/ports", apih.PortsHandler)
+			r.Get("/tls/expiring", apih.TLSExpiringHandler)
+			r.Get("/tls/report", apih.TLSReportHandler)
+			r.Get("/extracted/urls", apih.ExtractedURLsHandler)
+			r.Get("/extracted/secrets", apih.SecretsHandler)
 			r.Get("/logo", apih.LogoHandler)
 			r.Post("/search", apih.SearchHandler)
 			r.Post("/submit", apih.SubmitHandler)
 			r.Post("/submit/single", apih.SubmitSingleHandler)
+			r.Post("/submit/file", apih.SubmitFileHandler)
+			r.Get("/jobs", apih.JobsHandler)
+			r.Post("/jobs", apih.SubmitJobHandler)
+			r.Get("/jobs/{id}", apih.JobHandler)
 
 			r.Get("/results/gallery", apih.GalleryHandler)
 			r.Get("/results/list", apih.ListHandler)
 			r.Get("/results/detail/{id}", apih.DetailHandler)
+			r.Get("/results/screenshot/{id}", apih.ScreenshotHandler)
 			r.Post("/results/delete", apih.DeleteResultHandler)
+			r.Post("/results/purge", apih.PurgeResultsHandler)
 			r.Get("/results/technology", apih.TechnologyListHandler)
+			r.Post("/results/triage", apih.TriageHandler)
+			r.Post("/results/{id}/tags", apih.AddResultTagHandler)
+			r.Post("/results/{id}/rescan", apih.RescanResultHandler)
+			r.Delete("/results/{id}/tags/{name}", apih.RemoveResultTagHandler)
+			r.Get("/tags", apih.TagsHandler)
+			r.Get("/notes", apih.NotesHandler)
+			r.Post("/notes", apih.CreateNoteHandler)
+			r.Put("/notes/{id}", apih.UpdateNoteHandler)
+			r.Delete("/notes/{id}", apih.DeleteNoteHandler)
+
+			r.Get("/users/me", apih.MeHandler)
+			r.Group(func(r chi.Router) {
+				r.Use(requireAdmin)
+				r.Get("/users", apih.UsersHandler)
+				r.Post("/users", apih.CreateUserHandler)
+				r.Delete("/users/{id}", apih.DeleteUserHandler)
+			})
 		})
 
-		// screenshot files
-		r.Mount("/screenshots", http.StripPrefix("/screenshots/", http.FileServer(http.Dir(s.ScreenshotPath))))
+		// screenshot thumbnails, generated on the fly when missing
+		r.Get("/screenshots/thumb/*", s.thumbnailHandler)
+
+		// screenshot files. directory listings are not served, use
+		// /api/results/screenshot/{id} to resolve per scan session.
+		r.Mount("/screenshots", http.StripPrefix("/screenshots/", http.FileServer(fileOnlyFS{http.Dir(s.ScreenshotPath)})))
 
 		// swagger documentation
 		r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL("/swagger/doc.json")))
@@ -299,10 +538,41 @@ func (s *Server) Run() {
 	})
 
 	log.Info("starting web server", "host", s.Host, "port", s.Port)
-	if s.Password != "" {
+	if s.oidc != nil {
+		log.Info("oidc authentication enabled", "issuer", s.OIDCIssuer, "default-role", s.OIDCRole)
+	}
+	if s.sessionLogins() {
+		log.Info("per-user authentication enabled")
+		if s.Password != "" {
+			log.Warn("per-user logins are enabled, so the shared password is ignored")
+		}
+	} else if s.Password != "" {
 		log.Info("password protection enabled")
 	}
-	if err := http.ListenAndServe(s.Host+":"+strconv.Itoa(s.Port), r); err != nil {
+	if len(s.AllowedIPs) > 0 {
+		log.Info("ip allowlist enabled", "allowed", s.AllowedIPs)
+	}
+	if s.ReadOnly {
+		log.Info("read-only mode enabled")
+	}
+	if len(s.AllowedOrigins) > 0 {
+		log.Info("cross-origin requests enabled", "origins", s.AllowedOrigins)
+	}
+	if s.Metrics {
+		log.Info("metrics enabled", "path", "/metrics")
+	}
+	if s.AgentToken != "" {
+		log.Info("agents enabled", "agent-kinds", s.AgentKinds)
+	}
+
+	addr := s.Host + ":" + strconv.Itoa(s.Port)
+	if s.tlsEnabled() {
+		log.Info("tls enabled", "cert", s.TLSCert)
+		err = http.ListenAndServeTLS(addr, s.TLSCert, s.TLSKey, r)
+	} else {
+		err = http.ListenAndServe(addr, r)
+	}
+	if err != nil {
 		log.Error("server listen error", "err", err)
 	}
 }
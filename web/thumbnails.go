@@ -0,0 +1,49 @@
+package web
+
+import (
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/pkg/thumbnail"
+)
+
+// thumbnailHandler serves a thumbnail of a screenshot, generating it on
+// the fly if it does not exist yet. The width defaults to
+// thumbnail.DefaultWidth and can be set to one of thumbnail.Widths with the
+// w query parameter.
+func (s *Server) thumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	// thumbnail.Clean keeps us inside the screenshot path
+	file := thumbnail.Clean(chi.URLParam(r, "*"))
+	if file == "" {
+		http.Error(w, "Screenshot not found", http.StatusNotFound)
+		return
+	}
+
+	width := thumbnail.DefaultWidth
+	if raw := r.URL.Query().Get("w"); raw != "" {
+		var err error
+		width, err = strconv.Atoi(raw)
+		if err != nil || !slices.Contains(thumbnail.Widths, width) {
+			http.Error(w, "Invalid thumbnail width", http.StatusBadRequest)
+			return
+		}
+	}
+
+	path, err := thumbnail.Generate(s.ScreenshotPath, file, width)
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "Screenshot not found", http.StatusNotFound)
+			return
+		}
+
+		log.Error("failed to generate thumbnail", "file", file, "width", width, "err", err)
+		http.Error(w, "Error generating thumbnail", http.StatusInternalServerError)
+		return
+	}
+
+	http.ServeFile(w, r, path)
+}
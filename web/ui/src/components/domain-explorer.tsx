@@ -113,6 +113,7 @@ export function DomainExplorer({ domains }: DomainExplorerProps) {
                   </span>
                 </div>
                 <div className="flex items-center gap-2">
+                  {domain.in_scope && <Badge variant="secondary">in scope</Badge>}
                   <Badge variant="outline">{domain.count} screenshots</Badge>
                   <LayersIcon className="h-4 w-4 text-muted-foreground" />
                 </div>
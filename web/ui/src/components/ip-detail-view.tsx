@@ -21,7 +21,8 @@ import {
   MapIcon,
   CompassIcon,
   MailIcon,
-  RouteIcon
+  RouteIcon,
+  HistoryIcon
 } from "lucide-react";
 import { get } from "@/lib/api/api";
 import * as apitypes from "@/lib/api/types";
@@ -193,6 +194,25 @@ export function IPDetailView({ ipAddress, onBack }: IPDetailViewProps) {
           </div>
         </div>
         
+        {/* Netblock Information */}
+        {ipInfo.netblock && (
+          <div className="bg-card border rounded-lg p-4">
+            <div className="font-medium text-sm text-muted-foreground mb-1 flex items-center gap-2">
+              <BuildingIcon className="h-4 w-4" />
+              Netblock
+            </div>
+            <div className="font-semibold text-foreground">
+              {ipInfo.netblock.owner || ipInfo.netblock.name}
+            </div>
+            <div className="text-sm text-muted-foreground font-mono">
+              {ipInfo.netblock.cidrs?.length > 0
+                ? ipInfo.netblock.cidrs.join(", ")
+                : `${ipInfo.netblock.start_address} - ${ipInfo.netblock.end_address}`}
+              {" "}({ipInfo.netblock.handle}{ipInfo.netblock.country && `, ${ipInfo.netblock.country}`})
+            </div>
+          </div>
+        )}
+
         {/* Shodan Information */}
         {ipInfo.shodan_info && (
           <div className="space-y-6">
@@ -450,6 +470,38 @@ export function IPDetailView({ ipAddress, onBack }: IPDetailViewProps) {
                         <div className="text-sm font-medium">{port.service}</div>
                       </div>
                     )}
+
+                    {(port.product || port.version) && (
+                      <div className="mb-2">
+                        <div className="text-xs text-muted-foreground mb-1">Product</div>
+                        <div className="text-sm font-medium">
+                          {[port.product, port.version].filter(Boolean).join(' ')}
+                        </div>
+                      </div>
+                    )}
+
+                    {(port.http_title || port.http_status) && (
+                      <div className="mb-2">
+                        <div className="text-xs text-muted-foreground mb-1">HTTP</div>
+                        <div className="text-sm truncate" title={port.http_title}>
+                          {port.http_status ? `${port.http_status} ` : ''}{port.http_title}
+                        </div>
+                      </div>
+                    )}
+
+                    {port.tls_subject && (
+                      <div className="mb-2">
+                        <div className="text-xs text-muted-foreground mb-1">TLS Certificate</div>
+                        <div className="text-sm truncate" title={port.tls_subject}>{port.tls_subject}</div>
+                        <div className="text-xs text-muted-foreground">
+                          {port.tls_issuer && <>Issued by {port.tls_issuer}</>}
+                          {port.tls_expires && <> · Expires {new Date(port.tls_expires).toLocaleDateString()}</>}
+                        </div>
+                        {port.tls_versions && (
+                          <div className="text-xs text-muted-foreground">{port.tls_versions.split(',').join(', ')}</div>
+                        )}
+                      </div>
+                    )}
                     
                     <div className="flex flex-wrap gap-2 mb-3">
                       {port.is_cdn && (
@@ -558,6 +610,80 @@ export function IPDetailView({ ipAddress, onBack }: IPDetailViewProps) {
             )}
           </CollapsibleContent>
         </Collapsible>
+
+        {/* History */}
+        <Collapsible
+          open={openSections.has('history')}
+          onOpenChange={() => toggleSection('history')}
+        >
+          <CollapsibleTrigger className="flex items-center gap-2 text-lg font-semibold hover:text-blue-600 transition-colors">
+            {openSections.has('history') ? (
+              <ChevronDownIcon className="h-5 w-5" />
+            ) : (
+              <ChevronRightIcon className="h-5 w-5" />
+            )}
+            <HistoryIcon className="h-5 w-5" />
+            History ({ipInfo.history?.length || 0})
+          </CollapsibleTrigger>
+          <CollapsibleContent className="mt-4">
+            {!ipInfo.history || ipInfo.history.length === 0 ? (
+              <div className="text-muted-foreground italic">No history recorded</div>
+            ) : (
+              <div className="space-y-3">
+                {ipInfo.history.map((snapshot, index) => (
+                  <div key={index} className="bg-card border rounded-lg p-4">
+                    <div className="flex items-center justify-between text-sm">
+                      <div className="flex items-center gap-1 font-medium">
+                        <ClockIcon className="h-3 w-3" />
+                        {new Date(snapshot.recorded_at).toLocaleString()}
+                      </div>
+                      <div className="flex gap-2">
+                        {snapshot.source && (
+                          <Badge variant="secondary" className="text-xs">{snapshot.source}</Badge>
+                        )}
+                        {snapshot.scan_session_id && (
+                          <Badge variant="outline" className="text-xs">Session {snapshot.scan_session_id}</Badge>
+                        )}
+                      </div>
+                    </div>
+
+                    {index === ipInfo.history.length - 1 ? (
+                      <div className="text-xs text-muted-foreground mt-2">
+                        First recorded: {snapshot.organization || 'unknown organization'}, {snapshot.ports.length} ports, {snapshot.vulns.length} vulns
+                      </div>
+                    ) : !snapshot.changes || snapshot.changes.length === 0 ? (
+                      <div className="text-xs text-muted-foreground mt-2">No changes</div>
+                    ) : (
+                      <div className="mt-2 space-y-1">
+                        {snapshot.changes.map((change) => (
+                          <div key={change.field} className="text-xs flex flex-wrap items-center gap-1">
+                            <span className="font-medium capitalize">{change.field}:</span>
+                            {change.from !== undefined || change.to !== undefined ? (
+                              <span>
+                                <span className="text-red-600 line-through">{change.from || 'none'}</span>
+                                {' → '}
+                                <span className="text-green-600">{change.to || 'none'}</span>
+                              </span>
+                            ) : (
+                              <>
+                                {change.added?.map((value) => (
+                                  <Badge key={`+${value}`} variant="outline" className="text-xs text-green-600">+{value}</Badge>
+                                ))}
+                                {change.removed?.map((value) => (
+                                  <Badge key={`-${value}`} variant="outline" className="text-xs text-red-600">-{value}</Badge>
+                                ))}
+                              </>
+                            )}
+                          </div>
+                        ))}
+                      </div>
+                    )}
+                  </div>
+                ))}
+              </div>
+            )}
+          </CollapsibleContent>
+        </Collapsible>
       </CardContent>
     </Card>
   );
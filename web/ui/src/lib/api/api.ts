@@ -1,4 +1,5 @@
-import { gallery, list, statistics, wappalyzer, detail, searchresult, technologylist, IPInfoResponse } from "@/lib/api/types";
+import { gallery, list, statistics, wappalyzer, wappalyzertechnology, detail, searchresult, technologylist, IPInfoResponse, DomainInfoResponse, domainexpiry, job, projectsummary, tlsentry, tlsreport, taglistentry, resulttag, note, extractedurl, secret } from "@/lib/api/types";
+import { getCookie } from "@/lib/cookies";
 
 // Dynamically determine the base API path from the current URL
 function getApiBasePath(): string {
@@ -24,6 +25,22 @@ function getScreenshotsBasePath(): string {
   return `/screenshots`;
 }
 
+// The scan session the UI is limited to. Opening any page with ?session=<id>
+// limits the UI to that session, and ?session= shows all sessions again.
+function getSessionFilter(): string | null {
+  const params = new URLSearchParams(window.location.search);
+  if (params.has('session')) {
+    const session = params.get('session') || '';
+    if (session) {
+      sessionStorage.setItem('gowitness_session', session);
+    } else {
+      sessionStorage.removeItem('gowitness_session');
+    }
+  }
+
+  return sessionStorage.getItem('gowitness_session');
+}
+
 const endpoints = {
   // screenshot path (kept for backward compatibility)
   screenshot: {
@@ -40,6 +57,10 @@ const endpoints = {
     path: `/wappalyzer`,
     returnas: {} as wappalyzer
   },
+  wappalyzerversions: {
+    path: `/wappalyzer/versions`,
+    returnas: [] as wappalyzertechnology[]
+  },
   gallery: {
     path: `/results/gallery`,
     returnas: {} as gallery
@@ -60,6 +81,50 @@ const endpoints = {
     path: `/ip/:ip`,
     returnas: {} as IPInfoResponse
   },
+  domaininfo: {
+    path: `/domain/:domain`,
+    returnas: {} as DomainInfoResponse
+  },
+  expiringdomains: {
+    path: `/domains/expiring`,
+    returnas: [] as domainexpiry[]
+  },
+  jobs: {
+    path: `/jobs`,
+    returnas: [] as job[]
+  },
+  job: {
+    path: `/jobs/:id`,
+    returnas: {} as job
+  },
+  projectsummary: {
+    path: `/projects/:name/summary`,
+    returnas: {} as projectsummary
+  },
+  tlsexpiring: {
+    path: `/tls/expiring`,
+    returnas: [] as tlsentry[]
+  },
+  tlsreport: {
+    path: `/tls/report`,
+    returnas: {} as tlsreport
+  },
+  tags: {
+    path: `/tags`,
+    returnas: [] as taglistentry[]
+  },
+  notes: {
+    path: `/notes`,
+    returnas: [] as note[]
+  },
+  extractedurls: {
+    path: `/extracted/urls`,
+    returnas: [] as extractedurl[]
+  },
+  secrets: {
+    path: `/extracted/secrets`,
+    returnas: [] as secret[]
+  },
 
   // post endpoints
   search: {
@@ -77,6 +142,36 @@ const endpoints = {
   submitsingle: {
     path: `/submit/single`,
     returnas: {} as detail
+  },
+  submitjob: {
+    path: `/jobs`,
+    returnas: {} as job
+  },
+  triage: {
+    path: `/results/triage`,
+    returnas: "" as string
+  },
+  addtag: {
+    path: `/results/:id/tags`,
+    returnas: {} as resulttag
+  },
+  rescan: {
+    path: `/results/:id/rescan`,
+    returnas: {} as detail
+  },
+  createnote: {
+    path: `/notes`,
+    returnas: {} as note
+  },
+
+  // delete endpoints
+  removetag: {
+    path: `/results/:id/tags/:name`,
+    returnas: "" as string
+  },
+  deletenote: {
+    path: `/notes/:id`,
+    returnas: "" as string
   }
 };
 
@@ -126,6 +221,10 @@ const get = async <K extends keyof Endpoints>(
 
   const endpoint = endpoints[endpointKey];
   const [pathWithParams, remainingParams] = replacePathParams(endpoint.path, params);
+  const session = getSessionFilter();
+  if (session && !('session' in remainingParams)) {
+    remainingParams.session = session;
+  }
   const queryString = remainingParams ? serializeParams(remainingParams) : '';
 
   // Dynamically determine the base API path for each request
@@ -143,19 +242,26 @@ const get = async <K extends keyof Endpoints>(
 
 const post = async <K extends keyof Endpoints>(
   endpointKey: K,
-  data?: unknown
+  data?: unknown,
+  params?: Record<string, string | number | boolean>
 ): Promise<EndpointReturnType<K>> => {
 
   const endpoint = endpoints[endpointKey];
+  const [pathWithParams] = replacePathParams(endpoint.path, params);
+  const session = getSessionFilter();
+  const queryString = session ? serializeParams({ session }) : '';
   
   // Dynamically determine the base API path for each request
   const basePath = import.meta.env.VITE_GOWITNESS_API_BASE_URL 
     ? import.meta.env.VITE_GOWITNESS_API_BASE_URL + `/api`
     : getApiBasePath();
 
-  const res = await fetch(`${basePath}${endpoint.path}`, {
+  const res = await fetch(`${basePath}${pathWithParams}${queryString}`, {
     method: 'POST',
-    headers: { 'Content-Type': 'application/json' },
+    headers: {
+      'Content-Type': 'application/json',
+      'X-CSRF-Token': getCookie('gowitness_csrf') || '',
+    },
     body: JSON.stringify(data)
   });
 
@@ -164,6 +270,31 @@ const post = async <K extends keyof Endpoints>(
   return await res.json() as EndpointReturnType<K>;
 };
 
+const del = async <K extends keyof Endpoints>(
+  endpointKey: K,
+  params?: Record<string, string | number | boolean>
+): Promise<EndpointReturnType<K>> => {
+
+  const endpoint = endpoints[endpointKey];
+  const [pathWithParams] = replacePathParams(endpoint.path, params);
+
+  // Dynamically determine the base API path for each request
+  const basePath = import.meta.env.VITE_GOWITNESS_API_BASE_URL 
+    ? import.meta.env.VITE_GOWITNESS_API_BASE_URL + `/api`
+    : getApiBasePath();
+
+  const res = await fetch(`${basePath}${pathWithParams}`, {
+    method: 'DELETE',
+    headers: {
+      'X-CSRF-Token': getCookie('gowitness_csrf') || '',
+    },
+  });
+
+  if (!res.ok) throw new Error(`HTTP Error: ${res.status}`);
+
+  return await res.json() as EndpointReturnType<K>;
+};
+
 // Export the screenshot path function for use in components
 const getScreenshotUrl = (filename: string): string => {
   const basePath = import.meta.env.VITE_GOWITNESS_API_BASE_URL 
@@ -172,4 +303,4 @@ const getScreenshotUrl = (filename: string): string => {
   return `${basePath}/${filename}`;
 };
 
-export { endpoints, get, post, getScreenshotUrl };
\ No newline at end of file
+export { endpoints, get, post, del, getScreenshotUrl };
\ No newline at end of file
@@ -14,10 +14,26 @@ type statistics = {
 interface target_information {
   company_name: string;
   main_domain: string;
+  apex_domains: string[];
   logo_path?: string;
   scan_start_time: string;
   scan_status: string;
   notes: string;
+  industry?: string;
+  employee_count?: number;
+  netblocks?: string[];
+  domains?: string[];
+  enrichment_source?: string;
+  breaches?: breach_summary[];
+  breached_accounts: number;
+}
+
+interface breach_summary {
+  domain: string;
+  source: string;
+  accounts: number;
+  breaches: number;
+  checked_at: string;
 }
 
 interface response_code_stats {
@@ -41,6 +57,7 @@ interface ip_statistics {
 interface apex_domain {
   domain: string;
   is_apex: boolean;
+  in_scope: boolean;
   result_id?: number;
   subdomains: subdomain[];
   count: number;
@@ -77,6 +94,23 @@ type wappalyzer = {
   [name: string]: string;
 };
 
+// wappalyzer versions
+type wappalyzerversion = {
+  version: string;
+  cpe?: string;
+  count: number;
+};
+
+type wappalyzertechnology = {
+  name: string;
+  icon?: string;
+  website?: string;
+  categories: string[];
+  cpe?: string;
+  count: number;
+  versions: wappalyzerversion[];
+};
+
 // gallery
 type gallery = {
   results: galleryResult[];
@@ -92,9 +126,16 @@ type galleryResult = {
   title: string;
   response_code: number;
   file_name: string;
+  thumbnail: string;
   screenshot: string;
   failed: boolean;
+  noise: boolean;
   technologies: string[];
+  annotations: string[];
+  tags: string[];
+  reviewed: boolean;
+  interesting: boolean;
+  assigned_to?: string;
 };
 
 // list
@@ -109,6 +150,8 @@ type list = {
   title: string;
   failed: boolean;
   failed_reason: string;
+  security_score: number;
+  security_grade: string;
 };
 
 // details
@@ -137,6 +180,9 @@ interface technology {
   id: number;
   result_id: number;
   value: string;
+  name: string;
+  version?: string;
+  cpe?: string;
 }
 
 interface header {
@@ -183,9 +229,91 @@ interface cookie {
   source_port: number;
 }
 
+interface resulttag {
+  id: number;
+  result_id: number;
+  name: string;
+  source: string;
+  created_at: string;
+}
+
+interface discoveredpath {
+  id: number;
+  result_id: number;
+  path: string;
+  url: string;
+  status_code: number;
+  content_length: number;
+  content_type: string;
+  title: string;
+  location?: string;
+  screenshot_result_id?: number;
+  created_at: string;
+}
+
+interface sitefile {
+  id: number;
+  result_id: number;
+  kind: string;
+  url: string;
+  status_code: number;
+  content: string;
+  url_count: number;
+  created_at: string;
+}
+
+interface extractedurl {
+  id: number;
+  result_id: number;
+  kind: string;
+  url: string;
+  host: string;
+  source: string;
+  result_url?: string;
+}
+
+interface secret {
+  id: number;
+  result_id: number;
+  kind: string;
+  value: string;
+  source: string;
+  location: string;
+  result_url?: string;
+}
+
+interface note {
+  id: number;
+  result_id?: number;
+  ip_info_id?: number;
+  scan_session_id?: number;
+  author: string;
+  body: string;
+  created_at: string;
+  updated_at: string;
+}
+
+interface taglistentry {
+  id: number;
+  name: string;
+  description?: string;
+  created_at: string;
+  results: number;
+}
+
+interface securityissue {
+  id: number;
+  result_id: number;
+  kind: string;
+  severity: string;
+  detail: string;
+  penalty: number;
+}
+
 interface detail {
   id: number;
   url: string;
+  previous_result_id?: number;
   ip_address: string;
   probed_at: string;
   final_url: string;
@@ -197,6 +325,8 @@ interface detail {
   title: string;
   perception_hash: string;
   file_name: string;
+  full_page_file_name?: string;
+  element_file_name?: string;
   is_pdf: boolean;
   failed: boolean;
   failed_reason: string;
@@ -207,6 +337,20 @@ interface detail {
   network: networklog[];
   console: consolelog[];
   cookies: cookie[];
+  security_score: number;
+  security_grade: string;
+  security_issues?: securityissue[];
+  tags?: resulttag[];
+  notes?: note[];
+  paths?: discoveredpath[];
+  site_files?: sitefile[];
+  extracted_urls?: extractedurl[];
+  secrets?: secret[];
+  reviewed: boolean;
+  reviewed_by?: string;
+  reviewed_at?: string;
+  interesting: boolean;
+  assigned_to?: string;
 }
 
 interface searchresult {
@@ -233,6 +377,14 @@ interface IPPortInfo {
   service: string;
   state: string;
   banner: string;
+  product?: string;
+  version?: string;
+  http_status?: number;
+  http_title?: string;
+  tls_subject?: string;
+  tls_issuer?: string;
+  tls_versions?: string;
+  tls_expires?: string;
   scan_session_id?: number;
   discovered_at: string;
   is_cdn: boolean;
@@ -275,9 +427,34 @@ interface ShodanInfo {
   shodan_domains?: string[];
   vulns?: string[];
   last_update?: string;
+  first_seen?: string;
   updated_at?: string;
 }
 
+interface IPInfoChange {
+  field: string;
+  from?: string;
+  to?: string;
+  added?: string[];
+  removed?: string[];
+}
+
+interface IPInfoSnapshot {
+  recorded_at: string;
+  scan_session_id?: number;
+  source?: string;
+  organization?: string;
+  isp?: string;
+  asn?: string;
+  country?: string;
+  os?: string;
+  ports: number[];
+  vulns: string[];
+  tags: string[];
+  hostnames: string[];
+  changes?: IPInfoChange[];
+}
+
 interface IPInfoResponse {
   ip_address: string;
   open_ports: IPPortInfo[];
@@ -286,11 +463,172 @@ interface IPInfoResponse {
   total_domains: number;
   scan_sessions: number[];
   shodan_info?: ShodanInfo;
+  netblock?: NetblockInfo;
+  history: IPInfoSnapshot[];
+  enrichment_job_id?: number;
+}
+
+interface NetblockInfo {
+  handle: string;
+  name: string;
+  start_address: string;
+  end_address: string;
+  cidrs: string[];
+  owner: string;
+  country: string;
+  type: string;
+  checked_at: string;
+  ip_addresses?: string[];
+}
+
+interface DomainRegistrationInfo {
+  registrar: string;
+  status: string[];
+  nameservers: string[];
+  created_date?: string;
+  expiry_date?: string;
+  updated_date?: string;
+  days_until_expiry?: number;
+  checked_at: string;
+}
+
+interface domainexpiry {
+  domain: string;
+  registrar: string;
+  expiry_date: string;
+  days_left: number;
+  scan_session_id?: number;
+}
+
+interface joberror {
+  url: string;
+  error: string;
+}
+
+interface job {
+  id: number;
+  kind: string;
+  status: string;
+  priority: number;
+  attempts: number;
+  max_attempts: number;
+  total: number;
+  completed: number;
+  failed: number;
+  progress: number;
+  errors: joberror[];
+  error?: string;
+  worker?: string;
+  created_at: string;
+  run_at: string;
+  started_at?: string;
+  heartbeat_at?: string;
+  finished_at?: string;
+}
+
+interface dnsrecord {
+  id: number;
+  host: string;
+  type: string;
+  value: string;
+  dangling: boolean;
+  first_seen: string;
+  last_seen: string;
+  scan_session_id?: number;
+}
+
+interface finding {
+  id: number;
+  result_id?: number;
+  ip_address: string;
+  source: string;
+  title: string;
+  severity: string;
+  description: string;
+  cve?: string;
+  cvss?: number;
+  scan_session_id?: number;
+  created_at: string;
+}
+
+interface DomainTLSInfo {
+  subject_name: string;
+  issuer: string;
+  san_list: string[];
+  valid_from: string;
+  valid_to: string;
+  protocol: string;
+  cipher: string;
+  first_seen: string;
+  last_seen: string;
+}
+
+interface DomainTechnologyInfo {
+  value: string;
+  name: string;
+  version?: string;
+  last_seen: string;
+}
+
+interface DomainPortInfo extends IPPortInfo {
+  ip_address: string;
+}
+
+interface DomainInfoResponse {
+  domain: string;
+  apex_domain: string;
+  registration?: DomainRegistrationInfo;
+  results: DomainInfo[];
+  total_results: number;
+  scan_sessions: number[];
+  ip_addresses: string[];
+  dns_records: dnsrecord[];
+  tls_history: DomainTLSInfo[];
+  technologies: DomainTechnologyInfo[];
+  open_ports: DomainPortInfo[];
+  findings: finding[];
+  netblocks: NetblockInfo[];
+}
+
+interface projectsummary {
+  name: string;
+  company_name?: string;
+  main_domain?: string;
+  status: string;
+  phase?: string;
+  domains: number;
+  ips: number;
+  open_ports: number;
+  screenshots: number;
+  vulns: number;
+  vulns_by_severity: Record<string, number>;
+  last_scan_at?: string;
+}
+
+interface tlsentry {
+  result_id: number;
+  url: string;
+  host: string;
+  subject_name: string;
+  issuer: string;
+  protocol: string;
+  cipher: string;
+  valid_to: string;
+  days_left: number;
+}
+
+interface tlsreport {
+  expiring: tlsentry[];
+  self_signed: tlsentry[];
+  deprecated_protocols: tlsentry[];
+  weak_ciphers: tlsentry[];
 }
 
 export type {
   statistics,
   wappalyzer,
+  wappalyzerversion,
+  wappalyzertechnology,
   gallery,
   list,
   galleryResult,
@@ -301,6 +639,14 @@ export type {
   networklog,
   consolelog,
   cookie,
+  resulttag,
+  taglistentry,
+  note,
+  discoveredpath,
+  sitefile,
+  extractedurl,
+  secret,
+  securityissue,
   detail,
   searchresult,
   technologylist,
@@ -311,8 +657,25 @@ export type {
   ip_entry,
   ip_domain_entry,
   target_information,
+  breach_summary,
   IPPortInfo,
   DomainInfo,
   ShodanInfo,
+  IPInfoChange,
+  IPInfoSnapshot,
   IPInfoResponse,
+  NetblockInfo,
+  DomainRegistrationInfo,
+  DomainInfoResponse,
+  dnsrecord,
+  finding,
+  DomainTLSInfo,
+  DomainTechnologyInfo,
+  DomainPortInfo,
+  domainexpiry,
+  joberror,
+  job,
+  projectsummary,
+  tlsentry,
+  tlsreport,
 };
\ No newline at end of file
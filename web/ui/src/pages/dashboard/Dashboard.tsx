@@ -76,13 +76,59 @@ export default function DashboardPage() {
                   <div className="text-lg font-semibold">{stats.target_info.company_name}</div>
                 </div>
                 <div>
-                  <div className="text-sm font-medium text-muted-foreground">Main Domain</div>
-                  <div className="text-lg font-semibold">{stats.target_info.main_domain}</div>
+                  <div className="text-sm font-medium text-muted-foreground">
+                    {stats.target_info.apex_domains?.length > 1 ? "Main Domains" : "Main Domain"}
+                  </div>
+                  <div className="text-lg font-semibold">
+                    {stats.target_info.apex_domains?.length > 0
+                      ? stats.target_info.apex_domains.join(", ")
+                      : stats.target_info.main_domain}
+                  </div>
                 </div>
                 <div>
                   <div className="text-sm font-medium text-muted-foreground">Scan Started</div>
                   <div className="text-lg font-semibold">{new Date(stats.target_info.scan_start_time).toLocaleDateString()}</div>
                 </div>
+                {stats.target_info.industry && (
+                  <div>
+                    <div className="text-sm font-medium text-muted-foreground">Industry</div>
+                    <div className="text-lg font-semibold">{stats.target_info.industry}</div>
+                  </div>
+                )}
+                {!!stats.target_info.employee_count && (
+                  <div>
+                    <div className="text-sm font-medium text-muted-foreground">Employees</div>
+                    <div className="text-lg font-semibold">{stats.target_info.employee_count.toLocaleString()}</div>
+                  </div>
+                )}
+                {stats.target_info.netblocks && stats.target_info.netblocks.length > 0 && (
+                  <div>
+                    <div className="text-sm font-medium text-muted-foreground">Known Netblocks</div>
+                    <div className="text-sm font-mono" title={stats.target_info.netblocks.join("\n")}>
+                      {stats.target_info.netblocks.slice(0, 3).join(", ")}
+                      {stats.target_info.netblocks.length > 3 && ` +${stats.target_info.netblocks.length - 3} more`}
+                    </div>
+                  </div>
+                )}
+                {stats.target_info.domains && stats.target_info.domains.length > 1 && (
+                  <div className="md:col-span-2">
+                    <div className="text-sm font-medium text-muted-foreground">Official Domains</div>
+                    <div className="text-sm">{stats.target_info.domains.join(", ")}</div>
+                  </div>
+                )}
+                {stats.target_info.breaches && stats.target_info.breaches.length > 0 && (
+                  <div>
+                    <div className="text-sm font-medium text-muted-foreground">Breached Accounts</div>
+                    <div
+                      className="text-lg font-semibold"
+                      title={stats.target_info.breaches
+                        .map((b) => `${b.domain} (${b.source}): ${b.accounts} accounts in ${b.breaches} breaches`)
+                        .join("\n")}
+                    >
+                      {stats.target_info.breached_accounts.toLocaleString()}
+                    </div>
+                  </div>
+                )}
               </div>
               
               {/* Logo Section - Now on the right */}
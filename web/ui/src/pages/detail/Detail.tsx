@@ -4,13 +4,15 @@ import { Table, TableBody, TableCell, TableHead, TableHeader, TableRow } from "@
 import { Badge } from "@/components/ui/badge";
 import { Button } from "@/components/ui/button";
 import { ScrollArea } from "@/components/ui/scroll-area";
-import { ExternalLink, ChevronLeft, ChevronRight, Code, ClockIcon, Trash2Icon, DownloadIcon, ImagesIcon, ZoomInIcon, CopyIcon, ServerIcon } from 'lucide-react';
+import { ExternalLink, ChevronLeft, ChevronRight, Code, ClockIcon, Trash2Icon, DownloadIcon, ImagesIcon, ZoomInIcon, CopyIcon, ServerIcon, CheckCircleIcon, StarIcon, XIcon, CameraIcon } from 'lucide-react';
 import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, DialogTitle, DialogTrigger, } from "@/components/ui/dialog";
 import { WideSkeleton } from '@/components/loading';
 import { Form, Link, useNavigate, useParams } from 'react-router-dom';
 import { format, formatDistanceToNow } from 'date-fns';
 import { Tooltip, TooltipContent, TooltipProvider, TooltipTrigger } from '@/components/ui/tooltip';
 import { Tabs, TabsContent, TabsList, TabsTrigger } from '@/components/ui/tabs';
+import { Input } from '@/components/ui/input';
+import { toast } from '@/hooks/use-toast';
 import { copyToClipboard, getIconUrl, getStatusColor, extractIPAddress } from '@/lib/common';
 import * as api from "@/lib/api/api";
 import * as apitypes from "@/lib/api/types";
@@ -26,6 +28,9 @@ const ScreenshotDetailPage = () => {
   const [duration, setDuration] = useState<string>('');
   const [wappalyzer, setWappalyzer] = useState<apitypes.wappalyzer>({});
   const [loading, setLoading] = useState<boolean>(true);
+  const [newTag, setNewTag] = useState<string>('');
+  const [retaking, setRetaking] = useState<boolean>(false);
+  const [newNote, setNewNote] = useState<string>('');
   const navigate = useNavigate();
 
   const { id } = useParams<{ id: string; }>();
@@ -93,6 +98,96 @@ const ScreenshotDetailPage = () => {
     }
   };
 
+  const handleTriage = async (update: { reviewed?: boolean; interesting?: boolean; }) => {
+    if (!detail) return;
+    try {
+      await api.post('triage', { ids: [detail.id], ...update });
+      setDetail({ ...detail, ...update });
+    } catch (err) {
+      toast({
+        title: "Error",
+        description: `Could not update result: ${err}`,
+        variant: "destructive"
+      });
+    }
+  };
+
+  const handleRetake = async () => {
+    if (!detail) return;
+    setRetaking(true);
+    try {
+      const result = await api.post('rescan', {}, { id: detail.id });
+      navigate(`/screenshot/${result.id}`);
+    } catch (err) {
+      toast({
+        title: "Error",
+        description: `Could not retake screenshot: ${err}`,
+        variant: "destructive"
+      });
+    } finally {
+      setRetaking(false);
+    }
+  };
+
+  const handleAddTag = async () => {
+    if (!detail || !newTag.trim()) return;
+    try {
+      const tag = await api.post('addtag', { name: newTag }, { id: detail.id });
+      const tags = (detail.tags || []).filter(t => t.name !== tag.name);
+      setDetail({ ...detail, tags: [...tags, tag] });
+      setNewTag('');
+    } catch (err) {
+      toast({
+        title: "Error",
+        description: `Could not tag result: ${err}`,
+        variant: "destructive"
+      });
+    }
+  };
+
+  const handleRemoveTag = async (name: string) => {
+    if (!detail) return;
+    try {
+      await api.del('removetag', { id: detail.id, name });
+      setDetail({ ...detail, tags: (detail.tags || []).filter(t => t.name !== name) });
+    } catch (err) {
+      toast({
+        title: "Error",
+        description: `Could not untag result: ${err}`,
+        variant: "destructive"
+      });
+    }
+  };
+
+  const handleAddNote = async () => {
+    if (!detail || !newNote.trim()) return;
+    try {
+      const note = await api.post('createnote', { result_id: detail.id, body: newNote });
+      setDetail({ ...detail, notes: [...(detail.notes || []), note] });
+      setNewNote('');
+    } catch (err) {
+      toast({
+        title: "Error",
+        description: `Could not add note: ${err}`,
+        variant: "destructive"
+      });
+    }
+  };
+
+  const handleDeleteNote = async (noteId: number) => {
+    if (!detail) return;
+    try {
+      await api.del('deletenote', { id: noteId });
+      setDetail({ ...detail, notes: (detail.notes || []).filter(n => n.id !== noteId) });
+    } catch (err) {
+      toast({
+        title: "Error",
+        description: `Could not delete note: ${err}`,
+        variant: "destructive"
+      });
+    }
+  };
+
   if (loading) return <WideSkeleton />;
   if (!detail) return;
 
@@ -118,6 +213,22 @@ const ScreenshotDetailPage = () => {
           </Link>
         </div>
         <div className="flex space-x-2">
+          <Button
+            variant={detail.reviewed ? "secondary" : "outline"}
+            size="sm"
+            onClick={() => handleTriage({ reviewed: !detail.reviewed })}
+          >
+            <CheckCircleIcon className="mr-2 h-4 w-4" />
+            {detail.reviewed ? "Reviewed" : "Mark Reviewed"}
+          </Button>
+          <Button
+            variant={detail.interesting ? "secondary" : "outline"}
+            size="sm"
+            onClick={() => handleTriage({ interesting: !detail.interesting })}
+          >
+            <StarIcon className="mr-2 h-4 w-4" />
+            {detail.interesting ? "Interesting" : "Mark Interesting"}
+          </Button>
           <TooltipProvider delayDuration={0}>
             <Tooltip>
               <TooltipTrigger asChild>
@@ -135,6 +246,24 @@ const ScreenshotDetailPage = () => {
               </TooltipContent>
             </Tooltip>
           </TooltipProvider>
+          <TooltipProvider delayDuration={0}>
+            <Tooltip>
+              <TooltipTrigger asChild>
+                <Button
+                  variant="outline"
+                  size="sm"
+                  disabled={retaking}
+                  onClick={handleRetake}
+                >
+                  <CameraIcon className="mr-2 h-4 w-4" />
+                  {retaking ? "Retaking..." : "Retake"}
+                </Button>
+              </TooltipTrigger>
+              <TooltipContent>
+                <p>Probe the URL again for a fresh screenshot</p>
+              </TooltipContent>
+            </Tooltip>
+          </TooltipProvider>
           <TooltipProvider>
             <Tooltip>
               <TooltipTrigger asChild>
@@ -245,10 +374,30 @@ const ScreenshotDetailPage = () => {
               ) : null;
             })()}
           </div>
-          <Button onClick={() => window.open(detail.url, '_blank')}>
-            <ExternalLink className="mr-2 h-4 w-4" />
-            Open URL
-          </Button>
+          <div className="flex items-center gap-2">
+            {detail.full_page_file_name && (
+              <Button
+                variant="outline"
+                onClick={() => window.open(api.endpoints.screenshot.path + "/" + detail.full_page_file_name, '_blank')}
+              >
+                <ImagesIcon className="mr-2 h-4 w-4" />
+                Full Page
+              </Button>
+            )}
+            {detail.element_file_name && (
+              <Button
+                variant="outline"
+                onClick={() => window.open(api.endpoints.screenshot.path + "/" + detail.element_file_name, '_blank')}
+              >
+                <CameraIcon className="mr-2 h-4 w-4" />
+                Element
+              </Button>
+            )}
+            <Button onClick={() => window.open(detail.url, '_blank')}>
+              <ExternalLink className="mr-2 h-4 w-4" />
+              Open URL
+            </Button>
+          </div>
         </CardFooter>
       </Card>
     );
@@ -700,9 +849,173 @@ const ScreenshotDetailPage = () => {
     );
   };
 
+  const securityTab = (detail: apitypes.detail) => {
+    const issues = detail.security_issues || [];
+    return (
+      <TabsContent value="security">
+        <Card>
+          <CardHeader>
+            <div className="flex justify-between items-center">
+              <CardTitle>Security Headers</CardTitle>
+              {detail.security_grade && (
+                <Badge variant="outline">
+                  Grade {detail.security_grade} ({detail.security_score}/100)
+                </Badge>
+              )}
+            </div>
+          </CardHeader>
+          <CardContent>
+            {issues.length === 0 ? (
+              <div className="text-center text-muted-foreground">No issues</div>
+            ) : (
+              <Table>
+                <TableHeader>
+                  <TableRow>
+                    <TableHead>Issue</TableHead>
+                    <TableHead>Severity</TableHead>
+                    <TableHead>Penalty</TableHead>
+                  </TableRow>
+                </TableHeader>
+                <TableBody>
+                  {issues.map((issue) => (
+                    <TableRow key={issue.id}>
+                      <TableCell>{issue.detail}</TableCell>
+                      <TableCell>
+                        <Badge variant="secondary">{issue.severity}</Badge>
+                      </TableCell>
+                      <TableCell className="font-mono">-{issue.penalty}</TableCell>
+                    </TableRow>
+                  ))}
+                </TableBody>
+              </Table>
+            )}
+          </CardContent>
+        </Card>
+      </TabsContent>
+    );
+  };
+
+  const pathsTab = (paths: apitypes.discoveredpath[] = []) => {
+    return (
+      <TabsContent value="paths">
+        <Card>
+          <CardHeader>
+            <CardTitle>Discovered Paths</CardTitle>
+          </CardHeader>
+          <CardContent>
+            {paths.length === 0 ? (
+              <div className="text-center text-muted-foreground">No paths found. Run 'gowitness scan paths' to brute force them</div>
+            ) : (
+              <Table>
+                <TableHeader>
+                  <TableRow>
+                    <TableHead>Path</TableHead>
+                    <TableHead>Code</TableHead>
+                    <TableHead>Size</TableHead>
+                    <TableHead>Title</TableHead>
+                  </TableRow>
+                </TableHeader>
+                <TableBody>
+                  {paths.map((path) => (
+                    <TableRow key={path.id}>
+                      <TableCell className="font-mono">
+                        {path.screenshot_result_id ? (
+                          <Link to={`/screenshot/${path.screenshot_result_id}`} className="hover:underline">{path.path}</Link>
+                        ) : (
+                          <a href={path.url} target="_blank" rel="noopener noreferrer" className="hover:underline">{path.path}</a>
+                        )}
+                      </TableCell>
+                      <TableCell>
+                        <Badge className={`${getStatusColor(path.status_code)} text-xs px-1 py-0`}>{path.status_code}</Badge>
+                      </TableCell>
+                      <TableCell>{path.content_length}</TableCell>
+                      <TableCell>{path.title || path.location}</TableCell>
+                    </TableRow>
+                  ))}
+                </TableBody>
+              </Table>
+            )}
+          </CardContent>
+        </Card>
+      </TabsContent>
+    );
+  };
+
+  const notesTab = (notes: apitypes.note[] = []) => {
+    return (
+      <TabsContent value="notes">
+        <Card>
+          <CardHeader>
+            <CardTitle>Notes</CardTitle>
+          </CardHeader>
+          <CardContent className="space-y-4">
+            {notes.length === 0 && (
+              <div className="text-center text-muted-foreground">No notes</div>
+            )}
+            {notes.map((note) => (
+              <div key={note.id} className="border rounded-md p-3 space-y-1">
+                <div className="flex justify-between items-center text-sm text-muted-foreground">
+                  <span>
+                    {note.author} &middot; {formatDistanceToNow(new Date(note.created_at), { addSuffix: true })}
+                  </span>
+                  <Button variant="ghost" size="sm" onClick={() => handleDeleteNote(note.id)}>
+                    <Trash2Icon className="h-4 w-4" />
+                  </Button>
+                </div>
+                <p className="text-sm whitespace-pre-wrap">{note.body}</p>
+              </div>
+            ))}
+            <div className="flex gap-2">
+              <Input
+                placeholder="Add a note..."
+                value={newNote}
+                onChange={(e) => setNewNote(e.target.value)}
+                onKeyDown={(e) => {
+                  e.stopPropagation();
+                  if (e.key === 'Enter') handleAddNote();
+                }}
+              />
+              <Button variant="outline" onClick={handleAddNote}>Add</Button>
+            </div>
+          </CardContent>
+        </Card>
+      </TabsContent>
+    );
+  };
+
+  const getTags = () => {
+    const tags = [...new Map((detail.tags || []).map(t => [t.name, t])).values()];
+    return (
+      <div className="flex flex-wrap items-center gap-2">
+        {tags.map(tag => (
+          <Badge key={tag.name} variant="secondary" className="flex items-center gap-1">
+            {tag.name}
+            <button onClick={() => handleRemoveTag(tag.name)} aria-label={`Remove tag ${tag.name}`}>
+              <XIcon className="h-3 w-3" />
+            </button>
+          </Badge>
+        ))}
+        <Input
+          className="h-8 w-[160px]"
+          placeholder="Add tag..."
+          value={newTag}
+          onChange={(e) => setNewTag(e.target.value)}
+          onKeyDown={(e) => {
+            e.stopPropagation();
+            if (e.key === 'Enter') handleAddTag();
+          }}
+        />
+        {detail.assigned_to && (
+          <span className="text-sm text-muted-foreground">Assigned to {detail.assigned_to}</span>
+        )}
+      </div>
+    );
+  };
+
   return (
     <div className="space-y-6">
       {getNavigation()}
+      {getTags()}
       <div className="flex flex-col lg:flex-row gap-4">
         {/* Left Column */}
         <div className="w-full lg:w-2/5 space-y-4">
@@ -721,11 +1034,17 @@ const ScreenshotDetailPage = () => {
               <TabsTrigger value="console">Console Log</TabsTrigger>
               <TabsTrigger value="headers">Response Headers</TabsTrigger>
               <TabsTrigger value="cookies">Cookies</TabsTrigger>
+              <TabsTrigger value="security">Security</TabsTrigger>
+              <TabsTrigger value="paths">Paths</TabsTrigger>
+              <TabsTrigger value="notes">Notes</TabsTrigger>
             </TabsList>
             {networkLogTab(detail.network)}
             {consoleLogTab(detail.console)}
             {headersTab(detail.headers)}
             {cookiesTab(detail.cookies)}
+            {securityTab(detail)}
+            {pathsTab(detail.paths)}
+            {notesTab(detail.notes)}
           </Tabs>
         </div>
       </div>
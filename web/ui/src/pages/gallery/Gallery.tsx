@@ -6,7 +6,7 @@ import { WideSkeleton } from "@/components/loading";
 import { Badge } from "@/components/ui/badge";
 import {
   AlertOctagonIcon, BanIcon, CheckIcon, ChevronLeftIcon, ChevronRightIcon, ClockIcon, ExternalLinkIcon,
-  FilterIcon, GroupIcon, ShieldCheckIcon, XIcon
+  FilterIcon, FlagIcon, GroupIcon, ShieldCheckIcon, TagIcon, XIcon
 } from "lucide-react";
 import { Tooltip, TooltipContent, TooltipProvider, TooltipTrigger } from "@/components/ui/tooltip";
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select";
@@ -21,6 +21,23 @@ import { getIconUrl, getStatusColor } from "@/lib/common";
 import { Label } from "@/components/ui/label";
 import { Switch } from "@/components/ui/switch";
 
+// annotationKinds are the kinds of annotations results can be flagged with
+const annotationKinds = [
+  "large-body",
+  "small-body",
+  "auth-challenge",
+  "cross-apex-redirect",
+  "protected-server",
+];
+
+// classifierTags are the tags the classifier adds to results
+const classifierTags = [
+  "login-panel",
+  "admin-interface",
+  "default-page",
+  "directory-listing",
+  "default-credentials",
+];
 
 const GalleryPage = () => {
   const [gallery, setGallery] = useState<apitypes.galleryResult[]>();
@@ -36,9 +53,12 @@ const GalleryPage = () => {
   //filters
   const technologyFilter = searchParams.get("technologies") || "";
   const statusFilter = searchParams.get("status") || "";
+  const annotationFilter = searchParams.get("annotations") || "";
+  const tagFilter = searchParams.get("tags") || "";
   // toggles
   const perceptionGroup = searchParams.get("perception") === "true";
   const showFailed = searchParams.get("failed") !== "false"; // Default to true
+  const showNoise = searchParams.get("noise") === "true";
 
   useEffect(() => {
     getWappalyzerData(setWappalyzer, setTechnology);
@@ -47,9 +67,9 @@ const GalleryPage = () => {
   useEffect(() => {
     getData(
       setLoading, setGallery, setTotalPages,
-      page, limit, technologyFilter, statusFilter, perceptionGroup, showFailed
+      page, limit, technologyFilter, statusFilter, annotationFilter, tagFilter, perceptionGroup, showFailed, showNoise
     );
-  }, [page, limit, perceptionGroup, statusFilter, technologyFilter, showFailed]);
+  }, [page, limit, perceptionGroup, statusFilter, technologyFilter, annotationFilter, tagFilter, showFailed, showNoise]);
 
   const handlePageChange = (newPage: number) => {
     setSearchParams(prev => {
@@ -99,6 +119,42 @@ const GalleryPage = () => {
     });
   };
 
+  const handleAnnotationFilter = (kind: string) => {
+    const field = "annotations";
+    setSearchParams(prev => {
+      const currentAnnotations = prev.get(field)?.split(",").filter(Boolean) || [];
+
+      if (currentAnnotations.includes(kind)) {
+        const updatedAnnotations = currentAnnotations.filter(s => s !== kind);
+        prev.set(field, updatedAnnotations.join(","));
+      } else {
+        currentAnnotations.push(kind);
+        prev.set(field, currentAnnotations.join(","));
+      }
+
+      return prev;
+    });
+    handlePageChange(1); // back to page 1
+  };
+
+  const handleTagFilter = (tag: string) => {
+    const field = "tags";
+    setSearchParams(prev => {
+      const currentTags = prev.get(field)?.split(",").filter(Boolean) || [];
+
+      if (currentTags.includes(tag)) {
+        const updatedTags = currentTags.filter(s => s !== tag);
+        prev.set(field, updatedTags.join(","));
+      } else {
+        currentTags.push(tag);
+        prev.set(field, currentTags.join(","));
+      }
+
+      return prev;
+    });
+    handlePageChange(1); // back to page 1
+  };
+
   const handleGroupBySimilar = () => {
     setSearchParams(prev => {
       prev.set("perception", (!perceptionGroup).toString());
@@ -113,6 +169,13 @@ const GalleryPage = () => {
     });
   };
 
+  const handleToggleShowNoise = () => {
+    setSearchParams(prev => {
+      prev.set("noise", (!showNoise).toString());
+      return prev;
+    });
+  };
+
   const sortedTechnologies = useMemo(() => {
     if (!technology) return [];
     const selectedTechnologies = technologyFilter.split(',').filter(Boolean);
@@ -161,7 +224,9 @@ const GalleryPage = () => {
               <img
                 src={screenshot.screenshot
                   ? `data:image/png;base64,${screenshot.screenshot}`
-                  : api.endpoints.screenshot.path + "/" + screenshot.file_name}
+                  : screenshot.thumbnail
+                    ? api.endpoints.screenshot.path + "/thumbs/" + screenshot.thumbnail
+                    : api.endpoints.screenshot.path + "/thumb/" + screenshot.file_name}
                 alt={screenshot.url}
                 loading="lazy"
                 className="w-full h-48 object-cover transition-all duration-300 filter group-hover:scale-105"
@@ -194,6 +259,24 @@ const GalleryPage = () => {
               <div className="w-full truncate text-xs text-muted-foreground mt-1">
                 {screenshot.url}
               </div>
+              {screenshot.annotations?.length > 0 && (
+                <div className="flex flex-wrap gap-1 mt-1">
+                  {screenshot.annotations.map(kind => (
+                    <Badge key={kind} variant="outline" className="text-xs">
+                      {kind}
+                    </Badge>
+                  ))}
+                </div>
+              )}
+              {screenshot.tags?.length > 0 && (
+                <div className="flex flex-wrap gap-1 mt-1">
+                  {screenshot.tags.map(tag => (
+                    <Badge key={tag} variant="secondary" className="text-xs">
+                      {tag}
+                    </Badge>
+                  ))}
+                </div>
+              )}
             </div>
             <div className="w-full flex items-center justify-between mt-2">
               <TooltipProvider delayDuration={0}>
@@ -286,6 +369,78 @@ const GalleryPage = () => {
               </Command>
             </PopoverContent>
           </Popover>
+          <Popover>
+            <PopoverTrigger asChild>
+              <Button variant="outline" className="w-[200px] justify-start">
+                <FlagIcon className="mr-2 h-4 w-4" />
+                {annotationFilter.split(',').filter(n => n).length > 0 ? (
+                  <>
+                    {annotationFilter.split(',').filter(n => n).length} selected
+                  </>
+                ) : (
+                  "Filter by Annotation"
+                )}
+              </Button>
+            </PopoverTrigger>
+            <PopoverContent className="w-[200px] p-0">
+              <Command>
+                <CommandList>
+                  <CommandGroup>
+                    {annotationKinds.map((kind) => (
+                      <CommandItem
+                        key={kind}
+                        onSelect={() => handleAnnotationFilter(kind)}
+                      >
+                        <CheckIcon
+                          className={cn(
+                            "mr-2 h-4 w-4",
+                            annotationFilter.split(',').includes(kind) ? "opacity-100" : "opacity-0"
+                          )}
+                        />
+                        {kind}
+                      </CommandItem>
+                    ))}
+                  </CommandGroup>
+                </CommandList>
+              </Command>
+            </PopoverContent>
+          </Popover>
+          <Popover>
+            <PopoverTrigger asChild>
+              <Button variant="outline" className="w-[200px] justify-start">
+                <TagIcon className="mr-2 h-4 w-4" />
+                {tagFilter.split(',').filter(n => n).length > 0 ? (
+                  <>
+                    {tagFilter.split(',').filter(n => n).length} selected
+                  </>
+                ) : (
+                  "Filter by Tag"
+                )}
+              </Button>
+            </PopoverTrigger>
+            <PopoverContent className="w-[200px] p-0">
+              <Command>
+                <CommandList>
+                  <CommandGroup>
+                    {classifierTags.map((tag) => (
+                      <CommandItem
+                        key={tag}
+                        onSelect={() => handleTagFilter(tag)}
+                      >
+                        <CheckIcon
+                          className={cn(
+                            "mr-2 h-4 w-4",
+                            tagFilter.split(',').includes(tag) ? "opacity-100" : "opacity-0"
+                          )}
+                        />
+                        {tag}
+                      </CommandItem>
+                    ))}
+                  </CommandGroup>
+                </CommandList>
+              </Command>
+            </PopoverContent>
+          </Popover>
           <Button
             variant={statusFilter.includes("200") ? "secondary" : "outline"}
             onClick={() => handleStatusFilter("200")}
@@ -324,6 +479,16 @@ const GalleryPage = () => {
               Show Failed
             </Label>
           </div>
+          <div className="flex items-center space-x-2 p-2">
+            <Switch
+              id="show-noise"
+              checked={showNoise}
+              onCheckedChange={handleToggleShowNoise}
+            />
+            <Label htmlFor="show-noise" className="text-sm">
+              Show Noise
+            </Label>
+          </div>
         </div>
         <div className="flex items-center space-x-2">
           <Button
@@ -30,8 +30,11 @@ const getData = async (
   limit: number,
   technologyFilter: string,
   statusFilter: string,
+  annotationFilter: string,
+  tagFilter: string,
   perceptionGroup: boolean,
   showFailed: boolean,
+  showNoise: boolean,
 ) => {
   setLoading(true);
   try {
@@ -40,8 +43,11 @@ const getData = async (
       limit,
       technologies: technologyFilter,
       status: statusFilter,
+      annotations: annotationFilter,
+      tags: tagFilter,
       perception: perceptionGroup ? 'true' : 'false',
       failed: showFailed ? 'true' : 'false',
+      noise: showNoise ? 'true' : 'false',
     });
     setGallery(s.results);
     setTotalPages(Math.ceil(s.total_count / limit));
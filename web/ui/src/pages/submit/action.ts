@@ -23,8 +23,9 @@ const submitJobAction = async ({ formData }: { formData: FormData; }) => {
     window_y: parseInt(formData.get('window_y') as string),
   };
 
+  let job;
   try {
-    await api.post('submit', { urls, options });
+    job = await api.post('submitjob', { urls, options });
   } catch (err) {
     toast({
       title: "Error",
@@ -36,7 +37,7 @@ const submitJobAction = async ({ formData }: { formData: FormData; }) => {
 
   toast({
     title: "Success!",
-    description: "Probe has been submitted"
+    description: `Probe has been submitted as job #${job.id}`
   });
 
   return redirect("/submit");
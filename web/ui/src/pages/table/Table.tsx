@@ -98,6 +98,9 @@ export default function TablePage() {
               <TableHead className="cursor-pointer" onClick={() => handleSort("content_length")}>
                 Size {sortColumn === "content_length" && <ArrowUpDown className="ml-2 h-4 w-4 inline" />}
               </TableHead>
+              <TableHead className="cursor-pointer" onClick={() => handleSort("security_score")}>
+                Security {sortColumn === "security_score" && <ArrowUpDown className="ml-2 h-4 w-4 inline" />}
+              </TableHead>
               <TableHead>Protocol</TableHead>
             </TableRow>
           </TableHeader>
@@ -127,6 +130,13 @@ export default function TablePage() {
                   {item.title}
                 </TableCell>
                 <TableCell>{(item.content_length / 1024).toFixed(2)} KB</TableCell>
+                <TableCell>
+                  {item.security_grade && (
+                    <Badge variant="outline">
+                      {item.security_grade} ({item.security_score})
+                    </Badge>
+                  )}
+                </TableCell>
                 <TableCell>{item.protocol}</TableCell>
               </TableRow>
             ))}
@@ -0,0 +1,127 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sensepost/gowitness/pkg/auth"
+	"github.com/sensepost/gowitness/pkg/log"
+	"github.com/sensepost/gowitness/web/api"
+)
+
+// sessionCookieName is the cookie holding a user's login session token
+const sessionCookieName = "gowitness_session"
+
+// cookiePath returns the path login cookies are set on
+func cookiePath(basePath string) string {
+	if basePath != "/" {
+		return basePath[:len(basePath)-1] // Remove trailing slash for non-root paths
+	}
+
+	return basePath
+}
+
+// userAuthMiddleware checks for a valid user login session, adding the
+// user to the request context
+func (s *Server) userAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookieName)
+		if err != nil || cookie.Value == "" {
+			http.Redirect(w, r, getBasePath(r)+"login", http.StatusTemporaryRedirect)
+			return
+		}
+
+		user, err := auth.SessionUser(s.db, cookie.Value)
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidSession) {
+				log.Error("failed to get session user", "err", err)
+			}
+			http.Redirect(w, r, getBasePath(r)+"login", http.StatusTemporaryRedirect)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(api.WithUser(r.Context(), user)))
+	})
+}
+
+// roleMiddleware rejects state changing requests from users whose role
+// does not allow changing data
+func (s *Server) roleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := api.RequestUser(r)
+		if user == nil || auth.CanWrite(user.Role) || readOnlyRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log.Warn("rejected state changing request from a read-only user", "user", user.Username, "path", r.URL.Path)
+		http.Error(w, "Your role does not allow changing data", http.StatusForbidden)
+	})
+}
+
+// requireAdmin rejects requests from users that are not admins
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := api.RequestUser(r)
+		if user == nil || user.Role != auth.RoleAdmin {
+			http.Error(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logoutHandler ends the current login session
+func (s *Server) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	basePath := getBasePath(r)
+
+	if cookie, err := r.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
+		if err := auth.EndSession(s.db, cookie.Value); err != nil {
+			log.Error("failed to end session", "err", err)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     cookiePath(basePath),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, basePath+"login", http.StatusTemporaryRedirect)
+}
+
+// userLogin processes a per-user login form submission
+func (s *Server) userLogin(w http.ResponseWriter, r *http.Request, basePath string) {
+	user, err := auth.Authenticate(s.db, r.FormValue("username"), r.FormValue("password"))
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidCredentials) {
+			log.Error("failed to authenticate user", "err", err)
+		}
+		log.Warn("failed login attempt", "remote", r.RemoteAddr, "username", r.FormValue("username"))
+		s.renderLoginPage(w, "Invalid username or password", basePath)
+		return
+	}
+
+	token, err := auth.NewSession(s.db, user)
+	if err != nil {
+		log.Error("failed to start login session", "err", err)
+		s.renderLoginPage(w, "Login failed, please try again", basePath)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     cookiePath(basePath),
+		Expires:  time.Now().Add(auth.SessionLifetime),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, basePath, http.StatusSeeOther)
+}